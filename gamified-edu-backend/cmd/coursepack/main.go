@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"gamified-edu-backend/internal/config"
+	"gamified-edu-backend/internal/coursepack"
+	"gamified-edu-backend/internal/media"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/internal/tenant"
+	"gamified-edu-backend/internal/xapi"
+	"gamified-edu-backend/pkg"
+	"log"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const usage = `Exports courses into a course bundle, or imports one, for an organization.
+Bundles are directories or .zip files, see package internal/coursepack.
+
+Usage:
+  go run ./cmd/coursepack export [-org slug] [-course slug]... -out bundle.zip|dir
+  go run ./cmd/coursepack import [-org slug] [-author email] [-dry-run] [-publish] bundle.zip|dir
+`
+
+// courseSlugs collects repeated -course flags
+type courseSlugs []string
+
+func (s *courseSlugs) String() string     { return strings.Join(*s, ",") }
+func (s *courseSlugs) Set(v string) error { *s = append(*s, v); return nil }
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	org := flags.String("org", models.DefaultOrganizationSlug, "slug of the organization")
+	var courses courseSlugs
+	out := flags.String("out", "", "export: zip file or directory to write")
+	flags.Var(&courses, "course", "export: slug of a course to export, may be repeated (defaults to all courses)")
+	author := flags.String("author", "", "import: email of the user to record as the author")
+	dryRun := flags.Bool("dry-run", false, "import: report what would change without changing anything")
+	publish := flags.Bool("publish", false, "import: publish the imported courses instead of leaving drafts")
+	flags.Parse(args)
+
+	config.LoadEnv()
+	db := config.ConnectDB()
+
+	organization, err := repositories.NewOrganizationRepository(db).FindBySlug(*org)
+	if err != nil {
+		log.Fatalf("Could not find organization %q: %v", *org, err)
+	}
+	ctx := tenant.WithOrganization(context.Background(), organization.ID)
+
+	assetStore, err := media.BlobStoreFromEnv()
+	if err != nil {
+		log.Fatal("Could not set up the media asset store: ", err)
+	}
+	searchIndex, err := repositories.SearchIndexFromEnv(db)
+	if err != nil {
+		log.Fatal("Could not set up the search index: ", err)
+	}
+	userRepo := repositories.NewUserRepository(db)
+	courseRepo := repositories.NewCourseRepository(db)
+	courseVersionRepo := repositories.NewCourseVersionRepository(db)
+	progressRepo := repositories.NewProgressRepository(db)
+	enrollmentRepo := repositories.NewEnrollmentRepository(db)
+	xapiService := services.NewXAPIService(repositories.NewXAPIStatementRepository(db), repositories.NewOrganizationRepository(db), userRepo, xapi.ClientFromEnv())
+	// Publishing may complete courses, and with them learning paths
+	learningPathService := services.NewLearningPathService(repositories.NewLearningPathRepository(db), courseRepo, progressRepo, enrollmentRepo, userRepo, pkg.SystemClock)
+	learningRecorder := services.LearningRecorders(xapiService, learningPathService)
+	progressService := services.NewProgressService(progressRepo, userRepo, repositories.NewActivityRepository(db), courseRepo, enrollmentRepo, learningRecorder, repositories.NewCohortRepository(db), pkg.SystemClock)
+	searchService := services.NewSearchService(searchIndex, courseRepo, enrollmentRepo, progressRepo, repositories.NewOrganizationRepository(db))
+	authoringService := services.NewCourseAuthoringService(courseRepo, courseVersionRepo, progressService, searchService)
+	coursePackService := services.NewCoursePackService(courseRepo, courseVersionRepo, repositories.NewQuizRepository(db), userRepo, authoringService, services.NewMediaAssetService(repositories.NewMediaAssetRepository(db), assetStore))
+
+	switch command {
+	case "export":
+		if *out == "" {
+			log.Fatal("-out is required")
+		}
+		bundle, err := coursePackService.Export(ctx, courses)
+		if err != nil {
+			log.Fatal("Export failed: ", err)
+		}
+		bundle.Name = organization.Slug
+		if err := writeBundle(*out, bundle); err != nil {
+			log.Fatal("Could not write the bundle: ", err)
+		}
+		log.Printf("Exported %d courses and %d quizzes to %s", len(bundle.Courses), len(bundle.Quizzes), *out)
+
+	case "import":
+		if flags.NArg() != 1 {
+			log.Fatal("Give the bundle to import")
+		}
+		authorID := primitive.NilObjectID
+		if *author != "" {
+			user, err := userRepo.FindByEmail(*author)
+			if err != nil {
+				log.Fatalf("Could not find author %q: %v", *author, err)
+			}
+			authorID = user.ID
+		}
+		bundle, err := coursepack.Open(flags.Arg(0))
+		if err != nil {
+			fatalBundleError("Could not read the bundle", err)
+		}
+		report, err := coursePackService.Import(ctx, authorID, bundle, services.ImportOptions{DryRun: *dryRun, Publish: *publish})
+		if err != nil {
+			fatalBundleError("Import failed", err)
+		}
+		printReport(report)
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// writeBundle writes a zip file when out ends in .zip and a directory otherwise
+func writeBundle(out string, bundle *coursepack.Bundle) error {
+	if !strings.HasSuffix(strings.ToLower(out), ".zip") {
+		return coursepack.WriteDir(out, bundle)
+	}
+	file, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := coursepack.WriteZip(file, bundle); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func fatalBundleError(message string, err error) {
+	var invalid *coursepack.ValidationError
+	if errors.As(err, &invalid) {
+		for _, problem := range invalid.Problems {
+			fmt.Fprintln(os.Stderr, "  "+problem)
+		}
+		log.Fatalf("%s: %d problems", message, len(invalid.Problems))
+	}
+	log.Fatalf("%s: %v", message, err)
+}
+
+func printReport(report *services.ImportReport) {
+	if report.DryRun {
+		fmt.Println("Dry run, nothing was changed.")
+	}
+	for _, quiz := range report.Quizzes {
+		fmt.Printf("quiz %s: %s\n", quiz.Slug, quiz.Action)
+	}
+	for _, course := range report.Courses {
+		line := fmt.Sprintf("course %s: %s", course.Slug, course.Action)
+		if course.SettingsChanged {
+			line += ", settings changed"
+		}
+		if course.PublishedVersion > 0 {
+			line += fmt.Sprintf(", published as version %d", course.PublishedVersion)
+		}
+		fmt.Println(line)
+		if course.Action == services.ImportCreated || course.Changes == nil {
+			continue
+		}
+		for _, module := range course.Changes.Modules {
+			fmt.Printf("  module %q %s %s\n", module.Title, module.Change, strings.Join(module.Fields, ", "))
+		}
+		for _, chapter := range course.Changes.Chapters {
+			fmt.Printf("  chapter %q %s %s\n", chapter.Title, chapter.Change, strings.Join(chapter.Fields, ", "))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"gamified-edu-backend/internal/config"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/services"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Builds a personal data export for one user and writes it to a local zip file.
+// Usage: go run ./cmd/export-user -email learner@example.com -out export.zip
+func main() {
+	userIDHex := flag.String("user", "", "ID of the user to export")
+	email := flag.String("email", "", "email of the user to export (alternative to -user)")
+	out := flag.String("out", "", "path of the zip file to write (defaults to <user id>.zip)")
+	flag.Parse()
+
+	if *userIDHex == "" && *email == "" {
+		log.Fatal("Either -user or -email is required")
+	}
+
+	config.LoadEnv()
+	db := config.ConnectDB()
+
+	userRepo := repositories.NewUserRepository(db)
+	exportService := services.NewDataExportService(repositories.NewDataExportRepository(db), repositories.NewUserDataRepository(db))
+
+	var user *models.User
+	if *userIDHex != "" {
+		id, err := primitive.ObjectIDFromHex(*userIDHex)
+		if err != nil {
+			log.Fatal("Invalid user ID:", err)
+		}
+		user, err = userRepo.FindByID(id)
+		if err != nil {
+			log.Fatal("Could not find user:", err)
+		}
+	} else {
+		found, err := userRepo.FindByEmail(*email)
+		if err != nil {
+			log.Fatal("Could not find user:", err)
+		}
+		user = found
+	}
+	userID := user.ID
+
+	path := *out
+	if path == "" {
+		path = userID.Hex() + ".zip"
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Fatal("Could not create output file:", err)
+	}
+	defer file.Close()
+
+	if err := exportService.WriteArchive(userID, user.OrganizationID, file); err != nil {
+		log.Fatal("Export failed:", err)
+	}
+	log.Printf("Wrote data export for user %s to %s", userID.Hex(), path)
+}
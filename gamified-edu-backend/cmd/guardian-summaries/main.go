@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"gamified-edu-backend/internal/config"
+	"gamified-edu-backend/internal/mailer"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/internal/tenant"
+	"gamified-edu-backend/internal/xapi"
+	"gamified-edu-backend/pkg"
+	"log"
+)
+
+// Sends the weekly progress summaries to guardians of every organization.
+// Meant to run from cron once a week; guardians who already got a summary
+// this week are skipped, so running it more often is harmless.
+// Usage: go run ./cmd/guardian-summaries
+func main() {
+	config.LoadEnv()
+	db := config.ConnectDB()
+
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatal("Could not set up the mailer: ", err)
+	}
+	searchIndex, err := repositories.SearchIndexFromEnv(db)
+	if err != nil {
+		log.Fatal("Could not set up the search index: ", err)
+	}
+
+	userRepo := repositories.NewUserRepository(db)
+	progressRepo := repositories.NewProgressRepository(db)
+	xapiService := services.NewXAPIService(repositories.NewXAPIStatementRepository(db), repositories.NewOrganizationRepository(db), userRepo, xapi.ClientFromEnv())
+	courseRepo := repositories.NewCourseRepository(db)
+	enrollmentRepo := repositories.NewEnrollmentRepository(db)
+	searchService := services.NewSearchService(searchIndex, courseRepo, enrollmentRepo, progressRepo, repositories.NewOrganizationRepository(db))
+	courseService := services.NewCourseService(courseRepo, progressRepo, enrollmentRepo, userRepo, xapiService, searchService, repositories.NewCohortRepository(db), pkg.SystemClock)
+	dashboardService := services.NewDashboardService(repositories.NewDashboardRepository(db), progressRepo, userRepo)
+	guardianService := services.NewGuardianService(repositories.NewGuardianRepository(db), userRepo, progressRepo, dashboardService, courseService, mail)
+
+	organizations, err := repositories.NewOrganizationRepository(db).FindAll()
+	if err != nil {
+		log.Fatal("Could not list organizations: ", err)
+	}
+
+	total := 0
+	for _, organization := range organizations {
+		ctx := tenant.WithOrganization(context.Background(), organization.ID)
+		sent, err := guardianService.SendWeeklySummaries(ctx)
+		total += sent
+		if err != nil {
+			log.Printf("Summaries for organization %s stopped after %d: %v", organization.Slug, sent, err)
+		}
+	}
+	log.Printf("Sent %d guardian summaries", total)
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"gamified-edu-backend/internal/config"
 	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
+	"time"
 )
 
 func main() {
@@ -28,67 +30,99 @@ func main() {
 
 	log.Println("Seeding database with initial courses...")
 
+	// Seeded courses belong to the default organization
+	organization, err := repositories.NewOrganizationRepository(db).EnsureDefault()
+	if err != nil {
+		log.Fatal("Error creating default organization:", err)
+	}
+
 	// Create the Forest Ecosystems course with proper chapters
 	forestCourse := models.Course{
-		ID:          primitive.NewObjectID(),
-		Title:       "Forest Ecosystems",
-		Description: "An introduction to woodland biodiversity and conservation.",
-		Chapters: []models.Chapter{
-			{
-				ID:            primitive.NewObjectID(),
-				Title:         "Introduction to Forest Ecosystems",
-				ChapterNumber: 1,
-				VideoURL:      "https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/BigBuckBunny.mp4",
-				QuizID:        "quiz_1",
-				PPTLink:       "https://example.com/forest_intro.pdf",
-				DurationMins:  30,
-			},
-			{
-				ID:            primitive.NewObjectID(),
-				Title:         "Biodiversity and Conservation",
-				ChapterNumber: 2,
-				VideoURL:      "https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/ElephantsDream.mp4",
-				QuizID:        "quiz_2",
-				PPTLink:       "https://example.com/biodiversity.pdf",
-				DurationMins:  25,
-			},
-			{
-				ID:            primitive.NewObjectID(),
-				Title:         "Ecosystem Services and Management",
-				ChapterNumber: 3,
-				VideoURL:      "https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/ForBiggerBlazes.mp4",
-				QuizID:        "quiz_3",
-				PPTLink:       "https://example.com/ecosystem_services.pdf",
-				DurationMins:  35,
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organization.ID,
+		Slug:           "forest-ecosystems",
+		Title:          "Forest Ecosystems",
+		Description:    "An introduction to woodland biodiversity and conservation.",
+		Categories:     []string{"Science", "Environment"},
+		Tags:           []string{"ecology", "forests", "conservation"},
+		Difficulty:     models.DifficultyBeginner,
+		Language:       "en",
+		Thumbnail:      "https://example.com/thumbnails/forest-ecosystems.jpg",
+		// Seeded content is live right away
+		PublishedVersion: 1,
+		Modules: []models.Module{{
+			ID:       primitive.NewObjectID(),
+			Title:    models.DefaultModuleTitle,
+			Position: 1,
+			Chapters: []models.Chapter{
+				{
+					ID:            primitive.NewObjectID(),
+					Title:         "Introduction to Forest Ecosystems",
+					ChapterNumber: 1,
+					Components:    lessonComponents("https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/BigBuckBunny.mp4", "quiz_1", "https://example.com/forest_intro.pdf"),
+					DurationMins:  30,
+				},
+				{
+					ID:            primitive.NewObjectID(),
+					Title:         "Biodiversity and Conservation",
+					ChapterNumber: 2,
+					Components:    lessonComponents("https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/ElephantsDream.mp4", "quiz_2", "https://example.com/biodiversity.pdf"),
+					DurationMins:  25,
+				},
+				{
+					ID:            primitive.NewObjectID(),
+					Title:         "Ecosystem Services and Management",
+					ChapterNumber: 3,
+					Components:    lessonComponents("https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/ForBiggerBlazes.mp4", "quiz_3", "https://example.com/ecosystem_services.pdf"),
+					DurationMins:  35,
+				},
 			},
-		},
+		}},
 	}
 
 	// Create Ocean Ecosystems course
 	oceanCourse := models.Course{
-		ID:          primitive.NewObjectID(),
-		Title:       "Ocean Ecosystems",
-		Description: "Explore marine biodiversity and ocean conservation efforts.",
-		Chapters: []models.Chapter{
-			{
-				ID:            primitive.NewObjectID(),
-				Title:         "Marine Biodiversity Basics",
-				ChapterNumber: 1,
-				VideoURL:      "https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/BigBuckBunny.mp4",
-				QuizID:        "quiz_ocean_1",
-				PPTLink:       "https://example.com/marine_basics.pdf",
-				DurationMins:  28,
-			},
-			{
-				ID:            primitive.NewObjectID(),
-				Title:         "Coral Reef Conservation",
-				ChapterNumber: 2,
-				VideoURL:      "https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/ElephantsDream.mp4",
-				QuizID:        "quiz_ocean_2",
-				PPTLink:       "https://example.com/coral_reefs.pdf",
-				DurationMins:  32,
+		ID:               primitive.NewObjectID(),
+		OrganizationID:   organization.ID,
+		Slug:             "ocean-ecosystems",
+		Title:            "Ocean Ecosystems",
+		Description:      "Explore marine biodiversity and ocean conservation efforts.",
+		Categories:       []string{"Science", "Environment"},
+		Tags:             []string{"ecology", "oceans", "marine-biology"},
+		Difficulty:       models.DifficultyIntermediate,
+		Language:         "en",
+		Thumbnail:        "https://example.com/thumbnails/ocean-ecosystems.jpg",
+		PublishedVersion: 1,
+		// Builds on the ecosystem basics of the forest course
+		PrerequisiteIDs: []primitive.ObjectID{forestCourse.ID},
+		Modules: []models.Module{{
+			ID:       primitive.NewObjectID(),
+			Title:    models.DefaultModuleTitle,
+			Position: 1,
+			Chapters: []models.Chapter{
+				{
+					ID:            primitive.NewObjectID(),
+					Title:         "Marine Biodiversity Basics",
+					ChapterNumber: 1,
+					Components:    lessonComponents("https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/BigBuckBunny.mp4", "quiz_ocean_1", "https://example.com/marine_basics.pdf"),
+					DurationMins:  28,
+				},
+				{
+					ID:            primitive.NewObjectID(),
+					Title:         "Coral Reef Conservation",
+					ChapterNumber: 2,
+					Components:    lessonComponents("https://commondatastorage.googleapis.com/gtv-videos-bucket/sample/ElephantsDream.mp4", "quiz_ocean_2", "https://example.com/coral_reefs.pdf"),
+					DurationMins:  32,
+				},
 			},
-		},
+		}},
+	}
+
+	// Slugs let course bundles update the seeded courses later
+	for _, course := range []*models.Course{&forestCourse, &oceanCourse} {
+		content := course.Content()
+		content.AssignSlugs(nil)
+		course.Modules = content.Modules
 	}
 
 	// Insert courses
@@ -98,8 +132,54 @@ func main() {
 		log.Fatal("Error inserting courses:", err)
 	}
 
+	// Record the seeded content as the first version of each course
+	now := time.Now()
+	var versions []interface{}
+	for _, course := range []models.Course{forestCourse, oceanCourse} {
+		versions = append(versions, models.CourseVersion{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: course.OrganizationID,
+			CourseID:       course.ID,
+			Version:        course.PublishedVersion,
+			CourseContent:  course.Content(),
+			PublishedAt:    now,
+			Note:           "Seeded content",
+		})
+	}
+	if _, err := db.Collection("course_versions").InsertMany(context.Background(), versions); err != nil {
+		log.Fatal("Error recording course versions:", err)
+	}
+
+	// Chain the courses into a learning path
+	ecologyPath := models.LearningPath{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organization.ID,
+		Title:          "Ecology Fundamentals",
+		Description:    "From woodlands to the open sea: the basics of ecosystems and how to protect them.",
+		Steps: []models.LearningPathStep{
+			{CourseID: forestCourse.ID},
+			{CourseID: oceanCourse.ID},
+		},
+		RewardXP:  200,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := db.Collection("learning_paths").InsertOne(context.Background(), ecologyPath); err != nil {
+		log.Fatal("Error inserting learning path:", err)
+	}
+
 	log.Printf("Successfully seeded database with %d courses:", len(result.InsertedIDs))
 	log.Printf("- %s (3 chapters)", forestCourse.Title)
 	log.Printf("- %s (2 chapters)", oceanCourse.Title)
+	log.Printf("and the learning path %s", ecologyPath.Title)
 	log.Println("Database seeding completed!")
-}
\ No newline at end of file
+}
+
+// lessonComponents builds the video, quiz and slides every seeded chapter has
+func lessonComponents(videoURL, quizID, slidesURL string) []models.ChapterComponent {
+	return []models.ChapterComponent{
+		{Key: "video", Type: models.ComponentVideo, Title: "Video", URL: videoURL},
+		{Key: "quiz", Type: models.ComponentQuiz, Title: "Quiz", QuizID: quizID},
+		{Key: "ppt", Type: models.ComponentSlides, Title: "Slides", URL: slidesURL},
+	}
+}
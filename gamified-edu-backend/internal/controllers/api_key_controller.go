@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKeyController struct {
+	apiKeyService services.APIKeyService
+}
+
+func NewAPIKeyController(service services.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: service}
+}
+
+// POST /api/v1/api-keys
+func (ctrl *APIKeyController) CreateKey(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, err := ctrl.apiKeyService.CreateUserKey(userID.(primitive.ObjectID), input)
+	if err != nil {
+		sendAPIKeyCreateError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, key)
+}
+
+// GET /api/v1/api-keys
+func (ctrl *APIKeyController) ListKeys(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	keys, err := ctrl.apiKeyService.ListUserKeys(userID.(primitive.ObjectID), page)
+	if err != nil {
+		sendAPIKeyError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, keys)
+}
+
+// DELETE /api/v1/api-keys/:keyId
+func (ctrl *APIKeyController) RevokeKey(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	keyID, err := primitive.ObjectIDFromHex(c.Param("keyId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid API key ID format")
+		return
+	}
+
+	if err := ctrl.apiKeyService.RevokeUserKey(userID.(primitive.ObjectID), keyID); err != nil {
+		sendAPIKeyError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// POST /api/v1/admin/api-keys
+func (ctrl *APIKeyController) CreateOrganizationKey(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+	organizationID, _ := c.Get("organizationID")
+	var input services.CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, err := ctrl.apiKeyService.CreateOrganizationKey(adminID.(primitive.ObjectID), organizationID.(primitive.ObjectID), input)
+	if err != nil {
+		sendAPIKeyCreateError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, key)
+}
+
+// GET /api/v1/admin/organizations/:organizationId/api-keys
+func (ctrl *APIKeyController) ListOrganizationKeys(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("organizationId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid organization ID format")
+		return
+	}
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	keys, err := ctrl.apiKeyService.ListOrganizationKeys(organizationID, page)
+	if err != nil {
+		sendAPIKeyError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, keys)
+}
+
+// DELETE /api/v1/admin/organizations/:organizationId/api-keys/:keyId
+func (ctrl *APIKeyController) RevokeOrganizationKey(c *gin.Context) {
+	organizationID, err := primitive.ObjectIDFromHex(c.Param("organizationId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid organization ID format")
+		return
+	}
+	keyID, err := primitive.ObjectIDFromHex(c.Param("keyId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid API key ID format")
+		return
+	}
+
+	if err := ctrl.apiKeyService.RevokeOrganizationKey(organizationID, keyID); err != nil {
+		sendAPIKeyError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// sendAPIKeyCreateError reports why a key could not be created
+func sendAPIKeyCreateError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrTwoFactorSetupPending) {
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+		return
+	}
+	pkg.SendError(c, http.StatusBadRequest, err.Error())
+}
+
+func sendAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, pkg.ErrInvalidCursor):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
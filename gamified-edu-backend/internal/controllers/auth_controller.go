@@ -1,6 +1,7 @@
 package controllers
 
 import (
+    "errors"
     "gamified-edu-backend/internal/services"
     "gamified-edu-backend/pkg"
     "net/http"
@@ -24,6 +25,10 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 
     _, err := ctrl.authService.RegisterUser(input)
     if err != nil {
+        if errors.Is(err, services.ErrInvalidOrganizationCode) {
+            pkg.SendError(c, http.StatusBadRequest, err.Error())
+            return
+        }
         pkg.SendError(c, http.StatusInternalServerError, "Failed to register user")
         return
     }
@@ -38,11 +43,39 @@ func (ctrl *AuthController) Login(c *gin.Context) {
         return
     }
 
-    token, err := ctrl.authService.LoginUser(input)
+    result, err := ctrl.authService.LoginUser(input, clientInfo(c))
     if err != nil {
         pkg.SendError(c, http.StatusUnauthorized, err.Error())
         return
     }
 
-    pkg.SendResponse(c, http.StatusOK, gin.H{"token": token})
-}
\ No newline at end of file
+    pkg.SendResponse(c, http.StatusOK, result)
+}
+
+// POST /api/v1/auth/login/2fa completes a login started with a challenge token
+func (ctrl *AuthController) CompleteTwoFactorLogin(c *gin.Context) {
+    var input services.TwoFactorLoginInput
+    if err := c.ShouldBindJSON(&input); err != nil {
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    result, err := ctrl.authService.CompleteTwoFactorLogin(input, clientInfo(c))
+    if err != nil {
+        status := http.StatusUnauthorized
+        if errors.Is(err, services.ErrTwoFactorLocked) {
+            status = http.StatusTooManyRequests
+        }
+        pkg.SendError(c, status, err.Error())
+        return
+    }
+
+    pkg.SendResponse(c, http.StatusOK, result)
+}
+
+func clientInfo(c *gin.Context) services.ClientInfo {
+    return services.ClientInfo{
+        UserAgent: c.Request.UserAgent(),
+        IPAddress: c.ClientIP(),
+    }
+}
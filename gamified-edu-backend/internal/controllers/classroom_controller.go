@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ClassroomController struct {
+	classroomService services.ClassroomService
+}
+
+func NewClassroomController(service services.ClassroomService) *ClassroomController {
+	return &ClassroomController{classroomService: service}
+}
+
+// POST /api/v1/classrooms
+func (ctrl *ClassroomController) CreateClassroom(c *gin.Context) {
+	var input services.CreateClassroomInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	classroom, err := ctrl.classroomService.CreateClassroom(c.Request.Context(), classroomActor(c).UserID, input)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, classroom)
+}
+
+// GET /api/v1/classrooms
+func (ctrl *ClassroomController) ListClassrooms(c *gin.Context) {
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	classrooms, err := ctrl.classroomService.ListClassrooms(c.Request.Context(), classroomActor(c), page)
+	if err != nil {
+		if errors.Is(err, pkg.ErrInvalidCursor) {
+			pkg.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, classrooms)
+}
+
+// GET /api/v1/classrooms/:classroomId
+func (ctrl *ClassroomController) GetClassroom(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	classroom, err := ctrl.classroomService.GetClassroom(c.Request.Context(), classroomActor(c), classroomID)
+	if err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, classroom)
+}
+
+// DELETE /api/v1/classrooms/:classroomId
+func (ctrl *ClassroomController) DeleteClassroom(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.classroomService.DeleteClassroom(c.Request.Context(), classroomActor(c), classroomID); err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Classroom deleted"})
+}
+
+// POST /api/v1/classrooms/:classroomId/join-code issues a new join code
+func (ctrl *ClassroomController) RotateJoinCode(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	classroom, err := ctrl.classroomService.RotateJoinCode(c.Request.Context(), classroomActor(c), classroomID)
+	if err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, classroom)
+}
+
+// POST /api/v1/classrooms/join
+func (ctrl *ClassroomController) JoinClassroom(c *gin.Context) {
+	var input services.JoinClassroomInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	classroom, err := ctrl.classroomService.JoinClassroom(c.Request.Context(), classroomActor(c), input.Code)
+	if err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, classroom)
+}
+
+// DELETE /api/v1/classrooms/:classroomId/members/:userId
+func (ctrl *ClassroomController) RemoveMember(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	if err := ctrl.classroomService.RemoveMember(c.Request.Context(), classroomActor(c), classroomID, userID); err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Student removed from classroom"})
+}
+
+// POST /api/v1/classrooms/:classroomId/assignments
+func (ctrl *ClassroomController) CreateAssignment(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.CreateAssignmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	assignment, err := ctrl.classroomService.CreateAssignment(c.Request.Context(), classroomActor(c), classroomID, input)
+	if err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, assignment)
+}
+
+// GET /api/v1/classrooms/:classroomId/assignments
+func (ctrl *ClassroomController) ListAssignments(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	assignments, err := ctrl.classroomService.ListAssignments(c.Request.Context(), classroomActor(c), classroomID, page)
+	if err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, assignments)
+}
+
+// DELETE /api/v1/classrooms/:classroomId/assignments/:assignmentId
+func (ctrl *ClassroomController) DeleteAssignment(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	assignmentID, err := primitive.ObjectIDFromHex(c.Param("assignmentId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid assignment ID format")
+		return
+	}
+
+	if err := ctrl.classroomService.DeleteAssignment(c.Request.Context(), classroomActor(c), classroomID, assignmentID); err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Assignment deleted"})
+}
+
+// GET /api/v1/classrooms/:classroomId/roster
+func (ctrl *ClassroomController) GetRoster(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	roster, err := ctrl.classroomService.GetRoster(c.Request.Context(), classroomActor(c), classroomID)
+	if err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, roster)
+}
+
+func classroomActor(c *gin.Context) services.ClassroomActor {
+	userID, _ := c.Get("userID")
+	return services.ClassroomActor{UserID: userID.(primitive.ObjectID), Role: c.GetString("role")}
+}
+
+func classroomIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	classroomID, err := primitive.ObjectIDFromHex(c.Param("classroomId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid classroom ID format")
+		return primitive.NilObjectID, false
+	}
+	return classroomID, true
+}
+
+func sendClassroomError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrClassroomNotFound), errors.Is(err, services.ErrAssignmentNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotInstructor):
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+	default:
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	}
+}
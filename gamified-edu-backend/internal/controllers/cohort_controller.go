@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CohortController struct {
+	cohortService services.CohortService
+}
+
+func NewCohortController(cohortService services.CohortService) *CohortController {
+	return &CohortController{cohortService: cohortService}
+}
+
+// GET /api/v1/courses/:courseId/cohorts
+func (ctrl *CohortController) ListCohorts(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	cohorts, err := ctrl.cohortService.ListCohorts(c.Request.Context(), courseID, page)
+	if err != nil {
+		sendCohortError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, cohorts)
+}
+
+// POST /api/v1/courses/:courseId/cohorts
+func (ctrl *CohortController) CreateCohort(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.CohortInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	cohort, err := ctrl.cohortService.CreateCohort(c.Request.Context(), userID.(primitive.ObjectID), courseID, input)
+	if err != nil {
+		sendCohortError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, cohort)
+}
+
+// PUT /api/v1/courses/:courseId/cohorts/:cohortId
+func (ctrl *CohortController) UpdateCohort(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	cohortID, ok := cohortIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.CohortInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	cohort, err := ctrl.cohortService.UpdateCohort(c.Request.Context(), courseID, cohortID, input)
+	if err != nil {
+		sendCohortError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, cohort)
+}
+
+// DELETE /api/v1/courses/:courseId/cohorts/:cohortId
+func (ctrl *CohortController) DeleteCohort(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	cohortID, ok := cohortIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.cohortService.DeleteCohort(c.Request.Context(), courseID, cohortID); err != nil {
+		sendCohortError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Cohort deleted"})
+}
+
+// PUT /api/v1/courses/:courseId/cohort
+func (ctrl *CohortController) JoinCohort(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.JoinCohortInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	cohort, err := ctrl.cohortService.JoinCohort(c.Request.Context(), userID.(primitive.ObjectID), courseID, input)
+	if err != nil {
+		sendCohortError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, cohort)
+}
+
+// DELETE /api/v1/courses/:courseId/cohort
+func (ctrl *CohortController) LeaveCohort(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.cohortService.LeaveCohort(c.Request.Context(), userID.(primitive.ObjectID), courseID); err != nil {
+		sendCohortError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Left the cohort"})
+}
+
+func cohortIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	cohortID, err := primitive.ObjectIDFromHex(c.Param("cohortId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid cohort ID format")
+		return primitive.NilObjectID, false
+	}
+	return cohortID, true
+}
+
+func sendCohortError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCohortNotFound), errors.Is(err, services.ErrCourseNotFound), errors.Is(err, services.ErrNotEnrolled):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidCohort), errors.Is(err, pkg.ErrInvalidCursor):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
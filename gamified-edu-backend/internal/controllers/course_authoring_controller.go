@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CourseAuthoringController struct {
+	authoringService services.CourseAuthoringService
+}
+
+func NewCourseAuthoringController(service services.CourseAuthoringService) *CourseAuthoringController {
+	return &CourseAuthoringController{authoringService: service}
+}
+
+// POST /api/v1/courses creates an unpublished course
+func (ctrl *CourseAuthoringController) CreateCourse(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.CreateCourseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	draft, err := ctrl.authoringService.CreateCourse(c.Request.Context(), userID.(primitive.ObjectID), input)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, draft)
+}
+
+// GET /api/v1/courses/:courseId/draft
+func (ctrl *CourseAuthoringController) GetDraft(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	draft, err := ctrl.authoringService.GetDraft(c.Request.Context(), courseID)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, draft)
+}
+
+// PUT /api/v1/courses/:courseId/draft
+func (ctrl *CourseAuthoringController) SaveDraft(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.CourseContentBody
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	draft, err := ctrl.authoringService.SaveDraft(c.Request.Context(), userID.(primitive.ObjectID), courseID, input)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, draft)
+}
+
+// DELETE /api/v1/courses/:courseId/draft
+func (ctrl *CourseAuthoringController) DiscardDraft(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.authoringService.DiscardDraft(c.Request.Context(), courseID); err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Draft discarded"})
+}
+
+// POST /api/v1/courses/:courseId/publish
+func (ctrl *CourseAuthoringController) Publish(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.PublishInput
+	if !bindOptionalJSON(c, &input) {
+		return
+	}
+	version, err := ctrl.authoringService.Publish(c.Request.Context(), userID.(primitive.ObjectID), courseID, input)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, version)
+}
+
+// GET /api/v1/courses/:courseId/versions
+func (ctrl *CourseAuthoringController) ListVersions(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	versions, err := ctrl.authoringService.ListVersions(c.Request.Context(), courseID, page)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, versions)
+}
+
+// GET /api/v1/courses/:courseId/versions/:version
+func (ctrl *CourseAuthoringController) GetVersion(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := versionParam(c)
+	if !ok {
+		return
+	}
+	found, err := ctrl.authoringService.GetVersion(c.Request.Context(), courseID, version)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, found)
+}
+
+// GET /api/v1/courses/:courseId/versions/:version/diff?against=
+// compares against the previous version unless another one is given
+func (ctrl *CourseAuthoringController) DiffVersion(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := versionParam(c)
+	if !ok {
+		return
+	}
+	against := version - 1
+	if raw := c.Query("against"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid version to compare against")
+			return
+		}
+		against = parsed
+	}
+	diff, err := ctrl.authoringService.DiffVersions(c.Request.Context(), courseID, against, version)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, diff)
+}
+
+// POST /api/v1/courses/:courseId/versions/:version/rollback
+func (ctrl *CourseAuthoringController) Rollback(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	version, ok := versionParam(c)
+	if !ok {
+		return
+	}
+	var input services.PublishInput
+	if !bindOptionalJSON(c, &input) {
+		return
+	}
+	published, err := ctrl.authoringService.Rollback(c.Request.Context(), userID.(primitive.ObjectID), courseID, version, input)
+	if err != nil {
+		sendAuthoringError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, published)
+}
+
+func courseIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	courseID, err := primitive.ObjectIDFromHex(c.Param("courseId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid course ID format")
+		return primitive.NilObjectID, false
+	}
+	return courseID, true
+}
+
+func versionParam(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid version")
+		return 0, false
+	}
+	return version, true
+}
+
+// bindOptionalJSON binds the body if there is one
+func bindOptionalJSON(c *gin.Context, input interface{}) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func sendAuthoringError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCourseNotFound), errors.Is(err, services.ErrVersionNotFound), errors.Is(err, services.ErrNoDraft):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrDraftOutdated), errors.Is(err, services.ErrPublishConflict), errors.Is(err, services.ErrCourseSlugTaken):
+		pkg.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidCourseContent), errors.Is(err, services.ErrNoChanges), errors.Is(err, services.ErrAlreadyPublished), errors.Is(err, pkg.ErrInvalidCursor):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
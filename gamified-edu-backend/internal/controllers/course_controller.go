@@ -1,6 +1,7 @@
 package controllers
 
 import (
+    "errors"
     "gamified-edu-backend/internal/services"
     "gamified-edu-backend/pkg"
     "net/http"
@@ -17,15 +18,35 @@ func NewCourseController(courseService services.CourseService) *CourseController
 }
 
 func (ctrl *CourseController) GetAllCourses(c *gin.Context) {
+    ctrl.listCourses(c, c.Query("category"), c.Query("tag"))
+}
+
+// GET /api/v1/catalog/categories/:category/courses
+func (ctrl *CourseController) GetCategoryCourses(c *gin.Context) {
+    ctrl.listCourses(c, c.Param("category"), "")
+}
+
+// GET /api/v1/catalog/tags/:tag/courses
+func (ctrl *CourseController) GetTagCourses(c *gin.Context) {
+    ctrl.listCourses(c, "", c.Param("tag"))
+}
+
+func (ctrl *CourseController) listCourses(c *gin.Context, category, tag string) {
     val, exists := c.Get("userID")
     if !exists {
         pkg.SendError(c, http.StatusUnauthorized, "User not authenticated")
         return
     }
     userID := val.(primitive.ObjectID)
-    courses, err := ctrl.courseService.GetAllCoursesWithProgress(userID)
+    page, err := pkg.PageRequestFromQuery(c)
     if err != nil {
-        pkg.SendError(c, http.StatusInternalServerError, err.Error())
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+    query := services.CourseListQuery{Sort: c.Query("sort"), Completion: c.Query("completion"), Category: category, Tag: tag, Page: page}
+    courses, err := ctrl.courseService.GetAllCoursesWithProgress(c.Request.Context(), userID, query)
+    if err != nil {
+        sendCourseError(c, err)
         return
     }
     pkg.SendResponse(c, http.StatusOK, courses)
@@ -46,11 +67,126 @@ func (ctrl *CourseController) GetCourseDetails(c *gin.Context) {
         return
     }
     
-    course, err := ctrl.courseService.GetCourseDetails(courseID, userID)
+    course, err := ctrl.courseService.GetCourseDetails(c.Request.Context(), courseID, userID)
     if err != nil {
-        pkg.SendError(c, http.StatusInternalServerError, err.Error())
+        sendCourseError(c, err)
         return
     }
     
     pkg.SendResponse(c, http.StatusOK, course)
+}
+
+// GET /api/v1/catalog/categories lists the categories with how many courses each has
+func (ctrl *CourseController) GetCategories(c *gin.Context) {
+    page, err := pkg.PageRequestFromQuery(c)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+    categories, err := ctrl.courseService.ListCategories(c.Request.Context(), page)
+    if err != nil {
+        sendCourseError(c, err)
+        return
+    }
+    pkg.SendResponse(c, http.StatusOK, categories)
+}
+
+// GET /api/v1/catalog/tags
+func (ctrl *CourseController) GetTags(c *gin.Context) {
+    page, err := pkg.PageRequestFromQuery(c)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+    tags, err := ctrl.courseService.ListTags(c.Request.Context(), page)
+    if err != nil {
+        sendCourseError(c, err)
+        return
+    }
+    pkg.SendResponse(c, http.StatusOK, tags)
+}
+
+// GET /api/v1/courses/enrolled lists the user's courses
+func (ctrl *CourseController) GetEnrolledCourses(c *gin.Context) {
+    userID, _ := c.Get("userID")
+    page, err := pkg.PageRequestFromQuery(c)
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+    courses, err := ctrl.courseService.GetEnrolledCourses(c.Request.Context(), userID.(primitive.ObjectID), page)
+    if err != nil {
+        sendCourseError(c, err)
+        return
+    }
+    pkg.SendResponse(c, http.StatusOK, courses)
+}
+
+// POST /api/v1/courses/:courseId/enrollment
+func (ctrl *CourseController) Enroll(c *gin.Context) {
+    userID, _ := c.Get("userID")
+    courseID, err := primitive.ObjectIDFromHex(c.Param("courseId"))
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid course ID format")
+        return
+    }
+
+    enrollment, err := ctrl.courseService.Enroll(c.Request.Context(), userID.(primitive.ObjectID), courseID)
+    if err != nil {
+        sendCourseError(c, err)
+        return
+    }
+    pkg.SendResponse(c, http.StatusOK, enrollment)
+}
+
+// DELETE /api/v1/courses/:courseId/enrollment
+func (ctrl *CourseController) Unenroll(c *gin.Context) {
+    userID, _ := c.Get("userID")
+    courseID, err := primitive.ObjectIDFromHex(c.Param("courseId"))
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid course ID format")
+        return
+    }
+
+    if err := ctrl.courseService.Unenroll(c.Request.Context(), userID.(primitive.ObjectID), courseID); err != nil {
+        sendCourseError(c, err)
+        return
+    }
+    pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Unenrolled from course"})
+}
+
+// PUT /api/v1/courses/:courseId/settings
+func (ctrl *CourseController) UpdateCourseSettings(c *gin.Context) {
+    courseID, err := primitive.ObjectIDFromHex(c.Param("courseId"))
+    if err != nil {
+        pkg.SendError(c, http.StatusBadRequest, "Invalid course ID format")
+        return
+    }
+    var input services.UpdateCourseSettingsInput
+    if err := c.ShouldBindJSON(&input); err != nil {
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    settings, err := ctrl.courseService.UpdateCourseSettings(c.Request.Context(), courseID, input)
+    if err != nil {
+        if errors.Is(err, services.ErrCourseNotFound) {
+            pkg.SendError(c, http.StatusNotFound, err.Error())
+            return
+        }
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+    pkg.SendResponse(c, http.StatusOK, settings)
+}
+
+func sendCourseError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrCourseNotFound), errors.Is(err, services.ErrNotEnrolled):
+        pkg.SendError(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, services.ErrInvalidCourseQuery), errors.Is(err, pkg.ErrInvalidCursor):
+        pkg.SendError(c, http.StatusBadRequest, err.Error())
+    default:
+        pkg.SendError(c, http.StatusInternalServerError, err.Error())
+    }
 }
\ No newline at end of file
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/coursepack"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxBundleUpload caps uploaded bundles; media inside is capped by coursepack.MaxMediaSize
+const maxBundleUpload = coursepack.MaxMediaSize + 64<<20
+
+type CoursePackController struct {
+	coursePackService services.CoursePackService
+}
+
+func NewCoursePackController(service services.CoursePackService) *CoursePackController {
+	return &CoursePackController{coursePackService: service}
+}
+
+// GET /api/v1/admin/coursepack/export?course=<slug>&course=<slug> downloads a
+// bundle zip of the given courses, or of all courses
+func (ctrl *CoursePackController) Export(c *gin.Context) {
+	bundle, err := ctrl.coursePackService.Export(c.Request.Context(), c.QueryArray("course"))
+	if err != nil {
+		sendCoursePackError(c, err)
+		return
+	}
+	// Check before the status line goes out, a broken bundle should not become a broken download
+	if err := bundle.Validate(); err != nil {
+		sendCoursePackError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="coursepack-`+time.Now().Format("2006-01-02")+`.zip"`)
+	c.Status(http.StatusOK)
+	if err := coursepack.WriteZip(c.Writer, bundle); err != nil {
+		log.Printf("Could not write course bundle: %v", err)
+	}
+}
+
+// POST /api/v1/admin/coursepack/import?dry_run=true&publish=true takes a
+// bundle zip as the "bundle" form file
+func (ctrl *CoursePackController) Import(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	options := services.ImportOptions{}
+	for name, target := range map[string]*bool{"dry_run": &options.DryRun, "publish": &options.Publish} {
+		if raw := c.Query(name); raw != "" {
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				pkg.SendError(c, http.StatusBadRequest, "Invalid value for "+name)
+				return
+			}
+			*target = value
+		}
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleUpload)
+	header, err := c.FormFile("bundle")
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Upload the bundle zip as the \"bundle\" file: "+err.Error())
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer file.Close()
+
+	bundle, err := coursepack.ReadZip(file, header.Size)
+	if err != nil {
+		sendCoursePackError(c, err)
+		return
+	}
+	report, err := ctrl.coursePackService.Import(c.Request.Context(), userID.(primitive.ObjectID), bundle, options)
+	if err != nil {
+		sendCoursePackError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, report)
+}
+
+func sendCoursePackError(c *gin.Context, err error) {
+	var invalid *coursepack.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		// Every problem at once, so a bundle can be fixed in one go
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"status": "error", "message": coursepack.ErrInvalidBundle.Error(), "problems": invalid.Problems})
+	case errors.Is(err, services.ErrCourseNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidCourseContent), errors.Is(err, services.ErrCourseSlugTaken), errors.Is(err, services.ErrCourseNotExportable):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
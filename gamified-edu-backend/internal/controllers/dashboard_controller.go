@@ -28,7 +28,7 @@ func (ctrl *DashboardController) GetDashboard(c *gin.Context) {
 	}
 
 	log.Printf("Getting dashboard data for user: %v", userID)
-	dashboardData, err := ctrl.dashboardService.GetDashboardData(userID.(primitive.ObjectID))
+	dashboardData, err := ctrl.dashboardService.GetDashboardData(c.Request.Context(), userID.(primitive.ObjectID))
 	if err != nil {
 		log.Printf("Error getting dashboard data: %v", err)
 		pkg.SendError(c, http.StatusInternalServerError, "Could not generate dashboard data")
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DataExportController struct {
+	dataExportService services.DataExportService
+}
+
+func NewDataExportController(service services.DataExportService) *DataExportController {
+	return &DataExportController{dataExportService: service}
+}
+
+// POST /api/v1/me/exports
+func (ctrl *DataExportController) RequestExport(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	organizationID, _ := c.Get("organizationID")
+	export, err := ctrl.dataExportService.RequestExport(userID.(primitive.ObjectID), organizationID.(primitive.ObjectID), userID.(primitive.ObjectID))
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusAccepted, export)
+}
+
+// GET /api/v1/me/exports
+func (ctrl *DataExportController) ListExports(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	organizationID, _ := c.Get("organizationID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	exports, err := ctrl.dataExportService.ListExports(userID.(primitive.ObjectID), organizationID.(primitive.ObjectID), page)
+	if err != nil {
+		sendExportListError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, exports)
+}
+
+// GET /api/v1/me/exports/:exportId
+func (ctrl *DataExportController) GetExport(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	organizationID, _ := c.Get("organizationID")
+	exportID, err := primitive.ObjectIDFromHex(c.Param("exportId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid export ID format")
+		return
+	}
+
+	export, err := ctrl.dataExportService.GetExport(userID.(primitive.ObjectID), organizationID.(primitive.ObjectID), exportID)
+	if err != nil {
+		if errors.Is(err, services.ErrDataExportNotFound) {
+			pkg.SendError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, export)
+}
+
+// GET /api/v1/exports/:exportId/download?expires=...&signature=...
+// The signed query string is the only credential, so the link works without a token.
+func (ctrl *DataExportController) Download(c *gin.Context) {
+	exportID, err := primitive.ObjectIDFromHex(c.Param("exportId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid export ID format")
+		return
+	}
+
+	export, err := ctrl.dataExportService.OpenDownload(exportID, c.Request.URL.Path, c.Request.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, pkg.ErrSignatureInvalid):
+			pkg.SendError(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, pkg.ErrSignatureExpired), errors.Is(err, services.ErrDataExportNotReady):
+			pkg.SendError(c, http.StatusGone, err.Error())
+		case errors.Is(err, services.ErrDataExportNotFound):
+			pkg.SendError(c, http.StatusNotFound, err.Error())
+		default:
+			pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	c.FileAttachment(export.FilePath, "data-export-"+export.CreatedAt.Format("2006-01-02")+".zip")
+}
+
+// POST /api/v1/admin/users/:userId/exports answers a data-subject access request on a user's behalf
+func (ctrl *DataExportController) RequestExportForUser(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+	organizationID, _ := c.Get("organizationID")
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	export, err := ctrl.dataExportService.RequestExport(userID, organizationID.(primitive.ObjectID), adminID.(primitive.ObjectID))
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusAccepted, export)
+}
+
+// GET /api/v1/admin/users/:userId/exports
+func (ctrl *DataExportController) ListExportsForUser(c *gin.Context) {
+	organizationID, _ := c.Get("organizationID")
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	exports, err := ctrl.dataExportService.ListExports(userID, organizationID.(primitive.ObjectID), page)
+	if err != nil {
+		sendExportListError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, exports)
+}
+
+func sendExportListError(c *gin.Context, err error) {
+	if errors.Is(err, pkg.ErrInvalidCursor) {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	pkg.SendError(c, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GradebookController struct {
+	gradebookService services.GradebookService
+}
+
+func NewGradebookController(service services.GradebookService) *GradebookController {
+	return &GradebookController{gradebookService: service}
+}
+
+// GET /api/v1/classrooms/:classroomId/gradebook?course_id=&search=&sort=&order=&page=&page_size=
+func (ctrl *GradebookController) GetGradebook(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	query, ok := gradebookQuery(c)
+	if !ok {
+		return
+	}
+
+	gradebook, err := ctrl.gradebookService.GetGradebook(c.Request.Context(), classroomActor(c), classroomID, query)
+	if err != nil {
+		sendClassroomError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gradebook)
+}
+
+// GET /api/v1/classrooms/:classroomId/gradebook/export?format=csv|xlsx takes the same filters as the gradebook
+func (ctrl *GradebookController) ExportGradebook(c *gin.Context) {
+	classroomID, ok := classroomIDParam(c)
+	if !ok {
+		return
+	}
+	query, ok := gradebookQuery(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", services.GradebookFormatCSV)
+	export, err := ctrl.gradebookService.ExportGradebook(c.Request.Context(), classroomActor(c), classroomID, query, format)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedExportFormat) {
+			pkg.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendClassroomError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Content-Disposition", `attachment; filename="`+export.Filename+`"`)
+	c.Status(http.StatusOK)
+	// The status line is already sent, so a failure can only cut the download short
+	if err := export.Write(c.Writer); err != nil {
+		log.Printf("Could not write gradebook export for classroom %s: %v", classroomID.Hex(), err)
+	}
+}
+
+func gradebookQuery(c *gin.Context) (services.GradebookQuery, bool) {
+	query := services.GradebookQuery{
+		Search: c.Query("search"),
+		Sort:   c.Query("sort"),
+	}
+	if courseIDHex := c.Query("course_id"); courseIDHex != "" {
+		courseID, err := primitive.ObjectIDFromHex(courseIDHex)
+		if err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid course ID format")
+			return query, false
+		}
+		query.CourseID = courseID
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		pkg.SendError(c, http.StatusBadRequest, "order must be asc or desc")
+		return query, false
+	}
+
+	var err error
+	if page := c.Query("page"); page != "" {
+		if query.Page, err = strconv.Atoi(page); err != nil || query.Page < 1 {
+			pkg.SendError(c, http.StatusBadRequest, "page must be a positive number")
+			return query, false
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if query.PageSize, err = strconv.Atoi(pageSize); err != nil || query.PageSize < 1 {
+			pkg.SendError(c, http.StatusBadRequest, "page_size must be a positive number")
+			return query, false
+		}
+	}
+	return query, true
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GuardianController struct {
+	guardianService services.GuardianService
+}
+
+func NewGuardianController(service services.GuardianService) *GuardianController {
+	return &GuardianController{guardianService: service}
+}
+
+// POST /api/v1/me/guardians invites a guardian by email
+func (ctrl *GuardianController) InviteGuardian(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.InviteGuardianInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	link, err := ctrl.guardianService.InviteGuardian(c.Request.Context(), userID.(primitive.ObjectID), input)
+	if err != nil {
+		sendGuardianError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, link)
+}
+
+// GET /api/v1/me/guardians
+func (ctrl *GuardianController) ListGuardians(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	links, err := ctrl.guardianService.ListGuardians(c.Request.Context(), userID.(primitive.ObjectID), page)
+	if err != nil {
+		if errors.Is(err, pkg.ErrInvalidCursor) {
+			pkg.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, links)
+}
+
+// POST /api/v1/me/guardians/:linkId/approve
+func (ctrl *GuardianController) ApproveLink(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	linkID, ok := guardianLinkIDParam(c)
+	if !ok {
+		return
+	}
+	link, err := ctrl.guardianService.ApproveLink(c.Request.Context(), userID.(primitive.ObjectID), linkID)
+	if err != nil {
+		sendGuardianError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, link)
+}
+
+// DELETE /api/v1/me/guardians/:linkId and /api/v1/guardian/links/:linkId
+func (ctrl *GuardianController) RevokeLink(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	linkID, ok := guardianLinkIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.guardianService.RevokeLink(c.Request.Context(), userID.(primitive.ObjectID), linkID); err != nil {
+		sendGuardianError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Guardian link revoked"})
+}
+
+// POST /api/v1/guardian/links asks a student for access
+func (ctrl *GuardianController) RequestLink(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.RequestGuardianLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	link, err := ctrl.guardianService.RequestLink(c.Request.Context(), userID.(primitive.ObjectID), input)
+	if err != nil {
+		sendGuardianError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, link)
+}
+
+// POST /api/v1/guardian/links/accept redeems a student's invite code
+func (ctrl *GuardianController) AcceptInvite(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.AcceptGuardianInviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	link, err := ctrl.guardianService.AcceptInvite(c.Request.Context(), userID.(primitive.ObjectID), input.Code)
+	if err != nil {
+		sendGuardianError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, link)
+}
+
+// GET /api/v1/guardian/students
+func (ctrl *GuardianController) ListStudents(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	links, err := ctrl.guardianService.ListStudents(c.Request.Context(), userID.(primitive.ObjectID), page)
+	if err != nil {
+		if errors.Is(err, pkg.ErrInvalidCursor) {
+			pkg.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, links)
+}
+
+// GET /api/v1/guardian/students/:studentId/overview
+func (ctrl *GuardianController) GetStudentOverview(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	studentID, err := primitive.ObjectIDFromHex(c.Param("studentId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	overview, err := ctrl.guardianService.GetStudentOverview(c.Request.Context(), userID.(primitive.ObjectID), studentID)
+	if err != nil {
+		sendGuardianError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, overview)
+}
+
+func guardianLinkIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	linkID, err := primitive.ObjectIDFromHex(c.Param("linkId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid link ID format")
+		return primitive.NilObjectID, false
+	}
+	return linkID, true
+}
+
+func sendGuardianError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGuardianLinkNotFound), errors.Is(err, services.ErrStudentNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrGuardianLinkExists):
+		pkg.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrNotStudent), errors.Is(err, services.ErrInviteEmailMismatch):
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+	default:
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	}
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LearningPathController struct {
+	pathService services.LearningPathService
+}
+
+func NewLearningPathController(pathService services.LearningPathService) *LearningPathController {
+	return &LearningPathController{pathService: pathService}
+}
+
+// GET /api/v1/paths
+func (ctrl *LearningPathController) ListPaths(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	paths, err := ctrl.pathService.ListPaths(c.Request.Context(), userID.(primitive.ObjectID), page)
+	if err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, paths)
+}
+
+// GET /api/v1/paths/enrolled
+func (ctrl *LearningPathController) GetEnrolledPaths(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	paths, err := ctrl.pathService.GetEnrolledPaths(c.Request.Context(), userID.(primitive.ObjectID), page)
+	if err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, paths)
+}
+
+// GET /api/v1/paths/:pathId
+func (ctrl *LearningPathController) GetPath(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	pathID, ok := pathIDParam(c)
+	if !ok {
+		return
+	}
+	path, err := ctrl.pathService.GetPath(c.Request.Context(), userID.(primitive.ObjectID), pathID)
+	if err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, path)
+}
+
+// POST /api/v1/paths/:pathId/enrollment
+func (ctrl *LearningPathController) Enroll(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	pathID, ok := pathIDParam(c)
+	if !ok {
+		return
+	}
+	path, err := ctrl.pathService.Enroll(c.Request.Context(), userID.(primitive.ObjectID), pathID)
+	if err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, path)
+}
+
+// DELETE /api/v1/paths/:pathId/enrollment
+func (ctrl *LearningPathController) Unenroll(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	pathID, ok := pathIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.pathService.Unenroll(c.Request.Context(), userID.(primitive.ObjectID), pathID); err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Unenrolled from learning path"})
+}
+
+// POST /api/v1/paths
+func (ctrl *LearningPathController) CreatePath(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.LearningPathInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	path, err := ctrl.pathService.CreatePath(c.Request.Context(), userID.(primitive.ObjectID), input)
+	if err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, path)
+}
+
+// PUT /api/v1/paths/:pathId
+func (ctrl *LearningPathController) UpdatePath(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	pathID, ok := pathIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.LearningPathInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	path, err := ctrl.pathService.UpdatePath(c.Request.Context(), userID.(primitive.ObjectID), pathID, input)
+	if err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, path)
+}
+
+// DELETE /api/v1/paths/:pathId
+func (ctrl *LearningPathController) DeletePath(c *gin.Context) {
+	pathID, ok := pathIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.pathService.DeletePath(c.Request.Context(), pathID); err != nil {
+		sendLearningPathError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Learning path deleted"})
+}
+
+func pathIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	pathID, err := primitive.ObjectIDFromHex(c.Param("pathId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid learning path ID format")
+		return primitive.NilObjectID, false
+	}
+	return pathID, true
+}
+
+func sendLearningPathError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPathNotFound), errors.Is(err, services.ErrNotEnrolledInPath):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidPath), errors.Is(err, pkg.ErrInvalidCursor):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
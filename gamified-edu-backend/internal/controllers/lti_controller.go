@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/lti"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LTIController struct {
+	ltiService services.LTIService
+}
+
+func NewLTIController(service services.LTIService) *LTIController {
+	return &LTIController{ltiService: service}
+}
+
+// GET or POST /api/v1/lti/login
+// Platforms start every launch here, with a third-party login initiation.
+func (ctrl *LTIController) Login(c *gin.Context) {
+	// Platforms may send the initiation as a query or as a form
+	param := func(name string) string {
+		if value, ok := c.GetPostForm(name); ok {
+			return value
+		}
+		return c.Query(name)
+	}
+	redirectURL, err := ctrl.ltiService.Login(c.Request.Context(), lti.LoginRequest{
+		Issuer:          param("iss"),
+		LoginHint:       param("login_hint"),
+		TargetLinkURI:   param("target_link_uri"),
+		LTIMessageHint:  param("lti_message_hint"),
+		ClientID:        param("client_id"),
+		LTIDeploymentID: param("lti_deployment_id"),
+	})
+	if err != nil {
+		sendLTIError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// POST /api/v1/lti/launch
+// The platform's authorization endpoint posts the launch's id_token here.
+func (ctrl *LTIController) Launch(c *gin.Context) {
+	idToken, state := c.PostForm("id_token"), c.PostForm("state")
+	if idToken == "" || state == "" {
+		pkg.SendError(c, http.StatusBadRequest, "id_token and state are required")
+		return
+	}
+	redirectURL, err := ctrl.ltiService.Launch(c.Request.Context(), idToken, state, clientInfo(c))
+	if err != nil {
+		sendLTIError(c, err)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// GET /api/v1/lti/jwks
+// Platforms verify deep linking responses and grade service requests with these keys.
+func (ctrl *LTIController) KeySet(c *gin.Context) {
+	c.JSON(http.StatusOK, ctrl.ltiService.KeySet())
+}
+
+// POST /api/v1/lti/deep-links/:deepLinkId
+func (ctrl *LTIController) CompleteDeepLink(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	deepLinkID, err := primitive.ObjectIDFromHex(c.Param("deepLinkId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid deep link ID format")
+		return
+	}
+	var input services.CompleteDeepLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := ctrl.ltiService.CompleteDeepLink(c.Request.Context(), userID.(primitive.ObjectID), deepLinkID, input)
+	if err != nil {
+		sendLTIError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, response)
+}
+
+// GET /api/v1/admin/lti/tool
+func (ctrl *LTIController) GetToolConfiguration(c *gin.Context) {
+	pkg.SendResponse(c, http.StatusOK, ctrl.ltiService.ToolConfiguration())
+}
+
+// POST /api/v1/admin/lti/platforms
+func (ctrl *LTIController) RegisterPlatform(c *gin.Context) {
+	var input services.RegisterLTIPlatformInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	platform, err := ctrl.ltiService.RegisterPlatform(c.Request.Context(), input)
+	if err != nil {
+		sendLTIError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, platform)
+}
+
+// GET /api/v1/admin/lti/platforms
+func (ctrl *LTIController) ListPlatforms(c *gin.Context) {
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	platforms, err := ctrl.ltiService.ListPlatforms(c.Request.Context(), page)
+	if err != nil {
+		sendLTIError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, platforms)
+}
+
+// DELETE /api/v1/admin/lti/platforms/:platformId
+func (ctrl *LTIController) DeletePlatform(c *gin.Context) {
+	platformID, err := primitive.ObjectIDFromHex(c.Param("platformId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid platform ID format")
+		return
+	}
+	if err := ctrl.ltiService.DeletePlatform(c.Request.Context(), platformID); err != nil {
+		sendLTIError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "LTI platform deleted"})
+}
+
+func sendLTIError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, lti.ErrInvalidLaunch), errors.Is(err, services.ErrInvalidDeepLink), errors.Is(err, pkg.ErrInvalidCursor):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrLTILoginExpired):
+		pkg.SendError(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrLTIUserConflict), errors.Is(err, services.ErrDeepLinkNotAllowed):
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrLTIPlatformNotFound), errors.Is(err, services.ErrDeepLinkNotFound),
+		errors.Is(err, services.ErrLTICourseNotLinked), errors.Is(err, services.ErrCourseNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrLTIPlatformExists):
+		pkg.SendError(c, http.StatusConflict, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MediaAssetController struct {
+	assetService services.MediaAssetService
+}
+
+func NewMediaAssetController(service services.MediaAssetService) *MediaAssetController {
+	return &MediaAssetController{assetService: service}
+}
+
+// POST /api/v1/media/assets
+// The file is uploaded as the "file" part of a multipart form, optionally
+// with its hex SHA-256 as the "sha256" field.
+func (ctrl *MediaAssetController) Upload(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxAssetUpload)
+	header, err := c.FormFile("file")
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Upload the asset as the \"file\" part: "+err.Error())
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer file.Close()
+
+	input := services.UploadAssetInput{FileName: header.Filename, SHA256: c.PostForm("sha256")}
+	asset, err := ctrl.assetService.Upload(c.Request.Context(), userID.(primitive.ObjectID), input, file, header.Size)
+	if err != nil {
+		sendMediaAssetError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, asset)
+}
+
+// GET /api/v1/media/assets
+func (ctrl *MediaAssetController) ListAssets(c *gin.Context) {
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	assets, err := ctrl.assetService.ListAssets(c.Request.Context(), page)
+	if err != nil {
+		sendMediaAssetError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, assets)
+}
+
+// GET /api/v1/media/assets/:assetId
+func (ctrl *MediaAssetController) GetAsset(c *gin.Context) {
+	assetID, ok := assetIDParam(c)
+	if !ok {
+		return
+	}
+	asset, err := ctrl.assetService.GetAsset(c.Request.Context(), assetID)
+	if err != nil {
+		sendMediaAssetError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, asset)
+}
+
+// DELETE /api/v1/media/assets/:assetId
+func (ctrl *MediaAssetController) DeleteAsset(c *gin.Context) {
+	assetID, ok := assetIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.assetService.DeleteAsset(c.Request.Context(), assetID); err != nil {
+		sendMediaAssetError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Media asset deleted"})
+}
+
+// GET /api/v1/media/assets/:assetId/content?expires=...&signature=...
+// The signed query string is the only credential, so players can load the
+// URL directly. Range requests let videos stream and seek.
+func (ctrl *MediaAssetController) Content(c *gin.Context) {
+	assetID, ok := assetIDParam(c)
+	if !ok {
+		return
+	}
+	asset, blob, err := ctrl.assetService.OpenContent(c.Request.Context(), assetID, c.Request.URL.Path, c.Request.URL.Query())
+	if err != nil {
+		sendMediaAssetError(c, err)
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Type", asset.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": asset.FileName}))
+	// Content never changes under an asset ID, only the signature does
+	c.Header("ETag", `"`+asset.SHA256+`"`)
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, asset.FileName, blob.ModTime(), blob)
+}
+
+func assetIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	assetID, err := primitive.ObjectIDFromHex(c.Param("assetId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid asset ID format")
+		return primitive.NilObjectID, false
+	}
+	return assetID, true
+}
+
+func sendMediaAssetError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAssetTypeNotAllowed):
+		pkg.SendError(c, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, services.ErrAssetTooLarge):
+		pkg.SendError(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrAssetChecksumMismatch), errors.Is(err, pkg.ErrInvalidCursor):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, pkg.ErrSignatureInvalid):
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, pkg.ErrSignatureExpired):
+		pkg.SendError(c, http.StatusGone, err.Error())
+	case errors.Is(err, services.ErrAssetNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
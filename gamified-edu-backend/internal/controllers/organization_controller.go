@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrganizationController struct {
+	organizationService services.OrganizationService
+}
+
+func NewOrganizationController(service services.OrganizationService) *OrganizationController {
+	return &OrganizationController{organizationService: service}
+}
+
+// POST /api/v1/platform/organizations
+func (ctrl *OrganizationController) CreateOrganization(c *gin.Context) {
+	var input services.CreateOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	organization, err := ctrl.organizationService.CreateOrganization(input)
+	if err != nil {
+		if errors.Is(err, services.ErrOrganizationExists) {
+			pkg.SendError(c, http.StatusConflict, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, organization)
+}
+
+// GET /api/v1/platform/organizations
+func (ctrl *OrganizationController) ListOrganizations(c *gin.Context) {
+	organizationID, _ := c.Get("organizationID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	organizations, err := ctrl.organizationService.ListOrganizations(organizationID.(primitive.ObjectID), page)
+	if err != nil {
+		if errors.Is(err, pkg.ErrInvalidCursor) {
+			pkg.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, organizations)
+}
+
+// PUT /api/v1/platform/users/:userId/organization
+func (ctrl *OrganizationController) AssignUser(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+	var input services.AssignOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	organizationID, err := primitive.ObjectIDFromHex(input.OrganizationID)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid organization ID format")
+		return
+	}
+
+	if err := ctrl.organizationService.AssignUser(userID, organizationID); err != nil {
+		if errors.Is(err, services.ErrOrganizationNotFound) || errors.Is(err, services.ErrUserNotFound) {
+			pkg.SendError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "User moved to organization, their sessions were revoked"})
+}
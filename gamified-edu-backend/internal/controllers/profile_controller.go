@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProfileController struct {
+	profileService services.ProfileService
+}
+
+func NewProfileController(service services.ProfileService) *ProfileController {
+	return &ProfileController{profileService: service}
+}
+
+// GET /api/v1/me
+func (ctrl *ProfileController) GetProfile(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	profile, err := ctrl.profileService.GetProfile(userID.(primitive.ObjectID))
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, profile)
+}
+
+// PATCH /api/v1/me
+func (ctrl *ProfileController) UpdateProfile(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.UpdateProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	profile, err := ctrl.profileService.UpdateProfile(userID.(primitive.ObjectID), input)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, profile)
+}
+
+// DELETE /api/v1/me
+func (ctrl *ProfileController) DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.DeleteAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := ctrl.profileService.DeleteAccount(userID.(primitive.ObjectID), input); err != nil {
+		if errors.Is(err, services.ErrInvalidPassword) {
+			pkg.SendError(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Account deleted"})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+    "errors"
     "gamified-edu-backend/internal/services"
     "gamified-edu-backend/pkg"
     "github.com/gin-gonic/gin"
@@ -21,7 +22,7 @@ func (ctrl *ProgressController) MarkComponentComplete(c *gin.Context) {
     userID, _ := c.Get("userID")
     chapterIDHex := c.Param("chapterId")
     courseIDHex := c.Param("courseId")
-    component := c.Param("component") // "video", "quiz", or "ppt"
+    component := c.Param("component") // The component key, e.g. "video", "quiz" or "ppt"
 
     chapterID, err := primitive.ObjectIDFromHex(chapterIDHex)
     if err != nil {
@@ -34,9 +35,28 @@ func (ctrl *ProgressController) MarkComponentComplete(c *gin.Context) {
         return
     }
 
-    err = ctrl.progressService.MarkComponentAsComplete(userID.(primitive.ObjectID), chapterID, courseID, component)
+    var input services.MarkComponentInput
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&input); err != nil {
+            pkg.SendError(c, http.StatusBadRequest, err.Error())
+            return
+        }
+    }
+
+    err = ctrl.progressService.MarkComponentAsComplete(c.Request.Context(), userID.(primitive.ObjectID), chapterID, courseID, component, input)
     if err != nil {
-        pkg.SendError(c, http.StatusInternalServerError, err.Error())
+        switch {
+        case errors.Is(err, services.ErrCourseNotFound), errors.Is(err, services.ErrChapterNotInCourse), errors.Is(err, services.ErrComponentNotFound):
+            pkg.SendError(c, http.StatusNotFound, err.Error())
+        case errors.Is(err, services.ErrInvalidComponentReport):
+            pkg.SendError(c, http.StatusBadRequest, err.Error())
+        case errors.Is(err, services.ErrNotEnrolled), errors.Is(err, services.ErrChapterLocked), errors.Is(err, services.ErrChapterNotReleased):
+            pkg.SendError(c, http.StatusForbidden, err.Error())
+        case errors.Is(err, services.ErrChapterAlreadyCompleted):
+            pkg.SendError(c, http.StatusConflict, err.Error())
+        default:
+            pkg.SendError(c, http.StatusInternalServerError, err.Error())
+        }
         return
     }
 
@@ -54,11 +74,32 @@ func (ctrl *ProgressController) GetCourseProgress(c *gin.Context) {
         return
     }
 
-    progress, err := ctrl.progressService.GetUserCourseProgress(userID.(primitive.ObjectID), courseID)
+    progress, err := ctrl.progressService.GetUserCourseProgress(c.Request.Context(), userID.(primitive.ObjectID), courseID)
     if err != nil {
         pkg.SendError(c, http.StatusInternalServerError, err.Error())
         return
     }
 
     pkg.SendResponse(c, http.StatusOK, gin.H{"progress": progress})
-}
\ No newline at end of file
+}
+
+// GET /api/v1/reports/progress?course_id=
+func (ctrl *ProgressController) GetProgressReport(c *gin.Context) {
+    courseID := primitive.NilObjectID
+    if courseIDHex := c.Query("course_id"); courseIDHex != "" {
+        id, err := primitive.ObjectIDFromHex(courseIDHex)
+        if err != nil {
+            pkg.SendError(c, http.StatusBadRequest, "Invalid course ID format")
+            return
+        }
+        courseID = id
+    }
+
+    report, err := ctrl.progressService.GetProgressReport(c.Request.Context(), courseID)
+    if err != nil {
+        pkg.SendError(c, http.StatusInternalServerError, err.Error())
+        return
+    }
+
+    pkg.SendResponse(c, http.StatusOK, gin.H{"progress": report})
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type QuizController struct {
+	quizService services.QuizService
+}
+
+func NewQuizController(quizService services.QuizService) *QuizController {
+	return &QuizController{quizService: quizService}
+}
+
+type gradeQuizInput struct {
+	Answers []int `json:"answers" binding:"required"`
+}
+
+// GET /api/v1/quizzes/:quizId returns the questions without their answers
+func (ctrl *QuizController) GetQuiz(c *gin.Context) {
+	quiz, err := ctrl.quizService.GetQuiz(c.Request.Context(), c.Param("quizId"))
+	if err != nil {
+		sendQuizError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, quiz)
+}
+
+// POST /api/v1/quizzes/course/:courseId/chapter/:chapterId/:component grades
+// an attempt at the component's quiz
+func (ctrl *QuizController) GradeQuiz(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, chapterID, ok := runtimeIDs(c)
+	if !ok {
+		return
+	}
+	var input gradeQuizInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := ctrl.quizService.GradeQuiz(c.Request.Context(), userID.(primitive.ObjectID), courseID, chapterID, c.Param("component"), input.Answers)
+	if err != nil {
+		sendQuizError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, result)
+}
+
+func sendQuizError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrQuizNotFound), errors.Is(err, services.ErrCourseNotFound),
+		errors.Is(err, services.ErrChapterNotInCourse), errors.Is(err, services.ErrComponentNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidAnswers), errors.Is(err, services.ErrNotQuizComponent),
+		errors.Is(err, services.ErrInvalidComponentReport):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrNotEnrolled), errors.Is(err, services.ErrChapterLocked), errors.Is(err, services.ErrChapterNotReleased):
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
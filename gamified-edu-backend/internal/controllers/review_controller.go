@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReviewController struct {
+	reviewService services.ReviewService
+}
+
+func NewReviewController(reviewService services.ReviewService) *ReviewController {
+	return &ReviewController{reviewService: reviewService}
+}
+
+// GET /api/v1/courses/:courseId/reviews
+func (ctrl *ReviewController) GetReviews(c *gin.Context) {
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	reviews, err := ctrl.reviewService.GetReviews(c.Request.Context(), courseID, page)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, reviews)
+}
+
+// GET /api/v1/courses/:courseId/review
+func (ctrl *ReviewController) GetMyReview(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	review, err := ctrl.reviewService.GetMyReview(c.Request.Context(), userID.(primitive.ObjectID), courseID)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, review)
+}
+
+// PUT /api/v1/courses/:courseId/review
+func (ctrl *ReviewController) SubmitReview(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	review, err := ctrl.reviewService.SubmitReview(c.Request.Context(), userID.(primitive.ObjectID), courseID, input)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, review)
+}
+
+// DELETE /api/v1/courses/:courseId/review
+func (ctrl *ReviewController) DeleteReview(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, ok := courseIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.reviewService.DeleteReview(c.Request.Context(), userID.(primitive.ObjectID), courseID); err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Review deleted"})
+}
+
+// POST /api/v1/reviews/:reviewId/report
+func (ctrl *ReviewController) ReportReview(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	reviewID, ok := reviewIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.ReportReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := ctrl.reviewService.ReportReview(c.Request.Context(), userID.(primitive.ObjectID), reviewID, input); err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, gin.H{"message": "Review reported"})
+}
+
+// PUT /api/v1/reviews/:reviewId/reply
+func (ctrl *ReviewController) ReplyToReview(c *gin.Context) {
+	reviewID, ok := reviewIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.ReviewReplyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	review, err := ctrl.reviewService.ReplyToReview(c.Request.Context(), reviewActor(c), reviewID, input)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, review)
+}
+
+// DELETE /api/v1/reviews/:reviewId/reply
+func (ctrl *ReviewController) DeleteReply(c *gin.Context) {
+	reviewID, ok := reviewIDParam(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.reviewService.DeleteReply(c.Request.Context(), reviewActor(c), reviewID); err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Reply deleted"})
+}
+
+// GET /api/v1/admin/reviews?status= lists reviews to moderate, flagged ones by default
+func (ctrl *ReviewController) ListReviewsForModeration(c *gin.Context) {
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	status := c.DefaultQuery("status", models.ReviewFlagged)
+	reviews, err := ctrl.reviewService.ListReviewsForModeration(c.Request.Context(), status, page)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, reviews)
+}
+
+// PUT /api/v1/admin/reviews/:reviewId/moderation
+func (ctrl *ReviewController) ModerateReview(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	reviewID, ok := reviewIDParam(c)
+	if !ok {
+		return
+	}
+	var input services.ModerateReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	review, err := ctrl.reviewService.ModerateReview(c.Request.Context(), userID.(primitive.ObjectID), reviewID, input)
+	if err != nil {
+		sendReviewError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, review)
+}
+
+func reviewActor(c *gin.Context) services.ReviewActor {
+	userID, _ := c.Get("userID")
+	return services.ReviewActor{UserID: userID.(primitive.ObjectID), Role: c.GetString("role")}
+}
+
+func reviewIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	reviewID, err := primitive.ObjectIDFromHex(c.Param("reviewId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid review ID format")
+		return primitive.NilObjectID, false
+	}
+	return reviewID, true
+}
+
+func sendReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrReviewNotFound), errors.Is(err, services.ErrCourseNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrReviewTooEarly), errors.Is(err, services.ErrNotCourseAuthor), errors.Is(err, services.ErrReportOwnReview):
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrAlreadyReported):
+		pkg.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidReviewQuery), errors.Is(err, pkg.ErrInvalidCursor):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"archive/zip"
+	"errors"
+	"gamified-edu-backend/internal/scorm"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxPackageUpload caps uploaded SCORM zips; their unpacked files are capped by scorm.MaxPackageSize
+const maxPackageUpload = scorm.MaxPackageSize + 64<<20
+
+type SCORMController struct {
+	scormService services.SCORMService
+}
+
+func NewSCORMController(service services.SCORMService) *SCORMController {
+	return &SCORMController{scormService: service}
+}
+
+// POST /api/v1/scorm/packages?publish=true takes a SCORM zip as the "package"
+// form file and creates a course from it
+func (ctrl *SCORMController) ImportPackage(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	publish := false
+	if raw := c.Query("publish"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid value for publish")
+			return
+		}
+		publish = value
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPackageUpload)
+	header, err := c.FormFile("package")
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Upload the SCORM zip as the \"package\" file: "+err.Error())
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer file.Close()
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Not a zip file: "+err.Error())
+		return
+	}
+
+	response, err := ctrl.scormService.ImportPackage(c.Request.Context(), userID.(primitive.ObjectID), archive, publish)
+	if err != nil {
+		sendSCORMError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusCreated, response)
+}
+
+// GET /api/v1/scorm/course/:courseId/chapter/:chapterId/:component is called
+// by the SCO's Initialize (LMSInitialize in SCORM 1.2)
+func (ctrl *SCORMController) GetRuntime(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, chapterID, ok := runtimeIDs(c)
+	if !ok {
+		return
+	}
+	response, err := ctrl.scormService.GetRuntime(c.Request.Context(), userID.(primitive.ObjectID), courseID, chapterID, c.Param("component"))
+	if err != nil {
+		sendSCORMError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, response)
+}
+
+// PUT /api/v1/scorm/course/:courseId/chapter/:chapterId/:component is called
+// by the SCO's Commit and Finish (LMSCommit and LMSFinish in SCORM 1.2)
+func (ctrl *SCORMController) CommitRuntime(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	courseID, chapterID, ok := runtimeIDs(c)
+	if !ok {
+		return
+	}
+	var input services.CommitRuntimeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := ctrl.scormService.CommitRuntime(c.Request.Context(), userID.(primitive.ObjectID), courseID, chapterID, c.Param("component"), input)
+	if err != nil {
+		sendSCORMError(c, err)
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, response)
+}
+
+func runtimeIDs(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	courseID, err := primitive.ObjectIDFromHex(c.Param("courseId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid course ID format")
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	chapterID, err := primitive.ObjectIDFromHex(c.Param("chapterId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid chapter ID format")
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return courseID, chapterID, true
+}
+
+func sendSCORMError(c *gin.Context, err error) {
+	var invalid *services.CMIDataError
+	switch {
+	case errors.As(err, &invalid):
+		// The SCORM error code of every rejected element, for the SCO's GetLastError
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"status": "error", "message": services.ErrInvalidCMIData.Error(), "errors": invalid.Errors})
+	case errors.Is(err, scorm.ErrInvalidPackage), errors.Is(err, services.ErrInvalidCourseContent),
+		errors.Is(err, services.ErrInvalidCMIData), errors.Is(err, services.ErrNotSCORMComponent):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrCourseNotFound), errors.Is(err, services.ErrChapterNotInCourse),
+		errors.Is(err, services.ErrComponentNotFound), errors.Is(err, services.ErrSCORMPackageNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotEnrolled), errors.Is(err, services.ErrChapterLocked), errors.Is(err, services.ErrChapterNotReleased):
+		pkg.SendError(c, http.StatusForbidden, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SearchController struct {
+	searchService services.SearchService
+}
+
+func NewSearchController(service services.SearchService) *SearchController {
+	return &SearchController{searchService: service}
+}
+
+// GET /api/v1/search?q=...&tag=...&difficulty=...&min_duration=...&max_duration=...&completion=...&limit=...
+func (ctrl *SearchController) Search(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	input := services.SearchInput{
+		Query:      c.Query("q"),
+		Tag:        c.Query("tag"),
+		Difficulty: c.Query("difficulty"),
+		Completion: c.Query("completion"),
+	}
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"min_duration", &input.MinDuration}, {"max_duration", &input.MaxDuration}, {"limit", &input.Limit}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			pkg.SendError(c, http.StatusBadRequest, param.name+" must be a positive number")
+			return
+		}
+		*param.value = value
+	}
+
+	results, err := ctrl.searchService.Search(c.Request.Context(), userID.(primitive.ObjectID), input)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSearch) {
+			pkg.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, results)
+}
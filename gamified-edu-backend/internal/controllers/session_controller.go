@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionController struct {
+	sessionService services.SessionService
+}
+
+func NewSessionController(service services.SessionService) *SessionController {
+	return &SessionController{sessionService: service}
+}
+
+// GET /api/v1/sessions
+func (ctrl *SessionController) ListSessions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	sessionID, _ := c.Get("sessionID")
+	page, err := pkg.PageRequestFromQuery(c)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sessions, err := ctrl.sessionService.ListSessions(userID.(primitive.ObjectID), sessionID.(primitive.ObjectID), page)
+	if err != nil {
+		if errors.Is(err, pkg.ErrInvalidCursor) {
+			pkg.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, sessions)
+}
+
+// DELETE /api/v1/sessions/:sessionId
+func (ctrl *SessionController) RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("sessionId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid session ID format")
+		return
+	}
+
+	if err := ctrl.sessionService.RevokeSession(userID.(primitive.ObjectID), sessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			pkg.SendError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// DELETE /api/v1/sessions revokes every session except the one making the request
+func (ctrl *SessionController) RevokeOtherSessions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	sessionID, _ := c.Get("sessionID")
+
+	count, err := ctrl.sessionService.RevokeOtherSessions(userID.(primitive.ObjectID), sessionID.(primitive.ObjectID))
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"revoked": count})
+}
+
+// POST /api/v1/auth/logout
+func (ctrl *SessionController) Logout(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	sessionID, _ := c.Get("sessionID")
+
+	if err := ctrl.sessionService.RevokeSession(userID.(primitive.ObjectID), sessionID.(primitive.ObjectID)); err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// DELETE /api/v1/admin/users/:userId/sessions
+func (ctrl *SessionController) RevokeUserSessions(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	count, err := ctrl.sessionService.RevokeAllForUser(userID)
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"revoked": count})
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TwoFactorController struct {
+	twoFactorService services.TwoFactorService
+}
+
+func NewTwoFactorController(service services.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{twoFactorService: service}
+}
+
+// POST /api/v1/auth/2fa/enroll
+func (ctrl *TwoFactorController) Enroll(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	enrollment, err := ctrl.twoFactorService.BeginEnrollment(userID.(primitive.ObjectID))
+	if err != nil {
+		pkg.SendError(c, twoFactorErrorStatus(err), err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, enrollment)
+}
+
+// POST /api/v1/auth/2fa/verify
+func (ctrl *TwoFactorController) Verify(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := ctrl.twoFactorService.ConfirmEnrollment(userID.(primitive.ObjectID), input.Code)
+	if err != nil {
+		pkg.SendError(c, twoFactorErrorStatus(err), err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled. Please log in again.",
+		"recovery_codes": codes,
+	})
+}
+
+// POST /api/v1/auth/2fa/disable
+func (ctrl *TwoFactorController) Disable(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := ctrl.twoFactorService.Disable(userID.(primitive.ObjectID), input.Code); err != nil {
+		pkg.SendError(c, twoFactorErrorStatus(err), err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// POST /api/v1/auth/2fa/recovery-codes
+func (ctrl *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	var input services.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := ctrl.twoFactorService.RegenerateRecoveryCodes(userID.(primitive.ObjectID), input.Code)
+	if err != nil {
+		pkg.SendError(c, twoFactorErrorStatus(err), err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// GET /api/v1/admin/security/two-factor
+func (ctrl *TwoFactorController) GetPolicy(c *gin.Context) {
+	policy, err := ctrl.twoFactorService.GetPolicy()
+	if err != nil {
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"required_roles": policy.TwoFactorRequiredRoles})
+}
+
+// PUT /api/v1/admin/security/two-factor
+func (ctrl *TwoFactorController) UpdatePolicy(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+	var input services.TwoFactorPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	policy, err := ctrl.twoFactorService.UpdatePolicy(adminID.(primitive.ObjectID), input)
+	if err != nil {
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	pkg.SendResponse(c, http.StatusOK, gin.H{"required_roles": policy.TwoFactorRequiredRoles})
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrTwoFactorLocked):
+		return http.StatusTooManyRequests
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorNotStarted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/internal/xapi"
+	"gamified-edu-backend/pkg"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatementsUpload caps the body of a statements request
+const maxStatementsUpload = 10 << 20
+
+// XAPIController is the built-in LRS. It speaks the xAPI statements
+// resource, so its responses are plain xAPI documents rather than the API's
+// usual envelope.
+type XAPIController struct {
+	xapiService services.XAPIService
+}
+
+func NewXAPIController(service services.XAPIService) *XAPIController {
+	return &XAPIController{xapiService: service}
+}
+
+// GET /api/v1/xapi/about
+func (ctrl *XAPIController) About(c *gin.Context) {
+	c.Header(xapi.VersionHeader, xapi.Version)
+	c.JSON(http.StatusOK, gin.H{"version": []string{xapi.Version}})
+}
+
+// POST /api/v1/xapi/statements stores a statement or a list of statements
+// and answers with their IDs
+func (ctrl *XAPIController) PostStatements(c *gin.Context) {
+	body, ok := statementsBody(c)
+	if !ok {
+		return
+	}
+	ids, err := ctrl.xapiService.StoreStatements(c.Request.Context(), body)
+	if err != nil {
+		sendXAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ids)
+}
+
+// PUT /api/v1/xapi/statements?statementId=... stores a statement under the given ID
+func (ctrl *XAPIController) PutStatement(c *gin.Context) {
+	statementID := c.Query("statementId")
+	if statementID == "" {
+		pkg.SendError(c, http.StatusBadRequest, "statementId is required")
+		return
+	}
+	body, ok := statementsBody(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.xapiService.PutStatement(c.Request.Context(), statementID, body); err != nil {
+		sendXAPIError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /api/v1/xapi/statements returns the statement named by statementId or
+// voidedStatementId, or else the statements matching the query parameters,
+// newest first. A "more" link continues the listing.
+func (ctrl *XAPIController) GetStatements(c *gin.Context) {
+	if !xapiVersionSent(c) {
+		return
+	}
+	statementID, voidedID := c.Query("statementId"), c.Query("voidedStatementId")
+	if statementID != "" || voidedID != "" {
+		if statementID != "" && voidedID != "" {
+			pkg.SendError(c, http.StatusBadRequest, "Use either statementId or voidedStatementId")
+			return
+		}
+		statement, err := ctrl.xapiService.GetStatement(c.Request.Context(), statementID+voidedID, voidedID != "")
+		if err != nil {
+			sendXAPIError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, statement)
+		return
+	}
+
+	input := services.StatementQueryInput{
+		Agent:        c.Query("agent"),
+		Verb:         c.Query("verb"),
+		Activity:     c.Query("activity"),
+		Registration: c.Query("registration"),
+		Since:        c.Query("since"),
+		Until:        c.Query("until"),
+		Limit:        c.Query("limit"),
+		Ascending:    c.Query("ascending"),
+		Cursor:       c.Query("cursor"),
+	}
+	response, err := ctrl.xapiService.QueryStatements(c.Request.Context(), input)
+	if err != nil {
+		sendXAPIError(c, err)
+		return
+	}
+	more := ""
+	if response.NextCursor != "" {
+		query := c.Request.URL.Query()
+		query.Set("cursor", response.NextCursor)
+		more = c.Request.URL.Path + "?" + query.Encode()
+	}
+	c.JSON(http.StatusOK, gin.H{"statements": response.Statements, "more": more})
+}
+
+// xapiVersionSent checks the version header every xAPI request carries and
+// answers with the LRS's version
+func xapiVersionSent(c *gin.Context) bool {
+	c.Header(xapi.VersionHeader, xapi.Version)
+	if !strings.HasPrefix(c.GetHeader(xapi.VersionHeader), "1.0") {
+		pkg.SendError(c, http.StatusBadRequest, "The "+xapi.VersionHeader+" header must name version 1.0.x")
+		return false
+	}
+	return true
+}
+
+func statementsBody(c *gin.Context) ([]byte, bool) {
+	if !xapiVersionSent(c) {
+		return nil, false
+	}
+	if !strings.HasPrefix(c.ContentType(), "application/json") {
+		pkg.SendError(c, http.StatusBadRequest, "Statements must be sent as application/json")
+		return nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxStatementsUpload))
+	if err != nil {
+		pkg.SendError(c, http.StatusRequestEntityTooLarge, "Statements are too large")
+		return nil, false
+	}
+	return body, true
+}
+
+func sendXAPIError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, xapi.ErrInvalidStatement), errors.Is(err, services.ErrInvalidStatementQuery),
+		errors.Is(err, services.ErrStatementIDMismatch), errors.Is(err, services.ErrSingleStatementExpected):
+		pkg.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrStatementNotFound):
+		pkg.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrStatementConflict):
+		pkg.SendError(c, http.StatusConflict, err.Error())
+	default:
+		pkg.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,140 @@
+// Package coursepack reads and writes course bundles: a directory or zip file
+// with a coursepack.yaml manifest that lists course and quiz manifests in YAML
+// or JSON, plus the media files components refer to. Bundles move courses
+// between environments; everything in them is keyed by slug, so importing the
+// same bundle twice changes nothing.
+//
+// A minimal bundle looks like this:
+//
+//	coursepack.yaml
+//	courses/intro-to-ai.yaml
+//	quizzes/ai-basics.yaml
+//	media/intro-to-ai/welcome.mp4
+//
+// with a manifest of
+//
+//	format_version: 1
+//	courses: [courses/intro-to-ai.yaml]
+//	quizzes: [quizzes/ai-basics.yaml]
+package coursepack
+
+import (
+	"errors"
+	"time"
+)
+
+// FormatVersion is the bundle format this package reads and writes. It is
+// raised when a change would make older readers misread a bundle.
+const FormatVersion = 1
+
+// ManifestNames are the names the bundle manifest is looked up by, in order.
+var ManifestNames = []string{"coursepack.yaml", "coursepack.yml", "coursepack.json"}
+
+// ErrInvalidBundle is matched by every *ValidationError.
+var ErrInvalidBundle = errors.New("invalid course bundle")
+
+// Manifest is the coursepack.yaml at the root of a bundle. Paths are relative
+// to the bundle root and use forward slashes.
+type Manifest struct {
+	FormatVersion int      `yaml:"format_version" json:"format_version"`
+	Name          string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Courses       []string `yaml:"courses,omitempty" json:"courses,omitempty"`
+	Quizzes       []string `yaml:"quizzes,omitempty" json:"quizzes,omitempty"`
+}
+
+// Bundle is a decoded bundle.
+type Bundle struct {
+	Name    string
+	Courses []Course
+	Quizzes []Quiz
+	// Files holds the media files that components refer to, by bundle path
+	Files map[string][]byte
+}
+
+// Course is a course manifest. Prerequisites are course slugs, which may be
+// courses of the same bundle or courses the importing organization already has.
+type Course struct {
+	Slug               string   `yaml:"slug" json:"slug"`
+	Title              string   `yaml:"title" json:"title"`
+	Description        string   `yaml:"description,omitempty" json:"description,omitempty"`
+	SequentialChapters bool     `yaml:"sequential_chapters,omitempty" json:"sequential_chapters,omitempty"`
+	ProgressWeighting  string   `yaml:"progress_weighting,omitempty" json:"progress_weighting,omitempty"`
+	Prerequisites      []string `yaml:"prerequisites,omitempty" json:"prerequisites,omitempty"`
+	Categories         []string `yaml:"categories,omitempty" json:"categories,omitempty"`
+	Tags               []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Difficulty         string   `yaml:"difficulty,omitempty" json:"difficulty,omitempty"`
+	// Language is a BCP 47 language tag such as en or pt-BR
+	Language string `yaml:"language,omitempty" json:"language,omitempty"`
+	// The thumbnail is an image URL, or the bundle path of an image in ThumbnailFile
+	Thumbnail     string `yaml:"thumbnail,omitempty" json:"thumbnail,omitempty"`
+	ThumbnailFile string `yaml:"thumbnail_file,omitempty" json:"thumbnail_file,omitempty"`
+	// Authors are the emails of instructors of the importing organization;
+	// without them a course keeps its authors
+	Authors []string `yaml:"authors,omitempty" json:"authors,omitempty"`
+	Modules []Module `yaml:"modules" json:"modules"`
+
+	// HideUnreleasedChapters leaves chapters out until they are released, instead of showing them locked
+	HideUnreleasedChapters bool `yaml:"hide_unreleased_chapters,omitempty" json:"hide_unreleased_chapters,omitempty"`
+
+	// path is the manifest the course was read from, for problem reports
+	path string
+}
+
+// Module slugs are unique within their course and match the module on import.
+type Module struct {
+	Slug     string    `yaml:"slug" json:"slug"`
+	Title    string    `yaml:"title" json:"title"`
+	Chapters []Chapter `yaml:"chapters" json:"chapters"`
+}
+
+// Chapter slugs are unique within their course and match the chapter on
+// import, so learners keep their progress when a chapter moves.
+type Chapter struct {
+	Slug         string      `yaml:"slug" json:"slug"`
+	Title        string      `yaml:"title" json:"title"`
+	DurationMins int         `yaml:"duration_mins,omitempty" json:"duration_mins,omitempty"`
+	Release      *Release    `yaml:"release,omitempty" json:"release,omitempty"`
+	Components   []Component `yaml:"components" json:"components"`
+}
+
+// Release schedules a chapter: rule date releases it at At, rules enrollment
+// and cohort OffsetDays after the learner enrolled or their cohort started.
+type Release struct {
+	Rule       string     `yaml:"rule" json:"rule"`
+	At         *time.Time `yaml:"at,omitempty" json:"at,omitempty"`
+	OffsetDays int        `yaml:"offset_days,omitempty" json:"offset_days,omitempty"`
+}
+
+// Component links to its material either by URL or by File, the bundle path
+// of a media file that is stored on import. Quiz is the slug of a quiz.
+type Component struct {
+	Key             string `yaml:"key" json:"key"`
+	Type            string `yaml:"type" json:"type"`
+	Title           string `yaml:"title,omitempty" json:"title,omitempty"`
+	URL             string `yaml:"url,omitempty" json:"url,omitempty"`
+	File            string `yaml:"file,omitempty" json:"file,omitempty"`
+	Quiz            string `yaml:"quiz,omitempty" json:"quiz,omitempty"`
+	XP              int    `yaml:"xp,omitempty" json:"xp,omitempty"`
+	Optional        bool   `yaml:"optional,omitempty" json:"optional,omitempty"`
+	PassingScore    int    `yaml:"passing_score,omitempty" json:"passing_score,omitempty"`
+	MinWatchPercent int    `yaml:"min_watch_percent,omitempty" json:"min_watch_percent,omitempty"`
+	MinReadSeconds  int    `yaml:"min_read_seconds,omitempty" json:"min_read_seconds,omitempty"`
+}
+
+// Quiz is a quiz manifest.
+type Quiz struct {
+	Slug         string     `yaml:"slug" json:"slug"`
+	Title        string     `yaml:"title" json:"title"`
+	PassingScore int        `yaml:"passing_score,omitempty" json:"passing_score,omitempty"`
+	Questions    []Question `yaml:"questions" json:"questions"`
+
+	path string
+}
+
+// Question is a multiple choice question; Answer is the index of the right choice.
+type Question struct {
+	Prompt      string   `yaml:"prompt" json:"prompt"`
+	Choices     []string `yaml:"choices" json:"choices"`
+	Answer      int      `yaml:"answer" json:"answer"`
+	Explanation string   `yaml:"explanation,omitempty" json:"explanation,omitempty"`
+}
@@ -0,0 +1,214 @@
+package coursepack
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxMediaSize caps the media files of a bundle together, so a small zip
+// cannot expand into more than the server can hold.
+const MaxMediaSize = 1 << 30
+
+// Open reads the bundle at filePath, a directory or a zip file.
+func Open(filePath string) (*Bundle, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return Read(os.DirFS(filePath))
+	}
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	return Read(archive)
+}
+
+// ReadZip reads a bundle from a zip file of the given size.
+func ReadZip(r io.ReaderAt, size int64) (*Bundle, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, &ValidationError{Problems: []string{"not a zip file: " + err.Error()}}
+	}
+	return Read(archive)
+}
+
+// Read decodes and validates the bundle in fsys. The manifest is expected at
+// the root, or in the only directory at the root, as zip tools tend to add one.
+func Read(fsys fs.FS) (*Bundle, error) {
+	root, manifestPath, err := findManifest(fsys)
+	if err != nil {
+		return nil, err
+	}
+	problems := &ValidationError{}
+
+	var manifest Manifest
+	if err := decodeFile(root, manifestPath, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, &ValidationError{Problems: []string{fmt.Sprintf("%s: format_version %d is not supported, expected %d", manifestPath, manifest.FormatVersion, FormatVersion)}}
+	}
+
+	bundle := &Bundle{Name: manifest.Name, Files: map[string][]byte{}}
+	for _, coursePath := range manifest.Courses {
+		var course Course
+		if err := decodeFile(root, coursePath, &course); err != nil {
+			if !problems.merge(err) {
+				return nil, err
+			}
+			continue
+		}
+		course.path = coursePath
+		bundle.Courses = append(bundle.Courses, course)
+	}
+	for _, quizPath := range manifest.Quizzes {
+		var quiz Quiz
+		if err := decodeFile(root, quizPath, &quiz); err != nil {
+			if !problems.merge(err) {
+				return nil, err
+			}
+			continue
+		}
+		quiz.path = quizPath
+		bundle.Quizzes = append(bundle.Quizzes, quiz)
+	}
+
+	// Load the media files the courses and their components refer to
+	var files []string
+	for _, course := range bundle.Courses {
+		if course.ThumbnailFile != "" {
+			files = append(files, course.ThumbnailFile)
+		}
+		for _, module := range course.Modules {
+			for _, chapter := range module.Chapters {
+				for _, component := range chapter.Components {
+					if component.File != "" {
+						files = append(files, component.File)
+					}
+				}
+			}
+		}
+	}
+	var total int64
+	for _, file := range files {
+		filePath, ok := cleanPath(file)
+		if !ok {
+			continue // reported by Validate
+		}
+		if _, loaded := bundle.Files[filePath]; loaded {
+			continue
+		}
+		info, err := fs.Stat(root, filePath)
+		if err != nil || info.IsDir() {
+			continue // reported by Validate
+		}
+		total += info.Size()
+		if total > MaxMediaSize {
+			return nil, &ValidationError{Problems: []string{fmt.Sprintf("media files are larger than %d MiB together", MaxMediaSize>>20)}}
+		}
+		data, err := fs.ReadFile(root, filePath)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Files[filePath] = data
+	}
+
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func findManifest(fsys fs.FS) (fs.FS, string, error) {
+	for _, name := range ManifestNames {
+		if _, err := fs.Stat(fsys, name); err == nil {
+			return fsys, name, nil
+		}
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, "", err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || entry.Name() == "__MACOSX" {
+			continue
+		}
+		if !entry.IsDir() {
+			dirs = nil
+			break
+		}
+		dirs = append(dirs, entry.Name())
+	}
+	if len(dirs) == 1 {
+		sub, err := fs.Sub(fsys, dirs[0])
+		if err != nil {
+			return nil, "", err
+		}
+		for _, name := range ManifestNames {
+			if _, err := fs.Stat(sub, name); err == nil {
+				return sub, name, nil
+			}
+		}
+	}
+	return nil, "", &ValidationError{Problems: []string{"no " + ManifestNames[0] + " found"}}
+}
+
+// decodeFile decodes a YAML or JSON file, going by its extension. Unknown
+// fields are rejected, so a misspelt field does not silently go missing.
+func decodeFile(fsys fs.FS, filePath string, target interface{}) error {
+	cleaned, ok := cleanPath(filePath)
+	if !ok {
+		return &ValidationError{Problems: []string{fmt.Sprintf("%q is not a path inside the bundle", filePath)}}
+	}
+	data, err := fs.ReadFile(fsys, cleaned)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &ValidationError{Problems: []string{fmt.Sprintf("%s: file not found", filePath)}}
+		}
+		return err
+	}
+
+	switch path.Ext(cleaned) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(target)
+		if errors.Is(err, io.EOF) {
+			err = errors.New("file is empty")
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(target)
+	default:
+		err = errors.New("manifests must be .yaml, .yml or .json files")
+	}
+	if err != nil {
+		return &ValidationError{Problems: []string{fmt.Sprintf("%s: %v", filePath, err)}}
+	}
+	return nil
+}
+
+// cleanPath turns a bundle path into an fs.FS path, rejecting paths that
+// would leave the bundle.
+func cleanPath(filePath string) (string, bool) {
+	cleaned := path.Clean(strings.TrimPrefix(filePath, "./"))
+	return cleaned, fs.ValidPath(cleaned) && cleaned != "."
+}
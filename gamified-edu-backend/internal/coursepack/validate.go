@@ -0,0 +1,254 @@
+package coursepack
+
+import (
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"regexp"
+	"strings"
+)
+
+var (
+	// slugPattern is the slug format of courses, modules and chapters
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// quizSlugPattern also allows the underscores of quiz IDs like quiz_1
+	quizSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// ValidationError lists everything wrong with a bundle, each problem
+// prefixed with the file and field it was found in.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return ErrInvalidBundle.Error() + ": " + strings.Join(e.Problems, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidBundle
+}
+
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// merge adds the problems of err if it is a *ValidationError
+func (e *ValidationError) merge(err error) bool {
+	other, ok := err.(*ValidationError)
+	if ok {
+		e.Problems = append(e.Problems, other.Problems...)
+	}
+	return ok
+}
+
+// File returns the media file at a component's File path.
+func (b *Bundle) File(filePath string) ([]byte, bool) {
+	cleaned, ok := cleanPath(filePath)
+	if !ok {
+		return nil, false
+	}
+	data, ok := b.Files[cleaned]
+	return data, ok
+}
+
+// Validate checks the bundle against the format. Whether component types and
+// their settings make sense is up to the importer, as with any course edit.
+func (b *Bundle) Validate() error {
+	problems := &ValidationError{}
+	if len(b.Courses) == 0 && len(b.Quizzes) == 0 {
+		problems.add("the bundle has no courses or quizzes")
+	}
+
+	courses := map[string]bool{}
+	for i := range b.Courses {
+		course := &b.Courses[i]
+		at := course.path
+		if at == "" {
+			at = fmt.Sprintf("courses[%d]", i)
+		}
+		if !slugPattern.MatchString(course.Slug) {
+			problems.add("%s: slug %q may only contain lowercase letters, digits and single dashes", at, course.Slug)
+		} else if courses[course.Slug] {
+			problems.add("%s: course slug %q is used twice", at, course.Slug)
+		}
+		courses[course.Slug] = true
+		b.validateCourse(course, at, problems)
+	}
+
+	quizzes := map[string]bool{}
+	for i := range b.Quizzes {
+		quiz := &b.Quizzes[i]
+		at := quiz.path
+		if at == "" {
+			at = fmt.Sprintf("quizzes[%d]", i)
+		}
+		if !quizSlugPattern.MatchString(quiz.Slug) {
+			problems.add("%s: slug %q may only contain lowercase letters, digits, - and _", at, quiz.Slug)
+		} else if quizzes[quiz.Slug] {
+			problems.add("%s: quiz slug %q is used twice", at, quiz.Slug)
+		}
+		quizzes[quiz.Slug] = true
+		validateQuiz(quiz, at, problems)
+	}
+
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (b *Bundle) validateCourse(course *Course, at string, problems *ValidationError) {
+	if strings.TrimSpace(course.Title) == "" {
+		problems.add("%s: title is required", at)
+	}
+	switch course.ProgressWeighting {
+	case "", models.ProgressWeightingChapters, models.ProgressWeightingDuration:
+	default:
+		problems.add("%s: progress_weighting must be %q or %q", at, models.ProgressWeightingChapters, models.ProgressWeightingDuration)
+	}
+	switch course.Difficulty {
+	case "", models.DifficultyBeginner, models.DifficultyIntermediate, models.DifficultyAdvanced:
+	default:
+		problems.add("%s: difficulty must be %q, %q or %q", at, models.DifficultyBeginner, models.DifficultyIntermediate, models.DifficultyAdvanced)
+	}
+	if course.ThumbnailFile != "" {
+		if course.Thumbnail != "" {
+			problems.add("%s: give either thumbnail or thumbnail_file, not both", at)
+		}
+		if _, ok := cleanPath(course.ThumbnailFile); !ok {
+			problems.add("%s: thumbnail_file %q is not a path inside the bundle", at, course.ThumbnailFile)
+		} else if _, ok := b.File(course.ThumbnailFile); !ok {
+			problems.add("%s: thumbnail_file %q is not in the bundle", at, course.ThumbnailFile)
+		}
+	}
+	for _, email := range course.Authors {
+		if !strings.Contains(email, "@") {
+			problems.add("%s: author %q is not an email address", at, email)
+		}
+	}
+	prerequisites := map[string]bool{}
+	for _, slug := range course.Prerequisites {
+		switch {
+		case !slugPattern.MatchString(slug):
+			problems.add("%s: prerequisite %q is not a course slug", at, slug)
+		case slug == course.Slug:
+			problems.add("%s: a course cannot be its own prerequisite", at)
+		case prerequisites[slug]:
+			problems.add("%s: prerequisite %q is listed twice", at, slug)
+		}
+		prerequisites[slug] = true
+	}
+
+	modules, chapters := map[string]bool{}, map[string]bool{}
+	for i, module := range course.Modules {
+		moduleAt := fmt.Sprintf("%s: modules[%d]", at, i)
+		checkSlug(module.Slug, moduleAt, "module", modules, problems)
+		if strings.TrimSpace(module.Title) == "" {
+			problems.add("%s: title is required", moduleAt)
+		}
+		for j, chapter := range module.Chapters {
+			chapterAt := fmt.Sprintf("%s.chapters[%d]", moduleAt, j)
+			checkSlug(chapter.Slug, chapterAt, "chapter", chapters, problems)
+			if strings.TrimSpace(chapter.Title) == "" {
+				problems.add("%s: title is required", chapterAt)
+			}
+			if chapter.DurationMins < 0 {
+				problems.add("%s: duration_mins cannot be negative", chapterAt)
+			}
+			if chapter.Release != nil {
+				validateRelease(*chapter.Release, chapterAt+".release", problems)
+			}
+			for k, component := range chapter.Components {
+				b.validateComponent(component, fmt.Sprintf("%s.components[%d]", chapterAt, k), problems)
+			}
+		}
+	}
+}
+
+func validateRelease(release Release, at string, problems *ValidationError) {
+	switch release.Rule {
+	case models.ReleaseOnDate:
+		if release.At == nil {
+			problems.add("%s: at is required for rule %q", at, release.Rule)
+		}
+		if release.OffsetDays != 0 {
+			problems.add("%s: offset_days does not apply to rule %q", at, release.Rule)
+		}
+	case models.ReleaseAfterEnrollment, models.ReleaseAfterCohortStart:
+		if release.At != nil {
+			problems.add("%s: at does not apply to rule %q", at, release.Rule)
+		}
+		if release.OffsetDays < 0 {
+			problems.add("%s: offset_days cannot be negative", at)
+		}
+	default:
+		problems.add("%s: rule must be %q, %q or %q", at, models.ReleaseOnDate, models.ReleaseAfterEnrollment, models.ReleaseAfterCohortStart)
+	}
+}
+
+func (b *Bundle) validateComponent(component Component, at string, problems *ValidationError) {
+	if component.Key == "" {
+		problems.add("%s: key is required", at)
+	}
+	if component.Type == "" {
+		problems.add("%s: type is required", at)
+	}
+	if component.XP < 0 {
+		problems.add("%s: xp cannot be negative", at)
+	}
+	if component.Quiz != "" && !quizSlugPattern.MatchString(component.Quiz) {
+		problems.add("%s: quiz %q is not a quiz slug", at, component.Quiz)
+	}
+	if component.File == "" {
+		return
+	}
+	if component.URL != "" {
+		problems.add("%s: give either url or file, not both", at)
+	}
+	if _, ok := cleanPath(component.File); !ok {
+		problems.add("%s: file %q is not a path inside the bundle", at, component.File)
+	} else if _, ok := b.File(component.File); !ok {
+		problems.add("%s: file %q is not in the bundle", at, component.File)
+	}
+}
+
+func validateQuiz(quiz *Quiz, at string, problems *ValidationError) {
+	if strings.TrimSpace(quiz.Title) == "" {
+		problems.add("%s: title is required", at)
+	}
+	if quiz.PassingScore < 0 || quiz.PassingScore > 100 {
+		problems.add("%s: passing_score must be between 0 and 100", at)
+	}
+	if len(quiz.Questions) == 0 {
+		problems.add("%s: a quiz needs at least one question", at)
+	}
+	for i, question := range quiz.Questions {
+		questionAt := fmt.Sprintf("%s: questions[%d]", at, i)
+		if strings.TrimSpace(question.Prompt) == "" {
+			problems.add("%s: prompt is required", questionAt)
+		}
+		if len(question.Choices) < 2 {
+			problems.add("%s: a question needs at least two choices", questionAt)
+		}
+		for j, choice := range question.Choices {
+			if strings.TrimSpace(choice) == "" {
+				problems.add("%s: choices[%d] is empty", questionAt, j)
+			}
+		}
+		if question.Answer < 0 || question.Answer >= len(question.Choices) {
+			problems.add("%s: answer %d is not the index of a choice", questionAt, question.Answer)
+		}
+	}
+}
+
+func checkSlug(slug, at, kind string, seen map[string]bool, problems *ValidationError) {
+	switch {
+	case slug == "":
+		problems.add("%s: slug is required", at)
+	case !slugPattern.MatchString(slug):
+		problems.add("%s: slug %q may only contain lowercase letters, digits and single dashes", at, slug)
+	case seen[slug]:
+		problems.add("%s: %s slug %q is used twice in the course", at, kind, slug)
+	}
+	seen[slug] = true
+}
@@ -0,0 +1,96 @@
+package coursepack
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CoursePath and QuizPath are where Write puts the manifests of a bundle.
+func CoursePath(slug string) string { return "courses/" + slug + ".yaml" }
+func QuizPath(slug string) string   { return "quizzes/" + slug + ".yaml" }
+
+// WriteDir writes the bundle into dir, creating it if needed.
+func WriteDir(dir string, bundle *Bundle) error {
+	return bundle.write(func(name string, data []byte) error {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o644)
+	})
+}
+
+// WriteZip writes the bundle as a zip file to w.
+func WriteZip(w io.Writer, bundle *Bundle) error {
+	archive := zip.NewWriter(w)
+	err := bundle.write(func(name string, data []byte) error {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// write emits the manifest, the course and quiz manifests and the media
+// files, always in the same order so exports of the same content are identical.
+func (b *Bundle) write(emit func(name string, data []byte) error) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	manifest := Manifest{FormatVersion: FormatVersion, Name: b.Name}
+	files := map[string]interface{}{}
+	for _, course := range b.Courses {
+		manifest.Courses = append(manifest.Courses, CoursePath(course.Slug))
+		files[CoursePath(course.Slug)] = course
+	}
+	for _, quiz := range b.Quizzes {
+		manifest.Quizzes = append(manifest.Quizzes, QuizPath(quiz.Slug))
+		files[QuizPath(quiz.Slug)] = quiz
+	}
+	files[ManifestNames[0]] = manifest
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var data bytes.Buffer
+		encoder := yaml.NewEncoder(&data)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(files[name]); err != nil {
+			return err
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+		if err := emit(name, data.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	media := make([]string, 0, len(b.Files))
+	for name := range b.Files {
+		media = append(media, name)
+	}
+	sort.Strings(media)
+	for _, name := range media {
+		if err := emit(path.Clean(name), b.Files[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
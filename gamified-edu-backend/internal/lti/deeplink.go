@@ -0,0 +1,51 @@
+package lti
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ContentItemLink is the content item type of a link launching the tool.
+const ContentItemLink = "ltiResourceLink"
+
+// ContentItem is a resource the user picked during deep linking.
+type ContentItem struct {
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text,omitempty"`
+	// URL is launched for the item; empty means the tool's launch URL
+	URL    string            `json:"url,omitempty"`
+	Custom map[string]string `json:"custom,omitempty"`
+	// LineItem asks the platform to create a gradebook column for the link
+	LineItem *ContentLineItem `json:"lineItem,omitempty"`
+}
+
+type ContentLineItem struct {
+	Label        string  `json:"label,omitempty"`
+	ScoreMaximum float64 `json:"scoreMaximum"`
+	ResourceID   string  `json:"resourceId,omitempty"`
+	Tag          string  `json:"tag,omitempty"`
+}
+
+// DeepLinkingResponse is the signed message returning the picked items to
+// the platform. The browser posts it to the deep linking return URL as the
+// JWT form field.
+func (k *ToolKey) DeepLinkingResponse(platform Platform, deploymentID string, settings DeepLinkingSettings, items []ContentItem) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":             platform.ClientID,
+		"aud":             platform.Issuer,
+		"iat":             now.Unix(),
+		"exp":             now.Add(5 * time.Minute).Unix(),
+		"nonce":           RandomString(),
+		claimMessageType:  MessageDeepLinkingResponse,
+		claimVersion:      Version,
+		claimDeploymentID: deploymentID,
+		claimContentItems: items,
+	}
+	if settings.Data != "" {
+		claims[claimDeepLinkData] = settings.Data
+	}
+	return k.Sign(claims)
+}
@@ -0,0 +1,129 @@
+// Package lti implements the tool side of LTI 1.3: OIDC login initiation,
+// validating launches against the platform's key set, deep linking
+// responses and the Assignment and Grade Services that push scores into the
+// platform's gradebook.
+package lti
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ToolKey is the key pair the tool signs its messages to platforms with.
+// Platforms fetch the public half from the tool's key set URL.
+type ToolKey struct {
+	private *rsa.PrivateKey
+	// ID names the key in signed messages and in the key set
+	ID string
+}
+
+// JWK is a public RSA key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// KeySet is a JSON Web Key Set.
+type KeySet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewToolKey wraps an RSA private key.
+func NewToolKey(private *rsa.PrivateKey) *ToolKey {
+	der, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	sum := sha256.Sum256(der)
+	return &ToolKey{private: private, ID: base64.RawURLEncoding.EncodeToString(sum[:16])}
+}
+
+// LoadOrCreateKey reads the PEM encoded RSA key at path, creating one there
+// if the file does not exist yet.
+func LoadOrCreateKey(path string) (*ToolKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			return nil, err
+		}
+		return NewToolKey(private), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewToolKey(private), nil
+}
+
+// KeyFromEnv loads the tool key from LTI_KEY_FILE (default
+// data/lti/tool-key.pem), creating it on first use.
+func KeyFromEnv() (*ToolKey, error) {
+	path := os.Getenv("LTI_KEY_FILE")
+	if path == "" {
+		path = filepath.Join("data", "lti", "tool-key.pem")
+	}
+	return LoadOrCreateKey(path)
+}
+
+// KeySet publishes the public key.
+func (k *ToolKey) KeySet() KeySet {
+	public := k.private.PublicKey
+	return KeySet{Keys: []JWK{{
+		Kty: "RSA",
+		Kid: k.ID,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}}
+}
+
+// Sign returns claims as a JWT signed with the key.
+func (k *ToolKey) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.private)
+}
+
+// publicKey decodes an RSA key of a key set.
+func (j JWK) publicKey() (*rsa.PublicKey, error) {
+	if j.Kty != "RSA" {
+		return nil, fmt.Errorf("key %s is not an RSA key", j.Kid)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", j.Kid, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", j.Kid, err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31 {
+		return nil, fmt.Errorf("key %s has an invalid exponent", j.Kid)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
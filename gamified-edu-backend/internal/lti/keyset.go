@@ -0,0 +1,110 @@
+package lti
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// keySetTTL is how long a platform's key set is used before it is fetched again
+	keySetTTL = time.Hour
+	// keySetRefetchInterval limits refetches for keys a cached set does not have
+	keySetRefetchInterval = time.Minute
+)
+
+// KeySets fetches and caches the key sets platforms sign launches with.
+type KeySets struct {
+	http *http.Client
+	// mu guards cache; each set has its own lock for fetching it, so a slow
+	// platform only holds up launches from that platform
+	mu    sync.Mutex
+	cache map[string]*cachedKeySet
+}
+
+type cachedKeySet struct {
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewKeySets(client *http.Client) *KeySets {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &KeySets{http: client, cache: map[string]*cachedKeySet{}}
+}
+
+// Key returns the key called kid from the key set at url. Platforms rotate
+// keys, so an unknown kid makes it fetch the set again.
+func (k *KeySets) Key(ctx context.Context, url, kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	cached, ok := k.cache[url]
+	if !ok {
+		cached = &cachedKeySet{}
+		k.cache[url] = cached
+	}
+	k.mu.Unlock()
+
+	// Launches waiting on the same set use the keys of one fetch
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if cached.keys != nil {
+		age := time.Since(cached.fetchedAt)
+		if key, ok := cached.keys[kid]; ok && age < keySetTTL {
+			return key, nil
+		}
+		if age < keySetRefetchInterval {
+			return nil, fmt.Errorf("the platform's key set has no key %q", kid)
+		}
+	}
+
+	keys, err := k.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	cached.keys, cached.fetchedAt = keys, time.Now()
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("the platform's key set has no key %q", kid)
+	}
+	return key, nil
+}
+
+func (k *KeySets) fetch(ctx context.Context, url string) (map[string]*rsa.PublicKey, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := k.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetching the platform's key set: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the platform's key set: %s", response.Status)
+	}
+	var set KeySet
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("reading the platform's key set: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || jwk.Use == "enc" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
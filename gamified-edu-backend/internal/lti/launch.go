@@ -0,0 +1,284 @@
+package lti
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidLaunch is returned for launches that fail validation.
+var ErrInvalidLaunch = errors.New("invalid LTI launch")
+
+// Message types
+const (
+	MessageResourceLink        = "LtiResourceLinkRequest"
+	MessageDeepLinkingRequest  = "LtiDeepLinkingRequest"
+	MessageDeepLinkingResponse = "LtiDeepLinkingResponse"
+)
+
+// Version is the LTI version launches must declare.
+const Version = "1.3.0"
+
+// Claims of the messages the tool sends
+const (
+	claimMessageType  = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	claimVersion      = "https://purl.imsglobal.org/spec/lti/claim/version"
+	claimDeploymentID = "https://purl.imsglobal.org/spec/lti/claim/deployment_id"
+	claimContentItems = "https://purl.imsglobal.org/spec/lti-dl/claim/content_items"
+	claimDeepLinkData = "https://purl.imsglobal.org/spec/lti-dl/claim/data"
+)
+
+// Platform is an LMS registered with the tool.
+type Platform struct {
+	Issuer   string
+	ClientID string
+	// AuthLoginURL is the platform's OIDC authorization endpoint
+	AuthLoginURL string
+	// AuthTokenURL is the platform's OAuth 2 token endpoint, for grade services
+	AuthTokenURL string
+	// KeySetURL is where the platform publishes the keys it signs launches with
+	KeySetURL     string
+	DeploymentIDs []string
+}
+
+// LoginRequest is the third-party login initiation a platform sends before a launch.
+type LoginRequest struct {
+	Issuer          string
+	LoginHint       string
+	TargetLinkURI   string
+	LTIMessageHint  string
+	ClientID        string
+	LTIDeploymentID string
+}
+
+// AuthenticationURL is where the tool sends the browser to complete a login
+// initiation: the platform's authorization endpoint, which answers by
+// posting the launch to redirectURI.
+func (p Platform) AuthenticationURL(login LoginRequest, redirectURI, state, nonce string) (string, error) {
+	target, err := url.Parse(p.AuthLoginURL)
+	if err != nil {
+		return "", err
+	}
+	query := target.Query()
+	query.Set("scope", "openid")
+	query.Set("response_type", "id_token")
+	query.Set("response_mode", "form_post")
+	query.Set("prompt", "none")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("login_hint", login.LoginHint)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	if login.LTIMessageHint != "" {
+		query.Set("lti_message_hint", login.LTIMessageHint)
+	}
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
+
+// Launch is a validated launch message.
+type Launch struct {
+	MessageType   string
+	DeploymentID  string
+	Nonce         string
+	TargetLinkURI string
+	// Subject identifies the user within the platform
+	Subject      string
+	Email        string
+	GivenName    string
+	FamilyName   string
+	Name         string
+	Roles        []string
+	ResourceLink *ResourceLink
+	Context      *LaunchContext
+	Custom       map[string]string
+	// Endpoint is set when the platform offers the Assignment and Grade Services
+	Endpoint    *GradeEndpoint
+	DeepLinking *DeepLinkingSettings
+}
+
+type ResourceLink struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type LaunchContext struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// GradeEndpoint is the Assignment and Grade Services claim of a launch.
+type GradeEndpoint struct {
+	Scope []string `json:"scope"`
+	// LineItems lists and creates the context's line items
+	LineItems string `json:"lineitems"`
+	// LineItem is the line item of the launched resource link, if it has one
+	LineItem string `json:"lineitem"`
+}
+
+// Allows reports whether the platform granted scope.
+func (e *GradeEndpoint) Allows(scope string) bool {
+	for _, granted := range e.Scope {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type DeepLinkingSettings struct {
+	ReturnURL      string   `json:"deep_link_return_url"`
+	AcceptTypes    []string `json:"accept_types"`
+	AcceptMultiple bool     `json:"accept_multiple"`
+	Data           string   `json:"data"`
+}
+
+// Accepts reports whether the platform takes content items of type.
+func (s *DeepLinkingSettings) Accepts(itemType string) bool {
+	for _, accepted := range s.AcceptTypes {
+		if accepted == itemType {
+			return true
+		}
+	}
+	return false
+}
+
+// Role vocabularies. Roles may also be sent without their vocabulary prefix.
+const (
+	RoleInstructor       = "http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"
+	RoleContentDeveloper = "http://purl.imsglobal.org/vocab/lis/v2/membership#ContentDeveloper"
+)
+
+// HasRole reports whether the user holds a context role, given as a full
+// URI. The short form "Instructor" matches as well.
+func (l *Launch) HasRole(role string) bool {
+	short := role[strings.LastIndex(role, "#")+1:]
+	for _, held := range l.Roles {
+		if held == role || held == short {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator validates launch messages.
+type Validator struct {
+	keys *KeySets
+}
+
+func NewValidator(keys *KeySets) *Validator {
+	return &Validator{keys: keys}
+}
+
+// launchClaims are the claims of a launch id_token
+type launchClaims struct {
+	jwt.RegisteredClaims
+	AuthorizedParty string               `json:"azp"`
+	Nonce           string               `json:"nonce"`
+	Email           string               `json:"email"`
+	GivenName       string               `json:"given_name"`
+	FamilyName      string               `json:"family_name"`
+	Name            string               `json:"name"`
+	MessageType     string               `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version         string               `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID    string               `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	TargetLinkURI   string               `json:"https://purl.imsglobal.org/spec/lti/claim/target_link_uri"`
+	ResourceLink    *ResourceLink        `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link"`
+	Context         *LaunchContext       `json:"https://purl.imsglobal.org/spec/lti/claim/context"`
+	Roles           []string             `json:"https://purl.imsglobal.org/spec/lti/claim/roles"`
+	Custom          map[string]string    `json:"https://purl.imsglobal.org/spec/lti/claim/custom"`
+	Endpoint        *GradeEndpoint       `json:"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"`
+	DeepLinking     *DeepLinkingSettings `json:"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"`
+}
+
+// Validate checks the id_token a platform posted to the launch URL: its
+// signature against the platform's key set, who issued it and for whom, its
+// age and the LTI claims each message type requires. The caller checks the
+// nonce against the one it sent with the login.
+func (v *Validator) Validate(ctx context.Context, idToken string, platform Platform) (*Launch, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidLaunch, fmt.Sprintf(format, args...))
+	}
+
+	var claims launchClaims
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(platform.Issuer),
+		jwt.WithAudience(platform.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	_, err := parser.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("the id_token names no key")
+		}
+		return v.keys.Key(ctx, platform.KeySetURL, kid)
+	})
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+
+	// With several audiences the token must say which party it is for
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != platform.ClientID {
+		return nil, invalid("azp must be the tool's client_id")
+	}
+	if claims.Nonce == "" {
+		return nil, invalid("nonce is required")
+	}
+	if claims.Version != Version {
+		return nil, invalid("LTI version must be %s", Version)
+	}
+	if !contains(platform.DeploymentIDs, claims.DeploymentID) {
+		return nil, invalid("deployment %q is not registered", claims.DeploymentID)
+	}
+	switch claims.MessageType {
+	case MessageResourceLink:
+		if claims.ResourceLink == nil || claims.ResourceLink.ID == "" {
+			return nil, invalid("resource link launches need a resource_link id")
+		}
+	case MessageDeepLinkingRequest:
+		if claims.DeepLinking == nil || claims.DeepLinking.ReturnURL == "" {
+			return nil, invalid("deep linking requests need deep_linking_settings")
+		}
+	default:
+		return nil, invalid("unsupported message type %q", claims.MessageType)
+	}
+	// Anonymous launches have no subject; the tool needs to know the user
+	if claims.Subject == "" {
+		return nil, invalid("sub is required")
+	}
+
+	return &Launch{
+		MessageType:   claims.MessageType,
+		DeploymentID:  claims.DeploymentID,
+		Nonce:         claims.Nonce,
+		TargetLinkURI: claims.TargetLinkURI,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		Name:          claims.Name,
+		Roles:         claims.Roles,
+		ResourceLink:  claims.ResourceLink,
+		Context:       claims.Context,
+		Custom:        claims.Custom,
+		Endpoint:      claims.Endpoint,
+		DeepLinking:   claims.DeepLinking,
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,493 @@
+package lti
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "tool-client"
+	testDeploymentID = "deployment-1"
+)
+
+// mockPlatform is an LMS: it publishes the key it signs launches with,
+// grants access tokens to the tool and keeps a gradebook.
+type mockPlatform struct {
+	t      *testing.T
+	server *httptest.Server
+	// key signs launches; toolKey is the tool's, registered with the platform
+	key     *ToolKey
+	toolKey *ToolKey
+
+	mu            sync.Mutex
+	keySetFetches int
+	tokenRequests int
+	lineItems     []LineItem
+	scores        map[string][]Score
+}
+
+func newTestKey(t *testing.T) *ToolKey {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewToolKey(private)
+}
+
+func newMockPlatform(t *testing.T, toolKey *ToolKey) *mockPlatform {
+	p := &mockPlatform{t: t, key: newTestKey(t), toolKey: toolKey, scores: map[string][]Score{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jwks", p.serveKeySet)
+	mux.HandleFunc("POST /token", p.serveToken)
+	mux.HandleFunc("GET /lineitems", p.listLineItems)
+	mux.HandleFunc("POST /lineitems", p.createLineItem)
+	mux.HandleFunc("POST /lineitems/{item}/scores", p.postScore)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockPlatform) platform() Platform {
+	return Platform{
+		Issuer:        p.server.URL,
+		ClientID:      testClientID,
+		AuthLoginURL:  p.server.URL + "/auth?tenant=1",
+		AuthTokenURL:  p.server.URL + "/token",
+		KeySetURL:     p.server.URL + "/jwks",
+		DeploymentIDs: []string{testDeploymentID},
+	}
+}
+
+// launch signs a resource link launch, with changes applied to its claims
+func (p *mockPlatform) launch(changes jwt.MapClaims) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":             p.server.URL,
+		"aud":             testClientID,
+		"sub":             "lms-user-1",
+		"iat":             now.Unix(),
+		"exp":             now.Add(5 * time.Minute).Unix(),
+		"nonce":           "nonce-1",
+		"email":           "ada@example.com",
+		"given_name":      "Ada",
+		"family_name":     "Lovelace",
+		claimMessageType:  MessageResourceLink,
+		claimVersion:      Version,
+		claimDeploymentID: testDeploymentID,
+		"https://purl.imsglobal.org/spec/lti/claim/resource_link": map[string]string{"id": "link-1"},
+		"https://purl.imsglobal.org/spec/lti/claim/roles":         []string{RoleInstructor},
+		"https://purl.imsglobal.org/spec/lti/claim/custom":        map[string]string{"course": "algebra"},
+		"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint": map[string]interface{}{
+			"scope":     []string{ScopeLineItem, ScopeScore},
+			"lineitems": p.server.URL + "/lineitems",
+		},
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	token, err := p.key.Sign(claims)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return token
+}
+
+func (p *mockPlatform) serveKeySet(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.keySetFetches++
+	key := p.key
+	p.mu.Unlock()
+	writeJSON(w, key.KeySet())
+}
+
+// serveToken grants a token for a client assertion signed by the tool key
+func (p *mockPlatform) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+		http.Error(w, "unsupported grant", http.StatusBadRequest)
+		return
+	}
+	_, err := jwt.Parse(r.PostFormValue("client_assertion"), func(token *jwt.Token) (interface{}, error) {
+		return &p.toolKey.private.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer(testClientID), jwt.WithSubject(testClientID), jwt.WithAudience(p.server.URL+"/token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	for _, scope := range []string{ScopeLineItem, ScopeScore} {
+		if !strings.Contains(r.PostFormValue("scope"), scope) {
+			http.Error(w, "missing scope "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+	p.mu.Lock()
+	p.tokenRequests++
+	p.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"access_token": "platform-token", "token_type": "bearer", "expires_in": 3600})
+}
+
+func (p *mockPlatform) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "Bearer platform-token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (p *mockPlatform) listLineItems(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	items := []LineItem{}
+	for _, item := range p.lineItems {
+		if resourceID := r.URL.Query().Get("resource_id"); resourceID == "" || item.ResourceID == resourceID {
+			items = append(items, item)
+		}
+	}
+	w.Header().Set("Content-Type", mediaLineItemContainer)
+	writeJSON(w, items)
+}
+
+func (p *mockPlatform) createLineItem(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != mediaLineItem {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+	var item LineItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	item.ID = fmt.Sprintf("%s/lineitems/%d", p.server.URL, len(p.lineItems)+1)
+	p.lineItems = append(p.lineItems, item)
+	p.mu.Unlock()
+	w.Header().Set("Content-Type", mediaLineItem)
+	writeJSON(w, item)
+}
+
+func (p *mockPlatform) postScore(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != mediaScore {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+	var score Score
+	if err := json.NewDecoder(r.Body).Decode(&score); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	p.scores[r.PathValue("item")] = append(p.scores[r.PathValue("item")], score)
+	p.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	json.NewEncoder(w).Encode(value)
+}
+
+func TestAuthenticationURL(t *testing.T) {
+	platform := newMockPlatform(t, newTestKey(t)).platform()
+	login := LoginRequest{Issuer: platform.Issuer, LoginHint: "hint-1", LTIMessageHint: "message-hint", ClientID: testClientID}
+
+	target, err := platform.AuthenticationURL(login, "https://tool.example.org/api/v1/lti/launch", "state-1", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != platform.Issuer+"/auth" {
+		t.Errorf("sent to %s, want the platform's authorization endpoint", got)
+	}
+	want := map[string]string{
+		"tenant":           "1",
+		"scope":            "openid",
+		"response_type":    "id_token",
+		"response_mode":    "form_post",
+		"prompt":           "none",
+		"client_id":        testClientID,
+		"redirect_uri":     "https://tool.example.org/api/v1/lti/launch",
+		"login_hint":       "hint-1",
+		"lti_message_hint": "message-hint",
+		"state":            "state-1",
+		"nonce":            "nonce-1",
+	}
+	query := parsed.Query()
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestValidateLaunch(t *testing.T) {
+	platform := newMockPlatform(t, newTestKey(t))
+
+	launch, err := NewValidator(NewKeySets(platform.server.Client())).Validate(context.Background(), platform.launch(nil), platform.platform())
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if launch.MessageType != MessageResourceLink || launch.Subject != "lms-user-1" || launch.Nonce != "nonce-1" || launch.Email != "ada@example.com" {
+		t.Errorf("got launch %+v", launch)
+	}
+	if launch.ResourceLink == nil || launch.ResourceLink.ID != "link-1" || launch.Custom["course"] != "algebra" {
+		t.Errorf("got resource link %+v and custom %v", launch.ResourceLink, launch.Custom)
+	}
+	if !launch.HasRole(RoleInstructor) {
+		t.Errorf("got roles %v, want instructor", launch.Roles)
+	}
+	if launch.Endpoint == nil || !launch.Endpoint.Allows(ScopeScore) || launch.Endpoint.LineItems != platform.server.URL+"/lineitems" {
+		t.Errorf("got grade endpoint %+v", launch.Endpoint)
+	}
+}
+
+func TestValidateRejectsInvalidLaunches(t *testing.T) {
+	platform := newMockPlatform(t, newTestKey(t))
+	forger := newTestKey(t)
+	forged := func() string {
+		// Signed with another key under the platform key's ID
+		forger.ID = platform.key.ID
+		token, err := forger.Sign(jwt.MapClaims{"iss": platform.server.URL, "aud": testClientID, "sub": "lms-user-1", "nonce": "n", "exp": time.Now().Add(time.Minute).Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"forged signature", forged()},
+		{"unknown key", func() string {
+			token, _ := newTestKey(t).Sign(jwt.MapClaims{"iss": platform.server.URL, "aud": testClientID})
+			return token
+		}()},
+		{"other issuer", platform.launch(jwt.MapClaims{"iss": "https://other.example.org"})},
+		{"other audience", platform.launch(jwt.MapClaims{"aud": "other-client"})},
+		{"other authorized party", platform.launch(jwt.MapClaims{"aud": []string{testClientID, "other-client"}, "azp": "other-client"})},
+		{"expired", platform.launch(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})},
+		{"no expiry", platform.launch(jwt.MapClaims{"exp": nil})},
+		{"no nonce", platform.launch(jwt.MapClaims{"nonce": nil})},
+		{"no subject", platform.launch(jwt.MapClaims{"sub": nil})},
+		{"old version", platform.launch(jwt.MapClaims{claimVersion: "1.1"})},
+		{"unregistered deployment", platform.launch(jwt.MapClaims{claimDeploymentID: "deployment-2"})},
+		{"unknown message type", platform.launch(jwt.MapClaims{claimMessageType: "LtiSubmissionReviewRequest"})},
+		{"no resource link", platform.launch(jwt.MapClaims{"https://purl.imsglobal.org/spec/lti/claim/resource_link": nil})},
+		{"deep linking without settings", platform.launch(jwt.MapClaims{claimMessageType: MessageDeepLinkingRequest})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewValidator(NewKeySets(platform.server.Client())).Validate(context.Background(), test.token, platform.platform())
+			if !errors.Is(err, ErrInvalidLaunch) {
+				t.Errorf("got %v, want ErrInvalidLaunch", err)
+			}
+		})
+	}
+}
+
+func TestKeySetsFetchRotatedKeys(t *testing.T) {
+	platform := newMockPlatform(t, newTestKey(t))
+	keySets := NewKeySets(platform.server.Client())
+	validator := NewValidator(keySets)
+	ctx := context.Background()
+
+	if _, err := validator.Validate(ctx, platform.launch(nil), platform.platform()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if _, err := validator.Validate(ctx, platform.launch(nil), platform.platform()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if platform.keySetFetches != 1 {
+		t.Errorf("fetched the key set %d times, want it cached after the first launch", platform.keySetFetches)
+	}
+
+	platform.mu.Lock()
+	platform.key = newTestKey(t)
+	platform.mu.Unlock()
+	// Right after a fetch, unknown keys do not make it fetch again
+	if _, err := validator.Validate(ctx, platform.launch(nil), platform.platform()); !errors.Is(err, ErrInvalidLaunch) {
+		t.Fatalf("got %v right after the rotation, want ErrInvalidLaunch", err)
+	}
+	keySets.cache[platform.platform().KeySetURL].fetchedAt = time.Now().Add(-2 * keySetRefetchInterval)
+	if _, err := validator.Validate(ctx, platform.launch(nil), platform.platform()); err != nil {
+		t.Fatalf("Validate with the rotated key: %v", err)
+	}
+	if platform.keySetFetches != 2 {
+		t.Errorf("fetched the key set %d times, want 2", platform.keySetFetches)
+	}
+}
+
+func TestKeySetsFetchEachPlatformOnItsOwn(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		writeJSON(w, KeySet{})
+	}))
+	defer slow.Close()
+	defer close(release)
+	platform := newMockPlatform(t, newTestKey(t))
+	keySets := NewKeySets(nil)
+
+	go keySets.Key(context.Background(), slow.URL, "any")
+	// Wait until the slow fetch is under way
+	for {
+		keySets.mu.Lock()
+		_, started := keySets.cache[slow.URL]
+		keySets.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := keySets.Key(context.Background(), platform.platform().KeySetURL, platform.key.KeySet().Keys[0].Kid)
+		fetched <- err
+	}()
+	select {
+	case err := <-fetched:
+		if err != nil {
+			t.Fatalf("Key: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waited for another platform's key set to be fetched")
+	}
+}
+
+func TestDeepLinking(t *testing.T) {
+	toolKey := newTestKey(t)
+	platform := newMockPlatform(t, toolKey)
+	request := platform.launch(jwt.MapClaims{
+		claimMessageType: MessageDeepLinkingRequest,
+		"https://purl.imsglobal.org/spec/lti/claim/resource_link": nil,
+		"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings": map[string]interface{}{
+			"deep_link_return_url": platform.server.URL + "/deep-links/return",
+			"accept_types":         []string{ContentItemLink},
+			"accept_multiple":      true,
+			"data":                 "opaque-data",
+		},
+	})
+	launch, err := NewValidator(NewKeySets(platform.server.Client())).Validate(context.Background(), request, platform.platform())
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if launch.DeepLinking == nil || !launch.DeepLinking.Accepts(ContentItemLink) || !launch.DeepLinking.AcceptMultiple {
+		t.Fatalf("got deep linking settings %+v", launch.DeepLinking)
+	}
+
+	items := []ContentItem{{
+		Type:     ContentItemLink,
+		Title:    "Algebra",
+		URL:      "https://tool.example.org/api/v1/lti/launch",
+		Custom:   map[string]string{"course": "algebra"},
+		LineItem: &ContentLineItem{Label: "Algebra", ScoreMaximum: 100, ResourceID: "course:1"},
+	}}
+	response, err := toolKey.DeepLinkingResponse(platform.platform(), launch.DeploymentID, *launch.DeepLinking, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The platform checks the response against the tool's key set
+	toolKeys := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, toolKey.KeySet())
+	}))
+	defer toolKeys.Close()
+	var claims struct {
+		jwt.RegisteredClaims
+		MessageType  string        `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+		DeploymentID string        `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+		Items        []ContentItem `json:"https://purl.imsglobal.org/spec/lti-dl/claim/content_items"`
+		Data         string        `json:"https://purl.imsglobal.org/spec/lti-dl/claim/data"`
+	}
+	_, err = jwt.ParseWithClaims(response, &claims, func(token *jwt.Token) (interface{}, error) {
+		return NewKeySets(toolKeys.Client()).Key(context.Background(), toolKeys.URL, token.Header["kid"].(string))
+	}, jwt.WithIssuer(testClientID), jwt.WithAudience(platform.server.URL), jwt.WithExpirationRequired())
+	if err != nil {
+		t.Fatalf("the platform rejects the response: %v", err)
+	}
+	if claims.MessageType != MessageDeepLinkingResponse || claims.DeploymentID != testDeploymentID || claims.Data != "opaque-data" {
+		t.Errorf("got response claims %+v", claims)
+	}
+	if len(claims.Items) != 1 || claims.Items[0].Custom["course"] != "algebra" || claims.Items[0].LineItem == nil || claims.Items[0].LineItem.ScoreMaximum != 100 {
+		t.Errorf("got content items %+v", claims.Items)
+	}
+}
+
+func TestGradePassback(t *testing.T) {
+	toolKey := newTestKey(t)
+	platform := newMockPlatform(t, toolKey)
+	client := NewGradeClient(toolKey, platform.server.Client())
+	ctx := context.Background()
+	lineItemsURL := platform.server.URL + "/lineitems"
+
+	found, err := client.FindLineItem(ctx, platform.platform(), lineItemsURL, "course:1")
+	if err != nil || found != nil {
+		t.Fatalf("FindLineItem before creating it = %+v, %v", found, err)
+	}
+	created, err := client.CreateLineItem(ctx, platform.platform(), lineItemsURL, LineItem{Label: "Algebra", ScoreMaximum: 100, ResourceID: "course:1"})
+	if err != nil {
+		t.Fatalf("CreateLineItem: %v", err)
+	}
+	found, err = client.FindLineItem(ctx, platform.platform(), lineItemsURL, "course:1")
+	if err != nil || found == nil || found.ID != created.ID {
+		t.Fatalf("FindLineItem = %+v, %v, want %s", found, err, created.ID)
+	}
+
+	score := Score{UserID: "lms-user-1", ScoreGiven: 80, ScoreMaximum: 100, ActivityProgress: ActivityCompleted, GradingProgress: GradingFullyGraded, Timestamp: time.Now().Format(time.RFC3339)}
+	if err := client.PostScore(ctx, platform.platform(), created.ID, score); err != nil {
+		t.Fatalf("PostScore: %v", err)
+	}
+	scores := platform.scores["1"]
+	if len(scores) != 1 || scores[0].UserID != "lms-user-1" || scores[0].ScoreGiven != 80 || scores[0].GradingProgress != GradingFullyGraded {
+		t.Errorf("the gradebook has scores %+v", platform.scores)
+	}
+	if platform.tokenRequests != 1 {
+		t.Errorf("requested %d access tokens, want one kept for every call", platform.tokenRequests)
+	}
+}
+
+func TestGradePassbackWithUnregisteredToolKey(t *testing.T) {
+	platform := newMockPlatform(t, newTestKey(t))
+	// The platform knows another key than the one the tool signs with
+	client := NewGradeClient(newTestKey(t), platform.server.Client())
+
+	err := client.PostScore(context.Background(), platform.platform(), platform.server.URL+"/lineitems/1", Score{UserID: "lms-user-1"})
+	if err == nil || !strings.Contains(err.Error(), "access token") {
+		t.Errorf("got %v, want the access token refused", err)
+	}
+	if len(platform.scores) != 0 {
+		t.Errorf("the gradebook has scores %+v", platform.scores)
+	}
+}
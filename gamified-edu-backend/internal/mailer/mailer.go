@@ -0,0 +1,72 @@
+// Package mailer sends the emails the backend produces, such as guardian
+// invites and weekly summaries. Services depend on the Mailer interface so the
+// delivery mechanism can be swapped without touching them.
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FileOutbox writes every message as an .eml file into a directory instead of
+// sending it, for local development and tests.
+type FileOutbox struct {
+	dir string
+	seq atomic.Uint64
+}
+
+func NewFileOutbox(dir string) *FileOutbox {
+	return &FileOutbox{dir: dir}
+}
+
+func (o *FileOutbox) Send(msg Message) error {
+	if err := os.MkdirAll(o.dir, 0o700); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%04d.eml", now.Format("20060102T150405.000000000"), o.seq.Add(1))
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&builder, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&builder, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	builder.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return os.WriteFile(filepath.Join(o.dir, name), []byte(builder.String()), 0o600)
+}
+
+// FromEnv builds the mailer selected by MAILER. The file outbox is the only
+// transport so far and the default; it writes to MAIL_OUTBOX_DIR.
+func FromEnv() (Mailer, error) {
+	switch transport := os.Getenv("MAILER"); transport {
+	case "", "file":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = filepath.Join("data", "outbox")
+		}
+		return NewFileOutbox(dir), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", transport)
+	}
+}
+
+// headerValue keeps user-provided text from injecting extra headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readOutbox(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading outbox: %v", err)
+	}
+	messages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".eml" {
+			t.Fatalf("unexpected file %s in outbox", entry.Name())
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("reading %s: %v", entry.Name(), err)
+		}
+		messages = append(messages, string(data))
+	}
+	return messages
+}
+
+func TestFileOutboxWritesOneFilePerMessageInOrder(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	outbox := NewFileOutbox(dir)
+	for _, subject := range []string{"first", "second", "third"} {
+		if err := outbox.Send(Message{To: "parent@example.com", Subject: subject, Body: "Hello\nthere"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	messages := readOutbox(t, dir)
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(messages))
+	}
+	// File names sort in the order messages were sent
+	for i, subject := range []string{"first", "second", "third"} {
+		if !strings.Contains(messages[i], "Subject: "+subject+"\r\n") {
+			t.Errorf("message %d is not %q:\n%s", i, subject, messages[i])
+		}
+	}
+	message := messages[0]
+	if !strings.HasPrefix(message, "To: parent@example.com\r\n") {
+		t.Errorf("missing recipient:\n%s", message)
+	}
+	if !strings.HasSuffix(message, "\r\n\r\nHello\r\nthere") {
+		t.Errorf("body is not separated from the headers with CRLF line endings:\n%q", message)
+	}
+}
+
+func TestFileOutboxKeepsHeadersOnOneLine(t *testing.T) {
+	dir := t.TempDir()
+	err := NewFileOutbox(dir).Send(Message{
+		To:      "parent@example.com\r\nBcc: everyone@example.com",
+		Subject: "Progress\nBcc: everyone@example.com",
+		Body:    "Body",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	message := readOutbox(t, dir)[0]
+	headers := message[:strings.Index(message, "\r\n\r\n")]
+	for _, line := range strings.Split(headers, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") {
+			t.Fatalf("user input injected a header:\n%s", message)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MAILER", "")
+	t.Setenv("MAIL_OUTBOX_DIR", dir)
+	mail, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv: %v", err)
+	}
+	if err := mail.Send(Message{To: "a@example.com", Subject: "s", Body: "b"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := len(readOutbox(t, dir)); got != 1 {
+		t.Errorf("got %d messages in MAIL_OUTBOX_DIR, want 1", got)
+	}
+
+	t.Setenv("MAILER", "smtp")
+	if _, err := FromEnv(); err == nil {
+		t.Error("FromEnv accepted an unknown transport")
+	}
+}
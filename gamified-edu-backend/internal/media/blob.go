@@ -0,0 +1,163 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrBlobNotFound is returned by BlobStore.Open for keys with nothing stored.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps media assets by key. It does not serve them itself:
+// assets are private and only reachable through signed URLs.
+type BlobStore interface {
+	// Put stores size bytes from body under key, replacing what was there
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Open returns the blob under key, seekable so it can be served in ranges
+	Open(ctx context.Context, key string) (Blob, error)
+	// Delete removes the blob under key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Blob is a stored blob being read.
+type Blob interface {
+	io.ReadSeekCloser
+	Size() int64
+	ModTime() time.Time
+}
+
+// DirBlobStore keeps blobs as files below a local directory.
+type DirBlobStore struct {
+	dir string
+}
+
+func NewDirBlobStore(dir string) *DirBlobStore {
+	return &DirBlobStore{dir: dir}
+}
+
+func (s *DirBlobStore) path(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *DirBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a half-written blob is never served
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	written, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("blob %s: wrote %d bytes, expected %d", key, written, size)
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (s *DirBlobStore) Open(ctx context.Context, key string) (Blob, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileBlob{File: file, info: info}, nil
+}
+
+func (s *DirBlobStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+type fileBlob struct {
+	*os.File
+	info os.FileInfo
+}
+
+func (b *fileBlob) Size() int64        { return b.info.Size() }
+func (b *fileBlob) ModTime() time.Time { return b.info.ModTime() }
+
+// BlobStoreFromEnv builds the asset store selected by ASSET_STORE: "dir",
+// the default, keeps assets in ASSET_DIR; "s3" keeps them in the
+// ASSET_S3_BUCKET bucket of any S3-compatible service at ASSET_S3_ENDPOINT.
+func BlobStoreFromEnv() (BlobStore, error) {
+	switch backend := os.Getenv("ASSET_STORE"); backend {
+	case "", "dir":
+		dir := os.Getenv("ASSET_DIR")
+		if dir == "" {
+			dir = filepath.Join("data", "assets")
+		}
+		return NewDirBlobStore(dir), nil
+	case "s3":
+		region := os.Getenv("ASSET_S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return NewS3BlobStore(S3Config{
+			Endpoint:  os.Getenv("ASSET_S3_ENDPOINT"),
+			Bucket:    os.Getenv("ASSET_S3_BUCKET"),
+			Region:    region,
+			AccessKey: os.Getenv("ASSET_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("ASSET_S3_SECRET_KEY"),
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown asset store %q", backend)
+	}
+}
+
+// SniffLength is how much of a file SniffContentType looks at.
+const SniffLength = 512
+
+// SniffContentType detects the media type of a file from its first bytes.
+// Formats that sniff as something generic are told apart by their name:
+// slides are zip archives and captions are text.
+func SniffContentType(head []byte, name string) string {
+	contentType := http.DetectContentType(head)
+	extension := strings.ToLower(path.Ext(name))
+	switch {
+	case contentType == "application/zip" && extension == ".pptx":
+		return "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	case strings.HasPrefix(contentType, "text/plain") && extension == ".vtt":
+		return "text/vtt"
+	}
+	return contentType
+}
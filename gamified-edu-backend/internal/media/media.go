@@ -0,0 +1,62 @@
+// Package media stores the files course components link to. BlobStore keeps
+// uploaded assets, which are only served through signed URLs. DirStore reads
+// the files course bundles were imported with before bundled media became
+// assets, so they can be moved into the asset store. Services depend on the
+// interfaces so the storage backend can be swapped without touching them.
+package media
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotStored is returned by Open for URLs that do not point into the store.
+var ErrNotStored = errors.New("media file is not in the store")
+
+// DirStore reads files from a local directory, each below a directory named
+// after its checksum, by the URL they used to be served at. Nothing is
+// served from it anymore.
+type DirStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewDirStore reads files in dir that were served at baseURL, which is a
+// path such as /media.
+func NewDirStore(dir, baseURL string) *DirStore {
+	return &DirStore{dir: dir, baseURL: "/" + strings.Trim(baseURL, "/")}
+}
+
+// BaseURL is the path the files were served at, which courses link them by
+func (s *DirStore) BaseURL() string {
+	return s.baseURL
+}
+
+// Open returns the name and content of a file by its URL
+func (s *DirStore) Open(url string) (string, []byte, error) {
+	stored, ok := strings.CutPrefix(url, s.baseURL+"/")
+	if !ok || stored == "" || path.Clean(stored) != stored || strings.HasPrefix(stored, "../") {
+		return "", nil, ErrNotStored
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(stored)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil, ErrNotStored
+		}
+		return "", nil, err
+	}
+	return path.Base(stored), data, nil
+}
+
+// LegacyStoreFromEnv reads the files bundles were imported with from
+// MEDIA_DIR (default data/media); courses link them below /media.
+func LegacyStoreFromEnv() *DirStore {
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = filepath.Join("data", "media")
+	}
+	return NewDirStore(dir, "/media")
+}
@@ -0,0 +1,277 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config locates a bucket of an S3-compatible service, such as AWS S3 or
+// a local MinIO.
+type S3Config struct {
+	// Endpoint is the service's base URL, e.g. https://s3.eu-central-1.amazonaws.com
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3BlobStore keeps blobs as objects in an S3 bucket, addressed path-style
+// so any S3-compatible service works. Requests are signed with AWS
+// Signature Version 4.
+type S3BlobStore struct {
+	config   S3Config
+	endpoint *url.URL
+	http     *http.Client
+}
+
+func NewS3BlobStore(config S3Config, client *http.Client) (*S3BlobStore, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+	if config.Bucket == "" || config.AccessKey == "" || config.SecretKey == "" {
+		return nil, errors.New("an S3 store needs a bucket and credentials")
+	}
+	if client == nil {
+		// No overall timeout: reading a video can take as long as watching it
+		client = &http.Client{}
+	}
+	return &S3BlobStore{config: config, endpoint: endpoint, http: client}, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	request, err := s.request(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+	request.Header.Set("Content-Type", contentType)
+	response, err := s.do(request, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func (s *S3BlobStore) Open(ctx context.Context, key string) (Blob, error) {
+	request, err := s.request(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.do(request, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	modTime, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+	return &s3Blob{store: s, ctx: ctx, key: key, size: response.ContentLength, modTime: modTime}, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	request, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	response, err := s.do(request, emptyPayloadHash)
+	if err != nil && !errors.Is(err, ErrBlobNotFound) {
+		return err
+	}
+	if response != nil {
+		response.Body.Close()
+	}
+	return nil
+}
+
+func (s *S3BlobStore) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	target := *s.endpoint
+	target.Path = s.endpoint.Path + "/" + s.config.Bucket + "/" + key
+	target.RawPath = s.endpoint.EscapedPath() + "/" + escapeS3Path(s.config.Bucket+"/"+key)
+	return http.NewRequestWithContext(ctx, method, target.String(), body)
+}
+
+// do signs and sends request, turning error statuses into errors
+func (s *S3BlobStore) do(request *http.Request, payloadHash string) (*http.Response, error) {
+	signS3Request(request, s.config, payloadHash, time.Now())
+	response, err := s.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1000))
+		response.Body.Close()
+		return nil, fmt.Errorf("S3 %s %s: %s %s", request.Method, request.URL.Path, response.Status, strings.TrimSpace(string(message)))
+	}
+	return response, nil
+}
+
+// s3Blob reads an object with ranged GETs from the read position, so seeking
+// to the part of a video being watched does not download what comes before.
+type s3Blob struct {
+	store   *S3BlobStore
+	ctx     context.Context
+	key     string
+	size    int64
+	modTime time.Time
+	offset  int64
+	body    io.ReadCloser
+}
+
+func (b *s3Blob) Size() int64        { return b.size }
+func (b *s3Blob) ModTime() time.Time { return b.modTime }
+
+func (b *s3Blob) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.body == nil {
+		request, err := b.store.request(b.ctx, http.MethodGet, b.key, nil)
+		if err != nil {
+			return 0, err
+		}
+		request.Header.Set("Range", "bytes="+strconv.FormatInt(b.offset, 10)+"-")
+		response, err := b.store.do(request, emptyPayloadHash)
+		if err != nil {
+			return 0, err
+		}
+		b.body = response.Body
+	}
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	return n, err
+}
+
+func (b *s3Blob) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before the start of the blob")
+	}
+	if offset != b.offset && b.body != nil {
+		b.body.Close()
+		b.body = nil
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *s3Blob) Close() error {
+	if b.body == nil {
+		return nil
+	}
+	return b.body.Close()
+}
+
+const (
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// emptyPayloadHash is the SHA-256 of no bytes, for requests without a body
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// signS3Request adds an AWS Signature Version 4 Authorization header. It
+// signs the host, the range and the x-amz-* headers.
+func signS3Request(request *http.Request, config S3Config, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		lower := strings.ToLower(name)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		canonicalS3Query(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+config.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalS3Query(query url.Values) string {
+	parts := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			parts = append(parts, escapeS3(name)+"="+escapeS3(value))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+// escapeS3 percent-encodes everything but the unreserved characters, as
+// signatures require
+func escapeS3(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func escapeS3Path(value string) string {
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = escapeS3(segment)
+	}
+	return strings.Join(segments, "/")
+}
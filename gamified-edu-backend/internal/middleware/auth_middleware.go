@@ -1,34 +1,168 @@
 package middleware
 
 import (
+    "gamified-edu-backend/internal/models"
+    "gamified-edu-backend/internal/tenant"
     "gamified-edu-backend/pkg"
     "net/http"
     "strings"
     "github.com/gin-gonic/gin"
+    "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// Authentication methods recorded in the request context under "authMethod".
+const (
+    AuthMethodToken  = "token"
+    AuthMethodAPIKey = "api_key"
+)
+
+// apiKeyMarker is the prefix that distinguishes API keys from JWTs.
+const apiKeyMarker = "gep_"
+
+// SessionValidator checks that the session behind a token is still active.
+type SessionValidator interface {
+    ValidateSession(sessionID, userID primitive.ObjectID) error
+}
+
+// APIKeyValidator resolves a raw API key to the stored key and the role and
+// organization it acts with.
+type APIKeyValidator interface {
+    AuthenticateAPIKey(rawKey, ipAddress string) (*models.APIKey, string, primitive.ObjectID, error)
+}
+
+// Authenticator builds the authentication middlewares used by the routes.
+type Authenticator struct {
+    sessions SessionValidator
+    apiKeys  APIKeyValidator
+}
+
+func NewAuthenticator(sessions SessionValidator, apiKeys APIKeyValidator) *Authenticator {
+    return &Authenticator{sessions: sessions, apiKeys: apiKeys}
+}
+
+// RequireAuth only accepts session tokens. API keys are rejected so that a
+// key can never reach a route that has not declared a scope for it.
+func (a *Authenticator) RequireAuth() gin.HandlerFunc {
+    return a.requireToken(false)
+}
+
+// RequireAuthDuringTwoFactorSetup is RequireAuth for the routes that set up
+// 2FA, which also accept the token of a user who still has to set it up.
+func (a *Authenticator) RequireAuthDuringTwoFactorSetup() gin.HandlerFunc {
+    return a.requireToken(true)
+}
+
+func (a *Authenticator) requireToken(allowSetup bool) gin.HandlerFunc {
     return func(c *gin.Context) {
-        authHeader := c.GetHeader("Authorization")
-        if authHeader == "" {
-            pkg.SendError(c, http.StatusUnauthorized, "Authorization header is required")
+        credential, ok := bearerCredential(c)
+        if !ok {
             return
         }
-
-        parts := strings.Split(authHeader, " ")
-        if len(parts) != 2 || parts[0] != "Bearer" {
-            pkg.SendError(c, http.StatusUnauthorized, "Authorization header format must be Bearer {token}")
+        if isAPIKey(credential) {
+            pkg.SendError(c, http.StatusForbidden, "API keys cannot be used for this endpoint")
             return
         }
+        a.authenticateToken(c, credential, allowSetup)
+    }
+}
 
-        userID, err := pkg.ValidateToken(parts[1])
-        if err != nil {
-            pkg.SendError(c, http.StatusUnauthorized, "Invalid token")
+// RequireScope accepts session tokens, or API keys that were granted scope.
+// Keys can be sent as a Bearer credential, in the X-API-Key header or as the
+// password of HTTP basic auth.
+func (a *Authenticator) RequireScope(scope string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if rawKey := c.GetHeader("X-API-Key"); rawKey != "" {
+            a.authenticateAPIKey(c, rawKey, scope)
+            return
+        }
+        // xAPI clients only speak HTTP basic auth, with the API key as the password
+        if _, password, ok := c.Request.BasicAuth(); ok {
+            a.authenticateAPIKey(c, password, scope)
+            return
+        }
+        credential, ok := bearerCredential(c)
+        if !ok {
             return
         }
+        if isAPIKey(credential) {
+            a.authenticateAPIKey(c, credential, scope)
+            return
+        }
+        a.authenticateToken(c, credential, false)
+    }
+}
+
+// authenticateToken accepts tokens of users who must still set up 2FA only
+// with allowSetup, so they cannot do anything else until they did.
+func (a *Authenticator) authenticateToken(c *gin.Context, tokenString string, allowSetup bool) {
+    claims, err := pkg.ValidateToken(tokenString)
+    if err != nil {
+        pkg.SendError(c, http.StatusUnauthorized, "Invalid token")
+        return
+    }
+
+    // The token is only as good as its session, which may have been revoked
+    if err := a.sessions.ValidateSession(claims.SessionID, claims.UserID); err != nil {
+        pkg.SendError(c, http.StatusUnauthorized, "Session expired or revoked, please log in again")
+        return
+    }
+    if claims.TwoFactorSetupRequired && !allowSetup {
+        pkg.SendError(c, http.StatusForbidden, "Two-factor authentication must be set up before accessing this resource")
+        return
+    }
+
+    // Set userID in context for subsequent handlers to use
+    c.Set("userID", claims.UserID)
+    c.Set("sessionID", claims.SessionID)
+    c.Set("role", claims.Role)
+    c.Set("twoFactorSetupRequired", claims.TwoFactorSetupRequired)
+    c.Set("authMethod", AuthMethodToken)
+    setOrganization(c, claims.OrganizationID)
+    c.Next()
+}
 
-        // Set userID in context for subsequent handlers to use
-        c.Set("userID", userID)
-        c.Next()
+func (a *Authenticator) authenticateAPIKey(c *gin.Context, rawKey, scope string) {
+    key, role, organizationID, err := a.apiKeys.AuthenticateAPIKey(rawKey, c.ClientIP())
+    if err != nil {
+        pkg.SendError(c, http.StatusUnauthorized, "Invalid API key")
+        return
     }
-}
\ No newline at end of file
+    if !key.HasScope(scope) {
+        pkg.SendError(c, http.StatusForbidden, "API key is missing the required scope: "+scope)
+        return
+    }
+
+    c.Set("userID", key.UserID)
+    c.Set("role", role)
+    c.Set("apiKeyID", key.ID)
+    c.Set("authMethod", AuthMethodAPIKey)
+    setOrganization(c, organizationID)
+    c.Next()
+}
+
+// setOrganization scopes the rest of the request to organizationID. The
+// organization is put on the request context, which tenant-scoped
+// repositories read it from.
+func setOrganization(c *gin.Context, organizationID primitive.ObjectID) {
+    c.Set("organizationID", organizationID)
+    c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), organizationID))
+}
+
+func bearerCredential(c *gin.Context) (string, bool) {
+    authHeader := c.GetHeader("Authorization")
+    if authHeader == "" {
+        pkg.SendError(c, http.StatusUnauthorized, "Authorization header is required")
+        return "", false
+    }
+
+    parts := strings.Split(authHeader, " ")
+    if len(parts) != 2 || parts[0] != "Bearer" {
+        pkg.SendError(c, http.StatusUnauthorized, "Authorization header format must be Bearer {token}")
+        return "", false
+    }
+    return parts[1], true
+}
+
+func isAPIKey(credential string) bool {
+    return strings.HasPrefix(credential, apiKeyMarker)
+}
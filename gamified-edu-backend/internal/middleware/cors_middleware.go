@@ -23,6 +23,7 @@ func CorsMiddleware() gin.HandlerFunc {
 		"Accept",
 		"Authorization",
 		"X-Requested-With",
+		"X-API-Key",
 	}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
 
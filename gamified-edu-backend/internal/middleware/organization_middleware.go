@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MemberFinder looks up the user a route acts on.
+type MemberFinder interface {
+	FindByID(id primitive.ObjectID) (*models.User, error)
+}
+
+// RequireOwnOrganization only lets requests through whose organization path
+// parameter is the caller's organization. It must run after authentication.
+func RequireOwnOrganization(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID, err := primitive.ObjectIDFromHex(c.Param(param))
+		if err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid organization ID format")
+			return
+		}
+		if organizationID != c.MustGet("organizationID").(primitive.ObjectID) {
+			pkg.SendError(c, http.StatusNotFound, "organization not found")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOrganizationMember only lets requests through whose user path
+// parameter names a member of the caller's organization. Users of other
+// organizations are reported as not found, so their IDs reveal nothing. It
+// must run after authentication.
+func RequireOrganizationMember(users MemberFinder, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.Param(param))
+		if err != nil {
+			pkg.SendError(c, http.StatusBadRequest, "Invalid user ID format")
+			return
+		}
+		user, err := users.FindByID(userID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			pkg.SendError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err != nil || user.OrganizationID != c.MustGet("organizationID").(primitive.ObjectID) {
+			pkg.SendError(c, http.StatusNotFound, "user not found")
+			return
+		}
+		c.Next()
+	}
+}
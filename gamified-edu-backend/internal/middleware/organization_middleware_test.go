@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"gamified-edu-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeMembers map[primitive.ObjectID]*models.User
+
+func (users fakeMembers) FindByID(id primitive.ObjectID) (*models.User, error) {
+	user, ok := users[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return user, nil
+}
+
+// serve runs a request as an admin of organizationID through guard
+func serve(organizationID primitive.ObjectID, route, path string, guard gin.HandlerFunc) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET(route, func(c *gin.Context) {
+		c.Set("organizationID", organizationID)
+		c.Next()
+	}, guard, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder.Code
+}
+
+func TestRequireOrganizationMember(t *testing.T) {
+	ownOrganization, otherOrganization := primitive.NewObjectID(), primitive.NewObjectID()
+	member := &models.User{ID: primitive.NewObjectID(), OrganizationID: ownOrganization}
+	outsider := &models.User{ID: primitive.NewObjectID(), OrganizationID: otherOrganization}
+	guard := RequireOrganizationMember(fakeMembers{member.ID: member, outsider.ID: outsider}, "userId")
+
+	tests := map[string]struct {
+		userID string
+		want   int
+	}{
+		"member":             {member.ID.Hex(), http.StatusNoContent},
+		"other organization": {outsider.ID.Hex(), http.StatusNotFound},
+		"unknown user":       {primitive.NewObjectID().Hex(), http.StatusNotFound},
+		"invalid ID":         {"nope", http.StatusBadRequest},
+	}
+	for name, test := range tests {
+		if got := serve(ownOrganization, "/users/:userId", "/users/"+test.userID, guard); got != test.want {
+			t.Errorf("%s: got %d, want %d", name, got, test.want)
+		}
+	}
+}
+
+func TestRequireOwnOrganization(t *testing.T) {
+	ownOrganization := primitive.NewObjectID()
+	guard := RequireOwnOrganization("organizationId")
+
+	tests := map[string]struct {
+		organizationID string
+		want           int
+	}{
+		"own organization":   {ownOrganization.Hex(), http.StatusNoContent},
+		"other organization": {primitive.NewObjectID().Hex(), http.StatusNotFound},
+		"invalid ID":         {"nope", http.StatusBadRequest},
+	}
+	for name, test := range tests {
+		if got := serve(ownOrganization, "/organizations/:organizationId", "/organizations/"+test.organizationID, guard); got != test.want {
+			t.Errorf("%s: got %d, want %d", name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"gamified-edu-backend/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets requests through whose token carries one of roles.
+// It must run after Authenticator.RequireAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Users whose role requires 2FA must enroll before using privileged routes
+		if c.GetBool("twoFactorSetupRequired") {
+			pkg.SendError(c, http.StatusForbidden, "Two-factor authentication must be set up before accessing this resource")
+			return
+		}
+
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		pkg.SendError(c, http.StatusForbidden, "You do not have permission to access this resource")
+	}
+}
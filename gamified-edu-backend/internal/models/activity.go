@@ -6,7 +6,8 @@ import (
 )
 
 type UserActivity struct {
-    ID        primitive.ObjectID `bson:"_id,omitempty"`
-    UserID    primitive.ObjectID `bson:"user_id"`
-    Timestamp time.Time          `bson:"timestamp"`
+    ID             primitive.ObjectID `bson:"_id,omitempty"`
+    OrganizationID primitive.ObjectID `bson:"organization_id"`
+    UserID         primitive.ObjectID `bson:"user_id"`
+    Timestamp      time.Time          `bson:"timestamp"`
 }
\ No newline at end of file
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// API key owners. User keys act as the user who created them; organization
+// keys belong to an organization rather than a person and are managed by admins.
+const (
+	APIKeyOwnerUser         = "user"
+	APIKeyOwnerOrganization = "organization"
+)
+
+// Scopes an API key can be granted. JWT sessions are not limited by scopes.
+const (
+	ScopeCoursesRead   = "courses:read"
+	ScopeCoursesWrite  = "courses:write"
+	ScopeProgressRead  = "progress:read"
+	ScopeProgressWrite = "progress:write"
+	ScopeDashboardRead = "dashboard:read"
+	// Statements scopes let LRS clients use the built-in xAPI statements endpoint
+	ScopeStatementsRead  = "statements:read"
+	ScopeStatementsWrite = "statements:write"
+)
+
+var APIKeyScopes = []string{
+	ScopeCoursesRead,
+	ScopeCoursesWrite,
+	ScopeProgressRead,
+	ScopeProgressWrite,
+	ScopeDashboardRead,
+	ScopeStatementsRead,
+	ScopeStatementsWrite,
+}
+
+// APIKey is a long-lived credential for scripts and integrations. Only a
+// SHA-256 hash of the key is stored; Prefix is kept in clear to identify it.
+type APIKey struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	Name           string             `bson:"name"`
+	Prefix         string             `bson:"prefix"`
+	KeyHash        string             `bson:"key_hash"`
+	OwnerType      string             `bson:"owner_type"`
+	UserID         primitive.ObjectID `bson:"user_id"` // owner for user keys, creator for organization keys
+	OrganizationID primitive.ObjectID `bson:"organization_id,omitempty"`
+	Scopes         []string           `bson:"scopes"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty"`
+	LastUsedAt     *time.Time         `bson:"last_used_at,omitempty"`
+	LastUsedIP     string             `bson:"last_used_ip,omitempty"`
+	UsageCount     int64              `bson:"usage_count"`
+	RevokedAt      *time.Time         `bson:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the key can still authenticate requests at t.
+func (k *APIKey) IsActive(t time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || t.Before(*k.ExpiresAt)
+}
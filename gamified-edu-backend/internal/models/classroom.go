@@ -0,0 +1,48 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Classroom groups students under an instructor. Students join with JoinCode.
+type Classroom struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	Name           string             `bson:"name"`
+	InstructorID   primitive.ObjectID `bson:"instructor_id"`
+	JoinCode       string             `bson:"join_code"`
+	CreatedAt      time.Time          `bson:"created_at"`
+}
+
+// ClassroomMember records that a student joined a classroom.
+type ClassroomMember struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	ClassroomID    primitive.ObjectID `bson:"classroom_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	JoinedAt       time.Time          `bson:"joined_at"`
+}
+
+// Statuses of a student's work on an assignment, computed from their chapter progress.
+const (
+	AssignmentNotStarted = "not_started"
+	AssignmentInProgress = "in_progress"
+	AssignmentCompleted  = "completed"
+	AssignmentLate       = "late"    // completed after the due date
+	AssignmentMissing    = "missing" // not completed and past the due date
+)
+
+// Assignment asks the students of a classroom to complete a course, or only
+// some of its chapters, by DueAt.
+type Assignment struct {
+	ID             primitive.ObjectID   `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID   `bson:"organization_id"`
+	ClassroomID    primitive.ObjectID   `bson:"classroom_id"`
+	CourseID       primitive.ObjectID   `bson:"course_id"`
+	ChapterIDs     []primitive.ObjectID `bson:"chapter_ids,omitempty"` // empty means the whole course
+	Title          string               `bson:"title"`
+	DueAt          time.Time            `bson:"due_at"`
+	CreatedBy      primitive.ObjectID   `bson:"created_by"`
+	CreatedAt      time.Time            `bson:"created_at"`
+}
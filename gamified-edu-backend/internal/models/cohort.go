@@ -0,0 +1,19 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Cohort is a group of a course's learners who start together. Chapters
+// released relative to the cohort start open for all of them at once.
+type Cohort struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	CourseID       primitive.ObjectID `bson:"course_id"`
+	Name           string             `bson:"name"`
+	StartsAt       time.Time          `bson:"starts_at"`
+	CreatedBy      primitive.ObjectID `bson:"created_by"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
@@ -1,20 +1,316 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "sort"
+    "time"
+)
+
+// Ways chapters can count towards course progress.
+const (
+    ProgressWeightingChapters = "chapters" // every chapter counts the same
+    ProgressWeightingDuration = "duration" // chapters count by DurationMins
+)
+
+// Difficulty levels of a course.
+const (
+    DifficultyBeginner     = "beginner"
+    DifficultyIntermediate = "intermediate"
+    DifficultyAdvanced     = "advanced"
+)
+
+// DefaultModuleTitle names the module that chapters of a course without
+// modules were moved into.
+const DefaultModuleTitle = "Course content"
 
 type Course struct {
-    ID          primitive.ObjectID `bson:"_id,omitempty"`
-    Title       string             `bson:"title"`
-    Description string             `bson:"description"`
-    Chapters    []Chapter          `bson:"chapters"`
+    ID             primitive.ObjectID `bson:"_id,omitempty"`
+    OrganizationID primitive.ObjectID `bson:"organization_id"`
+    // Slug identifies the course across environments, e.g. in course bundles
+    Slug           string             `bson:"slug"`
+    Title          string             `bson:"title"`
+    Description    string             `bson:"description"`
+    // Modules group the chapters into units. Short courses have a single module.
+    Modules        []Module           `bson:"modules"`
+    // SequentialChapters locks each chapter until the one before it is completed
+    SequentialChapters bool `bson:"sequential_chapters"`
+    // HideUnreleasedChapters leaves chapters out of the course until they are
+    // released; otherwise they show locked with the date they open
+    HideUnreleasedChapters bool `bson:"hide_unreleased_chapters"`
+    // PrerequisiteIDs are courses that must be completed before this one can be started
+    PrerequisiteIDs []primitive.ObjectID `bson:"prerequisite_ids,omitempty"`
+    // ProgressWeighting is one of the ProgressWeighting constants; empty means by chapters
+    ProgressWeighting string `bson:"progress_weighting,omitempty"`
+    // Catalog metadata, settings like the above rather than versioned content.
+    // The catalog is browsed by Categories and Tags; Difficulty is one of the
+    // Difficulty constants and Language a BCP 47 tag such as "en" or "pt-BR".
+    Categories []string `bson:"categories,omitempty"`
+    Tags       []string `bson:"tags,omitempty"`
+    Difficulty string   `bson:"difficulty,omitempty"`
+    Language   string   `bson:"language,omitempty"`
+    // Thumbnail is an image URL or a media asset ref, like component URLs
+    Thumbnail  string   `bson:"thumbnail,omitempty"`
+    // AuthorIDs are the users credited with the course, its creator first
+    AuthorIDs  []primitive.ObjectID `bson:"author_ids,omitempty"`
+    // EnrollmentCount is how many learners are enrolled, for sorting by popularity
+    EnrollmentCount int `bson:"enrollment_count"`
+    // Rating sums up the visible reviews of the course
+    Rating CourseRating `bson:"rating"`
+
+    // Title, Description and Modules above are the content of PublishedVersion,
+    // which is what learners see. Zero means the course was never published.
+    PublishedVersion int `bson:"published_version"`
+    // Draft holds unpublished edits to the content; nil when there are none
+    Draft *CourseDraft `bson:"draft,omitempty"`
+}
+
+// CourseContent is the part of a course that is edited in drafts and
+// snapshotted when published. Settings like prerequisites apply immediately
+// and are not versioned.
+type CourseContent struct {
+    Title       string   `bson:"title"`
+    Description string   `bson:"description"`
+    Modules     []Module `bson:"modules"`
 }
 
+// CourseDraft is work in progress on the next version of a course.
+type CourseDraft struct {
+    CourseContent `bson:",inline"`
+    // BasedOnVersion is the published version the draft started from
+    BasedOnVersion int                `bson:"based_on_version"`
+    UpdatedAt      time.Time          `bson:"updated_at"`
+    UpdatedBy      primitive.ObjectID `bson:"updated_by"`
+}
+
+// Module is a section of a course. Modules are ordered by Position.
+type Module struct {
+    ID       primitive.ObjectID `bson:"_id,omitempty"`
+    // Slug is unique within the course and stays the same across versions
+    Slug     string             `bson:"slug,omitempty"`
+    Title    string             `bson:"title"`
+    Position int                `bson:"position"`
+    Chapters []Chapter          `bson:"chapters"`
+}
+
+// Chapter is ordered by ChapterNumber within its module.
 type Chapter struct {
     ID            primitive.ObjectID `bson:"_id,omitempty"`
+    // Slug is unique within the course and stays the same across versions
+    Slug          string             `bson:"slug,omitempty"`
     Title         string             `bson:"title"`
     ChapterNumber int                `bson:"chapter_number"`
-    VideoURL      string             `bson:"video_url"`
-    QuizID        string             `bson:"quiz_id"`
-    PPTLink       string             `bson:"ppt_link"`
     DurationMins  int                `bson:"duration_mins"` // <-- ADD THIS LINE
-}
\ No newline at end of file
+    // Release schedules when learners get the chapter; nil releases it right away
+    Release       *ChapterRelease    `bson:"release,omitempty"`
+    // Components are the parts of the chapter, in the order learners work through them
+    Components []ChapterComponent `bson:"components"`
+}
+
+// Chapter release rules.
+const (
+    ReleaseOnDate           = "date"       // on At, for everyone
+    ReleaseAfterEnrollment  = "enrollment" // OffsetDays after the learner enrolled
+    // OffsetDays after the learner's cohort starts, or after they enrolled if they are in no cohort
+    ReleaseAfterCohortStart = "cohort"
+)
+
+// ChapterRelease is the drip schedule of a chapter.
+type ChapterRelease struct {
+    // Rule is one of the Release constants
+    Rule       string     `bson:"rule"`
+    At         *time.Time `bson:"at,omitempty"`
+    OffsetDays int        `bson:"offset_days,omitempty"`
+}
+
+// Equal reports whether the two releases are the same schedule; nil is released right away.
+func (r *ChapterRelease) Equal(other *ChapterRelease) bool {
+    if r == nil || other == nil {
+        return r == other
+    }
+    if (r.At == nil) != (other.At == nil) || r.At != nil && !r.At.Equal(*other.At) {
+        return false
+    }
+    return r.Rule == other.Rule && r.OffsetDays == other.OffsetDays
+}
+
+// Chapter component types. Each type has its own completion rule, see
+// services.ComponentType.
+const (
+    ComponentVideo      = "video"
+    ComponentQuiz       = "quiz"
+    ComponentReading    = "reading"
+    ComponentSlides     = "slides"
+    ComponentAssignment = "assignment"
+    ComponentLink       = "link"
+    ComponentSCORM      = "scorm"
+)
+
+// ChapterComponent is one piece of work in a chapter.
+type ChapterComponent struct {
+    // Key identifies the component within its chapter and appears in progress URLs
+    Key    string `bson:"key"`
+    Type   string `bson:"type"`
+    Title  string `bson:"title,omitempty"`
+    URL    string `bson:"url,omitempty"`
+    QuizID string `bson:"quiz_id,omitempty"`
+    // XP is awarded on completion; zero means the type's default
+    XP int `bson:"xp,omitempty"`
+    // Optional components earn XP but are not needed to complete the chapter.
+    // A chapter with only optional components is completed by any one of them.
+    Optional bool `bson:"optional,omitempty"`
+
+    // Completion rule settings, each used by one type only
+    PassingScore    int `bson:"passing_score,omitempty"`     // quiz
+    MinWatchPercent int `bson:"min_watch_percent,omitempty"` // video
+    MinReadSeconds  int `bson:"min_read_seconds,omitempty"`  // reading
+
+    // The SCO a scorm component launches, see SCORMPackage
+    PackageID primitive.ObjectID `bson:"package_id,omitempty"`
+    SCOID     string             `bson:"sco_id,omitempty"`
+}
+
+// Component returns the chapter's component with key.
+func (ch *Chapter) Component(key string) (*ChapterComponent, bool) {
+    for i := range ch.Components {
+        if ch.Components[i].Key == key {
+            return &ch.Components[i], true
+        }
+    }
+    return nil, false
+}
+
+// Slugs returns the slugs of the modules and chapters by ID.
+func (c *CourseContent) Slugs() map[primitive.ObjectID]string {
+    slugs := map[primitive.ObjectID]string{}
+    for _, module := range c.Modules {
+        slugs[module.ID] = module.Slug
+        for _, chapter := range module.Chapters {
+            slugs[chapter.ID] = chapter.Slug
+        }
+    }
+    return slugs
+}
+
+// AssignSlugs gives modules and chapters without a slug one. A module or
+// chapter gets back the slug it had in previous, found by ID, if that is still
+// free; otherwise the slug is derived from its title.
+func (c *CourseContent) AssignSlugs(previous map[primitive.ObjectID]string) {
+    modules, chapters := map[string]bool{}, map[string]bool{}
+    for _, module := range c.Modules {
+        modules[module.Slug] = module.Slug != ""
+        for _, chapter := range module.Chapters {
+            chapters[chapter.Slug] = chapter.Slug != ""
+        }
+    }
+    pick := func(id primitive.ObjectID, title, fallback string, taken map[string]bool) string {
+        if slug := previous[id]; slug != "" && !taken[slug] {
+            taken[slug] = true
+            return slug
+        }
+        return UniqueSlug(Slugify(title, fallback), taken)
+    }
+    for i := range c.Modules {
+        module := &c.Modules[i]
+        if module.Slug == "" {
+            module.Slug = pick(module.ID, module.Title, "module", modules)
+        }
+        for j := range module.Chapters {
+            chapter := &module.Chapters[j]
+            if chapter.Slug == "" {
+                chapter.Slug = pick(chapter.ID, chapter.Title, "chapter", chapters)
+            }
+        }
+    }
+}
+
+// Content returns the published content of the course.
+func (c *Course) Content() CourseContent {
+    return CourseContent{Title: c.Title, Description: c.Description, Modules: c.Modules}
+}
+
+// IsPublished reports whether learners can see the course.
+func (c *Course) IsPublished() bool {
+    return c.PublishedVersion > 0
+}
+
+// ChapterIDs returns the IDs of every chapter of the course.
+func (c *Course) ChapterIDs() []primitive.ObjectID {
+    ids := make([]primitive.ObjectID, 0, c.ChapterCount())
+    for _, module := range c.Modules {
+        for _, chapter := range module.Chapters {
+            ids = append(ids, chapter.ID)
+        }
+    }
+    return ids
+}
+
+// FindChapter returns the chapter of the course with chapterID.
+func (c *Course) FindChapter(chapterID primitive.ObjectID) (*Chapter, bool) {
+    for i := range c.Modules {
+        for j := range c.Modules[i].Chapters {
+            if c.Modules[i].Chapters[j].ID == chapterID {
+                return &c.Modules[i].Chapters[j], true
+            }
+        }
+    }
+    return nil, false
+}
+
+// OrderedModules returns the modules sorted by Position, each with its chapters in order.
+func (c *Course) OrderedModules() []Module {
+    modules := make([]Module, len(c.Modules))
+    for i, module := range c.Modules {
+        module.Chapters = append([]Chapter(nil), module.Chapters...)
+        sort.SliceStable(module.Chapters, func(a, b int) bool { return module.Chapters[a].ChapterNumber < module.Chapters[b].ChapterNumber })
+        modules[i] = module
+    }
+    sort.SliceStable(modules, func(a, b int) bool { return modules[a].Position < modules[b].Position })
+    return modules
+}
+
+// OrderedChapters returns every chapter of the course in learning order.
+func (c *Course) OrderedChapters() []Chapter {
+    var chapters []Chapter
+    for _, module := range c.OrderedModules() {
+        chapters = append(chapters, module.Chapters...)
+    }
+    return chapters
+}
+
+// DurationMins estimates the time the course takes, the sum of its chapters' durations.
+func (c *Course) DurationMins() int {
+    total := 0
+    for _, module := range c.Modules {
+        for _, chapter := range module.Chapters {
+            total += chapter.DurationMins
+        }
+    }
+    return total
+}
+
+// ChapterCount returns the number of chapters across all modules.
+func (c *Course) ChapterCount() int {
+    count := 0
+    for _, module := range c.Modules {
+        count += len(module.Chapters)
+    }
+    return count
+}
+
+// HasChapter reports whether chapterID is a chapter of the course.
+func (c *Course) HasChapter(chapterID primitive.ObjectID) bool {
+    _, ok := c.FindChapter(chapterID)
+    return ok
+}
+
+// ChapterWeight is how much a chapter counts towards the progress of the course.
+func (c *Course) ChapterWeight(chapter Chapter) float64 {
+    if c.ProgressWeighting == ProgressWeightingDuration {
+        // Chapters without a duration still count a little
+        if chapter.DurationMins < 1 {
+            return 1
+        }
+        return float64(chapter.DurationMins)
+    }
+    return 1
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Moderation states of a course review.
+const (
+	ReviewVisible = "visible"
+	// Flagged reviews were reported by enough learners and wait for a moderator
+	ReviewFlagged = "flagged"
+	ReviewHidden  = "hidden"
+)
+
+// CourseReview is a learner's rating of a course, with an optional review.
+// Learners have one review per course, which they can edit.
+type CourseReview struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	CourseID       primitive.ObjectID `bson:"course_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	// Rating is from 1 to 5 stars
+	Rating int    `bson:"rating"`
+	Body   string `bson:"body,omitempty"`
+	// Status is one of the Review constants; only visible reviews count towards the course rating
+	Status string `bson:"status"`
+	// ModeratedAt is when a moderator last decided on the review; reports from before it are settled
+	ModeratedAt    *time.Time         `bson:"moderated_at,omitempty"`
+	ModeratedBy    primitive.ObjectID `bson:"moderated_by,omitempty"`
+	ModerationNote string             `bson:"moderation_note,omitempty"`
+	Reply          *ReviewReply       `bson:"reply,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+// ReviewReply is a course author's public answer to a review.
+type ReviewReply struct {
+	AuthorID  primitive.ObjectID `bson:"author_id"`
+	Body      string             `bson:"body"`
+	RepliedAt time.Time          `bson:"replied_at"`
+}
+
+// ReviewReport records that a learner reported a review, once per learner.
+type ReviewReport struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	ReviewID       primitive.ObjectID `bson:"review_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	Reason         string             `bson:"reason"`
+	CreatedAt      time.Time          `bson:"created_at"`
+}
+
+// CourseRating sums up the ratings of a course's visible reviews.
+type CourseRating struct {
+	Count   int     `bson:"count"`
+	Average float64 `bson:"average"`
+	// Distribution counts the ratings of 1 to 5 stars
+	Distribution [5]int `bson:"distribution"`
+}
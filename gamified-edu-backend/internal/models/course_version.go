@@ -0,0 +1,23 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// CourseVersion is the content of a course as it was published. Versions are
+// numbered from 1 per course and never change once written; a rollback
+// publishes the content of an old version again as a new one.
+type CourseVersion struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	CourseID       primitive.ObjectID `bson:"course_id"`
+	Version        int                `bson:"version"`
+	CourseContent  `bson:",inline"`
+	PublishedAt    time.Time `bson:"published_at"`
+	// PublishedBy is empty for versions created by migrations
+	PublishedBy primitive.ObjectID `bson:"published_by,omitempty"`
+	Note        string             `bson:"note,omitempty"`
+	// RestoredFrom is the version a rollback republished
+	RestoredFrom int `bson:"restored_from,omitempty"`
+}
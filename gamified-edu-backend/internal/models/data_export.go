@@ -0,0 +1,32 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Data export job states.
+const (
+	DataExportPending   = "pending"
+	DataExportRunning   = "running"
+	DataExportCompleted = "completed"
+	DataExportFailed    = "failed"
+	DataExportExpired   = "expired"
+)
+
+// DataExport is a background job that packages all of a user's data into a zip archive.
+type DataExport struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty"`
+	UserID primitive.ObjectID `bson:"user_id"`
+	// OrganizationID is the organization the export was requested in; it
+	// only covers the user's data there
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	RequestedBy    primitive.ObjectID `bson:"requested_by"`
+	Status         string             `bson:"status"`
+	FilePath       string             `bson:"file_path,omitempty"`
+	FileSize       int64              `bson:"file_size,omitempty"`
+	Error          string             `bson:"error,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	CompletedAt    *time.Time         `bson:"completed_at,omitempty"`
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty"`
+}
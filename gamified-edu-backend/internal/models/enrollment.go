@@ -0,0 +1,26 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Enrollment records that a user signed up for a course. Unenrolling keeps
+// the document, so dates survive a later re-enrollment.
+type Enrollment struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	CourseID       primitive.ObjectID `bson:"course_id"`
+	EnrolledAt     time.Time          `bson:"enrolled_at"`
+	// CohortID is the cohort the learner takes the course with, if any
+	CohortID primitive.ObjectID `bson:"cohort_id,omitempty"`
+	// CompletedAt is set once every chapter of the course is completed
+	CompletedAt  *time.Time `bson:"completed_at,omitempty"`
+	UnenrolledAt *time.Time `bson:"unenrolled_at,omitempty"`
+}
+
+// IsActive reports whether the user is currently enrolled.
+func (e *Enrollment) IsActive() bool {
+	return e.UnenrolledAt == nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Guardian link states.
+const (
+	GuardianLinkPending = "pending"
+	GuardianLinkActive  = "active"
+	GuardianLinkRevoked = "revoked"
+)
+
+// Who started a guardian link. The other side has to accept it.
+const (
+	GuardianLinkByStudent  = "student"
+	GuardianLinkByGuardian = "guardian"
+)
+
+// GuardianLink gives a guardian read-only access to a student's progress.
+// Students invite a guardian by email, or guardians ask a student for access;
+// the link only becomes active once the other side accepts.
+type GuardianLink struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	StudentID      primitive.ObjectID `bson:"student_id"`
+	// GuardianID is unset while a student's invite has not been accepted yet
+	GuardianID    primitive.ObjectID `bson:"guardian_id,omitempty"`
+	GuardianEmail string             `bson:"guardian_email"`
+	Status        string             `bson:"status"`
+	InitiatedBy   string             `bson:"initiated_by"`
+	// InviteTokenHash is the SHA-256 hash of the code sent with a student's invite
+	InviteTokenHash string     `bson:"invite_token_hash,omitempty"`
+	InviteExpiresAt *time.Time `bson:"invite_expires_at,omitempty"`
+	CreatedAt       time.Time  `bson:"created_at"`
+	AcceptedAt      *time.Time `bson:"accepted_at,omitempty"`
+	RevokedAt       *time.Time `bson:"revoked_at,omitempty"`
+	// LastSummaryAt is when the guardian was last sent a weekly summary
+	LastSummaryAt *time.Time `bson:"last_summary_at,omitempty"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// LearningPath is a curated sequence of courses. A learner completes the path
+// by completing every required course and ElectivesRequired of its electives,
+// and earns RewardXP for it.
+type LearningPath struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	Title          string             `bson:"title"`
+	Description    string             `bson:"description"`
+	// Steps are the courses in the order they are meant to be taken
+	Steps             []LearningPathStep `bson:"steps"`
+	ElectivesRequired int                `bson:"electives_required"`
+	RewardXP          int                `bson:"reward_xp"`
+	CreatedBy         primitive.ObjectID `bson:"created_by"`
+	CreatedAt         time.Time          `bson:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at"`
+}
+
+type LearningPathStep struct {
+	CourseID primitive.ObjectID `bson:"course_id"`
+	// Elective courses are optional, learners pick ElectivesRequired of them
+	Elective bool `bson:"elective,omitempty"`
+}
+
+// CourseIDs returns the courses of the path in order.
+func (p *LearningPath) CourseIDs() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(p.Steps))
+	for _, step := range p.Steps {
+		ids = append(ids, step.CourseID)
+	}
+	return ids
+}
+
+// CoursesToComplete is how many courses completing the path takes.
+func (p *LearningPath) CoursesToComplete() int {
+	required := 0
+	for _, step := range p.Steps {
+		if !step.Elective {
+			required++
+		}
+	}
+	return required + p.ElectivesRequired
+}
+
+// PathEnrollment records that a user signed up for a learning path. Like
+// course enrollments, unenrolling keeps the document.
+type PathEnrollment struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	PathID         primitive.ObjectID `bson:"path_id"`
+	EnrolledAt     time.Time          `bson:"enrolled_at"`
+	// CompletedAt is set once the path is completed, when its reward is given
+	CompletedAt  *time.Time `bson:"completed_at,omitempty"`
+	RewardXP     int        `bson:"reward_xp,omitempty"`
+	UnenrolledAt *time.Time `bson:"unenrolled_at,omitempty"`
+}
+
+// IsActive reports whether the user is currently enrolled.
+func (e *PathEnrollment) IsActive() bool {
+	return e.UnenrolledAt == nil
+}
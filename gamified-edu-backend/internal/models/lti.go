@@ -0,0 +1,88 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// LTIPlatform is an LMS registered to launch the organization's courses
+// over LTI 1.3.
+type LTIPlatform struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	Name           string             `bson:"name"`
+	Issuer         string             `bson:"issuer"`
+	// ClientID is the tool's client ID at the platform
+	ClientID      string    `bson:"client_id"`
+	DeploymentIDs []string  `bson:"deployment_ids"`
+	AuthLoginURL  string    `bson:"auth_login_url"`
+	AuthTokenURL  string    `bson:"auth_token_url"`
+	KeySetURL     string    `bson:"key_set_url"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+// LTILoginState is kept between the login initiation and the launch it
+// leads to. Each state can be used for one launch only.
+type LTILoginState struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	State          string             `bson:"state"`
+	Nonce          string             `bson:"nonce"`
+	PlatformID     primitive.ObjectID `bson:"platform_id"`
+	ExpiresAt      time.Time          `bson:"expires_at"`
+}
+
+// LTIIdentity links a platform's user to the user they launch as.
+type LTIIdentity struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	PlatformID     primitive.ObjectID `bson:"platform_id"`
+	// Subject is the user's ID at the platform
+	Subject string             `bson:"subject"`
+	UserID  primitive.ObjectID `bson:"user_id"`
+	// CreatedUser is set when the launch created the user's account
+	CreatedUser bool      `bson:"created_user,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+// LTICourseLink is a link in an LMS course that launches one of our courses.
+// Scores of learners who launched it go to the LMS course's gradebook.
+type LTICourseLink struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	PlatformID     primitive.ObjectID `bson:"platform_id"`
+	DeploymentID   string             `bson:"deployment_id"`
+	ResourceLinkID string             `bson:"resource_link_id"`
+	ContextID      string             `bson:"context_id,omitempty"`
+	CourseID       primitive.ObjectID `bson:"course_id"`
+	// LineItemsURL and LineItemURL come from the Assignment and Grade Services
+	// claim, empty when the platform offers no grade services
+	LineItemsURL string   `bson:"line_items_url,omitempty"`
+	LineItemURL  string   `bson:"line_item_url,omitempty"`
+	Scopes       []string `bson:"scopes,omitempty"`
+	// LineItems caches the line items created for chapters and quizzes
+	LineItems  []LTILineItem        `bson:"line_items,omitempty"`
+	LearnerIDs []primitive.ObjectID `bson:"learner_ids"`
+	UpdatedAt  time.Time            `bson:"updated_at"`
+}
+
+// LTILineItem is a gradebook column the tool created.
+type LTILineItem struct {
+	// ResourceID names what is graded, e.g. "chapter:<id>"
+	ResourceID string `bson:"resource_id"`
+	URL        string `bson:"url"`
+}
+
+// LTIDeepLink is a deep linking request waiting for the user to pick courses.
+type LTIDeepLink struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	PlatformID     primitive.ObjectID `bson:"platform_id"`
+	DeploymentID   string             `bson:"deployment_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	ReturnURL      string             `bson:"return_url"`
+	AcceptMultiple bool               `bson:"accept_multiple"`
+	// Data is returned to the platform unchanged
+	Data      string    `bson:"data,omitempty"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
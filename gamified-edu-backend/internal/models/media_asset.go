@@ -0,0 +1,22 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// MediaAsset is an uploaded file, such as a video or slides, kept in the
+// asset store. Components link to it as "asset:<id>".
+type MediaAsset struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	// Key names the blob in the asset store
+	Key         string `bson:"key"`
+	FileName    string `bson:"file_name"`
+	ContentType string `bson:"content_type"`
+	Size        int64  `bson:"size"`
+	// SHA256 is the hex checksum of the content; an organization keeps each content once
+	SHA256     string             `bson:"sha256"`
+	UploadedBy primitive.ObjectID `bson:"uploaded_by"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
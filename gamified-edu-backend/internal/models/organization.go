@@ -0,0 +1,21 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// DefaultOrganizationSlug identifies the organization that users and content
+// created before multi-tenancy, or without a school, belong to.
+const DefaultOrganizationSlug = "default"
+
+// Organization is a tenant, typically a school. Courses, progress and
+// activities of one organization are never visible to another.
+type Organization struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty"`
+	Name string             `bson:"name"`
+	Slug string             `bson:"slug"`
+	// RegistrationCode lets learners sign up directly into the organization
+	RegistrationCode string    `bson:"registration_code,omitempty"`
+	CreatedAt        time.Time `bson:"created_at"`
+}
@@ -1,15 +1,34 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "time"
+)
 
 // This model is now much more detailed to track each component
 type UserChapterStatus struct {
     ID                 primitive.ObjectID `bson:"_id,omitempty"`
+    OrganizationID     primitive.ObjectID `bson:"organization_id"`
     UserID             primitive.ObjectID `bson:"user_id"`
     ChapterID          primitive.ObjectID `bson:"chapter_id"`
     CourseID           primitive.ObjectID `bson:"course_id"`
-    HasViewedVideo     bool               `bson:"has_viewed_video"`
-    HasCompletedQuiz   bool               `bson:"has_completed_quiz"`
-    HasDownloadedPPT   bool               `bson:"has_downloaded_ppt"`
-    IsChapterCompleted bool               `bson:"is_chapter_completed"` // True when all 3 are done
+    // Components holds the progress on each component, keyed by ChapterComponent.Key
+    Components         map[string]ComponentProgress `bson:"components"`
+    IsChapterCompleted bool               `bson:"is_chapter_completed"` // True when every required component is done
+    CompletedAt        *time.Time         `bson:"completed_at,omitempty"` // When the chapter was completed
+}
+
+// ComponentProgress is a learner's progress on one chapter component. Graded
+// components can have attempts recorded before they are completed.
+type ComponentProgress struct {
+    Completed   bool       `bson:"completed"`
+    CompletedAt *time.Time `bson:"completed_at,omitempty"`
+    BestScore   *int       `bson:"best_score,omitempty"` // Best reported score, 0-100
+    Submission  string     `bson:"submission,omitempty"`
+    XP          int        `bson:"xp"` // XP awarded for the component
+}
+
+// HasCompleted reports whether the component with key is complete.
+func (s *UserChapterStatus) HasCompleted(key string) bool {
+    return s.Components[key].Completed
 }
\ No newline at end of file
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Quiz is a multiple choice quiz that quiz components refer to by slug
+// through ChapterComponent.QuizID. Quizzes the frontend ships itself, such as
+// those of the seeded courses, have no document.
+type Quiz struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	Slug           string             `bson:"slug"`
+	Title          string             `bson:"title"`
+	// PassingScore is the suggested pass mark in percent
+	PassingScore int            `bson:"passing_score,omitempty"`
+	Questions    []QuizQuestion `bson:"questions"`
+	UpdatedAt    time.Time      `bson:"updated_at"`
+}
+
+type QuizQuestion struct {
+	Prompt  string   `bson:"prompt"`
+	Choices []string `bson:"choices"`
+	// Answer is the index of the correct choice
+	Answer      int    `bson:"answer"`
+	Explanation string `bson:"explanation,omitempty"`
+}
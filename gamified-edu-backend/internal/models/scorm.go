@@ -0,0 +1,62 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// SCORMPackage is an uploaded SCORM package. Its files are served locally and
+// each of its SCOs became a chapter with a scorm component.
+type SCORMPackage struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	CourseID       primitive.ObjectID `bson:"course_id"`
+	// Version is "1.2" or "2004"
+	Version    string             `bson:"version"`
+	Title      string             `bson:"title"`
+	SCOs       []SCORMSCO         `bson:"scos"`
+	UploadedBy primitive.ObjectID `bson:"uploaded_by"`
+	UploadedAt time.Time          `bson:"uploaded_at"`
+}
+
+// SCORMSCO is a launchable item of a package.
+type SCORMSCO struct {
+	Identifier string `bson:"identifier"`
+	Title      string `bson:"title"`
+	// Launch is the path of the SCO's start page within the package, with its parameters
+	Launch string `bson:"launch"`
+	// LaunchData is handed to the SCO as cmi.launch_data
+	LaunchData string `bson:"launch_data,omitempty"`
+}
+
+// SCORMAttempt is the runtime data a learner's sessions with a SCO left behind.
+type SCORMAttempt struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	CourseID       primitive.ObjectID `bson:"course_id"`
+	ChapterID      primitive.ObjectID `bson:"chapter_id"`
+	ComponentKey   string             `bson:"component_key"`
+	PackageID      primitive.ObjectID `bson:"package_id"`
+	SCOID          string             `bson:"sco_id"`
+	// Values are the CMI data model elements the SCO set, like cmi.suspend_data
+	Values []CMIValue `bson:"values"`
+	// TotalSeconds is the time spent in finished sessions
+	TotalSeconds float64 `bson:"total_seconds"`
+	// SessionSeconds is the session time reported by the session in progress
+	SessionSeconds float64 `bson:"session_seconds"`
+	// Suspended is set when the last session exited with suspend, so the next one resumes
+	Suspended bool `bson:"suspended"`
+	Completed bool `bson:"completed"`
+	// Score is the best score in percent the SCO reported
+	Score     *int      `bson:"score,omitempty"`
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// CMIValue is one element of the SCORM runtime data model. Elements contain
+// dots, so they are stored as a list rather than as document keys.
+type CMIValue struct {
+	Element string `bson:"element"`
+	Value   string `bson:"value"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// SecurityPolicyID is the _id of the single platform-wide policy document.
+const SecurityPolicyID = "security"
+
+// SecurityPolicy holds platform-wide security settings managed by admins.
+type SecurityPolicy struct {
+	ID                     string             `bson:"_id"`
+	TwoFactorRequiredRoles []string           `bson:"two_factor_required_roles"`
+	UpdatedBy              primitive.ObjectID `bson:"updated_by,omitempty"`
+	UpdatedAt              time.Time          `bson:"updated_at"`
+}
+
+// RequiresTwoFactor reports whether users with the given role must use 2FA.
+func (p *SecurityPolicy) RequiresTwoFactor(role string) bool {
+	for _, r := range p.TwoFactorRequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Slugify turns a title into lowercase words joined by single dashes. It
+// returns fallback when the title has no letters or digits.
+func Slugify(title, fallback string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	slug := builder.String()
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		return fallback
+	}
+	return slug
+}
+
+// UniqueSlug returns slug, or slug-2, slug-3 and so on, whichever is not in
+// taken yet, and adds it to taken.
+func UniqueSlug(slug string, taken map[string]bool) string {
+	candidate := slug
+	for i := 2; taken[candidate]; i++ {
+		candidate = slug + "-" + strconv.Itoa(i)
+	}
+	taken[candidate] = true
+	return candidate
+}
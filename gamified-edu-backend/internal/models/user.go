@@ -1,14 +1,88 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+    "go.mongodb.org/mongo-driver/bson/primitive"
+    "time"
+)
+
+// Roles a user can hold. Accounts created before roles existed have an empty
+// role and are treated as students.
+const (
+    RoleStudent    = "student"
+    RoleInstructor = "instructor"
+    RoleAdmin      = "admin"
+    // RoleGuardian is held by parents who follow linked students read-only
+    RoleGuardian   = "guardian"
+    // RoleOperator runs the platform: it sets up organizations and moves
+    // users between them. Admins only manage their own organization.
+    RoleOperator   = "operator"
+)
 
 // User now includes XP and Level for gamification
 type User struct {
-    ID           primitive.ObjectID `bson:"_id,omitempty"`
-    FirstName    string             `bson:"first_name"`
-    LastName     string             `bson:"last_name"`
-    Email        string             `bson:"email"`
-    PasswordHash string             `bson:"password_hash"`
-    XP           int                `bson:"xp"`    // New field for experience points
-    Level        int                `bson:"level"` // New field for user level
-}
\ No newline at end of file
+    ID             primitive.ObjectID `bson:"_id,omitempty"`
+    FirstName      string             `bson:"first_name"`
+    LastName       string             `bson:"last_name"`
+    Email          string             `bson:"email"`
+    PasswordHash   string             `bson:"password_hash"`
+    Role           string             `bson:"role"`
+    // OrganizationID is the school the user belongs to. The user's courses,
+    // progress and activities are all scoped to it.
+    OrganizationID primitive.ObjectID `bson:"organization_id"`
+    XP             int                `bson:"xp"`    // New field for experience points
+    Level          int                `bson:"level"` // New field for user level
+    TwoFactor      TwoFactorSettings  `bson:"two_factor"`
+    Profile        UserProfile        `bson:"profile"`
+    CreatedAt      time.Time          `bson:"created_at"`
+}
+
+// UserProfile holds the user-editable settings exposed through /me.
+type UserProfile struct {
+    DisplayName   string                  `bson:"display_name,omitempty"`
+    AvatarRef     string                  `bson:"avatar_ref,omitempty"` // URL or asset ID of the avatar image
+    TimeZone      string                  `bson:"time_zone,omitempty"`  // IANA name, e.g. "Europe/Berlin"
+    Locale        string                  `bson:"locale,omitempty"`     // BCP 47 tag, e.g. "en-US"
+    Notifications NotificationPreferences `bson:"notifications"`
+}
+
+type NotificationPreferences struct {
+    ProgressSummaries bool `bson:"progress_summaries" json:"progress_summaries"`
+    StreakReminders   bool `bson:"streak_reminders" json:"streak_reminders"`
+    ProductUpdates    bool `bson:"product_updates" json:"product_updates"`
+}
+
+// DefaultNotificationPreferences are applied to newly registered users.
+func DefaultNotificationPreferences() NotificationPreferences {
+    return NotificationPreferences{ProgressSummaries: true, StreakReminders: true}
+}
+
+// TwoFactorSettings holds the TOTP enrollment state of a user.
+type TwoFactorSettings struct {
+    Enabled bool `bson:"enabled"`
+    // Secret is set during enrollment and only becomes active once Enabled is true
+    Secret string `bson:"secret,omitempty"`
+    // LastUsedStep is the last accepted TOTP time step, used to prevent code replay
+    LastUsedStep int64 `bson:"last_used_step,omitempty"`
+    // RecoveryCodeHashes are bcrypt hashes of unused one-time recovery codes
+    RecoveryCodeHashes []string `bson:"recovery_code_hashes,omitempty"`
+    // FailedAttempts counts the invalid codes since the last valid one; too
+    // many lock the second factor until LockedUntil
+    FailedAttempts int        `bson:"failed_attempts,omitempty"`
+    LockedUntil    *time.Time `bson:"locked_until,omitempty"`
+}
+
+// EffectiveRole returns the user's role, defaulting legacy accounts to student.
+func (u *User) EffectiveRole() string {
+    if u.Role == "" {
+        return RoleStudent
+    }
+    return u.Role
+}
+
+// DisplayNameOrFullName returns the chosen display name, falling back to first and last name.
+func (u *User) DisplayNameOrFullName() string {
+    if u.Profile.DisplayName != "" {
+        return u.Profile.DisplayName
+    }
+    return u.FirstName + " " + u.LastName
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// XAPIStatement is a learning record in xAPI format, emitted for a learning
+// event or received by the built-in LRS. The statement is kept as the JSON
+// it is served as; the fields next to it are what the LRS queries by.
+type XAPIStatement struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `bson:"organization_id"`
+	// StatementID is the statement's own UUID
+	StatementID string `bson:"statement_id"`
+	Statement   string `bson:"statement"`
+	// ActorKey identifies the actor by its inverse functional identifier, see xapi.Agent.Key
+	ActorKey string `bson:"actor_key"`
+	// UserID is the learner the actor's account names, unset for other actors
+	UserID       primitive.ObjectID `bson:"user_id,omitempty"`
+	VerbID       string             `bson:"verb_id"`
+	ActivityID   string             `bson:"activity_id,omitempty"`
+	Registration string             `bson:"registration,omitempty"`
+	Voided       bool               `bson:"voided"`
+	Timestamp    time.Time          `bson:"timestamp"`
+	Stored       time.Time          `bson:"stored"`
+	Forward      XAPIForwarding     `bson:"forward"`
+}
+
+// XAPIForwarding tracks the delivery of a statement to the configured LRS.
+type XAPIForwarding struct {
+	// Pending is set until the statement was delivered or delivery was given up
+	Pending       bool       `bson:"pending"`
+	Attempts      int        `bson:"attempts"`
+	NextAttemptAt time.Time  `bson:"next_attempt_at"`
+	ForwardedAt   *time.Time `bson:"forwarded_at,omitempty"`
+	LastError     string     `bson:"last_error,omitempty"`
+}
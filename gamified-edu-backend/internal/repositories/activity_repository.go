@@ -10,25 +10,26 @@ import (
 
 // ActivityRepository is the interface for activity data operations.
 type ActivityRepository interface {
-	LogActivity(userID primitive.ObjectID) error
+	LogActivity(ctx context.Context, userID primitive.ObjectID) error
 }
 
 type activityRepository struct {
-	collection *mongo.Collection
+	collection *scopedCollection
 }
 
 // NewActivityRepository creates a new repository for activities.
 func NewActivityRepository(db *mongo.Database) ActivityRepository {
-	return &activityRepository{collection: db.Collection("activities")}
+	return &activityRepository{collection: newScopedCollection(db.Collection("activities"))}
 }
 
-// LogActivity inserts a new document with the user's ID and the current time.
-func (r *activityRepository) LogActivity(userID primitive.ObjectID) error {
+// LogActivity inserts a new document with the user's ID and the current time
+// into the organization in ctx.
+func (r *activityRepository) LogActivity(ctx context.Context, userID primitive.ObjectID) error {
 	activity := models.UserActivity{
 		UserID:    userID,
 		Timestamp: time.Now(),
 	}
 
-	_, err := r.collection.InsertOne(context.Background(), activity)
+	_, err := r.collection.InsertOne(ctx, activity)
 	return err
 }
\ No newline at end of file
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	FindByPrefix(prefix string) (*models.APIKey, error)
+	// FindPageByUser returns up to limit of the user's personal keys created
+	// before the key with ID before, or the newest with a nil before
+	FindPageByUser(userID, before primitive.ObjectID, limit int) ([]models.APIKey, error)
+	// FindPageByOrganization pages the organization-owned keys the same way
+	FindPageByOrganization(organizationID, before primitive.ObjectID, limit int) ([]models.APIKey, error)
+	RevokeUserKey(userID, keyID primitive.ObjectID) (bool, error)
+	RevokeOrganizationKey(organizationID, keyID primitive.ObjectID) (bool, error)
+	RecordUsage(keyID primitive.ObjectID, lastUsedAt time.Time, lastUsedIP string, uses int64) error
+}
+
+type apiKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository(db *mongo.Database) APIKeyRepository {
+	return &apiKeyRepository{collection: db.Collection("api_keys")}
+}
+
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	result, err := r.collection.InsertOne(context.Background(), key)
+	if err != nil {
+		return err
+	}
+	key.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *apiKeyRepository) FindByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOne(context.Background(), bson.M{"prefix": prefix}).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindPageByUser(userID, before primitive.ObjectID, limit int) ([]models.APIKey, error) {
+	return r.findPage(bson.M{"owner_type": models.APIKeyOwnerUser, "user_id": userID}, before, limit)
+}
+
+func (r *apiKeyRepository) FindPageByOrganization(organizationID, before primitive.ObjectID, limit int) ([]models.APIKey, error) {
+	return r.findPage(bson.M{"owner_type": models.APIKeyOwnerOrganization, "organization_id": organizationID}, before, limit)
+}
+
+func (r *apiKeyRepository) findPage(filter bson.M, before primitive.ObjectID, limit int) ([]models.APIKey, error) {
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	// IDs grow with creation time
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	keys := []models.APIKey{}
+	if err := cursor.All(context.Background(), &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeUserKey revokes one of the user's personal keys and reports whether it existed.
+func (r *apiKeyRepository) RevokeUserKey(userID, keyID primitive.ObjectID) (bool, error) {
+	return r.revoke(bson.M{"_id": keyID, "owner_type": models.APIKeyOwnerUser, "user_id": userID})
+}
+
+// RevokeOrganizationKey revokes one of the organization's keys and reports whether it existed.
+func (r *apiKeyRepository) RevokeOrganizationKey(organizationID, keyID primitive.ObjectID) (bool, error) {
+	return r.revoke(bson.M{"_id": keyID, "owner_type": models.APIKeyOwnerOrganization, "organization_id": organizationID})
+}
+
+func (r *apiKeyRepository) revoke(filter bson.M) (bool, error) {
+	filter["revoked_at"] = bson.M{"$exists": false}
+	result, err := r.collection.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// RecordUsage stores the latest use of a key and adds uses to its usage counter.
+func (r *apiKeyRepository) RecordUsage(keyID primitive.ObjectID, lastUsedAt time.Time, lastUsedIP string, uses int64) error {
+	update := bson.M{
+		"$set": bson.M{"last_used_at": lastUsedAt, "last_used_ip": lastUsedIP},
+		"$inc": bson.M{"usage_count": uses},
+	}
+	_, err := r.collection.UpdateOne(context.Background(), bson.M{"_id": keyID}, update)
+	return err
+}
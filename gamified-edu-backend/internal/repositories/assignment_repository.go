@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AssignmentRepository stores classroom assignments, scoped to the organization in ctx.
+type AssignmentRepository interface {
+	Create(ctx context.Context, assignment *models.Assignment) error
+	FindByClassroom(ctx context.Context, classroomID primitive.ObjectID) ([]models.Assignment, error)
+	// FindPageByClassroom returns up to limit of the classroom's assignments
+	// after the given one, soonest due first
+	FindPageByClassroom(ctx context.Context, classroomID primitive.ObjectID, after *models.Assignment, limit int) ([]models.Assignment, error)
+	Delete(ctx context.Context, classroomID, assignmentID primitive.ObjectID) (bool, error)
+	DeleteByClassroom(ctx context.Context, classroomID primitive.ObjectID) error
+}
+
+type assignmentRepository struct {
+	collection *scopedCollection
+}
+
+func NewAssignmentRepository(db *mongo.Database) AssignmentRepository {
+	return &assignmentRepository{collection: newScopedCollection(db.Collection("assignments"))}
+}
+
+func (r *assignmentRepository) Create(ctx context.Context, assignment *models.Assignment) error {
+	id, err := r.collection.InsertOne(ctx, assignment)
+	if err != nil {
+		return err
+	}
+	assignment.ID = id
+	return nil
+}
+
+// FindByClassroom lists the classroom's assignments, soonest due first
+func (r *assignmentRepository) FindByClassroom(ctx context.Context, classroomID primitive.ObjectID) ([]models.Assignment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"classroom_id": classroomID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	assignments := []models.Assignment{}
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *assignmentRepository) FindPageByClassroom(ctx context.Context, classroomID primitive.ObjectID, after *models.Assignment, limit int) ([]models.Assignment, error) {
+	filter := bson.M{"classroom_id": classroomID}
+	if after != nil {
+		filter["$or"] = bson.A{
+			bson.M{"due_at": bson.M{"$gt": after.DueAt}},
+			bson.M{"due_at": after.DueAt, "_id": bson.M{"$gt": after.ID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	assignments := []models.Assignment{}
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *assignmentRepository) Delete(ctx context.Context, classroomID, assignmentID primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": assignmentID, "classroom_id": classroomID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *assignmentRepository) DeleteByClassroom(ctx context.Context, classroomID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"classroom_id": classroomID})
+	return err
+}
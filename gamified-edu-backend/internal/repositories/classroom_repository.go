@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// ClassroomRepository stores classrooms and their members, scoped to the organization in ctx.
+type ClassroomRepository interface {
+	Create(ctx context.Context, classroom *models.Classroom) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Classroom, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Classroom, error)
+	FindByJoinCode(ctx context.Context, code string) (*models.Classroom, error)
+	// FindPageForUser returns up to limit of the classrooms the user teaches
+	// or is a member of after the given one, by name
+	FindPageForUser(ctx context.Context, userID primitive.ObjectID, after *models.Classroom, limit int) ([]models.Classroom, error)
+	UpdateJoinCode(ctx context.Context, id primitive.ObjectID, code string) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	AddMember(ctx context.Context, classroomID, userID primitive.ObjectID) error
+	RemoveMember(ctx context.Context, classroomID, userID primitive.ObjectID) (bool, error)
+	IsMember(ctx context.Context, classroomID, userID primitive.ObjectID) (bool, error)
+	FindMembers(ctx context.Context, classroomID primitive.ObjectID) ([]models.ClassroomMember, error)
+}
+
+type classroomRepository struct {
+	classrooms *scopedCollection
+	members    *scopedCollection
+}
+
+func NewClassroomRepository(db *mongo.Database) ClassroomRepository {
+	return &classroomRepository{
+		classrooms: newScopedCollection(db.Collection("classrooms")),
+		members:    newScopedCollection(db.Collection("classroom_members")),
+	}
+}
+
+func (r *classroomRepository) Create(ctx context.Context, classroom *models.Classroom) error {
+	id, err := r.classrooms.InsertOne(ctx, classroom)
+	if err != nil {
+		return err
+	}
+	classroom.ID = id
+	return nil
+}
+
+func (r *classroomRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Classroom, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *classroomRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Classroom, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *classroomRepository) FindByJoinCode(ctx context.Context, code string) (*models.Classroom, error) {
+	return r.findOne(ctx, bson.M{"join_code": code})
+}
+
+func (r *classroomRepository) FindPageForUser(ctx context.Context, userID primitive.ObjectID, after *models.Classroom, limit int) ([]models.Classroom, error) {
+	memberships, err := r.findMembers(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	joined := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		joined = append(joined, membership.ClassroomID)
+	}
+	filter := bson.M{"$or": bson.A{bson.M{"instructor_id": userID}, bson.M{"_id": bson.M{"$in": joined}}}}
+	if after != nil {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$gt": after.Name}},
+			bson.M{"name": after.Name, "_id": bson.M{"$gt": after.ID}},
+		}}}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.classrooms.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	classrooms := []models.Classroom{}
+	if err := cursor.All(ctx, &classrooms); err != nil {
+		return nil, err
+	}
+	return classrooms, nil
+}
+
+func (r *classroomRepository) findOne(ctx context.Context, filter bson.M) (*models.Classroom, error) {
+	var classroom models.Classroom
+	if err := r.classrooms.FindOne(ctx, filter, &classroom); err != nil {
+		return nil, err
+	}
+	return &classroom, nil
+}
+
+func (r *classroomRepository) find(ctx context.Context, filter bson.M) ([]models.Classroom, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.classrooms.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	classrooms := []models.Classroom{}
+	if err := cursor.All(ctx, &classrooms); err != nil {
+		return nil, err
+	}
+	return classrooms, nil
+}
+
+func (r *classroomRepository) UpdateJoinCode(ctx context.Context, id primitive.ObjectID, code string) error {
+	_, err := r.classrooms.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"join_code": code}})
+	return err
+}
+
+// Delete removes the classroom together with its memberships
+func (r *classroomRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := r.members.DeleteMany(ctx, bson.M{"classroom_id": id}); err != nil {
+		return err
+	}
+	_, err := r.classrooms.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// AddMember adds the user to the classroom; joining twice is a no-op
+func (r *classroomRepository) AddMember(ctx context.Context, classroomID, userID primitive.ObjectID) error {
+	filter := bson.M{"classroom_id": classroomID, "user_id": userID}
+	update := bson.M{"$setOnInsert": bson.M{"joined_at": time.Now()}}
+	_, err := r.members.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *classroomRepository) RemoveMember(ctx context.Context, classroomID, userID primitive.ObjectID) (bool, error) {
+	result, err := r.members.DeleteMany(ctx, bson.M{"classroom_id": classroomID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *classroomRepository) IsMember(ctx context.Context, classroomID, userID primitive.ObjectID) (bool, error) {
+	count, err := r.members.CountDocuments(ctx, bson.M{"classroom_id": classroomID, "user_id": userID})
+	return count > 0, err
+}
+
+func (r *classroomRepository) FindMembers(ctx context.Context, classroomID primitive.ObjectID) ([]models.ClassroomMember, error) {
+	return r.findMembers(ctx, bson.M{"classroom_id": classroomID})
+}
+
+func (r *classroomRepository) findMembers(ctx context.Context, filter bson.M) ([]models.ClassroomMember, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "joined_at", Value: 1}})
+	cursor, err := r.members.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	members := []models.ClassroomMember{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
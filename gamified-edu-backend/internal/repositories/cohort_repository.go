@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CohortRepository stores the cohorts of courses, scoped to the organization in ctx.
+type CohortRepository interface {
+	Create(ctx context.Context, cohort *models.Cohort) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Cohort, error)
+	// FindPageByCourse returns up to limit of the course's cohorts after the
+	// given one, the first to start first
+	FindPageByCourse(ctx context.Context, courseID primitive.ObjectID, after *models.Cohort, limit int) ([]models.Cohort, error)
+	Update(ctx context.Context, cohort *models.Cohort) error
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+}
+
+type cohortRepository struct {
+	collection *scopedCollection
+}
+
+func NewCohortRepository(db *mongo.Database) CohortRepository {
+	return &cohortRepository{collection: newScopedCollection(db.Collection("cohorts"))}
+}
+
+func (r *cohortRepository) Create(ctx context.Context, cohort *models.Cohort) error {
+	id, err := r.collection.InsertOne(ctx, cohort)
+	if err != nil {
+		return err
+	}
+	cohort.ID = id
+	return nil
+}
+
+func (r *cohortRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Cohort, error) {
+	var cohort models.Cohort
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}, &cohort); err != nil {
+		return nil, err
+	}
+	return &cohort, nil
+}
+
+func (r *cohortRepository) FindPageByCourse(ctx context.Context, courseID primitive.ObjectID, after *models.Cohort, limit int) ([]models.Cohort, error) {
+	filter := bson.M{"course_id": courseID}
+	if after != nil {
+		filter["$or"] = bson.A{
+			bson.M{"starts_at": bson.M{"$gt": after.StartsAt}},
+			bson.M{"starts_at": after.StartsAt, "_id": bson.M{"$gt": after.ID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "starts_at", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	cohorts := []models.Cohort{}
+	if err := cursor.All(ctx, &cohorts); err != nil {
+		return nil, err
+	}
+	return cohorts, nil
+}
+
+func (r *cohortRepository) Update(ctx context.Context, cohort *models.Cohort) error {
+	update := bson.M{"$set": bson.M{
+		"name":       cohort.Name,
+		"starts_at":  cohort.StartsAt,
+		"updated_at": cohort.UpdatedAt,
+	}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": cohort.ID}, update)
+	return err
+}
+
+func (r *cohortRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
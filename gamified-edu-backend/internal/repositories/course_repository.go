@@ -3,41 +3,560 @@ package repositories
 import (
     "context"
     "gamified-edu-backend/internal/models"
+    "regexp"
+    "strings"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
     "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Orders of course pages
+const (
+    CourseOrderTitle   = "title"
+    CourseOrderNewest  = "newest"
+    CourseOrderPopular = "popular"
+)
+
+// CoursePageQuery selects a page of published courses.
+type CoursePageQuery struct {
+    // Order is one of the CourseOrder constants
+    Order string
+    // Only and Except restrict the courses by ID when not nil
+    Only   []primitive.ObjectID
+    Except []primitive.ObjectID
+    // Category and Tag keep the courses listed under them when not empty
+    Category string
+    Tag      string
+    // After is the last course of the previous page, nil for the first page
+    After *models.Course
+    Limit int
+}
+
+// CourseFacet is a category or tag and how many published courses have it.
+type CourseFacet struct {
+    Name        string `bson:"_id" json:"name"`
+    CourseCount int    `bson:"course_count" json:"course_count"`
+}
+
+// LinkedURL is a URL a course links in its content or as its thumbnail.
+type LinkedURL struct {
+    OrganizationID primitive.ObjectID
+    URL            string
+}
+
+// CourseRepository only ever sees the courses of the organization in ctx.
 type CourseRepository interface {
-    FindAll() ([]models.Course, error)
-    FindByID(id primitive.ObjectID) (*models.Course, error)
+    FindAll(ctx context.Context) ([]models.Course, error)
+    FindPublishedPage(ctx context.Context, query CoursePageQuery) ([]models.Course, error)
+    // CountCategories and CountTags return up to limit facets of published
+    // courses after the given one, most used first
+    CountCategories(ctx context.Context, after *CourseFacet, limit int) ([]CourseFacet, error)
+    CountTags(ctx context.Context, after *CourseFacet, limit int) ([]CourseFacet, error)
+    FindByID(ctx context.Context, id primitive.ObjectID) (*models.Course, error)
+    FindBySlug(ctx context.Context, slug string) (*models.Course, error)
+    Create(ctx context.Context, course *models.Course) error
+    UpdateSettings(ctx context.Context, course *models.Course) error
+    AdjustEnrollmentCount(ctx context.Context, courseID primitive.ObjectID, delta int) error
+    SetRating(ctx context.Context, courseID primitive.ObjectID, rating models.CourseRating) error
+    SaveDraft(ctx context.Context, courseID primitive.ObjectID, draft *models.CourseDraft) error
+    DiscardDraft(ctx context.Context, courseID primitive.ObjectID) (bool, error)
+    Publish(ctx context.Context, courseID primitive.ObjectID, fromVersion int, content models.CourseContent, clearDraft bool) (bool, error)
+    WrapChaptersInModules() error
+    MoveChapterActivitiesToComponents() error
+    AssignSlugs() error
+    RecountEnrollments() error
+    // FindLinkedURLs and ReplaceLinkedURL work across organizations, for
+    // migrations. They cover the published content, drafts and thumbnails.
+    FindLinkedURLs(prefix string) ([]LinkedURL, error)
+    ReplaceLinkedURL(organizationID primitive.ObjectID, oldURL, newURL string) error
 }
 
 type courseRepository struct {
-    collection *mongo.Collection
+    collection *scopedCollection
+    // all is the unscoped collection, only for migrations across organizations
+    all *mongo.Collection
 }
 
 func NewCourseRepository(db *mongo.Database) CourseRepository {
-    return &courseRepository{collection: db.Collection("courses")}
+    return &courseRepository{collection: newScopedCollection(db.Collection("courses")), all: db.Collection("courses")}
 }
 
-func (r *courseRepository) FindAll() ([]models.Course, error) {
+func (r *courseRepository) FindAll(ctx context.Context) ([]models.Course, error) {
     var courses []models.Course
-    cursor, err := r.collection.Find(context.Background(), bson.M{})
+    cursor, err := r.collection.Find(ctx, bson.M{})
     if err != nil {
         return nil, err
     }
-    if err = cursor.All(context.Background(), &courses); err != nil {
+    if err = cursor.All(ctx, &courses); err != nil {
         return nil, err
     }
     return courses, nil
 }
 
-func (r *courseRepository) FindByID(id primitive.ObjectID) (*models.Course, error) {
+// titleCollation compares titles ignoring case, in sorts and in the filters resuming them
+var titleCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// FindPublishedPage returns up to query.Limit published courses following
+// query.After in query.Order. Ties are broken by ID so every course has one place.
+func (r *courseRepository) FindPublishedPage(ctx context.Context, query CoursePageQuery) ([]models.Course, error) {
+    filter := bson.M{"published_version": bson.M{"$gt": 0}}
+    ids := bson.M{}
+    if query.Only != nil {
+        ids["$in"] = query.Only
+    }
+    if query.Except != nil {
+        ids["$nin"] = query.Except
+    }
+    if len(ids) > 0 {
+        filter["_id"] = ids
+    }
+    if query.Category != "" {
+        filter["categories"] = query.Category
+    }
+    if query.Tag != "" {
+        filter["tags"] = query.Tag
+    }
+
+    opts := options.Find().SetLimit(int64(query.Limit))
+    var after bson.M
+    switch query.Order {
+    case CourseOrderNewest:
+        opts.SetSort(bson.D{{Key: "_id", Value: -1}})
+        if query.After != nil {
+            after = bson.M{"_id": bson.M{"$lt": query.After.ID}}
+        }
+    case CourseOrderPopular:
+        opts.SetSort(bson.D{{Key: "enrollment_count", Value: -1}, {Key: "_id", Value: 1}})
+        if query.After != nil {
+            after = bson.M{"$or": []bson.M{
+                {"enrollment_count": bson.M{"$lt": query.After.EnrollmentCount}},
+                {"enrollment_count": query.After.EnrollmentCount, "_id": bson.M{"$gt": query.After.ID}},
+            }}
+        }
+    default:
+        opts.SetSort(bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}}).SetCollation(titleCollation)
+        if query.After != nil {
+            after = bson.M{"$or": []bson.M{
+                {"title": bson.M{"$gt": query.After.Title}},
+                {"title": query.After.Title, "_id": bson.M{"$gt": query.After.ID}},
+            }}
+        }
+    }
+    if after != nil {
+        filter = bson.M{"$and": []bson.M{filter, after}}
+    }
+
+    cursor, err := r.collection.Find(ctx, filter, opts)
+    if err != nil {
+        return nil, err
+    }
+    courses := []models.Course{}
+    if err := cursor.All(ctx, &courses); err != nil {
+        return nil, err
+    }
+    return courses, nil
+}
+
+func (r *courseRepository) CountCategories(ctx context.Context, after *CourseFacet, limit int) ([]CourseFacet, error) {
+    return r.countFacet(ctx, "categories", after, limit)
+}
+
+func (r *courseRepository) CountTags(ctx context.Context, after *CourseFacet, limit int) ([]CourseFacet, error) {
+    return r.countFacet(ctx, "tags", after, limit)
+}
+
+func (r *courseRepository) countFacet(ctx context.Context, field string, after *CourseFacet, limit int) ([]CourseFacet, error) {
+    pipeline := mongo.Pipeline{
+        {{Key: "$match", Value: bson.M{"published_version": bson.M{"$gt": 0}}}},
+        {{Key: "$unwind", Value: "$" + field}},
+        {{Key: "$group", Value: bson.M{"_id": "$" + field, "course_count": bson.M{"$sum": 1}}}},
+    }
+    if after != nil {
+        pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+            bson.M{"course_count": bson.M{"$lt": after.CourseCount}},
+            bson.M{"course_count": after.CourseCount, "_id": bson.M{"$gt": after.Name}},
+        }}}})
+    }
+    pipeline = append(pipeline,
+        bson.D{{Key: "$sort", Value: bson.D{{Key: "course_count", Value: -1}, {Key: "_id", Value: 1}}}},
+        bson.D{{Key: "$limit", Value: limit}},
+    )
+    cursor, err := r.collection.Aggregate(ctx, pipeline)
+    if err != nil {
+        return nil, err
+    }
+    facets := []CourseFacet{}
+    if err := cursor.All(ctx, &facets); err != nil {
+        return nil, err
+    }
+    return facets, nil
+}
+
+func (r *courseRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Course, error) {
     var course models.Course
-    err := r.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&course)
+    err := r.collection.FindOne(ctx, bson.M{"_id": id}, &course)
     if err != nil {
         return nil, err
     }
     return &course, nil
-}
\ No newline at end of file
+}
+
+func (r *courseRepository) FindBySlug(ctx context.Context, slug string) (*models.Course, error) {
+    var course models.Course
+    err := r.collection.FindOne(ctx, bson.M{"slug": slug}, &course)
+    if err != nil {
+        return nil, err
+    }
+    return &course, nil
+}
+
+// Create stores a new course in the organization in ctx and sets its ID
+func (r *courseRepository) Create(ctx context.Context, course *models.Course) error {
+    id, err := r.collection.InsertOne(ctx, course)
+    if err != nil {
+        return err
+    }
+    course.ID = id
+    return nil
+}
+
+// UpdateSettings saves how the course is unlocked and released, how its
+// progress is weighted and its catalog metadata
+func (r *courseRepository) UpdateSettings(ctx context.Context, course *models.Course) error {
+    update := bson.M{"$set": bson.M{
+        "sequential_chapters":      course.SequentialChapters,
+        "hide_unreleased_chapters": course.HideUnreleasedChapters,
+        "prerequisite_ids":         course.PrerequisiteIDs,
+        "progress_weighting":       course.ProgressWeighting,
+        "categories":               course.Categories,
+        "tags":                     course.Tags,
+        "difficulty":               course.Difficulty,
+        "language":                 course.Language,
+        "thumbnail":                course.Thumbnail,
+        "author_ids":               course.AuthorIDs,
+    }}
+    _, err := r.collection.UpdateOne(ctx, bson.M{"_id": course.ID}, update)
+    return err
+}
+
+func (r *courseRepository) AdjustEnrollmentCount(ctx context.Context, courseID primitive.ObjectID, delta int) error {
+    _, err := r.collection.UpdateOne(ctx, bson.M{"_id": courseID}, bson.M{"$inc": bson.M{"enrollment_count": delta}})
+    return err
+}
+
+func (r *courseRepository) SetRating(ctx context.Context, courseID primitive.ObjectID, rating models.CourseRating) error {
+    _, err := r.collection.UpdateOne(ctx, bson.M{"_id": courseID}, bson.M{"$set": bson.M{"rating": rating}})
+    return err
+}
+
+// SaveDraft replaces the draft of the course
+func (r *courseRepository) SaveDraft(ctx context.Context, courseID primitive.ObjectID, draft *models.CourseDraft) error {
+    _, err := r.collection.UpdateOne(ctx, bson.M{"_id": courseID}, bson.M{"$set": bson.M{"draft": draft}})
+    return err
+}
+
+// DiscardDraft reports false if the course had no draft
+func (r *courseRepository) DiscardDraft(ctx context.Context, courseID primitive.ObjectID) (bool, error) {
+    filter := bson.M{"_id": courseID, "draft": bson.M{"$exists": true}}
+    result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"draft": ""}})
+    if err != nil {
+        return false, err
+    }
+    return result.ModifiedCount > 0, nil
+}
+
+// Publish makes content the live content of the course as version
+// fromVersion+1. It reports false, and changes nothing, if the published
+// version is no longer fromVersion because someone else published meanwhile.
+func (r *courseRepository) Publish(ctx context.Context, courseID primitive.ObjectID, fromVersion int, content models.CourseContent, clearDraft bool) (bool, error) {
+    filter := bson.M{"_id": courseID, "published_version": fromVersion}
+    update := bson.M{"$set": bson.M{
+        "title":             content.Title,
+        "description":       content.Description,
+        "modules":           content.Modules,
+        "published_version": fromVersion + 1,
+    }}
+    if clearDraft {
+        update["$unset"] = bson.M{"draft": ""}
+    }
+    result, err := r.collection.UpdateOne(ctx, filter, update)
+    if err != nil {
+        return false, err
+    }
+    return result.MatchedCount > 0, nil
+}
+
+// WrapChaptersInModules migrates courses written before modules existed, in
+// every organization, by moving their flat chapter list into a single default module.
+func (r *courseRepository) WrapChaptersInModules() error {
+    ctx := context.Background()
+    filter := bson.M{"modules": bson.M{"$exists": false}}
+    cursor, err := r.all.Find(ctx, filter)
+    if err != nil {
+        return err
+    }
+    var legacy []struct {
+        ID       primitive.ObjectID `bson:"_id"`
+        Chapters []models.Chapter   `bson:"chapters"`
+    }
+    if err := cursor.All(ctx, &legacy); err != nil {
+        return err
+    }
+
+    for _, course := range legacy {
+        chapters := course.Chapters
+        if chapters == nil {
+            chapters = []models.Chapter{}
+        }
+        module := models.Module{ID: primitive.NewObjectID(), Title: models.DefaultModuleTitle, Position: 1, Chapters: chapters}
+        update := bson.M{
+            "$set":   bson.M{"modules": []models.Module{module}},
+            "$unset": bson.M{"chapters": ""},
+        }
+        // Matching on the missing field again keeps concurrent runs from wrapping twice
+        if _, err := r.all.UpdateOne(ctx, bson.M{"_id": course.ID, "modules": bson.M{"$exists": false}}, update); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// MoveChapterActivitiesToComponents migrates chapters written before
+// components existed, in every organization. Their video, quiz and slides
+// become components with the keys "video", "quiz" and "ppt", which older
+// clients still post progress to.
+func (r *courseRepository) MoveChapterActivitiesToComponents() error {
+    ctx := context.Background()
+    filter := bson.M{"$or": []bson.M{
+        {"modules.chapters.video_url": bson.M{"$exists": true}},
+        {"modules.chapters.quiz_id": bson.M{"$exists": true}},
+        {"modules.chapters.ppt_link": bson.M{"$exists": true}},
+    }}
+    cursor, err := r.all.Find(ctx, filter)
+    if err != nil {
+        return err
+    }
+    type legacyChapter struct {
+        models.Chapter `bson:",inline"`
+        VideoURL       string `bson:"video_url"`
+        QuizID         string `bson:"quiz_id"`
+        PPTLink        string `bson:"ppt_link"`
+    }
+    var legacy []struct {
+        ID      primitive.ObjectID `bson:"_id"`
+        Modules []struct {
+            ID       primitive.ObjectID `bson:"_id"`
+            Title    string             `bson:"title"`
+            Position int                `bson:"position"`
+            Chapters []legacyChapter    `bson:"chapters"`
+        } `bson:"modules"`
+    }
+    if err := cursor.All(ctx, &legacy); err != nil {
+        return err
+    }
+
+    for _, course := range legacy {
+        modules := make([]models.Module, 0, len(course.Modules))
+        for _, module := range course.Modules {
+            chapters := make([]models.Chapter, 0, len(module.Chapters))
+            for _, old := range module.Chapters {
+                chapter := old.Chapter
+                // Chapters that already have components keep them as they are
+                if chapter.Components == nil {
+                    chapter.Components = []models.ChapterComponent{}
+                    if old.VideoURL != "" {
+                        chapter.Components = append(chapter.Components, models.ChapterComponent{Key: "video", Type: models.ComponentVideo, Title: "Video", URL: old.VideoURL})
+                    }
+                    if old.QuizID != "" {
+                        chapter.Components = append(chapter.Components, models.ChapterComponent{Key: "quiz", Type: models.ComponentQuiz, Title: "Quiz", QuizID: old.QuizID})
+                    }
+                    if old.PPTLink != "" {
+                        chapter.Components = append(chapter.Components, models.ChapterComponent{Key: "ppt", Type: models.ComponentSlides, Title: "Slides", URL: old.PPTLink})
+                    }
+                }
+                chapters = append(chapters, chapter)
+            }
+            modules = append(modules, models.Module{ID: module.ID, Title: module.Title, Position: module.Position, Chapters: chapters})
+        }
+        if _, err := r.all.UpdateOne(ctx, bson.M{"_id": course.ID}, bson.M{"$set": bson.M{"modules": modules}}); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// AssignSlugs migrates courses, modules and chapters written before they had
+// slugs, in every organization. Slugs are derived from titles and made unique
+// per organization for courses and per course for modules and chapters.
+func (r *courseRepository) AssignSlugs() error {
+    ctx := context.Background()
+    cursor, err := r.all.Find(ctx, bson.M{})
+    if err != nil {
+        return err
+    }
+    var courses []models.Course
+    if err := cursor.All(ctx, &courses); err != nil {
+        return err
+    }
+
+    taken := map[primitive.ObjectID]map[string]bool{}
+    for _, course := range courses {
+        if taken[course.OrganizationID] == nil {
+            taken[course.OrganizationID] = map[string]bool{}
+        }
+        if course.Slug != "" {
+            taken[course.OrganizationID][course.Slug] = true
+        }
+    }
+
+    for _, course := range courses {
+        set := bson.M{}
+        if course.Slug == "" {
+            set["slug"] = models.UniqueSlug(models.Slugify(course.Title, "course"), taken[course.OrganizationID])
+        }
+        content := course.Content()
+        if missingSlugs(content.Modules) {
+            content.AssignSlugs(nil)
+            set["modules"] = content.Modules
+        }
+        if course.Draft != nil && missingSlugs(course.Draft.Modules) {
+            course.Draft.AssignSlugs(content.Slugs())
+            set["draft.modules"] = course.Draft.Modules
+        }
+        if len(set) == 0 {
+            continue
+        }
+        if _, err := r.all.UpdateOne(ctx, bson.M{"_id": course.ID}, bson.M{"$set": set}); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func missingSlugs(modules []models.Module) bool {
+    for _, module := range modules {
+        if module.Slug == "" {
+            return true
+        }
+        for _, chapter := range module.Chapters {
+            if chapter.Slug == "" {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// RecountEnrollments sets the enrollment count of every course, in every
+// organization, from its active enrollments, for enrollments made without
+// AdjustEnrollmentCount such as backfilled ones.
+func (r *courseRepository) RecountEnrollments() error {
+    ctx := context.Background()
+    if _, err := r.all.UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"enrollment_count": 0}}); err != nil {
+        return err
+    }
+    pipeline := mongo.Pipeline{
+        {{Key: "$match", Value: bson.M{"unenrolled_at": bson.M{"$exists": false}}}},
+        {{Key: "$group", Value: bson.M{"_id": "$course_id", "enrollment_count": bson.M{"$sum": 1}}}},
+        {{Key: "$merge", Value: bson.M{"into": r.all.Name(), "on": "_id", "whenMatched": "merge", "whenNotMatched": "discard"}}},
+    }
+    cursor, err := r.all.Database().Collection("enrollments").Aggregate(ctx, pipeline)
+    if err != nil {
+        return err
+    }
+    return cursor.Close(ctx)
+}
+
+func (r *courseRepository) FindLinkedURLs(prefix string) ([]LinkedURL, error) {
+    return findLinkedURLs(r.all, prefix)
+}
+
+func (r *courseRepository) ReplaceLinkedURL(organizationID primitive.ObjectID, oldURL, newURL string) error {
+    return replaceLinkedURL(r.all, organizationID, oldURL, newURL)
+}
+
+// linkedContent is the part of course content that links URLs
+type linkedContent struct {
+    Modules []struct {
+        Chapters []struct {
+            Components []struct {
+                URL string `bson:"url"`
+            } `bson:"components"`
+        } `bson:"chapters"`
+    } `bson:"modules"`
+}
+
+// findLinkedURLs returns the distinct URLs starting with prefix that the
+// content, draft or thumbnail of documents in collection link, which holds
+// courses or course versions
+func findLinkedURLs(collection *mongo.Collection, prefix string) ([]LinkedURL, error) {
+    ctx := context.Background()
+    pattern := bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}
+    filter := bson.M{"$or": []bson.M{
+        {"modules.chapters.components.url": pattern},
+        {"draft.modules.chapters.components.url": pattern},
+        {"thumbnail": pattern},
+    }}
+    cursor, err := collection.Find(ctx, filter)
+    if err != nil {
+        return nil, err
+    }
+    var documents []struct {
+        OrganizationID primitive.ObjectID `bson:"organization_id"`
+        linkedContent  `bson:",inline"`
+        Draft          *linkedContent `bson:"draft"`
+        Thumbnail      string         `bson:"thumbnail"`
+    }
+    if err := cursor.All(ctx, &documents); err != nil {
+        return nil, err
+    }
+
+    seen := map[LinkedURL]bool{}
+    linked := []LinkedURL{}
+    add := func(organizationID primitive.ObjectID, url string) {
+        entry := LinkedURL{OrganizationID: organizationID, URL: url}
+        if strings.HasPrefix(url, prefix) && !seen[entry] {
+            seen[entry] = true
+            linked = append(linked, entry)
+        }
+    }
+    for _, document := range documents {
+        contents := []linkedContent{document.linkedContent}
+        if document.Draft != nil {
+            contents = append(contents, *document.Draft)
+        }
+        for _, content := range contents {
+            for _, module := range content.Modules {
+                for _, chapter := range module.Chapters {
+                    for _, component := range chapter.Components {
+                        add(document.OrganizationID, component.URL)
+                    }
+                }
+            }
+        }
+        add(document.OrganizationID, document.Thumbnail)
+    }
+    return linked, nil
+}
+
+// replaceLinkedURL makes the organization's documents in collection link
+// newURL wherever they linked oldURL
+func replaceLinkedURL(collection *mongo.Collection, organizationID primitive.ObjectID, oldURL, newURL string) error {
+    ctx := context.Background()
+    // Array filters only descend into the modules and chapters that link the URL
+    arrayFilters := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+        bson.M{"module.chapters.components.url": oldURL},
+        bson.M{"chapter.components.url": oldURL},
+        bson.M{"component.url": oldURL},
+    }})
+    for _, content := range []string{"", "draft."} {
+        filter := bson.M{"organization_id": organizationID, content + "modules.chapters.components.url": oldURL}
+        update := bson.M{"$set": bson.M{content + "modules.$[module].chapters.$[chapter].components.$[component].url": newURL}}
+        if _, err := collection.UpdateMany(ctx, filter, update, arrayFilters); err != nil {
+            return err
+        }
+    }
+    _, err := collection.UpdateMany(ctx, bson.M{"organization_id": organizationID, "thumbnail": oldURL}, bson.M{"$set": bson.M{"thumbnail": newURL}})
+    return err
+}
@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// ReviewPageQuery selects a page of reviews, newest first.
+type ReviewPageQuery struct {
+	// CourseID keeps the reviews of one course; zero lists every course's
+	CourseID primitive.ObjectID
+	// Status keeps the reviews in one moderation state; empty lists all of them
+	Status string
+	// Before resumes after the review with this ID
+	Before primitive.ObjectID
+	Limit  int
+}
+
+// CourseReviewRepository stores course reviews and the reports on them,
+// scoped to the organization in ctx.
+type CourseReviewRepository interface {
+	// Save creates the user's review of the course or updates its rating and
+	// body. New reviews start out visible; edits keep the moderation state.
+	Save(ctx context.Context, review *models.CourseReview) (*models.CourseReview, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.CourseReview, error)
+	// FindByUserAndCourse returns nil if the user has not reviewed the course
+	FindByUserAndCourse(ctx context.Context, userID, courseID primitive.ObjectID) (*models.CourseReview, error)
+	// FindCoursesReviewedBy returns the IDs of the courses the user reviewed
+	FindCoursesReviewedBy(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	FindPage(ctx context.Context, query ReviewPageQuery) ([]models.CourseReview, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+	SetReply(ctx context.Context, id primitive.ObjectID, reply *models.ReviewReply) (bool, error)
+	Moderate(ctx context.Context, id primitive.ObjectID, status string, moderatorID primitive.ObjectID, note string, at time.Time) (bool, error)
+	// Flag moves a visible review to flagged, and reports whether it did
+	Flag(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// RatingStats sums up the visible reviews of the course
+	RatingStats(ctx context.Context, courseID primitive.ObjectID) (models.CourseRating, error)
+
+	// AddReport records the user's report of the review, and reports false if they had already reported it
+	AddReport(ctx context.Context, report *models.ReviewReport) (bool, error)
+	// CountReports counts the reports on the review made after since, or all of them for a nil since
+	CountReports(ctx context.Context, reviewID primitive.ObjectID, since *time.Time) (int64, error)
+	DeleteReports(ctx context.Context, reviewID primitive.ObjectID) error
+}
+
+type courseReviewRepository struct {
+	reviews *scopedCollection
+	reports *scopedCollection
+}
+
+func NewCourseReviewRepository(db *mongo.Database) CourseReviewRepository {
+	return &courseReviewRepository{
+		reviews: newScopedCollection(db.Collection("course_reviews")),
+		reports: newScopedCollection(db.Collection("review_reports")),
+	}
+}
+
+func (r *courseReviewRepository) Save(ctx context.Context, review *models.CourseReview) (*models.CourseReview, error) {
+	filter := bson.M{"user_id": review.UserID, "course_id": review.CourseID}
+	update := bson.M{
+		"$set": bson.M{
+			"rating":     review.Rating,
+			"body":       review.Body,
+			"updated_at": review.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"status":     models.ReviewVisible,
+			"created_at": review.UpdatedAt,
+		},
+	}
+	if _, err := r.reviews.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return nil, err
+	}
+	return r.FindByUserAndCourse(ctx, review.UserID, review.CourseID)
+}
+
+func (r *courseReviewRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.CourseReview, error) {
+	var review models.CourseReview
+	if err := r.reviews.FindOne(ctx, bson.M{"_id": id}, &review); err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *courseReviewRepository) FindByUserAndCourse(ctx context.Context, userID, courseID primitive.ObjectID) (*models.CourseReview, error) {
+	var review models.CourseReview
+	err := r.reviews.FindOne(ctx, bson.M{"user_id": userID, "course_id": courseID}, &review)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *courseReviewRepository) FindCoursesReviewedBy(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"course_id": 1})
+	cursor, err := r.reviews.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reviews []models.CourseReview
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+	courseIDs := make([]primitive.ObjectID, 0, len(reviews))
+	for _, review := range reviews {
+		courseIDs = append(courseIDs, review.CourseID)
+	}
+	return courseIDs, nil
+}
+
+func (r *courseReviewRepository) FindPage(ctx context.Context, query ReviewPageQuery) ([]models.CourseReview, error) {
+	filter := bson.M{}
+	if !query.CourseID.IsZero() {
+		filter["course_id"] = query.CourseID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if !query.Before.IsZero() {
+		filter["_id"] = bson.M{"$lt": query.Before}
+	}
+	// IDs grow with creation time
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(query.Limit))
+	cursor, err := r.reviews.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	reviews := []models.CourseReview{}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+func (r *courseReviewRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.reviews.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// SetReply replaces the reply to the review, or removes it for a nil reply
+func (r *courseReviewRepository) SetReply(ctx context.Context, id primitive.ObjectID, reply *models.ReviewReply) (bool, error) {
+	update := bson.M{"$set": bson.M{"reply": reply}}
+	if reply == nil {
+		update = bson.M{"$unset": bson.M{"reply": ""}}
+	}
+	result, err := r.reviews.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *courseReviewRepository) Moderate(ctx context.Context, id primitive.ObjectID, status string, moderatorID primitive.ObjectID, note string, at time.Time) (bool, error) {
+	update := bson.M{"$set": bson.M{
+		"status":          status,
+		"moderated_at":    at,
+		"moderated_by":    moderatorID,
+		"moderation_note": note,
+	}}
+	result, err := r.reviews.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *courseReviewRepository) Flag(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "status": models.ReviewVisible}
+	result, err := r.reviews.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"status": models.ReviewFlagged}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *courseReviewRepository) RatingStats(ctx context.Context, courseID primitive.ObjectID) (models.CourseRating, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"course_id": courseID, "status": models.ReviewVisible}}},
+		{{Key: "$group", Value: bson.M{"_id": "$rating", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.reviews.Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.CourseRating{}, err
+	}
+	var groups []struct {
+		Rating int `bson:"_id"`
+		Count  int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return models.CourseRating{}, err
+	}
+
+	var rating models.CourseRating
+	sum := 0
+	for _, group := range groups {
+		if group.Rating < 1 || group.Rating > 5 {
+			continue
+		}
+		rating.Distribution[group.Rating-1] = group.Count
+		rating.Count += group.Count
+		sum += group.Rating * group.Count
+	}
+	if rating.Count > 0 {
+		rating.Average = float64(sum) / float64(rating.Count)
+	}
+	return rating, nil
+}
+
+func (r *courseReviewRepository) AddReport(ctx context.Context, report *models.ReviewReport) (bool, error) {
+	filter := bson.M{"review_id": report.ReviewID, "user_id": report.UserID}
+	update := bson.M{"$setOnInsert": bson.M{"reason": report.Reason, "created_at": report.CreatedAt}}
+	result, err := r.reports.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
+
+func (r *courseReviewRepository) CountReports(ctx context.Context, reviewID primitive.ObjectID, since *time.Time) (int64, error) {
+	filter := bson.M{"review_id": reviewID}
+	if since != nil {
+		filter["created_at"] = bson.M{"$gt": *since}
+	}
+	return r.reports.CountDocuments(ctx, filter)
+}
+
+func (r *courseReviewRepository) DeleteReports(ctx context.Context, reviewID primitive.ObjectID) error {
+	_, err := r.reports.DeleteMany(ctx, bson.M{"review_id": reviewID})
+	return err
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// CourseVersionRepository stores the published versions of courses, scoped
+// to the organization in ctx.
+type CourseVersionRepository interface {
+	Create(ctx context.Context, version *models.CourseVersion) error
+	FindByCourse(ctx context.Context, courseID primitive.ObjectID) ([]models.CourseVersion, error)
+	// FindPageByCourse returns up to limit versions of the course older than
+	// before, newest first; zero before starts from the newest
+	FindPageByCourse(ctx context.Context, courseID primitive.ObjectID, before, limit int) ([]models.CourseVersion, error)
+	FindByVersion(ctx context.Context, courseID primitive.ObjectID, version int) (*models.CourseVersion, error)
+	SnapshotUnversionedCourses() error
+	// FindLinkedURLs and ReplaceLinkedURL work across organizations, for migrations
+	FindLinkedURLs(prefix string) ([]LinkedURL, error)
+	ReplaceLinkedURL(organizationID primitive.ObjectID, oldURL, newURL string) error
+}
+
+type courseVersionRepository struct {
+	collection *scopedCollection
+	db         *mongo.Database
+}
+
+func NewCourseVersionRepository(db *mongo.Database) CourseVersionRepository {
+	return &courseVersionRepository{collection: newScopedCollection(db.Collection("course_versions")), db: db}
+}
+
+func (r *courseVersionRepository) Create(ctx context.Context, version *models.CourseVersion) error {
+	id, err := r.collection.InsertOne(ctx, version)
+	if err != nil {
+		return err
+	}
+	version.ID = id
+	return nil
+}
+
+// FindByCourse returns the versions of the course, newest first
+func (r *courseVersionRepository) FindByCourse(ctx context.Context, courseID primitive.ObjectID) ([]models.CourseVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"course_id": courseID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	versions := []models.CourseVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *courseVersionRepository) FindPageByCourse(ctx context.Context, courseID primitive.ObjectID, before, limit int) ([]models.CourseVersion, error) {
+	filter := bson.M{"course_id": courseID}
+	if before > 0 {
+		filter["version"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	versions := []models.CourseVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *courseVersionRepository) FindByVersion(ctx context.Context, courseID primitive.ObjectID, version int) (*models.CourseVersion, error) {
+	var found models.CourseVersion
+	if err := r.collection.FindOne(ctx, bson.M{"course_id": courseID, "version": version}, &found); err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+// SnapshotUnversionedCourses migrates courses created before versioning, in
+// every organization. Their current content becomes published version 1.
+func (r *courseVersionRepository) SnapshotUnversionedCourses() error {
+	ctx := context.Background()
+	courses := r.db.Collection("courses")
+	cursor, err := courses.Find(ctx, bson.M{"published_version": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	var unversioned []models.Course
+	if err := cursor.All(ctx, &unversioned); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, course := range unversioned {
+		// Claiming the course first keeps concurrent runs from writing version 1 twice
+		result, err := courses.UpdateOne(ctx,
+			bson.M{"_id": course.ID, "published_version": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"published_version": 1}})
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		version := models.CourseVersion{
+			OrganizationID: course.OrganizationID,
+			CourseID:       course.ID,
+			Version:        1,
+			CourseContent:  course.Content(),
+			PublishedAt:    now,
+			Note:           "Content from before versioning",
+		}
+		if _, err := r.db.Collection("course_versions").InsertOne(ctx, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *courseVersionRepository) FindLinkedURLs(prefix string) ([]LinkedURL, error) {
+	return findLinkedURLs(r.db.Collection("course_versions"), prefix)
+}
+
+func (r *courseVersionRepository) ReplaceLinkedURL(organizationID primitive.ObjectID, oldURL, newURL string) error {
+	return replaceLinkedURL(r.db.Collection("course_versions"), organizationID, oldURL, newURL)
+}
@@ -10,37 +10,43 @@ import (
 	"time"
 )
 
+// DashboardRepository only ever sees the courses and activities of the organization in ctx.
 type DashboardRepository interface {
-	GetTotalChapterCount() (int64, error)
-	GetRecentActivityTimestamps(userID primitive.ObjectID) ([]time.Time, error)
+	GetTotalChapterCount(ctx context.Context) (int64, error)
+	GetRecentActivityTimestamps(ctx context.Context, userID primitive.ObjectID) ([]time.Time, error)
 }
 
 type dashboardRepository struct {
-	courseCollection   *mongo.Collection
-	activityCollection *mongo.Collection
+	courseCollection   *scopedCollection
+	activityCollection *scopedCollection
 }
 
 func NewDashboardRepository(db *mongo.Database) DashboardRepository {
 	return &dashboardRepository{
-		courseCollection:   db.Collection("courses"),
-		activityCollection: db.Collection("activities"),
+		courseCollection:   newScopedCollection(db.Collection("courses")),
+		activityCollection: newScopedCollection(db.Collection("activities")),
 	}
 }
 
-// Uses an aggregation pipeline to count all chapters across all courses
-func (r *dashboardRepository) GetTotalChapterCount() (int64, error) {
+// Uses an aggregation pipeline to count all chapters across the organization's courses and their modules
+func (r *dashboardRepository) GetTotalChapterCount(ctx context.Context) (int64, error) {
+	moduleChapterCounts := bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$modules", bson.A{}}},
+		"as":    "module",
+		"in":    bson.M{"$size": bson.M{"$ifNull": bson.A{"$$module.chapters", bson.A{}}}},
+	}}
 	pipeline := mongo.Pipeline{
-		{{"$project", bson.D{{"chapterCount", bson.D{{"$size", "$chapters"}}}}}},
-		{{"$group", bson.D{{"_id", nil}, {"totalChapters", bson.D{{"$sum", "$chapterCount"}}}}}},
+		{{Key: "$project", Value: bson.D{{Key: "chapterCount", Value: bson.D{{Key: "$sum", Value: moduleChapterCounts}}}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "totalChapters", Value: bson.D{{Key: "$sum", Value: "$chapterCount"}}}}}},
 	}
 
-	cursor, err := r.courseCollection.Aggregate(context.Background(), pipeline)
+	cursor, err := r.courseCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
-	if cursor.Next(context.Background()) {
+	if cursor.Next(ctx) {
 		var result struct {
 			TotalChapters int64 `bson:"totalChapters"`
 		}
@@ -53,22 +59,22 @@ func (r *dashboardRepository) GetTotalChapterCount() (int64, error) {
 }
 
 // Fetches the last 30 days of activity timestamps for streak calculation
-func (r *dashboardRepository) GetRecentActivityTimestamps(userID primitive.ObjectID) ([]time.Time, error) {
+func (r *dashboardRepository) GetRecentActivityTimestamps(ctx context.Context, userID primitive.ObjectID) ([]time.Time, error) {
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 	filter := bson.M{
 		"user_id":   userID,
 		"timestamp": bson.M{"$gte": thirtyDaysAgo},
 	}
-	opts := options.Find().SetSort(bson.D{{"timestamp", -1}}) // Sort by most recent
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}) // Sort by most recent
 
-	cursor, err := r.activityCollection.Find(context.Background(), filter, opts)
+	cursor, err := r.activityCollection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
 	var timestamps []time.Time
-	for cursor.Next(context.Background()) {
+	for cursor.Next(ctx) {
 		var activity models.UserActivity
 		if err := cursor.Decode(&activity); err == nil {
 			timestamps = append(timestamps, activity.Timestamp)
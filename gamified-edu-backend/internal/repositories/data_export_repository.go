@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type DataExportRepository interface {
+	Create(export *models.DataExport) error
+	FindByID(id primitive.ObjectID) (*models.DataExport, error)
+	// FindPageByUser returns up to limit of the user's exports in the
+	// organization requested before the export with ID before, or the newest
+	// with a nil before
+	FindPageByUser(organizationID, userID, before primitive.ObjectID, limit int) ([]models.DataExport, error)
+	FindUnfinishedByUser(organizationID, userID primitive.ObjectID) (*models.DataExport, error)
+	FindExpired(now time.Time) ([]models.DataExport, error)
+	Update(export *models.DataExport) error
+}
+
+type dataExportRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDataExportRepository(db *mongo.Database) DataExportRepository {
+	return &dataExportRepository{collection: db.Collection("data_exports")}
+}
+
+func (r *dataExportRepository) Create(export *models.DataExport) error {
+	result, err := r.collection.InsertOne(context.Background(), export)
+	if err != nil {
+		return err
+	}
+	export.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *dataExportRepository) FindByID(id primitive.ObjectID) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *dataExportRepository) FindPageByUser(organizationID, userID, before primitive.ObjectID, limit int) ([]models.DataExport, error) {
+	filter := bson.M{"organization_id": organizationID, "user_id": userID}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	// IDs grow with creation time
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	exports := []models.DataExport{}
+	if err := cursor.All(context.Background(), &exports); err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// FindUnfinishedByUser returns the user's pending or running export in the organization, or nil if there is none.
+func (r *dataExportRepository) FindUnfinishedByUser(organizationID, userID primitive.ObjectID) (*models.DataExport, error) {
+	filter := bson.M{
+		"organization_id": organizationID,
+		"user_id":         userID,
+		"status":          bson.M{"$in": []string{models.DataExportPending, models.DataExportRunning}},
+	}
+	var export models.DataExport
+	err := r.collection.FindOne(context.Background(), filter).Decode(&export)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindExpired returns completed exports whose download window has closed.
+func (r *dataExportRepository) FindExpired(now time.Time) ([]models.DataExport, error) {
+	return r.find(bson.M{"status": models.DataExportCompleted, "expires_at": bson.M{"$lte": now}})
+}
+
+func (r *dataExportRepository) find(filter bson.M) ([]models.DataExport, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	exports := []models.DataExport{}
+	if err := cursor.All(context.Background(), &exports); err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func (r *dataExportRepository) Update(export *models.DataExport) error {
+	_, err := r.collection.ReplaceOne(context.Background(), bson.M{"_id": export.ID}, export)
+	return err
+}
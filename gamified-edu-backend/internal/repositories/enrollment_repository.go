@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// EnrollmentRepository stores course enrollments, scoped to the organization in ctx.
+type EnrollmentRepository interface {
+	Find(ctx context.Context, userID, courseID primitive.ObjectID) (*models.Enrollment, error)
+	// FindByUser returns every enrollment of the user, ended ones too
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Enrollment, error)
+	FindActiveByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Enrollment, error)
+	FindActiveByCourse(ctx context.Context, courseID primitive.ObjectID) ([]models.Enrollment, error)
+	Enroll(ctx context.Context, userID, courseID primitive.ObjectID, at time.Time) (*models.Enrollment, error)
+	Unenroll(ctx context.Context, userID, courseID primitive.ObjectID, at time.Time) (bool, error)
+	MarkCompleted(ctx context.Context, userID, courseID primitive.ObjectID, at time.Time) (bool, error)
+	// SetCohort puts the user's active enrollment in the cohort, or in none for a zero cohortID.
+	// It reports false if the user is not enrolled.
+	SetCohort(ctx context.Context, userID, courseID, cohortID primitive.ObjectID) (bool, error)
+	// ClearCohort takes every enrollment out of the cohort
+	ClearCohort(ctx context.Context, cohortID primitive.ObjectID) error
+	// CountActiveByCohort counts the active enrollments of the course in each of its cohorts
+	CountActiveByCohort(ctx context.Context, courseID primitive.ObjectID) (map[primitive.ObjectID]int, error)
+	// BackfillFromProgress enrolls at the given time and reports how many
+	// enrollments it created
+	BackfillFromProgress(at time.Time) (int64, error)
+}
+
+type enrollmentRepository struct {
+	collection *scopedCollection
+	db         *mongo.Database
+}
+
+func NewEnrollmentRepository(db *mongo.Database) EnrollmentRepository {
+	return &enrollmentRepository{collection: newScopedCollection(db.Collection("enrollments")), db: db}
+}
+
+// Find returns the user's enrollment in the course, active or not, or nil if they never enrolled
+func (r *enrollmentRepository) Find(ctx context.Context, userID, courseID primitive.ObjectID) (*models.Enrollment, error) {
+	var enrollment models.Enrollment
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "course_id": courseID}, &enrollment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+func (r *enrollmentRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Enrollment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	enrollments := []models.Enrollment{}
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+func (r *enrollmentRepository) FindActiveByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Enrollment, error) {
+	filter := bson.M{"user_id": userID, "unenrolled_at": bson.M{"$exists": false}}
+	opts := options.Find().SetSort(bson.D{{Key: "enrolled_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	enrollments := []models.Enrollment{}
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+func (r *enrollmentRepository) FindActiveByCourse(ctx context.Context, courseID primitive.ObjectID) ([]models.Enrollment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"course_id": courseID, "unenrolled_at": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	enrollments := []models.Enrollment{}
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+// Enroll creates the enrollment, or reactivates a previous one with a new enrollment date
+func (r *enrollmentRepository) Enroll(ctx context.Context, userID, courseID primitive.ObjectID, at time.Time) (*models.Enrollment, error) {
+	filter := bson.M{"user_id": userID, "course_id": courseID}
+	update := bson.M{
+		"$set":   bson.M{"enrolled_at": at},
+		"$unset": bson.M{"unenrolled_at": ""},
+	}
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return nil, err
+	}
+	return r.Find(ctx, userID, courseID)
+}
+
+// Unenroll reports false if the user was not enrolled
+func (r *enrollmentRepository) Unenroll(ctx context.Context, userID, courseID primitive.ObjectID, at time.Time) (bool, error) {
+	filter := bson.M{"user_id": userID, "course_id": courseID, "unenrolled_at": bson.M{"$exists": false}}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"unenrolled_at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// MarkCompleted records the first completion of the course only, and reports whether this was it
+func (r *enrollmentRepository) MarkCompleted(ctx context.Context, userID, courseID primitive.ObjectID, at time.Time) (bool, error) {
+	filter := bson.M{"user_id": userID, "course_id": courseID, "completed_at": bson.M{"$exists": false}}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"completed_at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *enrollmentRepository) SetCohort(ctx context.Context, userID, courseID, cohortID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"user_id": userID, "course_id": courseID, "unenrolled_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"cohort_id": cohortID}}
+	if cohortID.IsZero() {
+		update = bson.M{"$unset": bson.M{"cohort_id": ""}}
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *enrollmentRepository) ClearCohort(ctx context.Context, cohortID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"cohort_id": cohortID}, bson.M{"$unset": bson.M{"cohort_id": ""}})
+	return err
+}
+
+func (r *enrollmentRepository) CountActiveByCohort(ctx context.Context, courseID primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"course_id": courseID, "cohort_id": bson.M{"$exists": true}, "unenrolled_at": bson.M{"$exists": false}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$cohort_id", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		CohortID primitive.ObjectID `bson:"_id"`
+		Count    int                `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	counts := make(map[primitive.ObjectID]int, len(groups))
+	for _, group := range groups {
+		counts[group.CohortID] = group.Count
+	}
+	return counts, nil
+}
+
+// BackfillFromProgress enrolls users in every course they made progress in
+// before enrollments existed. It runs across all organizations and leaves
+// existing enrollments, including ended ones, untouched.
+func (r *enrollmentRepository) BackfillFromProgress(at time.Time) (int64, error) {
+	ctx := context.Background()
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": bson.M{
+			"organization_id": "$organization_id",
+			"user_id":         "$user_id",
+			"course_id":       "$course_id",
+		}}}},
+	}
+	cursor, err := r.db.Collection("progress").Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	var pairs []struct {
+		Key struct {
+			OrganizationID primitive.ObjectID `bson:"organization_id"`
+			UserID         primitive.ObjectID `bson:"user_id"`
+			CourseID       primitive.ObjectID `bson:"course_id"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &pairs); err != nil {
+		return 0, err
+	}
+	if len(pairs) == 0 {
+		return 0, nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(pairs))
+	for _, pair := range pairs {
+		filter := bson.M{
+			organizationField: pair.Key.OrganizationID,
+			"user_id":         pair.Key.UserID,
+			"course_id":       pair.Key.CourseID,
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{"enrolled_at": at}}).
+			SetUpsert(true))
+	}
+	result, err := r.db.Collection("enrollments").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return result.UpsertedCount, nil
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// GuardianRepository stores links between guardians and students, scoped to the organization in ctx.
+type GuardianRepository interface {
+	Create(ctx context.Context, link *models.GuardianLink) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.GuardianLink, error)
+	FindByInviteTokenHash(ctx context.Context, tokenHash string) (*models.GuardianLink, error)
+	// FindPageByStudent returns up to limit of the student's pending and
+	// active links created after the link with ID after, oldest first
+	FindPageByStudent(ctx context.Context, studentID, after primitive.ObjectID, limit int) ([]models.GuardianLink, error)
+	// FindPageByGuardian pages the guardian's pending and active links the same way
+	FindPageByGuardian(ctx context.Context, guardianID, after primitive.ObjectID, limit int) ([]models.GuardianLink, error)
+	FindOpen(ctx context.Context, studentID primitive.ObjectID, guardianEmail string) (*models.GuardianLink, error)
+	FindActive(ctx context.Context, studentID, guardianID primitive.ObjectID) (*models.GuardianLink, error)
+	FindDueForSummary(ctx context.Context, cutoff time.Time) ([]models.GuardianLink, error)
+	Update(ctx context.Context, link *models.GuardianLink) error
+	MarkSummarySent(ctx context.Context, id primitive.ObjectID, sentAt time.Time) error
+}
+
+type guardianRepository struct {
+	collection *scopedCollection
+}
+
+func NewGuardianRepository(db *mongo.Database) GuardianRepository {
+	return &guardianRepository{collection: newScopedCollection(db.Collection("guardian_links"))}
+}
+
+func (r *guardianRepository) Create(ctx context.Context, link *models.GuardianLink) error {
+	id, err := r.collection.InsertOne(ctx, link)
+	if err != nil {
+		return err
+	}
+	link.ID = id
+	return nil
+}
+
+func (r *guardianRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.GuardianLink, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *guardianRepository) FindByInviteTokenHash(ctx context.Context, tokenHash string) (*models.GuardianLink, error) {
+	return r.findOne(ctx, bson.M{"invite_token_hash": tokenHash, "status": models.GuardianLinkPending})
+}
+
+func (r *guardianRepository) FindPageByStudent(ctx context.Context, studentID, after primitive.ObjectID, limit int) ([]models.GuardianLink, error) {
+	return r.findPage(ctx, bson.M{"student_id": studentID, "status": bson.M{"$ne": models.GuardianLinkRevoked}}, after, limit)
+}
+
+func (r *guardianRepository) FindPageByGuardian(ctx context.Context, guardianID, after primitive.ObjectID, limit int) ([]models.GuardianLink, error) {
+	return r.findPage(ctx, bson.M{"guardian_id": guardianID, "status": bson.M{"$ne": models.GuardianLinkRevoked}}, after, limit)
+}
+
+func (r *guardianRepository) findPage(ctx context.Context, filter bson.M, after primitive.ObjectID, limit int) ([]models.GuardianLink, error) {
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	// IDs grow with creation time
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	links := []models.GuardianLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// FindOpen returns the pending or active link between the student and the
+// guardian email, or nil if there is none
+func (r *guardianRepository) FindOpen(ctx context.Context, studentID primitive.ObjectID, guardianEmail string) (*models.GuardianLink, error) {
+	link, err := r.findOne(ctx, bson.M{
+		"student_id":     studentID,
+		"guardian_email": guardianEmail,
+		"status":         bson.M{"$in": []string{models.GuardianLinkPending, models.GuardianLinkActive}},
+	})
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return link, err
+}
+
+func (r *guardianRepository) FindActive(ctx context.Context, studentID, guardianID primitive.ObjectID) (*models.GuardianLink, error) {
+	return r.findOne(ctx, bson.M{"student_id": studentID, "guardian_id": guardianID, "status": models.GuardianLinkActive})
+}
+
+// FindDueForSummary returns active links whose last summary was sent before cutoff, or never
+func (r *guardianRepository) FindDueForSummary(ctx context.Context, cutoff time.Time) ([]models.GuardianLink, error) {
+	return r.find(ctx, bson.M{
+		"status": models.GuardianLinkActive,
+		"$or": []bson.M{
+			{"last_summary_at": bson.M{"$exists": false}},
+			{"last_summary_at": bson.M{"$lt": cutoff}},
+		},
+	})
+}
+
+func (r *guardianRepository) findOne(ctx context.Context, filter bson.M) (*models.GuardianLink, error) {
+	var link models.GuardianLink
+	if err := r.collection.FindOne(ctx, filter, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *guardianRepository) find(ctx context.Context, filter bson.M) ([]models.GuardianLink, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	links := []models.GuardianLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *guardianRepository) Update(ctx context.Context, link *models.GuardianLink) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": link.ID}, bson.M{"$set": bson.M{
+		"guardian_id":       link.GuardianID,
+		"status":            link.Status,
+		"invite_token_hash": link.InviteTokenHash,
+		"invite_expires_at": link.InviteExpiresAt,
+		"accepted_at":       link.AcceptedAt,
+		"revoked_at":        link.RevokedAt,
+	}})
+	return err
+}
+
+func (r *guardianRepository) MarkSummarySent(ctx context.Context, id primitive.ObjectID, sentAt time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_summary_at": sentAt}})
+	return err
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// LearningPathRepository stores learning paths and the enrollments in them,
+// scoped to the organization in ctx.
+type LearningPathRepository interface {
+	Create(ctx context.Context, path *models.LearningPath) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.LearningPath, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.LearningPath, error)
+	// FindPage returns up to limit paths created before the one with ID before, newest first
+	FindPage(ctx context.Context, before primitive.ObjectID, limit int) ([]models.LearningPath, error)
+	FindByCourse(ctx context.Context, courseID primitive.ObjectID) ([]models.LearningPath, error)
+	Update(ctx context.Context, path *models.LearningPath) error
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+
+	FindEnrollment(ctx context.Context, userID, pathID primitive.ObjectID) (*models.PathEnrollment, error)
+	// FindEnrollmentPage returns up to limit of the user's active enrollments
+	// after the given one, most recent enrollment first
+	FindEnrollmentPage(ctx context.Context, userID primitive.ObjectID, after *models.PathEnrollment, limit int) ([]models.PathEnrollment, error)
+	FindActiveEnrollmentsByPath(ctx context.Context, pathID primitive.ObjectID) ([]models.PathEnrollment, error)
+	Enroll(ctx context.Context, userID, pathID primitive.ObjectID, at time.Time) (*models.PathEnrollment, error)
+	Unenroll(ctx context.Context, userID, pathID primitive.ObjectID, at time.Time) (bool, error)
+	// MarkCompleted records the first completion of the path only, and reports whether this was it
+	MarkCompleted(ctx context.Context, userID, pathID primitive.ObjectID, at time.Time, rewardXP int) (bool, error)
+	DeleteEnrollments(ctx context.Context, pathID primitive.ObjectID) error
+}
+
+type learningPathRepository struct {
+	paths       *scopedCollection
+	enrollments *scopedCollection
+}
+
+func NewLearningPathRepository(db *mongo.Database) LearningPathRepository {
+	return &learningPathRepository{
+		paths:       newScopedCollection(db.Collection("learning_paths")),
+		enrollments: newScopedCollection(db.Collection("path_enrollments")),
+	}
+}
+
+func (r *learningPathRepository) Create(ctx context.Context, path *models.LearningPath) error {
+	id, err := r.paths.InsertOne(ctx, path)
+	if err != nil {
+		return err
+	}
+	path.ID = id
+	return nil
+}
+
+func (r *learningPathRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.LearningPath, error) {
+	var path models.LearningPath
+	if err := r.paths.FindOne(ctx, bson.M{"_id": id}, &path); err != nil {
+		return nil, err
+	}
+	return &path, nil
+}
+
+func (r *learningPathRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.LearningPath, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find())
+}
+
+func (r *learningPathRepository) FindPage(ctx context.Context, before primitive.ObjectID, limit int) ([]models.LearningPath, error) {
+	filter := bson.M{}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	// IDs grow with creation time
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit)))
+}
+
+func (r *learningPathRepository) FindByCourse(ctx context.Context, courseID primitive.ObjectID) ([]models.LearningPath, error) {
+	return r.find(ctx, bson.M{"steps.course_id": courseID}, options.Find())
+}
+
+func (r *learningPathRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.LearningPath, error) {
+	cursor, err := r.paths.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	paths := []models.LearningPath{}
+	if err := cursor.All(ctx, &paths); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func (r *learningPathRepository) Update(ctx context.Context, path *models.LearningPath) error {
+	update := bson.M{"$set": bson.M{
+		"title":              path.Title,
+		"description":        path.Description,
+		"steps":              path.Steps,
+		"electives_required": path.ElectivesRequired,
+		"reward_xp":          path.RewardXP,
+		"updated_at":         path.UpdatedAt,
+	}}
+	_, err := r.paths.UpdateOne(ctx, bson.M{"_id": path.ID}, update)
+	return err
+}
+
+func (r *learningPathRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.paths.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// FindEnrollment returns the user's enrollment in the path, active or not, or nil if they never enrolled
+func (r *learningPathRepository) FindEnrollment(ctx context.Context, userID, pathID primitive.ObjectID) (*models.PathEnrollment, error) {
+	var enrollment models.PathEnrollment
+	err := r.enrollments.FindOne(ctx, bson.M{"user_id": userID, "path_id": pathID}, &enrollment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+func (r *learningPathRepository) FindEnrollmentPage(ctx context.Context, userID primitive.ObjectID, after *models.PathEnrollment, limit int) ([]models.PathEnrollment, error) {
+	filter := bson.M{"user_id": userID, "unenrolled_at": bson.M{"$exists": false}}
+	if after != nil {
+		filter["$or"] = bson.A{
+			bson.M{"enrolled_at": bson.M{"$lt": after.EnrolledAt}},
+			bson.M{"enrolled_at": after.EnrolledAt, "_id": bson.M{"$lt": after.ID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "enrolled_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	return r.findEnrollments(ctx, filter, opts)
+}
+
+func (r *learningPathRepository) FindActiveEnrollmentsByPath(ctx context.Context, pathID primitive.ObjectID) ([]models.PathEnrollment, error) {
+	return r.findEnrollments(ctx, bson.M{"path_id": pathID, "unenrolled_at": bson.M{"$exists": false}}, options.Find())
+}
+
+func (r *learningPathRepository) findEnrollments(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.PathEnrollment, error) {
+	cursor, err := r.enrollments.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	enrollments := []models.PathEnrollment{}
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+// Enroll creates the enrollment, or reactivates a previous one with a new enrollment date
+func (r *learningPathRepository) Enroll(ctx context.Context, userID, pathID primitive.ObjectID, at time.Time) (*models.PathEnrollment, error) {
+	filter := bson.M{"user_id": userID, "path_id": pathID}
+	update := bson.M{
+		"$set":   bson.M{"enrolled_at": at},
+		"$unset": bson.M{"unenrolled_at": ""},
+	}
+	if _, err := r.enrollments.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return nil, err
+	}
+	return r.FindEnrollment(ctx, userID, pathID)
+}
+
+// Unenroll reports false if the user was not enrolled
+func (r *learningPathRepository) Unenroll(ctx context.Context, userID, pathID primitive.ObjectID, at time.Time) (bool, error) {
+	filter := bson.M{"user_id": userID, "path_id": pathID, "unenrolled_at": bson.M{"$exists": false}}
+	result, err := r.enrollments.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"unenrolled_at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *learningPathRepository) MarkCompleted(ctx context.Context, userID, pathID primitive.ObjectID, at time.Time, rewardXP int) (bool, error) {
+	filter := bson.M{"user_id": userID, "path_id": pathID, "completed_at": bson.M{"$exists": false}}
+	result, err := r.enrollments.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"completed_at": at, "reward_xp": rewardXP}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *learningPathRepository) DeleteEnrollments(ctx context.Context, pathID primitive.ObjectID) error {
+	_, err := r.enrollments.DeleteMany(ctx, bson.M{"path_id": pathID})
+	return err
+}
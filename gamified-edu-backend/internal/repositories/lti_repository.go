@@ -0,0 +1,311 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LTIPlatformRepository stores registered LMS platforms, scoped to the
+// organization in ctx. Login initiations name only the platform, so
+// FindByIssuer looks across all organizations.
+type LTIPlatformRepository interface {
+	Create(ctx context.Context, platform *models.LTIPlatform) error
+	// FindByID returns nil when the organization has no such platform
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.LTIPlatform, error)
+	// FindPage returns up to limit of the organization's platforms registered
+	// after the platform with ID after, oldest first
+	FindPage(ctx context.Context, after primitive.ObjectID, limit int) ([]models.LTIPlatform, error)
+	// Delete reports false if the organization has no such platform
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// FindByIssuer returns the platforms with issuer, and with clientID unless it is empty
+	FindByIssuer(issuer, clientID string) ([]models.LTIPlatform, error)
+}
+
+type ltiPlatformRepository struct {
+	collection *scopedCollection
+	// all is the unscoped collection, only for login initiations
+	all *mongo.Collection
+}
+
+func NewLTIPlatformRepository(db *mongo.Database) LTIPlatformRepository {
+	return &ltiPlatformRepository{collection: newScopedCollection(db.Collection("lti_platforms")), all: db.Collection("lti_platforms")}
+}
+
+func (r *ltiPlatformRepository) Create(ctx context.Context, platform *models.LTIPlatform) error {
+	id, err := r.collection.InsertOne(ctx, platform)
+	if err != nil {
+		return err
+	}
+	platform.ID = id
+	return nil
+}
+
+func (r *ltiPlatformRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.LTIPlatform, error) {
+	var platform models.LTIPlatform
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}, &platform)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &platform, nil
+}
+
+func (r *ltiPlatformRepository) FindPage(ctx context.Context, after primitive.ObjectID, limit int) ([]models.LTIPlatform, error) {
+	filter := bson.M{}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	// IDs grow with creation time
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	platforms := []models.LTIPlatform{}
+	if err := cursor.All(ctx, &platforms); err != nil {
+		return nil, err
+	}
+	return platforms, nil
+}
+
+func (r *ltiPlatformRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *ltiPlatformRepository) FindByIssuer(issuer, clientID string) ([]models.LTIPlatform, error) {
+	ctx := context.Background()
+	filter := bson.M{"issuer": issuer}
+	if clientID != "" {
+		filter["client_id"] = clientID
+	}
+	cursor, err := r.all.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	platforms := []models.LTIPlatform{}
+	if err := cursor.All(ctx, &platforms); err != nil {
+		return nil, err
+	}
+	return platforms, nil
+}
+
+// LTILoginStateRepository keeps login states until their launch arrives.
+// Launches name only the state, so it is not scoped to an organization.
+type LTILoginStateRepository interface {
+	Create(state *models.LTILoginState) error
+	// Take removes and returns the state, or nil if there is none
+	Take(state string) (*models.LTILoginState, error)
+}
+
+type ltiLoginStateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLTILoginStateRepository(db *mongo.Database) LTILoginStateRepository {
+	return &ltiLoginStateRepository{collection: db.Collection("lti_login_states")}
+}
+
+// Create also clears out states whose launch never came
+func (r *ltiLoginStateRepository) Create(state *models.LTILoginState) error {
+	ctx := context.Background()
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": time.Now()}}); err != nil {
+		return err
+	}
+	_, err := r.collection.InsertOne(ctx, state)
+	return err
+}
+
+func (r *ltiLoginStateRepository) Take(state string) (*models.LTILoginState, error) {
+	var loginState models.LTILoginState
+	err := r.collection.FindOneAndDelete(context.Background(), bson.M{"state": state}).Decode(&loginState)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &loginState, nil
+}
+
+// LTIIdentityRepository links platform users to users, scoped to the organization in ctx.
+type LTIIdentityRepository interface {
+	// Find returns nil when the platform's user never launched
+	Find(ctx context.Context, platformID primitive.ObjectID, subject string) (*models.LTIIdentity, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.LTIIdentity, error)
+	Create(ctx context.Context, identity *models.LTIIdentity) error
+}
+
+type ltiIdentityRepository struct {
+	collection *scopedCollection
+}
+
+func NewLTIIdentityRepository(db *mongo.Database) LTIIdentityRepository {
+	return &ltiIdentityRepository{collection: newScopedCollection(db.Collection("lti_identities"))}
+}
+
+func (r *ltiIdentityRepository) Find(ctx context.Context, platformID primitive.ObjectID, subject string) (*models.LTIIdentity, error) {
+	var identity models.LTIIdentity
+	err := r.collection.FindOne(ctx, bson.M{"platform_id": platformID, "subject": subject}, &identity)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *ltiIdentityRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.LTIIdentity, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	identities := []models.LTIIdentity{}
+	if err := cursor.All(ctx, &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *ltiIdentityRepository) Create(ctx context.Context, identity *models.LTIIdentity) error {
+	id, err := r.collection.InsertOne(ctx, identity)
+	if err != nil {
+		return err
+	}
+	identity.ID = id
+	return nil
+}
+
+// LTICourseLinkRepository stores the LMS links that launch courses, scoped
+// to the organization in ctx.
+type LTICourseLinkRepository interface {
+	// Save creates or updates the link with the platform's resource link ID
+	// and adds learnerID to the learners who launched it
+	Save(ctx context.Context, link *models.LTICourseLink, learnerID primitive.ObjectID) (*models.LTICourseLink, error)
+	// FindByResourceLink returns nil when the link never launched
+	FindByResourceLink(ctx context.Context, platformID primitive.ObjectID, resourceLinkID string) (*models.LTICourseLink, error)
+	// FindForLearner returns the links to the course the learner launched
+	FindForLearner(ctx context.Context, userID, courseID primitive.ObjectID) ([]models.LTICourseLink, error)
+	AddLineItem(ctx context.Context, linkID primitive.ObjectID, item models.LTILineItem) error
+}
+
+type ltiCourseLinkRepository struct {
+	collection *scopedCollection
+}
+
+func NewLTICourseLinkRepository(db *mongo.Database) LTICourseLinkRepository {
+	return &ltiCourseLinkRepository{collection: newScopedCollection(db.Collection("lti_course_links"))}
+}
+
+func (r *ltiCourseLinkRepository) Save(ctx context.Context, link *models.LTICourseLink, learnerID primitive.ObjectID) (*models.LTICourseLink, error) {
+	filter := bson.M{"platform_id": link.PlatformID, "resource_link_id": link.ResourceLinkID}
+	update := bson.M{
+		"$set": bson.M{
+			"deployment_id":  link.DeploymentID,
+			"context_id":     link.ContextID,
+			"course_id":      link.CourseID,
+			"line_items_url": link.LineItemsURL,
+			"line_item_url":  link.LineItemURL,
+			"scopes":         link.Scopes,
+			"updated_at":     link.UpdatedAt,
+		},
+		"$addToSet": bson.M{"learner_ids": learnerID},
+	}
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return nil, err
+	}
+	return r.FindByResourceLink(ctx, link.PlatformID, link.ResourceLinkID)
+}
+
+func (r *ltiCourseLinkRepository) FindByResourceLink(ctx context.Context, platformID primitive.ObjectID, resourceLinkID string) (*models.LTICourseLink, error) {
+	var link models.LTICourseLink
+	err := r.collection.FindOne(ctx, bson.M{"platform_id": platformID, "resource_link_id": resourceLinkID}, &link)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *ltiCourseLinkRepository) FindForLearner(ctx context.Context, userID, courseID primitive.ObjectID) ([]models.LTICourseLink, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"course_id": courseID, "learner_ids": userID})
+	if err != nil {
+		return nil, err
+	}
+	links := []models.LTICourseLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *ltiCourseLinkRepository) AddLineItem(ctx context.Context, linkID primitive.ObjectID, item models.LTILineItem) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": linkID}, bson.M{"$push": bson.M{"line_items": item}})
+	return err
+}
+
+// LTIDeepLinkRepository keeps deep linking requests until the user picked
+// courses, scoped to the organization in ctx.
+type LTIDeepLinkRepository interface {
+	Create(ctx context.Context, deepLink *models.LTIDeepLink) error
+	// Find returns nil when the user has no such request
+	Find(ctx context.Context, id, userID primitive.ObjectID) (*models.LTIDeepLink, error)
+	// Delete reports false if the request was already completed
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+}
+
+type ltiDeepLinkRepository struct {
+	collection *scopedCollection
+}
+
+func NewLTIDeepLinkRepository(db *mongo.Database) LTIDeepLinkRepository {
+	return &ltiDeepLinkRepository{collection: newScopedCollection(db.Collection("lti_deep_links"))}
+}
+
+// Create also clears out requests that were never completed
+func (r *ltiDeepLinkRepository) Create(ctx context.Context, deepLink *models.LTIDeepLink) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": time.Now()}}); err != nil {
+		return err
+	}
+	id, err := r.collection.InsertOne(ctx, deepLink)
+	if err != nil {
+		return err
+	}
+	deepLink.ID = id
+	return nil
+}
+
+func (r *ltiDeepLinkRepository) Find(ctx context.Context, id, userID primitive.ObjectID) (*models.LTIDeepLink, error) {
+	var deepLink models.LTIDeepLink
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID}, &deepLink)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &deepLink, nil
+}
+
+func (r *ltiDeepLinkRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MediaAssetRepository stores uploaded media assets, scoped to the
+// organization in ctx. Signed download URLs carry no organization, so
+// FindForDownload looks across all of them.
+type MediaAssetRepository interface {
+	Create(ctx context.Context, asset *models.MediaAsset) error
+	// FindByID returns nil when the organization has no such asset
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.MediaAsset, error)
+	// FindByChecksum returns nil when the organization has no asset with the content
+	FindByChecksum(ctx context.Context, sha256 string) (*models.MediaAsset, error)
+	// FindPage returns up to limit of the organization's assets created
+	// before the asset with ID before, or the newest with a nil before
+	FindPage(ctx context.Context, before primitive.ObjectID, limit int) ([]models.MediaAsset, error)
+	// Delete reports false if the organization has no such asset
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// FindForDownload returns nil when there is no such asset
+	FindForDownload(id primitive.ObjectID) (*models.MediaAsset, error)
+}
+
+type mediaAssetRepository struct {
+	collection *scopedCollection
+	// all is the unscoped collection, only for signed downloads
+	all *mongo.Collection
+}
+
+func NewMediaAssetRepository(db *mongo.Database) MediaAssetRepository {
+	return &mediaAssetRepository{collection: newScopedCollection(db.Collection("media_assets")), all: db.Collection("media_assets")}
+}
+
+func (r *mediaAssetRepository) Create(ctx context.Context, asset *models.MediaAsset) error {
+	id, err := r.collection.InsertOne(ctx, asset)
+	if err != nil {
+		return err
+	}
+	asset.ID = id
+	return nil
+}
+
+func (r *mediaAssetRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.MediaAsset, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mediaAssetRepository) FindByChecksum(ctx context.Context, sha256 string) (*models.MediaAsset, error) {
+	return r.findOne(ctx, bson.M{"sha256": sha256})
+}
+
+func (r *mediaAssetRepository) findOne(ctx context.Context, filter bson.M) (*models.MediaAsset, error) {
+	var asset models.MediaAsset
+	err := r.collection.FindOne(ctx, filter, &asset)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &asset, nil
+}
+
+func (r *mediaAssetRepository) FindPage(ctx context.Context, before primitive.ObjectID, limit int) ([]models.MediaAsset, error) {
+	filter := bson.M{}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	// IDs grow with creation time
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	assets := []models.MediaAsset{}
+	if err := cursor.All(ctx, &assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (r *mediaAssetRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *mediaAssetRepository) FindForDownload(id primitive.ObjectID) (*models.MediaAsset, error) {
+	var asset models.MediaAsset
+	err := r.all.FindOne(context.Background(), bson.M{"_id": id}).Decode(&asset)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &asset, nil
+}
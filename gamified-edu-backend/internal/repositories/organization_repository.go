@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type OrganizationRepository interface {
+	Create(organization *models.Organization) error
+	FindByID(id primitive.ObjectID) (*models.Organization, error)
+	FindBySlug(slug string) (*models.Organization, error)
+	FindByRegistrationCode(code string) (*models.Organization, error)
+	FindAll() ([]models.Organization, error)
+	// FindPage returns up to limit organizations after the given one, by name
+	FindPage(after *models.Organization, limit int) ([]models.Organization, error)
+	EnsureDefault() (*models.Organization, error)
+	AdoptUnassigned(organizationID primitive.ObjectID) error
+}
+
+// tenantCollections lists every collection whose documents carry an
+// organization_id. Any new tenant-owned collection must be registered here so
+// that documents written before it was scoped are adopted by an organization.
+var tenantCollections = []string{"users", "courses", "progress", "activities"}
+
+type organizationRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewOrganizationRepository(db *mongo.Database) OrganizationRepository {
+	return &organizationRepository{db: db, collection: db.Collection("organizations")}
+}
+
+func (r *organizationRepository) Create(organization *models.Organization) error {
+	result, err := r.collection.InsertOne(context.Background(), organization)
+	if err != nil {
+		return err
+	}
+	organization.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *organizationRepository) FindByID(id primitive.ObjectID) (*models.Organization, error) {
+	return r.findOne(bson.M{"_id": id})
+}
+
+func (r *organizationRepository) FindBySlug(slug string) (*models.Organization, error) {
+	return r.findOne(bson.M{"slug": slug})
+}
+
+func (r *organizationRepository) FindByRegistrationCode(code string) (*models.Organization, error) {
+	return r.findOne(bson.M{"registration_code": code})
+}
+
+func (r *organizationRepository) findOne(filter bson.M) (*models.Organization, error) {
+	var organization models.Organization
+	err := r.collection.FindOne(context.Background(), filter).Decode(&organization)
+	if err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (r *organizationRepository) FindAll() ([]models.Organization, error) {
+	cursor, err := r.collection.Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	organizations := []models.Organization{}
+	if err := cursor.All(context.Background(), &organizations); err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
+
+func (r *organizationRepository) FindPage(after *models.Organization, limit int) ([]models.Organization, error) {
+	filter := bson.M{}
+	if after != nil {
+		filter["$or"] = bson.A{
+			bson.M{"name": bson.M{"$gt": after.Name}},
+			bson.M{"name": after.Name, "_id": bson.M{"$gt": after.ID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	organizations := []models.Organization{}
+	if err := cursor.All(context.Background(), &organizations); err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
+
+// EnsureDefault returns the default organization, creating it on first use.
+func (r *organizationRepository) EnsureDefault() (*models.Organization, error) {
+	filter := bson.M{"slug": models.DefaultOrganizationSlug}
+	update := bson.M{"$setOnInsert": bson.M{
+		"name":       "Default",
+		"slug":       models.DefaultOrganizationSlug,
+		"created_at": time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var organization models.Organization
+	err := r.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&organization)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("default organization could not be created")
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// AdoptUnassigned moves every document that has no organization yet, i.e. was
+// written before multi-tenancy, into organizationID. Until then such documents
+// are invisible to the tenant-scoped repositories.
+func (r *organizationRepository) AdoptUnassigned(organizationID primitive.ObjectID) error {
+	// A null match also covers documents where the field is missing
+	filter := bson.M{organizationField: bson.M{"$in": bson.A{nil, primitive.NilObjectID}}}
+	update := bson.M{"$set": bson.M{organizationField: organizationID}}
+	for _, name := range tenantCollections {
+		if _, err := r.db.Collection(name).UpdateMany(context.Background(), filter, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -7,30 +7,42 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	// "go.mongodb.org/mongo-driver/mongo/options" // This line was removed
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
+// ProgressRepository only ever sees the progress of the organization in ctx.
 type ProgressRepository interface {
-	FindOrCreateStatus(userID, chapterID, courseID primitive.ObjectID) (*models.UserChapterStatus, error)
-	UpdateStatus(status *models.UserChapterStatus) error
-	CountCompletedChapters(userID, courseID primitive.ObjectID) (int64, error)
-	FindByUserAndChapter(userID, chapterID primitive.ObjectID) (*models.UserChapterStatus, error)
-	GetUserCourseProgress(userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	FindOrCreateStatus(ctx context.Context, userID, chapterID, courseID primitive.ObjectID) (*models.UserChapterStatus, error)
+	UpdateStatus(ctx context.Context, status *models.UserChapterStatus) error
+	CountCompletedChapters(ctx context.Context, userID, courseID primitive.ObjectID) (int64, error)
+	FindByUserAndChapter(ctx context.Context, userID, chapterID primitive.ObjectID) (*models.UserChapterStatus, error)
+	GetUserCourseProgress(ctx context.Context, userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	FindAll(ctx context.Context, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	FindIncomplete(ctx context.Context, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	FindForUsers(ctx context.Context, userIDs, courseIDs []primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	CountCompletedByUser(ctx context.Context, userIDs, chapterIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error)
+	// FindStartedCourses reports which of courseIDs the user has recorded progress in
+	FindStartedCourses(ctx context.Context, userID primitive.ObjectID, courseIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+	CountCompletedSince(ctx context.Context, userID primitive.ObjectID, since time.Time) (int64, error)
+	MoveActivityFlagsToComponents() error
 }
 
 type progressRepository struct {
-	collection *mongo.Collection
+	collection *scopedCollection
+	// all is the unscoped collection, only for migrations across organizations
+	all *mongo.Collection
 }
 
 func NewProgressRepository(db *mongo.Database) ProgressRepository {
-	return &progressRepository{collection: db.Collection("progress")}
+	return &progressRepository{collection: newScopedCollection(db.Collection("progress")), all: db.Collection("progress")}
 }
 
-func (r *progressRepository) FindOrCreateStatus(userID, chapterID, courseID primitive.ObjectID) (*models.UserChapterStatus, error) {
+func (r *progressRepository) FindOrCreateStatus(ctx context.Context, userID, chapterID, courseID primitive.ObjectID) (*models.UserChapterStatus, error) {
 	filter := bson.M{"user_id": userID, "chapter_id": chapterID}
 	var status models.UserChapterStatus
 
-	err := r.collection.FindOne(context.Background(), filter).Decode(&status)
+	err := r.collection.FindOne(ctx, filter, &status)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			newStatus := models.UserChapterStatus{
@@ -38,10 +50,11 @@ func (r *progressRepository) FindOrCreateStatus(userID, chapterID, courseID prim
 				ChapterID: chapterID,
 				CourseID:  courseID,
 			}
-			_, insertErr := r.collection.InsertOne(context.Background(), newStatus)
+			id, insertErr := r.collection.InsertOne(ctx, newStatus)
 			if insertErr != nil {
 				return nil, insertErr
 			}
+			newStatus.ID = id
 			return &newStatus, nil
 		}
 		return nil, err
@@ -49,13 +62,13 @@ func (r *progressRepository) FindOrCreateStatus(userID, chapterID, courseID prim
 	return &status, nil
 }
 
-func (r *progressRepository) UpdateStatus(status *models.UserChapterStatus) error {
+func (r *progressRepository) UpdateStatus(ctx context.Context, status *models.UserChapterStatus) error {
 	filter := bson.M{"_id": status.ID}
-	_, err := r.collection.UpdateOne(context.Background(), filter, bson.M{"$set": status})
+	_, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": status})
 	return err
 }
 
-func (r *progressRepository) CountCompletedChapters(userID, courseID primitive.ObjectID) (int64, error) {
+func (r *progressRepository) CountCompletedChapters(ctx context.Context, userID, courseID primitive.ObjectID) (int64, error) {
 	filter := bson.M{
 		"user_id":                userID,
 		"is_chapter_completed": true,
@@ -65,13 +78,13 @@ func (r *progressRepository) CountCompletedChapters(userID, courseID primitive.O
         filter["course_id"] = courseID
     }
 
-	return r.collection.CountDocuments(context.Background(), filter)
+	return r.collection.CountDocuments(ctx, filter)
 }
 
-func (r *progressRepository) FindByUserAndChapter(userID, chapterID primitive.ObjectID) (*models.UserChapterStatus, error) {
+func (r *progressRepository) FindByUserAndChapter(ctx context.Context, userID, chapterID primitive.ObjectID) (*models.UserChapterStatus, error) {
 	filter := bson.M{"user_id": userID, "chapter_id": chapterID}
 	var status models.UserChapterStatus
-	err := r.collection.FindOne(context.Background(), filter).Decode(&status)
+	err := r.collection.FindOne(ctx, filter, &status)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil // Return nil instead of error for no documents
@@ -81,16 +94,101 @@ func (r *progressRepository) FindByUserAndChapter(userID, chapterID primitive.Ob
 	return &status, nil
 }
 
-func (r *progressRepository) GetUserCourseProgress(userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error) {
-	filter := bson.M{"user_id": userID, "course_id": courseID}
-	cursor, err := r.collection.Find(context.Background(), filter)
+func (r *progressRepository) GetUserCourseProgress(ctx context.Context, userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error) {
+	return r.find(ctx, bson.M{"user_id": userID, "course_id": courseID})
+}
+
+// FindAll returns the progress of every user in the organization, optionally limited to one course
+func (r *progressRepository) FindAll(ctx context.Context, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error) {
+	filter := bson.M{}
+	if !courseID.IsZero() {
+		filter["course_id"] = courseID
+	}
+	return r.find(ctx, filter)
+}
+
+// FindIncomplete returns the progress on chapters of the course that were started but not completed
+func (r *progressRepository) FindIncomplete(ctx context.Context, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error) {
+	return r.find(ctx, bson.M{"course_id": courseID, "is_chapter_completed": bson.M{"$ne": true}})
+}
+
+// FindForUsers returns the progress of several users in several courses at once
+func (r *progressRepository) FindForUsers(ctx context.Context, userIDs, courseIDs []primitive.ObjectID) ([]*models.UserChapterStatus, error) {
+	return r.find(ctx, bson.M{"user_id": bson.M{"$in": userIDs}, "course_id": bson.M{"$in": courseIDs}})
+}
+
+func (r *progressRepository) FindStartedCourses(ctx context.Context, userID primitive.ObjectID, courseIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	started := make(map[primitive.ObjectID]bool, len(courseIDs))
+	if len(courseIDs) == 0 {
+		return started, nil
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "course_id": bson.M{"$in": courseIDs}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$course_id"}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		CourseID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		started[result.CourseID] = true
+	}
+	return started, nil
+}
+
+// CountCompletedByUser counts, per user, how many of chapterIDs they completed.
+// Users without any completed chapter are left out of the result.
+func (r *progressRepository) CountCompletedByUser(ctx context.Context, userIDs, chapterIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"user_id":              bson.M{"$in": userIDs},
+			"chapter_id":           bson.M{"$in": chapterIDs},
+			"is_chapter_completed": true,
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$user_id", "completed": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		UserID    primitive.ObjectID `bson:"_id"`
+		Completed int                `bson:"completed"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	counts := make(map[primitive.ObjectID]int, len(results))
+	for _, result := range results {
+		counts[result.UserID] = result.Completed
+	}
+	return counts, nil
+}
+
+// CountCompletedSince counts the chapters the user completed at or after since
+func (r *progressRepository) CountCompletedSince(ctx context.Context, userID primitive.ObjectID, since time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"user_id":              userID,
+		"is_chapter_completed": true,
+		"completed_at":         bson.M{"$gte": since},
+	})
+}
+
+func (r *progressRepository) find(ctx context.Context, filter bson.M) ([]*models.UserChapterStatus, error) {
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
 	var progressList []*models.UserChapterStatus
-	for cursor.Next(context.Background()) {
+	for cursor.Next(ctx) {
 		var progress models.UserChapterStatus
 		if err := cursor.Decode(&progress); err != nil {
 			return nil, err
@@ -103,4 +201,60 @@ func (r *progressRepository) GetUserCourseProgress(userID, courseID primitive.Ob
 	}
 
 	return progressList, nil
-}
\ No newline at end of file
+}
+
+// legacyActivityXP is what each video, quiz and slides awarded before chapters had components
+const legacyActivityXP = 25
+
+// MoveActivityFlagsToComponents migrates progress written before chapters
+// had components, in every organization. The video, quiz and slides flags
+// become the components "video", "quiz" and "ppt", matching the keys
+// CourseRepository.MoveChapterActivitiesToComponents gives those activities.
+func (r *progressRepository) MoveActivityFlagsToComponents() error {
+	ctx := context.Background()
+	legacyFields := []string{"has_viewed_video", "has_completed_quiz", "has_downloaded_ppt", "quiz_score"}
+	conditions := make([]bson.M, 0, len(legacyFields))
+	unset := bson.M{}
+	for _, field := range legacyFields {
+		conditions = append(conditions, bson.M{field: bson.M{"$exists": true}})
+		unset[field] = ""
+	}
+	cursor, err := r.all.Find(ctx, bson.M{"$or": conditions})
+	if err != nil {
+		return err
+	}
+	var legacy []struct {
+		ID               primitive.ObjectID `bson:"_id"`
+		HasViewedVideo   bool               `bson:"has_viewed_video"`
+		HasCompletedQuiz bool               `bson:"has_completed_quiz"`
+		HasDownloadedPPT bool               `bson:"has_downloaded_ppt"`
+		QuizScore        *int               `bson:"quiz_score"`
+	}
+	if err := cursor.All(ctx, &legacy); err != nil {
+		return err
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(legacy))
+	for _, status := range legacy {
+		set := bson.M{}
+		if status.HasViewedVideo {
+			set["components.video"] = models.ComponentProgress{Completed: true, XP: legacyActivityXP}
+		}
+		if status.HasCompletedQuiz || status.QuizScore != nil {
+			set["components.quiz"] = models.ComponentProgress{Completed: status.HasCompletedQuiz, BestScore: status.QuizScore, XP: legacyActivityXP}
+		}
+		if status.HasDownloadedPPT {
+			set["components.ppt"] = models.ComponentProgress{Completed: true, XP: legacyActivityXP}
+		}
+		update := bson.M{"$unset": unset}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": status.ID}).SetUpdate(update))
+	}
+	_, err = r.all.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
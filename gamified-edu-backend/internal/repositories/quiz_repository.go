@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// QuizRepository stores quizzes by slug, scoped to the organization in ctx.
+type QuizRepository interface {
+	// FindBySlug returns nil when the organization has no quiz with the slug
+	FindBySlug(ctx context.Context, slug string) (*models.Quiz, error)
+	// Upsert creates or replaces the quiz with the slug of quiz
+	Upsert(ctx context.Context, quiz *models.Quiz) error
+}
+
+type quizRepository struct {
+	collection *scopedCollection
+}
+
+func NewQuizRepository(db *mongo.Database) QuizRepository {
+	return &quizRepository{collection: newScopedCollection(db.Collection("quizzes"))}
+}
+
+func (r *quizRepository) FindBySlug(ctx context.Context, slug string) (*models.Quiz, error) {
+	var quiz models.Quiz
+	err := r.collection.FindOne(ctx, bson.M{"slug": slug}, &quiz)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+func (r *quizRepository) Upsert(ctx context.Context, quiz *models.Quiz) error {
+	update := bson.M{"$set": bson.M{
+		"title":         quiz.Title,
+		"passing_score": quiz.PassingScore,
+		"questions":     quiz.Questions,
+		"updated_at":    quiz.UpdatedAt,
+	}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"slug": quiz.Slug}, update, options.Update().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// organizationField is the tenant key stored on every tenant-owned document.
+const organizationField = "organization_id"
+
+// scopedCollection wraps a collection of tenant-owned documents. Every
+// operation reads the organization from the context and adds it to the
+// filter, or to the document on insert. Operations fail with
+// tenant.ErrNoOrganization when the context has no organization, so a
+// repository method cannot accidentally run unscoped.
+type scopedCollection struct {
+	collection *mongo.Collection
+}
+
+func newScopedCollection(collection *mongo.Collection) *scopedCollection {
+	return &scopedCollection{collection: collection}
+}
+
+func (s *scopedCollection) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	scoped, err := scopeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.collection.Find(ctx, scoped, opts...)
+}
+
+// FindOne decodes the first matching document into result.
+func (s *scopedCollection) FindOne(ctx context.Context, filter bson.M, result interface{}, opts ...*options.FindOneOptions) error {
+	scoped, err := scopeFilter(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return s.collection.FindOne(ctx, scoped, opts...).Decode(result)
+}
+
+func (s *scopedCollection) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	scoped, err := scopeFilter(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return s.collection.CountDocuments(ctx, scoped)
+}
+
+// InsertOne stores document with the context's organization and returns the new _id.
+func (s *scopedCollection) InsertOne(ctx context.Context, document interface{}) (primitive.ObjectID, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	doc, err := toDocument(document)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	doc[organizationField] = organizationID
+
+	result, err := s.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	id, _ := result.InsertedID.(primitive.ObjectID)
+	return id, nil
+}
+
+func (s *scopedCollection) UpdateOne(ctx context.Context, filter bson.M, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	scoped, scopedUpdate, err := scopeUpdate(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+	return s.collection.UpdateOne(ctx, scoped, scopedUpdate, opts...)
+}
+
+func (s *scopedCollection) UpdateMany(ctx context.Context, filter bson.M, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	scoped, scopedUpdate, err := scopeUpdate(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+	return s.collection.UpdateMany(ctx, scoped, scopedUpdate, opts...)
+}
+
+func (s *scopedCollection) DeleteOne(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	scoped, err := scopeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.collection.DeleteOne(ctx, scoped)
+}
+
+func (s *scopedCollection) DeleteMany(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	scoped, err := scopeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.collection.DeleteMany(ctx, scoped)
+}
+
+// Aggregate runs pipeline on the organization's documents only.
+func (s *scopedCollection) Aggregate(ctx context.Context, pipeline mongo.Pipeline) (*mongo.Cursor, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	match := bson.D{{Key: "$match", Value: bson.M{organizationField: organizationID}}}
+	scoped := append(mongo.Pipeline{match}, pipeline...)
+	return s.collection.Aggregate(ctx, scoped)
+}
+
+// scopeFilter copies filter and pins it to the context's organization,
+// overriding any organization the caller may have put in it.
+func scopeFilter(ctx context.Context, filter bson.M) (bson.M, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := bson.M{}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+	scoped[organizationField] = organizationID
+	return scoped, nil
+}
+
+// scopeUpdate scopes the filter and makes sure the update cannot move a
+// document to another organization.
+func scopeUpdate(ctx context.Context, filter bson.M, update interface{}) (bson.M, bson.M, error) {
+	scoped, err := scopeFilter(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := toDocument(update)
+	if err != nil {
+		return nil, nil, err
+	}
+	for operator, value := range doc {
+		fields, ok := value.(bson.M)
+		if !ok {
+			continue
+		}
+		switch operator {
+		case "$set", "$setOnInsert":
+			fields[organizationField] = scoped[organizationField]
+		default:
+			delete(fields, organizationField)
+		}
+	}
+	return scoped, doc, nil
+}
+
+// toDocument converts a struct or map into a bson.M by round-tripping it through BSON.
+func toDocument(value interface{}) (bson.M, error) {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SCORMPackageRepository stores uploaded SCORM packages, scoped to the organization in ctx.
+type SCORMPackageRepository interface {
+	Create(ctx context.Context, pkg *models.SCORMPackage) error
+	// FindByID returns nil when the organization has no such package
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.SCORMPackage, error)
+}
+
+type scormPackageRepository struct {
+	collection *scopedCollection
+}
+
+func NewSCORMPackageRepository(db *mongo.Database) SCORMPackageRepository {
+	return &scormPackageRepository{collection: newScopedCollection(db.Collection("scorm_packages"))}
+}
+
+// Create stores the package under its ID, which names its files in the package store
+func (r *scormPackageRepository) Create(ctx context.Context, pkg *models.SCORMPackage) error {
+	_, err := r.collection.InsertOne(ctx, pkg)
+	return err
+}
+
+func (r *scormPackageRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.SCORMPackage, error) {
+	var pkg models.SCORMPackage
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}, &pkg)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// SCORMAttemptRepository stores learners' SCORM runtime data, one attempt per
+// learner and component, scoped to the organization in ctx.
+type SCORMAttemptRepository interface {
+	// Find returns nil when the learner never launched the component
+	Find(ctx context.Context, userID, chapterID primitive.ObjectID, componentKey string) (*models.SCORMAttempt, error)
+	// Save creates or replaces the learner's attempt on the component
+	Save(ctx context.Context, attempt *models.SCORMAttempt) error
+}
+
+type scormAttemptRepository struct {
+	collection *scopedCollection
+}
+
+func NewSCORMAttemptRepository(db *mongo.Database) SCORMAttemptRepository {
+	return &scormAttemptRepository{collection: newScopedCollection(db.Collection("scorm_attempts"))}
+}
+
+func (r *scormAttemptRepository) Find(ctx context.Context, userID, chapterID primitive.ObjectID, componentKey string) (*models.SCORMAttempt, error) {
+	var attempt models.SCORMAttempt
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "chapter_id": chapterID, "component_key": componentKey}, &attempt)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *scormAttemptRepository) Save(ctx context.Context, attempt *models.SCORMAttempt) error {
+	filter := bson.M{"user_id": attempt.UserID, "chapter_id": attempt.ChapterID, "component_key": attempt.ComponentKey}
+	update := bson.M{"$set": bson.M{
+		"course_id":       attempt.CourseID,
+		"package_id":      attempt.PackageID,
+		"sco_id":          attempt.SCOID,
+		"values":          attempt.Values,
+		"total_seconds":   attempt.TotalSeconds,
+		"session_seconds": attempt.SessionSeconds,
+		"suspended":       attempt.Suspended,
+		"completed":       attempt.Completed,
+		"score":           attempt.Score,
+		"created_at":      attempt.CreatedAt,
+		"updated_at":      attempt.UpdatedAt,
+	}}
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
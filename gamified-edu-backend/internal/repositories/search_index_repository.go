@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"gamified-edu-backend/internal/search"
+	"os"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SearchIndexRepository is a search.Index on a MongoDB text index, scoped
+// to the organization in ctx. MongoDB ranks the hits; typos are corrected
+// against the indexed words before the query reaches it.
+type SearchIndexRepository interface {
+	search.Index
+	// EnsureTextIndex creates the text index the searches need
+	EnsureTextIndex() error
+}
+
+type searchIndexRepository struct {
+	collection *scopedCollection
+	// all is the unscoped collection, only for creating its indexes
+	all *mongo.Collection
+}
+
+func NewSearchIndexRepository(db *mongo.Database) SearchIndexRepository {
+	return &searchIndexRepository{collection: newScopedCollection(db.Collection("search_documents")), all: db.Collection("search_documents")}
+}
+
+// SearchIndexFromEnv returns the index SEARCH_INDEX names: "memory", the
+// default, or "mongo" for the text index in db, which instances share.
+func SearchIndexFromEnv(db *mongo.Database) (search.Index, error) {
+	switch kind := os.Getenv("SEARCH_INDEX"); kind {
+	case "", "memory":
+		return search.NewMemoryIndex(), nil
+	case "mongo":
+		index := NewSearchIndexRepository(db)
+		if err := index.EnsureTextIndex(); err != nil {
+			return nil, err
+		}
+		return index, nil
+	default:
+		return nil, fmt.Errorf("unknown SEARCH_INDEX %q, use memory or mongo", kind)
+	}
+}
+
+// searchDocument is a search.Document as stored
+type searchDocument struct {
+	Kind         string             `bson:"kind"`
+	CourseID     primitive.ObjectID `bson:"course_id"`
+	ChapterID    primitive.ObjectID `bson:"chapter_id,omitempty"`
+	CourseTitle  string             `bson:"course_title,omitempty"`
+	Title        string             `bson:"title"`
+	Body         string             `bson:"body"`
+	Tags         []string           `bson:"tags"`
+	Difficulty   string             `bson:"difficulty,omitempty"`
+	DurationMins int                `bson:"duration_mins"`
+	// Terms are the document's words, the vocabulary typos are corrected against
+	Terms []string `bson:"terms"`
+	// Score is the text score of a search hit
+	Score float64 `bson:"score,omitempty"`
+}
+
+func (r *searchIndexRepository) EnsureTextIndex() error {
+	_, err := r.all.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "title", Value: "text"}, {Key: "tags", Value: "text"}, {Key: "body", Value: "text"}},
+			Options: options.Index().SetName("search_text").
+				SetWeights(bson.M{"title": 3, "tags": 2, "body": 1}),
+		},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "course_id", Value: 1}}},
+	})
+	return err
+}
+
+func (r *searchIndexRepository) ReplaceCourse(ctx context.Context, courseID primitive.ObjectID, documents []search.Document) error {
+	if err := r.RemoveCourse(ctx, courseID); err != nil {
+		return err
+	}
+	for _, document := range documents {
+		terms := map[string]bool{}
+		for _, text := range append([]string{document.Title, document.Body}, document.Tags...) {
+			for _, term := range search.Tokenize(text) {
+				terms[term] = true
+			}
+		}
+		stored := searchDocument{
+			Kind:         document.Kind,
+			CourseID:     document.CourseID,
+			ChapterID:    document.ChapterID,
+			CourseTitle:  document.CourseTitle,
+			Title:        document.Title,
+			Body:         document.Body,
+			Tags:         document.Tags,
+			Difficulty:   document.Difficulty,
+			DurationMins: document.DurationMins,
+			Terms:        make([]string, 0, len(terms)),
+		}
+		for term := range terms {
+			stored.Terms = append(stored.Terms, term)
+		}
+		if _, err := r.collection.InsertOne(ctx, stored); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *searchIndexRepository) RemoveCourse(ctx context.Context, courseID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"course_id": courseID})
+	return err
+}
+
+func (r *searchIndexRepository) Search(ctx context.Context, query search.Query) ([]search.Hit, error) {
+	filter := searchFilter(query)
+	findOptions := options.Find()
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	queryTerms := search.Tokenize(query.Text)
+	var matched []string
+	if len(queryTerms) == 0 {
+		filter["kind"] = search.KindCourse
+		findOptions.SetSort(bson.D{{Key: "title", Value: 1}})
+	} else {
+		vocabulary, err := r.vocabulary(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// MongoDB matches any of the words; each query word brings the words it may mean
+		searchTerms := map[string]bool{}
+		for i, term := range queryTerms {
+			searchTerms[term] = true
+			for _, match := range search.Expand(term, i == len(queryTerms)-1, vocabulary) {
+				searchTerms[match.Term] = true
+			}
+		}
+		for term := range searchTerms {
+			matched = append(matched, term)
+		}
+		sort.Strings(matched)
+		filter["$text"] = bson.M{"$search": strings.Join(matched, " ")}
+		score := bson.M{"$meta": "textScore"}
+		findOptions.SetProjection(bson.M{"score": score}).SetSort(bson.D{{Key: "score", Value: score}})
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	var stored []searchDocument
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	hits := make([]search.Hit, 0, len(stored))
+	for _, document := range stored {
+		hits = append(hits, search.Hit{
+			Document: search.Document{
+				Kind:         document.Kind,
+				CourseID:     document.CourseID,
+				ChapterID:    document.ChapterID,
+				CourseTitle:  document.CourseTitle,
+				Title:        document.Title,
+				Body:         document.Body,
+				Tags:         document.Tags,
+				Difficulty:   document.Difficulty,
+				DurationMins: document.DurationMins,
+			},
+			Score: document.Score,
+			Terms: matched,
+		})
+	}
+	return hits, nil
+}
+
+func searchFilter(query search.Query) bson.M {
+	filter := bson.M{}
+	if query.Tag != "" {
+		filter["tags"] = query.Tag
+	}
+	if query.Difficulty != "" {
+		filter["difficulty"] = query.Difficulty
+	}
+	duration := bson.M{}
+	if query.MinDuration > 0 {
+		duration["$gte"] = query.MinDuration
+	}
+	if query.MaxDuration > 0 {
+		duration["$lte"] = query.MaxDuration
+	}
+	if len(duration) > 0 {
+		filter["duration_mins"] = duration
+	}
+	return filter
+}
+
+// vocabulary returns every word indexed for the organization
+func (r *searchIndexRepository) vocabulary(ctx context.Context) ([]string, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$unwind", Value: "$terms"}},
+		{{Key: "$group", Value: bson.M{"_id": "$terms"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var terms []struct {
+		Term string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &terms); err != nil {
+		return nil, err
+	}
+	vocabulary := make([]string, 0, len(terms))
+	for _, term := range terms {
+		vocabulary = append(vocabulary, term.Term)
+	}
+	return vocabulary, nil
+}
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SecurityPolicyRepository stores the platform-wide security policy document.
+type SecurityPolicyRepository interface {
+	Get() (*models.SecurityPolicy, error)
+	Save(policy *models.SecurityPolicy) error
+}
+
+type securityPolicyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSecurityPolicyRepository(db *mongo.Database) SecurityPolicyRepository {
+	return &securityPolicyRepository{collection: db.Collection("settings")}
+}
+
+// Get returns the stored policy, or an empty default policy if none was saved yet.
+func (r *securityPolicyRepository) Get() (*models.SecurityPolicy, error) {
+	var policy models.SecurityPolicy
+	err := r.collection.FindOne(context.Background(), bson.M{"_id": models.SecurityPolicyID}).Decode(&policy)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &models.SecurityPolicy{ID: models.SecurityPolicyID}, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *securityPolicyRepository) Save(policy *models.SecurityPolicy) error {
+	policy.ID = models.SecurityPolicyID
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.collection.ReplaceOne(context.Background(), bson.M{"_id": policy.ID}, policy, opts)
+	return err
+}
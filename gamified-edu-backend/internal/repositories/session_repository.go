@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	FindByID(id primitive.ObjectID) (*models.Session, error)
+	// FindActivePageByUser returns up to limit of the user's active sessions
+	// after the given one, most recently used first
+	FindActivePageByUser(userID primitive.ObjectID, after *models.Session, limit int) ([]models.Session, error)
+	Revoke(userID, sessionID primitive.ObjectID) (bool, error)
+	RevokeAllForUser(userID, exceptSessionID primitive.ObjectID) ([]primitive.ObjectID, error)
+	UpdateLastSeen(sessionID primitive.ObjectID, lastSeen time.Time) error
+}
+
+type sessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) SessionRepository {
+	return &sessionRepository{collection: db.Collection("sessions")}
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+	result, err := r.collection.InsertOne(context.Background(), session)
+	if err != nil {
+		return err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *sessionRepository) FindByID(id primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindActivePageByUser pages sessions that are neither revoked nor expired.
+func (r *sessionRepository) FindActivePageByUser(userID primitive.ObjectID, after *models.Session, limit int) ([]models.Session, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	if after != nil {
+		filter["$or"] = bson.A{
+			bson.M{"last_seen_at": bson.M{"$lt": after.LastSeenAt}},
+			bson.M{"last_seen_at": after.LastSeenAt, "_id": bson.M{"$lt": after.ID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []models.Session{}
+	if err := cursor.All(context.Background(), &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Revoke marks one of the user's sessions as revoked and reports whether it existed.
+func (r *sessionRepository) Revoke(userID, sessionID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": sessionID, "user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	result, err := r.collection.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// RevokeAllForUser revokes every active session of the user except exceptSessionID
+// (pass primitive.NilObjectID to revoke all) and returns the revoked session IDs.
+func (r *sessionRepository) RevokeAllForUser(userID, exceptSessionID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	if !exceptSessionID.IsZero() {
+		filter["_id"] = bson.M{"$ne": exceptSessionID}
+	}
+
+	// Collect the IDs first so callers can drop them from any cache
+	cursor, err := r.collection.Find(context.Background(), filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(context.Background(), &docs); err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+	_, err = r.collection.UpdateMany(context.Background(), bson.M{"_id": bson.M{"$in": ids}}, update)
+	return ids, err
+}
+
+func (r *sessionRepository) UpdateLastSeen(sessionID primitive.ObjectID, lastSeen time.Time) error {
+	filter := bson.M{"_id": sessionID}
+	_, err := r.collection.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"last_seen_at": lastSeen}})
+	return err
+}
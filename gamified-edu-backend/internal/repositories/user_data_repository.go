@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userDataCollection describes a collection whose documents are keyed by a user.
+type userDataCollection struct {
+	name      string
+	userField string
+	// filter narrows the documents that belong to the user, e.g. by owner type
+	filter bson.M
+	// anonymize keeps the documents on account deletion and only detaches them
+	// from the user, for records other people still rely on
+	anonymize bool
+	// pull keeps the documents on account deletion and only removes the user
+	// from userField, an array of the users a shared record lists
+	pull bool
+	// exclude lists fields left out of personal data exports, e.g. secrets
+	exclude []string
+	// global collections are not split by organization, so exports take all
+	// of the user's documents instead of those of one organization
+	global bool
+}
+
+// userDataCollections lists every collection that holds per-user documents.
+// Account deletion and personal data exports both walk this list, so any new
+// collection keyed by a user must be registered here.
+var userDataCollections = []userDataCollection{
+	{name: "progress", userField: "user_id"},
+	{name: "activities", userField: "user_id"},
+	{name: "enrollments", userField: "user_id"},
+	{name: "path_enrollments", userField: "user_id"},
+	{name: "course_reviews", userField: "user_id"},
+	{name: "review_reports", userField: "user_id"},
+	{name: "scorm_attempts", userField: "user_id"},
+	// Learning records name the learner in their actor; copies already
+	// forwarded to the district's LRS are the district's to delete
+	{name: "xapi_statements", userField: "user_id"},
+	{name: "sessions", userField: "user_id", global: true},
+	{name: "api_keys", userField: "user_id", filter: bson.M{"owner_type": models.APIKeyOwnerUser}, exclude: []string{"key_hash"}, global: true},
+	// Organization keys stay with the organization when their creator leaves
+	{name: "api_keys", userField: "user_id", filter: bson.M{"owner_type": models.APIKeyOwnerOrganization}, anonymize: true, exclude: []string{"key_hash"}},
+	{name: "data_exports", userField: "user_id", exclude: []string{"file_path"}},
+	{name: "classroom_members", userField: "user_id"},
+	// Classrooms and assignments stay with their students when the instructor leaves
+	{name: "classrooms", userField: "instructor_id", anonymize: true},
+	{name: "assignments", userField: "created_by", anonymize: true},
+	{name: "learning_paths", userField: "created_by", anonymize: true},
+	{name: "cohorts", userField: "created_by", anonymize: true},
+	// Packages and assets stay with the courses using them when their uploader leaves
+	{name: "scorm_packages", userField: "uploaded_by", anonymize: true},
+	{name: "media_assets", userField: "uploaded_by", anonymize: true},
+	// Replies stay on the reviews they answer when their author leaves
+	{name: "course_reviews", userField: "reply.author_id", anonymize: true},
+	// LMS sign-ins and course picks go with the account; the LMS links it
+	// launched stay for the other learners, without it
+	{name: "lti_identities", userField: "user_id"},
+	{name: "lti_deep_links", userField: "user_id"},
+	{name: "lti_course_links", userField: "learner_ids", pull: true, exclude: []string{"learner_ids"}},
+	// Courses stay when an author leaves; the author comes off the credits
+	// and the drafts and versions they saved. Exports leave out the content
+	// and the other authors.
+	{name: "courses", userField: "author_ids", pull: true, exclude: courseExcludedFields},
+	{name: "courses", userField: "draft.updated_by", anonymize: true, exclude: courseExcludedFields},
+	{name: "course_versions", userField: "published_by", anonymize: true, exclude: []string{"modules"}},
+	// Guardian links end with either side's account
+	{name: "guardian_links", userField: "student_id", exclude: []string{"invite_token_hash"}},
+	{name: "guardian_links", userField: "guardian_id", exclude: []string{"invite_token_hash"}},
+}
+
+// courseExcludedFields are the course content and co-authors left out of exports
+var courseExcludedFields = []string{"modules", "draft.modules", "author_ids"}
+
+// userExcludedFields are the credentials left out of the exported user document.
+var userExcludedFields = []string{"password_hash", "two_factor.secret", "two_factor.recovery_code_hashes", "two_factor.last_used_step"}
+
+// UserDataSet holds the documents of one collection that belong to a user.
+type UserDataSet struct {
+	Collection string
+	Documents  []bson.M
+}
+
+// UserDataRepository operates on all of a user's data across collections.
+type UserDataRepository interface {
+	// ExportAllForUser exports the user's data in the organization in ctx
+	ExportAllForUser(ctx context.Context, userID primitive.ObjectID) ([]UserDataSet, error)
+	DeleteAllForUser(userID primitive.ObjectID) error
+}
+
+type userDataRepository struct {
+	db *mongo.Database
+}
+
+func NewUserDataRepository(db *mongo.Database) UserDataRepository {
+	return &userDataRepository{db: db}
+}
+
+// ExportAllForUser returns the user document followed by every registered
+// collection's documents for the user, with secrets left out. The user must
+// belong to the organization in ctx, and data they left in other
+// organizations stays out.
+func (r *userDataRepository) ExportAllForUser(ctx context.Context, userID primitive.ObjectID) ([]UserDataSet, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := r.findAll(ctx, "users", bson.M{"_id": userID, organizationField: organizationID}, userExcludedFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	sets := []UserDataSet{{Collection: "users", Documents: users}}
+
+	// Collections registered more than once are merged into a single set,
+	// with documents found through several fields only once
+	index := map[string]int{}
+	seen := map[string]map[any]bool{}
+	for _, source := range userDataCollections {
+		filter := source.userFilter(userID)
+		if !source.global {
+			filter[organizationField] = organizationID
+		}
+		documents, err := r.findAll(ctx, source.name, filter, source.exclude)
+		if err != nil {
+			return nil, err
+		}
+		if seen[source.name] == nil {
+			seen[source.name] = map[any]bool{}
+		}
+		unseen := documents[:0]
+		for _, document := range documents {
+			if !seen[source.name][document["_id"]] {
+				seen[source.name][document["_id"]] = true
+				unseen = append(unseen, document)
+			}
+		}
+		if i, ok := index[source.name]; ok {
+			sets[i].Documents = append(sets[i].Documents, unseen...)
+			continue
+		}
+		index[source.name] = len(sets)
+		sets = append(sets, UserDataSet{Collection: source.name, Documents: unseen})
+	}
+	return sets, nil
+}
+
+func (r *userDataRepository) findAll(ctx context.Context, collection string, filter bson.M, exclude []string) ([]bson.M, error) {
+	opts := options.Find()
+	if len(exclude) > 0 {
+		projection := bson.M{}
+		for _, field := range exclude {
+			projection[field] = 0
+		}
+		opts.SetProjection(projection)
+	}
+
+	cursor, err := r.db.Collection(collection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	documents := []bson.M{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// DeleteAllForUser removes or anonymizes every registered document of the
+// user and finally deletes the user document itself.
+func (r *userDataRepository) DeleteAllForUser(userID primitive.ObjectID) error {
+	ctx := context.Background()
+	for _, source := range userDataCollections {
+		collection := r.db.Collection(source.name)
+		filter := source.userFilter(userID)
+
+		var err error
+		switch {
+		case source.anonymize:
+			_, err = collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{source.userField: primitive.NilObjectID}})
+		case source.pull:
+			_, err = collection.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{source.userField: userID}})
+		default:
+			_, err = collection.DeleteMany(ctx, filter)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.Collection("users").DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
+
+func (c userDataCollection) userFilter(userID primitive.ObjectID) bson.M {
+	filter := bson.M{c.userField: userID}
+	for key, value := range c.filter {
+		filter[key] = value
+	}
+	return filter
+}
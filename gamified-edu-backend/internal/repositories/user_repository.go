@@ -6,13 +6,30 @@ import (
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
     "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
+    "time"
 )
 
 type UserRepository interface {
     Create(user *models.User) error
     FindByEmail(email string) (*models.User, error)
     FindByID(id primitive.ObjectID) (*models.User, error)
+    FindByIDs(ids []primitive.ObjectID) ([]models.User, error)
     UpdateXPAndLevel(user *models.User) error
+    UpdateTwoFactor(userID primitive.ObjectID, settings models.TwoFactorSettings) error
+    // UseTOTPStep records step as the user's last used TOTP step unless it
+    // is not newer than the recorded one, which reports false
+    UseTOTPStep(userID primitive.ObjectID, step int64) (bool, error)
+    // UseRecoveryCode removes the recovery code hash, reporting false if it
+    // was already used
+    UseRecoveryCode(userID primitive.ObjectID, hash string) (bool, error)
+    // RecordTwoFactorFailure counts an invalid code and returns the count
+    // since the last valid one
+    RecordTwoFactorFailure(userID primitive.ObjectID) (int, error)
+    // LockTwoFactor refuses the user's codes until the given time
+    LockTwoFactor(userID primitive.ObjectID, until time.Time) error
+    UpdateProfile(user *models.User) error
+    UpdateOrganization(userID, organizationID primitive.ObjectID) error
 }
 
 type userRepository struct {
@@ -40,10 +57,99 @@ func (r *userRepository) FindByID(id primitive.ObjectID) (*models.User, error) {
     return &user, err
 }
 
+func (r *userRepository) FindByIDs(ids []primitive.ObjectID) ([]models.User, error) {
+    cursor, err := r.collection.Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}})
+    if err != nil {
+        return nil, err
+    }
+    users := []models.User{}
+    if err := cursor.All(context.Background(), &users); err != nil {
+        return nil, err
+    }
+    return users, nil
+}
+
 // Updates both XP and Level in a single database call
 func (r *userRepository) UpdateXPAndLevel(user *models.User) error {
     filter := bson.M{"_id": user.ID}
     update := bson.M{"$set": bson.M{"xp": user.XP, "level": user.Level}}
     _, err := r.collection.UpdateOne(context.Background(), filter, update)
     return err
-}
\ No newline at end of file
+}
+
+// Replaces the user's whole two-factor sub-document
+func (r *userRepository) UpdateTwoFactor(userID primitive.ObjectID, settings models.TwoFactorSettings) error {
+    filter := bson.M{"_id": userID}
+    update := bson.M{"$set": bson.M{"two_factor": settings}}
+    _, err := r.collection.UpdateOne(context.Background(), filter, update)
+    return err
+}
+
+// Sets the step in a single update that only matches older steps, so two
+// requests with the same code cannot both succeed
+func (r *userRepository) UseTOTPStep(userID primitive.ObjectID, step int64) (bool, error) {
+    filter := bson.M{"_id": userID, "$or": bson.A{
+        bson.M{"two_factor.last_used_step": bson.M{"$lt": step}},
+        bson.M{"two_factor.last_used_step": bson.M{"$exists": false}},
+    }}
+    update := bson.M{
+        "$set":   bson.M{"two_factor.last_used_step": step},
+        "$unset": bson.M{"two_factor.failed_attempts": "", "two_factor.locked_until": ""},
+    }
+    result, err := r.collection.UpdateOne(context.Background(), filter, update)
+    if err != nil {
+        return false, err
+    }
+    return result.ModifiedCount == 1, nil
+}
+
+func (r *userRepository) UseRecoveryCode(userID primitive.ObjectID, hash string) (bool, error) {
+    filter := bson.M{"_id": userID, "two_factor.recovery_code_hashes": hash}
+    update := bson.M{
+        "$pull":  bson.M{"two_factor.recovery_code_hashes": hash},
+        "$unset": bson.M{"two_factor.failed_attempts": "", "two_factor.locked_until": ""},
+    }
+    result, err := r.collection.UpdateOne(context.Background(), filter, update)
+    if err != nil {
+        return false, err
+    }
+    return result.ModifiedCount == 1, nil
+}
+
+func (r *userRepository) RecordTwoFactorFailure(userID primitive.ObjectID) (int, error) {
+    var user models.User
+    err := r.collection.FindOneAndUpdate(context.Background(),
+        bson.M{"_id": userID},
+        bson.M{"$inc": bson.M{"two_factor.failed_attempts": 1}},
+        options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"two_factor.failed_attempts": 1}),
+    ).Decode(&user)
+    return user.TwoFactor.FailedAttempts, err
+}
+
+// Starts the count of failures over once the lock ends
+func (r *userRepository) LockTwoFactor(userID primitive.ObjectID, until time.Time) error {
+    filter := bson.M{"_id": userID}
+    update := bson.M{"$set": bson.M{"two_factor.locked_until": until, "two_factor.failed_attempts": 0}}
+    _, err := r.collection.UpdateOne(context.Background(), filter, update)
+    return err
+}
+
+// Updates the user-editable name and profile fields
+func (r *userRepository) UpdateProfile(user *models.User) error {
+    filter := bson.M{"_id": user.ID}
+    update := bson.M{"$set": bson.M{
+        "first_name": user.FirstName,
+        "last_name":  user.LastName,
+        "profile":    user.Profile,
+    }}
+    _, err := r.collection.UpdateOne(context.Background(), filter, update)
+    return err
+}
+
+// Moves the user to another organization
+func (r *userRepository) UpdateOrganization(userID, organizationID primitive.ObjectID) error {
+    filter := bson.M{"_id": userID}
+    update := bson.M{"$set": bson.M{"organization_id": organizationID}}
+    _, err := r.collection.UpdateOne(context.Background(), filter, update)
+    return err
+}
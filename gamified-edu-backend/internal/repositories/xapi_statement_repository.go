@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// XAPIStatementQuery selects statements the way the xAPI statements resource
+// does. Empty fields do not filter.
+type XAPIStatementQuery struct {
+	ActorKey     string
+	VerbID       string
+	ActivityID   string
+	Registration string
+	// Since and Until bound the time statements were stored
+	Since *time.Time
+	Until *time.Time
+	// After continues a listing after the statement with this _id
+	After     primitive.ObjectID
+	Ascending bool
+	Limit     int
+}
+
+// XAPIStatementRepository stores xAPI statements, scoped to the organization
+// in ctx. Forwarding to the LRS runs across all organizations.
+type XAPIStatementRepository interface {
+	Insert(ctx context.Context, statement *models.XAPIStatement) error
+	// FindByStatementID returns nil when the organization has no such statement
+	FindByStatementID(ctx context.Context, statementID string) (*models.XAPIStatement, error)
+	MarkVoided(ctx context.Context, statementID string) error
+	// Query leaves out voided statements
+	Query(ctx context.Context, query XAPIStatementQuery) ([]models.XAPIStatement, error)
+	FindDueForForwarding(now time.Time, limit int) ([]models.XAPIStatement, error)
+	MarkForwarded(ids []primitive.ObjectID, at time.Time) error
+	// ScheduleForwardRetry records a failed delivery; giveUp stops further attempts
+	ScheduleForwardRetry(id primitive.ObjectID, attempts int, next time.Time, lastError string, giveUp bool) error
+	// AssignUsers sets the user ID of statements stored before it was kept,
+	// for actors whose key is accountPrefix followed by a user ID
+	AssignUsers(accountPrefix string) error
+}
+
+type xapiStatementRepository struct {
+	collection *scopedCollection
+	// all is the unscoped collection, only for forwarding
+	all *mongo.Collection
+}
+
+func NewXAPIStatementRepository(db *mongo.Database) XAPIStatementRepository {
+	return &xapiStatementRepository{collection: newScopedCollection(db.Collection("xapi_statements")), all: db.Collection("xapi_statements")}
+}
+
+func (r *xapiStatementRepository) Insert(ctx context.Context, statement *models.XAPIStatement) error {
+	id, err := r.collection.InsertOne(ctx, statement)
+	if err != nil {
+		return err
+	}
+	statement.ID = id
+	return nil
+}
+
+func (r *xapiStatementRepository) FindByStatementID(ctx context.Context, statementID string) (*models.XAPIStatement, error) {
+	var statement models.XAPIStatement
+	err := r.collection.FindOne(ctx, bson.M{"statement_id": statementID}, &statement)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &statement, nil
+}
+
+func (r *xapiStatementRepository) MarkVoided(ctx context.Context, statementID string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"statement_id": statementID}, bson.M{"$set": bson.M{"voided": true}})
+	return err
+}
+
+func (r *xapiStatementRepository) Query(ctx context.Context, query XAPIStatementQuery) ([]models.XAPIStatement, error) {
+	filter := bson.M{"voided": false}
+	if query.ActorKey != "" {
+		filter["actor_key"] = query.ActorKey
+	}
+	if query.VerbID != "" {
+		filter["verb_id"] = query.VerbID
+	}
+	if query.ActivityID != "" {
+		filter["activity_id"] = query.ActivityID
+	}
+	if query.Registration != "" {
+		filter["registration"] = query.Registration
+	}
+	stored := bson.M{}
+	if query.Since != nil {
+		stored["$gt"] = *query.Since
+	}
+	if query.Until != nil {
+		stored["$lte"] = *query.Until
+	}
+	if len(stored) > 0 {
+		filter["stored"] = stored
+	}
+
+	order := -1
+	if query.Ascending {
+		order = 1
+	}
+	if !query.After.IsZero() {
+		if query.Ascending {
+			filter["_id"] = bson.M{"$gt": query.After}
+		} else {
+			filter["_id"] = bson.M{"$lt": query.After}
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: order}})
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	statements := []models.XAPIStatement{}
+	if err := cursor.All(ctx, &statements); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// FindDueForForwarding returns the oldest statements of any organization
+// that are waiting for delivery and due for an attempt
+func (r *xapiStatementRepository) FindDueForForwarding(now time.Time, limit int) ([]models.XAPIStatement, error) {
+	ctx := context.Background()
+	filter := bson.M{"forward.pending": true, "forward.next_attempt_at": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.all.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	statements := []models.XAPIStatement{}
+	if err := cursor.All(ctx, &statements); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+func (r *xapiStatementRepository) MarkForwarded(ids []primitive.ObjectID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	update := bson.M{
+		"$set":   bson.M{"forward.pending": false, "forward.forwarded_at": at},
+		"$unset": bson.M{"forward.last_error": ""},
+	}
+	_, err := r.all.UpdateMany(context.Background(), bson.M{"_id": bson.M{"$in": ids}}, update)
+	return err
+}
+
+func (r *xapiStatementRepository) ScheduleForwardRetry(id primitive.ObjectID, attempts int, next time.Time, lastError string, giveUp bool) error {
+	update := bson.M{"$set": bson.M{
+		"forward.pending":         !giveUp,
+		"forward.attempts":        attempts,
+		"forward.next_attempt_at": next,
+		"forward.last_error":      lastError,
+	}}
+	_, err := r.all.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *xapiStatementRepository) AssignUsers(accountPrefix string) error {
+	filter := bson.M{
+		"user_id":   bson.M{"$exists": false},
+		"actor_key": bson.M{"$regex": "^" + regexp.QuoteMeta(accountPrefix) + "[0-9a-f]{24}$"},
+	}
+	userID := bson.M{"$substrCP": bson.A{"$actor_key", utf8.RuneCountInString(accountPrefix), 24}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"user_id": bson.M{"$toObjectId": userID}}}}}
+	_, err := r.all.UpdateMany(context.Background(), filter, update)
+	return err
+}
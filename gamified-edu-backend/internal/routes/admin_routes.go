@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(router *gin.RouterGroup, twoFactorCtrl *controllers.TwoFactorController, sessionCtrl *controllers.SessionController, apiKeyCtrl *controllers.APIKeyController, exportCtrl *controllers.DataExportController, coursePackCtrl *controllers.CoursePackController, ltiCtrl *controllers.LTIController, reviewCtrl *controllers.ReviewController, users middleware.MemberFinder, auth *middleware.Authenticator) {
+	admin := router.Group("/admin")
+	admin.Use(auth.RequireAuth(), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/security/two-factor", twoFactorCtrl.GetPolicy)
+		admin.PUT("/security/two-factor", twoFactorCtrl.UpdatePolicy)
+		// Admins only act on the users and keys of their own organization
+		member := middleware.RequireOrganizationMember(users, "userId")
+		admin.DELETE("/users/:userId/sessions", member, sessionCtrl.RevokeUserSessions)
+		admin.POST("/users/:userId/exports", member, exportCtrl.RequestExportForUser)
+		admin.GET("/users/:userId/exports", member, exportCtrl.ListExportsForUser)
+		ownOrganization := middleware.RequireOwnOrganization("organizationId")
+		admin.POST("/api-keys", apiKeyCtrl.CreateOrganizationKey)
+		admin.GET("/organizations/:organizationId/api-keys", ownOrganization, apiKeyCtrl.ListOrganizationKeys)
+		admin.DELETE("/organizations/:organizationId/api-keys/:keyId", ownOrganization, apiKeyCtrl.RevokeOrganizationKey)
+		// Course bundles move content between environments
+		admin.GET("/coursepack/export", coursePackCtrl.Export)
+		admin.POST("/coursepack/import", coursePackCtrl.Import)
+		// LMS platforms that launch the organization's courses
+		admin.GET("/lti/tool", ltiCtrl.GetToolConfiguration)
+		admin.POST("/lti/platforms", ltiCtrl.RegisterPlatform)
+		admin.GET("/lti/platforms", ltiCtrl.ListPlatforms)
+		admin.DELETE("/lti/platforms/:platformId", ltiCtrl.DeletePlatform)
+		// Reviews flagged by learners' reports wait for a moderator here
+		admin.GET("/reviews", reviewCtrl.ListReviewsForModeration)
+		admin.PUT("/reviews/:reviewId/moderation", reviewCtrl.ModerateReview)
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func APIKeyRoutes(router *gin.RouterGroup, ctrl *controllers.APIKeyController, auth *middleware.Authenticator) {
+	apiKeys := router.Group("/api-keys")
+	apiKeys.Use(auth.RequireAuth())
+	{
+		apiKeys.GET("/", ctrl.ListKeys)
+		apiKeys.POST("/", ctrl.CreateKey)
+		apiKeys.DELETE("/:keyId", ctrl.RevokeKey)
+	}
+}
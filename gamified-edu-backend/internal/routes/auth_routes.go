@@ -2,13 +2,26 @@ package routes
 
 import (
 	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(router *gin.RouterGroup, ctrl *controllers.AuthController) {
-	auth := router.Group("/auth")
+func AuthRoutes(router *gin.RouterGroup, ctrl *controllers.AuthController, twoFactorCtrl *controllers.TwoFactorController, sessionCtrl *controllers.SessionController, auth *middleware.Authenticator) {
+	authGroup := router.Group("/auth")
 	{
-		auth.POST("/register", ctrl.Register)
-		auth.POST("/login", ctrl.Login)
+		authGroup.POST("/register", ctrl.Register)
+		authGroup.POST("/login", ctrl.Login)
+		authGroup.POST("/login/2fa", ctrl.CompleteTwoFactorLogin)
+		authGroup.POST("/logout", auth.RequireAuthDuringTwoFactorSetup(), sessionCtrl.Logout)
 	}
-}
\ No newline at end of file
+
+	// Users whose role requires 2FA can only reach these routes until they set it up
+	twoFactor := authGroup.Group("/2fa")
+	twoFactor.Use(auth.RequireAuthDuringTwoFactorSetup())
+	{
+		twoFactor.POST("/enroll", twoFactorCtrl.Enroll)
+		twoFactor.POST("/verify", twoFactorCtrl.Verify)
+		twoFactor.POST("/disable", twoFactorCtrl.Disable)
+		twoFactor.POST("/recovery-codes", twoFactorCtrl.RegenerateRecoveryCodes)
+	}
+}
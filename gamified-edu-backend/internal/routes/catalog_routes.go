@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CatalogRoutes browse the published courses by category and tag.
+func CatalogRoutes(router *gin.RouterGroup, ctrl *controllers.CourseController, auth *middleware.Authenticator) {
+	catalog := router.Group("/catalog")
+	catalog.Use(auth.RequireScope(models.ScopeCoursesRead))
+	{
+		catalog.GET("/categories", ctrl.GetCategories)
+		catalog.GET("/categories/:category/courses", ctrl.GetCategoryCourses)
+		catalog.GET("/tags", ctrl.GetTags)
+		catalog.GET("/tags/:tag/courses", ctrl.GetTagCourses)
+	}
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func ClassroomRoutes(router *gin.RouterGroup, ctrl *controllers.ClassroomController, gradebookCtrl *controllers.GradebookController, auth *middleware.Authenticator) {
+	classrooms := router.Group("/classrooms")
+	classrooms.Use(auth.RequireAuth())
+	{
+		// Students and teachers
+		classrooms.GET("", ctrl.ListClassrooms)
+		classrooms.POST("/join", ctrl.JoinClassroom)
+		classrooms.GET("/:classroomId", ctrl.GetClassroom)
+		classrooms.GET("/:classroomId/assignments", ctrl.ListAssignments)
+		// Teachers remove students, students remove themselves to leave
+		classrooms.DELETE("/:classroomId/members/:userId", ctrl.RemoveMember)
+	}
+
+	teaching := classrooms.Group("")
+	teaching.Use(middleware.RequireRole(models.RoleInstructor, models.RoleAdmin))
+	{
+		teaching.POST("", ctrl.CreateClassroom)
+		teaching.DELETE("/:classroomId", ctrl.DeleteClassroom)
+		teaching.POST("/:classroomId/join-code", ctrl.RotateJoinCode)
+		teaching.POST("/:classroomId/assignments", ctrl.CreateAssignment)
+		teaching.DELETE("/:classroomId/assignments/:assignmentId", ctrl.DeleteAssignment)
+		teaching.GET("/:classroomId/roster", ctrl.GetRoster)
+		teaching.GET("/:classroomId/gradebook", gradebookCtrl.GetGradebook)
+		teaching.GET("/:classroomId/gradebook/export", gradebookCtrl.ExportGradebook)
+	}
+}
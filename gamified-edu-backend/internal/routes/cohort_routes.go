@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func CohortRoutes(router *gin.RouterGroup, ctrl *controllers.CohortController, auth *middleware.Authenticator) {
+	reading := router.Group("/courses/:courseId/cohorts")
+	reading.Use(auth.RequireScope(models.ScopeCoursesRead))
+	{
+		reading.GET("", ctrl.ListCohorts)
+	}
+
+	// Joining a cohort is a learner's write, like enrolling
+	joining := router.Group("/courses/:courseId/cohort")
+	joining.Use(auth.RequireScope(models.ScopeProgressWrite))
+	{
+		joining.PUT("", ctrl.JoinCohort)
+		joining.DELETE("", ctrl.LeaveCohort)
+	}
+
+	scheduling := router.Group("/courses/:courseId/cohorts")
+	scheduling.Use(auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin))
+	{
+		scheduling.POST("", ctrl.CreateCohort)
+		scheduling.PUT("/:cohortId", ctrl.UpdateCohort)
+		scheduling.DELETE("/:cohortId", ctrl.DeleteCohort)
+	}
+}
@@ -3,14 +3,46 @@ package routes
 import (
 	"gamified-edu-backend/internal/controllers"
 	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
-func CourseRoutes(router *gin.RouterGroup, ctrl *controllers.CourseController) {
+func CourseRoutes(router *gin.RouterGroup, ctrl *controllers.CourseController, authoringCtrl *controllers.CourseAuthoringController, auth *middleware.Authenticator) {
 	courses := router.Group("/courses")
-	courses.Use(middleware.AuthMiddleware())
+	courses.Use(auth.RequireScope(models.ScopeCoursesRead))
 	{
 		courses.GET("/", ctrl.GetAllCourses)
+		courses.GET("/enrolled", ctrl.GetEnrolledCourses)
 		courses.GET("/:courseId", ctrl.GetCourseDetails)
 	}
+
+	// Enrolling is a learner's write, like recording progress
+	enrollment := router.Group("/courses/:courseId/enrollment")
+	enrollment.Use(auth.RequireScope(models.ScopeProgressWrite))
+	{
+		enrollment.POST("", ctrl.Enroll)
+		enrollment.DELETE("", ctrl.Unenroll)
+	}
+
+	creation := router.Group("/courses")
+	creation.Use(auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin))
+	{
+		creation.POST("", authoringCtrl.CreateCourse)
+	}
+
+	authoring := router.Group("/courses/:courseId")
+	authoring.Use(auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin))
+	{
+		authoring.PUT("/settings", ctrl.UpdateCourseSettings)
+
+		// Edits go into a draft that learners see once it is published
+		authoring.GET("/draft", authoringCtrl.GetDraft)
+		authoring.PUT("/draft", authoringCtrl.SaveDraft)
+		authoring.DELETE("/draft", authoringCtrl.DiscardDraft)
+		authoring.POST("/publish", authoringCtrl.Publish)
+		authoring.GET("/versions", authoringCtrl.ListVersions)
+		authoring.GET("/versions/:version", authoringCtrl.GetVersion)
+		authoring.GET("/versions/:version/diff", authoringCtrl.DiffVersion)
+		authoring.POST("/versions/:version/rollback", authoringCtrl.Rollback)
+	}
 }
\ No newline at end of file
@@ -3,12 +3,13 @@ package routes
 import (
 	"gamified-edu-backend/internal/controllers"
 	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
-func DashboardRoutes(router *gin.RouterGroup, ctrl *controllers.DashboardController) {
+func DashboardRoutes(router *gin.RouterGroup, ctrl *controllers.DashboardController, auth *middleware.Authenticator) {
 	dashboard := router.Group("/dashboard")
-	dashboard.Use(middleware.AuthMiddleware())
+	dashboard.Use(auth.RequireScope(models.ScopeDashboardRead))
 	{
 		dashboard.GET("/", ctrl.GetDashboard)
 	}
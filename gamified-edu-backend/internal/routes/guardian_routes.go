@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func GuardianRoutes(router *gin.RouterGroup, ctrl *controllers.GuardianController, auth *middleware.Authenticator) {
+	// Students manage who can follow their progress
+	students := router.Group("/me/guardians")
+	students.Use(auth.RequireAuth())
+	{
+		students.GET("", ctrl.ListGuardians)
+		students.POST("", ctrl.InviteGuardian)
+		students.POST("/:linkId/approve", ctrl.ApproveLink)
+		students.DELETE("/:linkId", ctrl.RevokeLink)
+	}
+
+	guardian := router.Group("/guardian")
+	guardian.Use(auth.RequireAuth(), middleware.RequireRole(models.RoleGuardian))
+	{
+		guardian.GET("/students", ctrl.ListStudents)
+		guardian.GET("/students/:studentId/overview", ctrl.GetStudentOverview)
+		guardian.POST("/links", ctrl.RequestLink)
+		guardian.POST("/links/accept", ctrl.AcceptInvite)
+		guardian.DELETE("/links/:linkId", ctrl.RevokeLink)
+	}
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func LearningPathRoutes(router *gin.RouterGroup, ctrl *controllers.LearningPathController, auth *middleware.Authenticator) {
+	paths := router.Group("/paths")
+	paths.Use(auth.RequireScope(models.ScopeCoursesRead))
+	{
+		paths.GET("", ctrl.ListPaths)
+		paths.GET("/enrolled", ctrl.GetEnrolledPaths)
+		paths.GET("/:pathId", ctrl.GetPath)
+	}
+
+	// Enrolling is a learner's write, like enrolling in a course
+	enrollment := router.Group("/paths/:pathId/enrollment")
+	enrollment.Use(auth.RequireScope(models.ScopeProgressWrite))
+	{
+		enrollment.POST("", ctrl.Enroll)
+		enrollment.DELETE("", ctrl.Unenroll)
+	}
+
+	authoring := router.Group("/paths")
+	authoring.Use(auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin))
+	{
+		authoring.POST("", ctrl.CreatePath)
+		authoring.PUT("/:pathId", ctrl.UpdatePath)
+		authoring.DELETE("/:pathId", ctrl.DeletePath)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// LTIRoutes are called by LMS platforms and the browsers they launch.
+// Platforms are registered through the admin routes.
+func LTIRoutes(router *gin.RouterGroup, ctrl *controllers.LTIController, auth *middleware.Authenticator) {
+	lti := router.Group("/lti")
+	{
+		lti.GET("/login", ctrl.Login)
+		lti.POST("/login", ctrl.Login)
+		lti.POST("/launch", ctrl.Launch)
+		lti.GET("/jwks", ctrl.KeySet)
+
+		lti.POST("/deep-links/:deepLinkId", auth.RequireAuth(), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.CompleteDeepLink)
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// MediaRoutes manage uploaded media assets. Their content is served to
+// anyone holding a signed URL, which is how learners receive it.
+func MediaRoutes(router *gin.RouterGroup, ctrl *controllers.MediaAssetController, auth *middleware.Authenticator) {
+	media := router.Group("/media/assets")
+	{
+		media.POST("", auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.Upload)
+		media.GET("", auth.RequireScope(models.ScopeCoursesRead), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.ListAssets)
+		media.GET("/:assetId", auth.RequireScope(models.ScopeCoursesRead), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.GetAsset)
+		media.DELETE("/:assetId", auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.DeleteAsset)
+
+		media.GET("/:assetId/content", ctrl.Content)
+		media.HEAD("/:assetId/content", ctrl.Content)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// PlatformRoutes are the operators' routes, which reach across organizations
+func PlatformRoutes(router *gin.RouterGroup, organizationCtrl *controllers.OrganizationController, auth *middleware.Authenticator) {
+	platform := router.Group("/platform")
+	platform.Use(auth.RequireAuth(), middleware.RequireRole(models.RoleOperator))
+	{
+		platform.POST("/organizations", organizationCtrl.CreateOrganization)
+		platform.GET("/organizations", organizationCtrl.ListOrganizations)
+		platform.PUT("/users/:userId/organization", organizationCtrl.AssignUser)
+	}
+}
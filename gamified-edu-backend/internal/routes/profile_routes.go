@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ProfileRoutes(router *gin.RouterGroup, ctrl *controllers.ProfileController, exportCtrl *controllers.DataExportController, auth *middleware.Authenticator) {
+	me := router.Group("/me")
+	me.Use(auth.RequireAuth())
+	{
+		me.GET("", ctrl.GetProfile)
+		me.PATCH("", ctrl.UpdateProfile)
+		me.DELETE("", ctrl.DeleteAccount)
+		me.POST("/exports", exportCtrl.RequestExport)
+		me.GET("/exports", exportCtrl.ListExports)
+		me.GET("/exports/:exportId", exportCtrl.GetExport)
+	}
+
+	// Download links are signed and expire, so they don't need a token
+	router.GET("/exports/:exportId/download", exportCtrl.Download)
+}
@@ -3,16 +3,16 @@ package routes
 import (
     "gamified-edu-backend/internal/controllers"
     "gamified-edu-backend/internal/middleware"
+    "gamified-edu-backend/internal/models"
     "github.com/gin-gonic/gin"
 )
 
-func ProgressRoutes(router *gin.RouterGroup, ctrl *controllers.ProgressController) {
+func ProgressRoutes(router *gin.RouterGroup, ctrl *controllers.ProgressController, auth *middleware.Authenticator) {
     progress := router.Group("/progress")
-    progress.Use(middleware.AuthMiddleware())
     {
         // A single, powerful route to handle all component updates
-        progress.POST("/course/:courseId/chapter/:chapterId/:component", ctrl.MarkComponentComplete)
+        progress.POST("/course/:courseId/chapter/:chapterId/:component", auth.RequireScope(models.ScopeProgressWrite), ctrl.MarkComponentComplete)
         // Get user progress for a course
-        progress.GET("/course/:courseId", ctrl.GetCourseProgress)
+        progress.GET("/course/:courseId", auth.RequireScope(models.ScopeProgressRead), ctrl.GetCourseProgress)
     }
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// QuizRoutes serve the quizzes stored on the server. Quiz IDs of chapter
+// components are the quiz slugs; attempts are graded for a component, so the
+// score counts as the learner's progress on it.
+func QuizRoutes(router *gin.RouterGroup, ctrl *controllers.QuizController, auth *middleware.Authenticator) {
+	quizzes := router.Group("/quizzes")
+	{
+		quizzes.GET("/:quizId", auth.RequireScope(models.ScopeCoursesRead), ctrl.GetQuiz)
+		quizzes.POST("/course/:courseId/chapter/:chapterId/:component", auth.RequireScope(models.ScopeProgressWrite), ctrl.GradeQuiz)
+	}
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ReportRoutes expose data exports for admins and organization API keys.
+func ReportRoutes(router *gin.RouterGroup, progressCtrl *controllers.ProgressController, auth *middleware.Authenticator) {
+	reports := router.Group("/reports")
+	{
+		reports.GET("/progress", auth.RequireScope(models.ScopeProgressRead), middleware.RequireRole(models.RoleAdmin), progressCtrl.GetProgressReport)
+	}
+}
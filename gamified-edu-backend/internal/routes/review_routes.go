@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func ReviewRoutes(router *gin.RouterGroup, ctrl *controllers.ReviewController, auth *middleware.Authenticator) {
+	reading := router.Group("/courses/:courseId")
+	reading.Use(auth.RequireScope(models.ScopeCoursesRead))
+	{
+		reading.GET("/reviews", ctrl.GetReviews)
+		reading.GET("/review", ctrl.GetMyReview)
+	}
+
+	// Reviewing and reporting are a learner's writes, like enrolling
+	reviewing := router.Group("")
+	reviewing.Use(auth.RequireScope(models.ScopeProgressWrite))
+	{
+		reviewing.PUT("/courses/:courseId/review", ctrl.SubmitReview)
+		reviewing.DELETE("/courses/:courseId/review", ctrl.DeleteReview)
+		reviewing.POST("/reviews/:reviewId/report", ctrl.ReportReview)
+	}
+
+	// Course authors answer the reviews of their courses
+	replies := router.Group("/reviews/:reviewId/reply")
+	replies.Use(auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin))
+	{
+		replies.PUT("", ctrl.ReplyToReview)
+		replies.DELETE("", ctrl.DeleteReply)
+	}
+}
@@ -1,12 +1,21 @@
 package routes
 
 import (
+	"context"
 	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/lti"
+	"gamified-edu-backend/internal/mailer"
+	"gamified-edu-backend/internal/media"
+	"gamified-edu-backend/internal/middleware"
 	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/scorm"
 	"gamified-edu-backend/internal/services"
+	"gamified-edu-backend/internal/xapi"
+	"gamified-edu-backend/pkg"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
+	"log"
 	"time" // <-- IMPORT THE 'time' PACKAGE
 )
 
@@ -18,36 +27,198 @@ func RegisterRoutes(router *gin.Engine, db *mongo.Database) {
 	progressRepo := repositories.NewProgressRepository(db)
 	dashboardRepo := repositories.NewDashboardRepository(db)
 	activityRepo := repositories.NewActivityRepository(db)
+	securityPolicyRepo := repositories.NewSecurityPolicyRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	userDataRepo := repositories.NewUserDataRepository(db)
+	dataExportRepo := repositories.NewDataExportRepository(db)
+	organizationRepo := repositories.NewOrganizationRepository(db)
+	classroomRepo := repositories.NewClassroomRepository(db)
+	assignmentRepo := repositories.NewAssignmentRepository(db)
+	guardianRepo := repositories.NewGuardianRepository(db)
+	enrollmentRepo := repositories.NewEnrollmentRepository(db)
+	learningPathRepo := repositories.NewLearningPathRepository(db)
+	courseReviewRepo := repositories.NewCourseReviewRepository(db)
+	cohortRepo := repositories.NewCohortRepository(db)
+	courseVersionRepo := repositories.NewCourseVersionRepository(db)
+	quizRepo := repositories.NewQuizRepository(db)
+	scormPackageRepo := repositories.NewSCORMPackageRepository(db)
+	scormAttemptRepo := repositories.NewSCORMAttemptRepository(db)
+	xapiStatementRepo := repositories.NewXAPIStatementRepository(db)
+	ltiPlatformRepo := repositories.NewLTIPlatformRepository(db)
+	ltiLoginStateRepo := repositories.NewLTILoginStateRepository(db)
+	ltiIdentityRepo := repositories.NewLTIIdentityRepository(db)
+	ltiCourseLinkRepo := repositories.NewLTICourseLinkRepository(db)
+	ltiDeepLinkRepo := repositories.NewLTIDeepLinkRepository(db)
+	mediaAssetRepo := repositories.NewMediaAssetRepository(db)
+	searchIndex, err := repositories.SearchIndexFromEnv(db)
+	if err != nil {
+		log.Fatal("Could not set up the search index: ", err)
+	}
+
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatal("Could not set up the mailer: ", err)
+	}
+	assetStore, err := media.BlobStoreFromEnv()
+	if err != nil {
+		log.Fatal("Could not set up the media asset store: ", err)
+	}
+	scormStore, err := scorm.FromEnv()
+	if err != nil {
+		log.Fatal("Could not set up the SCORM package store: ", err)
+	}
+	ltiKey, err := lti.KeyFromEnv()
+	if err != nil {
+		log.Fatal("Could not load the LTI tool key: ", err)
+	}
 
 	// --- SERVICES ---
-	authService := services.NewAuthService(userRepo)
-	courseService := services.NewCourseService(courseRepo, progressRepo)
-	progressService := services.NewProgressService(progressRepo, userRepo, activityRepo)
+	sessionService := services.NewSessionService(sessionRepo)
+	authService := services.NewAuthService(userRepo, securityPolicyRepo, organizationRepo, sessionService)
+	organizationService := services.NewOrganizationService(organizationRepo, userRepo, sessionService)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, securityPolicyRepo)
+	dataExportService := services.NewDataExportService(dataExportRepo, userDataRepo)
+	profileService := services.NewProfileService(userRepo, userDataRepo, courseRepo, enrollmentRepo, courseReviewRepo, sessionService, dataExportService)
+	twoFactorService := services.NewTwoFactorService(userRepo, securityPolicyRepo)
+	lrsClient := xapi.ClientFromEnv()
+	xapiService := services.NewXAPIService(xapiStatementRepo, organizationRepo, userRepo, lrsClient)
+	ltiGradeService := services.NewLTIGradeService(ltiPlatformRepo, ltiIdentityRepo, ltiCourseLinkRepo, progressRepo, lti.NewGradeClient(ltiKey, nil))
+	learningPathService := services.NewLearningPathService(learningPathRepo, courseRepo, progressRepo, enrollmentRepo, userRepo, pkg.SystemClock)
+	reviewService := services.NewReviewService(courseReviewRepo, courseRepo, progressRepo, enrollmentRepo, userRepo, pkg.SystemClock)
+	learningRecorder := services.LearningRecorders(xapiService, ltiGradeService, learningPathService)
+	searchService := services.NewSearchService(searchIndex, courseRepo, enrollmentRepo, progressRepo, organizationRepo)
+	courseService := services.NewCourseService(courseRepo, progressRepo, enrollmentRepo, userRepo, learningRecorder, searchService, cohortRepo, pkg.SystemClock)
+	progressService := services.NewProgressService(progressRepo, userRepo, activityRepo, courseRepo, enrollmentRepo, learningRecorder, cohortRepo, pkg.SystemClock)
+	cohortService := services.NewCohortService(cohortRepo, courseRepo, enrollmentRepo, pkg.SystemClock)
+	courseAuthoringService := services.NewCourseAuthoringService(courseRepo, courseVersionRepo, progressService, searchService)
+	quizService := services.NewQuizService(quizRepo, courseRepo, progressService)
+	mediaAssetService := services.NewMediaAssetService(mediaAssetRepo, assetStore)
+	coursePackService := services.NewCoursePackService(courseRepo, courseVersionRepo, quizRepo, userRepo, courseAuthoringService, mediaAssetService)
+	scormService := services.NewSCORMService(scormPackageRepo, scormAttemptRepo, courseRepo, progressRepo, enrollmentRepo, cohortRepo, userRepo, courseAuthoringService, progressService, scormStore, pkg.SystemClock)
 	dashboardService := services.NewDashboardService(dashboardRepo, progressRepo, userRepo)
+	classroomService := services.NewClassroomService(classroomRepo, assignmentRepo, courseRepo, progressRepo, userRepo)
+	gradebookService := services.NewGradebookService(classroomRepo, assignmentRepo, courseRepo, progressRepo, userRepo)
+	ltiService := services.NewLTIService(ltiPlatformRepo, ltiLoginStateRepo, ltiIdentityRepo, ltiCourseLinkRepo, ltiDeepLinkRepo, userRepo, securityPolicyRepo, courseRepo, courseService, sessionService, ltiKey, lti.NewValidator(lti.NewKeySets(nil)), pkg.SystemClock)
+	guardianService := services.NewGuardianService(guardianRepo, userRepo, progressRepo, dashboardService, courseService, mail)
+
+	// Tenant-scoped repositories cannot see data without an organization
+	if err := organizationService.AdoptUnassignedData(); err != nil {
+		log.Fatal("Could not assign existing data to the default organization: ", err)
+	}
+	if err := courseRepo.WrapChaptersInModules(); err != nil {
+		log.Fatal("Could not move course chapters into modules: ", err)
+	}
+	// Chapters and progress from before components keep their video, quiz and slides
+	if err := courseRepo.MoveChapterActivitiesToComponents(); err != nil {
+		log.Fatal("Could not move chapter activities into components: ", err)
+	}
+	if err := progressRepo.MoveActivityFlagsToComponents(); err != nil {
+		log.Fatal("Could not move chapter progress into components: ", err)
+	}
+	// Learners who made progress before enrollments existed keep their courses
+	backfilled, err := enrollmentRepo.BackfillFromProgress(pkg.SystemClock.Now())
+	if err != nil {
+		log.Fatal("Could not enroll learners in the courses they already started: ", err)
+	}
+	// Courses sort by popularity on a count kept as learners enroll; enrollments backfilled above were not counted
+	if backfilled > 0 {
+		if err := courseRepo.RecountEnrollments(); err != nil {
+			log.Fatal("Could not count course enrollments: ", err)
+		}
+	}
+	if err := courseRepo.AssignSlugs(); err != nil {
+		log.Fatal("Could not give existing courses and chapters slugs: ", err)
+	}
+	// Courses from before versioning are published as version 1
+	if err := courseVersionRepo.SnapshotUnversionedCourses(); err != nil {
+		log.Fatal("Could not record the first version of existing courses: ", err)
+	}
+	// Media of imported course packs was served publicly from MEDIA_DIR; it becomes media assets of its organization
+	if err := coursePackService.MoveBundledMedia(media.LegacyStoreFromEnv()); err != nil {
+		log.Fatal("Could not move course pack media into the media asset store: ", err)
+	}
+	// Learning records go with their learner's account
+	if err := xapiService.AssignUsers(); err != nil {
+		log.Fatal("Could not tie learning records to their learners: ", err)
+	}
+	// The in-process index starts empty; a shared one catches up on courses published by tools like coursepack
+	if err := searchService.Rebuild(context.Background()); err != nil {
+		log.Fatal("Could not build the search index: ", err)
+	}
+	// Learning records are delivered to the district's LRS in the background
+	if lrsClient != nil {
+		go xapiService.RunForwarder(context.Background(), 30*time.Second)
+	}
 
 	// --- CONTROLLERS ---
 	authController := controllers.NewAuthController(authService)
+	twoFactorController := controllers.NewTwoFactorController(twoFactorService)
+	sessionController := controllers.NewSessionController(sessionService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	profileController := controllers.NewProfileController(profileService)
+	dataExportController := controllers.NewDataExportController(dataExportService)
+	organizationController := controllers.NewOrganizationController(organizationService)
+
+	// --- AUTH MIDDLEWARE ---
+	auth := middleware.NewAuthenticator(sessionService, apiKeyService)
 	courseController := controllers.NewCourseController(courseService)
+	courseAuthoringController := controllers.NewCourseAuthoringController(courseAuthoringService)
+	learningPathController := controllers.NewLearningPathController(learningPathService)
+	reviewController := controllers.NewReviewController(reviewService)
+	cohortController := controllers.NewCohortController(cohortService)
+	coursePackController := controllers.NewCoursePackController(coursePackService)
+	quizController := controllers.NewQuizController(quizService)
+	mediaAssetController := controllers.NewMediaAssetController(mediaAssetService)
+	searchController := controllers.NewSearchController(searchService)
+	scormController := controllers.NewSCORMController(scormService)
+	xapiController := controllers.NewXAPIController(xapiService)
+	ltiController := controllers.NewLTIController(ltiService)
 	progressController := controllers.NewProgressController(progressService)
 	dashboardController := controllers.NewDashboardController(dashboardService)
+	classroomController := controllers.NewClassroomController(classroomService)
+	gradebookController := controllers.NewGradebookController(gradebookService)
+	guardianController := controllers.NewGuardianController(guardianService)
 
 	// --- CORS MIDDLEWARE ---
 	// REPLACE THE PREVIOUS CONFIGURATION WITH THIS MORE EXPLICIT ONE
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // Allows all origins
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", xapi.VersionHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Range", "Accept-Ranges", xapi.VersionHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// SCORM packages are served from the API's origin, SCOs can only reach the player's runtime from there
+	router.GET("/scorm/*filepath", gin.WrapH(scormStore))
+	router.HEAD("/scorm/*filepath", gin.WrapH(scormStore))
+
 	// --- API V1 GROUP ---
 	apiV1 := router.Group("/api/v1")
 
 	// --- ROUTES REGISTRATION ---
-	AuthRoutes(apiV1, authController)
-	CourseRoutes(apiV1, courseController)
-	ProgressRoutes(apiV1, progressController)
-	DashboardRoutes(apiV1, dashboardController)
+	AuthRoutes(apiV1, authController, twoFactorController, sessionController, auth)
+	SessionRoutes(apiV1, sessionController, auth)
+	APIKeyRoutes(apiV1, apiKeyController, auth)
+	ProfileRoutes(apiV1, profileController, dataExportController, auth)
+	CourseRoutes(apiV1, courseController, courseAuthoringController, auth)
+	QuizRoutes(apiV1, quizController, auth)
+	MediaRoutes(apiV1, mediaAssetController, auth)
+	CatalogRoutes(apiV1, courseController, auth)
+	LearningPathRoutes(apiV1, learningPathController, auth)
+	ReviewRoutes(apiV1, reviewController, auth)
+	CohortRoutes(apiV1, cohortController, auth)
+	SearchRoutes(apiV1, searchController, auth)
+	SCORMRoutes(apiV1, scormController, auth)
+	XAPIRoutes(apiV1, xapiController, auth)
+	LTIRoutes(apiV1, ltiController, auth)
+	ProgressRoutes(apiV1, progressController, auth)
+	DashboardRoutes(apiV1, dashboardController, auth)
+	ClassroomRoutes(apiV1, classroomController, gradebookController, auth)
+	GuardianRoutes(apiV1, guardianController, auth)
+	ReportRoutes(apiV1, progressController, auth)
+	AdminRoutes(apiV1, twoFactorController, sessionController, apiKeyController, dataExportController, coursePackController, ltiController, reviewController, userRepo, auth)
+	PlatformRoutes(apiV1, organizationController, auth)
 }
\ No newline at end of file
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SCORMRoutes take SCORM package uploads and serve the runtime API the
+// learner's player relays SCO calls to.
+func SCORMRoutes(router *gin.RouterGroup, ctrl *controllers.SCORMController, auth *middleware.Authenticator) {
+	scorm := router.Group("/scorm")
+	{
+		scorm.POST("/packages", auth.RequireScope(models.ScopeCoursesWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.ImportPackage)
+
+		scorm.GET("/course/:courseId/chapter/:chapterId/:component", auth.RequireScope(models.ScopeProgressRead), ctrl.GetRuntime)
+		scorm.PUT("/course/:courseId/chapter/:chapterId/:component", auth.RequireScope(models.ScopeProgressWrite), ctrl.CommitRuntime)
+	}
+}
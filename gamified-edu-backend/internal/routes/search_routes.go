@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchRoutes search the published courses and chapters of the organization.
+func SearchRoutes(router *gin.RouterGroup, ctrl *controllers.SearchController, auth *middleware.Authenticator) {
+	router.GET("/search", auth.RequireScope(models.ScopeCoursesRead), ctrl.Search)
+}
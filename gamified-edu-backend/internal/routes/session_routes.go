@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func SessionRoutes(router *gin.RouterGroup, ctrl *controllers.SessionController, auth *middleware.Authenticator) {
+	sessions := router.Group("/sessions")
+	sessions.Use(auth.RequireAuth())
+	{
+		sessions.GET("/", ctrl.ListSessions)
+		sessions.DELETE("/", ctrl.RevokeOtherSessions)
+		sessions.DELETE("/:sessionId", ctrl.RevokeSession)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"gamified-edu-backend/internal/controllers"
+	"gamified-edu-backend/internal/middleware"
+	"gamified-edu-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// XAPIRoutes serve the built-in LRS, for testing and for districts without
+// an LRS of their own. Its base endpoint is /api/v1/xapi/.
+func XAPIRoutes(router *gin.RouterGroup, ctrl *controllers.XAPIController, auth *middleware.Authenticator) {
+	xapi := router.Group("/xapi")
+	{
+		xapi.GET("/about", ctrl.About)
+
+		xapi.GET("/statements", auth.RequireScope(models.ScopeStatementsRead), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.GetStatements)
+		xapi.POST("/statements", auth.RequireScope(models.ScopeStatementsWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.PostStatements)
+		xapi.PUT("/statements", auth.RequireScope(models.ScopeStatementsWrite), middleware.RequireRole(models.RoleInstructor, models.RoleAdmin), ctrl.PutStatement)
+	}
+}
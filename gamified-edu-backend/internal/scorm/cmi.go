@@ -0,0 +1,493 @@
+package scorm
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is the SCORM edition a package was written for. The runtime data
+// model differs between them, see the element tables below.
+type Version string
+
+const (
+	Version12   Version = "1.2"
+	Version2004 Version = "2004"
+)
+
+// maxIndex caps the index of collection elements such as cmi.interactions.n,
+// so a SCO cannot grow its stored data without bound
+const maxIndex = 250
+
+// Error is a runtime error for one element a SCO tried to set. Code is the
+// SCORM error code of the package's version, for the SCO's GetLastError.
+type Error struct {
+	Element string `json:"element"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (%d)", e.Element, e.Message, e.Code)
+}
+
+// Launch is what the runtime knows about a learner's attempt when a SCO starts.
+type Launch struct {
+	LearnerID   string
+	LearnerName string
+	// Entry is "ab-initio" for a first launch, "resume" after a suspended session and "" otherwise
+	Entry        string
+	TotalSeconds float64
+	LaunchData   string
+	MasteryScore int
+	// Values are the elements stored from earlier sessions
+	Values map[string]string
+}
+
+type access int
+
+const (
+	readOnly access = iota
+	writeOnly
+	readWrite
+)
+
+// rule is what a data model element accepts: up to maxLength characters,
+// one of vocabulary, a number within min and max, or a match of pattern.
+type rule struct {
+	access     access
+	maxLength  int
+	vocabulary []string
+	number     bool
+	integer    bool
+	min, max   float64
+	ranged     bool
+	pattern    *regexp.Regexp
+}
+
+var (
+	decimalPattern    = regexp.MustCompile(`^-?(\d+(\.\d*)?|\.\d+)$`)
+	identifierPattern = regexp.MustCompile(`^[^\s]{1,255}$`)
+	// CMITimespan and CMITime of SCORM 1.2
+	timespan12Pattern = regexp.MustCompile(`^(\d{2,4}):(\d{2}):(\d{2}(\.\d{1,2})?)$`)
+	time12Pattern     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d{1,2})?$`)
+	// timeinterval and time of SCORM 2004, ISO 8601 durations and timestamps
+	interval2004Pattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d{1,2})?)S)?)?$`)
+	time2004Pattern     = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2}(T\d{2}(:\d{2}(:\d{2}(\.\d{1,2})?)?)?)?)?)?(Z|[+-]\d{2}(:\d{2})?)?$`)
+	result12Pattern     = regexp.MustCompile(`^(correct|wrong|unanticipated|neutral|-?(\d+(\.\d*)?|\.\d+))$`)
+	result2004Pattern   = regexp.MustCompile(`^(correct|incorrect|unanticipated|neutral|-?(\d+(\.\d*)?|\.\d+))$`)
+)
+
+func text(access access, maxLength int) rule { return rule{access: access, maxLength: maxLength} }
+func words(access access, vocabulary ...string) rule {
+	return rule{access: access, vocabulary: vocabulary}
+}
+func decimal(access access) rule { return rule{access: access, number: true} }
+func decimalIn(access access, min, max float64) rule {
+	return rule{access: access, number: true, ranged: true, min: min, max: max}
+}
+func integerIn(access access, min, max float64) rule {
+	return rule{access: access, number: true, integer: true, ranged: true, min: min, max: max}
+}
+func matching(access access, pattern *regexp.Regexp) rule {
+	return rule{access: access, pattern: pattern}
+}
+
+// Elements of SCORM 1.2, with n standing for an index
+var elements12 = map[string]rule{
+	"cmi.core.student_id":                            text(readOnly, 255),
+	"cmi.core.student_name":                          text(readOnly, 255),
+	"cmi.core.lesson_location":                       text(readWrite, 255),
+	"cmi.core.credit":                                text(readOnly, 0),
+	"cmi.core.lesson_status":                         words(readWrite, "passed", "completed", "failed", "incomplete", "browsed"),
+	"cmi.core.entry":                                 text(readOnly, 0),
+	"cmi.core.score.raw":                             decimalIn(readWrite, 0, 100),
+	"cmi.core.score.min":                             decimalIn(readWrite, 0, 100),
+	"cmi.core.score.max":                             decimalIn(readWrite, 0, 100),
+	"cmi.core.total_time":                            text(readOnly, 0),
+	"cmi.core.lesson_mode":                           text(readOnly, 0),
+	"cmi.core.exit":                                  words(writeOnly, "time-out", "suspend", "logout", ""),
+	"cmi.core.session_time":                          matching(writeOnly, timespan12Pattern),
+	"cmi.suspend_data":                               text(readWrite, 4096),
+	"cmi.launch_data":                                text(readOnly, 0),
+	"cmi.comments":                                   text(readWrite, 4096),
+	"cmi.comments_from_lms":                          text(readOnly, 0),
+	"cmi.objectives.n.id":                            matching(readWrite, identifierPattern),
+	"cmi.objectives.n.score.raw":                     decimalIn(readWrite, 0, 100),
+	"cmi.objectives.n.score.min":                     decimalIn(readWrite, 0, 100),
+	"cmi.objectives.n.score.max":                     decimalIn(readWrite, 0, 100),
+	"cmi.objectives.n.status":                        words(readWrite, "passed", "completed", "failed", "incomplete", "browsed", "not attempted"),
+	"cmi.student_data.mastery_score":                 text(readOnly, 0),
+	"cmi.student_data.max_time_allowed":              text(readOnly, 0),
+	"cmi.student_data.time_limit_action":             text(readOnly, 0),
+	"cmi.student_preference.audio":                   integerIn(readWrite, -1, 100),
+	"cmi.student_preference.language":                text(readWrite, 255),
+	"cmi.student_preference.speed":                   integerIn(readWrite, -100, 100),
+	"cmi.student_preference.text":                    integerIn(readWrite, -1, 1),
+	"cmi.interactions.n.id":                          matching(writeOnly, identifierPattern),
+	"cmi.interactions.n.objectives.n.id":             matching(writeOnly, identifierPattern),
+	"cmi.interactions.n.time":                        matching(writeOnly, time12Pattern),
+	"cmi.interactions.n.type":                        words(writeOnly, "true-false", "choice", "fill-in", "matching", "performance", "sequencing", "likert", "numeric"),
+	"cmi.interactions.n.correct_responses.n.pattern": text(writeOnly, 255),
+	"cmi.interactions.n.weighting":                   decimal(writeOnly),
+	"cmi.interactions.n.student_response":            text(writeOnly, 255),
+	"cmi.interactions.n.result":                      matching(writeOnly, result12Pattern),
+	"cmi.interactions.n.latency":                     matching(writeOnly, timespan12Pattern),
+}
+
+// Elements of SCORM 2004, with n standing for an index
+var elements2004 = map[string]rule{
+	"cmi._version":                                   text(readOnly, 0),
+	"cmi.comments_from_learner.n.comment":            text(readWrite, 4000),
+	"cmi.comments_from_learner.n.location":           text(readWrite, 250),
+	"cmi.comments_from_learner.n.timestamp":          matching(readWrite, time2004Pattern),
+	"cmi.comments_from_lms.n.comment":                text(readOnly, 0),
+	"cmi.comments_from_lms.n.location":               text(readOnly, 0),
+	"cmi.comments_from_lms.n.timestamp":              text(readOnly, 0),
+	"cmi.completion_status":                          words(readWrite, "completed", "incomplete", "not attempted", "unknown"),
+	"cmi.completion_threshold":                       text(readOnly, 0),
+	"cmi.credit":                                     text(readOnly, 0),
+	"cmi.entry":                                      text(readOnly, 0),
+	"cmi.exit":                                       words(writeOnly, "time-out", "suspend", "logout", "normal", ""),
+	"cmi.interactions.n.id":                          text(readWrite, 4000),
+	"cmi.interactions.n.type":                        words(readWrite, "true-false", "choice", "fill-in", "long-fill-in", "matching", "performance", "sequencing", "likert", "numeric", "other"),
+	"cmi.interactions.n.objectives.n.id":             text(readWrite, 4000),
+	"cmi.interactions.n.timestamp":                   matching(readWrite, time2004Pattern),
+	"cmi.interactions.n.correct_responses.n.pattern": text(readWrite, 8000),
+	"cmi.interactions.n.weighting":                   decimal(readWrite),
+	"cmi.interactions.n.learner_response":            text(readWrite, 8000),
+	"cmi.interactions.n.result":                      matching(readWrite, result2004Pattern),
+	"cmi.interactions.n.latency":                     matching(readWrite, interval2004Pattern),
+	"cmi.interactions.n.description":                 text(readWrite, 250),
+	"cmi.launch_data":                                text(readOnly, 0),
+	"cmi.learner_id":                                 text(readOnly, 0),
+	"cmi.learner_name":                               text(readOnly, 0),
+	"cmi.learner_preference.audio_level":             decimalIn(readWrite, 0, math.MaxFloat64),
+	"cmi.learner_preference.language":                text(readWrite, 250),
+	"cmi.learner_preference.delivery_speed":          decimalIn(readWrite, 0, math.MaxFloat64),
+	"cmi.learner_preference.audio_captioning":        words(readWrite, "-1", "0", "1"),
+	"cmi.location":                                   text(readWrite, 1000),
+	"cmi.max_time_allowed":                           text(readOnly, 0),
+	"cmi.mode":                                       text(readOnly, 0),
+	"cmi.objectives.n.id":                            text(readWrite, 4000),
+	"cmi.objectives.n.score.scaled":                  decimalIn(readWrite, -1, 1),
+	"cmi.objectives.n.score.raw":                     decimal(readWrite),
+	"cmi.objectives.n.score.min":                     decimal(readWrite),
+	"cmi.objectives.n.score.max":                     decimal(readWrite),
+	"cmi.objectives.n.success_status":                words(readWrite, "passed", "failed", "unknown"),
+	"cmi.objectives.n.completion_status":             words(readWrite, "completed", "incomplete", "not attempted", "unknown"),
+	"cmi.objectives.n.progress_measure":              decimalIn(readWrite, 0, 1),
+	"cmi.objectives.n.description":                   text(readWrite, 250),
+	"cmi.progress_measure":                           decimalIn(readWrite, 0, 1),
+	"cmi.scaled_passing_score":                       text(readOnly, 0),
+	"cmi.score.scaled":                               decimalIn(readWrite, -1, 1),
+	"cmi.score.raw":                                  decimal(readWrite),
+	"cmi.score.min":                                  decimal(readWrite),
+	"cmi.score.max":                                  decimal(readWrite),
+	"cmi.session_time":                               matching(writeOnly, interval2004Pattern),
+	"cmi.success_status":                             words(readWrite, "passed", "failed", "unknown"),
+	"cmi.suspend_data":                               text(readWrite, 64000),
+	"cmi.time_limit_action":                          text(readOnly, 0),
+	"cmi.total_time":                                 text(readOnly, 0),
+	// Navigation requests are stored but not acted on, every SCO is its own chapter
+	"adl.nav.request": text(readWrite, 4000),
+}
+
+// errorCodes are the SCORM error codes for the ways a SetValue can fail
+type errorCodes struct {
+	undefined, keyword, readOnly, typeMismatch, outOfRange, badIndex int
+}
+
+// versionSpec is everything that differs between the SCORM editions
+type versionSpec struct {
+	elements    map[string]rule
+	codes       errorCodes
+	sessionTime string
+	exit        string
+	// collections get a _count element at launch, even when empty
+	collections []string
+}
+
+var versions = map[Version]versionSpec{
+	Version12: {
+		elements:    elements12,
+		codes:       errorCodes{undefined: 401, keyword: 402, readOnly: 403, typeMismatch: 405, outOfRange: 405, badIndex: 201},
+		sessionTime: "cmi.core.session_time",
+		exit:        "cmi.core.exit",
+		collections: []string{"cmi.objectives", "cmi.interactions"},
+	},
+	Version2004: {
+		elements:    elements2004,
+		codes:       errorCodes{undefined: 401, keyword: 404, readOnly: 404, typeMismatch: 406, outOfRange: 407, badIndex: 351},
+		sessionTime: "cmi.session_time",
+		exit:        "cmi.exit",
+		collections: []string{"cmi.comments_from_learner", "cmi.comments_from_lms", "cmi.objectives", "cmi.interactions"},
+	},
+}
+
+// Valid reports whether v is a SCORM version the runtime supports.
+func (v Version) Valid() bool {
+	_, ok := versions[v]
+	return ok
+}
+
+// Check validates that a SCO may set element to value.
+func (v Version) Check(element, value string) *Error {
+	spec := versions[v]
+	fail := func(code int, format string, args ...interface{}) *Error {
+		return &Error{Element: element, Code: code, Message: fmt.Sprintf(format, args...)}
+	}
+
+	generic, indexOK := genericElement(element)
+	last := element[strings.LastIndex(element, ".")+1:]
+	if strings.HasPrefix(last, "_") {
+		return fail(spec.codes.keyword, "%s is a keyword and cannot be set", last)
+	}
+	rule, ok := spec.elements[generic]
+	switch {
+	case !ok:
+		return fail(spec.codes.undefined, "not an element of the SCORM %s data model", v)
+	case !indexOK:
+		return fail(spec.codes.badIndex, "indexes go from 0 to %d", maxIndex-1)
+	case rule.access == readOnly:
+		return fail(spec.codes.readOnly, "the element is read only")
+	}
+
+	switch {
+	case rule.vocabulary != nil:
+		for _, word := range rule.vocabulary {
+			if value == word {
+				return nil
+			}
+		}
+		return fail(spec.codes.typeMismatch, "must be one of %q", rule.vocabulary)
+	case rule.number:
+		// SCORM 1.2 scores may be left blank
+		if value == "" && v == Version12 {
+			return nil
+		}
+		if !decimalPattern.MatchString(value) {
+			return fail(spec.codes.typeMismatch, "must be a number")
+		}
+		number, _ := strconv.ParseFloat(value, 64)
+		if rule.integer && number != math.Trunc(number) {
+			return fail(spec.codes.typeMismatch, "must be a whole number")
+		}
+		if rule.ranged && (number < rule.min || number > rule.max) {
+			return fail(spec.codes.outOfRange, "must be between %g and %g", rule.min, rule.max)
+		}
+	case rule.pattern != nil:
+		// An ISO 8601 duration needs at least one amount after its P and T
+		if !rule.pattern.MatchString(value) || (rule.pattern == interval2004Pattern && (value == "P" || strings.HasSuffix(value, "T"))) {
+			return fail(spec.codes.typeMismatch, "is not in the expected format")
+		}
+	}
+	if rule.maxLength > 0 && len([]rune(value)) > rule.maxLength {
+		return fail(spec.codes.typeMismatch, "must be at most %d characters", rule.maxLength)
+	}
+	return nil
+}
+
+// Readable reports whether a SCO may read back an element it set. Write-only
+// elements are never handed out again.
+func (v Version) Readable(element string) bool {
+	generic, _ := genericElement(element)
+	rule, ok := versions[v].elements[generic]
+	return ok && rule.access != writeOnly
+}
+
+// LaunchValues are the elements a SCO starts with: the ones it stored in
+// earlier sessions, the counts of its collections and what the runtime
+// supplies about the learner and the attempt.
+func (v Version) LaunchValues(launch Launch) map[string]string {
+	spec := versions[v]
+	values := map[string]string{}
+	counts := map[string]int{}
+	for _, collection := range spec.collections {
+		counts[collection] = 0
+	}
+	for element, value := range launch.Values {
+		if v.Readable(element) {
+			values[element] = value
+		}
+		// Every indexed prefix counts, e.g. cmi.interactions.0.objectives.1.id
+		// counts towards cmi.interactions and cmi.interactions.0.objectives,
+		// even when the elements themselves are write-only
+		segments := strings.Split(element, ".")
+		for i, segment := range segments {
+			if index, err := strconv.Atoi(segment); err == nil && i > 0 {
+				prefix := strings.Join(segments[:i], ".")
+				counts[prefix] = max(counts[prefix], index+1)
+			}
+		}
+	}
+	for collection, count := range counts {
+		values[collection+"._count"] = strconv.Itoa(count)
+	}
+
+	switch v {
+	case Version12:
+		if values["cmi.core.lesson_status"] == "" {
+			values["cmi.core.lesson_status"] = "not attempted"
+		}
+		values["cmi.core.student_id"] = launch.LearnerID
+		values["cmi.core.student_name"] = launch.LearnerName
+		values["cmi.core.credit"] = "credit"
+		values["cmi.core.entry"] = launch.Entry
+		values["cmi.core.lesson_mode"] = "normal"
+		values["cmi.core.total_time"] = formatTimespan12(launch.TotalSeconds)
+		values["cmi.launch_data"] = launch.LaunchData
+		if launch.MasteryScore > 0 {
+			values["cmi.student_data.mastery_score"] = strconv.Itoa(launch.MasteryScore)
+		}
+	case Version2004:
+		if values["cmi.completion_status"] == "" {
+			values["cmi.completion_status"] = "unknown"
+		}
+		if values["cmi.success_status"] == "" {
+			values["cmi.success_status"] = "unknown"
+		}
+		values["cmi._version"] = "1.0"
+		values["cmi.learner_id"] = launch.LearnerID
+		values["cmi.learner_name"] = launch.LearnerName
+		values["cmi.credit"] = "credit"
+		values["cmi.entry"] = launch.Entry
+		values["cmi.mode"] = "normal"
+		values["cmi.total_time"] = formatInterval2004(launch.TotalSeconds)
+		values["cmi.launch_data"] = launch.LaunchData
+		if launch.MasteryScore > 0 {
+			values["cmi.scaled_passing_score"] = strconv.FormatFloat(float64(launch.MasteryScore)/100, 'f', -1, 64)
+		}
+	}
+	return values
+}
+
+// SessionSeconds is the session time the SCO reported, 0 if it reported none.
+func (v Version) SessionSeconds(values map[string]string) float64 {
+	value := values[versions[v].sessionTime]
+	if v == Version12 {
+		parts := timespan12Pattern.FindStringSubmatch(value)
+		if parts == nil {
+			return 0
+		}
+		hours, _ := strconv.ParseFloat(parts[1], 64)
+		minutes, _ := strconv.ParseFloat(parts[2], 64)
+		seconds, _ := strconv.ParseFloat(parts[3], 64)
+		return hours*3600 + minutes*60 + seconds
+	}
+	parts := interval2004Pattern.FindStringSubmatch(value)
+	if parts == nil {
+		return 0
+	}
+	total := 0.0
+	// Years and months have no fixed length, the usual 365 and 30 days will do
+	for i, unit := range []float64{365 * 86400, 30 * 86400, 86400, 3600, 60, 1} {
+		amount, _ := strconv.ParseFloat(parts[i+1], 64)
+		total += amount * unit
+	}
+	return total
+}
+
+// EndSession removes the elements that only last for a session, the session
+// time and the exit, and returns the session's time and whether the SCO
+// suspended the attempt to resume it later.
+func (v Version) EndSession(values map[string]string) (float64, bool) {
+	spec := versions[v]
+	seconds := v.SessionSeconds(values)
+	suspended := values[spec.exit] == "suspend"
+	delete(values, spec.sessionTime)
+	delete(values, spec.exit)
+	return seconds, suspended
+}
+
+// Summarize reads whether the SCO is complete and its score in percent from
+// its values. masteryScore is the score in percent it has to reach, if any.
+//
+// SCORM 1.2 SCOs are complete once passed, or completed with the mastery
+// score reached. SCORM 2004 SCOs are complete once completed or passed,
+// unless they failed; with a mastery score, the scaled score decides success.
+func (v Version) Summarize(values map[string]string, masteryScore int) (bool, *int) {
+	switch v {
+	case Version12:
+		score := percent(values["cmi.core.score.raw"], values["cmi.core.score.min"], values["cmi.core.score.max"])
+		status := values["cmi.core.lesson_status"]
+		switch {
+		case status == "passed":
+			return true, score
+		case status != "completed" && status != "failed":
+			return false, score
+		case masteryScore == 0 || score == nil:
+			return status == "completed", score
+		}
+		// With a mastery score the score decides, whatever the SCO concluded
+		return *score >= masteryScore, score
+
+	case Version2004:
+		score := percent(values["cmi.score.raw"], values["cmi.score.min"], values["cmi.score.max"])
+		success := values["cmi.success_status"]
+		if scaled, err := strconv.ParseFloat(values["cmi.score.scaled"], 64); err == nil {
+			rounded := int(math.Round(math.Max(scaled, 0) * 100))
+			score = &rounded
+			if masteryScore > 0 {
+				success = "failed"
+				if scaled*100 >= float64(masteryScore) {
+					success = "passed"
+				}
+			}
+		}
+		completed := values["cmi.completion_status"] == "completed" || success == "passed"
+		return completed && success != "failed", score
+	}
+	return false, nil
+}
+
+// percent turns a raw score into a percentage of min to max. Without a
+// range, raw scores are taken as percentages already.
+func percent(raw, min, max string) *int {
+	score, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil
+	}
+	low, lowErr := strconv.ParseFloat(min, 64)
+	high, highErr := strconv.ParseFloat(max, 64)
+	if lowErr == nil && highErr == nil && high > low {
+		score = (score - low) / (high - low) * 100
+	} else if highErr == nil && high > 0 {
+		score = score / high * 100
+	}
+	rounded := int(math.Round(math.Min(math.Max(score, 0), 100)))
+	return &rounded
+}
+
+// genericElement replaces the indexes of an element with n, e.g.
+// cmi.objectives.2.id becomes cmi.objectives.n.id. It reports false for
+// indexes beyond maxIndex.
+func genericElement(element string) (string, bool) {
+	segments := strings.Split(element, ".")
+	ok := true
+	for i, segment := range segments {
+		if segment == "" || strings.Trim(segment, "0123456789") != "" {
+			continue
+		}
+		index, err := strconv.Atoi(segment)
+		if err != nil || index >= maxIndex || (len(segment) > 1 && segment[0] == '0') {
+			ok = false
+		}
+		segments[i] = "n"
+	}
+	return strings.Join(segments, "."), ok
+}
+
+func formatTimespan12(seconds float64) string {
+	hours := int(seconds / 3600)
+	minutes := int(math.Mod(seconds, 3600) / 60)
+	return fmt.Sprintf("%04d:%02d:%05.2f", min(hours, 9999), minutes, math.Mod(seconds, 60))
+}
+
+func formatInterval2004(seconds float64) string {
+	hours := int(seconds / 3600)
+	minutes := int(math.Mod(seconds, 3600) / 60)
+	return fmt.Sprintf("PT%dH%dM%sS", hours, minutes, strconv.FormatFloat(math.Round(math.Mod(seconds, 60)*100)/100, 'f', -1, 64))
+}
@@ -0,0 +1,369 @@
+// Package scorm reads SCORM 1.2 and SCORM 2004 content packages and
+// implements the rules of the SCORM runtime data model (CMI), so SCOs can be
+// served locally and their reports checked before they count as progress.
+//
+// A package is a zip with an imsmanifest.xml at its root. Its default
+// organization is a tree of items, each launching a resource. Only items
+// launching SCOs are kept: assets cannot report back to the runtime.
+package scorm
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ManifestName is where a package keeps its manifest.
+const ManifestName = "imsmanifest.xml"
+
+// maxManifestSize caps the manifest, which is read into memory
+const maxManifestSize = 8 << 20
+
+// ErrInvalidPackage is returned for zips that are not usable SCORM packages.
+var ErrInvalidPackage = errors.New("invalid SCORM package")
+
+// Package is what a manifest describes, organized the way courses are.
+type Package struct {
+	Version Version
+	Title   string
+	Modules []Module
+	// root is the directory of the zip the package is in, see packageRoot
+	root string
+}
+
+// Module is a top-level item of the organization with the SCOs below it.
+// Consecutive top-level SCOs are grouped into a module named after the organization.
+type Module struct {
+	Title string
+	SCOs  []SCO
+}
+
+// SCO is a launchable item of the package.
+type SCO struct {
+	Identifier string
+	Title      string
+	// Launch is the path of the start page within the package, with its parameters
+	Launch string
+	// LaunchData is handed to the SCO as cmi.launch_data
+	LaunchData string
+	// MasteryScore is the score in percent the SCO has to reach, 0 if it has none
+	MasteryScore int
+}
+
+// SCOs lists the SCOs of every module in order.
+func (p *Package) SCOs() []SCO {
+	var scos []SCO
+	for _, module := range p.Modules {
+		scos = append(scos, module.SCOs...)
+	}
+	return scos
+}
+
+// The parts of imsmanifest.xml the package needs. Elements are matched by
+// local name, so the namespaces of both SCORM versions are accepted.
+type xmlManifest struct {
+	SchemaVersion string `xml:"metadata>schemaversion"`
+	Organizations struct {
+		Default       string            `xml:"default,attr"`
+		Organizations []xmlOrganization `xml:"organization"`
+	} `xml:"organizations"`
+	Resources struct {
+		Base      string        `xml:"base,attr"`
+		Resources []xmlResource `xml:"resource"`
+	} `xml:"resources"`
+}
+
+type xmlOrganization struct {
+	Identifier string    `xml:"identifier,attr"`
+	Title      string    `xml:"title"`
+	Items      []xmlItem `xml:"item"`
+}
+
+type xmlItem struct {
+	Identifier    string    `xml:"identifier,attr"`
+	IdentifierRef string    `xml:"identifierref,attr"`
+	IsVisible     string    `xml:"isvisible,attr"`
+	Parameters    string    `xml:"parameters,attr"`
+	Title         string    `xml:"title"`
+	Items         []xmlItem `xml:"item"`
+	// SCORM 1.2
+	MasteryScore string `xml:"masteryscore"`
+	DataFromLMS  string `xml:"datafromlms"`
+	// SCORM 2004
+	DataFromLMS2004      string `xml:"dataFromLMS"`
+	MinNormalizedMeasure string `xml:"sequencing>objectives>primaryObjective>minNormalizedMeasure"`
+}
+
+type xmlResource struct {
+	Identifier string `xml:"identifier,attr"`
+	Href       string `xml:"href,attr"`
+	Base       string `xml:"base,attr"`
+	// SCORM 1.2 spells the attribute scormtype, SCORM 2004 scormType
+	SCORMType     string `xml:"scormtype,attr"`
+	SCORMType2004 string `xml:"scormType,attr"`
+}
+
+// ReadPackage reads the manifest of the package in archive and checks that
+// every SCO's start page is in the archive.
+func ReadPackage(archive *zip.Reader) (*Package, error) {
+	root, err := packageRoot(archive)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[zipName(file)] = file
+	}
+	manifest, err := files[path.Join(root, ManifestName)].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer manifest.Close()
+	pkg, err := ParseManifest(io.LimitReader(manifest, maxManifestSize))
+	if err != nil {
+		return nil, err
+	}
+	pkg.root = root
+
+	for _, sco := range pkg.SCOs() {
+		page, _, _ := strings.Cut(sco.Launch, "?")
+		page, _, _ = strings.Cut(page, "#")
+		if files[path.Join(root, page)] == nil {
+			return nil, fmt.Errorf("%w: SCO %q starts at %s, which is not in the package", ErrInvalidPackage, sco.Identifier, page)
+		}
+	}
+	return pkg, nil
+}
+
+// ParseManifest reads an imsmanifest.xml.
+func ParseManifest(r io.Reader) (*Package, error) {
+	var manifest xmlManifest
+	if err := xml.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPackage, ManifestName, err)
+	}
+
+	resources := make(map[string]xmlResource, len(manifest.Resources.Resources))
+	for _, resource := range manifest.Resources.Resources {
+		resources[resource.Identifier] = resource
+	}
+	version, err := detectVersion(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Organizations.Organizations) == 0 {
+		return nil, fmt.Errorf("%w: the manifest has no organization", ErrInvalidPackage)
+	}
+	organization := manifest.Organizations.Organizations[0]
+	for _, candidate := range manifest.Organizations.Organizations {
+		if candidate.Identifier == manifest.Organizations.Default {
+			organization = candidate
+		}
+	}
+
+	pkg := &Package{Version: version, Title: strings.TrimSpace(organization.Title)}
+	var loose *Module
+	for _, item := range organization.Items {
+		if !visible(item) {
+			continue
+		}
+		if len(item.Items) == 0 {
+			sco, ok, err := itemSCO(item, manifest.Resources.Base, resources)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if loose == nil {
+				pkg.Modules = append(pkg.Modules, Module{Title: pkg.Title})
+				loose = &pkg.Modules[len(pkg.Modules)-1]
+			}
+			loose.SCOs = append(loose.SCOs, sco)
+			continue
+		}
+		module := Module{Title: strings.TrimSpace(item.Title)}
+		if err := collectSCOs(item.Items, manifest.Resources.Base, resources, &module.SCOs); err != nil {
+			return nil, err
+		}
+		if len(module.SCOs) > 0 {
+			pkg.Modules = append(pkg.Modules, module)
+			loose = nil
+		}
+	}
+	if len(pkg.SCOs()) == 0 {
+		return nil, fmt.Errorf("%w: the default organization launches no SCOs", ErrInvalidPackage)
+	}
+	return pkg, nil
+}
+
+// detectVersion reads the schema version, falling back to how the resources
+// spell scormtype for manifests without metadata
+func detectVersion(manifest xmlManifest) (Version, error) {
+	schemaVersion := strings.TrimSpace(manifest.SchemaVersion)
+	switch {
+	case schemaVersion == "1.2":
+		return Version12, nil
+	case strings.Contains(schemaVersion, "2004"), schemaVersion == "CAM 1.3":
+		return Version2004, nil
+	case schemaVersion != "":
+		return "", fmt.Errorf("%w: schemaversion %q is not supported", ErrInvalidPackage, schemaVersion)
+	}
+	for _, resource := range manifest.Resources.Resources {
+		switch {
+		case resource.SCORMType2004 != "":
+			return Version2004, nil
+		case resource.SCORMType != "":
+			return Version12, nil
+		}
+	}
+	return "", fmt.Errorf("%w: the manifest does not say which SCORM version it uses", ErrInvalidPackage)
+}
+
+func collectSCOs(items []xmlItem, base string, resources map[string]xmlResource, scos *[]SCO) error {
+	for _, item := range items {
+		if !visible(item) {
+			continue
+		}
+		if len(item.Items) > 0 {
+			if err := collectSCOs(item.Items, base, resources, scos); err != nil {
+				return err
+			}
+			continue
+		}
+		sco, ok, err := itemSCO(item, base, resources)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*scos = append(*scos, sco)
+		}
+	}
+	return nil
+}
+
+func visible(item xmlItem) bool {
+	return strings.TrimSpace(item.IsVisible) != "false"
+}
+
+// itemSCO returns the SCO a leaf item launches, if it launches one
+func itemSCO(item xmlItem, base string, resources map[string]xmlResource) (SCO, bool, error) {
+	if item.IdentifierRef == "" {
+		return SCO{}, false, nil
+	}
+	resource, ok := resources[item.IdentifierRef]
+	if !ok {
+		return SCO{}, false, fmt.Errorf("%w: item %q refers to unknown resource %q", ErrInvalidPackage, item.Identifier, item.IdentifierRef)
+	}
+	scormType := resource.SCORMType
+	if resource.SCORMType2004 != "" {
+		scormType = resource.SCORMType2004
+	}
+	if !strings.EqualFold(scormType, "sco") {
+		return SCO{}, false, nil
+	}
+	if item.Identifier == "" {
+		return SCO{}, false, fmt.Errorf("%w: an item launching %q has no identifier", ErrInvalidPackage, resource.Identifier)
+	}
+
+	launch, err := launchPath(base, resource, item.Parameters)
+	if err != nil {
+		return SCO{}, false, fmt.Errorf("%w: item %q: %v", ErrInvalidPackage, item.Identifier, err)
+	}
+	sco := SCO{
+		Identifier: item.Identifier,
+		Title:      strings.TrimSpace(item.Title),
+		Launch:     launch,
+		LaunchData: strings.TrimSpace(item.DataFromLMS),
+	}
+	if sco.Title == "" {
+		sco.Title = item.Identifier
+	}
+	if sco.LaunchData == "" {
+		sco.LaunchData = strings.TrimSpace(item.DataFromLMS2004)
+	}
+
+	switch {
+	case strings.TrimSpace(item.MasteryScore) != "":
+		score, err := strconv.ParseFloat(strings.TrimSpace(item.MasteryScore), 64)
+		if err != nil || score < 0 || score > 100 {
+			return SCO{}, false, fmt.Errorf("%w: item %q: masteryscore must be between 0 and 100", ErrInvalidPackage, item.Identifier)
+		}
+		sco.MasteryScore = int(math.Ceil(score))
+	case strings.TrimSpace(item.MinNormalizedMeasure) != "":
+		measure, err := strconv.ParseFloat(strings.TrimSpace(item.MinNormalizedMeasure), 64)
+		if err != nil || measure < -1 || measure > 1 {
+			return SCO{}, false, fmt.Errorf("%w: item %q: minNormalizedMeasure must be between -1 and 1", ErrInvalidPackage, item.Identifier)
+		}
+		sco.MasteryScore = int(math.Ceil(math.Max(measure, 0) * 100))
+	}
+	return sco, true, nil
+}
+
+// launchPath joins the xml:base of the resources and the resource with its
+// href and appends the item's parameters. SCOs have to be inside the package.
+func launchPath(base string, resource xmlResource, parameters string) (string, error) {
+	href := strings.TrimSpace(resource.Href)
+	if href == "" {
+		return "", fmt.Errorf("resource %q has no href", resource.Identifier)
+	}
+	page, query, hasQuery := strings.Cut(href, "?")
+	full := strings.ReplaceAll(path.Join(strings.TrimSpace(base), strings.TrimSpace(resource.Base), page), `\`, "/")
+	if strings.Contains(full, "://") || strings.HasPrefix(full, "/") || full == ".." || strings.HasPrefix(full, "../") {
+		return "", fmt.Errorf("resource %q launches %s, which is not inside the package", resource.Identifier, href)
+	}
+	if hasQuery {
+		full += "?" + query
+	}
+
+	parameters = strings.TrimSpace(parameters)
+	switch {
+	case parameters == "":
+	case strings.HasPrefix(parameters, "#"):
+		full += parameters
+	default:
+		parameters = strings.TrimPrefix(parameters, "?")
+		if strings.Contains(full, "?") {
+			full += "&" + parameters
+		} else {
+			full += "?" + parameters
+		}
+	}
+	return full, nil
+}
+
+// packageRoot finds the manifest at the root of the zip, or in the only
+// directory at the root, as zip tools tend to add one
+func packageRoot(archive *zip.Reader) (string, error) {
+	tops := map[string]bool{}
+	for _, file := range archive.File {
+		name := zipName(file)
+		if name == ManifestName {
+			return ".", nil
+		}
+		top, _, _ := strings.Cut(name, "/")
+		tops[top] = true
+	}
+	if len(tops) == 1 {
+		for top := range tops {
+			for _, file := range archive.File {
+				if zipName(file) == top+"/"+ManifestName {
+					return top, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: there is no %s at the root of the zip", ErrInvalidPackage, ManifestName)
+}
+
+// zipName is the name of a file in the zip with forward slashes, as some
+// Windows tools write backslashes
+func zipName(file *zip.File) string {
+	return strings.ReplaceAll(file.Name, `\`, "/")
+}
@@ -0,0 +1,213 @@
+package scorm
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/pkg"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxPackageSize caps the unpacked files of a package together, so a small
+// zip cannot expand into more than the server can hold.
+const MaxPackageSize = 1 << 30
+
+// PackageStore keeps the files of packages and serves them over HTTP, so SCOs
+// run on the same origin as the runtime API they report to. Files are only
+// served through signed URLs, which learners get when they start a session.
+type PackageStore interface {
+	// Save unpacks the package in archive under id
+	Save(id string, archive *zip.Reader) error
+	// Remove deletes the files of the package saved under id
+	Remove(id string) error
+	// LaunchURL identifies the SCO launching at launch, see SCO.Launch. It is
+	// not served as it is; SignURL makes the URL it is served at.
+	LaunchURL(id, launch string) string
+	// SignURL turns a LaunchURL into a URL the SCO is served at until
+	// expiresAt, along with the files of its package it links to
+	SignURL(launchURL string, expiresAt time.Time) string
+	// ServeHTTP serves the package files below the store's base URL
+	http.Handler
+}
+
+// DirStore keeps every package in a directory named after its id. Files are
+// served at <baseURL>/<id>/<token>/<file>, the token signing <baseURL>/<id>.
+type DirStore struct {
+	dir     string
+	baseURL string
+	files   http.FileSystem
+}
+
+// NewDirStore keeps packages in dir and serves them at baseURL, which is a
+// path such as /scorm.
+func NewDirStore(dir, baseURL string) *DirStore {
+	baseURL = "/" + strings.Trim(baseURL, "/")
+	return &DirStore{dir: dir, baseURL: baseURL, files: http.Dir(dir)}
+}
+
+func (s *DirStore) Save(id string, archive *zip.Reader) error {
+	if id == "" || id != path.Base(id) || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("invalid package id %q", id)
+	}
+	root, err := packageRoot(archive)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	// Unpack next to the target first so a half-unpacked package is never served
+	tmp, err := os.MkdirTemp(s.dir, "."+id+"-")
+	if err != nil {
+		return err
+	}
+	if err := unpack(archive, root, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, id)); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *DirStore) Remove(id string) error {
+	if id == "" || id != path.Base(id) || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("invalid package id %q", id)
+	}
+	return os.RemoveAll(filepath.Join(s.dir, id))
+}
+
+func (s *DirStore) LaunchURL(id, launch string) string {
+	return s.baseURL + "/" + id + "/" + launch
+}
+
+func (s *DirStore) SignURL(launchURL string, expiresAt time.Time) string {
+	rest, ok := strings.CutPrefix(launchURL, s.baseURL+"/")
+	if !ok {
+		return launchURL
+	}
+	id, file, ok := strings.Cut(rest, "/")
+	if !ok {
+		return launchURL
+	}
+	prefix := s.baseURL + "/" + id
+	return prefix + "/" + pkg.SignPathPrefix(prefix, expiresAt) + "/" + file
+}
+
+func (s *DirStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, s.baseURL+"/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	id, token, file := parts[0], parts[1], parts[2]
+	if err := pkg.VerifyPathPrefix(s.baseURL+"/"+id, token); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	// The token only vouches for the one package
+	filePath := path.Clean("/" + id + "/" + file)
+	if !strings.HasPrefix(filePath, "/"+id+"/") {
+		http.NotFound(w, r)
+		return
+	}
+	served, err := s.files.Open(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer served.Close()
+	info, err := served.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	// Package files are served as they are, browsers must not guess other types
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Signed links are handed out per session, so a shared cache must not keep them
+	w.Header().Set("Cache-Control", "private")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), served)
+}
+
+// FromEnv builds the package store. Packages are kept in SCORM_DIR and served at /scorm.
+func FromEnv() (PackageStore, error) {
+	dir := os.Getenv("SCORM_DIR")
+	if dir == "" {
+		dir = filepath.Join("data", "scorm")
+	}
+	return NewDirStore(dir, "/scorm"), nil
+}
+
+// unpack writes the files below root in archive into dir. Names leaving the
+// package are rejected, as is content beyond MaxPackageSize.
+func unpack(archive *zip.Reader, root, dir string) error {
+	var total int64
+	for _, file := range archive.File {
+		name := zipName(file)
+		if root != "." {
+			var ok bool
+			if name, ok = strings.CutPrefix(name, root+"/"); !ok {
+				continue
+			}
+		}
+		if strings.HasSuffix(name, "/") || name == "" {
+			continue
+		}
+		if path.IsAbs(name) || path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, ":") {
+			return fmt.Errorf("%w: %s is not a valid file name", ErrInvalidPackage, file.Name)
+		}
+		if !file.Mode().IsRegular() {
+			return fmt.Errorf("%w: %s is not a regular file", ErrInvalidPackage, file.Name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		written, err := unpackFile(file, target, MaxPackageSize-total)
+		if err != nil {
+			return err
+		}
+		total += written
+	}
+	return nil
+}
+
+func unpackFile(file *zip.File, target string, limit int64) (int64, error) {
+	in, err := file.Open()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", ErrInvalidPackage, file.Name, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return 0, fmt.Errorf("%w: %s is in the zip twice", ErrInvalidPackage, file.Name)
+		}
+		return 0, err
+	}
+	written, err := io.Copy(out, io.LimitReader(in, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, err
+	}
+	if written > limit {
+		return written, fmt.Errorf("%w: the unpacked files are larger than %d MB", ErrInvalidPackage, MaxPackageSize>>20)
+	}
+	return written, nil
+}
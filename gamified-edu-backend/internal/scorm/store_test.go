@@ -0,0 +1,64 @@
+package scorm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirStoreServesPackagesOnlyThroughSignedURLs(t *testing.T) {
+	t.Setenv("URL_SIGNING_KEY", "test-key")
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"pkg1/index.html":    "sco",
+		"pkg1/assets/app.js": "script",
+		"pkg2/secret.html":   "other package",
+	} {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := NewDirStore(dir, "/scorm")
+	get := func(url string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		return recorder
+	}
+
+	launch := store.LaunchURL("pkg1", "index.html")
+	if response := get(launch); response.Code == http.StatusOK {
+		t.Fatalf("the unsigned launch URL %s was served", launch)
+	}
+
+	signed := store.SignURL(launch, time.Now().Add(time.Hour))
+	if response := get(signed); response.Code != http.StatusOK || response.Body.String() != "sco" {
+		t.Fatalf("GET %s = %d %q, want the SCO", signed, response.Code, response.Body.String())
+	}
+	// Files the SCO links to relatively carry the token along
+	base := signed[:strings.LastIndex(signed, "/")+1]
+	if response := get(base + "assets/app.js"); response.Code != http.StatusOK || response.Body.String() != "script" {
+		t.Errorf("relative link = %d %q, want the package file", response.Code, response.Body.String())
+	}
+	for _, url := range []string{
+		base + "../../pkg2/secret.html",
+		base + "%2e%2e/%2e%2e/pkg2/secret.html",
+		strings.Replace(signed, "/pkg1/", "/pkg2/", 1),
+	} {
+		if response := get(url); response.Code == http.StatusOK {
+			t.Errorf("GET %s served another package: %q", url, response.Body.String())
+		}
+	}
+
+	expired := store.SignURL(launch, time.Now().Add(-time.Minute))
+	if response := get(expired); response.Code != http.StatusForbidden {
+		t.Errorf("expired URL = %d, want 403", response.Code)
+	}
+}
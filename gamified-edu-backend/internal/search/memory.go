@@ -0,0 +1,221 @@
+package search
+
+import (
+	"context"
+	"gamified-edu-backend/internal/tenant"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Field weights: a word in a title counts as much as three in a body
+const (
+	titleWeight = 3
+	tagWeight   = 2
+	bodyWeight  = 1
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// MemoryIndex keeps the index in process, ranking with BM25. It is rebuilt
+// on every start, so it suits a single instance with a modest catalog.
+type MemoryIndex struct {
+	mu            sync.RWMutex
+	organizations map[primitive.ObjectID]*memoryOrganization
+}
+
+type memoryOrganization struct {
+	documents map[string]*memoryDocument
+	// postings holds the weighted frequency of each term in each document
+	postings    map[string]map[string]float64
+	totalLength float64
+}
+
+type memoryDocument struct {
+	Document
+	frequencies map[string]float64
+	length      float64
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{organizations: map[primitive.ObjectID]*memoryOrganization{}}
+}
+
+func (x *MemoryIndex) organization(ctx context.Context, create bool) (*memoryOrganization, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	organization := x.organizations[organizationID]
+	if organization == nil && create {
+		organization = &memoryOrganization{documents: map[string]*memoryDocument{}, postings: map[string]map[string]float64{}}
+		x.organizations[organizationID] = organization
+	}
+	return organization, nil
+}
+
+func (x *MemoryIndex) ReplaceCourse(ctx context.Context, courseID primitive.ObjectID, documents []Document) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	organization, err := x.organization(ctx, true)
+	if err != nil {
+		return err
+	}
+	organization.remove(courseID)
+	for _, document := range documents {
+		organization.add(document)
+	}
+	return nil
+}
+
+func (x *MemoryIndex) RemoveCourse(ctx context.Context, courseID primitive.ObjectID) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	organization, err := x.organization(ctx, false)
+	if err != nil || organization == nil {
+		return err
+	}
+	organization.remove(courseID)
+	return nil
+}
+
+func (o *memoryOrganization) add(document Document) {
+	frequencies := map[string]float64{}
+	for _, token := range Tokenize(document.Title) {
+		frequencies[token] += titleWeight
+	}
+	for _, tag := range document.Tags {
+		for _, token := range Tokenize(tag) {
+			frequencies[token] += tagWeight
+		}
+	}
+	for _, token := range Tokenize(document.Body) {
+		frequencies[token] += bodyWeight
+	}
+	indexed := &memoryDocument{Document: document, frequencies: frequencies}
+	for term, frequency := range frequencies {
+		indexed.length += frequency
+		if o.postings[term] == nil {
+			o.postings[term] = map[string]float64{}
+		}
+		o.postings[term][document.Key()] = frequency
+	}
+	o.documents[document.Key()] = indexed
+	o.totalLength += indexed.length
+}
+
+func (o *memoryOrganization) remove(courseID primitive.ObjectID) {
+	for key, document := range o.documents {
+		if document.CourseID != courseID {
+			continue
+		}
+		for term := range document.frequencies {
+			delete(o.postings[term], key)
+			if len(o.postings[term]) == 0 {
+				delete(o.postings, term)
+			}
+		}
+		o.totalLength -= document.length
+		delete(o.documents, key)
+	}
+}
+
+// Search requires every query word to match, each possibly as a prefix or
+// with typos. Documents score the BM25 of their best match for each word.
+func (x *MemoryIndex) Search(ctx context.Context, query Query) ([]Hit, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	organization, err := x.organization(ctx, false)
+	if err != nil || organization == nil || len(organization.documents) == 0 {
+		return []Hit{}, err
+	}
+
+	terms := Tokenize(query.Text)
+	if len(terms) == 0 {
+		return organization.list(query), nil
+	}
+	vocabulary := make([]string, 0, len(organization.postings))
+	for term := range organization.postings {
+		vocabulary = append(vocabulary, term)
+	}
+
+	count := float64(len(organization.documents))
+	averageLength := organization.totalLength / count
+	type candidate struct {
+		score float64
+		terms []string
+	}
+	var candidates map[string]*candidate
+	for i, term := range terms {
+		best := map[string]float64{}
+		matched := map[string][]string{}
+		for _, match := range Expand(term, i == len(terms)-1, vocabulary) {
+			postings := organization.postings[match.Term]
+			idf := math.Log(1 + (count-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+			for key, frequency := range postings {
+				length := organization.documents[key].length
+				score := match.Weight * idf * frequency * (bm25K1 + 1) / (frequency + bm25K1*(1-bm25B+bm25B*length/averageLength))
+				best[key] = math.Max(best[key], score)
+				matched[key] = append(matched[key], match.Term)
+			}
+		}
+		// Documents missing a word drop out
+		next := map[string]*candidate{}
+		for key, score := range best {
+			if candidates != nil && candidates[key] == nil {
+				continue
+			}
+			current := &candidate{}
+			if candidates != nil {
+				current = candidates[key]
+			}
+			current.score += score
+			current.terms = append(current.terms, matched[key]...)
+			next[key] = current
+		}
+		candidates = next
+	}
+
+	hits := []Hit{}
+	for key, candidate := range candidates {
+		document := organization.documents[key].Document
+		if query.Matches(document) {
+			hits = append(hits, Hit{Document: document, Score: candidate.score, Terms: candidate.terms})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Key() < hits[j].Key()
+	})
+	return limitHits(hits, query.Limit), nil
+}
+
+// list returns the courses passing the filters, by title
+func (o *memoryOrganization) list(query Query) []Hit {
+	hits := []Hit{}
+	for _, document := range o.documents {
+		if document.Kind == KindCourse && query.Matches(document.Document) {
+			hits = append(hits, Hit{Document: document.Document})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		return strings.ToLower(hits[i].Title) < strings.ToLower(hits[j].Title)
+	})
+	return limitHits(hits, query.Limit)
+}
+
+func limitHits(hits []Hit, limit int) []Hit {
+	if limit > 0 && len(hits) > limit {
+		return hits[:limit]
+	}
+	return hits
+}
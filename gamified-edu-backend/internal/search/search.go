@@ -0,0 +1,95 @@
+// Package search finds courses and chapters by their text. Services depend
+// on the Index interface; MemoryIndex keeps the index in process and the
+// repositories package has one on a MongoDB text index. Both share the
+// tokenizing, typo tolerance and highlighting here.
+package search
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of documents
+const (
+	KindCourse  = "course"
+	KindChapter = "chapter"
+)
+
+// Document is a course or a chapter as it is searched. Chapters carry the
+// tags and difficulty of their course so filters apply to them too.
+type Document struct {
+	Kind      string
+	CourseID  primitive.ObjectID
+	ChapterID primitive.ObjectID
+	// CourseTitle is the title of the course a chapter belongs to
+	CourseTitle  string
+	Title        string
+	Body         string
+	Tags         []string
+	Difficulty   string
+	DurationMins int
+}
+
+// Key identifies the document within its organization.
+func (d Document) Key() string {
+	if d.Kind == KindChapter {
+		return KindChapter + ":" + d.ChapterID.Hex()
+	}
+	return KindCourse + ":" + d.CourseID.Hex()
+}
+
+// Query is a search. Without Text, it lists the courses passing the filters.
+type Query struct {
+	Text       string
+	Tag        string
+	Difficulty string
+	// MinDuration and MaxDuration bound the duration in minutes; zero means no bound
+	MinDuration int
+	MaxDuration int
+	Limit       int
+}
+
+// Hit is a document matching a query.
+type Hit struct {
+	Document
+	Score float64
+	// Terms are the indexed terms the query matched, typos corrected, for Highlight
+	Terms []string
+}
+
+// Index keeps the documents of each organization's published courses. It
+// works on the organization in ctx.
+type Index interface {
+	// ReplaceCourse replaces the documents of a course, the course's own and its chapters'
+	ReplaceCourse(ctx context.Context, courseID primitive.ObjectID, documents []Document) error
+	RemoveCourse(ctx context.Context, courseID primitive.ObjectID) error
+	// Search returns the best hits first
+	Search(ctx context.Context, query Query) ([]Hit, error)
+}
+
+// Matches reports whether the document passes the query's filters.
+func (q Query) Matches(d Document) bool {
+	if q.Tag != "" && !containsString(d.Tags, q.Tag) {
+		return false
+	}
+	if q.Difficulty != "" && d.Difficulty != q.Difficulty {
+		return false
+	}
+	if q.MinDuration > 0 && d.DurationMins < q.MinDuration {
+		return false
+	}
+	if q.MaxDuration > 0 && d.DurationMins > q.MaxDuration {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stopWords are too common to tell documents apart
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "to": true, "with": true,
+}
+
+// Tokenize splits text into lowercase words, leaving out stop words.
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range words(text) {
+		token := strings.ToLower(text[word.start:word.end])
+		if !stopWords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+type span struct{ start, end int }
+
+// words returns the byte spans of the letter and digit runs of text
+func words(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// Match is an indexed term standing in for a query term.
+type Match struct {
+	Term string
+	// Weight is 1 for the term itself and less for prefixes and typos
+	Weight float64
+}
+
+const (
+	prefixWeight = 0.8
+	typoWeight   = 0.6
+)
+
+// Expand finds the terms of vocabulary a query term may mean: itself, words
+// it is the start of when prefix is set (the word being typed), and words
+// a typo or two away. Short terms tolerate fewer typos.
+func Expand(term string, prefix bool, vocabulary []string) []Match {
+	var matches []Match
+	maxEdits := allowedEdits(term)
+	for _, candidate := range vocabulary {
+		switch {
+		case candidate == term:
+			matches = append(matches, Match{Term: candidate, Weight: 1})
+		case prefix && utf8.RuneCountInString(term) >= 2 && strings.HasPrefix(candidate, term):
+			matches = append(matches, Match{Term: candidate, Weight: prefixWeight})
+		case maxEdits > 0:
+			if edits := editDistance(term, candidate, maxEdits); edits <= maxEdits {
+				matches = append(matches, Match{Term: candidate, Weight: typoWeight / float64(edits)})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Weight > matches[j].Weight })
+	return matches
+}
+
+func allowedEdits(term string) int {
+	switch length := utf8.RuneCountInString(term); {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// neighbours turning a into b. It gives up past limit, returning limit+1.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+	previous2 := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+const (
+	snippetBefore = 40
+	snippetLength = 160
+	maxSnippets   = 3
+)
+
+// Highlight returns snippets of text around the words starting with one of
+// terms, those words wrapped in <mark>. The rest of each snippet is
+// HTML-escaped, so snippets can be shown as HTML.
+func Highlight(text string, terms []string) []string {
+	var marked []span
+	for _, word := range words(text) {
+		token := strings.ToLower(text[word.start:word.end])
+		for _, term := range terms {
+			if strings.HasPrefix(token, term) {
+				marked = append(marked, word)
+				break
+			}
+		}
+	}
+	var snippets []string
+	for i := 0; i < len(marked) && len(snippets) < maxSnippets; {
+		start := wordStart(text, max(0, marked[i].start-snippetBefore))
+		end := wordEnd(text, min(len(text), start+snippetLength))
+		if end < marked[i].end {
+			end = marked[i].end
+		}
+
+		var snippet strings.Builder
+		if start > 0 {
+			snippet.WriteString("…")
+		}
+		at := start
+		for ; i < len(marked) && marked[i].end <= end; i++ {
+			snippet.WriteString(html.EscapeString(text[at:marked[i].start]))
+			snippet.WriteString("<mark>" + html.EscapeString(text[marked[i].start:marked[i].end]) + "</mark>")
+			at = marked[i].end
+		}
+		snippet.WriteString(html.EscapeString(text[at:end]))
+		if end < len(text) {
+			snippet.WriteString("…")
+		}
+		snippets = append(snippets, strings.TrimSpace(snippet.String()))
+	}
+	return snippets
+}
+
+// wordStart moves at forward to the start of a word, unless it is at one
+func wordStart(text string, at int) int {
+	if at == 0 {
+		return 0
+	}
+	for i, r := range text[at:] {
+		if i >= snippetBefore {
+			break
+		}
+		if unicode.IsSpace(r) {
+			return at + i + utf8.RuneLen(r)
+		}
+	}
+	// Stay on a rune boundary
+	for at < len(text) && !utf8.RuneStart(text[at]) {
+		at++
+	}
+	return at
+}
+
+// wordEnd moves at back to the end of a word, unless it is at one
+func wordEnd(text string, at int) int {
+	if at == len(text) {
+		return at
+	}
+	if i := strings.LastIndexFunc(text[:at], unicode.IsSpace); i > 0 && at-i < snippetBefore {
+		return i
+	}
+	for at > 0 && !utf8.RuneStart(text[at]) {
+		at--
+	}
+	return at
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// APIKeyPrefix marks a bearer credential as an API key rather than a JWT.
+	APIKeyPrefix = "gep_"
+
+	apiKeyIDLength     = 8
+	apiKeySecretLength = 32
+	apiKeyAlphabet     = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	// apiKeyCacheTTL bounds how long a revoked key keeps working on other instances.
+	apiKeyCacheTTL = time.Minute
+	// apiKeyUsageFlushInterval throttles usage writes to one per key per interval.
+	apiKeyUsageFlushInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrTwoFactorSetupPending refuses keys to users who still have to set up the 2FA their role requires
+	ErrTwoFactorSetupPending = errors.New("two-factor authentication must be set up before creating API keys")
+)
+
+type APIKeyService interface {
+	CreateUserKey(userID primitive.ObjectID, input CreateAPIKeyInput) (*CreatedAPIKeyResponse, error)
+	// CreateOrganizationKey creates a key of the admin's own organization
+	CreateOrganizationKey(adminID, organizationID primitive.ObjectID, input CreateAPIKeyInput) (*CreatedAPIKeyResponse, error)
+	// ListUserKeys lists a page of the user's personal keys, newest first
+	ListUserKeys(userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[APIKeyResponse], error)
+	// ListOrganizationKeys lists a page of the organization's keys, newest first
+	ListOrganizationKeys(organizationID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[APIKeyResponse], error)
+	RevokeUserKey(userID, keyID primitive.ObjectID) error
+	RevokeOrganizationKey(organizationID, keyID primitive.ObjectID) error
+	AuthenticateAPIKey(rawKey, ipAddress string) (*models.APIKey, string, primitive.ObjectID, error)
+}
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	ID             primitive.ObjectID  `json:"id"`
+	Name           string              `json:"name"`
+	Prefix         string              `json:"prefix"`
+	OwnerType      string              `json:"owner_type"`
+	OrganizationID *primitive.ObjectID `json:"organization_id,omitempty"`
+	Scopes         []string            `json:"scopes"`
+	CreatedAt      time.Time           `json:"created_at"`
+	ExpiresAt      *time.Time          `json:"expires_at,omitempty"`
+	LastUsedAt     *time.Time          `json:"last_used_at,omitempty"`
+	UsageCount     int64               `json:"usage_count"`
+	RevokedAt      *time.Time          `json:"revoked_at,omitempty"`
+}
+
+// CreatedAPIKeyResponse is only returned once, on creation; it is the sole
+// place the full key is ever shown.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type cachedAPIKey struct {
+	key            *models.APIKey
+	role           string
+	organizationID primitive.ObjectID
+	checkedAt      time.Time
+
+	pendingUses int64
+	lastUsedAt  time.Time
+	lastUsedIP  string
+	lastFlush   time.Time
+}
+
+type apiKeyService struct {
+	apiKeyRepo repositories.APIKeyRepository
+	userRepo   repositories.UserRepository
+	policyRepo repositories.SecurityPolicyRepository
+
+	mu    sync.Mutex
+	cache map[string]*cachedAPIKey
+}
+
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepository, userRepo repositories.UserRepository, policyRepo repositories.SecurityPolicyRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		policyRepo: policyRepo,
+		cache:      make(map[string]*cachedAPIKey),
+	}
+}
+
+func (s *apiKeyService) CreateUserKey(userID primitive.ObjectID, input CreateAPIKeyInput) (*CreatedAPIKeyResponse, error) {
+	return s.create(&models.APIKey{OwnerType: models.APIKeyOwnerUser, UserID: userID}, input)
+}
+
+func (s *apiKeyService) CreateOrganizationKey(adminID, organizationID primitive.ObjectID, input CreateAPIKeyInput) (*CreatedAPIKeyResponse, error) {
+	key := &models.APIKey{
+		OwnerType:      models.APIKeyOwnerOrganization,
+		UserID:         adminID,
+		OrganizationID: organizationID,
+	}
+	return s.create(key, input)
+}
+
+func (s *apiKeyService) create(key *models.APIKey, input CreateAPIKeyInput) (*CreatedAPIKeyResponse, error) {
+	scopes, err := validateScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+	// A key skips the second factor, so only users past it may create one
+	owner, err := s.userRepo.FindByID(key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.twoFactorSetupPending(owner)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, ErrTwoFactorSetupPending
+	}
+
+	keyID, err := randomString(apiKeyAlphabet, apiKeyIDLength)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(apiKeyAlphabet, apiKeySecretLength)
+	if err != nil {
+		return nil, err
+	}
+	prefix := APIKeyPrefix + keyID
+	rawKey := prefix + "_" + secret
+
+	key.Name = strings.TrimSpace(input.Name)
+	key.Prefix = prefix
+	key.KeyHash = hashAPIKey(rawKey)
+	key.Scopes = scopes
+	key.CreatedAt = now
+	key.ExpiresAt = input.ExpiresAt
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKeyResponse{APIKeyResponse: toAPIKeyResponse(key), Key: rawKey}, nil
+}
+
+type apiKeyCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+func (s *apiKeyService) ListUserKeys(userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[APIKeyResponse], error) {
+	return listAPIKeys(page, func(before primitive.ObjectID, limit int) ([]models.APIKey, error) {
+		return s.apiKeyRepo.FindPageByUser(userID, before, limit)
+	})
+}
+
+func (s *apiKeyService) ListOrganizationKeys(organizationID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[APIKeyResponse], error) {
+	return listAPIKeys(page, func(before primitive.ObjectID, limit int) ([]models.APIKey, error) {
+		return s.apiKeyRepo.FindPageByOrganization(organizationID, before, limit)
+	})
+}
+
+// listAPIKeys makes a page of the keys findPage returns
+func listAPIKeys(page pkg.PageRequest, findPage func(before primitive.ObjectID, limit int) ([]models.APIKey, error)) (*pkg.Page[APIKeyResponse], error) {
+	var after apiKeyCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	keys, err := findPage(after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	result := pkg.NewPage(toAPIKeyResponses(keys), limit, func(key APIKeyResponse) any { return apiKeyCursor{ID: key.ID} })
+	return &result, nil
+}
+
+func (s *apiKeyService) RevokeUserKey(userID, keyID primitive.ObjectID) error {
+	revoked, err := s.apiKeyRepo.RevokeUserKey(userID, keyID)
+	return s.afterRevoke(keyID, revoked, err)
+}
+
+func (s *apiKeyService) RevokeOrganizationKey(organizationID, keyID primitive.ObjectID) error {
+	revoked, err := s.apiKeyRepo.RevokeOrganizationKey(organizationID, keyID)
+	return s.afterRevoke(keyID, revoked, err)
+}
+
+func (s *apiKeyService) afterRevoke(keyID primitive.ObjectID, revoked bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for prefix, entry := range s.cache {
+		if entry.key.ID == keyID {
+			delete(s.cache, prefix)
+		}
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a raw key to the stored key and the role and
+// organization it acts with. User keys act with their owner's current role in
+// the owner's organization; organization keys act as admins of their
+// organization, restricted by their scopes. Keys are cached
+// for apiKeyCacheTTL and usage is flushed at most once per apiKeyUsageFlushInterval.
+func (s *apiKeyService) AuthenticateAPIKey(rawKey, ipAddress string) (*models.APIKey, string, primitive.ObjectID, error) {
+	prefix, ok := parseAPIKeyPrefix(rawKey)
+	if !ok {
+		return nil, "", primitive.NilObjectID, ErrInvalidAPIKey
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, cached := s.cache[prefix]
+	s.mu.Unlock()
+
+	if !cached || now.Sub(entry.checkedAt) > apiKeyCacheTTL {
+		loaded, err := s.load(prefix)
+		if err != nil {
+			return nil, "", primitive.NilObjectID, err
+		}
+		s.mu.Lock()
+		if cached {
+			// Carry unflushed usage over to the refreshed entry
+			loaded.pendingUses = entry.pendingUses
+			loaded.lastFlush = entry.lastFlush
+		}
+		s.cache[prefix] = loaded
+		entry = loaded
+		s.mu.Unlock()
+	}
+
+	if subtle.ConstantTimeCompare([]byte(entry.key.KeyHash), []byte(hashAPIKey(rawKey))) != 1 {
+		return nil, "", primitive.NilObjectID, ErrInvalidAPIKey
+	}
+	if !entry.key.IsActive(now) {
+		return nil, "", primitive.NilObjectID, ErrInvalidAPIKey
+	}
+
+	s.recordUsage(entry, now, ipAddress)
+	return entry.key, entry.role, entry.organizationID, nil
+}
+
+func (s *apiKeyService) load(prefix string) (*cachedAPIKey, error) {
+	key, err := s.apiKeyRepo.FindByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	role := models.RoleAdmin
+	organizationID := key.OrganizationID
+	if key.OwnerType == models.APIKeyOwnerUser {
+		owner, err := s.userRepo.FindByID(key.UserID)
+		if err != nil {
+			return nil, ErrInvalidAPIKey
+		}
+		// Keys of owners whose role came to require 2FA stop working until they set it up
+		pending, err := s.twoFactorSetupPending(owner)
+		if err != nil {
+			return nil, err
+		}
+		if pending {
+			return nil, ErrInvalidAPIKey
+		}
+		role = owner.EffectiveRole()
+		organizationID = owner.OrganizationID
+	}
+	// A key that cannot be scoped to an organization must not be usable at all
+	if organizationID.IsZero() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	lastFlush := time.Time{}
+	if key.LastUsedAt != nil {
+		lastFlush = *key.LastUsedAt
+	}
+	return &cachedAPIKey{key: key, role: role, organizationID: organizationID, checkedAt: time.Now(), lastFlush: lastFlush}, nil
+}
+
+// twoFactorSetupPending reports whether the user's role requires 2FA they have not set up
+func (s *apiKeyService) twoFactorSetupPending(user *models.User) (bool, error) {
+	if user.TwoFactor.Enabled {
+		return false, nil
+	}
+	policy, err := s.policyRepo.Get()
+	if err != nil {
+		return false, err
+	}
+	return policy.RequiresTwoFactor(user.EffectiveRole()), nil
+}
+
+func (s *apiKeyService) recordUsage(entry *cachedAPIKey, now time.Time, ipAddress string) {
+	s.mu.Lock()
+	entry.pendingUses++
+	entry.lastUsedAt = now
+	entry.lastUsedIP = ipAddress
+	if now.Sub(entry.lastFlush) < apiKeyUsageFlushInterval {
+		s.mu.Unlock()
+		return
+	}
+	uses := entry.pendingUses
+	entry.pendingUses = 0
+	entry.lastFlush = now
+	keyID := entry.key.ID
+	s.mu.Unlock()
+
+	if err := s.apiKeyRepo.RecordUsage(keyID, now, ipAddress, uses); err != nil {
+		log.Printf("Could not record usage for API key %s: %v", keyID.Hex(), err)
+	}
+}
+
+// parseAPIKeyPrefix extracts "gep_<id>" from a key of the form "gep_<id>_<secret>".
+func parseAPIKeyPrefix(rawKey string) (string, bool) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(rawKey, APIKeyPrefix), "_")
+	if len(parts) != 2 || len(parts[0]) != apiKeyIDLength || len(parts[1]) != apiKeySecretLength {
+		return "", false
+	}
+	return APIKeyPrefix + parts[0], true
+}
+
+func validateScopes(scopes []string) ([]string, error) {
+	known := map[string]bool{}
+	for _, scope := range models.APIKeyScopes {
+		known[scope] = true
+	}
+	seen := map[string]bool{}
+	result := []string{}
+	for _, scope := range scopes {
+		if !known[scope] {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns length characters picked uniformly from alphabet.
+func randomString(alphabet string, length int) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	out := make([]byte, length)
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = alphabet[n.Int64()]
+	}
+	return string(out), nil
+}
+
+func toAPIKeyResponse(key *models.APIKey) APIKeyResponse {
+	response := APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		OwnerType:  key.OwnerType,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		UsageCount: key.UsageCount,
+		RevokedAt:  key.RevokedAt,
+	}
+	if !key.OrganizationID.IsZero() {
+		organizationID := key.OrganizationID
+		response.OrganizationID = &organizationID
+	}
+	return response
+}
+
+func toAPIKeyResponses(keys []models.APIKey) []APIKeyResponse {
+	responses := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, toAPIKeyResponse(&keys[i]))
+	}
+	return responses
+}
@@ -7,19 +7,28 @@ import (
     "gamified-edu-backend/pkg"
     "go.mongodb.org/mongo-driver/mongo"
     "golang.org/x/crypto/bcrypt"
+    "strings"
+    "time"
 )
 
+// ErrInvalidOrganizationCode is returned when a user registers with an unknown organization code.
+var ErrInvalidOrganizationCode = errors.New("invalid organization code")
+
 type AuthService interface {
     RegisterUser(input RegisterInput) (*models.User, error)
-    LoginUser(input LoginInput) (string, error)
+    LoginUser(input LoginInput, client ClientInfo) (*LoginResult, error)
+    CompleteTwoFactorLogin(input TwoFactorLoginInput, client ClientInfo) (*LoginResult, error)
 }
 
 type authService struct {
-    userRepo repositories.UserRepository
+    userRepo         repositories.UserRepository
+    policyRepo       repositories.SecurityPolicyRepository
+    organizationRepo repositories.OrganizationRepository
+    sessionService   SessionService
 }
 
-func NewAuthService(userRepo repositories.UserRepository) AuthService {
-    return &authService{userRepo: userRepo}
+func NewAuthService(userRepo repositories.UserRepository, policyRepo repositories.SecurityPolicyRepository, organizationRepo repositories.OrganizationRepository, sessionService SessionService) AuthService {
+    return &authService{userRepo: userRepo, policyRepo: policyRepo, organizationRepo: organizationRepo, sessionService: sessionService}
 }
 
 type RegisterInput struct {
@@ -27,37 +36,137 @@ type RegisterInput struct {
     LastName  string `json:"last_name" binding:"required"`
     Email     string `json:"email" binding:"required"`
     Password  string `json:"password" binding:"required"`
+    // OrganizationCode is the registration code of the school to join. Users
+    // registering without one join the default organization.
+    OrganizationCode string `json:"organization_code"`
+    // AccountType is "student" (the default) or "guardian" for parents who
+    // follow a student's progress
+    AccountType string `json:"account_type" binding:"omitempty,oneof=student guardian"`
 }
 
 type LoginInput struct {
     Email    string `json:"email" binding:"required"`
     Password string `json:"password" binding:"required"`
+    // Device optionally names the client, e.g. "Work laptop"
+    Device string `json:"device"`
+}
+
+type TwoFactorLoginInput struct {
+    ChallengeToken string `json:"challenge_token" binding:"required"`
+    // Code is either a current TOTP code or one of the user's recovery codes
+    Code   string `json:"code" binding:"required"`
+    Device string `json:"device"`
+}
+
+// LoginResult either carries an access token, or a challenge token that must
+// be completed with a TOTP code through CompleteTwoFactorLogin.
+type LoginResult struct {
+    Token                  string `json:"token,omitempty"`
+    TwoFactorRequired      bool   `json:"two_factor_required,omitempty"`
+    ChallengeToken         string `json:"challenge_token,omitempty"`
+    TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"`
 }
 
 func (s *authService) RegisterUser(input RegisterInput) (*models.User, error) {
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
     if err != nil { return nil, err }
-    
+
+    organization, err := s.registrationOrganization(input.OrganizationCode)
+    if err != nil { return nil, err }
+
+    role := models.RoleStudent
+    if input.AccountType == models.RoleGuardian { role = models.RoleGuardian }
+
     // Initialize new users with proper starting values
     user := models.User{
-        FirstName:    input.FirstName, 
-        LastName:     input.LastName,
-        Email:        input.Email, 
-        PasswordHash: string(hashedPassword),
-        XP:           0,    // New users start with 0 XP
-        Level:        1,    // New users start at Level 1
+        FirstName:      input.FirstName,
+        LastName:       input.LastName,
+        Email:          input.Email,
+        PasswordHash:   string(hashedPassword),
+        Role:           role,
+        OrganizationID: organization.ID,
+        XP:             0,    // New users start with 0 XP
+        Level:          1,    // New users start at Level 1
+        Profile:        models.UserProfile{Notifications: models.DefaultNotificationPreferences()},
+        CreatedAt:      time.Now(),
     }
     err = s.userRepo.Create(&user)
     return &user, err
 }
 
-func (s *authService) LoginUser(input LoginInput) (string, error) {
+// registrationOrganization resolves the organization a new user joins
+func (s *authService) registrationOrganization(code string) (*models.Organization, error) {
+    code = strings.TrimSpace(code)
+    if code == "" {
+        return s.organizationRepo.EnsureDefault()
+    }
+    organization, err := s.organizationRepo.FindByRegistrationCode(code)
+    if err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) { return nil, ErrInvalidOrganizationCode }
+        return nil, err
+    }
+    return organization, nil
+}
+
+func (s *authService) LoginUser(input LoginInput, client ClientInfo) (*LoginResult, error) {
     user, err := s.userRepo.FindByEmail(input.Email)
     if err != nil {
-        if err == mongo.ErrNoDocuments { return "", errors.New("invalid credentials") }
-        return "", err
+        if err == mongo.ErrNoDocuments { return nil, errors.New("invalid credentials") }
+        return nil, err
     }
     err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
-    if err != nil { return "", errors.New("invalid credentials") }
-    return pkg.GenerateToken(user.ID)
-}
\ No newline at end of file
+    if err != nil { return nil, errors.New("invalid credentials") }
+
+    // Enrolled users have to finish the login with a second factor
+    if user.TwoFactor.Enabled {
+        challenge, err := pkg.GenerateChallengeToken(user.ID)
+        if err != nil { return nil, err }
+        return &LoginResult{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+    }
+
+    policy, err := s.policyRepo.Get()
+    if err != nil { return nil, err }
+    setupRequired := policy.RequiresTwoFactor(user.EffectiveRole())
+
+    client.Device = input.Device
+    token, err := s.issueToken(user, client, setupRequired)
+    if err != nil { return nil, err }
+    return &LoginResult{Token: token, TwoFactorSetupRequired: setupRequired}, nil
+}
+
+func (s *authService) CompleteTwoFactorLogin(input TwoFactorLoginInput, client ClientInfo) (*LoginResult, error) {
+    userID, err := pkg.ValidateChallengeToken(input.ChallengeToken)
+    if err != nil { return nil, errors.New("invalid or expired challenge") }
+
+    user, err := s.userRepo.FindByID(userID)
+    if err != nil { return nil, errors.New("invalid or expired challenge") }
+    if !user.TwoFactor.Enabled { return nil, errors.New("two-factor authentication is not enabled") }
+
+    if err := verifySecondFactor(s.userRepo, user, input.Code, true); err != nil {
+        return nil, err
+    }
+
+    client.Device = input.Device
+    token, err := s.issueToken(user, client, false)
+    if err != nil { return nil, err }
+    return &LoginResult{Token: token}, nil
+}
+
+// issueToken records a new session for the login and returns a token bound to it
+func (s *authService) issueToken(user *models.User, client ClientInfo, setupRequired bool) (string, error) {
+    return issueSessionToken(s.sessionService, user, client, setupRequired)
+}
+
+// issueSessionToken is issueToken for other ways of signing in, like LTI launches
+func issueSessionToken(sessionService SessionService, user *models.User, client ClientInfo, setupRequired bool) (string, error) {
+    if user.OrganizationID.IsZero() { return "", errors.New("account is not assigned to an organization") }
+    session, err := sessionService.CreateSession(user.ID, client)
+    if err != nil { return "", err }
+    return pkg.GenerateToken(pkg.AuthClaims{
+        UserID:                 user.ID,
+        SessionID:              session.ID,
+        Role:                   user.EffectiveRole(),
+        OrganizationID:         user.OrganizationID,
+        TwoFactorSetupRequired: setupRequired,
+    })
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrChapterNotReleased is returned for progress on a chapter before its release.
+var ErrChapterNotReleased = errors.New("chapter is not released yet")
+
+// releaseDateLayout shows release dates to learners, in their time zone
+const releaseDateLayout = "January 2, 2006"
+
+// releaseSchedule works out when a learner gets the chapters of a course,
+// from their enrollment and cohort.
+type releaseSchedule struct {
+	now        time.Time
+	enrolledAt *time.Time
+	cohort     *models.Cohort
+	// location is the learner's time zone, for the dates they are shown
+	location *time.Location
+}
+
+// loadReleaseSchedule reads the schedule of the user, whose enrollment may
+// be nil. Chapters released relative to an enrollment stay unreleased
+// without one.
+func loadReleaseSchedule(ctx context.Context, cohortRepo repositories.CohortRepository, userRepo repositories.UserRepository, userID primitive.ObjectID, enrollment *models.Enrollment, now time.Time) (*releaseSchedule, error) {
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &releaseSchedule{now: now, location: learnerLocation(user.Profile.TimeZone)}
+	if enrollment == nil || !enrollment.IsActive() {
+		return schedule, nil
+	}
+	enrolledAt := enrollment.EnrolledAt
+	schedule.enrolledAt = &enrolledAt
+	if enrollment.CohortID.IsZero() {
+		return schedule, nil
+	}
+	cohort, err := cohortRepo.FindByID(ctx, enrollment.CohortID)
+	if err != nil {
+		// A deleted cohort leaves its learners on their enrollment dates
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return schedule, nil
+		}
+		return nil, err
+	}
+	schedule.cohort = cohort
+	return schedule, nil
+}
+
+// availableOn returns when the chapter is released to the learner, nil if
+// it has no date for them yet, and whether it is released by now
+func (r *releaseSchedule) availableOn(chapter models.Chapter) (*time.Time, bool) {
+	release := chapter.Release
+	if release == nil {
+		return nil, true
+	}
+	var at *time.Time
+	switch release.Rule {
+	case models.ReleaseOnDate:
+		at = release.At
+	case models.ReleaseAfterCohortStart:
+		if r.cohort != nil {
+			opens := r.cohort.StartsAt.AddDate(0, 0, release.OffsetDays)
+			at = &opens
+			break
+		}
+		fallthrough
+	case models.ReleaseAfterEnrollment:
+		if r.enrolledAt != nil {
+			opens := r.enrolledAt.AddDate(0, 0, release.OffsetDays)
+			at = &opens
+		}
+	}
+	if at == nil {
+		return nil, false
+	}
+	return at, !r.now.Before(*at)
+}
+
+// learnerLocation loads the IANA time zone of a profile, UTC if it has none
+// or the name is not known here
+func learnerLocation(timeZone string) *time.Location {
+	if timeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// lockReason tells the learner when an unreleased chapter opens
+func (r *releaseSchedule) lockReason(release *models.ChapterRelease, availableOn *time.Time) string {
+	if availableOn != nil {
+		return "Available on " + availableOn.In(r.location).Format(releaseDateLayout)
+	}
+	if release.OffsetDays == 0 {
+		return "Available once you enroll"
+	}
+	return fmt.Sprintf("Available %d days after you enroll", release.OffsetDays)
+}
+
+// checkReleased rejects work on the chapter before its release
+func (r *releaseSchedule) checkReleased(chapter models.Chapter) error {
+	availableOn, released := r.availableOn(chapter)
+	if released {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrChapterNotReleased, r.lockReason(chapter.Release, availableOn))
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeCourses struct {
+	repositories.CourseRepository
+	course *models.Course
+}
+
+func (r *fakeCourses) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Course, error) {
+	if id != r.course.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return r.course, nil
+}
+
+type fakeEnrollments struct {
+	repositories.EnrollmentRepository
+	enrollment *models.Enrollment
+}
+
+func (r *fakeEnrollments) Find(ctx context.Context, userID, courseID primitive.ObjectID) (*models.Enrollment, error) {
+	if r.enrollment == nil || r.enrollment.UserID != userID || r.enrollment.CourseID != courseID {
+		return nil, nil
+	}
+	return r.enrollment, nil
+}
+
+type fakeCohorts struct {
+	repositories.CohortRepository
+	cohort *models.Cohort
+}
+
+func (r *fakeCohorts) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Cohort, error) {
+	if r.cohort == nil || id != r.cohort.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return r.cohort, nil
+}
+
+// fakeProgress has no progress for anyone
+type fakeProgress struct {
+	repositories.ProgressRepository
+}
+
+func (r *fakeProgress) FindByUserAndChapter(ctx context.Context, userID, chapterID primitive.ObjectID) (*models.UserChapterStatus, error) {
+	return nil, nil
+}
+
+func day(month time.Month, date int) time.Time {
+	return time.Date(2026, month, date, 0, 0, 0, 0, time.UTC)
+}
+
+// releaseTest is a learner who enrolled on September 10 in a course with a
+// chapter for each release rule, in a cohort that started on September 7
+type releaseTest struct {
+	clock       pkg.FixedClock
+	user        *models.User
+	course      *models.Course
+	enrollment  *models.Enrollment
+	cohort      *models.Cohort
+	chapters    map[string]primitive.ObjectID
+	courseRepo  *fakeCourses
+	cohortRepo  *fakeCohorts
+	userRepo    *fakeUsers
+	enrollments *fakeEnrollments
+}
+
+func newReleaseTest() *releaseTest {
+	nextWeek := day(time.September, 21)
+	today := day(time.September, 14)
+	releases := map[string]*models.ChapterRelease{
+		"on a past date":          {Rule: models.ReleaseOnDate, At: &today},
+		"on a later date":         {Rule: models.ReleaseOnDate, At: &nextWeek},
+		"3 days after enrolling":  {Rule: models.ReleaseAfterEnrollment, OffsetDays: 3},
+		"7 days after enrolling":  {Rule: models.ReleaseAfterEnrollment, OffsetDays: 7},
+		"7 days after the start":  {Rule: models.ReleaseAfterCohortStart, OffsetDays: 7},
+		"14 days after the start": {Rule: models.ReleaseAfterCohortStart, OffsetDays: 14},
+	}
+	test := &releaseTest{
+		clock:    pkg.FixedClock(time.Date(2026, time.September, 14, 12, 0, 0, 0, time.UTC)),
+		user:     &models.User{ID: primitive.NewObjectID()},
+		course:   &models.Course{ID: primitive.NewObjectID(), PublishedVersion: 1, Modules: []models.Module{{ID: primitive.NewObjectID()}}},
+		chapters: map[string]primitive.ObjectID{},
+	}
+	for title, release := range releases {
+		chapter := models.Chapter{ID: primitive.NewObjectID(), Title: title, Release: release}
+		test.course.Modules[0].Chapters = append(test.course.Modules[0].Chapters, chapter)
+		test.chapters[title] = chapter.ID
+	}
+	test.cohort = &models.Cohort{ID: primitive.NewObjectID(), CourseID: test.course.ID, StartsAt: day(time.September, 7)}
+	test.enrollment = &models.Enrollment{UserID: test.user.ID, CourseID: test.course.ID, EnrolledAt: day(time.September, 10), CohortID: test.cohort.ID}
+	test.courseRepo = &fakeCourses{course: test.course}
+	test.cohortRepo = &fakeCohorts{cohort: test.cohort}
+	test.userRepo = &fakeUsers{users: map[primitive.ObjectID]*models.User{test.user.ID: test.user}}
+	test.enrollments = &fakeEnrollments{enrollment: test.enrollment}
+	return test
+}
+
+func (test *releaseTest) details(t *testing.T) map[string]ChapterWithProgress {
+	service := NewCourseService(test.courseRepo, &fakeProgress{}, test.enrollments, test.userRepo, nil, nil, test.cohortRepo, test.clock)
+	details, err := service.GetCourseDetails(context.Background(), test.course.ID, test.user.ID)
+	if err != nil {
+		t.Fatalf("GetCourseDetails: %v", err)
+	}
+	chapters := map[string]ChapterWithProgress{}
+	for _, chapter := range details.Chapters {
+		chapters[chapter.Title] = chapter
+	}
+	return chapters
+}
+
+func TestChapterReleaseRules(t *testing.T) {
+	tests := []struct {
+		title       string
+		released    bool
+		availableOn time.Time
+	}{
+		{"on a past date", true, day(time.September, 14)},
+		{"on a later date", false, day(time.September, 21)},
+		{"3 days after enrolling", true, day(time.September, 13)},
+		{"7 days after enrolling", false, day(time.September, 17)},
+		{"7 days after the start", true, day(time.September, 14)},
+		{"14 days after the start", false, day(time.September, 21)},
+	}
+	chapters := newReleaseTest().details(t)
+	for _, test := range tests {
+		chapter := chapters[test.title]
+		if chapter.IsReleased != test.released || chapter.IsLocked == test.released {
+			t.Errorf("%s: got released %v and locked %v, want released %v", test.title, chapter.IsReleased, chapter.IsLocked, test.released)
+		}
+		if test.released {
+			continue
+		}
+		if chapter.AvailableOn == nil || !chapter.AvailableOn.Equal(test.availableOn) {
+			t.Errorf("%s: got available on %v, want %v", test.title, chapter.AvailableOn, test.availableOn)
+		}
+		if want := "Available on " + test.availableOn.Format(releaseDateLayout); chapter.LockReason != want {
+			t.Errorf("%s: got lock reason %q, want %q", test.title, chapter.LockReason, want)
+		}
+	}
+}
+
+func TestChapterReleaseRulesWithoutCohortOrEnrollment(t *testing.T) {
+	test := newReleaseTest()
+	test.enrollment.CohortID = primitive.NilObjectID
+
+	// Cohort chapters go by the enrollment date outside a cohort
+	chapters := test.details(t)
+	if chapter := chapters["7 days after the start"]; chapter.IsReleased || chapter.AvailableOn == nil || !chapter.AvailableOn.Equal(day(time.September, 17)) {
+		t.Errorf("outside a cohort: got released %v, available on %v, want September 17", chapter.IsReleased, chapter.AvailableOn)
+	}
+
+	test.enrollments.enrollment = nil
+	chapters = test.details(t)
+	if chapter := chapters["on a past date"]; !chapter.IsReleased {
+		t.Error("not enrolled: a chapter released on a past date is locked")
+	}
+	if chapter := chapters["3 days after enrolling"]; chapter.IsReleased || chapter.AvailableOn != nil || chapter.LockReason != "Available 3 days after you enroll" {
+		t.Errorf("not enrolled: got released %v, available on %v, lock reason %q", chapter.IsReleased, chapter.AvailableOn, chapter.LockReason)
+	}
+}
+
+func TestChapterReleaseDatesAreShownInTheLearnersTimeZone(t *testing.T) {
+	tests := map[string]string{
+		// Midnight UTC on September 21 is still September 20 in New York
+		"America/New_York": "Available on September 20, 2026",
+		"Asia/Tokyo":       "Available on September 21, 2026",
+		"":                 "Available on September 21, 2026",
+		"Not/A_Zone":       "Available on September 21, 2026",
+	}
+	for timeZone, want := range tests {
+		test := newReleaseTest()
+		test.user.Profile.TimeZone = timeZone
+		if got := test.details(t)["on a later date"].LockReason; got != want {
+			t.Errorf("time zone %q: got %q, want %q", timeZone, got, want)
+		}
+	}
+}
+
+func TestProgressOnUnreleasedChaptersIsRejected(t *testing.T) {
+	test := newReleaseTest()
+	test.user.Profile.TimeZone = "America/New_York"
+	service := NewProgressService(&fakeProgress{}, test.userRepo, nil, test.courseRepo, test.enrollments, nil, test.cohortRepo, test.clock)
+
+	err := service.MarkComponentAsComplete(context.Background(), test.user.ID, test.chapters["14 days after the start"], test.course.ID, "video", MarkComponentInput{})
+	if !errors.Is(err, ErrChapterNotReleased) || !strings.Contains(err.Error(), "September 20, 2026") {
+		t.Errorf("got %v, want ErrChapterNotReleased with the date in the learner's time zone", err)
+	}
+}
@@ -0,0 +1,628 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	classroomJoinCodeLength = 8
+	// classroomJoinCodeAlphabet leaves out characters that are easily confused, like 0/O and 1/I
+	classroomJoinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	classroomJoinCodeAttempts = 5
+)
+
+var (
+	ErrClassroomNotFound  = errors.New("classroom not found")
+	ErrAssignmentNotFound = errors.New("assignment not found")
+	ErrInvalidJoinCode    = errors.New("invalid join code")
+	ErrNotInstructor      = errors.New("only the classroom's instructor can do this")
+)
+
+type ClassroomService interface {
+	CreateClassroom(ctx context.Context, instructorID primitive.ObjectID, input CreateClassroomInput) (*ClassroomResponse, error)
+	ListClassrooms(ctx context.Context, actor ClassroomActor, page pkg.PageRequest) (*pkg.Page[ClassroomResponse], error)
+	GetClassroom(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) (*ClassroomResponse, error)
+	RotateJoinCode(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) (*ClassroomResponse, error)
+	DeleteClassroom(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) error
+	JoinClassroom(ctx context.Context, actor ClassroomActor, code string) (*ClassroomResponse, error)
+	RemoveMember(ctx context.Context, actor ClassroomActor, classroomID, userID primitive.ObjectID) error
+	CreateAssignment(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, input CreateAssignmentInput) (*AssignmentResponse, error)
+	ListAssignments(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[AssignmentResponse], error)
+	DeleteAssignment(ctx context.Context, actor ClassroomActor, classroomID, assignmentID primitive.ObjectID) error
+	GetRoster(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) (*RosterResponse, error)
+}
+
+// ClassroomActor is the user making a classroom request. Admins can manage
+// every classroom of their organization, instructors only their own.
+type ClassroomActor struct {
+	UserID primitive.ObjectID
+	Role   string
+}
+
+type CreateClassroomInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type JoinClassroomInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type CreateAssignmentInput struct {
+	CourseID string `json:"course_id" binding:"required"`
+	// ChapterIDs limits the assignment to some chapters of the course; empty means the whole course
+	ChapterIDs []string  `json:"chapter_ids"`
+	Title      string    `json:"title"`
+	DueAt      time.Time `json:"due_at" binding:"required"`
+}
+
+type ClassroomResponse struct {
+	ID           primitive.ObjectID `json:"id"`
+	Name         string             `json:"name"`
+	InstructorID primitive.ObjectID `json:"instructor_id"`
+	// JoinCode is only shown to the people managing the classroom
+	JoinCode  string    `json:"join_code,omitempty"`
+	CanManage bool      `json:"can_manage"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AssignmentResponse struct {
+	ID          primitive.ObjectID   `json:"id"`
+	ClassroomID primitive.ObjectID   `json:"classroom_id"`
+	CourseID    primitive.ObjectID   `json:"course_id"`
+	ChapterIDs  []primitive.ObjectID `json:"chapter_ids,omitempty"`
+	Title       string               `json:"title"`
+	DueAt       time.Time            `json:"due_at"`
+	CreatedAt   time.Time            `json:"created_at"`
+	// Progress is the requesting student's own progress; it is left out for instructors
+	Progress *AssignmentProgress `json:"progress,omitempty"`
+}
+
+// AssignmentProgress is a student's standing on one assignment.
+type AssignmentProgress struct {
+	AssignmentID      primitive.ObjectID `json:"assignment_id"`
+	Status            string             `json:"status"`
+	CompletedChapters int                `json:"completed_chapters"`
+	TotalChapters     int                `json:"total_chapters"`
+	CompletedAt       *time.Time         `json:"completed_at,omitempty"`
+}
+
+type RosterResponse struct {
+	ClassroomID primitive.ObjectID   `json:"classroom_id"`
+	Assignments []AssignmentResponse `json:"assignments"`
+	Students    []RosterStudent      `json:"students"`
+}
+
+type RosterStudent struct {
+	UserID      primitive.ObjectID   `json:"user_id"`
+	Name        string               `json:"name"`
+	Email       string               `json:"email"`
+	JoinedAt    time.Time            `json:"joined_at"`
+	Assignments []AssignmentProgress `json:"assignments"`
+}
+
+type classroomService struct {
+	classroomRepo  repositories.ClassroomRepository
+	assignmentRepo repositories.AssignmentRepository
+	courseRepo     repositories.CourseRepository
+	progressRepo   repositories.ProgressRepository
+	userRepo       repositories.UserRepository
+}
+
+func NewClassroomService(classroomRepo repositories.ClassroomRepository, assignmentRepo repositories.AssignmentRepository, courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, userRepo repositories.UserRepository) ClassroomService {
+	return &classroomService{classroomRepo, assignmentRepo, courseRepo, progressRepo, userRepo}
+}
+
+func (s *classroomService) CreateClassroom(ctx context.Context, instructorID primitive.ObjectID, input CreateClassroomInput) (*ClassroomResponse, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.New("name must not be empty")
+	}
+	code, err := s.newJoinCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	classroom := &models.Classroom{
+		Name:         name,
+		InstructorID: instructorID,
+		JoinCode:     code,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.classroomRepo.Create(ctx, classroom); err != nil {
+		return nil, err
+	}
+	return toClassroomResponse(classroom, true), nil
+}
+
+type classroomCursor struct {
+	Name string             `json:"name"`
+	ID   primitive.ObjectID `json:"id"`
+}
+
+type assignmentCursor struct {
+	DueAt time.Time          `json:"due_at"`
+	ID    primitive.ObjectID `json:"id"`
+}
+
+// ListClassrooms lists a page of the classrooms the user teaches or joined, by name
+func (s *classroomService) ListClassrooms(ctx context.Context, actor ClassroomActor, page pkg.PageRequest) (*pkg.Page[ClassroomResponse], error) {
+	var after *models.Classroom
+	if page.Cursor != "" {
+		var position classroomCursor
+		if err := pkg.DecodeCursor(page.Cursor, &position); err != nil {
+			return nil, err
+		}
+		after = &models.Classroom{ID: position.ID, Name: position.Name}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	classrooms, err := s.classroomRepo.FindPageForUser(ctx, actor.UserID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]ClassroomResponse, 0, len(classrooms))
+	for i := range classrooms {
+		responses = append(responses, *toClassroomResponse(&classrooms[i], classrooms[i].InstructorID == actor.UserID))
+	}
+	result := pkg.NewPage(responses, limit, func(classroom ClassroomResponse) any {
+		return classroomCursor{Name: classroom.Name, ID: classroom.ID}
+	})
+	return &result, nil
+}
+
+func (s *classroomService) GetClassroom(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) (*ClassroomResponse, error) {
+	classroom, manages, err := loadClassroom(ctx, s.classroomRepo, actor, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	return toClassroomResponse(classroom, manages), nil
+}
+
+// RotateJoinCode replaces the join code, e.g. after it was shared too widely.
+// Students who already joined stay in the classroom.
+func (s *classroomService) RotateJoinCode(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) (*ClassroomResponse, error) {
+	classroom, err := loadManagedClassroom(ctx, s.classroomRepo, actor, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.newJoinCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.classroomRepo.UpdateJoinCode(ctx, classroom.ID, code); err != nil {
+		return nil, err
+	}
+	classroom.JoinCode = code
+	return toClassroomResponse(classroom, true), nil
+}
+
+func (s *classroomService) DeleteClassroom(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) error {
+	classroom, err := loadManagedClassroom(ctx, s.classroomRepo, actor, classroomID)
+	if err != nil {
+		return err
+	}
+	if err := s.assignmentRepo.DeleteByClassroom(ctx, classroom.ID); err != nil {
+		return err
+	}
+	return s.classroomRepo.Delete(ctx, classroom.ID)
+}
+
+func (s *classroomService) JoinClassroom(ctx context.Context, actor ClassroomActor, code string) (*ClassroomResponse, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	classroom, err := s.classroomRepo.FindByJoinCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidJoinCode
+		}
+		return nil, err
+	}
+	if classroom.InstructorID == actor.UserID {
+		return nil, errors.New("you are the instructor of this classroom")
+	}
+	if err := s.classroomRepo.AddMember(ctx, classroom.ID, actor.UserID); err != nil {
+		return nil, err
+	}
+	return toClassroomResponse(classroom, false), nil
+}
+
+// RemoveMember lets instructors remove a student, and students leave a classroom
+func (s *classroomService) RemoveMember(ctx context.Context, actor ClassroomActor, classroomID, userID primitive.ObjectID) error {
+	if actor.UserID != userID {
+		if _, err := loadManagedClassroom(ctx, s.classroomRepo, actor, classroomID); err != nil {
+			return err
+		}
+	}
+	removed, err := s.classroomRepo.RemoveMember(ctx, classroomID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrClassroomNotFound
+	}
+	return nil
+}
+
+func (s *classroomService) CreateAssignment(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, input CreateAssignmentInput) (*AssignmentResponse, error) {
+	classroom, err := loadManagedClassroom(ctx, s.classroomRepo, actor, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	courseID, err := primitive.ObjectIDFromHex(input.CourseID)
+	if err != nil {
+		return nil, errors.New("invalid course ID format")
+	}
+	course, err := s.courseRepo.FindByID(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("course not found")
+		}
+		return nil, err
+	}
+	if !course.IsPublished() {
+		return nil, errors.New("course is not published yet")
+	}
+	chapterIDs, err := parseAssignmentChapters(course, input.ChapterIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		title = course.Title
+	}
+	assignment := &models.Assignment{
+		ClassroomID: classroom.ID,
+		CourseID:    course.ID,
+		ChapterIDs:  chapterIDs,
+		Title:       title,
+		DueAt:       input.DueAt,
+		CreatedBy:   actor.UserID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.assignmentRepo.Create(ctx, assignment); err != nil {
+		return nil, err
+	}
+	response := toAssignmentResponse(assignment)
+	return &response, nil
+}
+
+// ListAssignments lists a page of the classroom's assignments, soonest due
+// first. Students additionally get their own progress on each one.
+func (s *classroomService) ListAssignments(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[AssignmentResponse], error) {
+	var after *models.Assignment
+	if page.Cursor != "" {
+		var position assignmentCursor
+		if err := pkg.DecodeCursor(page.Cursor, &position); err != nil {
+			return nil, err
+		}
+		after = &models.Assignment{ID: position.ID, DueAt: position.DueAt}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	classroom, manages, err := loadClassroom(ctx, s.classroomRepo, actor, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.assignmentRepo.FindPageByClassroom(ctx, classroom.ID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	stored := pkg.NewPage(found, limit, func(assignment models.Assignment) any {
+		return assignmentCursor{DueAt: assignment.DueAt, ID: assignment.ID}
+	})
+	assignments := stored.Items
+	result := pkg.Page[AssignmentResponse]{Items: make([]AssignmentResponse, 0, len(assignments)), NextCursor: stored.NextCursor}
+	for i := range assignments {
+		result.Items = append(result.Items, toAssignmentResponse(&assignments[i]))
+	}
+	if manages || len(assignments) == 0 {
+		return &result, nil
+	}
+
+	tracker, err := s.newProgressTracker(ctx, assignments, []primitive.ObjectID{actor.UserID})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range assignments {
+		progress := tracker.progress(actor.UserID, &assignments[i], now)
+		result.Items[i].Progress = &progress
+	}
+	return &result, nil
+}
+
+func (s *classroomService) DeleteAssignment(ctx context.Context, actor ClassroomActor, classroomID, assignmentID primitive.ObjectID) error {
+	if _, err := loadManagedClassroom(ctx, s.classroomRepo, actor, classroomID); err != nil {
+		return err
+	}
+	deleted, err := s.assignmentRepo.Delete(ctx, classroomID, assignmentID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAssignmentNotFound
+	}
+	return nil
+}
+
+// GetRoster returns every student of the classroom with their status on every assignment
+func (s *classroomService) GetRoster(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID) (*RosterResponse, error) {
+	classroom, err := loadManagedClassroom(ctx, s.classroomRepo, actor, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.assignmentRepo.FindByClassroom(ctx, classroom.ID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.classroomRepo.FindMembers(ctx, classroom.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := &RosterResponse{
+		ClassroomID: classroom.ID,
+		Assignments: make([]AssignmentResponse, 0, len(assignments)),
+		Students:    make([]RosterStudent, 0, len(members)),
+	}
+	for i := range assignments {
+		roster.Assignments = append(roster.Assignments, toAssignmentResponse(&assignments[i]))
+	}
+	if len(members) == 0 {
+		return roster, nil
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	users, err := s.userRepo.FindByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	usersByID := make(map[primitive.ObjectID]*models.User, len(users))
+	for i := range users {
+		usersByID[users[i].ID] = &users[i]
+	}
+
+	tracker, err := s.newProgressTracker(ctx, assignments, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, member := range members {
+		student := RosterStudent{
+			UserID:      member.UserID,
+			JoinedAt:    member.JoinedAt,
+			Assignments: make([]AssignmentProgress, 0, len(assignments)),
+		}
+		if user, ok := usersByID[member.UserID]; ok {
+			student.Name = user.DisplayNameOrFullName()
+			student.Email = user.Email
+		}
+		for i := range assignments {
+			student.Assignments = append(student.Assignments, tracker.progress(member.UserID, &assignments[i], now))
+		}
+		roster.Students = append(roster.Students, student)
+	}
+	return roster, nil
+}
+
+// loadManagedClassroom returns the classroom if actor may manage it
+func loadManagedClassroom(ctx context.Context, classroomRepo repositories.ClassroomRepository, actor ClassroomActor, classroomID primitive.ObjectID) (*models.Classroom, error) {
+	classroom, manages, err := loadClassroom(ctx, classroomRepo, actor, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	if !manages {
+		return nil, ErrNotInstructor
+	}
+	return classroom, nil
+}
+
+// loadClassroom returns the classroom if actor manages it or is one of its
+// students, and whether actor manages it. Other users get ErrClassroomNotFound
+// so that classroom IDs cannot be probed.
+func loadClassroom(ctx context.Context, classroomRepo repositories.ClassroomRepository, actor ClassroomActor, classroomID primitive.ObjectID) (*models.Classroom, bool, error) {
+	classroom, err := classroomRepo.FindByID(ctx, classroomID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, ErrClassroomNotFound
+		}
+		return nil, false, err
+	}
+	if classroom.InstructorID == actor.UserID || actor.Role == models.RoleAdmin {
+		return classroom, true, nil
+	}
+	isMember, err := classroomRepo.IsMember(ctx, classroom.ID, actor.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !isMember {
+		return nil, false, ErrClassroomNotFound
+	}
+	return classroom, false, nil
+}
+
+func (s *classroomService) newJoinCode(ctx context.Context) (string, error) {
+	for attempt := 0; attempt < classroomJoinCodeAttempts; attempt++ {
+		code, err := randomString(classroomJoinCodeAlphabet, classroomJoinCodeLength)
+		if err != nil {
+			return "", err
+		}
+		_, err = s.classroomRepo.FindByJoinCode(ctx, code)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return code, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("could not generate a unique join code")
+}
+
+// parseAssignmentChapters checks that every chapter belongs to course
+func parseAssignmentChapters(course *models.Course, chapterIDHexes []string) ([]primitive.ObjectID, error) {
+	seen := map[primitive.ObjectID]bool{}
+	chapterIDs := []primitive.ObjectID{}
+	for _, hex := range chapterIDHexes {
+		chapterID, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return nil, errors.New("invalid chapter ID format")
+		}
+		if !course.HasChapter(chapterID) {
+			return nil, errors.New("chapter " + hex + " does not belong to the course")
+		}
+		if !seen[chapterID] {
+			seen[chapterID] = true
+			chapterIDs = append(chapterIDs, chapterID)
+		}
+	}
+	return chapterIDs, nil
+}
+
+// progressTracker holds the chapter progress needed to compute the status of
+// a set of students on a set of assignments, loaded with a single query.
+type progressTracker struct {
+	// chapters lists the chapters each assignment requires
+	chapters map[primitive.ObjectID][]primitive.ObjectID
+	// statuses is keyed by user, then chapter
+	statuses map[primitive.ObjectID]map[primitive.ObjectID]*models.UserChapterStatus
+}
+
+func (s *classroomService) newProgressTracker(ctx context.Context, assignments []models.Assignment, userIDs []primitive.ObjectID) (*progressTracker, error) {
+	tracker := &progressTracker{
+		chapters: make(map[primitive.ObjectID][]primitive.ObjectID, len(assignments)),
+		statuses: make(map[primitive.ObjectID]map[primitive.ObjectID]*models.UserChapterStatus, len(userIDs)),
+	}
+
+	courses := map[primitive.ObjectID]*models.Course{}
+	courseIDs := []primitive.ObjectID{}
+	for i := range assignments {
+		assignment := &assignments[i]
+		if _, loaded := courses[assignment.CourseID]; !loaded {
+			course, err := s.courseRepo.FindByID(ctx, assignment.CourseID)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, err
+			}
+			// A deleted course leaves whole-course assignments without chapters
+			courses[assignment.CourseID] = course
+			courseIDs = append(courseIDs, assignment.CourseID)
+		}
+		if len(assignment.ChapterIDs) > 0 {
+			tracker.chapters[assignment.ID] = assignment.ChapterIDs
+			continue
+		}
+		if course := courses[assignment.CourseID]; course != nil {
+			for _, chapter := range course.OrderedChapters() {
+				tracker.chapters[assignment.ID] = append(tracker.chapters[assignment.ID], chapter.ID)
+			}
+		}
+	}
+
+	statuses, err := s.progressRepo.FindForUsers(ctx, userIDs, courseIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		byChapter, ok := tracker.statuses[status.UserID]
+		if !ok {
+			byChapter = map[primitive.ObjectID]*models.UserChapterStatus{}
+			tracker.statuses[status.UserID] = byChapter
+		}
+		byChapter[status.ChapterID] = status
+	}
+	return tracker, nil
+}
+
+// progress computes the user's status on assignment at now. Work finished
+// after the due date is late, and work still unfinished after it is missing.
+func (t *progressTracker) progress(userID primitive.ObjectID, assignment *models.Assignment, now time.Time) AssignmentProgress {
+	chapterIDs := t.chapters[assignment.ID]
+	result := AssignmentProgress{AssignmentID: assignment.ID, TotalChapters: len(chapterIDs)}
+
+	started := false
+	onTime := true
+	for _, chapterID := range chapterIDs {
+		status := t.statuses[userID][chapterID]
+		if status == nil {
+			continue
+		}
+		if len(status.Components) > 0 {
+			started = true
+		}
+		if !status.IsChapterCompleted {
+			continue
+		}
+		result.CompletedChapters++
+		// Chapters completed before completion times were recorded count as on time
+		if status.CompletedAt == nil {
+			continue
+		}
+		if status.CompletedAt.After(assignment.DueAt) {
+			onTime = false
+		}
+		if result.CompletedAt == nil || status.CompletedAt.After(*result.CompletedAt) {
+			completedAt := *status.CompletedAt
+			result.CompletedAt = &completedAt
+		}
+	}
+
+	switch {
+	case result.TotalChapters > 0 && result.CompletedChapters == result.TotalChapters:
+		if onTime {
+			result.Status = models.AssignmentCompleted
+		} else {
+			result.Status = models.AssignmentLate
+		}
+	case now.After(assignment.DueAt):
+		result.Status = models.AssignmentMissing
+	case started:
+		result.Status = models.AssignmentInProgress
+	default:
+		result.Status = models.AssignmentNotStarted
+	}
+	if result.Status != models.AssignmentCompleted && result.Status != models.AssignmentLate {
+		result.CompletedAt = nil
+	}
+	return result
+}
+
+func toClassroomResponse(classroom *models.Classroom, manages bool) *ClassroomResponse {
+	response := &ClassroomResponse{
+		ID:           classroom.ID,
+		Name:         classroom.Name,
+		InstructorID: classroom.InstructorID,
+		CanManage:    manages,
+		CreatedAt:    classroom.CreatedAt,
+	}
+	if manages {
+		response.JoinCode = classroom.JoinCode
+	}
+	return response
+}
+
+func toAssignmentResponse(assignment *models.Assignment) AssignmentResponse {
+	return AssignmentResponse{
+		ID:          assignment.ID,
+		ClassroomID: assignment.ClassroomID,
+		CourseID:    assignment.CourseID,
+		ChapterIDs:  assignment.ChapterIDs,
+		Title:       assignment.Title,
+		DueAt:       assignment.DueAt,
+		CreatedAt:   assignment.CreatedAt,
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrCohortNotFound = errors.New("cohort not found")
+	ErrInvalidCohort  = errors.New("invalid cohort")
+)
+
+// CohortService runs the cohorts of courses. Instructors schedule cohorts
+// and enrolled learners join one, which releases the chapters scheduled
+// relative to the cohort start on the cohort's dates.
+type CohortService interface {
+	CreateCohort(ctx context.Context, authorID, courseID primitive.ObjectID, input CohortInput) (*CohortResponse, error)
+	// ListCohorts lists a page of the course's cohorts, the first to start first
+	ListCohorts(ctx context.Context, courseID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[CohortResponse], error)
+	UpdateCohort(ctx context.Context, courseID, cohortID primitive.ObjectID, input CohortInput) (*CohortResponse, error)
+	// DeleteCohort deletes the cohort; its learners go on by their enrollment dates
+	DeleteCohort(ctx context.Context, courseID, cohortID primitive.ObjectID) error
+	// JoinCohort moves the enrolled user into the cohort, out of any other of the course
+	JoinCohort(ctx context.Context, userID, courseID primitive.ObjectID, input JoinCohortInput) (*CohortSummary, error)
+	LeaveCohort(ctx context.Context, userID, courseID primitive.ObjectID) error
+}
+
+type CohortInput struct {
+	Name     string    `json:"name" binding:"required,max=100"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+}
+
+type JoinCohortInput struct {
+	CohortID string `json:"cohort_id" binding:"required"`
+}
+
+// CohortSummary is the cohort a learner takes a course with.
+type CohortSummary struct {
+	ID       primitive.ObjectID `json:"id"`
+	Name     string             `json:"name"`
+	StartsAt time.Time          `json:"starts_at"`
+}
+
+type CohortResponse struct {
+	CohortSummary
+	CourseID primitive.ObjectID `json:"course_id"`
+	// MemberCount counts the enrolled learners in the cohort
+	MemberCount int `json:"member_count"`
+}
+
+func cohortSummary(cohort *models.Cohort) *CohortSummary {
+	if cohort == nil {
+		return nil
+	}
+	return &CohortSummary{ID: cohort.ID, Name: cohort.Name, StartsAt: cohort.StartsAt}
+}
+
+type cohortService struct {
+	cohortRepo     repositories.CohortRepository
+	courseRepo     repositories.CourseRepository
+	enrollmentRepo repositories.EnrollmentRepository
+	clock          pkg.Clock
+}
+
+func NewCohortService(cohortRepo repositories.CohortRepository, courseRepo repositories.CourseRepository, enrollmentRepo repositories.EnrollmentRepository, clock pkg.Clock) CohortService {
+	return &cohortService{cohortRepo, courseRepo, enrollmentRepo, clock}
+}
+
+func (s *cohortService) CreateCohort(ctx context.Context, authorID, courseID primitive.ObjectID, input CohortInput) (*CohortResponse, error) {
+	if _, err := s.loadCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	cohort := &models.Cohort{CourseID: courseID, CreatedBy: authorID, CreatedAt: now}
+	if err := applyCohortInput(cohort, input, now); err != nil {
+		return nil, err
+	}
+	if err := s.cohortRepo.Create(ctx, cohort); err != nil {
+		return nil, err
+	}
+	return &CohortResponse{CohortSummary: *cohortSummary(cohort), CourseID: courseID}, nil
+}
+
+type cohortCursor struct {
+	StartsAt time.Time          `json:"starts_at"`
+	ID       primitive.ObjectID `json:"id"`
+}
+
+func (s *cohortService) ListCohorts(ctx context.Context, courseID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[CohortResponse], error) {
+	var after *models.Cohort
+	if page.Cursor != "" {
+		var position cohortCursor
+		if err := pkg.DecodeCursor(page.Cursor, &position); err != nil {
+			return nil, err
+		}
+		after = &models.Cohort{ID: position.ID, StartsAt: position.StartsAt}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	if _, err := s.loadCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	found, err := s.cohortRepo.FindPageByCourse(ctx, courseID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.enrollmentRepo.CountActiveByCohort(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	stored := pkg.NewPage(found, limit, func(cohort models.Cohort) any {
+		return cohortCursor{StartsAt: cohort.StartsAt, ID: cohort.ID}
+	})
+	cohorts := stored.Items
+	result := pkg.Page[CohortResponse]{Items: make([]CohortResponse, 0, len(cohorts)), NextCursor: stored.NextCursor}
+	for i := range cohorts {
+		result.Items = append(result.Items, CohortResponse{
+			CohortSummary: *cohortSummary(&cohorts[i]),
+			CourseID:      courseID,
+			MemberCount:   counts[cohorts[i].ID],
+		})
+	}
+	return &result, nil
+}
+
+// UpdateCohort renames or reschedules the cohort. Moving the start moves the
+// release of its learners' chapters along with it.
+func (s *cohortService) UpdateCohort(ctx context.Context, courseID, cohortID primitive.ObjectID, input CohortInput) (*CohortResponse, error) {
+	cohort, err := s.loadCohort(ctx, courseID, cohortID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCohortInput(cohort, input, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.cohortRepo.Update(ctx, cohort); err != nil {
+		return nil, err
+	}
+	counts, err := s.enrollmentRepo.CountActiveByCohort(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return &CohortResponse{CohortSummary: *cohortSummary(cohort), CourseID: courseID, MemberCount: counts[cohort.ID]}, nil
+}
+
+func applyCohortInput(cohort *models.Cohort, input CohortInput, now time.Time) error {
+	cohort.Name = strings.TrimSpace(input.Name)
+	if cohort.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCohort)
+	}
+	cohort.StartsAt = input.StartsAt.UTC()
+	cohort.UpdatedAt = now
+	return nil
+}
+
+func (s *cohortService) DeleteCohort(ctx context.Context, courseID, cohortID primitive.ObjectID) error {
+	if _, err := s.loadCohort(ctx, courseID, cohortID); err != nil {
+		return err
+	}
+	deleted, err := s.cohortRepo.Delete(ctx, cohortID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCohortNotFound
+	}
+	return s.enrollmentRepo.ClearCohort(ctx, cohortID)
+}
+
+func (s *cohortService) JoinCohort(ctx context.Context, userID, courseID primitive.ObjectID, input JoinCohortInput) (*CohortSummary, error) {
+	cohortID, err := primitive.ObjectIDFromHex(input.CohortID)
+	if err != nil {
+		return nil, ErrCohortNotFound
+	}
+	cohort, err := s.loadCohort(ctx, courseID, cohortID)
+	if err != nil {
+		return nil, err
+	}
+	joined, err := s.enrollmentRepo.SetCohort(ctx, userID, courseID, cohort.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !joined {
+		return nil, ErrNotEnrolled
+	}
+	return cohortSummary(cohort), nil
+}
+
+func (s *cohortService) LeaveCohort(ctx context.Context, userID, courseID primitive.ObjectID) error {
+	enrollment, err := s.enrollmentRepo.Find(ctx, userID, courseID)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil || !enrollment.IsActive() {
+		return ErrNotEnrolled
+	}
+	if enrollment.CohortID.IsZero() {
+		return ErrCohortNotFound
+	}
+	_, err = s.enrollmentRepo.SetCohort(ctx, userID, courseID, primitive.NilObjectID)
+	return err
+}
+
+func (s *cohortService) loadCourse(ctx context.Context, courseID primitive.ObjectID) (*models.Course, error) {
+	course, err := s.courseRepo.FindByID(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCourseNotFound
+		}
+		return nil, err
+	}
+	return course, nil
+}
+
+// loadCohort loads the cohort if it belongs to the course
+func (s *cohortService) loadCohort(ctx context.Context, courseID, cohortID primitive.ObjectID) (*models.Cohort, error) {
+	cohort, err := s.cohortRepo.FindByID(ctx, cohortID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCohortNotFound
+		}
+		return nil, err
+	}
+	if cohort.CourseID != courseID {
+		return nil, ErrCohortNotFound
+	}
+	return cohort, nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/xapi"
+	"strings"
+)
+
+// ComponentType defines how one kind of chapter component is completed.
+// New kinds of components are added by implementing it and registering the
+// implementation with RegisterComponentType.
+type ComponentType interface {
+	// DefaultXP is awarded for components that don't set their own XP
+	DefaultXP() int
+	// Validate checks that an author set up the component completely
+	Validate(component models.ChapterComponent) error
+	// Evaluate applies the component's completion rule to a learner's report.
+	// It returns an error for reports the component cannot accept at all.
+	Evaluate(component models.ChapterComponent, report MarkComponentInput) (bool, error)
+	// ActivityType is the xAPI activity type of the component in learning records
+	ActivityType() string
+}
+
+var componentTypes = map[string]ComponentType{
+	models.ComponentVideo:      videoComponent{},
+	models.ComponentQuiz:       quizComponent{},
+	models.ComponentReading:    readingComponent{},
+	models.ComponentSlides:     openedComponent{activityType: xapi.ActivitySlideDeck},
+	models.ComponentAssignment: assignmentComponent{},
+	models.ComponentLink:       openedComponent{activityType: xapi.ActivityLink},
+	models.ComponentSCORM:      scormComponent{},
+}
+
+// RegisterComponentType adds or replaces the component type called name.
+// It is meant to be called during start-up, before requests are served.
+func RegisterComponentType(name string, componentType ComponentType) {
+	componentTypes[name] = componentType
+}
+
+func lookupComponentType(name string) (ComponentType, error) {
+	componentType, ok := componentTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown component type %q", name)
+	}
+	return componentType, nil
+}
+
+// componentXP is the XP a component awards on completion
+func componentXP(component models.ChapterComponent) int {
+	if component.XP > 0 {
+		return component.XP
+	}
+	if componentType, err := lookupComponentType(component.Type); err == nil {
+		return componentType.DefaultXP()
+	}
+	return 0
+}
+
+// videoComponent is complete once watched, optionally up to MinWatchPercent.
+type videoComponent struct{}
+
+func (videoComponent) DefaultXP() int { return XP_PER_COMPONENT }
+
+func (videoComponent) ActivityType() string { return xapi.ActivityVideo }
+
+func (videoComponent) Validate(component models.ChapterComponent) error {
+	if component.URL == "" {
+		return errors.New("a video needs a url")
+	}
+	if component.MinWatchPercent < 0 || component.MinWatchPercent > 100 {
+		return errors.New("min_watch_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+func (videoComponent) Evaluate(component models.ChapterComponent, report MarkComponentInput) (bool, error) {
+	if component.MinWatchPercent == 0 {
+		return true, nil
+	}
+	if report.WatchedPercent == nil {
+		return false, errors.New("watched_percent is required for this video")
+	}
+	return *report.WatchedPercent >= component.MinWatchPercent, nil
+}
+
+// quizComponent is complete once taken, or once PassingScore is reached if set.
+// Only QuizService grades quizzes, so those with a passing score need a quiz
+// stored on the server.
+type quizComponent struct{}
+
+func (quizComponent) DefaultXP() int { return XP_PER_COMPONENT }
+
+func (quizComponent) ActivityType() string { return xapi.ActivityAssessment }
+
+func (quizComponent) Validate(component models.ChapterComponent) error {
+	if component.QuizID == "" {
+		return errors.New("a quiz needs a quiz_id")
+	}
+	if component.PassingScore < 0 || component.PassingScore > 100 {
+		return errors.New("passing_score must be between 0 and 100")
+	}
+	return nil
+}
+
+func (quizComponent) Evaluate(component models.ChapterComponent, report MarkComponentInput) (bool, error) {
+	if component.PassingScore == 0 {
+		return true, nil
+	}
+	if report.Score == nil {
+		return false, errors.New("this quiz is completed by submitting its answers to be graded")
+	}
+	return *report.Score >= component.PassingScore, nil
+}
+
+// readingComponent is complete once read, optionally for MinReadSeconds.
+type readingComponent struct{}
+
+func (readingComponent) DefaultXP() int { return XP_PER_COMPONENT }
+
+func (readingComponent) ActivityType() string { return xapi.ActivityDocument }
+
+func (readingComponent) Validate(component models.ChapterComponent) error {
+	if component.URL == "" {
+		return errors.New("a reading needs a url")
+	}
+	if component.MinReadSeconds < 0 {
+		return errors.New("min_read_seconds cannot be negative")
+	}
+	return nil
+}
+
+func (readingComponent) Evaluate(component models.ChapterComponent, report MarkComponentInput) (bool, error) {
+	if component.MinReadSeconds == 0 {
+		return true, nil
+	}
+	if report.SecondsSpent == nil {
+		return false, errors.New("seconds_spent is required for this reading")
+	}
+	return *report.SecondsSpent >= component.MinReadSeconds, nil
+}
+
+// assignmentComponent is complete once something was handed in.
+type assignmentComponent struct{}
+
+func (assignmentComponent) DefaultXP() int { return XP_PER_COMPONENT }
+
+func (assignmentComponent) ActivityType() string { return xapi.ActivityPerformance }
+
+func (assignmentComponent) Validate(models.ChapterComponent) error {
+	return nil
+}
+
+func (assignmentComponent) Evaluate(component models.ChapterComponent, report MarkComponentInput) (bool, error) {
+	if strings.TrimSpace(report.Submission) == "" {
+		return false, errors.New("a submission is required for this assignment")
+	}
+	return true, nil
+}
+
+// openedComponent is complete as soon as the learner opened it, like slides or links.
+type openedComponent struct {
+	activityType string
+}
+
+func (openedComponent) DefaultXP() int { return XP_PER_COMPONENT }
+
+func (c openedComponent) ActivityType() string { return c.activityType }
+
+func (openedComponent) Validate(component models.ChapterComponent) error {
+	if component.URL == "" {
+		return errors.New("this component needs a url")
+	}
+	return nil
+}
+
+func (openedComponent) Evaluate(models.ChapterComponent, MarkComponentInput) (bool, error) {
+	return true, nil
+}
+
+// scormComponent launches a SCO of an uploaded SCORM package. It is complete
+// once the SCO reports completion through the SCORM runtime, see SCORMService.
+type scormComponent struct{}
+
+func (scormComponent) DefaultXP() int { return XP_PER_COMPONENT }
+
+func (scormComponent) ActivityType() string { return xapi.ActivityLesson }
+
+func (scormComponent) Validate(component models.ChapterComponent) error {
+	if component.URL == "" || component.PackageID.IsZero() || component.SCOID == "" {
+		return errors.New("a SCORM component needs a url, package_id and sco_id")
+	}
+	if component.PassingScore < 0 || component.PassingScore > 100 {
+		return errors.New("passing_score must be between 0 and 100")
+	}
+	return nil
+}
+
+func (scormComponent) Evaluate(component models.ChapterComponent, report MarkComponentInput) (bool, error) {
+	if report.RuntimeCompleted == nil {
+		return false, errors.New("SCORM components are completed through the SCORM runtime")
+	}
+	return *report.RuntimeCompleted, nil
+}
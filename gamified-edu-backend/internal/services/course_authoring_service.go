@@ -0,0 +1,758 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoDraft              = errors.New("course has no draft")
+	ErrDraftOutdated        = errors.New("the draft was started from an older version than the one published now")
+	ErrNoChanges            = errors.New("the draft has no changes to publish")
+	ErrPublishConflict      = errors.New("the course was published by someone else in the meantime, reload and try again")
+	ErrVersionNotFound      = errors.New("course version not found")
+	ErrAlreadyPublished     = errors.New("this version is the one published now")
+	ErrInvalidCourseContent = errors.New("invalid course content")
+	ErrCourseSlugTaken      = errors.New("another course already uses this slug")
+)
+
+// componentKeyPattern keeps component keys usable in progress URLs
+var componentKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// CourseAuthoringService edits courses without disturbing learners. Edits go
+// into a draft; publishing snapshots the draft as a new version and makes it
+// live. See ProgressService.ReconcileCourse for what happens to progress.
+type CourseAuthoringService interface {
+	CreateCourse(ctx context.Context, authorID primitive.ObjectID, input CreateCourseInput) (*DraftResponse, error)
+	GetDraft(ctx context.Context, courseID primitive.ObjectID) (*DraftResponse, error)
+	SaveDraft(ctx context.Context, authorID, courseID primitive.ObjectID, input CourseContentBody) (*DraftResponse, error)
+	DiscardDraft(ctx context.Context, courseID primitive.ObjectID) error
+	Publish(ctx context.Context, authorID, courseID primitive.ObjectID, input PublishInput) (*CourseVersionResponse, error)
+	ListVersions(ctx context.Context, courseID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[CourseVersionSummary], error)
+	GetVersion(ctx context.Context, courseID primitive.ObjectID, version int) (*CourseVersionResponse, error)
+	DiffVersions(ctx context.Context, courseID primitive.ObjectID, from, to int) (*VersionDiffResponse, error)
+	Rollback(ctx context.Context, authorID, courseID primitive.ObjectID, version int, input PublishInput) (*CourseVersionResponse, error)
+}
+
+// CourseContentBody is the editable content of a course. GetDraft returns it
+// in the shape SaveDraft accepts. Modules and chapters keep their IDs across
+// versions, which is what carries learners' progress over; leave the ID empty
+// for new ones. Order follows the lists.
+type CourseContentBody struct {
+	Title       string       `json:"title" binding:"required,max=200"`
+	Description string       `json:"description" binding:"max=5000"`
+	Modules     []ModuleBody `json:"modules" binding:"dive"`
+}
+
+// CreateCourseInput is the first draft of a new course. The slug is derived
+// from the title when left empty and cannot change later.
+type CreateCourseInput struct {
+	Slug string `json:"slug"`
+	CourseContentBody
+}
+
+type ModuleBody struct {
+	ID       string        `json:"id"`
+	Slug     string        `json:"slug"`
+	Title    string        `json:"title" binding:"required,max=200"`
+	Chapters []ChapterBody `json:"chapters" binding:"dive"`
+}
+
+type ChapterBody struct {
+	ID           string          `json:"id"`
+	Slug         string          `json:"slug"`
+	Title        string          `json:"title" binding:"required,max=200"`
+	DurationMins int             `json:"duration_mins" binding:"min=0"`
+	Components   []ComponentBody `json:"components" binding:"dive"`
+	// Release schedules the chapter; without one it is released right away
+	Release *ChapterReleaseBody `json:"release,omitempty"`
+}
+
+// ChapterReleaseBody releases a chapter on a date, or offset_days after the
+// learner enrolled or their cohort started, see models.ChapterRelease.
+type ChapterReleaseBody struct {
+	Rule       string     `json:"rule" binding:"required,oneof=date enrollment cohort"`
+	At         *time.Time `json:"at,omitempty"`
+	OffsetDays int        `json:"offset_days,omitempty" binding:"min=0,max=3650"`
+}
+
+type ComponentBody struct {
+	Key             string `json:"key" binding:"required"`
+	Type            string `json:"type" binding:"required"`
+	Title           string `json:"title" binding:"max=200"`
+	URL             string `json:"url" binding:"max=2000"`
+	QuizID          string `json:"quiz_id" binding:"max=200"`
+	XP              int    `json:"xp" binding:"min=0"`
+	Optional        bool   `json:"optional"`
+	PassingScore    int    `json:"passing_score"`
+	MinWatchPercent int    `json:"min_watch_percent"`
+	MinReadSeconds  int    `json:"min_read_seconds"`
+	PackageID       string `json:"package_id" binding:"max=24"`
+	SCOID           string `json:"sco_id" binding:"max=200"`
+}
+
+type PublishInput struct {
+	Note string `json:"note" binding:"max=500"`
+	// Force publishes a draft even though a newer version was published after it was started
+	Force bool `json:"force"`
+}
+
+type DraftResponse struct {
+	CourseID         primitive.ObjectID `json:"course_id"`
+	CourseSlug       string             `json:"course_slug"`
+	PublishedVersion int                `json:"published_version"`
+	// HasDraft is false when Content is a copy of the published content
+	HasDraft       bool              `json:"has_draft"`
+	BasedOnVersion int               `json:"based_on_version"`
+	UpdatedAt      *time.Time        `json:"updated_at,omitempty"`
+	Content        CourseContentBody `json:"content"`
+	// Changes compares the draft with the published version
+	Changes *CourseDiff `json:"changes"`
+}
+
+type CourseVersionSummary struct {
+	Version      int                `json:"version"`
+	Title        string             `json:"title"`
+	ChapterCount int                `json:"chapter_count"`
+	PublishedAt  time.Time          `json:"published_at"`
+	PublishedBy  primitive.ObjectID `json:"published_by,omitempty"`
+	Note         string             `json:"note,omitempty"`
+	RestoredFrom int                `json:"restored_from,omitempty"`
+	// IsPublished marks the version learners currently see
+	IsPublished bool `json:"is_published"`
+}
+
+type CourseVersionResponse struct {
+	CourseVersionSummary
+	Content CourseContentBody `json:"content"`
+}
+
+type VersionDiffResponse struct {
+	// From is zero when comparing against an empty course
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Changes *CourseDiff `json:"changes"`
+}
+
+type courseAuthoringService struct {
+	courseRepo      repositories.CourseRepository
+	versionRepo     repositories.CourseVersionRepository
+	progressService ProgressService
+	indexer         CourseIndexer
+}
+
+func NewCourseAuthoringService(courseRepo repositories.CourseRepository, versionRepo repositories.CourseVersionRepository, progressService ProgressService, indexer CourseIndexer) CourseAuthoringService {
+	return &courseAuthoringService{courseRepo: courseRepo, versionRepo: versionRepo, progressService: progressService, indexer: indexer}
+}
+
+// CreateCourse starts a course as a draft. Learners see it once it is published.
+func (s *courseAuthoringService) CreateCourse(ctx context.Context, authorID primitive.ObjectID, input CreateCourseInput) (*DraftResponse, error) {
+	content, err := parseCourseContent(input.CourseContentBody, nil)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := s.courseSlug(ctx, input.Slug, content.Title)
+	if err != nil {
+		return nil, err
+	}
+	course := &models.Course{
+		Slug:        slug,
+		Title:       content.Title,
+		Description: content.Description,
+		Modules:     []models.Module{},
+		AuthorIDs:   courseAuthors(authorID),
+		Draft: &models.CourseDraft{
+			CourseContent: content,
+			UpdatedAt:     time.Now(),
+			UpdatedBy:     authorID,
+		},
+	}
+	if err := s.courseRepo.Create(ctx, course); err != nil {
+		return nil, err
+	}
+	return draftResponse(course), nil
+}
+
+// courseAuthors makes whoever creates a course its author. Imports run from
+// the command line may have no one to credit.
+func courseAuthors(authorID primitive.ObjectID) []primitive.ObjectID {
+	if authorID.IsZero() {
+		return nil
+	}
+	return []primitive.ObjectID{authorID}
+}
+
+func (s *courseAuthoringService) GetDraft(ctx context.Context, courseID primitive.ObjectID) (*DraftResponse, error) {
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return draftResponse(course), nil
+}
+
+// SaveDraft replaces the draft, starting one from the published version if there is none
+func (s *courseAuthoringService) SaveDraft(ctx context.Context, authorID, courseID primitive.ObjectID, input CourseContentBody) (*DraftResponse, error) {
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	known, err := s.knownIDs(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+	content, err := parseCourseContent(input, known)
+	if err != nil {
+		return nil, err
+	}
+
+	basedOn := course.PublishedVersion
+	if course.Draft != nil {
+		basedOn = course.Draft.BasedOnVersion
+	}
+	course.Draft = &models.CourseDraft{
+		CourseContent:  content,
+		BasedOnVersion: basedOn,
+		UpdatedAt:      time.Now(),
+		UpdatedBy:      authorID,
+	}
+	if err := s.courseRepo.SaveDraft(ctx, course.ID, course.Draft); err != nil {
+		return nil, err
+	}
+	return draftResponse(course), nil
+}
+
+func (s *courseAuthoringService) DiscardDraft(ctx context.Context, courseID primitive.ObjectID) error {
+	if _, err := s.loadCourse(ctx, courseID); err != nil {
+		return err
+	}
+	discarded, err := s.courseRepo.DiscardDraft(ctx, courseID)
+	if err != nil {
+		return err
+	}
+	if !discarded {
+		return ErrNoDraft
+	}
+	return nil
+}
+
+// Publish makes the draft the next version of the course
+func (s *courseAuthoringService) Publish(ctx context.Context, authorID, courseID primitive.ObjectID, input PublishInput) (*CourseVersionResponse, error) {
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if course.Draft == nil {
+		return nil, ErrNoDraft
+	}
+	if course.Draft.BasedOnVersion != course.PublishedVersion && !input.Force {
+		return nil, ErrDraftOutdated
+	}
+	content := course.Draft.CourseContent
+	if err := validateForPublish(content); err != nil {
+		return nil, err
+	}
+	if course.IsPublished() && !diffCourseContent(course.Content(), content).HasChanges() {
+		return nil, ErrNoChanges
+	}
+	return s.publish(ctx, course, content, authorID, strings.TrimSpace(input.Note), 0)
+}
+
+// versionCursor is the position after the last version of a page
+type versionCursor struct {
+	Version int `json:"version"`
+}
+
+// ListVersions lists a page of the course's versions, newest first
+func (s *courseAuthoringService) ListVersions(ctx context.Context, courseID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[CourseVersionSummary], error) {
+	var after versionCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.versionRepo.FindPageByCourse(ctx, course.ID, after.Version, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]CourseVersionSummary, 0, len(versions))
+	for i := range versions {
+		summaries = append(summaries, versionSummary(&versions[i], course.PublishedVersion))
+	}
+	result := pkg.NewPage(summaries, limit, func(version CourseVersionSummary) any { return versionCursor{Version: version.Version} })
+	return &result, nil
+}
+
+func (s *courseAuthoringService) GetVersion(ctx context.Context, courseID primitive.ObjectID, version int) (*CourseVersionResponse, error) {
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.loadVersion(ctx, course.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	return versionResponse(found, course.PublishedVersion), nil
+}
+
+// DiffVersions lists what changed from one version to another. Version 0 is
+// an empty course, so diffing from it lists everything in the other version.
+func (s *courseAuthoringService) DiffVersions(ctx context.Context, courseID primitive.ObjectID, from, to int) (*VersionDiffResponse, error) {
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[int]models.CourseContent, 2)
+	for _, version := range []int{from, to} {
+		if version == 0 {
+			contents[version] = models.CourseContent{}
+			continue
+		}
+		found, err := s.loadVersion(ctx, course.ID, version)
+		if err != nil {
+			return nil, err
+		}
+		contents[version] = found.CourseContent
+	}
+	return &VersionDiffResponse{From: from, To: to, Changes: diffCourseContent(contents[from], contents[to])}, nil
+}
+
+// Rollback publishes the content of an earlier version again, as a new
+// version. A draft in progress is left alone.
+func (s *courseAuthoringService) Rollback(ctx context.Context, authorID, courseID primitive.ObjectID, version int, input PublishInput) (*CourseVersionResponse, error) {
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.loadVersion(ctx, course.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if found.Version == course.PublishedVersion {
+		return nil, ErrAlreadyPublished
+	}
+	note := strings.TrimSpace(input.Note)
+	if note == "" {
+		note = fmt.Sprintf("Rolled back to version %d", version)
+	}
+	return s.publish(ctx, course, found.CourseContent, authorID, note, found.Version)
+}
+
+func (s *courseAuthoringService) publish(ctx context.Context, course *models.Course, content models.CourseContent, authorID primitive.ObjectID, note string, restoredFrom int) (*CourseVersionResponse, error) {
+	// Versions from before slugs existed get the slugs their chapters have now
+	current := course.Content()
+	content.AssignSlugs(current.Slugs())
+	clearDraft := restoredFrom == 0
+	published, err := s.courseRepo.Publish(ctx, course.ID, course.PublishedVersion, content, clearDraft)
+	if err != nil {
+		return nil, err
+	}
+	if !published {
+		return nil, ErrPublishConflict
+	}
+
+	version := &models.CourseVersion{
+		CourseID:      course.ID,
+		Version:       course.PublishedVersion + 1,
+		CourseContent: content,
+		PublishedAt:   time.Now(),
+		PublishedBy:   authorID,
+		Note:          note,
+		RestoredFrom:  restoredFrom,
+	}
+	if err := s.versionRepo.Create(ctx, version); err != nil {
+		return nil, err
+	}
+
+	course.Title, course.Description, course.Modules = content.Title, content.Description, content.Modules
+	course.PublishedVersion = version.Version
+	if err := s.progressService.ReconcileCourse(ctx, course); err != nil {
+		// The new version is live either way; learners' next progress write catches up
+		log.Printf("Could not reconcile progress for course %s version %d: %v", course.ID.Hex(), version.Version, err)
+	}
+	if err := s.indexer.IndexCourse(ctx, course); err != nil {
+		log.Printf("Could not update the search index for course %s: %v", course.ID.Hex(), err)
+	}
+	return versionResponse(version, version.Version), nil
+}
+
+func (s *courseAuthoringService) loadCourse(ctx context.Context, courseID primitive.ObjectID) (*models.Course, error) {
+	course, err := s.courseRepo.FindByID(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCourseNotFound
+		}
+		return nil, err
+	}
+	return course, nil
+}
+
+func (s *courseAuthoringService) loadVersion(ctx context.Context, courseID primitive.ObjectID, version int) (*models.CourseVersion, error) {
+	found, err := s.versionRepo.FindByVersion(ctx, courseID, version)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrVersionNotFound
+		}
+		return nil, err
+	}
+	return found, nil
+}
+
+// courseSlug checks the slug asked for, or derives one from title that no
+// course of the organization uses yet
+func (s *courseAuthoringService) courseSlug(ctx context.Context, requested, title string) (string, error) {
+	if slug := strings.ToLower(strings.TrimSpace(requested)); slug != "" {
+		if !slugPattern.MatchString(slug) {
+			return "", fmt.Errorf("%w: slug %q may only contain lowercase letters, digits and single dashes", ErrInvalidCourseContent, requested)
+		}
+		_, err := s.courseRepo.FindBySlug(ctx, slug)
+		if err == nil {
+			return "", ErrCourseSlugTaken
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return "", err
+		}
+		return slug, nil
+	}
+	courses, err := s.courseRepo.FindAll(ctx)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(courses))
+	for _, course := range courses {
+		taken[course.Slug] = true
+	}
+	return models.UniqueSlug(models.Slugify(title, "course"), taken), nil
+}
+
+// knownIDs collects the module and chapter IDs the course ever had, with the
+// slug each had most recently. Drafts may only reuse these IDs, so a chapter
+// removed earlier can come back with its progress.
+func (s *courseAuthoringService) knownIDs(ctx context.Context, course *models.Course) (map[primitive.ObjectID]string, error) {
+	versions, err := s.versionRepo.FindByCourse(ctx, course.ID)
+	if err != nil {
+		return nil, err
+	}
+	known := map[primitive.ObjectID]string{}
+	add := func(modules []models.Module) {
+		for _, module := range modules {
+			known[module.ID] = module.Slug
+			for _, chapter := range module.Chapters {
+				known[chapter.ID] = chapter.Slug
+			}
+		}
+	}
+	// Oldest first, so the most recent slug wins
+	for i := len(versions) - 1; i >= 0; i-- {
+		add(versions[i].Modules)
+	}
+	add(course.Modules)
+	if course.Draft != nil {
+		add(course.Draft.Modules)
+	}
+	return known, nil
+}
+
+// parseCourseContent checks the structure of submitted content and assigns
+// IDs to new modules and chapters. Existing IDs must be in known, which maps
+// them to their previous slug. Completeness is only required for publishing,
+// see validateForPublish.
+func parseCourseContent(input CourseContentBody, known map[primitive.ObjectID]string) (models.CourseContent, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidCourseContent, fmt.Sprintf(format, args...))
+	}
+	parseID := func(raw string, seen map[primitive.ObjectID]bool) (primitive.ObjectID, error) {
+		if raw == "" {
+			return primitive.NewObjectID(), nil
+		}
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return primitive.NilObjectID, invalid("invalid id %q", raw)
+		}
+		if _, ok := known[id]; !ok {
+			return primitive.NilObjectID, invalid("id %s is not part of this course", raw)
+		}
+		if seen[id] {
+			return primitive.NilObjectID, invalid("id %s is used twice", raw)
+		}
+		seen[id] = true
+		return id, nil
+	}
+
+	content := models.CourseContent{
+		Title:       strings.TrimSpace(input.Title),
+		Description: strings.TrimSpace(input.Description),
+		Modules:     make([]models.Module, 0, len(input.Modules)),
+	}
+	if content.Title == "" {
+		return content, invalid("title is required")
+	}
+	parseSlug := func(raw string, seen map[string]bool) (string, error) {
+		slug := strings.ToLower(strings.TrimSpace(raw))
+		if slug == "" {
+			return "", nil
+		}
+		if !slugPattern.MatchString(slug) {
+			return "", invalid("slug %q may only contain lowercase letters, digits and single dashes", raw)
+		}
+		if seen[slug] {
+			return "", invalid("slug %q is used twice", slug)
+		}
+		seen[slug] = true
+		return slug, nil
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	moduleSlugs, chapterSlugs := map[string]bool{}, map[string]bool{}
+	chapterNumber := 0
+	for i, moduleInput := range input.Modules {
+		id, err := parseID(moduleInput.ID, seen)
+		if err != nil {
+			return content, err
+		}
+		slug, err := parseSlug(moduleInput.Slug, moduleSlugs)
+		if err != nil {
+			return content, err
+		}
+		module := models.Module{ID: id, Slug: slug, Title: strings.TrimSpace(moduleInput.Title), Position: i + 1, Chapters: []models.Chapter{}}
+		if module.Title == "" {
+			return content, invalid("module %d needs a title", i+1)
+		}
+		for _, chapterInput := range moduleInput.Chapters {
+			id, err := parseID(chapterInput.ID, seen)
+			if err != nil {
+				return content, err
+			}
+			slug, err := parseSlug(chapterInput.Slug, chapterSlugs)
+			if err != nil {
+				return content, err
+			}
+			// Chapters are numbered through the whole course
+			chapterNumber++
+			chapter := models.Chapter{
+				ID:            id,
+				Slug:          slug,
+				Title:         strings.TrimSpace(chapterInput.Title),
+				ChapterNumber: chapterNumber,
+				DurationMins:  chapterInput.DurationMins,
+				Components:    make([]models.ChapterComponent, 0, len(chapterInput.Components)),
+			}
+			if chapter.Title == "" {
+				return content, invalid("chapter %d needs a title", chapterNumber)
+			}
+			if chapterInput.Release != nil {
+				release, err := parseRelease(*chapterInput.Release)
+				if err != nil {
+					return content, invalid("chapter %q: %v", chapter.Title, err)
+				}
+				chapter.Release = release
+			}
+			keys := map[string]bool{}
+			for _, componentInput := range chapterInput.Components {
+				component := models.ChapterComponent{
+					Key:             strings.TrimSpace(componentInput.Key),
+					Type:            strings.TrimSpace(componentInput.Type),
+					Title:           strings.TrimSpace(componentInput.Title),
+					URL:             strings.TrimSpace(componentInput.URL),
+					QuizID:          strings.TrimSpace(componentInput.QuizID),
+					XP:              componentInput.XP,
+					Optional:        componentInput.Optional,
+					PassingScore:    componentInput.PassingScore,
+					MinWatchPercent: componentInput.MinWatchPercent,
+					MinReadSeconds:  componentInput.MinReadSeconds,
+					SCOID:           strings.TrimSpace(componentInput.SCOID),
+				}
+				if componentInput.PackageID != "" {
+					packageID, err := primitive.ObjectIDFromHex(componentInput.PackageID)
+					if err != nil {
+						return content, invalid("chapter %q: component %q has an invalid package_id", chapter.Title, component.Key)
+					}
+					component.PackageID = packageID
+				}
+				if !componentKeyPattern.MatchString(component.Key) {
+					return content, invalid("chapter %q: component key %q must be lowercase letters, digits, - or _", chapter.Title, component.Key)
+				}
+				if keys[component.Key] {
+					return content, invalid("chapter %q: component key %q is used twice", chapter.Title, component.Key)
+				}
+				keys[component.Key] = true
+				componentType, err := lookupComponentType(component.Type)
+				if err != nil {
+					return content, invalid("chapter %q: %v", chapter.Title, err)
+				}
+				if err := componentType.Validate(component); err != nil {
+					return content, invalid("chapter %q, component %q: %v", chapter.Title, component.Key, err)
+				}
+				chapter.Components = append(chapter.Components, component)
+			}
+			module.Chapters = append(module.Chapters, chapter)
+		}
+		content.Modules = append(content.Modules, module)
+	}
+	content.AssignSlugs(known)
+	return content, nil
+}
+
+// parseRelease checks that the release has what its rule needs, and nothing else
+func parseRelease(body ChapterReleaseBody) (*models.ChapterRelease, error) {
+	release := &models.ChapterRelease{Rule: body.Rule}
+	switch body.Rule {
+	case models.ReleaseOnDate:
+		if body.At == nil {
+			return nil, errors.New("a release on a date needs at")
+		}
+		if body.OffsetDays != 0 {
+			return nil, errors.New("a release on a date takes no offset_days")
+		}
+		at := body.At.UTC()
+		release.At = &at
+	case models.ReleaseAfterEnrollment, models.ReleaseAfterCohortStart:
+		if body.At != nil {
+			return nil, fmt.Errorf("a release after %s takes offset_days, not at", body.Rule)
+		}
+		if body.OffsetDays < 0 || body.OffsetDays > 3650 {
+			return nil, errors.New("offset_days must be from 0 to 3650")
+		}
+		release.OffsetDays = body.OffsetDays
+	default:
+		return nil, fmt.Errorf("release rule must be %s, %s or %s", models.ReleaseOnDate, models.ReleaseAfterEnrollment, models.ReleaseAfterCohortStart)
+	}
+	return release, nil
+}
+
+func releaseBody(release *models.ChapterRelease) *ChapterReleaseBody {
+	if release == nil {
+		return nil
+	}
+	return &ChapterReleaseBody{Rule: release.Rule, At: release.At, OffsetDays: release.OffsetDays}
+}
+
+// validateForPublish requires content learners can complete: at least one
+// chapter, and something required to do in every chapter
+func validateForPublish(content models.CourseContent) error {
+	chapters := 0
+	for _, module := range content.Modules {
+		if len(module.Chapters) == 0 {
+			return fmt.Errorf("%w: module %q has no chapters", ErrInvalidCourseContent, module.Title)
+		}
+		for _, chapter := range module.Chapters {
+			chapters++
+			required := false
+			for _, component := range chapter.Components {
+				required = required || !component.Optional
+			}
+			if !required {
+				return fmt.Errorf("%w: chapter %q needs at least one required component", ErrInvalidCourseContent, chapter.Title)
+			}
+		}
+	}
+	if chapters == 0 {
+		return fmt.Errorf("%w: a course needs at least one chapter", ErrInvalidCourseContent)
+	}
+	return nil
+}
+
+func draftResponse(course *models.Course) *DraftResponse {
+	response := &DraftResponse{
+		CourseID:         course.ID,
+		CourseSlug:       course.Slug,
+		PublishedVersion: course.PublishedVersion,
+		BasedOnVersion:   course.PublishedVersion,
+	}
+	content := course.Content()
+	if course.Draft != nil {
+		updatedAt := course.Draft.UpdatedAt
+		response.HasDraft = true
+		response.BasedOnVersion = course.Draft.BasedOnVersion
+		response.UpdatedAt = &updatedAt
+		content = course.Draft.CourseContent
+	}
+	published := models.CourseContent{}
+	if course.IsPublished() {
+		published = course.Content()
+	}
+	response.Content = courseContentBody(content)
+	response.Changes = diffCourseContent(published, content)
+	return response
+}
+
+func versionSummary(version *models.CourseVersion, publishedVersion int) CourseVersionSummary {
+	course := models.Course{Modules: version.Modules}
+	return CourseVersionSummary{
+		Version:      version.Version,
+		Title:        version.Title,
+		ChapterCount: course.ChapterCount(),
+		PublishedAt:  version.PublishedAt,
+		PublishedBy:  version.PublishedBy,
+		Note:         version.Note,
+		RestoredFrom: version.RestoredFrom,
+		IsPublished:  version.Version == publishedVersion,
+	}
+}
+
+func versionResponse(version *models.CourseVersion, publishedVersion int) *CourseVersionResponse {
+	return &CourseVersionResponse{
+		CourseVersionSummary: versionSummary(version, publishedVersion),
+		Content:              courseContentBody(version.CourseContent),
+	}
+}
+
+func courseContentBody(content models.CourseContent) CourseContentBody {
+	body := CourseContentBody{Title: content.Title, Description: content.Description, Modules: []ModuleBody{}}
+	for _, module := range orderedModules(content) {
+		moduleBody := ModuleBody{ID: module.ID.Hex(), Slug: module.Slug, Title: module.Title, Chapters: []ChapterBody{}}
+		for _, chapter := range module.Chapters {
+			chapterBody := ChapterBody{ID: chapter.ID.Hex(), Slug: chapter.Slug, Title: chapter.Title, DurationMins: chapter.DurationMins, Release: releaseBody(chapter.Release), Components: []ComponentBody{}}
+			for _, component := range chapter.Components {
+				chapterBody.Components = append(chapterBody.Components, ComponentBody{
+					Key:             component.Key,
+					Type:            component.Type,
+					Title:           component.Title,
+					URL:             component.URL,
+					QuizID:          component.QuizID,
+					XP:              component.XP,
+					Optional:        component.Optional,
+					PassingScore:    component.PassingScore,
+					MinWatchPercent: component.MinWatchPercent,
+					MinReadSeconds:  component.MinReadSeconds,
+					PackageID:       packageIDHex(component.PackageID),
+					SCOID:           component.SCOID,
+				})
+			}
+			moduleBody.Chapters = append(moduleBody.Chapters, chapterBody)
+		}
+		body.Modules = append(body.Modules, moduleBody)
+	}
+	return body
+}
+
+// packageIDHex is empty for components without a SCORM package
+func packageIDHex(id primitive.ObjectID) string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.Hex()
+}
+
+// orderedModules returns the modules of content in learning order
+func orderedModules(content models.CourseContent) []models.Module {
+	course := models.Course{Modules: content.Modules}
+	return course.OrderedModules()
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"gamified-edu-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of change in a CourseDiff
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// CourseDiff lists what changed between two versions of a course's content.
+// Modules and chapters are matched by ID, components by key.
+type CourseDiff struct {
+	TitleChanged       bool            `json:"title_changed"`
+	DescriptionChanged bool            `json:"description_changed"`
+	Modules            []ModuleChange  `json:"modules"`
+	Chapters           []ChapterChange `json:"chapters"`
+}
+
+type ModuleChange struct {
+	ModuleID primitive.ObjectID `json:"module_id"`
+	Title    string             `json:"title"`
+	Change   string             `json:"change"`
+	// Fields names what changed, for changed modules
+	Fields []string `json:"fields,omitempty"`
+}
+
+type ChapterChange struct {
+	ChapterID primitive.ObjectID `json:"chapter_id"`
+	Title     string             `json:"title"`
+	Change    string             `json:"change"`
+	// Fields names what changed, for changed chapters
+	Fields     []string          `json:"fields,omitempty"`
+	Components []ComponentChange `json:"components,omitempty"`
+}
+
+type ComponentChange struct {
+	Key    string   `json:"key"`
+	Change string   `json:"change"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// HasChanges reports whether the two contents differ at all
+func (d *CourseDiff) HasChanges() bool {
+	return d.TitleChanged || d.DescriptionChanged || len(d.Modules) > 0 || len(d.Chapters) > 0
+}
+
+// diffCourseContent compares from with to. Changes are listed in the order of
+// to, followed by what was removed in the order of from.
+func diffCourseContent(from, to models.CourseContent) *CourseDiff {
+	diff := &CourseDiff{
+		TitleChanged:       from.Title != to.Title,
+		DescriptionChanged: from.Description != to.Description,
+		Modules:            []ModuleChange{},
+		Chapters:           []ChapterChange{},
+	}
+	fromModules, toModules := orderedModules(from), orderedModules(to)
+
+	type placedChapter struct {
+		chapter  models.Chapter
+		moduleID primitive.ObjectID
+	}
+	oldModules := map[primitive.ObjectID]models.Module{}
+	oldChapters := map[primitive.ObjectID]placedChapter{}
+	for _, module := range fromModules {
+		oldModules[module.ID] = module
+		for _, chapter := range module.Chapters {
+			oldChapters[chapter.ID] = placedChapter{chapter, module.ID}
+		}
+	}
+	newModules := map[primitive.ObjectID]bool{}
+	newChapters := map[primitive.ObjectID]bool{}
+
+	for _, module := range toModules {
+		newModules[module.ID] = true
+		old, existed := oldModules[module.ID]
+		if !existed {
+			diff.Modules = append(diff.Modules, ModuleChange{ModuleID: module.ID, Title: module.Title, Change: ChangeAdded})
+		} else {
+			var fields []string
+			if old.Title != module.Title {
+				fields = append(fields, "title")
+			}
+			if old.Position != module.Position {
+				fields = append(fields, "position")
+			}
+			if len(fields) > 0 {
+				diff.Modules = append(diff.Modules, ModuleChange{ModuleID: module.ID, Title: module.Title, Change: ChangeChanged, Fields: fields})
+			}
+		}
+
+		for _, chapter := range module.Chapters {
+			newChapters[chapter.ID] = true
+			old, existed := oldChapters[chapter.ID]
+			if !existed {
+				diff.Chapters = append(diff.Chapters, ChapterChange{ChapterID: chapter.ID, Title: chapter.Title, Change: ChangeAdded})
+				continue
+			}
+			if change, changed := diffChapter(old.chapter, chapter, old.moduleID != module.ID); changed {
+				diff.Chapters = append(diff.Chapters, change)
+			}
+		}
+	}
+
+	for _, module := range fromModules {
+		if !newModules[module.ID] {
+			diff.Modules = append(diff.Modules, ModuleChange{ModuleID: module.ID, Title: module.Title, Change: ChangeRemoved})
+		}
+		for _, chapter := range module.Chapters {
+			if !newChapters[chapter.ID] {
+				diff.Chapters = append(diff.Chapters, ChapterChange{ChapterID: chapter.ID, Title: chapter.Title, Change: ChangeRemoved})
+			}
+		}
+	}
+	return diff
+}
+
+func diffChapter(from, to models.Chapter, movedModule bool) (ChapterChange, bool) {
+	change := ChapterChange{ChapterID: to.ID, Title: to.Title, Change: ChangeChanged}
+	if from.Title != to.Title {
+		change.Fields = append(change.Fields, "title")
+	}
+	if from.DurationMins != to.DurationMins {
+		change.Fields = append(change.Fields, "duration_mins")
+	}
+	if !from.Release.Equal(to.Release) {
+		change.Fields = append(change.Fields, "release")
+	}
+	if movedModule {
+		change.Fields = append(change.Fields, "module")
+	}
+	if from.ChapterNumber != to.ChapterNumber {
+		change.Fields = append(change.Fields, "position")
+	}
+
+	oldComponents := map[string]models.ChapterComponent{}
+	for _, component := range from.Components {
+		oldComponents[component.Key] = component
+	}
+	newComponents := map[string]bool{}
+	for _, component := range to.Components {
+		newComponents[component.Key] = true
+		old, existed := oldComponents[component.Key]
+		if !existed {
+			change.Components = append(change.Components, ComponentChange{Key: component.Key, Change: ChangeAdded})
+			continue
+		}
+		if fields := diffComponent(old, component); len(fields) > 0 {
+			change.Components = append(change.Components, ComponentChange{Key: component.Key, Change: ChangeChanged, Fields: fields})
+		}
+	}
+	for _, component := range from.Components {
+		if !newComponents[component.Key] {
+			change.Components = append(change.Components, ComponentChange{Key: component.Key, Change: ChangeRemoved})
+		}
+	}
+	if len(change.Components) > 0 {
+		change.Fields = append(change.Fields, "components")
+	}
+	return change, len(change.Fields) > 0
+}
+
+func diffComponent(from, to models.ChapterComponent) []string {
+	var fields []string
+	for _, field := range []struct {
+		name    string
+		changed bool
+	}{
+		{"type", from.Type != to.Type},
+		{"title", from.Title != to.Title},
+		{"url", from.URL != to.URL},
+		{"quiz_id", from.QuizID != to.QuizID},
+		{"xp", from.XP != to.XP},
+		{"optional", from.Optional != to.Optional},
+		{"passing_score", from.PassingScore != to.PassingScore},
+		{"min_watch_percent", from.MinWatchPercent != to.MinWatchPercent},
+		{"min_read_seconds", from.MinReadSeconds != to.MinReadSeconds},
+		{"package_id", from.PackageID != to.PackageID},
+		{"sco_id", from.SCOID != to.SCOID},
+	} {
+		if field.changed {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
@@ -1,126 +1,1043 @@
 package services
 
 import (
+    "context"
+    "errors"
+    "fmt"
+    "gamified-edu-backend/internal/models"
     "gamified-edu-backend/internal/repositories"
+    "gamified-edu-backend/internal/tenant"
+    "gamified-edu-backend/pkg"
+    "golang.org/x/text/language"
+    "log"
     "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "net/url"
+    "slices"
+    "sort"
+    "strings"
+    "time"
+)
+
+var (
+    ErrCourseNotFound     = errors.New("course not found")
+    ErrChapterNotInCourse = errors.New("chapter does not belong to this course")
+    ErrNotEnrolled        = errors.New("you are not enrolled in this course")
+    ErrChapterLocked      = errors.New("chapter is locked")
+    ErrInvalidCourseQuery = errors.New("invalid course listing")
+)
+
+// Sorts of the course listing
+const (
+    CourseSortTitle    = "title"
+    CourseSortNewest   = "newest"
+    CourseSortPopular  = "popular"
+    CourseSortProgress = "progress" // the user's progress, most first
 )
 
 type CourseService interface {
-    GetAllCoursesWithProgress(userID primitive.ObjectID) ([]CourseResponse, error)
-    GetCourseDetails(courseID, userID primitive.ObjectID) (*CourseDetailResponse, error)
+    GetAllCoursesWithProgress(ctx context.Context, userID primitive.ObjectID, query CourseListQuery) (*pkg.Page[CourseResponse], error)
+    GetCourseDetails(ctx context.Context, courseID, userID primitive.ObjectID) (*CourseDetailResponse, error)
+    GetEnrolledCourses(ctx context.Context, userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[EnrolledCourseResponse], error)
+    Enroll(ctx context.Context, userID, courseID primitive.ObjectID) (*EnrollmentResponse, error)
+    Unenroll(ctx context.Context, userID, courseID primitive.ObjectID) error
+    UpdateCourseSettings(ctx context.Context, courseID primitive.ObjectID, input UpdateCourseSettingsInput) (*CourseSettingsResponse, error)
+    // ListCategories and ListTags list a page of the facets of published courses, most used first
+    ListCategories(ctx context.Context, page pkg.PageRequest) (*pkg.Page[repositories.CourseFacet], error)
+    ListTags(ctx context.Context, page pkg.PageRequest) (*pkg.Page[repositories.CourseFacet], error)
 }
 
 type courseService struct {
-    courseRepo   repositories.CourseRepository
-    progressRepo repositories.ProgressRepository
+    courseRepo     repositories.CourseRepository
+    progressRepo   repositories.ProgressRepository
+    enrollmentRepo repositories.EnrollmentRepository
+    userRepo       repositories.UserRepository
+    recorder       LearningRecorder
+    indexer        CourseIndexer
+    cohortRepo     repositories.CohortRepository
+    clock          pkg.Clock
+}
+
+func NewCourseService(courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, enrollmentRepo repositories.EnrollmentRepository, userRepo repositories.UserRepository, recorder LearningRecorder, indexer CourseIndexer, cohortRepo repositories.CohortRepository, clock pkg.Clock) CourseService {
+    return &courseService{courseRepo, progressRepo, enrollmentRepo, userRepo, recorder, indexer, cohortRepo, clock}
+}
+
+// CourseCatalog is how a course is described in the catalog.
+type CourseCatalog struct {
+    Categories   []string       `json:"categories"`
+    Tags         []string       `json:"tags"`
+    Difficulty   string         `json:"difficulty,omitempty"`
+    Language     string         `json:"language,omitempty"`
+    ThumbnailURL string         `json:"thumbnail_url,omitempty"`
+    Authors      []CourseAuthor `json:"authors"`
+    // DurationMins estimates the time the course takes, from its chapters' durations
+    DurationMins int `json:"duration_mins"`
+    Rating       CourseRatingStats `json:"rating"`
+    // authorIDs are named in Authors by nameAuthors
+    authorIDs []primitive.ObjectID
 }
 
-func NewCourseService(courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository) CourseService {
-    return &courseService{courseRepo, progressRepo}
+type CourseAuthor struct {
+    ID   primitive.ObjectID `json:"id"`
+    Name string             `json:"name"`
 }
 
 type CourseResponse struct {
     ID          primitive.ObjectID `json:"id"`
     Title       string             `json:"title"`
     Description string             `json:"description"`
+    CourseCatalog
     Progress    int                `json:"progress"`
+    IsEnrolled  bool               `json:"is_enrolled"`
+    EnrollmentCount int            `json:"enrollment_count"`
+}
+
+// CourseListQuery sorts, filters and pages the course listing.
+type CourseListQuery struct {
+    // Sort is one of the CourseSort constants; empty sorts by title
+    Sort string
+    // Completion is one of the Completion constants, for the user; empty lists every course
+    Completion string
+    // Category and Tag keep the courses listed under them
+    Category string
+    Tag      string
+    Page     pkg.PageRequest
+}
+
+// courseCursor is the position after the last course of a page
+type courseCursor struct {
+    Sort  string             `json:"sort"`
+    ID    primitive.ObjectID `json:"id"`
+    Title string             `json:"title"`
+    // Count is the enrollment count or the progress, as the sort needs
+    Count int `json:"count,omitempty"`
+}
+
+// enrolledCursor is the position after the last course of a page of the user's courses
+type enrolledCursor struct {
+    EnrolledAt time.Time          `json:"enrolled_at"`
+    CourseID   primitive.ObjectID `json:"course_id"`
+}
+
+type EnrollmentResponse struct {
+    CourseID    primitive.ObjectID `json:"course_id"`
+    EnrolledAt  time.Time          `json:"enrolled_at"`
+    CompletedAt *time.Time         `json:"completed_at,omitempty"`
+}
+
+// EnrolledCourseResponse is an entry of the "my courses" listing
+type EnrolledCourseResponse struct {
+    CourseResponse
+    EnrolledAt  time.Time  `json:"enrolled_at"`
+    CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
 
 type CourseDetailResponse struct {
-    ID          primitive.ObjectID     `json:"id"`
-    Title       string                 `json:"title"`
-    Description string                 `json:"description"`
-    Chapters    []ChapterWithProgress  `json:"chapters"`
-    Progress    int                    `json:"progress"`
+    ID                 primitive.ObjectID    `json:"id"`
+    Title              string                `json:"title"`
+    Description        string                `json:"description"`
+    CourseCatalog
+    // Version is the published version of the content
+    Version            int                   `json:"version"`
+    Chapters           []ChapterWithProgress `json:"chapters"`
+    Modules            []ModuleWithProgress  `json:"modules"`
+    Progress           int                   `json:"progress"`
+    IsEnrolled         bool                  `json:"is_enrolled"`
+    // Cohort is the cohort the user takes the course with, if any
+    Cohort             *CohortSummary        `json:"cohort,omitempty"`
+    SequentialChapters bool                  `json:"sequential_chapters"`
+    ProgressWeighting  string                `json:"progress_weighting"`
+    Prerequisites      []PrerequisiteStatus  `json:"prerequisites"`
+}
+
+// ModuleWithProgress lists the chapters of a module in order; the chapters
+// themselves are in CourseDetailResponse.Chapters
+type ModuleWithProgress struct {
+    ID         primitive.ObjectID   `json:"id"`
+    Title      string               `json:"title"`
+    Position   int                  `json:"position"`
+    Progress   int                  `json:"progress"`
+    ChapterIDs []primitive.ObjectID `json:"chapter_ids"`
+}
+
+// PrerequisiteStatus tells whether the user completed a prerequisite course
+type PrerequisiteStatus struct {
+    ID          primitive.ObjectID `json:"id"`
+    Title       string             `json:"title"`
+    IsCompleted bool               `json:"is_completed"`
+}
+
+// UpdateCourseSettingsInput changes how a course is unlocked and described. Omitted fields keep their value.
+type UpdateCourseSettingsInput struct {
+    SequentialChapters *bool     `json:"sequential_chapters"`
+    HideUnreleasedChapters *bool `json:"hide_unreleased_chapters"`
+    PrerequisiteIDs    *[]string `json:"prerequisite_ids"`
+    ProgressWeighting  *string   `json:"progress_weighting" binding:"omitempty,oneof=chapters duration"`
+    // Catalog metadata; Difficulty, Language and Thumbnail are cleared with ""
+    Categories         *[]string `json:"categories" binding:"omitempty,max=10,dive,max=60"`
+    Tags               *[]string `json:"tags" binding:"omitempty,max=20,dive,max=40"`
+    Difficulty         *string   `json:"difficulty"`
+    Language           *string   `json:"language"`
+    // Thumbnail is an image URL or a media asset ref
+    Thumbnail          *string   `json:"thumbnail" binding:"omitempty,max=2048"`
+    AuthorIDs          *[]string `json:"author_ids" binding:"omitempty,max=10"`
+}
+
+type CourseSettingsResponse struct {
+    ID                 primitive.ObjectID   `json:"id"`
+    SequentialChapters bool                 `json:"sequential_chapters"`
+    HideUnreleasedChapters bool             `json:"hide_unreleased_chapters"`
+    PrerequisiteIDs    []primitive.ObjectID `json:"prerequisite_ids"`
+    ProgressWeighting  string               `json:"progress_weighting"`
+    Categories         []string             `json:"categories"`
+    Tags               []string             `json:"tags"`
+    Difficulty         string               `json:"difficulty,omitempty"`
+    Language           string               `json:"language,omitempty"`
+    Thumbnail          string               `json:"thumbnail,omitempty"`
+    AuthorIDs          []primitive.ObjectID `json:"author_ids"`
 }
 
 type ChapterWithProgress struct {
     ID               primitive.ObjectID `json:"id"`
     Title            string             `json:"title"`
     ChapterNumber    int                `json:"chapter_number"`
+    DurationMins     int                `json:"duration_mins"`
+    Components       []ComponentWithProgress `json:"components"`
+    IsCompleted      bool               `json:"is_completed"`
+    IsLocked         bool               `json:"is_locked"`
+    // LockReason tells the learner what to complete first, or when the chapter is released
+    LockReason       string             `json:"lock_reason,omitempty"`
+    IsReleased       bool               `json:"is_released"`
+    // AvailableOn is when an unreleased chapter opens for the user, if it has a date for them yet
+    AvailableOn      *time.Time         `json:"available_on,omitempty"`
+
+    // The first video, quiz and slides of the chapter, for clients built
+    // before chapters had a list of components
     VideoURL         string             `json:"video_url"`
     QuizID           string             `json:"quiz_id"`
     PPTLink          string             `json:"ppt_link"`
-    DurationMins     int                `json:"duration_mins"`
     HasViewedVideo   bool               `json:"has_viewed_video"`
     HasCompletedQuiz bool               `json:"has_completed_quiz"`
     HasDownloadedPPT bool               `json:"has_downloaded_ppt"`
-    IsCompleted      bool               `json:"is_completed"`
 }
 
-func (s *courseService) GetAllCoursesWithProgress(userID primitive.ObjectID) ([]CourseResponse, error) {
-    courses, err := s.courseRepo.FindAll()
+type ComponentWithProgress struct {
+    Key         string `json:"key"`
+    Type        string `json:"type"`
+    Title       string `json:"title,omitempty"`
+    URL         string `json:"url,omitempty"`
+    QuizID      string `json:"quiz_id,omitempty"`
+    XP          int    `json:"xp"`
+    Optional    bool   `json:"optional"`
+    IsCompleted bool   `json:"is_completed"`
+    BestScore   *int   `json:"best_score,omitempty"`
+    // Completion rule settings, see models.ChapterComponent
+    PassingScore    int `json:"passing_score,omitempty"`
+    MinWatchPercent int `json:"min_watch_percent,omitempty"`
+    MinReadSeconds  int `json:"min_read_seconds,omitempty"`
+}
+
+// GetAllCoursesWithProgress lists a page of the published courses. Sorting
+// by the user's progress works out the progress of every course; the other
+// sorts page in the database.
+func (s *courseService) GetAllCoursesWithProgress(ctx context.Context, userID primitive.ObjectID, query CourseListQuery) (*pkg.Page[CourseResponse], error) {
+    if query.Sort == "" { query.Sort = CourseSortTitle }
+    switch query.Sort {
+    case CourseSortTitle, CourseSortNewest, CourseSortPopular, CourseSortProgress:
+    default:
+        return nil, fmt.Errorf("%w: sort must be title, newest, popular or progress", ErrInvalidCourseQuery)
+    }
+    switch query.Completion {
+    case "", CompletionNotStarted, CompletionInProgress, CompletionCompleted:
+    default:
+        return nil, fmt.Errorf("%w: completion must be not_started, in_progress or completed", ErrInvalidCourseQuery)
+    }
+    var after *courseCursor
+    if query.Page.Cursor != "" {
+        after = &courseCursor{}
+        if err := pkg.DecodeCursor(query.Page.Cursor, after); err != nil { return nil, err }
+        if after.Sort != query.Sort { return nil, fmt.Errorf("%w: it is for another sort", pkg.ErrInvalidCursor) }
+    }
+    limit := query.Page.Limit
+    if limit <= 0 { limit = pkg.DefaultPageLimit }
+
+    enrollments, err := s.enrollmentRepo.FindActiveByUser(ctx, userID)
     if err != nil { return nil, err }
+    completion, err := completionByCourse(ctx, s.progressRepo, userID, enrollments)
+    if err != nil { return nil, err }
+
     var responses []CourseResponse
-    for _, course := range courses {
-        totalChapters := len(course.Chapters)
-        if totalChapters == 0 {
-            responses = append(responses, CourseResponse{ID: course.ID, Title: course.Title, Description: course.Description, Progress: 0})
-            continue
+    if query.Sort == CourseSortProgress {
+        responses, err = s.coursesByProgress(ctx, userID, query, completion, after, limit+1)
+    } else {
+        responses, err = s.coursesPage(ctx, userID, query, completion, after, limit+1)
+    }
+    if err != nil { return nil, err }
+    for i := range responses {
+        _, responses[i].IsEnrolled = completion[responses[i].ID]
+    }
+    page := pkg.NewPage(responses, limit, func(course CourseResponse) any {
+        position := courseCursor{Sort: query.Sort, ID: course.ID, Title: course.Title}
+        switch query.Sort {
+        case CourseSortPopular:
+            position.Count = course.EnrollmentCount
+        case CourseSortProgress:
+            position.Count = course.Progress
         }
-        completedChapters, err := s.progressRepo.CountCompletedChapters(userID, course.ID)
+        return position
+    })
+    catalogs := make([]*CourseCatalog, 0, len(page.Items))
+    for i := range page.Items {
+        catalogs = append(catalogs, &page.Items[i].CourseCatalog)
+    }
+    if err := s.nameAuthors(catalogs...); err != nil { return nil, err }
+    return &page, nil
+}
+
+// coursesPage fetches up to limit courses after the cursor from the database
+func (s *courseService) coursesPage(ctx context.Context, userID primitive.ObjectID, query CourseListQuery, completion map[primitive.ObjectID]string, after *courseCursor, limit int) ([]CourseResponse, error) {
+    pageQuery := repositories.CoursePageQuery{Order: query.Sort, Category: query.Category, Tag: normalizeTag(query.Tag), Limit: limit}
+    if after != nil {
+        pageQuery.After = &models.Course{ID: after.ID, Title: after.Title, EnrollmentCount: after.Count}
+    }
+    switch query.Completion {
+    case CompletionNotStarted:
+        pageQuery.Except = []primitive.ObjectID{}
+        for courseID, state := range completion {
+            if state != CompletionNotStarted { pageQuery.Except = append(pageQuery.Except, courseID) }
+        }
+    case CompletionInProgress, CompletionCompleted:
+        pageQuery.Only = []primitive.ObjectID{}
+        for courseID, state := range completion {
+            if state == query.Completion { pageQuery.Only = append(pageQuery.Only, courseID) }
+        }
+    }
+    courses, err := s.courseRepo.FindPublishedPage(ctx, pageQuery)
+    if err != nil { return nil, err }
+
+    responses := make([]CourseResponse, 0, len(courses))
+    for i := range courses {
+        response, err := s.courseSummary(ctx, &courses[i], userID)
         if err != nil { return nil, err }
-        progress := int((float64(completedChapters) / float64(totalChapters)) * 100)
-        responses = append(responses, CourseResponse{ID: course.ID, Title: course.Title, Description: course.Description, Progress: progress})
+        responses = append(responses, *response)
     }
     return responses, nil
 }
 
-func (s *courseService) GetCourseDetails(courseID, userID primitive.ObjectID) (*CourseDetailResponse, error) {
-    course, err := s.courseRepo.FindByID(courseID)
+// coursesByProgress works out the user's progress in every course, in one
+// query, and returns up to limit courses after the cursor, most progress first
+func (s *courseService) coursesByProgress(ctx context.Context, userID primitive.ObjectID, query CourseListQuery, completion map[primitive.ObjectID]string, after *courseCursor, limit int) ([]CourseResponse, error) {
+    courses, err := s.courseRepo.FindAll(ctx)
+    if err != nil { return nil, err }
+    listed := []*models.Course{}
+    courseIDs := []primitive.ObjectID{}
+    for i := range courses {
+        // Courses that were never published only exist as drafts
+        if !courses[i].IsPublished() { continue }
+        state := completion[courses[i].ID]
+        if state == "" { state = CompletionNotStarted }
+        if query.Completion != "" && state != query.Completion { continue }
+        if query.Category != "" && !slices.Contains(courses[i].Categories, query.Category) { continue }
+        if query.Tag != "" && !slices.Contains(courses[i].Tags, normalizeTag(query.Tag)) { continue }
+        listed = append(listed, &courses[i])
+        courseIDs = append(courseIDs, courses[i].ID)
+    }
+    statuses, err := s.progressRepo.FindForUsers(ctx, []primitive.ObjectID{userID}, courseIDs)
+    if err != nil { return nil, err }
+    completed := make(map[primitive.ObjectID]bool, len(statuses))
+    for _, status := range statuses {
+        completed[status.ChapterID] = status.IsChapterCompleted
+    }
+
+    responses := make([]CourseResponse, 0, len(listed))
+    for _, course := range listed {
+        response := courseResponse(course)
+        response.Progress, _ = rollUpProgress(course, completed)
+        responses = append(responses, response)
+    }
+    sort.Slice(responses, func(i, j int) bool { return progressBefore(responses[i], responses[j]) })
+
+    start := 0
+    if after != nil {
+        position := CourseResponse{ID: after.ID, Title: after.Title, Progress: after.Count}
+        start = sort.Search(len(responses), func(i int) bool { return progressBefore(position, responses[i]) })
+    }
+    return responses[start:min(len(responses), start+limit)], nil
+}
+
+// progressBefore orders courses by progress, most first, then by title and ID
+func progressBefore(a, b CourseResponse) bool {
+    if a.Progress != b.Progress { return a.Progress > b.Progress }
+    if titleA, titleB := strings.ToLower(a.Title), strings.ToLower(b.Title); titleA != titleB { return titleA < titleB }
+    return a.ID.Hex() < b.ID.Hex()
+}
+
+// completionByCourse returns the Completion constant of each course the
+// user is enrolled in: in progress once they recorded progress in it. The
+// other courses are not started.
+func completionByCourse(ctx context.Context, progressRepo repositories.ProgressRepository, userID primitive.ObjectID, enrollments []models.Enrollment) (map[primitive.ObjectID]string, error) {
+    courseIDs := make([]primitive.ObjectID, 0, len(enrollments))
+    for _, enrollment := range enrollments {
+        if enrollment.CompletedAt == nil { courseIDs = append(courseIDs, enrollment.CourseID) }
+    }
+    started, err := progressRepo.FindStartedCourses(ctx, userID, courseIDs)
+    if err != nil { return nil, err }
+
+    completion := make(map[primitive.ObjectID]string, len(enrollments))
+    for _, enrollment := range enrollments {
+        switch {
+        case enrollment.CompletedAt != nil:
+            completion[enrollment.CourseID] = CompletionCompleted
+        case started[enrollment.CourseID]:
+            completion[enrollment.CourseID] = CompletionInProgress
+        default:
+            completion[enrollment.CourseID] = CompletionNotStarted
+        }
+    }
+    return completion, nil
+}
+
+// GetEnrolledCourses lists a page of the courses the user is enrolled in, most recent enrollment first
+func (s *courseService) GetEnrolledCourses(ctx context.Context, userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[EnrolledCourseResponse], error) {
+    var after *enrolledCursor
+    if page.Cursor != "" {
+        after = &enrolledCursor{}
+        if err := pkg.DecodeCursor(page.Cursor, after); err != nil { return nil, err }
+    }
+    limit := page.Limit
+    if limit <= 0 { limit = pkg.DefaultPageLimit }
+    enrollments, err := s.enrollmentRepo.FindActiveByUser(ctx, userID)
+    if err != nil { return nil, err }
+    // Enrollments at the same moment, e.g. from a bulk import, need an order too
+    sort.SliceStable(enrollments, func(i, j int) bool {
+        if !enrollments[i].EnrolledAt.Equal(enrollments[j].EnrolledAt) { return enrollments[i].EnrolledAt.After(enrollments[j].EnrolledAt) }
+        return enrollments[i].CourseID.Hex() < enrollments[j].CourseID.Hex()
+    })
+
+    responses := []EnrolledCourseResponse{}
+    for _, enrollment := range enrollments {
+        if len(responses) > limit { break }
+        if after != nil && (enrollment.EnrolledAt.After(after.EnrolledAt) ||
+            enrollment.EnrolledAt.Equal(after.EnrolledAt) && enrollment.CourseID.Hex() <= after.CourseID.Hex()) {
+            continue
+        }
+        course, err := s.courseRepo.FindByID(ctx, enrollment.CourseID)
+        if err != nil {
+            // Courses can be removed while learners are still enrolled
+            if errors.Is(err, mongo.ErrNoDocuments) { continue }
+            return nil, err
+        }
+        summary, err := s.courseSummary(ctx, course, userID)
+        if err != nil { return nil, err }
+        summary.IsEnrolled = true
+        responses = append(responses, EnrolledCourseResponse{
+            CourseResponse: *summary,
+            EnrolledAt:     enrollment.EnrolledAt,
+            CompletedAt:    enrollment.CompletedAt,
+        })
+    }
+    result := pkg.NewPage(responses, limit, func(course EnrolledCourseResponse) any {
+        return enrolledCursor{EnrolledAt: course.EnrolledAt, CourseID: course.ID}
+    })
+    catalogs := make([]*CourseCatalog, 0, len(result.Items))
+    for i := range result.Items {
+        catalogs = append(catalogs, &result.Items[i].CourseCatalog)
+    }
+    if err := s.nameAuthors(catalogs...); err != nil { return nil, err }
+    return &result, nil
+}
+
+func courseResponse(course *models.Course) CourseResponse {
+    return CourseResponse{ID: course.ID, Title: course.Title, Description: course.Description, CourseCatalog: courseCatalog(course), EnrollmentCount: course.EnrollmentCount}
+}
+
+type facetCursor struct {
+    Name        string `json:"name"`
+    CourseCount int    `json:"course_count"`
+}
+
+func (s *courseService) ListCategories(ctx context.Context, page pkg.PageRequest) (*pkg.Page[repositories.CourseFacet], error) {
+    return listFacets(page, func(after *repositories.CourseFacet, limit int) ([]repositories.CourseFacet, error) {
+        return s.courseRepo.CountCategories(ctx, after, limit)
+    })
+}
+
+func (s *courseService) ListTags(ctx context.Context, page pkg.PageRequest) (*pkg.Page[repositories.CourseFacet], error) {
+    return listFacets(page, func(after *repositories.CourseFacet, limit int) ([]repositories.CourseFacet, error) {
+        return s.courseRepo.CountTags(ctx, after, limit)
+    })
+}
+
+// listFacets makes a page of the facets count returns
+func listFacets(page pkg.PageRequest, count func(after *repositories.CourseFacet, limit int) ([]repositories.CourseFacet, error)) (*pkg.Page[repositories.CourseFacet], error) {
+    var after *repositories.CourseFacet
+    if page.Cursor != "" {
+        var position facetCursor
+        if err := pkg.DecodeCursor(page.Cursor, &position); err != nil { return nil, err }
+        after = &repositories.CourseFacet{Name: position.Name, CourseCount: position.CourseCount}
+    }
+    limit := page.Limit
+    if limit <= 0 { limit = pkg.DefaultPageLimit }
+    facets, err := count(after, limit+1)
+    if err != nil { return nil, err }
+    result := pkg.NewPage(facets, limit, func(facet repositories.CourseFacet) any {
+        return facetCursor{Name: facet.Name, CourseCount: facet.CourseCount}
+    })
+    return &result, nil
+}
+
+// courseCatalog describes the course without its authors' names, see nameAuthors
+func courseCatalog(course *models.Course) CourseCatalog {
+    catalog := CourseCatalog{
+        Categories:   course.Categories,
+        Tags:         course.Tags,
+        Difficulty:   course.Difficulty,
+        Language:     course.Language,
+        Authors:      []CourseAuthor{},
+        DurationMins: course.DurationMins(),
+        Rating:       courseRatingStats(course.Rating),
+        authorIDs:    course.AuthorIDs,
+    }
+    if catalog.Categories == nil { catalog.Categories = []string{} }
+    if catalog.Tags == nil { catalog.Tags = []string{} }
+    if course.Thumbnail != "" { catalog.ThumbnailURL = resolveAssetURL(course.Thumbnail) }
+    return catalog
+}
+
+// nameAuthors fills in the authors of the catalogs, looking them all up at
+// once. Authors whose accounts were deleted are left out.
+func (s *courseService) nameAuthors(catalogs ...*CourseCatalog) error {
+    var ids []primitive.ObjectID
+    for _, catalog := range catalogs {
+        ids = append(ids, catalog.authorIDs...)
+    }
+    if len(ids) == 0 { return nil }
+    users, err := s.userRepo.FindByIDs(ids)
+    if err != nil { return err }
+    names := make(map[primitive.ObjectID]string, len(users))
+    for i := range users {
+        names[users[i].ID] = users[i].DisplayNameOrFullName()
+    }
+    for _, catalog := range catalogs {
+        for _, id := range catalog.authorIDs {
+            if name, ok := names[id]; ok {
+                catalog.Authors = append(catalog.Authors, CourseAuthor{ID: id, Name: name})
+            }
+        }
+    }
+    return nil
+}
+
+func (s *courseService) courseSummary(ctx context.Context, course *models.Course, userID primitive.ObjectID) (*CourseResponse, error) {
+    response := courseResponse(course)
+    if course.ChapterCount() == 0 {
+        return &response, nil
+    }
+    statuses, err := s.progressRepo.GetUserCourseProgress(ctx, userID, course.ID)
+    if err != nil { return nil, err }
+    completed := make(map[primitive.ObjectID]bool, len(statuses))
+    for _, status := range statuses {
+        completed[status.ChapterID] = status.IsChapterCompleted
+    }
+    response.Progress, _ = rollUpProgress(course, completed)
+    return &response, nil
+}
+
+func (s *courseService) Enroll(ctx context.Context, userID, courseID primitive.ObjectID) (*EnrollmentResponse, error) {
+    course, err := s.courseRepo.FindByID(ctx, courseID)
+    if err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) { return nil, ErrCourseNotFound }
+        return nil, err
+    }
+    if !course.IsPublished() { return nil, ErrCourseNotFound }
+
+    // Enrolling twice keeps the original enrollment date
+    enrollment, err := s.enrollmentRepo.Find(ctx, userID, courseID)
+    if err != nil { return nil, err }
+    if enrollment == nil || !enrollment.IsActive() {
+        enrollment, err = s.enrollmentRepo.Enroll(ctx, userID, courseID, s.clock.Now())
+        if err != nil { return nil, err }
+        if err := s.courseRepo.AdjustEnrollmentCount(ctx, courseID, 1); err != nil {
+            log.Printf("Could not count the enrollment in course %s: %v", courseID.Hex(), err)
+        }
+        if err := s.recorder.RecordEnrollment(ctx, userID, course); err != nil {
+            log.Printf("Could not record learning event for user %s: %v", userID.Hex(), err)
+        }
+    }
+    return &EnrollmentResponse{CourseID: courseID, EnrolledAt: enrollment.EnrolledAt, CompletedAt: enrollment.CompletedAt}, nil
+}
+
+// Unenroll ends the enrollment. Progress is kept in case the user enrolls again.
+func (s *courseService) Unenroll(ctx context.Context, userID, courseID primitive.ObjectID) error {
+    unenrolled, err := s.enrollmentRepo.Unenroll(ctx, userID, courseID, s.clock.Now())
+    if err != nil { return err }
+    if !unenrolled { return ErrNotEnrolled }
+    if err := s.courseRepo.AdjustEnrollmentCount(ctx, courseID, -1); err != nil {
+        log.Printf("Could not count the unenrollment from course %s: %v", courseID.Hex(), err)
+    }
+    return nil
+}
+
+func (s *courseService) GetCourseDetails(ctx context.Context, courseID, userID primitive.ObjectID) (*CourseDetailResponse, error) {
+    course, err := s.courseRepo.FindByID(ctx, courseID)
+    if err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) { return nil, ErrCourseNotFound }
+        return nil, err
+    }
+    if !course.IsPublished() { return nil, ErrCourseNotFound }
+    enrollment, err := s.enrollmentRepo.Find(ctx, userID, courseID)
+    if err != nil { return nil, err }
+    prerequisites, err := prerequisiteStatuses(ctx, s.courseRepo, s.progressRepo, s.enrollmentRepo, course, userID)
+    if err != nil { return nil, err }
+    locks, err := chapterLocks(ctx, s.progressRepo, course, userID, prerequisites)
+    if err != nil { return nil, err }
+    schedule, err := loadReleaseSchedule(ctx, s.cohortRepo, s.userRepo, userID, enrollment, s.clock.Now())
     if err != nil { return nil, err }
 
     var chaptersWithProgress []ChapterWithProgress
-    completedChapters := 0
+    completed := map[primitive.ObjectID]bool{}
+    hidden := map[primitive.ObjectID]bool{}
 
-    for _, chapter := range course.Chapters {
+    for _, chapter := range course.OrderedChapters() {
         // Get progress for this specific chapter
-        progress, err := s.progressRepo.FindByUserAndChapter(userID, chapter.ID)
-        
-        hasViewedVideo := false
-        hasCompletedQuiz := false
-        hasDownloadedPPT := false
-        isCompleted := false
-
-        if err == nil && progress != nil {
-            hasViewedVideo = progress.HasViewedVideo
-            hasCompletedQuiz = progress.HasCompletedQuiz
-            hasDownloadedPPT = progress.HasDownloadedPPT
-            isCompleted = progress.IsChapterCompleted
-            
-            if isCompleted {
-                completedChapters++
-            }
+        progress, err := s.progressRepo.FindByUserAndChapter(ctx, userID, chapter.ID)
+        if err != nil || progress == nil {
+            progress = &models.UserChapterStatus{}
         }
+        completed[chapter.ID] = progress.IsChapterCompleted
 
-        chaptersWithProgress = append(chaptersWithProgress, ChapterWithProgress{
-            ID:               chapter.ID,
-            Title:            chapter.Title,
-            ChapterNumber:    chapter.ChapterNumber,
-            VideoURL:         chapter.VideoURL,
-            QuizID:           chapter.QuizID,
-            PPTLink:          chapter.PPTLink,
-            DurationMins:     chapter.DurationMins,
-            HasViewedVideo:   hasViewedVideo,
-            HasCompletedQuiz: hasCompletedQuiz,
-            HasDownloadedPPT: hasDownloadedPPT,
-            IsCompleted:      isCompleted,
-        })
+        // Unreleased chapters are hidden, or locked without their material
+        availableOn, released := schedule.availableOn(chapter)
+        if !released && course.HideUnreleasedChapters {
+            hidden[chapter.ID] = true
+            continue
+        }
+        lockReason := locks[chapter.ID]
+        if !released && lockReason == "" {
+            lockReason = schedule.lockReason(chapter.Release, availableOn)
+        }
+        response := chapterWithProgress(chapter, progress, lockReason)
+        if !released {
+            response = unreleasedChapter(response, availableOn)
+        }
+        chaptersWithProgress = append(chaptersWithProgress, response)
     }
 
-    totalChapters := len(course.Chapters)
-    progress := 0
-    if totalChapters > 0 {
-        progress = int((float64(completedChapters) / float64(totalChapters)) * 100)
+    progress, moduleProgress := rollUpProgress(course, completed)
+    modules := []ModuleWithProgress{}
+    for _, module := range course.OrderedModules() {
+        chapterIDs := []primitive.ObjectID{}
+        for _, chapter := range module.Chapters {
+            if !hidden[chapter.ID] {
+                chapterIDs = append(chapterIDs, chapter.ID)
+            }
+        }
+        modules = append(modules, ModuleWithProgress{
+            ID:         module.ID,
+            Title:      module.Title,
+            Position:   module.Position,
+            Progress:   moduleProgress[module.ID],
+            ChapterIDs: chapterIDs,
+        })
     }
 
     response := &CourseDetailResponse{
-        ID:          course.ID,
-        Title:       course.Title,
-        Description: course.Description,
-        Chapters:    chaptersWithProgress,
-        Progress:    progress,
+        ID:                 course.ID,
+        Title:              course.Title,
+        Description:        course.Description,
+        CourseCatalog:      courseCatalog(course),
+        Version:            course.PublishedVersion,
+        Chapters:           chaptersWithProgress,
+        Modules:            modules,
+        Progress:           progress,
+        IsEnrolled:         enrollment != nil && enrollment.IsActive(),
+        Cohort:             cohortSummary(schedule.cohort),
+        SequentialChapters: course.SequentialChapters,
+        ProgressWeighting:  progressWeighting(course),
+        Prerequisites:      prerequisites,
     }
+    if err := s.nameAuthors(&response.CourseCatalog); err != nil { return nil, err }
 
     return response, nil
+}
+
+func chapterWithProgress(chapter models.Chapter, progress *models.UserChapterStatus, lockReason string) ChapterWithProgress {
+    response := ChapterWithProgress{
+        ID:            chapter.ID,
+        Title:         chapter.Title,
+        ChapterNumber: chapter.ChapterNumber,
+        DurationMins:  chapter.DurationMins,
+        Components:    []ComponentWithProgress{},
+        IsCompleted:   progress.IsChapterCompleted,
+        IsLocked:      lockReason != "",
+        LockReason:    lockReason,
+        IsReleased:    true,
+    }
+    seen := map[string]bool{}
+    for _, component := range chapter.Components {
+        done := progress.HasCompleted(component.Key)
+        url := resolveAssetURL(component.URL)
+        // SCOs are only served through the signed URL their runtime session hands out
+        if component.Type == models.ComponentSCORM { url = "" }
+        response.Components = append(response.Components, ComponentWithProgress{
+            Key:             component.Key,
+            Type:            component.Type,
+            Title:           component.Title,
+            URL:             url,
+            QuizID:          component.QuizID,
+            XP:              componentXP(component),
+            Optional:        component.Optional,
+            IsCompleted:     done,
+            BestScore:       progress.Components[component.Key].BestScore,
+            PassingScore:    component.PassingScore,
+            MinWatchPercent: component.MinWatchPercent,
+            MinReadSeconds:  component.MinReadSeconds,
+        })
+
+        if seen[component.Type] {
+            continue
+        }
+        seen[component.Type] = true
+        switch component.Type {
+        case models.ComponentVideo:
+            response.VideoURL, response.HasViewedVideo = resolveAssetURL(component.URL), done
+        case models.ComponentQuiz:
+            response.QuizID, response.HasCompletedQuiz = component.QuizID, done
+        case models.ComponentSlides:
+            response.PPTLink, response.HasDownloadedPPT = resolveAssetURL(component.URL), done
+        }
+    }
+    return response
+}
+
+// unreleasedChapter locks the chapter until availableOn and leaves out the
+// links to its material, which learners only get once it is released
+func unreleasedChapter(chapter ChapterWithProgress, availableOn *time.Time) ChapterWithProgress {
+    chapter.IsLocked = true
+    chapter.IsReleased = false
+    chapter.AvailableOn = availableOn
+    for i := range chapter.Components {
+        chapter.Components[i].URL = ""
+        chapter.Components[i].QuizID = ""
+    }
+    chapter.VideoURL, chapter.QuizID, chapter.PPTLink = "", "", ""
+    return chapter
+}
+
+// UpdateCourseSettings changes sequential unlocking and the prerequisites of a course
+func (s *courseService) UpdateCourseSettings(ctx context.Context, courseID primitive.ObjectID, input UpdateCourseSettingsInput) (*CourseSettingsResponse, error) {
+    course, err := s.courseRepo.FindByID(ctx, courseID)
+    if err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) { return nil, ErrCourseNotFound }
+        return nil, err
+    }
+
+    if input.SequentialChapters != nil {
+        course.SequentialChapters = *input.SequentialChapters
+    }
+    if input.HideUnreleasedChapters != nil {
+        course.HideUnreleasedChapters = *input.HideUnreleasedChapters
+    }
+    if input.PrerequisiteIDs != nil {
+        course.PrerequisiteIDs, err = s.parsePrerequisites(ctx, course.ID, *input.PrerequisiteIDs)
+        if err != nil { return nil, err }
+    }
+    if input.ProgressWeighting != nil {
+        course.ProgressWeighting = *input.ProgressWeighting
+    }
+    if input.Categories != nil {
+        course.Categories = normalizeCategories(*input.Categories)
+    }
+    if input.Tags != nil {
+        course.Tags = normalizeTags(*input.Tags)
+    }
+    if input.Difficulty != nil {
+        if !validDifficulty(*input.Difficulty) && *input.Difficulty != "" {
+            return nil, errors.New("difficulty must be beginner, intermediate or advanced")
+        }
+        course.Difficulty = *input.Difficulty
+    }
+    if input.Language != nil {
+        course.Language, err = normalizeLanguage(*input.Language)
+        if err != nil { return nil, err }
+    }
+    if input.Thumbnail != nil {
+        if err := checkThumbnail(*input.Thumbnail); err != nil { return nil, err }
+        course.Thumbnail = *input.Thumbnail
+    }
+    if input.AuthorIDs != nil {
+        course.AuthorIDs, err = s.parseAuthors(ctx, *input.AuthorIDs)
+        if err != nil { return nil, err }
+    }
+
+    if err := s.courseRepo.UpdateSettings(ctx, course); err != nil {
+        return nil, err
+    }
+    if err := s.indexer.IndexCourse(ctx, course); err != nil {
+        log.Printf("Could not update the search index for course %s: %v", course.ID.Hex(), err)
+    }
+    prerequisiteIDs := course.PrerequisiteIDs
+    if prerequisiteIDs == nil {
+        prerequisiteIDs = []primitive.ObjectID{}
+    }
+    authorIDs := course.AuthorIDs
+    if authorIDs == nil {
+        authorIDs = []primitive.ObjectID{}
+    }
+    return &CourseSettingsResponse{
+        ID:                 course.ID,
+        SequentialChapters: course.SequentialChapters,
+        HideUnreleasedChapters: course.HideUnreleasedChapters,
+        PrerequisiteIDs:    prerequisiteIDs,
+        ProgressWeighting:  progressWeighting(course),
+        Categories:         normalizeCategories(course.Categories),
+        Tags:               normalizeTags(course.Tags),
+        Difficulty:         course.Difficulty,
+        Language:           course.Language,
+        Thumbnail:          course.Thumbnail,
+        AuthorIDs:          authorIDs,
+    }, nil
+}
+
+// parseAuthors checks that the authors are instructors or admins of the organization
+func (s *courseService) parseAuthors(ctx context.Context, rawIDs []string) ([]primitive.ObjectID, error) {
+    organizationID, err := tenant.OrganizationID(ctx)
+    if err != nil { return nil, err }
+    ids := []primitive.ObjectID{}
+    for _, raw := range rawIDs {
+        id, err := primitive.ObjectIDFromHex(raw)
+        if err != nil { return nil, fmt.Errorf("invalid author ID %q", raw) }
+        if !slices.Contains(ids, id) { ids = append(ids, id) }
+    }
+    users, err := s.userRepo.FindByIDs(ids)
+    if err != nil { return nil, err }
+    staff := map[primitive.ObjectID]bool{}
+    for _, user := range users {
+        staff[user.ID] = user.OrganizationID == organizationID && (user.Role == models.RoleInstructor || user.Role == models.RoleAdmin)
+    }
+    for _, id := range ids {
+        if !staff[id] { return nil, fmt.Errorf("author %s is not an instructor or admin of the organization", id.Hex()) }
+    }
+    return ids, nil
+}
+
+func validDifficulty(difficulty string) bool {
+    return difficulty == models.DifficultyBeginner || difficulty == models.DifficultyIntermediate || difficulty == models.DifficultyAdvanced
+}
+
+// normalizeLanguage returns the canonical form of a BCP 47 language tag, e.g. "pt-BR" for "pt-br"
+func normalizeLanguage(raw string) (string, error) {
+    raw = strings.TrimSpace(raw)
+    if raw == "" { return "", nil }
+    tag, err := language.Parse(raw)
+    if err != nil { return "", fmt.Errorf("language %q is not a BCP 47 language tag like \"en\" or \"pt-BR\"", raw) }
+    return tag.String(), nil
+}
+
+// checkThumbnail accepts media asset refs, http(s) URLs and paths on this server
+func checkThumbnail(thumbnail string) error {
+    if thumbnail == "" { return nil }
+    if assetID, ok := strings.CutPrefix(thumbnail, AssetURLPrefix); ok {
+        if _, err := primitive.ObjectIDFromHex(assetID); err != nil { return fmt.Errorf("invalid thumbnail asset ref %q", thumbnail) }
+        return nil
+    }
+    parsed, err := url.Parse(thumbnail)
+    if err != nil || !(parsed.Scheme == "http" || parsed.Scheme == "https" || parsed.Scheme == "" && parsed.Host == "" && strings.HasPrefix(parsed.Path, "/")) {
+        return fmt.Errorf("thumbnail must be an image URL or a media asset ref")
+    }
+    return nil
+}
+
+// normalizeTags lowercases and trims tags and drops empty and repeated ones
+func normalizeTags(tags []string) []string {
+    normalized := []string{}
+    seen := map[string]bool{}
+    for _, tag := range tags {
+        tag = normalizeTag(tag)
+        if tag == "" || seen[tag] { continue }
+        seen[tag] = true
+        normalized = append(normalized, tag)
+    }
+    return normalized
+}
+
+func normalizeTag(tag string) string {
+    return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeCategories trims categories and drops empty ones and ones
+// repeated in another case; categories keep their case for display
+func normalizeCategories(categories []string) []string {
+    normalized := []string{}
+    seen := map[string]bool{}
+    for _, category := range categories {
+        category = strings.TrimSpace(category)
+        if category == "" || seen[strings.ToLower(category)] { continue }
+        seen[strings.ToLower(category)] = true
+        normalized = append(normalized, category)
+    }
+    return normalized
+}
+
+// rollUpProgress returns the progress of the course and of each of its
+// modules in percent. Chapters count by the course's weighting, so a module
+// counts as much as its chapters together.
+func rollUpProgress(course *models.Course, completed map[primitive.ObjectID]bool) (int, map[primitive.ObjectID]int) {
+    modules := make(map[primitive.ObjectID]int, len(course.Modules))
+    var courseDone, courseTotal float64
+    for _, module := range course.Modules {
+        var done, total float64
+        for _, chapter := range module.Chapters {
+            weight := course.ChapterWeight(chapter)
+            total += weight
+            if completed[chapter.ID] {
+                done += weight
+            }
+        }
+        if total > 0 {
+            modules[module.ID] = int(done / total * 100)
+        }
+        courseDone += done
+        courseTotal += total
+    }
+    if courseTotal == 0 {
+        return 0, modules
+    }
+    return int(courseDone / courseTotal * 100), modules
+}
+
+func progressWeighting(course *models.Course) string {
+    if course.ProgressWeighting == "" {
+        return models.ProgressWeightingChapters
+    }
+    return course.ProgressWeighting
+}
+
+// parsePrerequisites validates the prerequisite IDs of courseID, rejecting
+// unknown courses and chains that would lead back to the course itself.
+func (s *courseService) parsePrerequisites(ctx context.Context, courseID primitive.ObjectID, rawIDs []string) ([]primitive.ObjectID, error) {
+    courses, err := s.courseRepo.FindAll(ctx)
+    if err != nil { return nil, err }
+    graph := make(map[primitive.ObjectID][]primitive.ObjectID, len(courses))
+    published := make(map[primitive.ObjectID]bool, len(courses))
+    for _, course := range courses {
+        graph[course.ID] = course.PrerequisiteIDs
+        published[course.ID] = course.IsPublished()
+    }
+
+    ids := []primitive.ObjectID{}
+    seen := map[primitive.ObjectID]bool{}
+    for _, raw := range rawIDs {
+        id, err := primitive.ObjectIDFromHex(raw)
+        if err != nil { return nil, fmt.Errorf("invalid prerequisite ID %q", raw) }
+        if id == courseID { return nil, errors.New("a course cannot be its own prerequisite") }
+        if _, ok := graph[id]; !ok { return nil, fmt.Errorf("prerequisite course %s not found", raw) }
+        if !published[id] { return nil, fmt.Errorf("prerequisite course %s is not published yet", raw) }
+        if !seen[id] {
+            seen[id] = true
+            ids = append(ids, id)
+        }
+    }
+
+    // Walk the prerequisites of the new prerequisites; reaching the course means a cycle
+    graph[courseID] = ids
+    visited := map[primitive.ObjectID]bool{}
+    stack := append([]primitive.ObjectID{}, ids...)
+    for len(stack) > 0 {
+        id := stack[len(stack)-1]
+        stack = stack[:len(stack)-1]
+        if id == courseID { return nil, errors.New("prerequisites cannot form a cycle") }
+        if visited[id] { continue }
+        visited[id] = true
+        stack = append(stack, graph[id]...)
+    }
+    return ids, nil
+}
+
+// prerequisiteStatuses reports which prerequisites of the course the user has completed.
+// Prerequisites that no longer exist are left out.
+func prerequisiteStatuses(ctx context.Context, courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, enrollmentRepo repositories.EnrollmentRepository, course *models.Course, userID primitive.ObjectID) ([]PrerequisiteStatus, error) {
+    statuses := []PrerequisiteStatus{}
+    for _, id := range course.PrerequisiteIDs {
+        prerequisite, err := courseRepo.FindByID(ctx, id)
+        if err != nil {
+            if errors.Is(err, mongo.ErrNoDocuments) { continue }
+            return nil, err
+        }
+        // A recorded completion stands even if chapters were added since
+        enrollment, err := enrollmentRepo.Find(ctx, userID, prerequisite.ID)
+        if err != nil { return nil, err }
+        isCompleted := enrollment != nil && enrollment.CompletedAt != nil
+        if !isCompleted {
+            completed, err := completedChapters(ctx, progressRepo, userID, prerequisite)
+            if err != nil { return nil, err }
+            isCompleted = completed >= prerequisite.ChapterCount()
+        }
+        statuses = append(statuses, PrerequisiteStatus{
+            ID:          prerequisite.ID,
+            Title:       prerequisite.Title,
+            IsCompleted: isCompleted,
+        })
+    }
+    return statuses, nil
+}
+
+// completedChapters counts the chapters of the course's published version the
+// user completed. Progress on chapters that were removed is kept but doesn't count.
+func completedChapters(ctx context.Context, progressRepo repositories.ProgressRepository, userID primitive.ObjectID, course *models.Course) (int, error) {
+    if course.ChapterCount() == 0 {
+        return 0, nil
+    }
+    counts, err := progressRepo.CountCompletedByUser(ctx, []primitive.ObjectID{userID}, course.ChapterIDs())
+    if err != nil { return 0, err }
+    return counts[userID], nil
+}
+
+// chapterLocks returns why each locked chapter of the course is locked for
+// the user; unlocked chapters are not in the map. Unmet prerequisites lock the
+// whole course, sequential courses lock chapters behind the first unfinished one.
+func chapterLocks(ctx context.Context, progressRepo repositories.ProgressRepository, course *models.Course, userID primitive.ObjectID, prerequisites []PrerequisiteStatus) (map[primitive.ObjectID]string, error) {
+    locks := map[primitive.ObjectID]string{}
+
+    var missing []string
+    for _, prerequisite := range prerequisites {
+        if !prerequisite.IsCompleted {
+            missing = append(missing, prerequisite.Title)
+        }
+    }
+    if len(missing) > 0 {
+        reason := fmt.Sprintf("Complete %s first", strings.Join(missing, ", "))
+        for _, chapter := range course.OrderedChapters() {
+            locks[chapter.ID] = reason
+        }
+        return locks, nil
+    }
+    if !course.SequentialChapters {
+        return locks, nil
+    }
+
+    statuses, err := progressRepo.GetUserCourseProgress(ctx, userID, course.ID)
+    if err != nil { return nil, err }
+    completed := map[primitive.ObjectID]bool{}
+    for _, status := range statuses {
+        if status.IsChapterCompleted {
+            completed[status.ChapterID] = true
+        }
+    }
+
+    // Everything after the first unfinished chapter is locked, across modules.
+    // Chapters finished before the course became sequential stay open.
+    var blocker *models.Chapter
+    chapters := course.OrderedChapters()
+    for i := range chapters {
+        if completed[chapters[i].ID] {
+            continue
+        }
+        if blocker == nil {
+            blocker = &chapters[i]
+            continue
+        }
+        locks[chapters[i].ID] = fmt.Sprintf("Complete %q first", blocker.Title)
+    }
+    return locks, nil
 }
\ No newline at end of file
@@ -0,0 +1,802 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/coursepack"
+	"gamified-edu-backend/internal/media"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/tenant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions an import takes on a course or quiz
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+)
+
+// ErrCourseNotExportable is returned for courses bundles cannot carry, such
+// as courses made from SCORM packages.
+var ErrCourseNotExportable = errors.New("course cannot be exported")
+
+// CoursePackService moves courses and their quizzes and media between
+// environments as bundles, see package coursepack. Courses, modules and
+// chapters are matched by slug, so learners keep their progress when a
+// course is imported again. Bundled media becomes media assets.
+type CoursePackService interface {
+	// Export bundles the courses with the given slugs, or all courses when none are given
+	Export(ctx context.Context, slugs []string) (*coursepack.Bundle, error)
+	Import(ctx context.Context, authorID primitive.ObjectID, bundle *coursepack.Bundle, options ImportOptions) (*ImportReport, error)
+	// MoveBundledMedia moves the media of bundles imported before bundled
+	// media became assets from legacy into the asset store, in every
+	// organization, and links the courses, drafts and versions to the assets
+	MoveBundledMedia(legacy *media.DirStore) error
+}
+
+type ImportOptions struct {
+	// DryRun reports what the import would change without changing anything
+	DryRun bool
+	// Publish makes the imported content live right away instead of leaving it in drafts
+	Publish bool
+}
+
+type ImportReport struct {
+	DryRun  bool                 `json:"dry_run"`
+	Courses []CourseImportResult `json:"courses"`
+	Quizzes []QuizImportResult   `json:"quizzes"`
+}
+
+type CourseImportResult struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	// CourseID is empty for courses a dry run would create
+	CourseID *primitive.ObjectID `json:"course_id,omitempty"`
+	Action   string              `json:"action"`
+	// Changes compares the bundle with the course's draft, or with its
+	// published content when there is no draft
+	Changes         *CourseDiff `json:"changes"`
+	SettingsChanged bool        `json:"settings_changed"`
+	// PublishedVersion is the version the import published, if it published one
+	PublishedVersion int `json:"published_version,omitempty"`
+}
+
+type QuizImportResult struct {
+	Slug   string `json:"slug"`
+	Action string `json:"action"`
+}
+
+type coursePackService struct {
+	courseRepo       repositories.CourseRepository
+	versionRepo      repositories.CourseVersionRepository
+	quizRepo         repositories.QuizRepository
+	userRepo         repositories.UserRepository
+	authoringService CourseAuthoringService
+	assetService     MediaAssetService
+}
+
+func NewCoursePackService(courseRepo repositories.CourseRepository, versionRepo repositories.CourseVersionRepository, quizRepo repositories.QuizRepository, userRepo repositories.UserRepository, authoringService CourseAuthoringService, assetService MediaAssetService) CoursePackService {
+	return &coursePackService{
+		courseRepo:       courseRepo,
+		versionRepo:      versionRepo,
+		quizRepo:         quizRepo,
+		userRepo:         userRepo,
+		authoringService: authoringService,
+		assetService:     assetService,
+	}
+}
+
+// Export writes the published content of each course, or the draft of
+// courses that were never published. Media assets go into the bundle;
+// other URLs are kept as they are. Quizzes the server does not have
+// are left out, their slugs stay on the components. Courses with SCORM
+// components are left out of exports of all courses.
+func (s *coursePackService) Export(ctx context.Context, slugs []string) (*coursepack.Bundle, error) {
+	courses, err := s.courseRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]*models.Course, len(courses))
+	slugsByID := make(map[primitive.ObjectID]string, len(courses))
+	for i := range courses {
+		bySlug[courses[i].Slug] = &courses[i]
+		slugsByID[courses[i].ID] = courses[i].Slug
+	}
+
+	var selected []*models.Course
+	if len(slugs) == 0 {
+		for i := range courses {
+			selected = append(selected, &courses[i])
+		}
+		sort.Slice(selected, func(a, b int) bool { return selected[a].Slug < selected[b].Slug })
+	}
+	for _, slug := range slugs {
+		course, ok := bySlug[slug]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrCourseNotFound, slug)
+		}
+		selected = append(selected, course)
+	}
+
+	bundle := &coursepack.Bundle{Files: map[string][]byte{}}
+	exported := map[string]bool{}
+	for _, course := range selected {
+		content := course.Content()
+		if !course.IsPublished() && course.Draft != nil {
+			content = course.Draft.CourseContent
+		}
+		if hasSCORMComponents(content) {
+			if len(slugs) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s has SCORM content, upload its SCORM package instead", ErrCourseNotExportable, course.Slug)
+		}
+		manifest := coursepack.Course{
+			Slug:                   course.Slug,
+			Title:                  content.Title,
+			Description:            content.Description,
+			SequentialChapters:     course.SequentialChapters,
+			HideUnreleasedChapters: course.HideUnreleasedChapters,
+			ProgressWeighting:      course.ProgressWeighting,
+			Categories:             course.Categories,
+			Tags:                   course.Tags,
+			Difficulty:             course.Difficulty,
+			Language:               course.Language,
+			Thumbnail:              course.Thumbnail,
+			Modules:                []coursepack.Module{},
+		}
+		for _, id := range course.PrerequisiteIDs {
+			if slug, ok := slugsByID[id]; ok {
+				manifest.Prerequisites = append(manifest.Prerequisites, slug)
+			}
+		}
+		file, err := s.exportAsset(ctx, bundle, course.Thumbnail, path.Join("media", course.Slug, "thumbnail"))
+		if err != nil {
+			return nil, err
+		}
+		if file != "" {
+			manifest.ThumbnailFile, manifest.Thumbnail = file, ""
+		}
+		if len(course.AuthorIDs) > 0 {
+			authors, err := s.userRepo.FindByIDs(course.AuthorIDs)
+			if err != nil {
+				return nil, err
+			}
+			emails := make(map[primitive.ObjectID]string, len(authors))
+			for _, author := range authors {
+				emails[author.ID] = author.Email
+			}
+			for _, id := range course.AuthorIDs {
+				if email, ok := emails[id]; ok {
+					manifest.Authors = append(manifest.Authors, email)
+				}
+			}
+		}
+
+		for _, module := range orderedModules(content) {
+			moduleManifest := coursepack.Module{Slug: module.Slug, Title: module.Title, Chapters: []coursepack.Chapter{}}
+			for _, chapter := range module.Chapters {
+				chapterManifest := coursepack.Chapter{Slug: chapter.Slug, Title: chapter.Title, DurationMins: chapter.DurationMins, Release: releaseManifest(chapter.Release), Components: []coursepack.Component{}}
+				for _, component := range chapter.Components {
+					componentManifest := coursepack.Component{
+						Key:             component.Key,
+						Type:            component.Type,
+						Title:           component.Title,
+						URL:             component.URL,
+						Quiz:            component.QuizID,
+						XP:              component.XP,
+						Optional:        component.Optional,
+						PassingScore:    component.PassingScore,
+						MinWatchPercent: component.MinWatchPercent,
+						MinReadSeconds:  component.MinReadSeconds,
+					}
+					file, err := s.exportAsset(ctx, bundle, component.URL, path.Join("media", course.Slug, chapter.Slug, component.Key))
+					if err != nil {
+						return nil, err
+					}
+					if file != "" {
+						componentManifest.File, componentManifest.URL = file, ""
+					}
+					if component.QuizID != "" && !exported[component.QuizID] {
+						exported[component.QuizID] = true
+						quiz, err := s.quizRepo.FindBySlug(ctx, component.QuizID)
+						if err != nil {
+							return nil, err
+						}
+						if quiz != nil {
+							bundle.Quizzes = append(bundle.Quizzes, quizManifest(quiz))
+						}
+					}
+					chapterManifest.Components = append(chapterManifest.Components, componentManifest)
+				}
+				moduleManifest.Chapters = append(moduleManifest.Chapters, chapterManifest)
+			}
+			manifest.Modules = append(manifest.Modules, moduleManifest)
+		}
+		bundle.Courses = append(bundle.Courses, manifest)
+	}
+	return bundle, nil
+}
+
+// exportAsset puts the media asset a URL links into the bundle below dir
+// and returns its bundle path, or "" for URLs that link no asset of the
+// organization
+func (s *coursePackService) exportAsset(ctx context.Context, bundle *coursepack.Bundle, url, dir string) (string, error) {
+	if !strings.HasPrefix(url, AssetURLPrefix) {
+		return "", nil
+	}
+	name, data, err := s.assetService.OpenFile(ctx, url)
+	if errors.Is(err, ErrAssetNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	file := path.Join(dir, name)
+	bundle.Files[file] = data
+	return file, nil
+}
+
+// bundleAssets turns the files of a bundle into media assets of the
+// organization. Files the organization has already link the existing
+// assets; the others are stored by save.
+type bundleAssets struct {
+	assetService MediaAssetService
+	bundle       *coursepack.Bundle
+	authorID     primitive.ObjectID
+	refs         map[string]string
+	byChecksum   map[string]*models.MediaAsset
+	pending      []*models.MediaAsset
+	pendingData  [][]byte
+}
+
+func newBundleAssets(assetService MediaAssetService, bundle *coursepack.Bundle, authorID primitive.ObjectID) *bundleAssets {
+	return &bundleAssets{assetService: assetService, bundle: bundle, authorID: authorID, refs: map[string]string{}, byChecksum: map[string]*models.MediaAsset{}}
+}
+
+// ref returns the ref of the asset the bundle file at filePath becomes
+func (a *bundleAssets) ref(ctx context.Context, filePath string) (string, error) {
+	if ref, ok := a.refs[filePath]; ok {
+		return ref, nil
+	}
+	data, _ := a.bundle.File(filePath)
+	asset, stored, err := a.assetService.PrepareFile(ctx, a.authorID, filePath, data)
+	if errors.Is(err, ErrAssetTypeNotAllowed) || errors.Is(err, ErrAssetTooLarge) {
+		return "", fmt.Errorf("%w: file %q: %v", ErrInvalidCourseContent, filePath, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	// The same content under two paths is stored once
+	if prepared, ok := a.byChecksum[asset.SHA256]; ok {
+		asset = prepared
+	} else if !stored {
+		a.byChecksum[asset.SHA256] = asset
+		a.pending = append(a.pending, asset)
+		a.pendingData = append(a.pendingData, data)
+	}
+	a.refs[filePath] = AssetURLPrefix + asset.ID.Hex()
+	return a.refs[filePath], nil
+}
+
+// save stores the assets that are new to the organization
+func (a *bundleAssets) save(ctx context.Context) error {
+	for i, asset := range a.pending {
+		if err := a.assetService.SaveFile(ctx, asset, a.pendingData[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coursePlan is what importing one course manifest does
+type coursePlan struct {
+	manifest *coursepack.Course
+	// course is nil for courses the import creates
+	course  *models.Course
+	body    CourseContentBody
+	content models.CourseContent
+	// catalog holds the catalog metadata the course gets
+	catalog models.Course
+	result  CourseImportResult
+}
+
+// Import creates or updates the courses and quizzes of the bundle. Course
+// content goes into drafts, which are published too with options.Publish.
+// Everything is checked before anything is written, so a bundle with a
+// problem changes nothing; problems are reported as a *coursepack.ValidationError.
+func (s *coursePackService) Import(ctx context.Context, authorID primitive.ObjectID, bundle *coursepack.Bundle, options ImportOptions) (*ImportReport, error) {
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	report := &ImportReport{DryRun: options.DryRun, Courses: []CourseImportResult{}, Quizzes: []QuizImportResult{}}
+
+	courses, err := s.courseRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]*models.Course, len(courses))
+	slugsByID := make(map[primitive.ObjectID]string, len(courses))
+	for i := range courses {
+		bySlug[courses[i].Slug] = &courses[i]
+		slugsByID[courses[i].ID] = courses[i].Slug
+	}
+
+	var problems []string
+	assets := newBundleAssets(s.assetService, bundle, authorID)
+	plans := make([]*coursePlan, 0, len(bundle.Courses))
+	for i := range bundle.Courses {
+		manifest := &bundle.Courses[i]
+		plan, err := s.planCourse(ctx, assets, manifest, bySlug[manifest.Slug], slugsByID)
+		if errors.Is(err, ErrInvalidCourseContent) {
+			problems = append(problems, fmt.Sprintf("course %q: %v", manifest.Slug, err))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if options.Publish {
+			if err := validateForPublish(plan.content); err != nil {
+				problems = append(problems, fmt.Sprintf("course %q cannot be published: %v", manifest.Slug, err))
+			}
+		}
+		plans = append(plans, plan)
+	}
+	problems = append(problems, checkImportedPrerequisites(bundle, bySlug, slugsByID, options.Publish)...)
+	if len(problems) > 0 {
+		return nil, &coursepack.ValidationError{Problems: problems}
+	}
+
+	quizzes := make([]*models.Quiz, 0, len(bundle.Quizzes))
+	for _, manifest := range bundle.Quizzes {
+		quiz := quizFromManifest(manifest)
+		existing, err := s.quizRepo.FindBySlug(ctx, quiz.Slug)
+		if err != nil {
+			return nil, err
+		}
+		action := ImportCreated
+		if existing != nil {
+			action = ImportUpdated
+			if existing.Title == quiz.Title && existing.PassingScore == quiz.PassingScore && reflect.DeepEqual(existing.Questions, quiz.Questions) {
+				action = ImportUnchanged
+			}
+		}
+		report.Quizzes = append(report.Quizzes, QuizImportResult{Slug: quiz.Slug, Action: action})
+		if action != ImportUnchanged {
+			quizzes = append(quizzes, quiz)
+		}
+	}
+
+	if options.DryRun {
+		for _, plan := range plans {
+			report.Courses = append(report.Courses, plan.result)
+		}
+		return report, nil
+	}
+
+	for _, quiz := range quizzes {
+		if err := s.quizRepo.Upsert(ctx, quiz); err != nil {
+			return nil, err
+		}
+	}
+	if err := assets.save(ctx); err != nil {
+		return nil, err
+	}
+
+	// Content first, so prerequisites can refer to courses created by this import
+	for _, plan := range plans {
+		switch {
+		case plan.course == nil:
+			draft, err := s.authoringService.CreateCourse(ctx, authorID, CreateCourseInput{Slug: plan.manifest.Slug, CourseContentBody: plan.body})
+			if err != nil {
+				return nil, fmt.Errorf("course %q: %w", plan.manifest.Slug, err)
+			}
+			plan.course = &models.Course{ID: draft.CourseID, Slug: plan.manifest.Slug, AuthorIDs: courseAuthors(authorID)}
+			bySlug[plan.manifest.Slug] = plan.course
+		case plan.result.Action == ImportUpdated:
+			if _, err := s.authoringService.SaveDraft(ctx, authorID, plan.course.ID, plan.body); err != nil {
+				return nil, fmt.Errorf("course %q: %w", plan.manifest.Slug, err)
+			}
+		}
+		courseID := plan.course.ID
+		plan.result.CourseID = &courseID
+	}
+
+	for _, plan := range plans {
+		if plan.result.SettingsChanged {
+			settings := &models.Course{
+				ID:                     plan.course.ID,
+				SequentialChapters:     plan.manifest.SequentialChapters,
+				HideUnreleasedChapters: plan.manifest.HideUnreleasedChapters,
+				ProgressWeighting:      plan.manifest.ProgressWeighting,
+				PrerequisiteIDs:        []primitive.ObjectID{},
+				Categories:             plan.catalog.Categories,
+				Tags:                   plan.catalog.Tags,
+				Difficulty:             plan.catalog.Difficulty,
+				Language:               plan.catalog.Language,
+				Thumbnail:              plan.catalog.Thumbnail,
+				AuthorIDs:              plan.catalog.AuthorIDs,
+			}
+			if settings.AuthorIDs == nil {
+				settings.AuthorIDs = plan.course.AuthorIDs
+			}
+			for _, slug := range plan.manifest.Prerequisites {
+				settings.PrerequisiteIDs = append(settings.PrerequisiteIDs, bySlug[slug].ID)
+			}
+			if err := s.courseRepo.UpdateSettings(ctx, settings); err != nil {
+				return nil, err
+			}
+		}
+
+		if options.Publish {
+			note := "Imported from a course bundle"
+			if bundle.Name != "" {
+				note = fmt.Sprintf("Imported from course bundle %q", bundle.Name)
+			}
+			version, err := s.authoringService.Publish(ctx, authorID, plan.course.ID, PublishInput{Note: note, Force: true})
+			switch {
+			case err == nil:
+				plan.result.PublishedVersion = version.Version
+			case errors.Is(err, ErrNoDraft), errors.Is(err, ErrNoChanges):
+				// Already live as it is in the bundle
+			default:
+				return nil, fmt.Errorf("course %q: %w", plan.manifest.Slug, err)
+			}
+		}
+		report.Courses = append(report.Courses, plan.result)
+	}
+	return report, nil
+}
+
+// planCourse works out the content the course gets from its manifest and how
+// that differs from what the course has now. Modules and chapters get the
+// IDs the course last had them under by slug.
+func (s *coursePackService) planCourse(ctx context.Context, assets *bundleAssets, manifest *coursepack.Course, course *models.Course, slugsByID map[primitive.ObjectID]string) (*coursePlan, error) {
+	plan := &coursePlan{manifest: manifest, course: course, result: CourseImportResult{Slug: manifest.Slug, Title: manifest.Title}}
+
+	ids := map[string]primitive.ObjectID{}
+	known := map[primitive.ObjectID]string{}
+	current := models.CourseContent{}
+	if course != nil {
+		versions, err := s.versionRepo.FindByCourse(ctx, course.ID)
+		if err != nil {
+			return nil, err
+		}
+		// The draft wins over the published content, which wins over older
+		// versions; slugs of modules and chapters are separate namespaces
+		var contents []models.CourseContent
+		if course.Draft != nil {
+			contents = append(contents, course.Draft.CourseContent)
+		}
+		contents = append(contents, course.Content())
+		for _, version := range versions {
+			contents = append(contents, version.CourseContent)
+		}
+		claimed := map[primitive.ObjectID]bool{}
+		claim := func(key string, id primitive.ObjectID) {
+			if _, taken := ids[key]; !taken && !claimed[id] {
+				ids[key] = id
+				claimed[id] = true
+			}
+		}
+		for _, content := range contents {
+			for _, module := range content.Modules {
+				known[module.ID] = module.Slug
+				claim("module/"+module.Slug, module.ID)
+				for _, chapter := range module.Chapters {
+					known[chapter.ID] = chapter.Slug
+					claim("chapter/"+chapter.Slug, chapter.ID)
+				}
+			}
+		}
+
+		if course.IsPublished() {
+			current = course.Content()
+		}
+		if course.Draft != nil {
+			current = course.Draft.CourseContent
+		}
+	}
+
+	plan.body = CourseContentBody{Title: manifest.Title, Description: manifest.Description, Modules: []ModuleBody{}}
+	for _, module := range manifest.Modules {
+		moduleBody := ModuleBody{Slug: module.Slug, Title: module.Title, Chapters: []ChapterBody{}}
+		if id, ok := ids["module/"+module.Slug]; ok {
+			moduleBody.ID = id.Hex()
+		}
+		for _, chapter := range module.Chapters {
+			chapterBody := ChapterBody{Slug: chapter.Slug, Title: chapter.Title, DurationMins: chapter.DurationMins, Components: []ComponentBody{}}
+			if chapter.Release != nil {
+				chapterBody.Release = &ChapterReleaseBody{Rule: chapter.Release.Rule, At: chapter.Release.At, OffsetDays: chapter.Release.OffsetDays}
+			}
+			if id, ok := ids["chapter/"+chapter.Slug]; ok {
+				chapterBody.ID = id.Hex()
+			}
+			for _, component := range chapter.Components {
+				componentBody := ComponentBody{
+					Key:             component.Key,
+					Type:            component.Type,
+					Title:           component.Title,
+					URL:             component.URL,
+					QuizID:          component.Quiz,
+					XP:              component.XP,
+					Optional:        component.Optional,
+					PassingScore:    component.PassingScore,
+					MinWatchPercent: component.MinWatchPercent,
+					MinReadSeconds:  component.MinReadSeconds,
+				}
+				if component.File != "" {
+					ref, err := assets.ref(ctx, component.File)
+					if err != nil {
+						return nil, err
+					}
+					componentBody.URL = ref
+				}
+				chapterBody.Components = append(chapterBody.Components, componentBody)
+			}
+			moduleBody.Chapters = append(moduleBody.Chapters, chapterBody)
+		}
+		plan.body.Modules = append(plan.body.Modules, moduleBody)
+	}
+
+	content, err := parseCourseContent(plan.body, known)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.planCatalog(ctx, assets, plan); err != nil {
+		return nil, err
+	}
+	plan.content = content
+	plan.result.Changes = diffCourseContent(current, content)
+	switch {
+	case course == nil:
+		plan.result.Action = ImportCreated
+	case plan.result.Changes.HasChanges():
+		plan.result.Action = ImportUpdated
+	default:
+		plan.result.Action = ImportUnchanged
+	}
+
+	catalog := &plan.catalog
+	if course == nil {
+		plan.result.SettingsChanged = manifest.SequentialChapters || manifest.HideUnreleasedChapters || manifest.ProgressWeighting != "" || len(manifest.Prerequisites) > 0 ||
+			len(catalog.Categories) > 0 || len(catalog.Tags) > 0 || catalog.Difficulty != "" || catalog.Language != "" || catalog.Thumbnail != "" || catalog.AuthorIDs != nil
+	} else {
+		var prerequisites []string
+		for _, id := range course.PrerequisiteIDs {
+			prerequisites = append(prerequisites, slugsByID[id])
+		}
+		plan.result.SettingsChanged = course.SequentialChapters != manifest.SequentialChapters ||
+			course.HideUnreleasedChapters != manifest.HideUnreleasedChapters ||
+			course.ProgressWeighting != manifest.ProgressWeighting ||
+			!sameStrings(prerequisites, manifest.Prerequisites) ||
+			!sameStrings(normalizeCategories(course.Categories), catalog.Categories) ||
+			!sameStrings(normalizeTags(course.Tags), catalog.Tags) ||
+			course.Difficulty != catalog.Difficulty ||
+			course.Language != catalog.Language ||
+			course.Thumbnail != catalog.Thumbnail ||
+			catalog.AuthorIDs != nil && !reflect.DeepEqual(course.AuthorIDs, catalog.AuthorIDs)
+	}
+	return plan, nil
+}
+
+// planCatalog works out the catalog metadata of the course from its
+// manifest. Authors are looked up by email in the organization.
+func (s *coursePackService) planCatalog(ctx context.Context, assets *bundleAssets, plan *coursePlan) error {
+	manifest := plan.manifest
+	catalog := &plan.catalog
+	catalog.Categories = normalizeCategories(manifest.Categories)
+	catalog.Tags = normalizeTags(manifest.Tags)
+	catalog.Difficulty = manifest.Difficulty
+	language, err := normalizeLanguage(manifest.Language)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCourseContent, err)
+	}
+	catalog.Language = language
+	catalog.Thumbnail = manifest.Thumbnail
+	if manifest.ThumbnailFile != "" {
+		ref, err := assets.ref(ctx, manifest.ThumbnailFile)
+		if err != nil {
+			return err
+		}
+		catalog.Thumbnail = ref
+	}
+	if err := checkThumbnail(catalog.Thumbnail); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCourseContent, err)
+	}
+
+	if len(manifest.Authors) == 0 {
+		return nil
+	}
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return err
+	}
+	catalog.AuthorIDs = []primitive.ObjectID{}
+	for _, email := range manifest.Authors {
+		user, err := s.userRepo.FindByEmail(strings.TrimSpace(email))
+		if errors.Is(err, mongo.ErrNoDocuments) || err == nil && user.OrganizationID != organizationID {
+			return fmt.Errorf("%w: author %q is not a user of the organization", ErrInvalidCourseContent, email)
+		}
+		if err != nil {
+			return err
+		}
+		if user.Role != models.RoleInstructor && user.Role != models.RoleAdmin {
+			return fmt.Errorf("%w: author %q is not an instructor or admin", ErrInvalidCourseContent, email)
+		}
+		if !slices.Contains(catalog.AuthorIDs, user.ID) {
+			catalog.AuthorIDs = append(catalog.AuthorIDs, user.ID)
+		}
+	}
+	return nil
+}
+
+// checkImportedPrerequisites makes sure every prerequisite is a course that
+// will be published after the import and that no chain of prerequisites
+// leads back to where it started.
+func checkImportedPrerequisites(bundle *coursepack.Bundle, existing map[string]*models.Course, slugsByID map[primitive.ObjectID]string, publish bool) []string {
+	var problems []string
+	graph := map[string][]string{}
+	for slug, course := range existing {
+		for _, id := range course.PrerequisiteIDs {
+			graph[slug] = append(graph[slug], slugsByID[id])
+		}
+	}
+	inBundle := map[string]bool{}
+	for _, manifest := range bundle.Courses {
+		inBundle[manifest.Slug] = true
+		graph[manifest.Slug] = manifest.Prerequisites
+	}
+
+	for _, manifest := range bundle.Courses {
+		for _, slug := range manifest.Prerequisites {
+			course, exists := existing[slug]
+			switch {
+			case !exists && !inBundle[slug]:
+				problems = append(problems, fmt.Sprintf("course %q: prerequisite %q is neither in the bundle nor an existing course", manifest.Slug, slug))
+			case !(exists && course.IsPublished()) && !(inBundle[slug] && publish):
+				problems = append(problems, fmt.Sprintf("course %q: prerequisite %q is not published", manifest.Slug, slug))
+			}
+		}
+
+		visited := map[string]bool{}
+		stack := append([]string{}, manifest.Prerequisites...)
+		for len(stack) > 0 {
+			slug := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if slug == manifest.Slug {
+				problems = append(problems, fmt.Sprintf("course %q: prerequisites cannot form a cycle", manifest.Slug))
+				break
+			}
+			if visited[slug] {
+				continue
+			}
+			visited[slug] = true
+			stack = append(stack, graph[slug]...)
+		}
+	}
+	return problems
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func quizManifest(quiz *models.Quiz) coursepack.Quiz {
+	manifest := coursepack.Quiz{Slug: quiz.Slug, Title: quiz.Title, PassingScore: quiz.PassingScore, Questions: []coursepack.Question{}}
+	for _, question := range quiz.Questions {
+		manifest.Questions = append(manifest.Questions, coursepack.Question{
+			Prompt:      question.Prompt,
+			Choices:     question.Choices,
+			Answer:      question.Answer,
+			Explanation: question.Explanation,
+		})
+	}
+	return manifest
+}
+
+func quizFromManifest(manifest coursepack.Quiz) *models.Quiz {
+	quiz := &models.Quiz{Slug: manifest.Slug, Title: manifest.Title, PassingScore: manifest.PassingScore, Questions: []models.QuizQuestion{}, UpdatedAt: time.Now()}
+	for _, question := range manifest.Questions {
+		quiz.Questions = append(quiz.Questions, models.QuizQuestion{
+			Prompt:      question.Prompt,
+			Choices:     question.Choices,
+			Answer:      question.Answer,
+			Explanation: question.Explanation,
+		})
+	}
+	return quiz
+}
+
+func releaseManifest(release *models.ChapterRelease) *coursepack.Release {
+	if release == nil {
+		return nil
+	}
+	return &coursepack.Release{Rule: release.Rule, At: release.At, OffsetDays: release.OffsetDays}
+}
+
+// MoveBundledMedia moves each linked file once per organization; files
+// missing from legacy and files assets cannot be are logged and keep their links.
+func (s *coursePackService) MoveBundledMedia(legacy *media.DirStore) error {
+	prefix := legacy.BaseURL() + "/"
+	linked, err := s.courseRepo.FindLinkedURLs(prefix)
+	if err != nil {
+		return err
+	}
+	versionLinked, err := s.versionRepo.FindLinkedURLs(prefix)
+	if err != nil {
+		return err
+	}
+
+	moved := map[repositories.LinkedURL]bool{}
+	for _, link := range append(linked, versionLinked...) {
+		if moved[link] {
+			continue
+		}
+		moved[link] = true
+		name, data, err := legacy.Open(link.URL)
+		if errors.Is(err, media.ErrNotStored) {
+			log.Printf("Course media %s is not in the media directory; courses keep linking it", link.URL)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		ctx := tenant.WithOrganization(context.Background(), link.OrganizationID)
+		asset, stored, err := s.assetService.PrepareFile(ctx, primitive.NilObjectID, name, data)
+		if errors.Is(err, ErrAssetTypeNotAllowed) || errors.Is(err, ErrAssetTooLarge) {
+			log.Printf("Course media %s cannot become a media asset (%v); courses keep linking it", link.URL, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !stored {
+			if err := s.assetService.SaveFile(ctx, asset, data); err != nil {
+				return err
+			}
+		}
+		ref := AssetURLPrefix + asset.ID.Hex()
+		if err := s.courseRepo.ReplaceLinkedURL(link.OrganizationID, link.URL, ref); err != nil {
+			return err
+		}
+		if err := s.versionRepo.ReplaceLinkedURL(link.OrganizationID, link.URL, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasSCORMComponents reports whether content launches SCORM packages, whose
+// files live in the SCORM package store rather than in the asset store
+func hasSCORMComponents(content models.CourseContent) bool {
+	for _, module := range content.Modules {
+		for _, chapter := range module.Chapters {
+			for _, component := range chapter.Components {
+				if component.Type == models.ComponentSCORM {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
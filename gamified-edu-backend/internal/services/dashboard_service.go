@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"gamified-edu-backend/internal/models"
 	"gamified-edu-backend/internal/repositories"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,7 +10,7 @@ import (
 )
 
 type DashboardService interface {
-	GetDashboardData(userID primitive.ObjectID) (*DashboardResponse, error)
+	GetDashboardData(ctx context.Context, userID primitive.ObjectID) (*DashboardResponse, error)
 }
 
 type dashboardService struct {
@@ -31,7 +32,7 @@ type DashboardResponse struct {
 	Level            int     `json:"level"`
 }
 
-func (s *dashboardService) GetDashboardData(userID primitive.ObjectID) (*DashboardResponse, error) {
+func (s *dashboardService) GetDashboardData(ctx context.Context, userID primitive.ObjectID) (*DashboardResponse, error) {
 	log.Printf("Getting dashboard data for user ID: %v", userID)
 	
 	// --- Get User Data for XP and Level ---
@@ -52,7 +53,7 @@ func (s *dashboardService) GetDashboardData(userID primitive.ObjectID) (*Dashboa
 	}
 
 	// --- Get Metrics ---
-	totalChapters, err := s.dashboardRepo.GetTotalChapterCount()
+	totalChapters, err := s.dashboardRepo.GetTotalChapterCount(ctx)
 	if err != nil { 
 		log.Printf("Error getting total chapters, using default: %v", err)
 		totalChapters = 6 // More realistic default
@@ -60,7 +61,7 @@ func (s *dashboardService) GetDashboardData(userID primitive.ObjectID) (*Dashboa
 		log.Printf("Total chapters: %d", totalChapters)
 	}
 
-	completedChapters, err := s.progressRepo.CountCompletedChapters(userID, primitive.NilObjectID) // Pass NilObjectID to count all
+	completedChapters, err := s.progressRepo.CountCompletedChapters(ctx, userID, primitive.NilObjectID) // Pass NilObjectID to count all
 	if err != nil { 
 		log.Printf("Error getting completed chapters, using default: %v", err)
 		completedChapters = 0 // New users have 0 completed chapters
@@ -74,7 +75,7 @@ func (s *dashboardService) GetDashboardData(userID primitive.ObjectID) (*Dashboa
 	}
 
 	// --- Calculate Streak ---
-	timestamps, err := s.dashboardRepo.GetRecentActivityTimestamps(userID)
+	timestamps, err := s.dashboardRepo.GetRecentActivityTimestamps(ctx, userID)
 	if err != nil { 
 		log.Printf("Error getting activity timestamps, using default: %v", err)
 		timestamps = []time.Time{} // Empty slice for default
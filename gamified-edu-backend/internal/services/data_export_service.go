@@ -0,0 +1,335 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/tenant"
+	"gamified-edu-backend/pkg"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// dataExportTTL is how long a finished export can be downloaded.
+	dataExportTTL = 7 * 24 * time.Hour
+	// dataExportFormatVersion is bumped whenever the archive layout changes.
+	dataExportFormatVersion = 1
+	dataExportDownloadPath  = "/api/v1/exports/%s/download"
+)
+
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+	ErrDataExportNotReady = errors.New("data export is not ready for download")
+)
+
+type DataExportService interface {
+	// RequestExport exports the user's data in the organization
+	RequestExport(userID, organizationID, requestedBy primitive.ObjectID) (*DataExportResponse, error)
+	// ListExports lists a page of the user's exports in the organization, newest first
+	ListExports(userID, organizationID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[DataExportResponse], error)
+	GetExport(userID, organizationID, exportID primitive.ObjectID) (*DataExportResponse, error)
+	OpenDownload(exportID primitive.ObjectID, path string, query url.Values) (*models.DataExport, error)
+	WriteArchive(userID, organizationID primitive.ObjectID, w io.Writer) error
+	PurgeUser(userID primitive.ObjectID) error
+}
+
+type dataExportService struct {
+	exportRepo   repositories.DataExportRepository
+	userDataRepo repositories.UserDataRepository
+	exportDir    string
+}
+
+func NewDataExportService(exportRepo repositories.DataExportRepository, userDataRepo repositories.UserDataRepository) DataExportService {
+	exportDir := os.Getenv("DATA_EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = filepath.Join("data", "exports")
+	}
+	return &dataExportService{exportRepo, userDataRepo, exportDir}
+}
+
+type DataExportResponse struct {
+	ID          primitive.ObjectID `json:"id"`
+	Status      string             `json:"status"`
+	Error       string             `json:"error,omitempty"`
+	FileSize    int64              `json:"file_size,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time         `json:"expires_at,omitempty"`
+	DownloadURL string             `json:"download_url,omitempty"`
+}
+
+type dataExportManifest struct {
+	FormatVersion int                      `json:"format_version"`
+	UserID        primitive.ObjectID       `json:"user_id"`
+	GeneratedAt   time.Time                `json:"generated_at"`
+	Files         []dataExportManifestFile `json:"files"`
+}
+
+type dataExportManifestFile struct {
+	Path          string `json:"path"`
+	Collection    string `json:"collection"`
+	DocumentCount int    `json:"document_count"`
+	SHA256        string `json:"sha256"`
+}
+
+// RequestExport queues a new export and builds it in the background. If the
+// user already has an export in progress, that one is returned instead.
+func (s *dataExportService) RequestExport(userID, organizationID, requestedBy primitive.ObjectID) (*DataExportResponse, error) {
+	s.cleanupExpired()
+
+	existing, err := s.exportRepo.FindUnfinishedByUser(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return toDataExportResponse(existing), nil
+	}
+
+	export := &models.DataExport{
+		UserID:         userID,
+		OrganizationID: organizationID,
+		RequestedBy:    requestedBy,
+		Status:         models.DataExportPending,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.exportRepo.Create(export); err != nil {
+		return nil, err
+	}
+
+	go s.run(*export)
+	return toDataExportResponse(export), nil
+}
+
+type dataExportCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+func (s *dataExportService) ListExports(userID, organizationID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[DataExportResponse], error) {
+	var after dataExportCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	exports, err := s.exportRepo.FindPageByUser(organizationID, userID, after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]DataExportResponse, 0, len(exports))
+	for i := range exports {
+		responses = append(responses, *toDataExportResponse(&exports[i]))
+	}
+	result := pkg.NewPage(responses, limit, func(export DataExportResponse) any { return dataExportCursor{ID: export.ID} })
+	return &result, nil
+}
+
+func (s *dataExportService) GetExport(userID, organizationID, exportID primitive.ObjectID) (*DataExportResponse, error) {
+	export, err := s.exportRepo.FindByID(exportID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, err
+	}
+	if export.UserID != userID || export.OrganizationID != organizationID {
+		return nil, ErrDataExportNotFound
+	}
+	return toDataExportResponse(export), nil
+}
+
+// OpenDownload verifies the signed download link and returns the finished export.
+func (s *dataExportService) OpenDownload(exportID primitive.ObjectID, path string, query url.Values) (*models.DataExport, error) {
+	if err := pkg.VerifySignedPath(path, query); err != nil {
+		return nil, err
+	}
+	export, err := s.exportRepo.FindByID(exportID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, err
+	}
+	if export.Status != models.DataExportCompleted || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, ErrDataExportNotReady
+	}
+	return export, nil
+}
+
+// WriteArchive writes a zip archive with one JSON file per collection and a
+// manifest.json describing the files and their checksums.
+func (s *dataExportService) WriteArchive(userID, organizationID primitive.ObjectID, w io.Writer) error {
+	sets, err := s.userDataRepo.ExportAllForUser(tenant.WithOrganization(context.Background(), organizationID), userID)
+	if err != nil {
+		return err
+	}
+
+	manifest := dataExportManifest{
+		FormatVersion: dataExportFormatVersion,
+		UserID:        userID,
+		GeneratedAt:   time.Now().UTC(),
+	}
+	contents := make([][]byte, 0, len(sets))
+	for _, set := range sets {
+		data, err := json.MarshalIndent(set.Documents, "", "  ")
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		path := set.Collection + ".json"
+		if set.Collection == "users" {
+			path = "profile.json"
+		}
+		manifest.Files = append(manifest.Files, dataExportManifestFile{
+			Path:          path,
+			Collection:    set.Collection,
+			DocumentCount: len(set.Documents),
+			SHA256:        hex.EncodeToString(sum[:]),
+		})
+		contents = append(contents, data)
+	}
+
+	archive := zip.NewWriter(w)
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipEntry(archive, "manifest.json", manifestData); err != nil {
+		return err
+	}
+	for i, file := range manifest.Files {
+		if err := writeZipEntry(archive, file.Path, contents[i]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// PurgeUser removes the user's export files from disk, e.g. on account deletion.
+func (s *dataExportService) PurgeUser(userID primitive.ObjectID) error {
+	return os.RemoveAll(filepath.Join(s.exportDir, userID.Hex()))
+}
+
+func (s *dataExportService) run(export models.DataExport) {
+	export.Status = models.DataExportRunning
+	if err := s.exportRepo.Update(&export); err != nil {
+		log.Printf("Could not start data export %s: %v", export.ID.Hex(), err)
+		return
+	}
+
+	path, size, err := s.buildFile(export)
+	now := time.Now()
+	export.CompletedAt = &now
+	if err != nil {
+		log.Printf("Data export %s failed: %v", export.ID.Hex(), err)
+		export.Status = models.DataExportFailed
+		export.Error = "export could not be generated"
+	} else {
+		expiresAt := now.Add(dataExportTTL)
+		export.Status = models.DataExportCompleted
+		export.FilePath = path
+		export.FileSize = size
+		export.ExpiresAt = &expiresAt
+	}
+	if err := s.exportRepo.Update(&export); err != nil {
+		log.Printf("Could not save data export %s: %v", export.ID.Hex(), err)
+	}
+}
+
+func (s *dataExportService) buildFile(export models.DataExport) (string, int64, error) {
+	dir := filepath.Join(s.exportDir, export.UserID.Hex())
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(dir, export.ID.Hex()+".zip")
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.WriteArchive(export.UserID, export.OrganizationID, file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return "", 0, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", 0, err
+	}
+	// Only expose complete archives under the final name
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+// cleanupExpired deletes archives whose download window has closed.
+func (s *dataExportService) cleanupExpired() {
+	expired, err := s.exportRepo.FindExpired(time.Now())
+	if err != nil {
+		log.Printf("Could not look up expired data exports: %v", err)
+		return
+	}
+	for i := range expired {
+		export := expired[i]
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("Could not remove data export file %s: %v", export.FilePath, err)
+				continue
+			}
+		}
+		export.Status = models.DataExportExpired
+		export.FilePath = ""
+		if err := s.exportRepo.Update(&export); err != nil {
+			log.Printf("Could not mark data export %s as expired: %v", export.ID.Hex(), err)
+		}
+	}
+}
+
+func writeZipEntry(archive *zip.Writer, name string, data []byte) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(data)
+	return err
+}
+
+func toDataExportResponse(export *models.DataExport) *DataExportResponse {
+	response := &DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		Error:       export.Error,
+		FileSize:    export.FileSize,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+	if export.Status == models.DataExportCompleted && export.ExpiresAt != nil && time.Now().Before(*export.ExpiresAt) {
+		response.DownloadURL = pkg.SignPath(fmt.Sprintf(dataExportDownloadPath, export.ID.Hex()), *export.ExpiresAt)
+	}
+	return response
+}
@@ -0,0 +1,549 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Gradebook sort keys.
+const (
+	GradebookSortName      = "name"
+	GradebookSortEmail     = "email"
+	GradebookSortXP        = "xp"
+	GradebookSortCompleted = "completed"
+	GradebookSortJoinedAt  = "joined_at"
+)
+
+// Gradebook export formats.
+const (
+	GradebookFormatCSV  = "csv"
+	GradebookFormatXLSX = "xlsx"
+)
+
+const (
+	DefaultGradebookPageSize = 25
+	MaxGradebookPageSize     = 100
+	// gradebookExportBatchSize is how many students' progress an export holds in memory at once.
+	gradebookExportBatchSize = 200
+)
+
+var ErrUnsupportedExportFormat = errors.New("unsupported export format, use csv or xlsx")
+
+type GradebookService interface {
+	GetGradebook(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, query GradebookQuery) (*GradebookResponse, error)
+	ExportGradebook(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, query GradebookQuery, format string) (*GradebookExport, error)
+}
+
+// GradebookQuery filters, sorts and pages the students of a gradebook.
+type GradebookQuery struct {
+	// CourseID limits the chapters to one course; by default all courses assigned to the classroom are shown
+	CourseID primitive.ObjectID
+	// Search matches part of a student's name or email
+	Search   string
+	Sort     string
+	Desc     bool
+	Page     int
+	PageSize int
+}
+
+type GradebookChapter struct {
+	CourseID      primitive.ObjectID `json:"course_id"`
+	CourseTitle   string             `json:"course_title"`
+	ChapterID     primitive.ObjectID `json:"chapter_id"`
+	ChapterNumber int                `json:"chapter_number"`
+	Title         string             `json:"title"`
+	// Components are the chapter's activities, in the order of the cells' columns
+	Components []GradebookComponent `json:"components"`
+	quizKey    string
+}
+
+type GradebookComponent struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+}
+
+// GradebookCell is a student's progress on one chapter.
+type GradebookCell struct {
+	ChapterID primitive.ObjectID `json:"chapter_id"`
+	// Components tells per component key whether it was completed
+	Components  map[string]bool `json:"components"`
+	IsCompleted bool            `json:"is_completed"`
+	// QuizScore is the best score on the chapter's first quiz
+	QuizScore *int `json:"quiz_score,omitempty"`
+	XP        int  `json:"xp"`
+}
+
+type GradebookStudent struct {
+	UserID            primitive.ObjectID `json:"user_id"`
+	Name              string             `json:"name"`
+	Email             string             `json:"email"`
+	XP                int                `json:"xp"`
+	Level             int                `json:"level"`
+	CompletedChapters int                `json:"completed_chapters"`
+	JoinedAt          time.Time          `json:"joined_at"`
+	Cells             []GradebookCell    `json:"cells"`
+}
+
+type GradebookResponse struct {
+	ClassroomID primitive.ObjectID `json:"classroom_id"`
+	Chapters    []GradebookChapter `json:"chapters"`
+	Students    []GradebookStudent `json:"students"`
+	Page        int                `json:"page"`
+	PageSize    int                `json:"page_size"`
+	Total       int                `json:"total"`
+}
+
+// GradebookExport is a prepared export. Access checks have passed by the time
+// it is returned, so Write only fails on database or write errors.
+type GradebookExport struct {
+	Filename    string
+	ContentType string
+	write       func(w io.Writer) error
+}
+
+// Write streams the export to w.
+func (e *GradebookExport) Write(w io.Writer) error {
+	return e.write(w)
+}
+
+type gradebookService struct {
+	classroomRepo  repositories.ClassroomRepository
+	assignmentRepo repositories.AssignmentRepository
+	courseRepo     repositories.CourseRepository
+	progressRepo   repositories.ProgressRepository
+	userRepo       repositories.UserRepository
+}
+
+func NewGradebookService(classroomRepo repositories.ClassroomRepository, assignmentRepo repositories.AssignmentRepository, courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, userRepo repositories.UserRepository) GradebookService {
+	return &gradebookService{classroomRepo, assignmentRepo, courseRepo, progressRepo, userRepo}
+}
+
+// gradebook is a classroom's filtered and sorted student list with the
+// chapters to report on. Chapter progress is loaded separately, page by page.
+type gradebook struct {
+	classroom *models.Classroom
+	chapters  []GradebookChapter
+	courseIDs []primitive.ObjectID
+	rows      []gradebookRow
+}
+
+type gradebookRow struct {
+	user      *models.User
+	joinedAt  time.Time
+	completed int
+}
+
+func (s *gradebookService) GetGradebook(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, query GradebookQuery) (*GradebookResponse, error) {
+	book, err := s.load(ctx, actor, classroomID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultGradebookPageSize
+	}
+	if query.PageSize > MaxGradebookPageSize {
+		query.PageSize = MaxGradebookPageSize
+	}
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	start := (query.Page - 1) * query.PageSize
+	if start > len(book.rows) {
+		start = len(book.rows)
+	}
+	end := start + query.PageSize
+	if end > len(book.rows) {
+		end = len(book.rows)
+	}
+
+	students, err := s.students(ctx, book, book.rows[start:end])
+	if err != nil {
+		return nil, err
+	}
+	return &GradebookResponse{
+		ClassroomID: book.classroom.ID,
+		Chapters:    book.chapters,
+		Students:    students,
+		Page:        query.Page,
+		PageSize:    query.PageSize,
+		Total:       len(book.rows),
+	}, nil
+}
+
+// ExportGradebook prepares an export of every student matching query, ignoring
+// its paging. Rows are written in batches as progress is loaded.
+func (s *gradebookService) ExportGradebook(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, query GradebookQuery, format string) (*GradebookExport, error) {
+	if format != GradebookFormatCSV && format != GradebookFormatXLSX {
+		return nil, ErrUnsupportedExportFormat
+	}
+	book, err := s.load(ctx, actor, classroomID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &GradebookExport{
+		Filename:    fmt.Sprintf("gradebook-%s-%s.%s", gradebookFileSlug(book.classroom.Name), time.Now().Format("2006-01-02"), format),
+		ContentType: "text/csv; charset=utf-8",
+	}
+	if format == GradebookFormatXLSX {
+		export.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	export.write = func(w io.Writer) error {
+		var out gradebookRowWriter
+		if format == GradebookFormatXLSX {
+			xlsx, err := pkg.NewXLSXWriter(w, book.classroom.Name)
+			if err != nil {
+				return err
+			}
+			out = xlsx
+		} else {
+			out = newCSVRowWriter(w)
+		}
+		if err := s.writeRows(ctx, book, out); err != nil {
+			return err
+		}
+		return out.Close()
+	}
+	return export, nil
+}
+
+func (s *gradebookService) writeRows(ctx context.Context, book *gradebook, out gradebookRowWriter) error {
+	header := []interface{}{"Name", "Email", "XP", "Level", "Completed chapters"}
+	for _, chapter := range book.chapters {
+		label := fmt.Sprintf("%s / %d. %s", chapter.CourseTitle, chapter.ChapterNumber, chapter.Title)
+		for _, component := range chapter.Components {
+			header = append(header, label+" "+component.Key)
+		}
+		header = append(header, label+" quiz score", label+" XP")
+	}
+	if err := out.WriteRow(header); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(book.rows); start += gradebookExportBatchSize {
+		end := start + gradebookExportBatchSize
+		if end > len(book.rows) {
+			end = len(book.rows)
+		}
+		students, err := s.students(ctx, book, book.rows[start:end])
+		if err != nil {
+			return err
+		}
+		for _, student := range students {
+			row := []interface{}{student.Name, student.Email, student.XP, student.Level, student.CompletedChapters}
+			for i, cell := range student.Cells {
+				for _, component := range book.chapters[i].Components {
+					row = append(row, yesNo(cell.Components[component.Key]))
+				}
+				var score interface{}
+				if cell.QuizScore != nil {
+					score = *cell.QuizScore
+				}
+				row = append(row, score, cell.XP)
+			}
+			if err := out.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		// Hand each batch to the client instead of buffering the whole file
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load resolves the chapters and the filtered, sorted students of a gradebook
+func (s *gradebookService) load(ctx context.Context, actor ClassroomActor, classroomID primitive.ObjectID, query GradebookQuery) (*gradebook, error) {
+	classroom, err := loadManagedClassroom(ctx, s.classroomRepo, actor, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	book := &gradebook{classroom: classroom, chapters: []GradebookChapter{}}
+	if err := s.loadChapters(ctx, book, query.CourseID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.classroomRepo.FindMembers(ctx, classroom.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return book, nil
+	}
+	userIDs := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	users, err := s.userRepo.FindByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	usersByID := make(map[primitive.ObjectID]*models.User, len(users))
+	for i := range users {
+		usersByID[users[i].ID] = &users[i]
+	}
+
+	search := strings.ToLower(strings.TrimSpace(query.Search))
+	for _, member := range members {
+		user, ok := usersByID[member.UserID]
+		if !ok {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(user.DisplayNameOrFullName()), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) {
+			continue
+		}
+		book.rows = append(book.rows, gradebookRow{user: user, joinedAt: member.JoinedAt})
+	}
+
+	if len(book.rows) > 0 && len(book.chapters) > 0 {
+		rowUserIDs := make([]primitive.ObjectID, 0, len(book.rows))
+		for _, row := range book.rows {
+			rowUserIDs = append(rowUserIDs, row.user.ID)
+		}
+		chapterIDs := make([]primitive.ObjectID, 0, len(book.chapters))
+		for _, chapter := range book.chapters {
+			chapterIDs = append(chapterIDs, chapter.ChapterID)
+		}
+		completed, err := s.progressRepo.CountCompletedByUser(ctx, rowUserIDs, chapterIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range book.rows {
+			book.rows[i].completed = completed[book.rows[i].user.ID]
+		}
+	}
+
+	if err := sortGradebookRows(book.rows, query.Sort, query.Desc); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// loadChapters lists the chapters of courseID, or of every course assigned to the classroom
+func (s *gradebookService) loadChapters(ctx context.Context, book *gradebook, courseID primitive.ObjectID) error {
+	if courseID.IsZero() {
+		assignments, err := s.assignmentRepo.FindByClassroom(ctx, book.classroom.ID)
+		if err != nil {
+			return err
+		}
+		seen := map[primitive.ObjectID]bool{}
+		for _, assignment := range assignments {
+			if !seen[assignment.CourseID] {
+				seen[assignment.CourseID] = true
+				book.courseIDs = append(book.courseIDs, assignment.CourseID)
+			}
+		}
+	} else {
+		book.courseIDs = []primitive.ObjectID{courseID}
+	}
+
+	for _, id := range book.courseIDs {
+		course, err := s.courseRepo.FindByID(ctx, id)
+		if errors.Is(err, mongo.ErrNoDocuments) && courseID.IsZero() {
+			// The course of an old assignment may have been removed since
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return errors.New("course not found")
+			}
+			return err
+		}
+		for _, chapter := range course.OrderedChapters() {
+			column := GradebookChapter{
+				CourseID:      course.ID,
+				CourseTitle:   course.Title,
+				ChapterID:     chapter.ID,
+				ChapterNumber: chapter.ChapterNumber,
+				Title:         chapter.Title,
+				Components:    make([]GradebookComponent, 0, len(chapter.Components)),
+			}
+			for _, component := range chapter.Components {
+				column.Components = append(column.Components, GradebookComponent{Key: component.Key, Type: component.Type, Title: component.Title})
+				if component.Type == models.ComponentQuiz && column.quizKey == "" {
+					column.quizKey = component.Key
+				}
+			}
+			book.chapters = append(book.chapters, column)
+		}
+	}
+	return nil
+}
+
+// students builds the chapter matrix for rows with a single progress query
+func (s *gradebookService) students(ctx context.Context, book *gradebook, rows []gradebookRow) ([]GradebookStudent, error) {
+	students := make([]GradebookStudent, 0, len(rows))
+	if len(rows) == 0 {
+		return students, nil
+	}
+
+	statuses := map[primitive.ObjectID]map[primitive.ObjectID]*models.UserChapterStatus{}
+	if len(book.courseIDs) > 0 {
+		userIDs := make([]primitive.ObjectID, 0, len(rows))
+		for _, row := range rows {
+			userIDs = append(userIDs, row.user.ID)
+		}
+		progress, err := s.progressRepo.FindForUsers(ctx, userIDs, book.courseIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, status := range progress {
+			if statuses[status.UserID] == nil {
+				statuses[status.UserID] = map[primitive.ObjectID]*models.UserChapterStatus{}
+			}
+			statuses[status.UserID][status.ChapterID] = status
+		}
+	}
+
+	for _, row := range rows {
+		level := row.user.Level
+		if level < 1 {
+			level = 1
+		}
+		student := GradebookStudent{
+			UserID:            row.user.ID,
+			Name:              row.user.DisplayNameOrFullName(),
+			Email:             row.user.Email,
+			XP:                row.user.XP,
+			Level:             level,
+			CompletedChapters: row.completed,
+			JoinedAt:          row.joinedAt,
+			Cells:             make([]GradebookCell, 0, len(book.chapters)),
+		}
+		for _, chapter := range book.chapters {
+			cell := GradebookCell{ChapterID: chapter.ChapterID, Components: make(map[string]bool, len(chapter.Components))}
+			status := statuses[row.user.ID][chapter.ChapterID]
+			for _, component := range chapter.Components {
+				cell.Components[component.Key] = status != nil && status.HasCompleted(component.Key)
+			}
+			if status != nil {
+				cell.IsCompleted = status.IsChapterCompleted
+				if chapter.quizKey != "" {
+					cell.QuizScore = status.Components[chapter.quizKey].BestScore
+				}
+				cell.XP = chapterXP(status)
+			}
+			student.Cells = append(student.Cells, cell)
+		}
+		students = append(students, student)
+	}
+	return students, nil
+}
+
+// chapterXP is the XP a chapter's progress has earned, following the awards in MarkComponentAsComplete
+func chapterXP(status *models.UserChapterStatus) int {
+	xp := 0
+	for _, component := range status.Components {
+		if component.Completed {
+			xp += component.XP
+		}
+	}
+	if status.IsChapterCompleted {
+		xp += XP_CHAPTER_BONUS
+	}
+	return xp
+}
+
+func sortGradebookRows(rows []gradebookRow, key string, desc bool) error {
+	var less func(a, b *gradebookRow) bool
+	switch key {
+	case "", GradebookSortName:
+		less = func(a, b *gradebookRow) bool {
+			return strings.ToLower(a.user.DisplayNameOrFullName()) < strings.ToLower(b.user.DisplayNameOrFullName())
+		}
+	case GradebookSortEmail:
+		less = func(a, b *gradebookRow) bool { return strings.ToLower(a.user.Email) < strings.ToLower(b.user.Email) }
+	case GradebookSortXP:
+		less = func(a, b *gradebookRow) bool { return a.user.XP < b.user.XP }
+	case GradebookSortCompleted:
+		less = func(a, b *gradebookRow) bool { return a.completed < b.completed }
+	case GradebookSortJoinedAt:
+		less = func(a, b *gradebookRow) bool { return a.joinedAt.Before(b.joinedAt) }
+	default:
+		return fmt.Errorf("unknown sort %q", key)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if desc {
+			return less(&rows[j], &rows[i])
+		}
+		return less(&rows[i], &rows[j])
+	})
+	return nil
+}
+
+// gradebookRowWriter is implemented by the streaming CSV and XLSX writers.
+type gradebookRowWriter interface {
+	WriteRow(cells []interface{}) error
+	Flush() error
+	Close() error
+}
+
+type csvRowWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVRowWriter(w io.Writer) *csvRowWriter {
+	return &csvRowWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvRowWriter) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		if cell != nil {
+			record[i] = fmt.Sprint(cell)
+		}
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+// gradebookFileSlug turns a classroom name into something safe for a file name
+func gradebookFileSlug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, name)
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return "classroom"
+	}
+	return slug
+}
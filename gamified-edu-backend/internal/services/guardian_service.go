@@ -0,0 +1,499 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/mailer"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	guardianInviteCodeLength = 10
+	guardianInviteTTL        = 7 * 24 * time.Hour
+	// guardianSummaryInterval is a little under a week, so a weekly job that
+	// runs slightly early never skips a week
+	guardianSummaryInterval = 6*24*time.Hour + 12*time.Hour
+	guardianSummaryWindow   = 7 * 24 * time.Hour
+)
+
+var (
+	ErrGuardianLinkNotFound = errors.New("guardian link not found")
+	ErrGuardianLinkExists   = errors.New("a guardian link with this account already exists")
+	ErrInvalidInviteCode    = errors.New("invalid or expired invite code")
+	ErrInviteEmailMismatch  = errors.New("this invite was sent to a different email address")
+	ErrStudentNotFound      = errors.New("student not found")
+	ErrNotStudent           = errors.New("only student accounts can be linked to guardians")
+)
+
+// GuardianService links guardians to students and gives guardians a
+// read-only view of the progress of their students.
+type GuardianService interface {
+	InviteGuardian(ctx context.Context, studentID primitive.ObjectID, input InviteGuardianInput) (*GuardianLinkResponse, error)
+	ListGuardians(ctx context.Context, studentID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[GuardianLinkResponse], error)
+	ApproveLink(ctx context.Context, studentID, linkID primitive.ObjectID) (*GuardianLinkResponse, error)
+	RequestLink(ctx context.Context, guardianID primitive.ObjectID, input RequestGuardianLinkInput) (*GuardianLinkResponse, error)
+	AcceptInvite(ctx context.Context, guardianID primitive.ObjectID, code string) (*GuardianLinkResponse, error)
+	ListStudents(ctx context.Context, guardianID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[GuardianLinkResponse], error)
+	RevokeLink(ctx context.Context, userID, linkID primitive.ObjectID) error
+	GetStudentOverview(ctx context.Context, guardianID, studentID primitive.ObjectID) (*GuardianOverviewResponse, error)
+	SendWeeklySummaries(ctx context.Context) (int, error)
+}
+
+type InviteGuardianInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type RequestGuardianLinkInput struct {
+	StudentEmail string `json:"student_email" binding:"required,email"`
+}
+
+type AcceptGuardianInviteInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type GuardianLinkResponse struct {
+	ID              primitive.ObjectID `json:"id"`
+	Status          string             `json:"status"`
+	InitiatedBy     string             `json:"initiated_by"`
+	StudentID       primitive.ObjectID `json:"student_id"`
+	StudentName     string             `json:"student_name,omitempty"`
+	GuardianID      primitive.ObjectID `json:"guardian_id,omitempty"`
+	GuardianName    string             `json:"guardian_name,omitempty"`
+	GuardianEmail   string             `json:"guardian_email"`
+	InviteExpiresAt *time.Time         `json:"invite_expires_at,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	AcceptedAt      *time.Time         `json:"accepted_at,omitempty"`
+}
+
+// GuardianOverviewResponse is what a guardian sees of a linked student
+type GuardianOverviewResponse struct {
+	StudentID        primitive.ObjectID `json:"student_id"`
+	StudentName      string             `json:"student_name"`
+	Dashboard        *DashboardResponse `json:"dashboard"`
+	CompletedCourses []CourseResponse   `json:"completed_courses"`
+}
+
+type guardianService struct {
+	guardianRepo     repositories.GuardianRepository
+	userRepo         repositories.UserRepository
+	progressRepo     repositories.ProgressRepository
+	dashboardService DashboardService
+	courseService    CourseService
+	mailer           mailer.Mailer
+}
+
+func NewGuardianService(guardianRepo repositories.GuardianRepository, userRepo repositories.UserRepository, progressRepo repositories.ProgressRepository, dashboardService DashboardService, courseService CourseService, mail mailer.Mailer) GuardianService {
+	return &guardianService{
+		guardianRepo:     guardianRepo,
+		userRepo:         userRepo,
+		progressRepo:     progressRepo,
+		dashboardService: dashboardService,
+		courseService:    courseService,
+		mailer:           mail,
+	}
+}
+
+// InviteGuardian emails a one-time code to the guardian. The link becomes
+// active once a guardian account with that email redeems the code.
+func (s *guardianService) InviteGuardian(ctx context.Context, studentID primitive.ObjectID, input InviteGuardianInput) (*GuardianLinkResponse, error) {
+	student, err := s.loadStudent(studentID)
+	if err != nil {
+		return nil, err
+	}
+	email := normalizeEmail(input.Email)
+	if strings.EqualFold(email, student.Email) {
+		return nil, errors.New("you cannot invite yourself as a guardian")
+	}
+	existing, err := s.guardianRepo.FindOpen(ctx, studentID, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrGuardianLinkExists
+	}
+
+	code, err := randomString(classroomJoinCodeAlphabet, guardianInviteCodeLength)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(guardianInviteTTL)
+	link := &models.GuardianLink{
+		OrganizationID:  student.OrganizationID,
+		StudentID:       studentID,
+		GuardianEmail:   email,
+		Status:          models.GuardianLinkPending,
+		InitiatedBy:     models.GuardianLinkByStudent,
+		InviteTokenHash: hashInviteCode(code),
+		InviteExpiresAt: &expiresAt,
+		CreatedAt:       now,
+	}
+
+	// Send before saving, so a failed delivery doesn't leave an invite nobody can redeem
+	err = s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("%s invited you to follow their learning progress", student.DisplayNameOrFullName()),
+		Body: fmt.Sprintf("%s would like you to be their guardian.\n\n"+
+			"Sign in with a guardian account registered to %s and enter this code to accept:\n\n    %s\n\n"+
+			"The code expires on %s.\n",
+			student.DisplayNameOrFullName(), email, code, expiresAt.UTC().Format("2 January 2006")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guardianRepo.Create(ctx, link); err != nil {
+		return nil, err
+	}
+	return s.toResponse(link), nil
+}
+
+type guardianLinkCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+func (s *guardianService) ListGuardians(ctx context.Context, studentID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[GuardianLinkResponse], error) {
+	return s.listLinks(page, func(after primitive.ObjectID, limit int) ([]models.GuardianLink, error) {
+		return s.guardianRepo.FindPageByStudent(ctx, studentID, after, limit)
+	})
+}
+
+// ApproveLink lets a student accept a guardian's request
+func (s *guardianService) ApproveLink(ctx context.Context, studentID, linkID primitive.ObjectID) (*GuardianLinkResponse, error) {
+	link, err := s.findLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link.StudentID != studentID || link.Status != models.GuardianLinkPending {
+		return nil, ErrGuardianLinkNotFound
+	}
+	if link.InitiatedBy != models.GuardianLinkByGuardian {
+		return nil, errors.New("your guardian has to accept this invite with the emailed code")
+	}
+
+	now := time.Now()
+	link.Status = models.GuardianLinkActive
+	link.AcceptedAt = &now
+	if err := s.guardianRepo.Update(ctx, link); err != nil {
+		return nil, err
+	}
+	return s.toResponse(link), nil
+}
+
+// RequestLink asks a student of the guardian's organization for access. The
+// student is notified and has to approve the request.
+func (s *guardianService) RequestLink(ctx context.Context, guardianID primitive.ObjectID, input RequestGuardianLinkInput) (*GuardianLinkResponse, error) {
+	guardian, err := s.userRepo.FindByID(guardianID)
+	if err != nil {
+		return nil, err
+	}
+	student, err := s.userRepo.FindByEmail(strings.TrimSpace(input.StudentEmail))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrStudentNotFound
+		}
+		return nil, err
+	}
+	// Students of other organizations are reported the same as unknown ones
+	if student.OrganizationID != guardian.OrganizationID || student.EffectiveRole() != models.RoleStudent {
+		return nil, ErrStudentNotFound
+	}
+
+	email := normalizeEmail(guardian.Email)
+	existing, err := s.guardianRepo.FindOpen(ctx, student.ID, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrGuardianLinkExists
+	}
+
+	link := &models.GuardianLink{
+		OrganizationID: guardian.OrganizationID,
+		StudentID:      student.ID,
+		GuardianID:     guardianID,
+		GuardianEmail:  email,
+		Status:         models.GuardianLinkPending,
+		InitiatedBy:    models.GuardianLinkByGuardian,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.guardianRepo.Create(ctx, link); err != nil {
+		return nil, err
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      student.Email,
+		Subject: fmt.Sprintf("%s asked to follow your learning progress", guardian.DisplayNameOrFullName()),
+		Body: fmt.Sprintf("%s (%s) asked to be linked to your account as a guardian.\n\n"+
+			"Guardians can see your dashboard, streak and completed courses, but cannot change anything.\n"+
+			"You can approve or decline the request under your guardian settings.\n",
+			guardian.DisplayNameOrFullName(), guardian.Email),
+	})
+	if err != nil {
+		// The request is visible in the student's settings even without the email
+		log.Printf("Could not notify student %s of guardian request %s: %v", student.ID.Hex(), link.ID.Hex(), err)
+	}
+	return s.toResponse(link), nil
+}
+
+// AcceptInvite redeems a student's invite code. The guardian's account must
+// use the email the invite was sent to.
+func (s *guardianService) AcceptInvite(ctx context.Context, guardianID primitive.ObjectID, code string) (*GuardianLinkResponse, error) {
+	link, err := s.guardianRepo.FindByInviteTokenHash(ctx, hashInviteCode(strings.ToUpper(strings.TrimSpace(code))))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidInviteCode
+		}
+		return nil, err
+	}
+	if link.InviteExpiresAt == nil || time.Now().After(*link.InviteExpiresAt) {
+		return nil, ErrInvalidInviteCode
+	}
+	guardian, err := s.userRepo.FindByID(guardianID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(link.GuardianEmail, normalizeEmail(guardian.Email)) {
+		return nil, ErrInviteEmailMismatch
+	}
+
+	now := time.Now()
+	link.GuardianID = guardianID
+	link.Status = models.GuardianLinkActive
+	link.AcceptedAt = &now
+	link.InviteTokenHash = ""
+	link.InviteExpiresAt = nil
+	if err := s.guardianRepo.Update(ctx, link); err != nil {
+		return nil, err
+	}
+	return s.toResponse(link), nil
+}
+
+func (s *guardianService) ListStudents(ctx context.Context, guardianID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[GuardianLinkResponse], error) {
+	return s.listLinks(page, func(after primitive.ObjectID, limit int) ([]models.GuardianLink, error) {
+		return s.guardianRepo.FindPageByGuardian(ctx, guardianID, after, limit)
+	})
+}
+
+// listLinks makes a page of the links findPage returns, oldest first
+func (s *guardianService) listLinks(page pkg.PageRequest, findPage func(after primitive.ObjectID, limit int) ([]models.GuardianLink, error)) (*pkg.Page[GuardianLinkResponse], error) {
+	var after guardianLinkCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	links, err := findPage(after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	result := pkg.NewPage(s.toResponses(links), limit, func(link GuardianLinkResponse) any { return guardianLinkCursor{ID: link.ID} })
+	return &result, nil
+}
+
+// RevokeLink ends a pending or active link. Both the student and the guardian can revoke.
+func (s *guardianService) RevokeLink(ctx context.Context, userID, linkID primitive.ObjectID) error {
+	link, err := s.findLink(ctx, linkID)
+	if err != nil {
+		return err
+	}
+	if link.Status == models.GuardianLinkRevoked || (link.StudentID != userID && link.GuardianID != userID) {
+		return ErrGuardianLinkNotFound
+	}
+
+	now := time.Now()
+	link.Status = models.GuardianLinkRevoked
+	link.RevokedAt = &now
+	link.InviteTokenHash = ""
+	link.InviteExpiresAt = nil
+	return s.guardianRepo.Update(ctx, link)
+}
+
+// GetStudentOverview returns the read-only view of a student the guardian is actively linked to
+func (s *guardianService) GetStudentOverview(ctx context.Context, guardianID, studentID primitive.ObjectID) (*GuardianOverviewResponse, error) {
+	if _, err := s.guardianRepo.FindActive(ctx, studentID, guardianID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrGuardianLinkNotFound
+		}
+		return nil, err
+	}
+	student, err := s.userRepo.FindByID(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard, err := s.dashboardService.GetDashboardData(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	completed := []CourseResponse{}
+	query := CourseListQuery{Page: pkg.PageRequest{Limit: pkg.MaxPageLimit}}
+	for {
+		courses, err := s.courseService.GetAllCoursesWithProgress(ctx, studentID, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, course := range courses.Items {
+			if course.Progress >= 100 {
+				completed = append(completed, course)
+			}
+		}
+		if courses.NextCursor == "" {
+			break
+		}
+		query.Page.Cursor = courses.NextCursor
+	}
+
+	return &GuardianOverviewResponse{
+		StudentID:        studentID,
+		StudentName:      student.DisplayNameOrFullName(),
+		Dashboard:        dashboard,
+		CompletedCourses: completed,
+	}, nil
+}
+
+// SendWeeklySummaries emails every guardian of the organization in ctx a
+// summary of each linked student's last week, unless they turned progress
+// summaries off or already got one this week. It returns how many were sent.
+func (s *guardianService) SendWeeklySummaries(ctx context.Context) (int, error) {
+	now := time.Now()
+	links, err := s.guardianRepo.FindDueForSummary(ctx, now.Add(-guardianSummaryInterval))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range links {
+		link := links[i]
+		guardian, err := s.userRepo.FindByID(link.GuardianID)
+		if err != nil {
+			log.Printf("Skipping summary for guardian link %s: %v", link.ID.Hex(), err)
+			continue
+		}
+		if !guardian.Profile.Notifications.ProgressSummaries {
+			continue
+		}
+		message, err := s.weeklySummary(ctx, guardian, link.StudentID, now)
+		if err != nil {
+			log.Printf("Could not build summary for guardian link %s: %v", link.ID.Hex(), err)
+			continue
+		}
+		if err := s.mailer.Send(*message); err != nil {
+			return sent, err
+		}
+		if err := s.guardianRepo.MarkSummarySent(ctx, link.ID, now); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (s *guardianService) weeklySummary(ctx context.Context, guardian *models.User, studentID primitive.ObjectID, now time.Time) (*mailer.Message, error) {
+	overview, err := s.GetStudentOverview(ctx, guardian.ID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	completedThisWeek, err := s.progressRepo.CountCompletedSince(ctx, studentID, now.Add(-guardianSummaryWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Here is how %s did this week.\n\n", overview.StudentName)
+	fmt.Fprintf(&body, "Chapters completed this week: %d\n", completedThisWeek)
+	fmt.Fprintf(&body, "Learning streak: %d days\n", overview.Dashboard.LearningStreak)
+	fmt.Fprintf(&body, "Level %d with %d XP\n", overview.Dashboard.Level, overview.Dashboard.XP)
+	fmt.Fprintf(&body, "Overall completion: %.0f%% of %d chapters\n", overview.Dashboard.CompletionRate, overview.Dashboard.TotalChapters)
+	if len(overview.CompletedCourses) > 0 {
+		body.WriteString("\nCompleted courses:\n")
+		for _, course := range overview.CompletedCourses {
+			fmt.Fprintf(&body, "  - %s\n", course.Title)
+		}
+	}
+	body.WriteString("\nYou can turn these emails off in your notification preferences.\n")
+
+	return &mailer.Message{
+		To:      guardian.Email,
+		Subject: fmt.Sprintf("Weekly progress summary for %s", overview.StudentName),
+		Body:    body.String(),
+	}, nil
+}
+
+// loadStudent returns the user if they hold the student role
+func (s *guardianService) loadStudent(userID primitive.ObjectID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.EffectiveRole() != models.RoleStudent {
+		return nil, ErrNotStudent
+	}
+	return user, nil
+}
+
+func (s *guardianService) findLink(ctx context.Context, linkID primitive.ObjectID) (*models.GuardianLink, error) {
+	link, err := s.guardianRepo.FindByID(ctx, linkID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrGuardianLinkNotFound
+		}
+		return nil, err
+	}
+	return link, nil
+}
+
+func (s *guardianService) toResponses(links []models.GuardianLink) []GuardianLinkResponse {
+	responses := make([]GuardianLinkResponse, 0, len(links))
+	for i := range links {
+		responses = append(responses, *s.toResponse(&links[i]))
+	}
+	return responses
+}
+
+// toResponse fills in the names of both sides where their accounts exist
+func (s *guardianService) toResponse(link *models.GuardianLink) *GuardianLinkResponse {
+	response := &GuardianLinkResponse{
+		ID:              link.ID,
+		Status:          link.Status,
+		InitiatedBy:     link.InitiatedBy,
+		StudentID:       link.StudentID,
+		GuardianID:      link.GuardianID,
+		GuardianEmail:   link.GuardianEmail,
+		InviteExpiresAt: link.InviteExpiresAt,
+		CreatedAt:       link.CreatedAt,
+		AcceptedAt:      link.AcceptedAt,
+	}
+	if student, err := s.userRepo.FindByID(link.StudentID); err == nil {
+		response.StudentName = student.DisplayNameOrFullName()
+	}
+	if !link.GuardianID.IsZero() {
+		if guardian, err := s.userRepo.FindByID(link.GuardianID); err == nil {
+			response.GuardianName = guardian.DisplayNameOrFullName()
+		}
+	}
+	return response
+}
+
+func hashInviteCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"gamified-edu-backend/internal/mailer"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeUsers serves users from memory; methods the test does not need panic
+type fakeUsers struct {
+	repositories.UserRepository
+	users map[primitive.ObjectID]*models.User
+}
+
+func (r *fakeUsers) FindByID(id primitive.ObjectID) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return user, nil
+}
+
+type fakeGuardianLinks struct {
+	repositories.GuardianRepository
+	links []*models.GuardianLink
+}
+
+func (r *fakeGuardianLinks) FindOpen(ctx context.Context, studentID primitive.ObjectID, guardianEmail string) (*models.GuardianLink, error) {
+	for _, link := range r.links {
+		if link.StudentID == studentID && link.GuardianEmail == guardianEmail {
+			return link, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeGuardianLinks) Create(ctx context.Context, link *models.GuardianLink) error {
+	link.ID = primitive.NewObjectID()
+	r.links = append(r.links, link)
+	return nil
+}
+
+func TestInviteGuardianMailsTheCodeThroughTheOutbox(t *testing.T) {
+	student := &models.User{ID: primitive.NewObjectID(), FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: models.RoleStudent, OrganizationID: primitive.NewObjectID()}
+	links := &fakeGuardianLinks{}
+	outboxDir := t.TempDir()
+	service := NewGuardianService(links, &fakeUsers{users: map[primitive.ObjectID]*models.User{student.ID: student}}, nil, nil, nil, mailer.NewFileOutbox(outboxDir))
+
+	if _, err := service.InviteGuardian(context.Background(), student.ID, InviteGuardianInput{Email: " Parent@Example.com "}); err != nil {
+		t.Fatalf("InviteGuardian: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(outboxDir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got %d messages in the outbox (%v), want 1", len(files), err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := string(data)
+	if !regexp.MustCompile(`(?m)^To: parent@example.com\r$`).MatchString(message) {
+		t.Errorf("invite is not addressed to the normalized email:\n%s", message)
+	}
+	code := regexp.MustCompile(`(?m)^    (\S+)\r$`).FindStringSubmatch(message)
+	if code == nil {
+		t.Fatalf("invite has no code:\n%s", message)
+	}
+
+	if len(links.links) != 1 {
+		t.Fatalf("got %d links, want 1", len(links.links))
+	}
+	link := links.links[0]
+	if link.Status != models.GuardianLinkPending || link.GuardianEmail != "parent@example.com" {
+		t.Errorf("link = %+v, want a pending link for parent@example.com", link)
+	}
+	// Only the hash of the mailed code is stored
+	if link.InviteTokenHash != hashInviteCode(code[1]) {
+		t.Error("the stored invite hash does not match the mailed code")
+	}
+
+	if _, err := service.InviteGuardian(context.Background(), student.ID, InviteGuardianInput{Email: "parent@example.com"}); err != ErrGuardianLinkExists {
+		t.Errorf("second invite: got %v, want ErrGuardianLinkExists", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(outboxDir, "*.eml")); len(files) != 1 {
+		t.Errorf("a refused invite was mailed anyway")
+	}
+}
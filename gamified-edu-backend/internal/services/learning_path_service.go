@@ -0,0 +1,610 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultPathRewardXP is what completing a learning path earns unless the path says otherwise
+const DefaultPathRewardXP = 200
+
+// Why a course is next up on a learning path
+const (
+	NextUpRequired = "required"
+	NextUpElective = "elective"
+)
+
+var (
+	ErrPathNotFound      = errors.New("learning path not found")
+	ErrNotEnrolledInPath = errors.New("not enrolled in the learning path")
+	ErrInvalidPath       = errors.New("invalid learning path")
+)
+
+// LearningPathService runs learning paths. It is a LearningRecorder too:
+// completing a course completes the learner's paths that it finishes.
+type LearningPathService interface {
+	LearningRecorder
+	CreatePath(ctx context.Context, authorID primitive.ObjectID, input LearningPathInput) (*LearningPathResponse, error)
+	UpdatePath(ctx context.Context, userID, pathID primitive.ObjectID, input LearningPathInput) (*LearningPathResponse, error)
+	DeletePath(ctx context.Context, pathID primitive.ObjectID) error
+	// ListPaths lists a page of the organization's paths, newest first, with the user's standing on each
+	ListPaths(ctx context.Context, userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[LearningPathResponse], error)
+	// GetEnrolledPaths lists a page of the paths the user is enrolled in, most recent enrollment first
+	GetEnrolledPaths(ctx context.Context, userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[LearningPathResponse], error)
+	GetPath(ctx context.Context, userID, pathID primitive.ObjectID) (*LearningPathResponse, error)
+	Enroll(ctx context.Context, userID, pathID primitive.ObjectID) (*LearningPathResponse, error)
+	Unenroll(ctx context.Context, userID, pathID primitive.ObjectID) error
+}
+
+// LearningPathInput creates or replaces a path.
+type LearningPathInput struct {
+	Title       string `json:"title" binding:"required,max=200"`
+	Description string `json:"description" binding:"max=5000"`
+	// Courses are the steps of the path in order
+	Courses           []LearningPathStepInput `json:"courses" binding:"required,min=1,max=50,dive"`
+	ElectivesRequired int                     `json:"electives_required" binding:"min=0"`
+	// RewardXP defaults to DefaultPathRewardXP
+	RewardXP *int `json:"reward_xp" binding:"omitempty,min=0,max=10000"`
+}
+
+type LearningPathStepInput struct {
+	CourseID string `json:"course_id" binding:"required"`
+	Elective bool   `json:"elective"`
+}
+
+type LearningPathResponse struct {
+	ID                primitive.ObjectID `json:"id"`
+	Title             string             `json:"title"`
+	Description       string             `json:"description"`
+	ElectivesRequired int                `json:"electives_required"`
+	RewardXP          int                `json:"reward_xp"`
+	// DurationMins estimates the time the required courses take
+	DurationMins int                  `json:"duration_mins"`
+	Courses      []LearningPathCourse `json:"courses"`
+	// The user's standing on the path
+	IsEnrolled        bool       `json:"is_enrolled"`
+	EnrolledAt        *time.Time `json:"enrolled_at,omitempty"`
+	Progress          int        `json:"progress"`
+	CompletedCourses  int        `json:"completed_courses"`
+	CoursesToComplete int        `json:"courses_to_complete"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	// NextUp is the course to take next, nil when the path needs no more courses
+	NextUp *LearningPathNextUp `json:"next_up"`
+}
+
+// LearningPathCourse is a course of a path with the user's standing on it.
+type LearningPathCourse struct {
+	ID           primitive.ObjectID `json:"id"`
+	Title        string             `json:"title"`
+	Elective     bool               `json:"elective"`
+	DurationMins int                `json:"duration_mins"`
+	IsEnrolled   bool               `json:"is_enrolled"`
+	// Completion is one of the Completion constants
+	Completion string `json:"completion"`
+	Progress   int    `json:"progress"`
+}
+
+type LearningPathNextUp struct {
+	LearningPathCourse
+	// Reason is NextUpRequired or NextUpElective
+	Reason string `json:"reason"`
+}
+
+type learningPathService struct {
+	pathRepo       repositories.LearningPathRepository
+	courseRepo     repositories.CourseRepository
+	progressRepo   repositories.ProgressRepository
+	enrollmentRepo repositories.EnrollmentRepository
+	userRepo       repositories.UserRepository
+	clock          pkg.Clock
+}
+
+func NewLearningPathService(pathRepo repositories.LearningPathRepository, courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, enrollmentRepo repositories.EnrollmentRepository, userRepo repositories.UserRepository, clock pkg.Clock) LearningPathService {
+	return &learningPathService{pathRepo, courseRepo, progressRepo, enrollmentRepo, userRepo, clock}
+}
+
+type pathCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+type pathEnrollmentCursor struct {
+	EnrolledAt time.Time          `json:"enrolled_at"`
+	ID         primitive.ObjectID `json:"id"`
+}
+
+func (s *learningPathService) CreatePath(ctx context.Context, authorID primitive.ObjectID, input LearningPathInput) (*LearningPathResponse, error) {
+	now := s.clock.Now()
+	path := &models.LearningPath{CreatedBy: authorID, CreatedAt: now}
+	if err := s.applyInput(ctx, path, input, now); err != nil {
+		return nil, err
+	}
+	if err := s.pathRepo.Create(ctx, path); err != nil {
+		return nil, err
+	}
+	return s.pathResponse(ctx, authorID, path)
+}
+
+// UpdatePath replaces the path. Learners whose progress now completes the
+// path complete it, with its reward; completed paths stay completed.
+func (s *learningPathService) UpdatePath(ctx context.Context, userID, pathID primitive.ObjectID, input LearningPathInput) (*LearningPathResponse, error) {
+	path, err := s.loadPath(ctx, pathID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(ctx, path, input, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.pathRepo.Update(ctx, path); err != nil {
+		return nil, err
+	}
+
+	enrollments, err := s.pathRepo.FindActiveEnrollmentsByPath(ctx, path.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, enrollment := range enrollments {
+		if enrollment.CompletedAt != nil {
+			continue
+		}
+		if err := s.completeIfFinished(ctx, enrollment.UserID, path); err != nil {
+			return nil, err
+		}
+	}
+	return s.pathResponse(ctx, userID, path)
+}
+
+// applyInput checks the input and sets it on the path. Paths may only hold
+// published courses, each once.
+func (s *learningPathService) applyInput(ctx context.Context, path *models.LearningPath, input LearningPathInput, now time.Time) error {
+	steps := make([]models.LearningPathStep, 0, len(input.Courses))
+	seen := map[primitive.ObjectID]bool{}
+	electives := 0
+	for _, step := range input.Courses {
+		courseID, err := primitive.ObjectIDFromHex(step.CourseID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid course ID %q", ErrInvalidPath, step.CourseID)
+		}
+		if seen[courseID] {
+			return fmt.Errorf("%w: course %s is listed twice", ErrInvalidPath, step.CourseID)
+		}
+		seen[courseID] = true
+		course, err := s.courseRepo.FindByID(ctx, courseID)
+		if errors.Is(err, mongo.ErrNoDocuments) || err == nil && !course.IsPublished() {
+			return fmt.Errorf("%w: course %s is not a published course", ErrInvalidPath, step.CourseID)
+		}
+		if err != nil {
+			return err
+		}
+		if step.Elective {
+			electives++
+		}
+		steps = append(steps, models.LearningPathStep{CourseID: courseID, Elective: step.Elective})
+	}
+	if input.ElectivesRequired > electives {
+		return fmt.Errorf("%w: the path requires %d electives but has %d", ErrInvalidPath, input.ElectivesRequired, electives)
+	}
+	if electives == len(steps) && input.ElectivesRequired == 0 {
+		return fmt.Errorf("%w: the path needs a required course or electives_required", ErrInvalidPath)
+	}
+
+	path.Title = input.Title
+	path.Description = input.Description
+	path.Steps = steps
+	path.ElectivesRequired = input.ElectivesRequired
+	path.RewardXP = DefaultPathRewardXP
+	if input.RewardXP != nil {
+		path.RewardXP = *input.RewardXP
+	}
+	path.UpdatedAt = now
+	return nil
+}
+
+// DeletePath deletes the path and its enrollments. Rewards already given are kept.
+func (s *learningPathService) DeletePath(ctx context.Context, pathID primitive.ObjectID) error {
+	deleted, err := s.pathRepo.Delete(ctx, pathID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPathNotFound
+	}
+	return s.pathRepo.DeleteEnrollments(ctx, pathID)
+}
+
+func (s *learningPathService) ListPaths(ctx context.Context, userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[LearningPathResponse], error) {
+	var after pathCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	paths, err := s.pathRepo.FindPage(ctx, after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	enrollments := map[primitive.ObjectID]*models.PathEnrollment{}
+	for _, path := range paths {
+		enrollment, err := s.pathRepo.FindEnrollment(ctx, userID, path.ID)
+		if err != nil {
+			return nil, err
+		}
+		if enrollment != nil && enrollment.IsActive() {
+			enrollments[path.ID] = enrollment
+		}
+	}
+	responses, err := s.pathResponses(ctx, userID, paths, enrollments)
+	if err != nil {
+		return nil, err
+	}
+	result := pkg.NewPage(responses, limit, func(path LearningPathResponse) any { return pathCursor{ID: path.ID} })
+	return &result, nil
+}
+
+func (s *learningPathService) GetEnrolledPaths(ctx context.Context, userID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[LearningPathResponse], error) {
+	var after *models.PathEnrollment
+	if page.Cursor != "" {
+		var position pathEnrollmentCursor
+		if err := pkg.DecodeCursor(page.Cursor, &position); err != nil {
+			return nil, err
+		}
+		after = &models.PathEnrollment{ID: position.ID, EnrolledAt: position.EnrolledAt}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	enrollments, err := s.pathRepo.FindEnrollmentPage(ctx, userID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[primitive.ObjectID]*models.PathEnrollment, len(enrollments))
+	pathIDs := make([]primitive.ObjectID, 0, len(enrollments))
+	for i := range enrollments {
+		byPath[enrollments[i].PathID] = &enrollments[i]
+		pathIDs = append(pathIDs, enrollments[i].PathID)
+	}
+	paths, err := s.pathRepo.FindByIDs(ctx, pathIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the order of the enrollments
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := byPath[paths[i].ID], byPath[paths[j].ID]
+		if !a.EnrolledAt.Equal(b.EnrolledAt) {
+			return a.EnrolledAt.After(b.EnrolledAt)
+		}
+		return a.ID.Hex() > b.ID.Hex()
+	})
+	responses, err := s.pathResponses(ctx, userID, paths, byPath)
+	if err != nil {
+		return nil, err
+	}
+	result := pkg.NewPage(responses, limit, func(path LearningPathResponse) any {
+		enrollment := byPath[path.ID]
+		return pathEnrollmentCursor{EnrolledAt: enrollment.EnrolledAt, ID: enrollment.ID}
+	})
+	return &result, nil
+}
+
+func (s *learningPathService) GetPath(ctx context.Context, userID, pathID primitive.ObjectID) (*LearningPathResponse, error) {
+	path, err := s.loadPath(ctx, pathID)
+	if err != nil {
+		return nil, err
+	}
+	return s.pathResponse(ctx, userID, path)
+}
+
+// Enroll signs the user up for the path. Courses they completed before count,
+// so enrolling may complete the path right away.
+func (s *learningPathService) Enroll(ctx context.Context, userID, pathID primitive.ObjectID) (*LearningPathResponse, error) {
+	path, err := s.loadPath(ctx, pathID)
+	if err != nil {
+		return nil, err
+	}
+	// Enrolling twice keeps the original enrollment date
+	enrollment, err := s.pathRepo.FindEnrollment(ctx, userID, pathID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil || !enrollment.IsActive() {
+		if _, err := s.pathRepo.Enroll(ctx, userID, pathID, s.clock.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.completeIfFinished(ctx, userID, path); err != nil {
+		return nil, err
+	}
+	return s.pathResponse(ctx, userID, path)
+}
+
+// Unenroll ends the enrollment. A completed path stays completed if the user enrolls again.
+func (s *learningPathService) Unenroll(ctx context.Context, userID, pathID primitive.ObjectID) error {
+	unenrolled, err := s.pathRepo.Unenroll(ctx, userID, pathID, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !unenrolled {
+		return ErrNotEnrolledInPath
+	}
+	return nil
+}
+
+func (s *learningPathService) loadPath(ctx context.Context, pathID primitive.ObjectID) (*models.LearningPath, error) {
+	path, err := s.pathRepo.FindByID(ctx, pathID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPathNotFound
+		}
+		return nil, err
+	}
+	return path, nil
+}
+
+// completeIfFinished completes the user's enrollment in the path once they
+// completed the courses it takes, and gives them its reward
+func (s *learningPathService) completeIfFinished(ctx context.Context, userID primitive.ObjectID, path *models.LearningPath) error {
+	enrollment, err := s.pathRepo.FindEnrollment(ctx, userID, path.ID)
+	if err != nil || enrollment == nil || !enrollment.IsActive() || enrollment.CompletedAt != nil {
+		return err
+	}
+	standings, err := s.courseStandings(ctx, userID, []models.LearningPath{*path})
+	if err != nil {
+		return err
+	}
+	if !pathStanding(path, standings).completed {
+		return nil
+	}
+	completed, err := s.pathRepo.MarkCompleted(ctx, userID, path.ID, s.clock.Now(), path.RewardXP)
+	if err != nil || !completed || path.RewardXP == 0 {
+		return err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	user.XP += path.RewardXP
+	user.Level = (user.XP / XP_PER_LEVEL) + 1
+	return s.userRepo.UpdateXPAndLevel(user)
+}
+
+func (s *learningPathService) RecordEnrollment(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	return nil
+}
+
+func (s *learningPathService) RecordComponent(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter, component models.ChapterComponent, report MarkComponentInput, completed bool) error {
+	return nil
+}
+
+func (s *learningPathService) RecordChapterCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter) error {
+	return nil
+}
+
+// RecordCourseCompleted completes the user's paths that the course finishes
+func (s *learningPathService) RecordCourseCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	paths, err := s.pathRepo.FindByCourse(ctx, course.ID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range paths {
+		if err := s.completeIfFinished(ctx, userID, &paths[i]); err != nil {
+			errs = append(errs, fmt.Errorf("learning path %s: %w", paths[i].ID.Hex(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// courseStanding is where the user stands on a published course of a path
+type courseStanding struct {
+	course   *models.Course
+	enrolled bool
+	// started is set once the user recorded progress in the course
+	started   bool
+	completed bool
+	progress  int
+}
+
+// courseStandings looks up the user's standing on the courses of the paths
+// at once. Courses that were deleted or never published are left out, and
+// the paths go on without them.
+func (s *learningPathService) courseStandings(ctx context.Context, userID primitive.ObjectID, paths []models.LearningPath) (map[primitive.ObjectID]*courseStanding, error) {
+	wanted := map[primitive.ObjectID]bool{}
+	for i := range paths {
+		for _, id := range paths[i].CourseIDs() {
+			wanted[id] = true
+		}
+	}
+	standings := map[primitive.ObjectID]*courseStanding{}
+	if len(wanted) == 0 {
+		return standings, nil
+	}
+	courses, err := s.courseRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	courseIDs := []primitive.ObjectID{}
+	for i := range courses {
+		if wanted[courses[i].ID] && courses[i].IsPublished() {
+			standings[courses[i].ID] = &courseStanding{course: &courses[i]}
+			courseIDs = append(courseIDs, courses[i].ID)
+		}
+	}
+
+	// A recorded completion stands even if the user unenrolled since
+	enrollments, err := s.enrollmentRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, enrollment := range enrollments {
+		if standing, ok := standings[enrollment.CourseID]; ok {
+			standing.enrolled = enrollment.IsActive()
+			standing.completed = enrollment.CompletedAt != nil
+		}
+	}
+	statuses, err := s.progressRepo.FindForUsers(ctx, []primitive.ObjectID{userID}, courseIDs)
+	if err != nil {
+		return nil, err
+	}
+	completed := make(map[primitive.ObjectID]bool, len(statuses))
+	for _, status := range statuses {
+		completed[status.ChapterID] = status.IsChapterCompleted
+		if standing, ok := standings[status.CourseID]; ok {
+			standing.started = true
+		}
+	}
+	for _, standing := range standings {
+		standing.progress, _ = rollUpProgress(standing.course, completed)
+		if standing.completed {
+			standing.progress = 100
+		}
+	}
+	return standings, nil
+}
+
+// pathProgress sums up the user's standing on a path
+type pathProgress struct {
+	courses           []LearningPathCourse
+	durationMins      int
+	progress          int
+	completedCourses  int
+	coursesToComplete int
+	completed         bool
+	nextUp            *LearningPathNextUp
+}
+
+// pathStanding works out the user's progress on the path. Required courses
+// count in full and electives by the best ElectivesRequired of them, or all
+// of them if fewer are left.
+func pathStanding(path *models.LearningPath, standings map[primitive.ObjectID]*courseStanding) pathProgress {
+	result := pathProgress{courses: []LearningPathCourse{}}
+	var electives []LearningPathCourse
+	progressSum := 0
+	for _, step := range path.Steps {
+		standing, ok := standings[step.CourseID]
+		if !ok {
+			continue
+		}
+		course := LearningPathCourse{
+			ID:           standing.course.ID,
+			Title:        standing.course.Title,
+			Elective:     step.Elective,
+			DurationMins: standing.course.DurationMins(),
+			IsEnrolled:   standing.enrolled,
+			Completion:   CompletionNotStarted,
+			Progress:     standing.progress,
+		}
+		switch {
+		case standing.completed:
+			course.Completion = CompletionCompleted
+		case standing.enrolled && standing.started:
+			course.Completion = CompletionInProgress
+		}
+		result.courses = append(result.courses, course)
+		if step.Elective {
+			electives = append(electives, course)
+			continue
+		}
+		result.coursesToComplete++
+		result.durationMins += course.DurationMins
+		progressSum += course.Progress
+		if course.Completion == CompletionCompleted {
+			result.completedCourses++
+		} else if result.nextUp == nil {
+			result.nextUp = &LearningPathNextUp{LearningPathCourse: course, Reason: NextUpRequired}
+		}
+	}
+
+	electivesNeeded := min(path.ElectivesRequired, len(electives))
+	result.coursesToComplete += electivesNeeded
+	best := append([]LearningPathCourse{}, electives...)
+	sort.SliceStable(best, func(i, j int) bool { return best[i].Progress > best[j].Progress })
+	electivesDone := 0
+	for i, course := range best {
+		if course.Completion == CompletionCompleted {
+			electivesDone++
+		}
+		if i < electivesNeeded {
+			progressSum += course.Progress
+		}
+	}
+	result.completedCourses += min(electivesDone, electivesNeeded)
+
+	// Electives come up once the required courses are done, those already started first
+	if result.nextUp == nil && electivesDone < electivesNeeded {
+		for _, course := range best {
+			if course.Completion != CompletionCompleted {
+				result.nextUp = &LearningPathNextUp{LearningPathCourse: course, Reason: NextUpElective}
+				break
+			}
+		}
+	}
+	if result.coursesToComplete > 0 {
+		result.progress = progressSum / result.coursesToComplete
+		result.completed = result.completedCourses == result.coursesToComplete
+	}
+	return result
+}
+
+func (s *learningPathService) pathResponse(ctx context.Context, userID primitive.ObjectID, path *models.LearningPath) (*LearningPathResponse, error) {
+	enrollment, err := s.pathRepo.FindEnrollment(ctx, userID, path.ID)
+	if err != nil {
+		return nil, err
+	}
+	enrollments := map[primitive.ObjectID]*models.PathEnrollment{}
+	if enrollment != nil && enrollment.IsActive() {
+		enrollments[path.ID] = enrollment
+	}
+	responses, err := s.pathResponses(ctx, userID, []models.LearningPath{*path}, enrollments)
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// pathResponses describes the paths with the user's standing on each, given
+// the user's active enrollments in them by path
+func (s *learningPathService) pathResponses(ctx context.Context, userID primitive.ObjectID, paths []models.LearningPath, enrollments map[primitive.ObjectID]*models.PathEnrollment) ([]LearningPathResponse, error) {
+	standings, err := s.courseStandings(ctx, userID, paths)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]LearningPathResponse, 0, len(paths))
+	for i := range paths {
+		path := &paths[i]
+		standing := pathStanding(path, standings)
+		response := LearningPathResponse{
+			ID:                path.ID,
+			Title:             path.Title,
+			Description:       path.Description,
+			ElectivesRequired: path.ElectivesRequired,
+			RewardXP:          path.RewardXP,
+			DurationMins:      standing.durationMins,
+			Courses:           standing.courses,
+			Progress:          standing.progress,
+			CompletedCourses:  standing.completedCourses,
+			CoursesToComplete: standing.coursesToComplete,
+			NextUp:            standing.nextUp,
+		}
+		if enrollment, ok := enrollments[path.ID]; ok {
+			enrolledAt := enrollment.EnrolledAt
+			response.IsEnrolled = true
+			response.EnrolledAt = &enrolledAt
+			response.CompletedAt = enrollment.CompletedAt
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LearningRecorder is told about learning events as they happen, to keep
+// learning records of them or report them elsewhere. Callers log its errors
+// rather than failing the event.
+type LearningRecorder interface {
+	RecordEnrollment(ctx context.Context, userID primitive.ObjectID, course *models.Course) error
+	// RecordComponent records a report on a component that completed it or carried a score
+	RecordComponent(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter, component models.ChapterComponent, report MarkComponentInput, completed bool) error
+	RecordChapterCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter) error
+	RecordCourseCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course) error
+}
+
+// LearningRecorders tells every one of recorders about each event.
+func LearningRecorders(recorders ...LearningRecorder) LearningRecorder {
+	return learningRecorders(recorders)
+}
+
+type learningRecorders []LearningRecorder
+
+func (r learningRecorders) each(record func(LearningRecorder) error) error {
+	var errs []error
+	for _, recorder := range r {
+		if err := record(recorder); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r learningRecorders) RecordEnrollment(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	return r.each(func(recorder LearningRecorder) error {
+		return recorder.RecordEnrollment(ctx, userID, course)
+	})
+}
+
+func (r learningRecorders) RecordComponent(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter, component models.ChapterComponent, report MarkComponentInput, completed bool) error {
+	return r.each(func(recorder LearningRecorder) error {
+		return recorder.RecordComponent(ctx, userID, course, chapter, component, report, completed)
+	})
+}
+
+func (r learningRecorders) RecordChapterCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter) error {
+	return r.each(func(recorder LearningRecorder) error {
+		return recorder.RecordChapterCompleted(ctx, userID, course, chapter)
+	})
+}
+
+func (r learningRecorders) RecordCourseCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	return r.each(func(recorder LearningRecorder) error {
+		return recorder.RecordCourseCompleted(ctx, userID, course)
+	})
+}
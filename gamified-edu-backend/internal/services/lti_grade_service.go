@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"gamified-edu-backend/internal/lti"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ltiGradeService is a LearningRecorder that sends the scores of learners
+// who launched a course from an LMS back to its gradebook: each chapter and
+// scored component gets a line item, and the course's completion goes to the
+// line item of the launched link if the platform made one.
+type ltiGradeService struct {
+	platformRepo repositories.LTIPlatformRepository
+	identityRepo repositories.LTIIdentityRepository
+	linkRepo     repositories.LTICourseLinkRepository
+	progressRepo repositories.ProgressRepository
+	client       *lti.GradeClient
+	// mu sends one score at a time, so each line item is created once
+	mu sync.Mutex
+}
+
+func NewLTIGradeService(platformRepo repositories.LTIPlatformRepository, identityRepo repositories.LTIIdentityRepository, linkRepo repositories.LTICourseLinkRepository, progressRepo repositories.ProgressRepository, client *lti.GradeClient) LearningRecorder {
+	return &ltiGradeService{platformRepo: platformRepo, identityRepo: identityRepo, linkRepo: linkRepo, progressRepo: progressRepo, client: client}
+}
+
+// ltiGrade is a score for a line item. An empty resourceID means the
+// launched link's own line item.
+type ltiGrade struct {
+	resourceID string
+	label      string
+	percent    int
+	completed  bool
+}
+
+func (s *ltiGradeService) RecordEnrollment(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	return nil
+}
+
+// RecordComponent sends the best score recorded for the component, which
+// progress only takes from grading on the server, never the report's own
+func (s *ltiGradeService) RecordComponent(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter, component models.ChapterComponent, report MarkComponentInput, completed bool) error {
+	status, err := s.progressRepo.FindByUserAndChapter(ctx, userID, chapter.ID)
+	if err != nil || status == nil {
+		return err
+	}
+	progress := status.Components[component.Key]
+	if progress.BestScore == nil {
+		return nil
+	}
+	// The gradebook keeps the best attempt, like the chapter status does
+	return s.send(ctx, userID, course.ID, ltiGrade{
+		resourceID: "component:" + chapter.ID.Hex() + ":" + component.Key,
+		label:      chapter.Title + ": " + componentLabel(component),
+		percent:    *progress.BestScore,
+		completed:  completed || progress.Completed,
+	})
+}
+
+func componentLabel(component models.ChapterComponent) string {
+	if component.Title != "" {
+		return component.Title
+	}
+	return component.Key
+}
+
+func (s *ltiGradeService) RecordChapterCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter) error {
+	return s.send(ctx, userID, course.ID, ltiGrade{resourceID: "chapter:" + chapter.ID.Hex(), label: chapter.Title, percent: 100, completed: true})
+}
+
+func (s *ltiGradeService) RecordCourseCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	return s.send(ctx, userID, course.ID, ltiGrade{label: course.Title, percent: 100, completed: true})
+}
+
+// send passes the grade to every link the learner launched the course from.
+// LMS calls can be slow, so they happen after the learner's request returns.
+func (s *ltiGradeService) send(ctx context.Context, userID, courseID primitive.ObjectID, grade ltiGrade) error {
+	links, err := s.linkRepo.FindForLearner(ctx, userID, courseID)
+	if err != nil || len(links) == 0 {
+		return err
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, link := range links {
+			if err := s.sendToLink(ctx, userID, link, grade); err != nil {
+				log.Printf("Could not send a grade to the LMS for user %s: %v", userID.Hex(), err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *ltiGradeService) sendToLink(ctx context.Context, userID primitive.ObjectID, link models.LTICourseLink, grade ltiGrade) error {
+	if !slices.Contains(link.Scopes, lti.ScopeScore) {
+		return nil
+	}
+	platform, err := s.platformRepo.FindByID(ctx, link.PlatformID)
+	if err != nil || platform == nil || platform.AuthTokenURL == "" {
+		return err
+	}
+	identities, err := s.identityRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	subject := ""
+	for _, identity := range identities {
+		if identity.PlatformID == link.PlatformID {
+			subject = identity.Subject
+		}
+	}
+	if subject == "" {
+		return nil
+	}
+
+	lineItemURL := link.LineItemURL
+	if grade.resourceID != "" {
+		if lineItemURL, err = s.lineItem(ctx, platform, link, grade); err != nil || lineItemURL == "" {
+			return err
+		}
+	}
+	if lineItemURL == "" {
+		return nil
+	}
+	activity := lti.ActivityInProgress
+	if grade.completed {
+		activity = lti.ActivityCompleted
+	}
+	return s.client.PostScore(ctx, toLTIPlatform(platform), lineItemURL, lti.Score{
+		UserID:           subject,
+		ScoreGiven:       float64(grade.percent),
+		ScoreMaximum:     100,
+		ActivityProgress: activity,
+		GradingProgress:  lti.GradingFullyGraded,
+		Timestamp:        time.Now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+// lineItem returns the URL of the grade's line item in the link's context,
+// finding or creating it the first time. It returns "" when the platform
+// does not let the tool manage line items.
+func (s *ltiGradeService) lineItem(ctx context.Context, platform *models.LTIPlatform, link models.LTICourseLink, grade ltiGrade) (string, error) {
+	if link.LineItemsURL == "" || !slices.Contains(link.Scopes, lti.ScopeLineItem) {
+		return "", nil
+	}
+	// Reread the link, as earlier grades may have added line items since it was loaded
+	current, err := s.linkRepo.FindByResourceLink(ctx, link.PlatformID, link.ResourceLinkID)
+	if err != nil || current == nil {
+		return "", err
+	}
+	for _, item := range current.LineItems {
+		if item.ResourceID == grade.resourceID {
+			return item.URL, nil
+		}
+	}
+
+	ltiPlatform := toLTIPlatform(platform)
+	item, err := s.client.FindLineItem(ctx, ltiPlatform, current.LineItemsURL, grade.resourceID)
+	if err != nil {
+		return "", err
+	}
+	if item == nil {
+		item, err = s.client.CreateLineItem(ctx, ltiPlatform, current.LineItemsURL, lti.LineItem{
+			Label:          grade.label,
+			ScoreMaximum:   100,
+			ResourceID:     grade.resourceID,
+			ResourceLinkID: current.ResourceLinkID,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := s.linkRepo.AddLineItem(ctx, current.ID, models.LTILineItem{ResourceID: grade.resourceID, URL: item.ID}); err != nil {
+		return "", err
+	}
+	return item.ID, nil
+}
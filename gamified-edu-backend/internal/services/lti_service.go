@@ -0,0 +1,617 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/lti"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/tenant"
+	"gamified-edu-backend/pkg"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// ltiLoginTTL is how long the platform has to send the launch after a login initiation
+	ltiLoginTTL = 10 * time.Minute
+	// ltiDeepLinkTTL is how long an instructor has to pick courses
+	ltiDeepLinkTTL = time.Hour
+	// ltiCourseParameter names the course a link launches, as a custom
+	// parameter or in the target link URI
+	ltiCourseParameter = "course"
+)
+
+var (
+	ErrLTIPlatformNotFound = errors.New("LTI platform not found")
+	ErrLTIPlatformExists   = errors.New("a platform with this issuer and client ID is already registered")
+	ErrLTILoginExpired     = errors.New("the LTI login expired, launch again from the LMS")
+	ErrLTICourseNotLinked  = errors.New("the LMS link does not name a course")
+	ErrLTIUserConflict     = errors.New("the launching user's email belongs to another account")
+	ErrDeepLinkNotFound    = errors.New("deep linking request not found or expired")
+	ErrDeepLinkNotAllowed  = errors.New("only instructors can pick courses for an LMS")
+	ErrInvalidDeepLink     = errors.New("invalid course selection")
+)
+
+// LTIService makes the organization's courses launchable from LMS platforms
+// over LTI 1.3.
+type LTIService interface {
+	RegisterPlatform(ctx context.Context, input RegisterLTIPlatformInput) (*LTIPlatformResponse, error)
+	// ListPlatforms lists a page of the organization's platforms, oldest first
+	ListPlatforms(ctx context.Context, page pkg.PageRequest) (*pkg.Page[LTIPlatformResponse], error)
+	DeletePlatform(ctx context.Context, platformID primitive.ObjectID) error
+	// ToolConfiguration is what platform administrators enter to register the tool
+	ToolConfiguration() LTIToolConfiguration
+	KeySet() lti.KeySet
+	// Login answers a login initiation with the URL to send the browser to
+	Login(ctx context.Context, login lti.LoginRequest) (string, error)
+	// Launch signs in the launching user and returns the frontend URL to send them to
+	Launch(ctx context.Context, idToken, state string, client ClientInfo) (string, error)
+	CompleteDeepLink(ctx context.Context, userID, deepLinkID primitive.ObjectID, input CompleteDeepLinkInput) (*DeepLinkResponse, error)
+}
+
+type RegisterLTIPlatformInput struct {
+	Name          string   `json:"name" binding:"required"`
+	Issuer        string   `json:"issuer" binding:"required,url"`
+	ClientID      string   `json:"client_id" binding:"required"`
+	DeploymentIDs []string `json:"deployment_ids" binding:"required,min=1,dive,required"`
+	AuthLoginURL  string   `json:"auth_login_url" binding:"required,url"`
+	// AuthTokenURL is only needed for grade passback
+	AuthTokenURL string `json:"auth_token_url" binding:"omitempty,url"`
+	KeySetURL    string `json:"key_set_url" binding:"required,url"`
+}
+
+type LTIPlatformResponse struct {
+	ID            primitive.ObjectID `json:"id"`
+	Name          string             `json:"name"`
+	Issuer        string             `json:"issuer"`
+	ClientID      string             `json:"client_id"`
+	DeploymentIDs []string           `json:"deployment_ids"`
+	AuthLoginURL  string             `json:"auth_login_url"`
+	AuthTokenURL  string             `json:"auth_token_url,omitempty"`
+	KeySetURL     string             `json:"key_set_url"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type LTIToolConfiguration struct {
+	LoginURL  string `json:"login_url"`
+	LaunchURL string `json:"launch_url"`
+	// DeepLinkingURL is the launch URL too; it is listed separately because
+	// platforms ask for it separately
+	DeepLinkingURL string `json:"deep_linking_url"`
+	KeySetURL      string `json:"key_set_url"`
+}
+
+type CompleteDeepLinkInput struct {
+	CourseIDs []string `json:"course_ids" binding:"required,min=1,max=50"`
+}
+
+// DeepLinkResponse is posted by the browser to the return URL, as the JWT form field.
+type DeepLinkResponse struct {
+	ReturnURL string `json:"return_url"`
+	JWT       string `json:"jwt"`
+}
+
+type ltiService struct {
+	platformRepo   repositories.LTIPlatformRepository
+	stateRepo      repositories.LTILoginStateRepository
+	identityRepo   repositories.LTIIdentityRepository
+	linkRepo       repositories.LTICourseLinkRepository
+	deepLinkRepo   repositories.LTIDeepLinkRepository
+	userRepo       repositories.UserRepository
+	policyRepo     repositories.SecurityPolicyRepository
+	courseRepo     repositories.CourseRepository
+	courseService  CourseService
+	sessionService SessionService
+	key            *lti.ToolKey
+	validator      *lti.Validator
+	clock          pkg.Clock
+}
+
+func NewLTIService(platformRepo repositories.LTIPlatformRepository, stateRepo repositories.LTILoginStateRepository, identityRepo repositories.LTIIdentityRepository, linkRepo repositories.LTICourseLinkRepository, deepLinkRepo repositories.LTIDeepLinkRepository, userRepo repositories.UserRepository, policyRepo repositories.SecurityPolicyRepository, courseRepo repositories.CourseRepository, courseService CourseService, sessionService SessionService, key *lti.ToolKey, validator *lti.Validator, clock pkg.Clock) LTIService {
+	return &ltiService{
+		platformRepo:   platformRepo,
+		stateRepo:      stateRepo,
+		identityRepo:   identityRepo,
+		linkRepo:       linkRepo,
+		deepLinkRepo:   deepLinkRepo,
+		userRepo:       userRepo,
+		policyRepo:     policyRepo,
+		courseRepo:     courseRepo,
+		courseService:  courseService,
+		sessionService: sessionService,
+		key:            key,
+		validator:      validator,
+		clock:          clock,
+	}
+}
+
+// ltiToolURL is where platforms reach the API
+func ltiToolURL() string {
+	if toolURL := os.Getenv("LTI_TOOL_URL"); toolURL != "" {
+		return strings.TrimSuffix(toolURL, "/")
+	}
+	return "http://localhost:8085"
+}
+
+// ltiFrontendURL is where launched users are sent
+func ltiFrontendURL() string {
+	if frontendURL := os.Getenv("FRONTEND_URL"); frontendURL != "" {
+		return strings.TrimSuffix(frontendURL, "/")
+	}
+	return "http://localhost:5173"
+}
+
+func (s *ltiService) ToolConfiguration() LTIToolConfiguration {
+	base := ltiToolURL() + "/api/v1/lti"
+	return LTIToolConfiguration{
+		LoginURL:       base + "/login",
+		LaunchURL:      base + "/launch",
+		DeepLinkingURL: base + "/launch",
+		KeySetURL:      base + "/jwks",
+	}
+}
+
+func (s *ltiService) KeySet() lti.KeySet {
+	return s.key.KeySet()
+}
+
+func (s *ltiService) RegisterPlatform(ctx context.Context, input RegisterLTIPlatformInput) (*LTIPlatformResponse, error) {
+	existing, err := s.platformRepo.FindByIssuer(input.Issuer, input.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	// Across organizations too: logins name only the issuer and client ID
+	if len(existing) > 0 {
+		return nil, ErrLTIPlatformExists
+	}
+	platform := &models.LTIPlatform{
+		Name:          strings.TrimSpace(input.Name),
+		Issuer:        input.Issuer,
+		ClientID:      input.ClientID,
+		DeploymentIDs: input.DeploymentIDs,
+		AuthLoginURL:  input.AuthLoginURL,
+		AuthTokenURL:  input.AuthTokenURL,
+		KeySetURL:     input.KeySetURL,
+		CreatedAt:     s.clock.Now(),
+	}
+	if err := s.platformRepo.Create(ctx, platform); err != nil {
+		return nil, err
+	}
+	response := toLTIPlatformResponse(*platform)
+	return &response, nil
+}
+
+type ltiPlatformCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+func (s *ltiService) ListPlatforms(ctx context.Context, page pkg.PageRequest) (*pkg.Page[LTIPlatformResponse], error) {
+	var after ltiPlatformCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	platforms, err := s.platformRepo.FindPage(ctx, after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]LTIPlatformResponse, 0, len(platforms))
+	for _, platform := range platforms {
+		responses = append(responses, toLTIPlatformResponse(platform))
+	}
+	result := pkg.NewPage(responses, limit, func(platform LTIPlatformResponse) any { return ltiPlatformCursor{ID: platform.ID} })
+	return &result, nil
+}
+
+func (s *ltiService) DeletePlatform(ctx context.Context, platformID primitive.ObjectID) error {
+	deleted, err := s.platformRepo.Delete(ctx, platformID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrLTIPlatformNotFound
+	}
+	return nil
+}
+
+func toLTIPlatformResponse(platform models.LTIPlatform) LTIPlatformResponse {
+	return LTIPlatformResponse{
+		ID:            platform.ID,
+		Name:          platform.Name,
+		Issuer:        platform.Issuer,
+		ClientID:      platform.ClientID,
+		DeploymentIDs: platform.DeploymentIDs,
+		AuthLoginURL:  platform.AuthLoginURL,
+		AuthTokenURL:  platform.AuthTokenURL,
+		KeySetURL:     platform.KeySetURL,
+		CreatedAt:     platform.CreatedAt,
+	}
+}
+
+func toLTIPlatform(platform *models.LTIPlatform) lti.Platform {
+	return lti.Platform{
+		Issuer:        platform.Issuer,
+		ClientID:      platform.ClientID,
+		AuthLoginURL:  platform.AuthLoginURL,
+		AuthTokenURL:  platform.AuthTokenURL,
+		KeySetURL:     platform.KeySetURL,
+		DeploymentIDs: platform.DeploymentIDs,
+	}
+}
+
+func (s *ltiService) Login(ctx context.Context, login lti.LoginRequest) (string, error) {
+	if login.Issuer == "" || login.LoginHint == "" {
+		return "", fmt.Errorf("%w: iss and login_hint are required", lti.ErrInvalidLaunch)
+	}
+	platforms, err := s.platformRepo.FindByIssuer(login.Issuer, login.ClientID)
+	if err != nil {
+		return "", err
+	}
+	// Without a client ID, the deployment tells registrations of one issuer apart
+	if len(platforms) > 1 && login.LTIDeploymentID != "" {
+		matching := platforms[:0]
+		for _, platform := range platforms {
+			for _, deploymentID := range platform.DeploymentIDs {
+				if deploymentID == login.LTIDeploymentID {
+					matching = append(matching, platform)
+					break
+				}
+			}
+		}
+		platforms = matching
+	}
+	switch {
+	case len(platforms) == 0:
+		return "", ErrLTIPlatformNotFound
+	case len(platforms) > 1:
+		return "", fmt.Errorf("%w: the login must name the client_id", lti.ErrInvalidLaunch)
+	}
+	platform := platforms[0]
+
+	state := &models.LTILoginState{
+		OrganizationID: platform.OrganizationID,
+		State:          lti.RandomString(),
+		Nonce:          lti.RandomString(),
+		PlatformID:     platform.ID,
+		ExpiresAt:      s.clock.Now().Add(ltiLoginTTL),
+	}
+	if err := s.stateRepo.Create(state); err != nil {
+		return "", err
+	}
+	return toLTIPlatform(&platform).AuthenticationURL(login, s.ToolConfiguration().LaunchURL, state.State, state.Nonce)
+}
+
+func (s *ltiService) Launch(ctx context.Context, idToken, state string, client ClientInfo) (string, error) {
+	// Taking the state makes every login good for one launch
+	loginState, err := s.stateRepo.Take(state)
+	if err != nil {
+		return "", err
+	}
+	if loginState == nil || s.clock.Now().After(loginState.ExpiresAt) {
+		return "", ErrLTILoginExpired
+	}
+	ctx = tenant.WithOrganization(ctx, loginState.OrganizationID)
+	platform, err := s.platformRepo.FindByID(ctx, loginState.PlatformID)
+	if err != nil {
+		return "", err
+	}
+	if platform == nil {
+		return "", ErrLTIPlatformNotFound
+	}
+	launch, err := s.validator.Validate(ctx, idToken, toLTIPlatform(platform))
+	if err != nil {
+		return "", err
+	}
+	if launch.Nonce != loginState.Nonce {
+		return "", fmt.Errorf("%w: the nonce does not match the login", lti.ErrInvalidLaunch)
+	}
+	if launch.MessageType == lti.MessageDeepLinkingRequest && !launch.DeepLinking.Accepts(lti.ContentItemLink) {
+		return "", fmt.Errorf("%w: the platform does not accept links", lti.ErrInvalidLaunch)
+	}
+
+	user, err := s.launchUser(ctx, platform, launch)
+	if err != nil {
+		return "", err
+	}
+	fragment := url.Values{}
+
+	if launch.MessageType == lti.MessageDeepLinkingRequest {
+		if role := user.EffectiveRole(); role != models.RoleInstructor && role != models.RoleAdmin {
+			return "", ErrDeepLinkNotAllowed
+		}
+		deepLink := &models.LTIDeepLink{
+			PlatformID:     platform.ID,
+			DeploymentID:   launch.DeploymentID,
+			UserID:         user.ID,
+			ReturnURL:      launch.DeepLinking.ReturnURL,
+			AcceptMultiple: launch.DeepLinking.AcceptMultiple,
+			Data:           launch.DeepLinking.Data,
+			ExpiresAt:      s.clock.Now().Add(ltiDeepLinkTTL),
+		}
+		if err := s.deepLinkRepo.Create(ctx, deepLink); err != nil {
+			return "", err
+		}
+		fragment.Set("deep_link_id", deepLink.ID.Hex())
+		fragment.Set("accept_multiple", fmt.Sprint(deepLink.AcceptMultiple))
+	} else {
+		course, err := s.launchCourse(ctx, platform, launch)
+		if err != nil {
+			return "", err
+		}
+		if user.EffectiveRole() == models.RoleStudent {
+			if _, err := s.courseService.Enroll(ctx, user.ID, course.ID); err != nil {
+				return "", err
+			}
+		}
+		link := &models.LTICourseLink{
+			PlatformID:     platform.ID,
+			DeploymentID:   launch.DeploymentID,
+			ResourceLinkID: launch.ResourceLink.ID,
+			CourseID:       course.ID,
+			UpdatedAt:      s.clock.Now(),
+		}
+		if launch.Context != nil {
+			link.ContextID = launch.Context.ID
+		}
+		if launch.Endpoint != nil {
+			link.LineItemsURL = launch.Endpoint.LineItems
+			link.LineItemURL = launch.Endpoint.LineItem
+			link.Scopes = launch.Endpoint.Scope
+		}
+		if _, err := s.linkRepo.Save(ctx, link, user.ID); err != nil {
+			return "", err
+		}
+		fragment.Set("course_id", course.ID.Hex())
+	}
+
+	// Tokens are sent in the fragment, which browsers keep out of logs and referrers
+	if err := s.signIn(user, client, fragment); err != nil {
+		return "", err
+	}
+	page := "/lti/launch"
+	if launch.MessageType == lti.MessageDeepLinkingRequest {
+		page = "/lti/deep-link"
+	}
+	return ltiFrontendURL() + page + "#" + fragment.Encode(), nil
+}
+
+// signIn adds the launching user's token to fragment. Like a password login,
+// a launch only gets users with 2FA a challenge to complete with their
+// second factor, and users the policy requires 2FA of a token for setting it up.
+func (s *ltiService) signIn(user *models.User, client ClientInfo, fragment url.Values) error {
+	if user.TwoFactor.Enabled {
+		challenge, err := pkg.GenerateChallengeToken(user.ID)
+		if err != nil {
+			return err
+		}
+		fragment.Set("two_factor_required", "true")
+		fragment.Set("challenge_token", challenge)
+		return nil
+	}
+
+	policy, err := s.policyRepo.Get()
+	if err != nil {
+		return err
+	}
+	setupRequired := policy.RequiresTwoFactor(user.EffectiveRole())
+	token, err := issueSessionToken(s.sessionService, user, client, setupRequired)
+	if err != nil {
+		return err
+	}
+	fragment.Set("token", token)
+	if setupRequired {
+		fragment.Set("two_factor_setup_required", "true")
+	}
+	return nil
+}
+
+// launchUser finds the user a launch signs in: the one who launched from the
+// platform before, else an account the platform created with the same email,
+// e.g. after the LMS gave the user a new ID. Anyone else gets an account, as
+// an instructor if the LMS says they teach. Accounts made outside the
+// platform are never signed in by email, since the platform vouches for the
+// email but not for owning the account.
+func (s *ltiService) launchUser(ctx context.Context, platform *models.LTIPlatform, launch *lti.Launch) (*models.User, error) {
+	identity, err := s.identityRepo.Find(ctx, platform.ID, launch.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user.OrganizationID != platform.OrganizationID {
+			return nil, ErrLTIUserConflict
+		}
+		return user, nil
+	}
+
+	var user *models.User
+	email := strings.TrimSpace(launch.Email)
+	if email != "" {
+		existing, err := s.userRepo.FindByEmail(email)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if existing != nil {
+			created, err := s.createdByPlatform(ctx, platform, existing)
+			if err != nil {
+				return nil, err
+			}
+			if !created {
+				return nil, ErrLTIUserConflict
+			}
+			user = existing
+		}
+	}
+	createdUser := user == nil
+	if createdUser {
+		if user, err = s.createLaunchUser(platform, launch, email); err != nil {
+			return nil, err
+		}
+	}
+
+	identity = &models.LTIIdentity{PlatformID: platform.ID, Subject: launch.Subject, UserID: user.ID, CreatedUser: createdUser, CreatedAt: s.clock.Now()}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createdByPlatform reports whether a launch from the platform created the user
+func (s *ltiService) createdByPlatform(ctx context.Context, platform *models.LTIPlatform, user *models.User) (bool, error) {
+	// Admins and operators are never matched, so a platform cannot sign in as one
+	if role := user.EffectiveRole(); user.OrganizationID != platform.OrganizationID || role == models.RoleAdmin || role == models.RoleOperator {
+		return false, nil
+	}
+	identities, err := s.identityRepo.FindByUser(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, identity := range identities {
+		if identity.PlatformID == platform.ID && identity.CreatedUser {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *ltiService) createLaunchUser(platform *models.LTIPlatform, launch *lti.Launch, email string) (*models.User, error) {
+	// Launched users sign in through the LMS; the password only keeps the account closed otherwise
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(lti.RandomString()), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	role := models.RoleStudent
+	if launch.HasRole(lti.RoleInstructor) || launch.HasRole(lti.RoleContentDeveloper) {
+		role = models.RoleInstructor
+	}
+	firstName, lastName := launch.GivenName, launch.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(launch.Name), " ")
+	}
+	user := &models.User{
+		ID:             primitive.NewObjectID(),
+		FirstName:      firstName,
+		LastName:       lastName,
+		Email:          email,
+		PasswordHash:   string(hashedPassword),
+		Role:           role,
+		OrganizationID: platform.OrganizationID,
+		Level:          1,
+		Profile:        models.UserProfile{Notifications: models.DefaultNotificationPreferences()},
+		CreatedAt:      s.clock.Now(),
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// launchCourse resolves the course a resource link launches: a deep linked
+// link names it in its custom parameters, a manually added one in its
+// target link URI, and a relaunch finds it by the link.
+func (s *ltiService) launchCourse(ctx context.Context, platform *models.LTIPlatform, launch *lti.Launch) (*models.Course, error) {
+	slug := launch.Custom[ltiCourseParameter]
+	if slug == "" {
+		if target, err := url.Parse(launch.TargetLinkURI); err == nil {
+			slug = target.Query().Get(ltiCourseParameter)
+		}
+	}
+	if slug != "" {
+		course, err := s.courseRepo.FindBySlug(ctx, slug)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCourseNotFound
+		}
+		return course, err
+	}
+
+	link, err := s.linkRepo.FindByResourceLink(ctx, platform.ID, launch.ResourceLink.ID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrLTICourseNotLinked
+	}
+	course, err := s.courseRepo.FindByID(ctx, link.CourseID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCourseNotFound
+	}
+	return course, err
+}
+
+func (s *ltiService) CompleteDeepLink(ctx context.Context, userID, deepLinkID primitive.ObjectID, input CompleteDeepLinkInput) (*DeepLinkResponse, error) {
+	deepLink, err := s.deepLinkRepo.Find(ctx, deepLinkID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if deepLink == nil || s.clock.Now().After(deepLink.ExpiresAt) {
+		return nil, ErrDeepLinkNotFound
+	}
+	if len(input.CourseIDs) > 1 && !deepLink.AcceptMultiple {
+		return nil, fmt.Errorf("%w: the LMS takes one course at a time", ErrInvalidDeepLink)
+	}
+	platform, err := s.platformRepo.FindByID(ctx, deepLink.PlatformID)
+	if err != nil {
+		return nil, err
+	}
+	if platform == nil {
+		return nil, ErrLTIPlatformNotFound
+	}
+
+	launchURL := s.ToolConfiguration().LaunchURL
+	items := make([]lti.ContentItem, 0, len(input.CourseIDs))
+	for _, hexID := range input.CourseIDs {
+		courseID, err := primitive.ObjectIDFromHex(hexID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid course ID %q", ErrInvalidDeepLink, hexID)
+		}
+		course, err := s.courseRepo.FindByID(ctx, courseID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrCourseNotFound
+			}
+			return nil, err
+		}
+		if !course.IsPublished() {
+			return nil, ErrCourseNotFound
+		}
+		items = append(items, lti.ContentItem{
+			Type:   lti.ContentItemLink,
+			Title:  course.Title,
+			Text:   course.Description,
+			URL:    launchURL,
+			Custom: map[string]string{ltiCourseParameter: course.Slug},
+			// The column receives the course's completion
+			LineItem: &lti.ContentLineItem{Label: course.Title, ScoreMaximum: 100, ResourceID: "course:" + course.ID.Hex()},
+		})
+	}
+
+	jwt, err := s.key.DeepLinkingResponse(toLTIPlatform(platform), deepLink.DeploymentID, lti.DeepLinkingSettings{Data: deepLink.Data}, items)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := s.deepLinkRepo.Delete(ctx, deepLink.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Another request completed it first
+	if !deleted {
+		return nil, ErrDeepLinkNotFound
+	}
+	return &DeepLinkResponse{ReturnURL: deepLink.ReturnURL, JWT: jwt}, nil
+}
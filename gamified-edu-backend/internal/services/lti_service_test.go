@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"gamified-edu-backend/internal/lti"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (r *fakeUsers) FindByEmail(email string) (*models.User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeUsers) Create(user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+type fakeLTIPlatforms struct {
+	repositories.LTIPlatformRepository
+	platform *models.LTIPlatform
+}
+
+func (r *fakeLTIPlatforms) FindByID(ctx context.Context, id primitive.ObjectID) (*models.LTIPlatform, error) {
+	if id != r.platform.ID {
+		return nil, nil
+	}
+	return r.platform, nil
+}
+
+type fakeLTILoginStates struct {
+	repositories.LTILoginStateRepository
+	states map[string]*models.LTILoginState
+}
+
+func (r *fakeLTILoginStates) Take(state string) (*models.LTILoginState, error) {
+	loginState := r.states[state]
+	delete(r.states, state)
+	return loginState, nil
+}
+
+type fakeLTIIdentities struct {
+	repositories.LTIIdentityRepository
+	identities []models.LTIIdentity
+}
+
+func (r *fakeLTIIdentities) Find(ctx context.Context, platformID primitive.ObjectID, subject string) (*models.LTIIdentity, error) {
+	for i := range r.identities {
+		if r.identities[i].PlatformID == platformID && r.identities[i].Subject == subject {
+			return &r.identities[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeLTIIdentities) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.LTIIdentity, error) {
+	identities := []models.LTIIdentity{}
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *fakeLTIIdentities) Create(ctx context.Context, identity *models.LTIIdentity) error {
+	identity.ID = primitive.NewObjectID()
+	r.identities = append(r.identities, *identity)
+	return nil
+}
+
+type fakeLTIDeepLinks struct {
+	repositories.LTIDeepLinkRepository
+	deepLinks []*models.LTIDeepLink
+}
+
+func (r *fakeLTIDeepLinks) Create(ctx context.Context, deepLink *models.LTIDeepLink) error {
+	deepLink.ID = primitive.NewObjectID()
+	r.deepLinks = append(r.deepLinks, deepLink)
+	return nil
+}
+
+type fakeSecurityPolicy struct {
+	repositories.SecurityPolicyRepository
+	policy models.SecurityPolicy
+}
+
+func (r *fakeSecurityPolicy) Get() (*models.SecurityPolicy, error) {
+	policy := r.policy
+	return &policy, nil
+}
+
+type fakeSessions struct {
+	SessionService
+	created int
+}
+
+func (s *fakeSessions) CreateSession(userID primitive.ObjectID, client ClientInfo) (*models.Session, error) {
+	s.created++
+	return &models.Session{ID: primitive.NewObjectID(), UserID: userID}, nil
+}
+
+// ltiLaunchTest is an LMS registered with the tool, which launches deep
+// linking requests signed with its key
+type ltiLaunchTest struct {
+	t          *testing.T
+	key        *lti.ToolKey
+	keySet     *httptest.Server
+	platform   *models.LTIPlatform
+	states     *fakeLTILoginStates
+	users      *fakeUsers
+	identities *fakeLTIIdentities
+	deepLinks  *fakeLTIDeepLinks
+	policy     *fakeSecurityPolicy
+	sessions   *fakeSessions
+	clock      pkg.FixedClock
+	service    LTIService
+}
+
+func newLTILaunchTest(t *testing.T) *ltiLaunchTest {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := &ltiLaunchTest{
+		t:          t,
+		key:        lti.NewToolKey(private),
+		states:     &fakeLTILoginStates{states: map[string]*models.LTILoginState{}},
+		users:      &fakeUsers{users: map[primitive.ObjectID]*models.User{}},
+		identities: &fakeLTIIdentities{},
+		deepLinks:  &fakeLTIDeepLinks{},
+		policy:     &fakeSecurityPolicy{},
+		sessions:   &fakeSessions{},
+		clock:      pkg.FixedClock(time.Now()),
+	}
+	test.keySet = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(test.key.KeySet())
+	}))
+	t.Cleanup(test.keySet.Close)
+	test.platform = &models.LTIPlatform{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: primitive.NewObjectID(),
+		Issuer:         "https://lms.example.org",
+		ClientID:       "tool-client",
+		DeploymentIDs:  []string{"deployment-1"},
+		KeySetURL:      test.keySet.URL,
+	}
+	test.service = NewLTIService(&fakeLTIPlatforms{platform: test.platform}, test.states, test.identities, nil, test.deepLinks,
+		test.users, test.policy, nil, nil, test.sessions, nil, lti.NewValidator(lti.NewKeySets(test.keySet.Client())), test.clock)
+	return test
+}
+
+// launch sends a deep linking request from the platform's user subject
+// with email and returns the fragment the browser is sent to
+func (test *ltiLaunchTest) launch(subject, email string) (url.Values, error) {
+	state := lti.RandomString()
+	test.states.states[state] = &models.LTILoginState{
+		OrganizationID: test.platform.OrganizationID,
+		State:          state,
+		Nonce:          "nonce-" + state,
+		PlatformID:     test.platform.ID,
+		ExpiresAt:      test.clock.Now().Add(time.Minute),
+	}
+	now := test.clock.Now()
+	idToken, err := test.key.Sign(jwt.MapClaims{
+		"iss":   test.platform.Issuer,
+		"aud":   test.platform.ClientID,
+		"sub":   subject,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"nonce": "nonce-" + state,
+		"email": email,
+		"name":  "Grace Hopper",
+		"https://purl.imsglobal.org/spec/lti/claim/message_type":  lti.MessageDeepLinkingRequest,
+		"https://purl.imsglobal.org/spec/lti/claim/version":       lti.Version,
+		"https://purl.imsglobal.org/spec/lti/claim/deployment_id": "deployment-1",
+		"https://purl.imsglobal.org/spec/lti/claim/roles":         []string{lti.RoleInstructor},
+		"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings": map[string]interface{}{
+			"deep_link_return_url": test.platform.Issuer + "/deep-links/return",
+			"accept_types":         []string{lti.ContentItemLink},
+		},
+	})
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	redirect, err := test.service.Launch(context.Background(), idToken, state, ClientInfo{})
+	if err != nil {
+		return nil, err
+	}
+	target, err := url.Parse(redirect)
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	fragment, err := url.ParseQuery(target.Fragment)
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	return fragment, nil
+}
+
+func (test *ltiLaunchTest) addUser(email, role string) *models.User {
+	user := &models.User{ID: primitive.NewObjectID(), Email: email, Role: role, OrganizationID: test.platform.OrganizationID}
+	test.users.users[user.ID] = user
+	return user
+}
+
+func TestLTILaunchCreatesAccountsAndSignsThemIn(t *testing.T) {
+	test := newLTILaunchTest(t)
+
+	fragment, err := test.launch("lms-1", "grace@example.com")
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	if fragment.Get("token") == "" || fragment.Get("deep_link_id") == "" {
+		t.Errorf("got fragment %v, want a token and the deep link", fragment)
+	}
+	if len(test.users.users) != 1 || len(test.identities.identities) != 1 {
+		t.Fatalf("got %d users and %d identities, want the launch to create one of each", len(test.users.users), len(test.identities.identities))
+	}
+	identity := test.identities.identities[0]
+	user := test.users.users[identity.UserID]
+	if !identity.CreatedUser || user.Email != "grace@example.com" || user.Role != models.RoleInstructor {
+		t.Errorf("got identity %+v for user %+v", identity, user)
+	}
+
+	// The LMS gave the user a new ID; their email still finds the account it created
+	if _, err := test.launch("lms-1-renamed", "grace@example.com"); err != nil {
+		t.Fatalf("Launch with a new subject: %v", err)
+	}
+	if len(test.users.users) != 1 || len(test.identities.identities) != 2 || test.identities.identities[1].UserID != user.ID {
+		t.Errorf("got %d users and identities %+v, want the new subject linked to the created account", len(test.users.users), test.identities.identities)
+	}
+}
+
+func TestLTILaunchRefusesAccountsItDidNotCreate(t *testing.T) {
+	for _, role := range []string{models.RoleStudent, models.RoleInstructor, models.RoleAdmin} {
+		t.Run(role, func(t *testing.T) {
+			test := newLTILaunchTest(t)
+			test.addUser("grace@example.com", role)
+
+			_, err := test.launch("lms-1", "Grace@example.com")
+			if !errors.Is(err, ErrLTIUserConflict) {
+				t.Errorf("got %v, want ErrLTIUserConflict", err)
+			}
+			if len(test.identities.identities) != 0 || test.sessions.created != 0 {
+				t.Errorf("the platform got %d identities and %d sessions for an account registered directly", len(test.identities.identities), test.sessions.created)
+			}
+		})
+	}
+}
+
+func TestLTILaunchRefusesAccountsAnotherPlatformCreated(t *testing.T) {
+	test := newLTILaunchTest(t)
+	user := test.addUser("grace@example.com", models.RoleStudent)
+	test.identities.identities = append(test.identities.identities, models.LTIIdentity{PlatformID: primitive.NewObjectID(), Subject: "lms-1", UserID: user.ID, CreatedUser: true})
+
+	if _, err := test.launch("lms-1", "grace@example.com"); !errors.Is(err, ErrLTIUserConflict) {
+		t.Errorf("got %v, want ErrLTIUserConflict", err)
+	}
+}
+
+func TestLTILaunchAsksForTheSecondFactor(t *testing.T) {
+	test := newLTILaunchTest(t)
+	user := test.addUser("grace@example.com", models.RoleInstructor)
+	user.TwoFactor.Enabled = true
+	test.identities.identities = append(test.identities.identities, models.LTIIdentity{PlatformID: test.platform.ID, Subject: "lms-1", UserID: user.ID})
+
+	fragment, err := test.launch("lms-1", "grace@example.com")
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	if fragment.Get("token") != "" || test.sessions.created != 0 {
+		t.Errorf("got fragment %v and %d sessions, want no session before the second factor", fragment, test.sessions.created)
+	}
+	if fragment.Get("two_factor_required") != "true" || fragment.Get("challenge_token") == "" {
+		t.Errorf("got fragment %v, want a challenge", fragment)
+	}
+}
+
+func TestLTILaunchRequiresTwoFactorSetup(t *testing.T) {
+	test := newLTILaunchTest(t)
+	test.policy.policy.TwoFactorRequiredRoles = []string{models.RoleInstructor}
+
+	fragment, err := test.launch("lms-1", "grace@example.com")
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	if fragment.Get("token") == "" || fragment.Get("two_factor_setup_required") != "true" {
+		t.Errorf("got fragment %v, want a setup token", fragment)
+	}
+}
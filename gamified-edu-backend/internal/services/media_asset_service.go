@@ -0,0 +1,355 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/media"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/tenant"
+	"gamified-edu-backend/pkg"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// AssetURLPrefix marks component URLs that point at an uploaded asset,
+	// e.g. "asset:<id>". Learners get a signed download URL in its place.
+	AssetURLPrefix = "asset:"
+
+	assetContentPath = "/api/v1/media/assets/%s/content"
+	// assetURLTTL keeps download URLs valid for a long video with pauses
+	assetURLTTL = 6 * time.Hour
+
+	maxVideoAssetSize = 2 << 30
+	maxAssetSize      = 100 << 20
+	// MaxAssetUpload caps upload requests: the largest asset and the form around it
+	MaxAssetUpload = maxVideoAssetSize + 1<<20
+)
+
+// assetSizeLimits are the content types assets may have, with their size limits
+var assetSizeLimits = map[string]int64{
+	"video/mp4":       maxVideoAssetSize,
+	"video/webm":      maxVideoAssetSize,
+	"audio/mpeg":      maxAssetSize,
+	"audio/wave":      maxAssetSize,
+	"image/png":       maxAssetSize,
+	"image/jpeg":      maxAssetSize,
+	"image/gif":       maxAssetSize,
+	"image/webp":      maxAssetSize,
+	"application/pdf": maxAssetSize,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": maxAssetSize,
+	"text/vtt": maxAssetSize,
+}
+
+var (
+	ErrAssetNotFound         = errors.New("media asset not found")
+	ErrAssetTypeNotAllowed   = errors.New("this kind of file cannot be uploaded")
+	ErrAssetTooLarge         = errors.New("the file is too large")
+	ErrAssetChecksumMismatch = errors.New("the file does not match its checksum")
+)
+
+// MediaAssetService keeps the organization's uploaded videos, slides and
+// other files, and hands out signed URLs to download them.
+type MediaAssetService interface {
+	Upload(ctx context.Context, userID primitive.ObjectID, input UploadAssetInput, file io.ReadSeeker, size int64) (*MediaAssetResponse, error)
+	ListAssets(ctx context.Context, page pkg.PageRequest) (*pkg.Page[MediaAssetResponse], error)
+	GetAsset(ctx context.Context, assetID primitive.ObjectID) (*MediaAssetResponse, error)
+	DeleteAsset(ctx context.Context, assetID primitive.ObjectID) error
+	// OpenContent verifies a signed download URL and opens the asset's content
+	OpenContent(ctx context.Context, assetID primitive.ObjectID, path string, query url.Values) (*models.MediaAsset, media.Blob, error)
+	// PrepareFile checks a file the way Upload does and returns the asset it
+	// is kept as: the organization's asset with the same content, reported as
+	// stored, or a new one with its ID assigned, which SaveFile stores
+	PrepareFile(ctx context.Context, userID primitive.ObjectID, fileName string, data []byte) (asset *models.MediaAsset, stored bool, err error)
+	SaveFile(ctx context.Context, asset *models.MediaAsset, data []byte) error
+	// OpenFile returns the file name and content of the organization's asset
+	// a ref links
+	OpenFile(ctx context.Context, ref string) (string, []byte, error)
+}
+
+type UploadAssetInput struct {
+	FileName string
+	// SHA256 is the checksum the client computed, verified if not empty
+	SHA256 string
+}
+
+type MediaAssetResponse struct {
+	ID          primitive.ObjectID `json:"id"`
+	FileName    string             `json:"file_name"`
+	ContentType string             `json:"content_type"`
+	Size        int64              `json:"size"`
+	SHA256      string             `json:"sha256"`
+	// Ref is what component URLs use to link the asset
+	Ref                  string             `json:"ref"`
+	DownloadURL          string             `json:"download_url"`
+	DownloadURLExpiresAt time.Time          `json:"download_url_expires_at"`
+	UploadedBy           primitive.ObjectID `json:"uploaded_by"`
+	CreatedAt            time.Time          `json:"created_at"`
+}
+
+type mediaAssetService struct {
+	assetRepo repositories.MediaAssetRepository
+	blobs     media.BlobStore
+}
+
+func NewMediaAssetService(assetRepo repositories.MediaAssetRepository, blobs media.BlobStore) MediaAssetService {
+	return &mediaAssetService{assetRepo: assetRepo, blobs: blobs}
+}
+
+// Upload stores the file unless the organization has it already, in which
+// case the existing asset is returned.
+func (s *mediaAssetService) Upload(ctx context.Context, userID primitive.ObjectID, input UploadAssetInput, file io.ReadSeeker, size int64) (*MediaAssetResponse, error) {
+	head := make([]byte, media.SniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	contentType, err := assetContentType(head[:n], input.FileName, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if input.SHA256 != "" && !strings.EqualFold(input.SHA256, checksum) {
+		return nil, ErrAssetChecksumMismatch
+	}
+
+	existing, err := s.assetRepo.FindByChecksum(ctx, checksum)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return toMediaAssetResponse(*existing), nil
+	}
+
+	asset := newMediaAsset(ctx, userID, input.FileName, contentType, size, checksum)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := s.blobs.Put(ctx, asset.Key, file, size, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.assetRepo.Create(ctx, asset); err != nil {
+		return nil, err
+	}
+	return toMediaAssetResponse(*asset), nil
+}
+
+func (s *mediaAssetService) PrepareFile(ctx context.Context, userID primitive.ObjectID, fileName string, data []byte) (*models.MediaAsset, bool, error) {
+	contentType, err := assetContentType(data[:min(len(data), media.SniffLength)], fileName, int64(len(data)))
+	if err != nil {
+		return nil, false, err
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	existing, err := s.assetRepo.FindByChecksum(ctx, checksum)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, true, nil
+	}
+	asset := newMediaAsset(ctx, userID, fileName, contentType, int64(len(data)), checksum)
+	asset.ID = primitive.NewObjectID()
+	return asset, false, nil
+}
+
+func (s *mediaAssetService) SaveFile(ctx context.Context, asset *models.MediaAsset, data []byte) error {
+	if err := s.blobs.Put(ctx, asset.Key, bytes.NewReader(data), int64(len(data)), asset.ContentType); err != nil {
+		return err
+	}
+	return s.assetRepo.Create(ctx, asset)
+}
+
+func (s *mediaAssetService) OpenFile(ctx context.Context, ref string) (string, []byte, error) {
+	assetID, err := primitive.ObjectIDFromHex(strings.TrimPrefix(ref, AssetURLPrefix))
+	if err != nil || !strings.HasPrefix(ref, AssetURLPrefix) {
+		return "", nil, ErrAssetNotFound
+	}
+	asset, err := s.assetRepo.FindByID(ctx, assetID)
+	if err != nil {
+		return "", nil, err
+	}
+	if asset == nil {
+		return "", nil, ErrAssetNotFound
+	}
+	blob, err := s.blobs.Open(ctx, asset.Key)
+	if err != nil {
+		if errors.Is(err, media.ErrBlobNotFound) {
+			return "", nil, ErrAssetNotFound
+		}
+		return "", nil, err
+	}
+	defer blob.Close()
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		return "", nil, err
+	}
+	return asset.FileName, data, nil
+}
+
+// assetContentType detects the type of a file from its first bytes and
+// checks that assets may have the type and the size
+func assetContentType(head []byte, fileName string, size int64) (string, error) {
+	contentType := media.SniffContentType(head, fileName)
+	limit, ok := assetSizeLimits[contentType]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrAssetTypeNotAllowed, contentType)
+	}
+	if size > limit {
+		return "", fmt.Errorf("%w: %s files can have up to %d MB", ErrAssetTooLarge, contentType, limit>>20)
+	}
+	return contentType, nil
+}
+
+// newMediaAsset describes content of the organization in ctx, stored under
+// its checksum
+func newMediaAsset(ctx context.Context, userID primitive.ObjectID, fileName, contentType string, size int64, checksum string) *models.MediaAsset {
+	organizationID, _ := tenant.OrganizationID(ctx)
+	return &models.MediaAsset{
+		Key:         organizationID.Hex() + "/" + checksum,
+		FileName:    assetFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      checksum,
+		UploadedBy:  userID,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// assetFileName keeps the base name of an uploaded file, for downloads
+func assetFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	return name
+}
+
+// assetCursor is the position after the last asset of a page
+type assetCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+// ListAssets lists a page of the organization's assets, newest first
+func (s *mediaAssetService) ListAssets(ctx context.Context, page pkg.PageRequest) (*pkg.Page[MediaAssetResponse], error) {
+	var after assetCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	assets, err := s.assetRepo.FindPage(ctx, after.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]MediaAssetResponse, 0, len(assets))
+	for _, asset := range assets {
+		responses = append(responses, *toMediaAssetResponse(asset))
+	}
+	result := pkg.NewPage(responses, limit, func(asset MediaAssetResponse) any { return assetCursor{ID: asset.ID} })
+	return &result, nil
+}
+
+func (s *mediaAssetService) GetAsset(ctx context.Context, assetID primitive.ObjectID) (*MediaAssetResponse, error) {
+	asset, err := s.assetRepo.FindByID(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, ErrAssetNotFound
+	}
+	return toMediaAssetResponse(*asset), nil
+}
+
+// DeleteAsset removes the asset. Components still linking to it lose their file.
+func (s *mediaAssetService) DeleteAsset(ctx context.Context, assetID primitive.ObjectID) error {
+	asset, err := s.assetRepo.FindByID(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return ErrAssetNotFound
+	}
+	deleted, err := s.assetRepo.Delete(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAssetNotFound
+	}
+	// The asset is gone either way; a blob left behind only takes space
+	if err := s.blobs.Delete(ctx, asset.Key); err != nil {
+		log.Printf("Could not delete the content of media asset %s: %v", assetID.Hex(), err)
+	}
+	return nil
+}
+
+func (s *mediaAssetService) OpenContent(ctx context.Context, assetID primitive.ObjectID, path string, query url.Values) (*models.MediaAsset, media.Blob, error) {
+	if err := pkg.VerifySignedPath(path, query); err != nil {
+		return nil, nil, err
+	}
+	asset, err := s.assetRepo.FindForDownload(assetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if asset == nil {
+		return nil, nil, ErrAssetNotFound
+	}
+	blob, err := s.blobs.Open(ctx, asset.Key)
+	if err != nil {
+		if errors.Is(err, media.ErrBlobNotFound) {
+			return nil, nil, ErrAssetNotFound
+		}
+		return nil, nil, err
+	}
+	return asset, blob, nil
+}
+
+func toMediaAssetResponse(asset models.MediaAsset) *MediaAssetResponse {
+	expiresAt := time.Now().Add(assetURLTTL)
+	return &MediaAssetResponse{
+		ID:                   asset.ID,
+		FileName:             asset.FileName,
+		ContentType:          asset.ContentType,
+		Size:                 asset.Size,
+		SHA256:               asset.SHA256,
+		Ref:                  AssetURLPrefix + asset.ID.Hex(),
+		DownloadURL:          pkg.SignPath(fmt.Sprintf(assetContentPath, asset.ID.Hex()), expiresAt),
+		DownloadURLExpiresAt: expiresAt,
+		UploadedBy:           asset.UploadedBy,
+		CreatedAt:            asset.CreatedAt,
+	}
+}
+
+// resolveAssetURL turns a component URL linking an asset into a signed
+// download URL; other URLs are returned as they are.
+func resolveAssetURL(componentURL string) string {
+	assetID, ok := strings.CutPrefix(componentURL, AssetURLPrefix)
+	if !ok {
+		return componentURL
+	}
+	return pkg.SignPath(fmt.Sprintf(assetContentPath, assetID), time.Now().Add(assetURLTTL))
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/media"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/tenant"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeMediaAssets struct {
+	repositories.MediaAssetRepository
+	assets []*models.MediaAsset
+}
+
+func (r *fakeMediaAssets) Create(ctx context.Context, asset *models.MediaAsset) error {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return err
+	}
+	if asset.ID.IsZero() {
+		asset.ID = primitive.NewObjectID()
+	}
+	asset.OrganizationID = organizationID
+	r.assets = append(r.assets, asset)
+	return nil
+}
+
+func (r *fakeMediaAssets) find(ctx context.Context, match func(*models.MediaAsset) bool) (*models.MediaAsset, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range r.assets {
+		if asset.OrganizationID == organizationID && match(asset) {
+			return asset, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeMediaAssets) FindByID(ctx context.Context, id primitive.ObjectID) (*models.MediaAsset, error) {
+	return r.find(ctx, func(asset *models.MediaAsset) bool { return asset.ID == id })
+}
+
+func (r *fakeMediaAssets) FindByChecksum(ctx context.Context, sha256 string) (*models.MediaAsset, error) {
+	return r.find(ctx, func(asset *models.MediaAsset) bool { return asset.SHA256 == sha256 })
+}
+
+func TestMediaAssetFilesAreStoredOncePerOrganization(t *testing.T) {
+	assets := &fakeMediaAssets{}
+	service := NewMediaAssetService(assets, media.NewDirBlobStore(t.TempDir()))
+	ctx := tenant.WithOrganization(context.Background(), primitive.NewObjectID())
+	captions := []byte("WEBVTT\n\n00:00.000 --> 00:01.000\nHello\n")
+
+	asset, stored, err := service.PrepareFile(ctx, primitive.NilObjectID, "media/intro/captions.vtt", captions)
+	if err != nil {
+		t.Fatalf("PrepareFile: %v", err)
+	}
+	if stored || asset.ID.IsZero() || asset.ContentType != "text/vtt" || asset.FileName != "captions.vtt" {
+		t.Fatalf("got %+v, stored %v, want a new captions asset", asset, stored)
+	}
+	// Nothing is kept until the file is saved, so dry runs leave no assets behind
+	if _, _, err := service.OpenFile(ctx, AssetURLPrefix+asset.ID.Hex()); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("OpenFile before SaveFile: got %v, want ErrAssetNotFound", err)
+	}
+	if err := service.SaveFile(ctx, asset, captions); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+
+	name, data, err := service.OpenFile(ctx, AssetURLPrefix+asset.ID.Hex())
+	if err != nil || name != "captions.vtt" || string(data) != string(captions) {
+		t.Errorf("OpenFile: got %q, %q, %v", name, data, err)
+	}
+	again, stored, err := service.PrepareFile(ctx, primitive.NilObjectID, "other/name.vtt", captions)
+	if err != nil || !stored || again.ID != asset.ID {
+		t.Errorf("the same content again: got %+v, stored %v, %v, want the existing asset", again, stored, err)
+	}
+
+	otherOrganization := tenant.WithOrganization(context.Background(), primitive.NewObjectID())
+	if _, _, err := service.OpenFile(otherOrganization, AssetURLPrefix+asset.ID.Hex()); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("OpenFile from another organization: got %v, want ErrAssetNotFound", err)
+	}
+	if _, stored, err := service.PrepareFile(otherOrganization, primitive.NilObjectID, "captions.vtt", captions); err != nil || stored {
+		t.Errorf("another organization: got stored %v, %v, want a new asset", stored, err)
+	}
+}
+
+func TestMediaAssetFilesAreChecked(t *testing.T) {
+	service := NewMediaAssetService(&fakeMediaAssets{}, media.NewDirBlobStore(t.TempDir()))
+	ctx := tenant.WithOrganization(context.Background(), primitive.NewObjectID())
+
+	if _, _, err := service.PrepareFile(ctx, primitive.NilObjectID, "setup.exe", []byte("MZ\x90\x00\x03\x00\x00\x00")); !errors.Is(err, ErrAssetTypeNotAllowed) {
+		t.Errorf("got %v, want ErrAssetTypeNotAllowed", err)
+	}
+	for _, ref := range []string{"https://cdn.example.org/intro.mp4", "asset:not-an-id", AssetURLPrefix + primitive.NewObjectID().Hex()} {
+		if _, _, err := service.OpenFile(ctx, ref); !errors.Is(err, ErrAssetNotFound) {
+			t.Errorf("OpenFile(%q): got %v, want ErrAssetNotFound", ref, err)
+		}
+	}
+}
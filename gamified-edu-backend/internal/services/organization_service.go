@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrOrganizationExists   = errors.New("an organization with this slug or registration code already exists")
+	ErrUserNotFound         = errors.New("user not found")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type OrganizationService interface {
+	CreateOrganization(input CreateOrganizationInput) (*OrganizationResponse, error)
+	// ListOrganizations lists a page of the organizations by name. Only the
+	// caller's own organization shows its registration code.
+	ListOrganizations(callerOrganizationID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[OrganizationResponse], error)
+	AssignUser(userID, organizationID primitive.ObjectID) error
+	AdoptUnassignedData() error
+}
+
+type CreateOrganizationInput struct {
+	Name string `json:"name" binding:"required"`
+	Slug string `json:"slug" binding:"required"`
+	// RegistrationCode, if set, lets learners register directly into the organization
+	RegistrationCode string `json:"registration_code"`
+}
+
+type AssignOrganizationInput struct {
+	OrganizationID string `json:"organization_id" binding:"required"`
+}
+
+type OrganizationResponse struct {
+	ID               primitive.ObjectID `json:"id"`
+	Name             string             `json:"name"`
+	Slug             string             `json:"slug"`
+	RegistrationCode string             `json:"registration_code,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+}
+
+type organizationService struct {
+	organizationRepo repositories.OrganizationRepository
+	userRepo         repositories.UserRepository
+	sessionService   SessionService
+}
+
+func NewOrganizationService(organizationRepo repositories.OrganizationRepository, userRepo repositories.UserRepository, sessionService SessionService) OrganizationService {
+	return &organizationService{organizationRepo: organizationRepo, userRepo: userRepo, sessionService: sessionService}
+}
+
+func (s *organizationService) CreateOrganization(input CreateOrganizationInput) (*OrganizationResponse, error) {
+	slug := strings.ToLower(strings.TrimSpace(input.Slug))
+	if !slugPattern.MatchString(slug) {
+		return nil, errors.New("slug may only contain lowercase letters, digits and single dashes")
+	}
+	code := strings.TrimSpace(input.RegistrationCode)
+
+	if _, err := s.organizationRepo.FindBySlug(slug); !errors.Is(err, mongo.ErrNoDocuments) {
+		if err == nil {
+			return nil, ErrOrganizationExists
+		}
+		return nil, err
+	}
+	if code != "" {
+		if _, err := s.organizationRepo.FindByRegistrationCode(code); !errors.Is(err, mongo.ErrNoDocuments) {
+			if err == nil {
+				return nil, ErrOrganizationExists
+			}
+			return nil, err
+		}
+	}
+
+	organization := &models.Organization{
+		Name:             strings.TrimSpace(input.Name),
+		Slug:             slug,
+		RegistrationCode: code,
+		CreatedAt:        time.Now(),
+	}
+	if err := s.organizationRepo.Create(organization); err != nil {
+		return nil, err
+	}
+	response := toOrganizationResponse(organization)
+	return &response, nil
+}
+
+type organizationCursor struct {
+	Name string             `json:"name"`
+	ID   primitive.ObjectID `json:"id"`
+}
+
+func (s *organizationService) ListOrganizations(callerOrganizationID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[OrganizationResponse], error) {
+	var after *models.Organization
+	if page.Cursor != "" {
+		var position organizationCursor
+		if err := pkg.DecodeCursor(page.Cursor, &position); err != nil {
+			return nil, err
+		}
+		after = &models.Organization{ID: position.ID, Name: position.Name}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	organizations, err := s.organizationRepo.FindPage(after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]OrganizationResponse, 0, len(organizations))
+	for i := range organizations {
+		response := toOrganizationResponse(&organizations[i])
+		if response.ID != callerOrganizationID {
+			response.RegistrationCode = ""
+		}
+		responses = append(responses, response)
+	}
+	result := pkg.NewPage(responses, limit, func(organization OrganizationResponse) any {
+		return organizationCursor{Name: organization.Name, ID: organization.ID}
+	})
+	return &result, nil
+}
+
+// AssignUser moves a user to another organization. The user's tokens carry the
+// old organization, so all of their sessions are revoked. Progress made in the
+// old organization stays there.
+func (s *organizationService) AssignUser(userID, organizationID primitive.ObjectID) error {
+	if _, err := s.organizationRepo.FindByID(organizationID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrOrganizationNotFound
+		}
+		return err
+	}
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if err := s.userRepo.UpdateOrganization(userID, organizationID); err != nil {
+		return err
+	}
+	_, err := s.sessionService.RevokeAllForUser(userID)
+	return err
+}
+
+// AdoptUnassignedData moves data written before organizations existed into
+// the default organization. It is run on startup.
+func (s *organizationService) AdoptUnassignedData() error {
+	organization, err := s.organizationRepo.EnsureDefault()
+	if err != nil {
+		return err
+	}
+	return s.organizationRepo.AdoptUnassigned(organization.ID)
+}
+
+func toOrganizationResponse(organization *models.Organization) OrganizationResponse {
+	return OrganizationResponse{
+		ID:               organization.ID,
+		Name:             organization.Name,
+		Slug:             organization.Slug,
+		RegistrationCode: organization.RegistrationCode,
+		CreatedAt:        organization.CreatedAt,
+	}
+}
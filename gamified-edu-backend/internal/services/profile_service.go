@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/tenant"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const maxDisplayNameLength = 64
+
+// localePattern accepts BCP 47 style tags such as "en", "en-US" or "zh-Hant-TW".
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+var ErrInvalidPassword = errors.New("password is incorrect")
+
+type ProfileService interface {
+	GetProfile(userID primitive.ObjectID) (*ProfileResponse, error)
+	UpdateProfile(userID primitive.ObjectID, input UpdateProfileInput) (*ProfileResponse, error)
+	DeleteAccount(userID primitive.ObjectID, input DeleteAccountInput) error
+}
+
+type profileService struct {
+	userRepo          repositories.UserRepository
+	userDataRepo      repositories.UserDataRepository
+	courseRepo        repositories.CourseRepository
+	enrollmentRepo    repositories.EnrollmentRepository
+	reviewRepo        repositories.CourseReviewRepository
+	sessionService    SessionService
+	dataExportService DataExportService
+}
+
+func NewProfileService(userRepo repositories.UserRepository, userDataRepo repositories.UserDataRepository, courseRepo repositories.CourseRepository, enrollmentRepo repositories.EnrollmentRepository, reviewRepo repositories.CourseReviewRepository, sessionService SessionService, dataExportService DataExportService) ProfileService {
+	return &profileService{userRepo, userDataRepo, courseRepo, enrollmentRepo, reviewRepo, sessionService, dataExportService}
+}
+
+type ProfileResponse struct {
+	ID                      primitive.ObjectID             `json:"id"`
+	Email                   string                         `json:"email"`
+	FirstName               string                         `json:"first_name"`
+	LastName                string                         `json:"last_name"`
+	DisplayName             string                         `json:"display_name"`
+	AvatarRef               string                         `json:"avatar_ref"`
+	TimeZone                string                         `json:"time_zone"`
+	Locale                  string                         `json:"locale"`
+	NotificationPreferences models.NotificationPreferences `json:"notification_preferences"`
+	Role                    string                         `json:"role"`
+	OrganizationID          primitive.ObjectID             `json:"organization_id"`
+	XP                      int                            `json:"xp"`
+	Level                   int                            `json:"level"`
+	TwoFactorEnabled        bool                           `json:"two_factor_enabled"`
+	CreatedAt               *time.Time                     `json:"created_at,omitempty"`
+}
+
+// UpdateProfileInput is a partial update: only fields present in the request are changed.
+type UpdateProfileInput struct {
+	FirstName               *string                            `json:"first_name"`
+	LastName                *string                            `json:"last_name"`
+	DisplayName             *string                            `json:"display_name"`
+	AvatarRef               *string                            `json:"avatar_ref"`
+	TimeZone                *string                            `json:"time_zone"`
+	Locale                  *string                            `json:"locale"`
+	NotificationPreferences *UpdateNotificationPreferencesInput `json:"notification_preferences"`
+}
+
+type UpdateNotificationPreferencesInput struct {
+	ProgressSummaries *bool `json:"progress_summaries"`
+	StreakReminders   *bool `json:"streak_reminders"`
+	ProductUpdates    *bool `json:"product_updates"`
+}
+
+type DeleteAccountInput struct {
+	// Password confirms that the account owner is the one deleting it
+	Password string `json:"password" binding:"required"`
+}
+
+func (s *profileService) GetProfile(userID primitive.ObjectID) (*ProfileResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return toProfileResponse(user), nil
+}
+
+func (s *profileService) UpdateProfile(userID primitive.ObjectID, input UpdateProfileInput) (*ProfileResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.FirstName != nil {
+		name := strings.TrimSpace(*input.FirstName)
+		if name == "" {
+			return nil, errors.New("first_name cannot be empty")
+		}
+		user.FirstName = name
+	}
+	if input.LastName != nil {
+		name := strings.TrimSpace(*input.LastName)
+		if name == "" {
+			return nil, errors.New("last_name cannot be empty")
+		}
+		user.LastName = name
+	}
+	if input.DisplayName != nil {
+		name := strings.TrimSpace(*input.DisplayName)
+		if len(name) > maxDisplayNameLength {
+			return nil, errors.New("display_name is too long")
+		}
+		user.Profile.DisplayName = name
+	}
+	if input.AvatarRef != nil {
+		user.Profile.AvatarRef = strings.TrimSpace(*input.AvatarRef)
+	}
+	if input.TimeZone != nil {
+		timeZone := strings.TrimSpace(*input.TimeZone)
+		if timeZone != "" {
+			if _, err := time.LoadLocation(timeZone); err != nil {
+				return nil, errors.New("time_zone must be an IANA time zone such as Europe/Berlin")
+			}
+		}
+		user.Profile.TimeZone = timeZone
+	}
+	if input.Locale != nil {
+		locale := strings.TrimSpace(*input.Locale)
+		if locale != "" && !localePattern.MatchString(locale) {
+			return nil, errors.New("locale must be a language tag such as en-US")
+		}
+		user.Profile.Locale = locale
+	}
+	if prefs := input.NotificationPreferences; prefs != nil {
+		if prefs.ProgressSummaries != nil {
+			user.Profile.Notifications.ProgressSummaries = *prefs.ProgressSummaries
+		}
+		if prefs.StreakReminders != nil {
+			user.Profile.Notifications.StreakReminders = *prefs.StreakReminders
+		}
+		if prefs.ProductUpdates != nil {
+			user.Profile.Notifications.ProductUpdates = *prefs.ProductUpdates
+		}
+	}
+
+	if err := s.userRepo.UpdateProfile(user); err != nil {
+		return nil, err
+	}
+	return toProfileResponse(user), nil
+}
+
+// DeleteAccount logs the user out everywhere and removes their data. Records
+// other users rely on are anonymized instead of deleted.
+func (s *profileService) DeleteAccount(userID primitive.ObjectID, input DeleteAccountInput) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)) != nil {
+		return ErrInvalidPassword
+	}
+
+	// Revoke first so cached sessions stop working before the data disappears
+	if _, err := s.sessionService.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	if err := s.dataExportService.PurgeUser(userID); err != nil {
+		return err
+	}
+
+	// Courses count their learners and sum up their reviews; the ones the
+	// user leaves are counted down and rated again without the user's review
+	ctx := tenant.WithOrganization(context.Background(), user.OrganizationID)
+	enrollments, err := s.enrollmentRepo.FindActiveByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	reviewed, err := s.reviewRepo.FindCoursesReviewedBy(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.userDataRepo.DeleteAllForUser(userID); err != nil {
+		return err
+	}
+	for _, enrollment := range enrollments {
+		if err := s.courseRepo.AdjustEnrollmentCount(ctx, enrollment.CourseID, -1); err != nil {
+			return err
+		}
+	}
+	for _, courseID := range reviewed {
+		rating, err := s.reviewRepo.RatingStats(ctx, courseID)
+		if err != nil {
+			return err
+		}
+		if err := s.courseRepo.SetRating(ctx, courseID, rating); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toProfileResponse(user *models.User) *ProfileResponse {
+	level := user.Level
+	if level < 1 {
+		level = 1
+	}
+	response := &ProfileResponse{
+		ID:                      user.ID,
+		Email:                   user.Email,
+		FirstName:               user.FirstName,
+		LastName:                user.LastName,
+		DisplayName:             user.DisplayNameOrFullName(),
+		AvatarRef:               user.Profile.AvatarRef,
+		TimeZone:                user.Profile.TimeZone,
+		Locale:                  user.Profile.Locale,
+		NotificationPreferences: user.Profile.Notifications,
+		Role:                    user.EffectiveRole(),
+		OrganizationID:          user.OrganizationID,
+		XP:                      user.XP,
+		Level:                   level,
+		TwoFactorEnabled:        user.TwoFactor.Enabled,
+	}
+	if !user.CreatedAt.IsZero() {
+		createdAt := user.CreatedAt
+		response.CreatedAt = &createdAt
+	}
+	return response
+}
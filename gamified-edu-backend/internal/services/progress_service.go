@@ -1,72 +1,133 @@
 package services // <-- THIS LINE WAS MISSING
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"gamified-edu-backend/internal/models"
 	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
 	"log" // You need to import the log package
+	"time"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-const XP_PER_COMPONENT = 25  // XP for each activity (video, quiz, ppt)
+const XP_PER_COMPONENT = 25  // Default XP for each component (video, quiz, slides, ...)
 const XP_CHAPTER_BONUS = 25  // Bonus XP for completing entire chapter
 const XP_PER_LEVEL = 100     // XP needed to reach NEXT level (Level 1 = 0-99, Level 2 = 100-199, etc.)
 
+// ErrComponentNotFound is returned for progress on a component the chapter doesn't have.
+var ErrComponentNotFound = errors.New("chapter has no such component")
+
+// ErrInvalidComponentReport is returned when a report lacks what the component's rule needs.
+var ErrInvalidComponentReport = errors.New("invalid component report")
+
+// ErrChapterAlreadyCompleted is returned for progress on a chapter the learner already completed.
+var ErrChapterAlreadyCompleted = errors.New("chapter already completed")
+
 type ProgressService interface {
-	MarkComponentAsComplete(userID, chapterID, courseID primitive.ObjectID, component string) error
-	GetUserCourseProgress(userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	MarkComponentAsComplete(ctx context.Context, userID, chapterID, courseID primitive.ObjectID, componentKey string, input MarkComponentInput) error
+	GetUserCourseProgress(ctx context.Context, userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	GetProgressReport(ctx context.Context, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error)
+	ReconcileCourse(ctx context.Context, course *models.Course) error
+}
+
+// MarkComponentInput is the optional body of a component update. Which
+// fields are needed depends on the component's completion rule.
+type MarkComponentInput struct {
+	// Score is the result in percent, graded on the server: by QuizService for
+	// quizzes and the SCORM runtime for SCORM components. Learners cannot
+	// report it themselves.
+	Score *int `json:"-"`
+	// WatchedPercent is how much of a video was watched
+	WatchedPercent *int `json:"watched_percent" binding:"omitempty,min=0,max=100"`
+	// SecondsSpent is how long a reading was open
+	SecondsSpent *int `json:"seconds_spent" binding:"omitempty,min=0"`
+	// Submission is the work handed in for an assignment, e.g. a link or text
+	Submission string `json:"submission" binding:"max=10000"`
+	// RuntimeCompleted is set by the SCORM runtime, for SCORM components.
+	// Learners cannot report it themselves.
+	RuntimeCompleted *bool `json:"-"`
 }
 
 type progressService struct {
-	progressRepo repositories.ProgressRepository
-	userRepo     repositories.UserRepository
-	activityRepo repositories.ActivityRepository
+	progressRepo   repositories.ProgressRepository
+	userRepo       repositories.UserRepository
+	activityRepo   repositories.ActivityRepository
+	courseRepo     repositories.CourseRepository
+	enrollmentRepo repositories.EnrollmentRepository
+	recorder       LearningRecorder
+	cohortRepo     repositories.CohortRepository
+	clock          pkg.Clock
 }
 
-func NewProgressService(progressRepo repositories.ProgressRepository, userRepo repositories.UserRepository, activityRepo repositories.ActivityRepository) ProgressService {
-	return &progressService{progressRepo, userRepo, activityRepo}
+func NewProgressService(progressRepo repositories.ProgressRepository, userRepo repositories.UserRepository, activityRepo repositories.ActivityRepository, courseRepo repositories.CourseRepository, enrollmentRepo repositories.EnrollmentRepository, recorder LearningRecorder, cohortRepo repositories.CohortRepository, clock pkg.Clock) ProgressService {
+	return &progressService{progressRepo, userRepo, activityRepo, courseRepo, enrollmentRepo, recorder, cohortRepo, clock}
 }
 
-func (s *progressService) MarkComponentAsComplete(userID, chapterID, courseID primitive.ObjectID, component string) error {
-	status, err := s.progressRepo.FindOrCreateStatus(userID, chapterID, courseID)
+// MarkComponentAsComplete records a learner's work on a chapter component.
+// The component's type decides whether the report completes it; scores are
+// kept even for attempts that don't, and the best score wins.
+func (s *progressService) MarkComponentAsComplete(ctx context.Context, userID, chapterID, courseID primitive.ObjectID, componentKey string, input MarkComponentInput) error {
+	course, err := enrolledCourse(ctx, s.courseRepo, s.progressRepo, s.enrollmentRepo, s.cohortRepo, s.userRepo, userID, courseID, chapterID, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	chapter, _ := course.FindChapter(chapterID)
+	component, ok := chapter.Component(componentKey)
+	if !ok {
+		return ErrComponentNotFound
+	}
+	componentType, err := lookupComponentType(component.Type)
+	if err != nil {
+		return err
+	}
+	completesComponent, err := componentType.Evaluate(*component, input)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidComponentReport, err)
+	}
+
+	status, err := s.progressRepo.FindOrCreateStatus(ctx, userID, chapterID, courseID)
 	if err != nil {
 		return err
 	}
 
 	if status.IsChapterCompleted {
-		return errors.New("chapter already completed")
+		return ErrChapterAlreadyCompleted
 	}
 
-	// Award XP for the specific component completion
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return err
 	}
 
-	// Check if this component was already completed to avoid double XP
-	componentAlreadyCompleted := false
-	switch component {
-	case "video":
-		componentAlreadyCompleted = status.HasViewedVideo
-		status.HasViewedVideo = true
-	case "quiz":
-		componentAlreadyCompleted = status.HasCompletedQuiz
-		status.HasCompletedQuiz = true
-	case "ppt":
-		componentAlreadyCompleted = status.HasDownloadedPPT
-		status.HasDownloadedPPT = true
-	default:
-		return errors.New("invalid component type")
+	if status.Components == nil {
+		status.Components = map[string]models.ComponentProgress{}
 	}
-
-	// Award XP only if component wasn't already completed
-	if !componentAlreadyCompleted {
-		user.XP += XP_PER_COMPONENT
+	progress := status.Components[component.Key]
+	if input.Score != nil && (progress.BestScore == nil || *input.Score > *progress.BestScore) {
+		progress.BestScore = input.Score
+	}
+	if input.Submission != "" {
+		progress.Submission = input.Submission
+	}
+	// Award XP only the first time the component is completed
+	newlyCompleted := completesComponent && !progress.Completed
+	if newlyCompleted {
+		now := s.clock.Now()
+		progress.Completed = true
+		progress.CompletedAt = &now
+		progress.XP = componentXP(*component)
+		user.XP += progress.XP
 	}
+	status.Components[component.Key] = progress
 
 	wasJustCompleted := false
-	if status.HasViewedVideo && status.HasCompletedQuiz && status.HasDownloadedPPT && !status.IsChapterCompleted {
+	if chapterComplete(chapter, status) {
 		status.IsChapterCompleted = true
+		completedAt := s.clock.Now()
+		status.CompletedAt = &completedAt
 		wasJustCompleted = true
 		// Award bonus XP for completing entire chapter
 		user.XP += XP_CHAPTER_BONUS
@@ -79,21 +140,200 @@ func (s *progressService) MarkComponentAsComplete(userID, chapterID, courseID pr
 		return err
 	}
 
-	if err := s.progressRepo.UpdateStatus(status); err != nil {
+	if err := s.progressRepo.UpdateStatus(ctx, status); err != nil {
 		return err
 	}
 
+	if newlyCompleted || input.Score != nil {
+		if err := s.recorder.RecordComponent(ctx, userID, course, chapter, *component, input, completesComponent); err != nil {
+			log.Printf("Could not record learning event for user %s: %v", userID.Hex(), err)
+		}
+	}
 	if wasJustCompleted {
+		if err := s.recorder.RecordChapterCompleted(ctx, userID, course, chapter); err != nil {
+			log.Printf("Could not record learning event for user %s: %v", userID.Hex(), err)
+		}
 		// Log this completion as an activity for the streak
-		if err := s.activityRepo.LogActivity(userID); err != nil {
+		if err := s.activityRepo.LogActivity(ctx, userID); err != nil {
 			// Log the error but don't block the main flow
 			log.Printf("Could not log activity for user %s: %v", userID.Hex(), err)
 		}
+		if err := s.completeCourseIfFinished(ctx, userID, course); err != nil {
+			log.Printf("Could not record course completion for user %s: %v", userID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+// chapterComplete reports whether every required component of the chapter is
+// done. A chapter whose components are all optional is complete once any of
+// them is done, so it does not hold up the chapters and course after it.
+func chapterComplete(chapter *models.Chapter, status *models.UserChapterStatus) bool {
+	required, done := 0, 0
+	for _, component := range chapter.Components {
+		if status.HasCompleted(component.Key) {
+			done++
+		}
+		if component.Optional {
+			continue
+		}
+		required++
+		if !status.HasCompleted(component.Key) {
+			return false
+		}
+	}
+	if required == 0 {
+		return done > 0
+	}
+	return true
+}
+
+// ReconcileCourse brings learners' progress in line with a newly published
+// version of the course. Progress follows chapters by ID, so it carries over
+// to every version that still has the chapter:
+//   - Completed chapters stay completed, whatever changed in them.
+//   - Progress on removed chapters, and the XP it earned, is kept. It no
+//     longer counts towards the course, but counts again if the chapter returns.
+//   - Added chapters start out not done and lower the course percentage.
+//   - Started chapters whose remaining required components were removed or
+//     made optional are completed now, with the usual chapter bonus.
+//   - Enrollments whose learners now have every chapter done are completed.
+//     A recorded course completion is never taken back.
+func (s *progressService) ReconcileCourse(ctx context.Context, course *models.Course) error {
+	started, err := s.progressRepo.FindIncomplete(ctx, course.ID)
+	if err != nil {
+		return err
+	}
+	for _, status := range started {
+		chapter, ok := course.FindChapter(status.ChapterID)
+		if !ok || !chapterComplete(chapter, status) {
+			continue
+		}
+		user, err := s.userRepo.FindByID(status.UserID)
+		if err != nil {
+			return err
+		}
+		completedAt := s.clock.Now()
+		status.IsChapterCompleted = true
+		status.CompletedAt = &completedAt
+		user.XP += XP_CHAPTER_BONUS
+		user.Level = (user.XP / XP_PER_LEVEL) + 1
+		if err := s.userRepo.UpdateXPAndLevel(user); err != nil {
+			return err
+		}
+		if err := s.progressRepo.UpdateStatus(ctx, status); err != nil {
+			return err
+		}
+		if err := s.recorder.RecordChapterCompleted(ctx, status.UserID, course, chapter); err != nil {
+			log.Printf("Could not record learning event for user %s: %v", status.UserID.Hex(), err)
+		}
+	}
+
+	if course.ChapterCount() == 0 {
+		return nil
+	}
+	enrollments, err := s.enrollmentRepo.FindActiveByCourse(ctx, course.ID)
+	if err != nil {
+		return err
+	}
+	var userIDs []primitive.ObjectID
+	for _, enrollment := range enrollments {
+		if enrollment.CompletedAt == nil {
+			userIDs = append(userIDs, enrollment.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	counts, err := s.progressRepo.CountCompletedByUser(ctx, userIDs, course.ChapterIDs())
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	for _, userID := range userIDs {
+		if counts[userID] >= course.ChapterCount() {
+			if err := s.markCourseCompleted(ctx, userID, course, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enrolledCourse checks that the user is enrolled in the course, that the
+// chapter belongs to it and that the chapter is unlocked and released by now
+func enrolledCourse(ctx context.Context, courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, enrollmentRepo repositories.EnrollmentRepository, cohortRepo repositories.CohortRepository, userRepo repositories.UserRepository, userID, courseID, chapterID primitive.ObjectID, now time.Time) (*models.Course, error) {
+	course, err := courseRepo.FindByID(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCourseNotFound
+		}
+		return nil, err
+	}
+	if !course.HasChapter(chapterID) {
+		return nil, ErrChapterNotInCourse
+	}
+
+	enrollment, err := enrollmentRepo.Find(ctx, userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil || !enrollment.IsActive() {
+		return nil, ErrNotEnrolled
 	}
 
+	prerequisites, err := prerequisiteStatuses(ctx, courseRepo, progressRepo, enrollmentRepo, course, userID)
+	if err != nil {
+		return nil, err
+	}
+	locks, err := chapterLocks(ctx, progressRepo, course, userID, prerequisites)
+	if err != nil {
+		return nil, err
+	}
+	if reason, locked := locks[chapterID]; locked {
+		return nil, fmt.Errorf("%w: %s", ErrChapterLocked, reason)
+	}
+	schedule, err := loadReleaseSchedule(ctx, cohortRepo, userRepo, userID, enrollment, now)
+	if err != nil {
+		return nil, err
+	}
+	chapter, _ := course.FindChapter(chapterID)
+	if err := schedule.checkReleased(*chapter); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// completeCourseIfFinished records the course completion on the enrollment once every chapter is done
+func (s *progressService) completeCourseIfFinished(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	completed, err := completedChapters(ctx, s.progressRepo, userID, course)
+	if err != nil {
+		return err
+	}
+	if course.ChapterCount() == 0 || completed < course.ChapterCount() {
+		return nil
+	}
+	return s.markCourseCompleted(ctx, userID, course, s.clock.Now())
+}
+
+// markCourseCompleted completes the enrollment and records the first completion only
+func (s *progressService) markCourseCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course, at time.Time) error {
+	completed, err := s.enrollmentRepo.MarkCompleted(ctx, userID, course.ID, at)
+	if err != nil || !completed {
+		return err
+	}
+	if err := s.recorder.RecordCourseCompleted(ctx, userID, course); err != nil {
+		log.Printf("Could not record learning event for user %s: %v", userID.Hex(), err)
+	}
 	return nil
 }
 
-func (s *progressService) GetUserCourseProgress(userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error) {
-	return s.progressRepo.GetUserCourseProgress(userID, courseID)
-}
\ No newline at end of file
+func (s *progressService) GetUserCourseProgress(ctx context.Context, userID, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error) {
+	return s.progressRepo.GetUserCourseProgress(ctx, userID, courseID)
+}
+
+// GetProgressReport returns the progress of every learner in the organization, optionally for one course only
+func (s *progressService) GetProgressReport(ctx context.Context, courseID primitive.ObjectID) ([]*models.UserChapterStatus, error) {
+	return s.progressRepo.FindAll(ctx, courseID)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"encoding/json"
+	"gamified-edu-backend/internal/models"
+	"testing"
+)
+
+func TestChapterComplete(t *testing.T) {
+	mixed := &models.Chapter{Components: []models.ChapterComponent{
+		{Key: "video"},
+		{Key: "quiz"},
+		{Key: "reading", Optional: true},
+	}}
+	allOptional := &models.Chapter{Components: []models.ChapterComponent{
+		{Key: "reading", Optional: true},
+		{Key: "extra", Optional: true},
+	}}
+	tests := []struct {
+		name    string
+		chapter *models.Chapter
+		done    []string
+		want    bool
+	}{
+		{"nothing done", mixed, nil, false},
+		{"some required done", mixed, []string{"video", "reading"}, false},
+		{"required done", mixed, []string{"video", "quiz"}, true},
+		{"all optional, nothing done", allOptional, nil, false},
+		{"all optional, one done", allOptional, []string{"extra"}, true},
+		{"no components", &models.Chapter{}, nil, false},
+	}
+	for _, test := range tests {
+		status := &models.UserChapterStatus{Components: map[string]models.ComponentProgress{}}
+		for _, key := range test.done {
+			status.Components[key] = models.ComponentProgress{Completed: true}
+		}
+		if got := chapterComplete(test.chapter, status); got != test.want {
+			t.Errorf("%s: chapterComplete = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLearnersCannotReportQuizScores(t *testing.T) {
+	var report MarkComponentInput
+	if err := json.Unmarshal([]byte(`{"score": 100}`), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Score != nil {
+		t.Fatalf("got a reported score of %d, want none", *report.Score)
+	}
+	quiz := models.ChapterComponent{Key: "quiz", Type: models.ComponentQuiz, QuizID: "basics", PassingScore: 80}
+	if completed, err := (quizComponent{}).Evaluate(quiz, report); completed || err == nil {
+		t.Errorf("got completed %v and error %v, want a quiz with a passing score to need grading", completed, err)
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrQuizNotFound     = errors.New("quiz not found")
+	ErrInvalidAnswers   = errors.New("invalid quiz answers")
+	ErrNotQuizComponent = errors.New("component is not a quiz")
+)
+
+// QuizService serves quizzes that were imported with a course bundle. The
+// answers stay on the server; learners submit theirs to be graded.
+type QuizService interface {
+	GetQuiz(ctx context.Context, slug string) (*QuizResponse, error)
+	// GradeQuiz grades an attempt at the quiz of a chapter component and
+	// records the score as the learner's progress on the component
+	GradeQuiz(ctx context.Context, userID, courseID, chapterID primitive.ObjectID, componentKey string, answers []int) (*QuizGradeResponse, error)
+}
+
+type QuizResponse struct {
+	Slug         string                 `json:"slug"`
+	Title        string                 `json:"title"`
+	PassingScore int                    `json:"passing_score"`
+	Questions    []QuizQuestionResponse `json:"questions"`
+}
+
+type QuizQuestionResponse struct {
+	Prompt  string   `json:"prompt"`
+	Choices []string `json:"choices"`
+}
+
+// QuizGradeResponse has the score in percent, which is recorded as the quiz
+// component's progress.
+type QuizGradeResponse struct {
+	Score   int                `json:"score"`
+	Passed  bool               `json:"passed"`
+	Results []QuizAnswerResult `json:"results"`
+}
+
+// QuizAnswerResult tells whether an answer was right. The right answer is
+// only given once the quiz is passed, so failed attempts can't collect them.
+type QuizAnswerResult struct {
+	Correct     bool   `json:"correct"`
+	Answer      *int   `json:"answer,omitempty"`
+	Explanation string `json:"explanation,omitempty"`
+}
+
+type quizService struct {
+	quizRepo        repositories.QuizRepository
+	courseRepo      repositories.CourseRepository
+	progressService ProgressService
+}
+
+func NewQuizService(quizRepo repositories.QuizRepository, courseRepo repositories.CourseRepository, progressService ProgressService) QuizService {
+	return &quizService{quizRepo: quizRepo, courseRepo: courseRepo, progressService: progressService}
+}
+
+func (s *quizService) GetQuiz(ctx context.Context, slug string) (*QuizResponse, error) {
+	quiz, err := s.quizRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if quiz == nil {
+		return nil, ErrQuizNotFound
+	}
+	response := &QuizResponse{Slug: quiz.Slug, Title: quiz.Title, PassingScore: quiz.PassingScore, Questions: []QuizQuestionResponse{}}
+	for _, question := range quiz.Questions {
+		response.Questions = append(response.Questions, QuizQuestionResponse{Prompt: question.Prompt, Choices: question.Choices})
+	}
+	return response, nil
+}
+
+// GradeQuiz scores one answer per question, each the index of the chosen
+// choice. The component's pass mark decides whether the quiz is passed,
+// falling back to the quiz's suggested one.
+func (s *quizService) GradeQuiz(ctx context.Context, userID, courseID, chapterID primitive.ObjectID, componentKey string, answers []int) (*QuizGradeResponse, error) {
+	component, err := s.quizComponent(ctx, courseID, chapterID, componentKey)
+	if err != nil {
+		return nil, err
+	}
+	quiz, err := s.quizRepo.FindBySlug(ctx, component.QuizID)
+	if err != nil {
+		return nil, err
+	}
+	if quiz == nil {
+		return nil, ErrQuizNotFound
+	}
+	if len(answers) != len(quiz.Questions) {
+		return nil, fmt.Errorf("%w: expected %d answers, got %d", ErrInvalidAnswers, len(quiz.Questions), len(answers))
+	}
+
+	response := &QuizGradeResponse{Results: make([]QuizAnswerResult, 0, len(answers))}
+	correct := 0
+	for i, question := range quiz.Questions {
+		result := QuizAnswerResult{Correct: answers[i] == question.Answer, Explanation: question.Explanation}
+		if result.Correct {
+			correct++
+		}
+		response.Results = append(response.Results, result)
+	}
+	if len(quiz.Questions) > 0 {
+		response.Score = correct * 100 / len(quiz.Questions)
+	}
+	passingScore := component.PassingScore
+	if passingScore == 0 {
+		passingScore = quiz.PassingScore
+	}
+	response.Passed = response.Score >= passingScore
+
+	// Progress checks that the learner may take the quiz and keeps the best score
+	report := MarkComponentInput{Score: &response.Score}
+	err = s.progressService.MarkComponentAsComplete(ctx, userID, chapterID, courseID, componentKey, report)
+	if err != nil && !errors.Is(err, ErrChapterAlreadyCompleted) {
+		return nil, err
+	}
+
+	if response.Passed {
+		for i, question := range quiz.Questions {
+			answer := question.Answer
+			response.Results[i].Answer = &answer
+		}
+	}
+	return response, nil
+}
+
+// quizComponent finds the quiz component whose quiz a learner submits answers to
+func (s *quizService) quizComponent(ctx context.Context, courseID, chapterID primitive.ObjectID, componentKey string) (*models.ChapterComponent, error) {
+	course, err := s.courseRepo.FindByID(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCourseNotFound
+		}
+		return nil, err
+	}
+	chapter, ok := course.FindChapter(chapterID)
+	if !ok {
+		return nil, ErrChapterNotInCourse
+	}
+	component, ok := chapter.Component(componentKey)
+	if !ok {
+		return nil, ErrComponentNotFound
+	}
+	if component.Type != models.ComponentQuiz {
+		return nil, ErrNotQuizComponent
+	}
+	if component.QuizID == "" {
+		return nil, ErrQuizNotFound
+	}
+	return component, nil
+}
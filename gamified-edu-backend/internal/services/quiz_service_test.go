@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeQuizzes struct {
+	repositories.QuizRepository
+	quiz *models.Quiz
+}
+
+func (r *fakeQuizzes) FindBySlug(ctx context.Context, slug string) (*models.Quiz, error) {
+	if slug != r.quiz.Slug {
+		return nil, nil
+	}
+	return r.quiz, nil
+}
+
+// fakeProgressService keeps the reports it is given
+type fakeProgressService struct {
+	ProgressService
+	reports []MarkComponentInput
+}
+
+func (s *fakeProgressService) MarkComponentAsComplete(ctx context.Context, userID, chapterID, courseID primitive.ObjectID, componentKey string, input MarkComponentInput) error {
+	s.reports = append(s.reports, input)
+	return nil
+}
+
+func newQuizTest() (*models.Course, *fakeProgressService, QuizService) {
+	quiz := &models.Quiz{Slug: "basics", PassingScore: 50, Questions: []models.QuizQuestion{
+		{Prompt: "1 + 1", Choices: []string{"1", "2"}, Answer: 1},
+		{Prompt: "2 + 2", Choices: []string{"4", "5"}, Answer: 0},
+	}}
+	chapter := models.Chapter{ID: primitive.NewObjectID(), Components: []models.ChapterComponent{
+		{Key: "quiz", Type: models.ComponentQuiz, QuizID: quiz.Slug, PassingScore: 100},
+		{Key: "video", Type: models.ComponentVideo},
+	}}
+	course := &models.Course{ID: primitive.NewObjectID(), Modules: []models.Module{{ID: primitive.NewObjectID(), Chapters: []models.Chapter{chapter}}}}
+	progress := &fakeProgressService{}
+	return course, progress, NewQuizService(&fakeQuizzes{quiz: quiz}, &fakeCourses{course: course}, progress)
+}
+
+func TestGradeQuizRecordsTheScoreAndHidesAnswersUntilPassed(t *testing.T) {
+	course, progress, service := newQuizTest()
+	chapterID := course.Modules[0].Chapters[0].ID
+	userID := primitive.NewObjectID()
+
+	// Half right passes the quiz's own mark but not the component's
+	result, err := service.GradeQuiz(context.Background(), userID, course.ID, chapterID, "quiz", []int{1, 1})
+	if err != nil {
+		t.Fatalf("GradeQuiz: %v", err)
+	}
+	if result.Score != 50 || result.Passed {
+		t.Errorf("got score %d and passed %v, want 50 and not passed", result.Score, result.Passed)
+	}
+	for i, answer := range result.Results {
+		if answer.Answer != nil {
+			t.Errorf("question %d: the answer was given before the quiz was passed", i)
+		}
+	}
+
+	result, err = service.GradeQuiz(context.Background(), userID, course.ID, chapterID, "quiz", []int{1, 0})
+	if err != nil {
+		t.Fatalf("GradeQuiz: %v", err)
+	}
+	if !result.Passed || result.Results[0].Answer == nil || *result.Results[0].Answer != 1 {
+		t.Errorf("got passed %v and answers %+v, want the answers of a passed quiz", result.Passed, result.Results)
+	}
+
+	if len(progress.reports) != 2 || *progress.reports[0].Score != 50 || *progress.reports[1].Score != 100 {
+		t.Errorf("got reports %+v, want the scores 50 and 100 recorded", progress.reports)
+	}
+}
+
+func TestGradeQuizOnlyGradesQuizComponents(t *testing.T) {
+	course, progress, service := newQuizTest()
+	chapterID := course.Modules[0].Chapters[0].ID
+
+	if _, err := service.GradeQuiz(context.Background(), primitive.NewObjectID(), course.ID, chapterID, "video", []int{1, 0}); err != ErrNotQuizComponent {
+		t.Errorf("got %v, want ErrNotQuizComponent", err)
+	}
+	if len(progress.reports) != 0 {
+		t.Errorf("got reports %+v, want none", progress.reports)
+	}
+}
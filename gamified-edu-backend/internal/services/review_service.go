@@ -0,0 +1,540 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// MinReviewProgress is the course progress, in percent, a learner needs before reviewing it
+	MinReviewProgress = 50
+	// ReviewReportsToFlag is how many reports flag a review for moderation
+	ReviewReportsToFlag = 3
+)
+
+var (
+	ErrReviewNotFound     = errors.New("review not found")
+	ErrReviewTooEarly     = fmt.Errorf("make at least %d%% progress in the course before reviewing it", MinReviewProgress)
+	ErrReportOwnReview    = errors.New("you cannot report your own review")
+	ErrAlreadyReported    = errors.New("you already reported this review")
+	ErrNotCourseAuthor    = errors.New("only the course's authors can reply to its reviews")
+	ErrInvalidReviewQuery = errors.New("invalid review query")
+)
+
+// ReviewService runs course ratings and reviews: learners review the courses
+// they made progress in, report reviews, and course authors reply to them.
+// Moderators hide reviews, and review those flagged by reports.
+type ReviewService interface {
+	// GetReviews lists a page of the course's visible reviews, newest first
+	GetReviews(ctx context.Context, courseID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[ReviewResponse], error)
+	GetMyReview(ctx context.Context, userID, courseID primitive.ObjectID) (*ReviewResponse, error)
+	// SubmitReview creates the user's review of the course or replaces its rating and body
+	SubmitReview(ctx context.Context, userID, courseID primitive.ObjectID, input ReviewInput) (*ReviewResponse, error)
+	DeleteReview(ctx context.Context, userID, courseID primitive.ObjectID) error
+	ReportReview(ctx context.Context, userID, reviewID primitive.ObjectID, input ReportReviewInput) error
+	ReplyToReview(ctx context.Context, actor ReviewActor, reviewID primitive.ObjectID, input ReviewReplyInput) (*ReviewResponse, error)
+	DeleteReply(ctx context.Context, actor ReviewActor, reviewID primitive.ObjectID) error
+	// ListReviewsForModeration lists a page of the organization's reviews in the status, newest first
+	ListReviewsForModeration(ctx context.Context, status string, page pkg.PageRequest) (*pkg.Page[ModerationReviewResponse], error)
+	ModerateReview(ctx context.Context, moderatorID, reviewID primitive.ObjectID, input ModerateReviewInput) (*ModerationReviewResponse, error)
+}
+
+// ReviewActor is the user replying to a review. Admins can reply on any
+// course, instructors on the courses they author.
+type ReviewActor struct {
+	UserID primitive.ObjectID
+	Role   string
+}
+
+type ReviewInput struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+type ReportReviewInput struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+type ReviewReplyInput struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
+
+type ModerateReviewInput struct {
+	// Status is visible to restore the review or hidden to take it down
+	Status string `json:"status" binding:"required,oneof=visible hidden"`
+	Note   string `json:"note" binding:"max=1000"`
+}
+
+type ReviewResponse struct {
+	ID       primitive.ObjectID `json:"id"`
+	CourseID primitive.ObjectID `json:"course_id"`
+	// Author is nil once the reviewer's account is deleted
+	Author    *CourseAuthor        `json:"author"`
+	Rating    int                  `json:"rating"`
+	Body      string               `json:"body"`
+	Status    string               `json:"status"`
+	Reply     *ReviewReplyResponse `json:"reply,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+type ReviewReplyResponse struct {
+	Author    *CourseAuthor `json:"author"`
+	Body      string        `json:"body"`
+	RepliedAt time.Time     `json:"replied_at"`
+}
+
+// ModerationReviewResponse is a review as moderators see it.
+type ModerationReviewResponse struct {
+	ReviewResponse
+	CourseTitle string `json:"course_title"`
+	// ReportCount counts the reports since the review was last moderated
+	ReportCount    int64      `json:"report_count"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+	ModerationNote string     `json:"moderation_note,omitempty"`
+}
+
+// CourseRatingStats sums up the visible reviews of a course.
+type CourseRatingStats struct {
+	Count int `json:"count"`
+	// Average is rounded to one decimal, 0 without ratings
+	Average float64 `json:"average"`
+	// Distribution counts the ratings by stars, "1" to "5"
+	Distribution map[string]int `json:"distribution"`
+}
+
+func courseRatingStats(rating models.CourseRating) CourseRatingStats {
+	stats := CourseRatingStats{
+		Count:        rating.Count,
+		Average:      math.Round(rating.Average*10) / 10,
+		Distribution: make(map[string]int, len(rating.Distribution)),
+	}
+	for i, count := range rating.Distribution {
+		stats.Distribution[strconv.Itoa(i+1)] = count
+	}
+	return stats
+}
+
+type reviewService struct {
+	reviewRepo     repositories.CourseReviewRepository
+	courseRepo     repositories.CourseRepository
+	progressRepo   repositories.ProgressRepository
+	enrollmentRepo repositories.EnrollmentRepository
+	userRepo       repositories.UserRepository
+	clock          pkg.Clock
+}
+
+func NewReviewService(reviewRepo repositories.CourseReviewRepository, courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, enrollmentRepo repositories.EnrollmentRepository, userRepo repositories.UserRepository, clock pkg.Clock) ReviewService {
+	return &reviewService{reviewRepo, courseRepo, progressRepo, enrollmentRepo, userRepo, clock}
+}
+
+type reviewCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+func (s *reviewService) GetReviews(ctx context.Context, courseID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[ReviewResponse], error) {
+	if _, err := s.loadCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	query, err := reviewPageQuery(page)
+	if err != nil {
+		return nil, err
+	}
+	query.CourseID = courseID
+	query.Status = models.ReviewVisible
+	reviews, err := s.reviewRepo.FindPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.reviewResponses(reviews)
+	if err != nil {
+		return nil, err
+	}
+	result := pkg.NewPage(responses, query.Limit-1, func(review ReviewResponse) any { return reviewCursor{ID: review.ID} })
+	return &result, nil
+}
+
+// reviewPageQuery reads the page request, asking for one more review than
+// the page holds to tell whether there is a next page
+func reviewPageQuery(page pkg.PageRequest) (repositories.ReviewPageQuery, error) {
+	var after reviewCursor
+	if page.Cursor != "" {
+		if err := pkg.DecodeCursor(page.Cursor, &after); err != nil {
+			return repositories.ReviewPageQuery{}, err
+		}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	return repositories.ReviewPageQuery{Before: after.ID, Limit: limit + 1}, nil
+}
+
+// GetMyReview returns the user's review in any status, so they can see that it was hidden
+func (s *reviewService) GetMyReview(ctx context.Context, userID, courseID primitive.ObjectID) (*ReviewResponse, error) {
+	review, err := s.reviewRepo.FindByUserAndCourse(ctx, userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, ErrReviewNotFound
+	}
+	return s.reviewResponse(review)
+}
+
+// SubmitReview takes reviews from learners who completed the course or made
+// MinReviewProgress in it. A moderator's decision on a review stands when it is edited.
+func (s *reviewService) SubmitReview(ctx context.Context, userID, courseID primitive.ObjectID, input ReviewInput) (*ReviewResponse, error) {
+	course, err := s.loadCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProgress(ctx, userID, course); err != nil {
+		return nil, err
+	}
+	review, err := s.reviewRepo.Save(ctx, &models.CourseReview{
+		CourseID:  courseID,
+		UserID:    userID,
+		Rating:    input.Rating,
+		Body:      input.Body,
+		UpdatedAt: s.clock.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshRating(ctx, courseID); err != nil {
+		return nil, err
+	}
+	return s.reviewResponse(review)
+}
+
+func (s *reviewService) checkProgress(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	// A recorded completion stands even if the user unenrolled since
+	enrollment, err := s.enrollmentRepo.Find(ctx, userID, course.ID)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil {
+		return ErrReviewTooEarly
+	}
+	if enrollment.CompletedAt != nil {
+		return nil
+	}
+	statuses, err := s.progressRepo.GetUserCourseProgress(ctx, userID, course.ID)
+	if err != nil {
+		return err
+	}
+	completed := make(map[primitive.ObjectID]bool, len(statuses))
+	for _, status := range statuses {
+		completed[status.ChapterID] = status.IsChapterCompleted
+	}
+	if progress, _ := rollUpProgress(course, completed); progress < MinReviewProgress {
+		return ErrReviewTooEarly
+	}
+	return nil
+}
+
+func (s *reviewService) DeleteReview(ctx context.Context, userID, courseID primitive.ObjectID) error {
+	review, err := s.reviewRepo.FindByUserAndCourse(ctx, userID, courseID)
+	if err != nil {
+		return err
+	}
+	if review == nil {
+		return ErrReviewNotFound
+	}
+	deleted, err := s.reviewRepo.Delete(ctx, review.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrReviewNotFound
+	}
+	if err := s.reviewRepo.DeleteReports(ctx, review.ID); err != nil {
+		return err
+	}
+	return s.refreshRating(ctx, courseID)
+}
+
+// ReportReview records the user's report. Once ReviewReportsToFlag learners
+// reported a review since it was last moderated, it is flagged and leaves
+// the course's reviews and rating until a moderator decides on it.
+func (s *reviewService) ReportReview(ctx context.Context, userID, reviewID primitive.ObjectID, input ReportReviewInput) error {
+	review, err := s.loadReview(ctx, reviewID)
+	if err != nil {
+		return err
+	}
+	if review.Status != models.ReviewVisible {
+		return ErrReviewNotFound
+	}
+	if review.UserID == userID {
+		return ErrReportOwnReview
+	}
+	added, err := s.reviewRepo.AddReport(ctx, &models.ReviewReport{
+		ReviewID:  reviewID,
+		UserID:    userID,
+		Reason:    input.Reason,
+		CreatedAt: s.clock.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrAlreadyReported
+	}
+
+	reports, err := s.reviewRepo.CountReports(ctx, reviewID, review.ModeratedAt)
+	if err != nil || reports < ReviewReportsToFlag {
+		return err
+	}
+	flagged, err := s.reviewRepo.Flag(ctx, reviewID)
+	if err != nil || !flagged {
+		return err
+	}
+	return s.refreshRating(ctx, review.CourseID)
+}
+
+func (s *reviewService) ReplyToReview(ctx context.Context, actor ReviewActor, reviewID primitive.ObjectID, input ReviewReplyInput) (*ReviewResponse, error) {
+	review, err := s.loadReviewForReply(ctx, actor, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	review.Reply = &models.ReviewReply{AuthorID: actor.UserID, Body: input.Body, RepliedAt: s.clock.Now()}
+	updated, err := s.reviewRepo.SetReply(ctx, reviewID, review.Reply)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrReviewNotFound
+	}
+	return s.reviewResponse(review)
+}
+
+func (s *reviewService) DeleteReply(ctx context.Context, actor ReviewActor, reviewID primitive.ObjectID) error {
+	review, err := s.loadReviewForReply(ctx, actor, reviewID)
+	if err != nil {
+		return err
+	}
+	if review.Reply == nil {
+		return ErrReviewNotFound
+	}
+	updated, err := s.reviewRepo.SetReply(ctx, reviewID, nil)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrReviewNotFound
+	}
+	return nil
+}
+
+// loadReviewForReply loads the review if the actor may answer it
+func (s *reviewService) loadReviewForReply(ctx context.Context, actor ReviewActor, reviewID primitive.ObjectID) (*models.CourseReview, error) {
+	review, err := s.loadReview(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.Role == models.RoleAdmin {
+		return review, nil
+	}
+	course, err := s.courseRepo.FindByID(ctx, review.CourseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	if !slices.Contains(course.AuthorIDs, actor.UserID) {
+		return nil, ErrNotCourseAuthor
+	}
+	return review, nil
+}
+
+func (s *reviewService) ListReviewsForModeration(ctx context.Context, status string, page pkg.PageRequest) (*pkg.Page[ModerationReviewResponse], error) {
+	switch status {
+	case models.ReviewVisible, models.ReviewFlagged, models.ReviewHidden:
+	default:
+		return nil, fmt.Errorf("%w: status must be %s, %s or %s", ErrInvalidReviewQuery, models.ReviewVisible, models.ReviewFlagged, models.ReviewHidden)
+	}
+	query, err := reviewPageQuery(page)
+	if err != nil {
+		return nil, err
+	}
+	query.Status = status
+	reviews, err := s.reviewRepo.FindPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.moderationResponses(ctx, reviews)
+	if err != nil {
+		return nil, err
+	}
+	result := pkg.NewPage(responses, query.Limit-1, func(review ModerationReviewResponse) any { return reviewCursor{ID: review.ID} })
+	return &result, nil
+}
+
+// ModerateReview shows or hides the review. Reports made until now are
+// settled by the decision; new ones can flag the review again.
+func (s *reviewService) ModerateReview(ctx context.Context, moderatorID, reviewID primitive.ObjectID, input ModerateReviewInput) (*ModerationReviewResponse, error) {
+	review, err := s.loadReview(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	moderated, err := s.reviewRepo.Moderate(ctx, reviewID, input.Status, moderatorID, input.Note, now)
+	if err != nil {
+		return nil, err
+	}
+	if !moderated {
+		return nil, ErrReviewNotFound
+	}
+	if err := s.refreshRating(ctx, review.CourseID); err != nil {
+		return nil, err
+	}
+	review.Status = input.Status
+	review.ModeratedAt = &now
+	review.ModeratedBy = moderatorID
+	review.ModerationNote = input.Note
+	responses, err := s.moderationResponses(ctx, []models.CourseReview{*review})
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+func (s *reviewService) loadCourse(ctx context.Context, courseID primitive.ObjectID) (*models.Course, error) {
+	course, err := s.courseRepo.FindByID(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCourseNotFound
+		}
+		return nil, err
+	}
+	if !course.IsPublished() {
+		return nil, ErrCourseNotFound
+	}
+	return course, nil
+}
+
+func (s *reviewService) loadReview(ctx context.Context, reviewID primitive.ObjectID) (*models.CourseReview, error) {
+	review, err := s.reviewRepo.FindByID(ctx, reviewID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return review, nil
+}
+
+// refreshRating recounts the course's rating from its visible reviews
+func (s *reviewService) refreshRating(ctx context.Context, courseID primitive.ObjectID) error {
+	rating, err := s.reviewRepo.RatingStats(ctx, courseID)
+	if err != nil {
+		return err
+	}
+	return s.courseRepo.SetRating(ctx, courseID, rating)
+}
+
+func (s *reviewService) reviewResponse(review *models.CourseReview) (*ReviewResponse, error) {
+	responses, err := s.reviewResponses([]models.CourseReview{*review})
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// reviewResponses names the reviewers and repliers, looking them all up at once
+func (s *reviewService) reviewResponses(reviews []models.CourseReview) ([]ReviewResponse, error) {
+	var ids []primitive.ObjectID
+	for _, review := range reviews {
+		ids = append(ids, review.UserID)
+		if review.Reply != nil {
+			ids = append(ids, review.Reply.AuthorID)
+		}
+	}
+	names := map[primitive.ObjectID]string{}
+	if len(ids) > 0 {
+		users, err := s.userRepo.FindByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			names[users[i].ID] = users[i].DisplayNameOrFullName()
+		}
+	}
+	author := func(id primitive.ObjectID) *CourseAuthor {
+		name, ok := names[id]
+		if !ok {
+			return nil
+		}
+		return &CourseAuthor{ID: id, Name: name}
+	}
+
+	responses := make([]ReviewResponse, 0, len(reviews))
+	for _, review := range reviews {
+		response := ReviewResponse{
+			ID:        review.ID,
+			CourseID:  review.CourseID,
+			Author:    author(review.UserID),
+			Rating:    review.Rating,
+			Body:      review.Body,
+			Status:    review.Status,
+			CreatedAt: review.CreatedAt,
+			UpdatedAt: review.UpdatedAt,
+		}
+		if review.Reply != nil {
+			response.Reply = &ReviewReplyResponse{
+				Author:    author(review.Reply.AuthorID),
+				Body:      review.Reply.Body,
+				RepliedAt: review.Reply.RepliedAt,
+			}
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (s *reviewService) moderationResponses(ctx context.Context, reviews []models.CourseReview) ([]ModerationReviewResponse, error) {
+	base, err := s.reviewResponses(reviews)
+	if err != nil {
+		return nil, err
+	}
+	titles := map[primitive.ObjectID]string{}
+	responses := make([]ModerationReviewResponse, 0, len(reviews))
+	for i, review := range reviews {
+		title, ok := titles[review.CourseID]
+		if !ok {
+			course, err := s.courseRepo.FindByID(ctx, review.CourseID)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, err
+			}
+			if course != nil {
+				title = course.Title
+			}
+			titles[review.CourseID] = title
+		}
+		reports, err := s.reviewRepo.CountReports(ctx, review.ID, review.ModeratedAt)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, ModerationReviewResponse{
+			ReviewResponse: base[i],
+			CourseTitle:    title,
+			ReportCount:    reports,
+			ModeratedAt:    review.ModeratedAt,
+			ModerationNote: review.ModerationNote,
+		})
+	}
+	return responses, nil
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/scorm"
+	"gamified-edu-backend/pkg"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxCMIElements caps how many elements one attempt stores
+const maxCMIElements = 2000
+
+// scormLaunchTTL keeps the package files of a session reachable for a long sitting
+const scormLaunchTTL = 6 * time.Hour
+
+var (
+	ErrSCORMPackageNotFound = errors.New("SCORM package not found")
+	ErrNotSCORMComponent    = errors.New("component is not a SCORM component")
+	ErrInvalidCMIData       = errors.New("invalid SCORM runtime data")
+)
+
+// CMIDataError lists every element of a commit the data model rejected.
+type CMIDataError struct {
+	Errors []*scorm.Error
+}
+
+func (e *CMIDataError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return ErrInvalidCMIData.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *CMIDataError) Is(target error) bool { return target == ErrInvalidCMIData }
+
+// SCORMService turns SCORM packages into courses and runs the SCORM runtime
+// for their SCOs. Every SCO becomes a chapter with one scorm component, which
+// is completed, and earns its XP, once the SCO reports completion.
+type SCORMService interface {
+	// ImportPackage creates a course from the package in archive
+	ImportPackage(ctx context.Context, authorID primitive.ObjectID, archive *zip.Reader, publish bool) (*SCORMImportResponse, error)
+	// GetRuntime starts a session: it returns where the SCO is served and the data it starts with
+	GetRuntime(ctx context.Context, userID, courseID, chapterID primitive.ObjectID, componentKey string) (*SCORMRuntimeResponse, error)
+	// CommitRuntime stores what the SCO set since its last commit and updates progress
+	CommitRuntime(ctx context.Context, userID, courseID, chapterID primitive.ObjectID, componentKey string, input CommitRuntimeInput) (*SCORMCommitResponse, error)
+}
+
+type SCORMImportResponse struct {
+	PackageID primitive.ObjectID `json:"package_id"`
+	Version   scorm.Version      `json:"version"`
+	SCOCount  int                `json:"sco_count"`
+	Draft     *DraftResponse     `json:"draft"`
+	// PublishedVersion is set when the course was published right away
+	PublishedVersion int `json:"published_version,omitempty"`
+}
+
+type SCORMRuntimeResponse struct {
+	Version scorm.Version `json:"version"`
+	// LaunchURL is signed, it serves the SCO and its package for scormLaunchTTL
+	LaunchURL string `json:"launch_url"`
+	// Values are the data model elements the SCO reads, by name, e.g. cmi.suspend_data
+	Values    map[string]string `json:"values"`
+	Completed bool              `json:"completed"`
+	Score     *int              `json:"score,omitempty"`
+}
+
+// CommitRuntimeInput is what a SCO set through SetValue since the last
+// commit. Finish ends the session, as the SCO's Finish or LMSFinish does.
+type CommitRuntimeInput struct {
+	Values map[string]string `json:"values" binding:"max=1000"`
+	Finish bool              `json:"finish"`
+}
+
+type SCORMCommitResponse struct {
+	Completed bool `json:"completed"`
+	Score     *int `json:"score,omitempty"`
+}
+
+type scormService struct {
+	packageRepo      repositories.SCORMPackageRepository
+	attemptRepo      repositories.SCORMAttemptRepository
+	courseRepo       repositories.CourseRepository
+	progressRepo     repositories.ProgressRepository
+	enrollmentRepo   repositories.EnrollmentRepository
+	cohortRepo       repositories.CohortRepository
+	userRepo         repositories.UserRepository
+	authoringService CourseAuthoringService
+	progressService  ProgressService
+	store            scorm.PackageStore
+	clock            pkg.Clock
+}
+
+func NewSCORMService(packageRepo repositories.SCORMPackageRepository, attemptRepo repositories.SCORMAttemptRepository, courseRepo repositories.CourseRepository, progressRepo repositories.ProgressRepository, enrollmentRepo repositories.EnrollmentRepository, cohortRepo repositories.CohortRepository, userRepo repositories.UserRepository, authoringService CourseAuthoringService, progressService ProgressService, store scorm.PackageStore, clock pkg.Clock) SCORMService {
+	return &scormService{
+		packageRepo:      packageRepo,
+		attemptRepo:      attemptRepo,
+		courseRepo:       courseRepo,
+		progressRepo:     progressRepo,
+		enrollmentRepo:   enrollmentRepo,
+		cohortRepo:       cohortRepo,
+		userRepo:         userRepo,
+		authoringService: authoringService,
+		progressService:  progressService,
+		store:            store,
+		clock:            clock,
+	}
+}
+
+// ImportPackage unpacks the package into the package store and creates a
+// course with a module per top-level item and a chapter per SCO. The course
+// starts out as a draft unless publish is set.
+func (s *scormService) ImportPackage(ctx context.Context, authorID primitive.ObjectID, archive *zip.Reader, publish bool) (*SCORMImportResponse, error) {
+	parsed, err := scorm.ReadPackage(archive)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &models.SCORMPackage{
+		ID:         primitive.NewObjectID(),
+		Version:    string(parsed.Version),
+		Title:      titleOr(parsed.Title, "SCORM package"),
+		UploadedBy: authorID,
+		UploadedAt: s.clock.Now(),
+	}
+
+	body := CourseContentBody{Title: pkg.Title, Modules: []ModuleBody{}}
+	for _, module := range parsed.Modules {
+		moduleBody := ModuleBody{Title: titleOr(module.Title, pkg.Title), Chapters: []ChapterBody{}}
+		for _, sco := range module.SCOs {
+			pkg.SCOs = append(pkg.SCOs, models.SCORMSCO{Identifier: sco.Identifier, Title: sco.Title, Launch: sco.Launch, LaunchData: sco.LaunchData})
+			moduleBody.Chapters = append(moduleBody.Chapters, ChapterBody{
+				Title: titleOr(sco.Title, sco.Identifier),
+				Components: []ComponentBody{{
+					Key:          "sco",
+					Type:         models.ComponentSCORM,
+					Title:        titleOr(sco.Title, sco.Identifier),
+					URL:          s.store.LaunchURL(pkg.ID.Hex(), sco.Launch),
+					PassingScore: sco.MasteryScore,
+					PackageID:    pkg.ID.Hex(),
+					SCOID:        sco.Identifier,
+				}},
+			})
+		}
+		body.Modules = append(body.Modules, moduleBody)
+	}
+
+	if err := s.store.Save(pkg.ID.Hex(), archive); err != nil {
+		return nil, err
+	}
+	draft, err := s.authoringService.CreateCourse(ctx, authorID, CreateCourseInput{CourseContentBody: body})
+	if err != nil {
+		if removeErr := s.store.Remove(pkg.ID.Hex()); removeErr != nil {
+			log.Printf("Could not remove the files of SCORM package %s: %v", pkg.ID.Hex(), removeErr)
+		}
+		return nil, err
+	}
+	pkg.CourseID = draft.CourseID
+	if err := s.packageRepo.Create(ctx, pkg); err != nil {
+		return nil, err
+	}
+
+	response := &SCORMImportResponse{PackageID: pkg.ID, Version: parsed.Version, SCOCount: len(pkg.SCOs), Draft: draft}
+	if publish {
+		version, err := s.authoringService.Publish(ctx, authorID, draft.CourseID, PublishInput{Note: "Imported from SCORM package " + pkg.Title})
+		if err != nil {
+			return nil, err
+		}
+		response.PublishedVersion = version.Version
+	}
+	return response, nil
+}
+
+// GetRuntime starts a session with the SCO. A session that was left without
+// finishing, e.g. because the browser was closed, is finished first.
+func (s *scormService) GetRuntime(ctx context.Context, userID, courseID, chapterID primitive.ObjectID, componentKey string) (*SCORMRuntimeResponse, error) {
+	component, pkg, sco, err := s.runtimeComponent(ctx, userID, courseID, chapterID, componentKey)
+	if err != nil {
+		return nil, err
+	}
+	version := scorm.Version(pkg.Version)
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := s.attemptRepo.Find(ctx, userID, chapterID, componentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	launch := scorm.Launch{
+		LearnerID:    userID.Hex(),
+		LearnerName:  strings.TrimSpace(user.LastName + ", " + user.FirstName),
+		Entry:        "ab-initio",
+		LaunchData:   sco.LaunchData,
+		MasteryScore: component.PassingScore,
+	}
+	response := &SCORMRuntimeResponse{Version: version, LaunchURL: s.store.SignURL(component.URL, s.clock.Now().Add(scormLaunchTTL))}
+	if attempt != nil {
+		values := cmiValues(attempt.Values)
+		if seconds, suspended := version.EndSession(values); len(values) < len(attempt.Values) || attempt.SessionSeconds > 0 {
+			attempt.TotalSeconds += seconds
+			attempt.SessionSeconds = 0
+			attempt.Suspended = suspended
+			attempt.Values = storedCMIValues(values)
+			attempt.UpdatedAt = s.clock.Now()
+			if err := s.attemptRepo.Save(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+		launch.Entry = ""
+		if attempt.Suspended {
+			launch.Entry = "resume"
+		}
+		launch.TotalSeconds = attempt.TotalSeconds
+		launch.Values = values
+		response.Completed, response.Score = attempt.Completed, attempt.Score
+	}
+	response.Values = version.LaunchValues(launch)
+	return response, nil
+}
+
+// CommitRuntime checks every element against the data model first and
+// rejects the whole commit if one is invalid, so the SCO can report the
+// errors without anything half stored. Completion and score go into the
+// learner's progress like a report on any other component.
+func (s *scormService) CommitRuntime(ctx context.Context, userID, courseID, chapterID primitive.ObjectID, componentKey string, input CommitRuntimeInput) (*SCORMCommitResponse, error) {
+	component, pkg, sco, err := s.runtimeComponent(ctx, userID, courseID, chapterID, componentKey)
+	if err != nil {
+		return nil, err
+	}
+	version := scorm.Version(pkg.Version)
+
+	elements := make([]string, 0, len(input.Values))
+	for element := range input.Values {
+		elements = append(elements, element)
+	}
+	sort.Strings(elements)
+	invalid := &CMIDataError{}
+	for _, element := range elements {
+		if err := version.Check(element, input.Values[element]); err != nil {
+			invalid.Errors = append(invalid.Errors, err)
+		}
+	}
+	if len(invalid.Errors) > 0 {
+		return nil, invalid
+	}
+
+	attempt, err := s.attemptRepo.Find(ctx, userID, chapterID, componentKey)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if attempt == nil {
+		attempt = &models.SCORMAttempt{
+			UserID:       userID,
+			CourseID:     courseID,
+			ChapterID:    chapterID,
+			ComponentKey: componentKey,
+			PackageID:    pkg.ID,
+			SCOID:        sco.Identifier,
+			CreatedAt:    now,
+		}
+	}
+	values := cmiValues(attempt.Values)
+	for element, value := range input.Values {
+		values[element] = value
+	}
+	if len(values) > maxCMIElements {
+		return nil, fmt.Errorf("%w: an attempt can store at most %d elements", ErrInvalidCMIData, maxCMIElements)
+	}
+
+	completed, score := version.Summarize(values, component.PassingScore)
+	attempt.SessionSeconds = version.SessionSeconds(values)
+	if input.Finish {
+		seconds, suspended := version.EndSession(values)
+		attempt.TotalSeconds += seconds
+		attempt.SessionSeconds = 0
+		attempt.Suspended = suspended
+	}
+
+	// Report to progress only what is new, progress keeps the best score itself
+	improved := score != nil && (attempt.Score == nil || *score > *attempt.Score)
+	if (completed && !attempt.Completed) || improved {
+		report := MarkComponentInput{Score: score, RuntimeCompleted: &completed}
+		err := s.progressService.MarkComponentAsComplete(ctx, userID, chapterID, courseID, componentKey, report)
+		if err != nil && !errors.Is(err, ErrChapterAlreadyCompleted) {
+			return nil, err
+		}
+	}
+	attempt.Completed = attempt.Completed || completed
+	if improved {
+		attempt.Score = score
+	}
+	attempt.Values = storedCMIValues(values)
+	attempt.UpdatedAt = now
+	if err := s.attemptRepo.Save(ctx, attempt); err != nil {
+		return nil, err
+	}
+	return &SCORMCommitResponse{Completed: attempt.Completed, Score: attempt.Score}, nil
+}
+
+// runtimeComponent finds the scorm component a learner may run, with its
+// package and SCO
+func (s *scormService) runtimeComponent(ctx context.Context, userID, courseID, chapterID primitive.ObjectID, componentKey string) (*models.ChapterComponent, *models.SCORMPackage, *models.SCORMSCO, error) {
+	course, err := enrolledCourse(ctx, s.courseRepo, s.progressRepo, s.enrollmentRepo, s.cohortRepo, s.userRepo, userID, courseID, chapterID, s.clock.Now())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	chapter, _ := course.FindChapter(chapterID)
+	component, ok := chapter.Component(componentKey)
+	if !ok {
+		return nil, nil, nil, ErrComponentNotFound
+	}
+	if component.Type != models.ComponentSCORM {
+		return nil, nil, nil, ErrNotSCORMComponent
+	}
+	pkg, err := s.packageRepo.FindByID(ctx, component.PackageID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if pkg == nil || !scorm.Version(pkg.Version).Valid() {
+		return nil, nil, nil, ErrSCORMPackageNotFound
+	}
+	for i := range pkg.SCOs {
+		if pkg.SCOs[i].Identifier == component.SCOID {
+			return component, pkg, &pkg.SCOs[i], nil
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("%w: it has no SCO %q", ErrSCORMPackageNotFound, component.SCOID)
+}
+
+func cmiValues(stored []models.CMIValue) map[string]string {
+	values := make(map[string]string, len(stored))
+	for _, value := range stored {
+		values[value.Element] = value.Value
+	}
+	return values
+}
+
+// storedCMIValues lists the values sorted by element
+func storedCMIValues(values map[string]string) []models.CMIValue {
+	stored := make([]models.CMIValue, 0, len(values))
+	for element, value := range values {
+		stored = append(stored, models.CMIValue{Element: element, Value: value})
+	}
+	sort.Slice(stored, func(a, b int) bool { return stored[a].Element < stored[b].Element })
+	return stored
+}
+
+// titleOr trims title to what courses accept, falling back when it is empty
+func titleOr(title, fallback string) string {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = fallback
+	}
+	if runes := []rune(title); len(runes) > 200 {
+		title = strings.TrimSpace(string(runes[:200]))
+	}
+	return title
+}
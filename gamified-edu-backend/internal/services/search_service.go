@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/search"
+	"gamified-edu-backend/internal/tenant"
+	"log"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidSearch is returned for searches with unknown filter values.
+var ErrInvalidSearch = errors.New("invalid search")
+
+// Completion states of a course for a learner
+const (
+	CompletionNotStarted = "not_started"
+	CompletionInProgress = "in_progress"
+	CompletionCompleted  = "completed"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// CourseIndexer keeps the search index up to date as courses change.
+type CourseIndexer interface {
+	// IndexCourse indexes what learners see of the course, or removes it when it is not published
+	IndexCourse(ctx context.Context, course *models.Course) error
+}
+
+type SearchService interface {
+	CourseIndexer
+	Search(ctx context.Context, userID primitive.ObjectID, input SearchInput) (*SearchResponse, error)
+	// Rebuild indexes the published courses of every organization
+	Rebuild(ctx context.Context) error
+}
+
+type searchService struct {
+	index            search.Index
+	courseRepo       repositories.CourseRepository
+	enrollmentRepo   repositories.EnrollmentRepository
+	progressRepo     repositories.ProgressRepository
+	organizationRepo repositories.OrganizationRepository
+}
+
+func NewSearchService(index search.Index, courseRepo repositories.CourseRepository, enrollmentRepo repositories.EnrollmentRepository, progressRepo repositories.ProgressRepository, organizationRepo repositories.OrganizationRepository) SearchService {
+	return &searchService{index: index, courseRepo: courseRepo, enrollmentRepo: enrollmentRepo, progressRepo: progressRepo, organizationRepo: organizationRepo}
+}
+
+// SearchInput is a search of the catalog. Every field is optional; without
+// Query the courses passing the filters are listed by title.
+type SearchInput struct {
+	Query       string
+	Tag         string
+	Difficulty  string
+	MinDuration int
+	MaxDuration int
+	// Completion is one of the Completion constants, for the searching user
+	Completion string
+	Limit      int
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResult is a course or a chapter of one.
+type SearchResult struct {
+	Kind        string              `json:"kind"`
+	CourseID    primitive.ObjectID  `json:"course_id"`
+	ChapterID   *primitive.ObjectID `json:"chapter_id,omitempty"`
+	CourseTitle string              `json:"course_title,omitempty"`
+	Title       string              `json:"title"`
+	Tags        []string            `json:"tags"`
+	Difficulty  string              `json:"difficulty,omitempty"`
+	// DurationMins is the chapter's, or the sum of the chapters' for a course
+	DurationMins int     `json:"duration_mins"`
+	Completion   string  `json:"completion"`
+	Score        float64 `json:"score"`
+	// Highlights are HTML snippets of the title and text, the matched words in <mark>
+	Highlights []string `json:"highlights"`
+}
+
+func (s *searchService) Search(ctx context.Context, userID primitive.ObjectID, input SearchInput) (*SearchResponse, error) {
+	if input.Difficulty != "" && input.Difficulty != models.DifficultyBeginner && input.Difficulty != models.DifficultyIntermediate && input.Difficulty != models.DifficultyAdvanced {
+		return nil, fmt.Errorf("%w: difficulty must be beginner, intermediate or advanced", ErrInvalidSearch)
+	}
+	if input.Completion != "" && input.Completion != CompletionNotStarted && input.Completion != CompletionInProgress && input.Completion != CompletionCompleted {
+		return nil, fmt.Errorf("%w: completion must be not_started, in_progress or completed", ErrInvalidSearch)
+	}
+	if input.MaxDuration > 0 && input.MinDuration > input.MaxDuration {
+		return nil, fmt.Errorf("%w: min_duration is above max_duration", ErrInvalidSearch)
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	query := search.Query{
+		Text:        input.Query,
+		Tag:         strings.ToLower(strings.TrimSpace(input.Tag)),
+		Difficulty:  input.Difficulty,
+		MinDuration: input.MinDuration,
+		MaxDuration: input.MaxDuration,
+		Limit:       limit,
+	}
+	// The index knows nothing of learners, so completion is filtered here from every hit
+	if input.Completion != "" {
+		query.Limit = 0
+	}
+	hits, err := s.index.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollments, err := s.enrollmentRepo.FindActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	completion, err := completionByCourse(ctx, s.progressRepo, userID, enrollments)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+	for _, hit := range hits {
+		state := completion[hit.CourseID]
+		if state == "" {
+			state = CompletionNotStarted
+		}
+		if input.Completion != "" && state != input.Completion {
+			continue
+		}
+		results = append(results, searchResult(hit, state))
+		if len(results) == limit {
+			break
+		}
+	}
+	return &SearchResponse{Query: input.Query, Results: results}, nil
+}
+
+func searchResult(hit search.Hit, completion string) SearchResult {
+	result := SearchResult{
+		Kind:         hit.Kind,
+		CourseID:     hit.CourseID,
+		CourseTitle:  hit.CourseTitle,
+		Title:        hit.Title,
+		Tags:         hit.Tags,
+		Difficulty:   hit.Difficulty,
+		DurationMins: hit.DurationMins,
+		Completion:   completion,
+		Score:        hit.Score,
+		Highlights:   []string{},
+	}
+	if hit.Kind == search.KindChapter {
+		chapterID := hit.ChapterID
+		result.ChapterID = &chapterID
+	}
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+	if len(hit.Terms) > 0 {
+		result.Highlights = append(search.Highlight(hit.Title, hit.Terms), search.Highlight(hit.Body, hit.Terms)...)
+	}
+	return result
+}
+
+func (s *searchService) IndexCourse(ctx context.Context, course *models.Course) error {
+	if !course.IsPublished() {
+		return s.index.RemoveCourse(ctx, course.ID)
+	}
+	return s.index.ReplaceCourse(ctx, course.ID, courseDocuments(course))
+}
+
+// courseDocuments are the course's document and one for each chapter. A
+// chapter's text is the titles of its components; a course's categories
+// match as its tags do.
+func courseDocuments(course *models.Course) []search.Document {
+	tags := normalizeTags(course.Tags)
+	documents := []search.Document{{
+		Kind:         search.KindCourse,
+		CourseID:     course.ID,
+		Title:        course.Title,
+		Body:         course.Description,
+		Tags:         tags,
+		Difficulty:   course.Difficulty,
+		DurationMins: course.DurationMins(),
+	}}
+	body := []string{course.Description}
+	for _, category := range normalizeCategories(course.Categories) {
+		if !slices.Contains(tags, strings.ToLower(category)) {
+			body = append(body, category)
+		}
+	}
+	documents[0].Body = strings.Join(slices.DeleteFunc(body, func(text string) bool { return text == "" }), ". ")
+	for _, module := range course.Modules {
+		for _, chapter := range module.Chapters {
+			var body []string
+			for _, component := range chapter.Components {
+				if component.Title != "" {
+					body = append(body, component.Title)
+				}
+			}
+			documents = append(documents, search.Document{
+				Kind:         search.KindChapter,
+				CourseID:     course.ID,
+				ChapterID:    chapter.ID,
+				CourseTitle:  course.Title,
+				Title:        chapter.Title,
+				Body:         strings.Join(body, ". "),
+				Tags:         tags,
+				Difficulty:   course.Difficulty,
+				DurationMins: chapter.DurationMins,
+			})
+		}
+	}
+	return documents
+}
+
+func (s *searchService) Rebuild(ctx context.Context) error {
+	organizations, err := s.organizationRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, organization := range organizations {
+		organizationCtx := tenant.WithOrganization(ctx, organization.ID)
+		courses, err := s.courseRepo.FindAll(organizationCtx)
+		if err != nil {
+			return err
+		}
+		for i := range courses {
+			if err := s.IndexCourse(organizationCtx, &courses[i]); err != nil {
+				return fmt.Errorf("course %s: %w", courses[i].ID.Hex(), err)
+			}
+		}
+		log.Printf("Indexed %d courses of organization %s for search", len(courses), organization.Slug)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// sessionCacheTTL bounds how long a revocation made on another server
+	// instance can take to be noticed by this one.
+	sessionCacheTTL = time.Minute
+	// lastSeenWriteInterval throttles last-seen updates so that authenticated
+	// requests do not each cause a database write.
+	lastSeenWriteInterval = 5 * time.Minute
+	// sessionCachePruneSize is the cache size above which stale entries are dropped.
+	sessionCachePruneSize = 10000
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionInactive = errors.New("session has been revoked or has expired")
+)
+
+type SessionService interface {
+	CreateSession(userID primitive.ObjectID, client ClientInfo) (*models.Session, error)
+	ValidateSession(sessionID, userID primitive.ObjectID) error
+	// ListSessions lists a page of the user's active sessions, most recently used first
+	ListSessions(userID, currentSessionID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[SessionResponse], error)
+	RevokeSession(userID, sessionID primitive.ObjectID) error
+	RevokeOtherSessions(userID, currentSessionID primitive.ObjectID) (int, error)
+	RevokeAllForUser(userID primitive.ObjectID) (int, error)
+}
+
+// ClientInfo describes the client a login request came from.
+type ClientInfo struct {
+	Device    string
+	UserAgent string
+	IPAddress string
+}
+
+type SessionResponse struct {
+	ID         primitive.ObjectID `json:"id"`
+	Device     string             `json:"device"`
+	UserAgent  string             `json:"user_agent"`
+	IPAddress  string             `json:"ip_address"`
+	CreatedAt  time.Time          `json:"created_at"`
+	LastSeenAt time.Time          `json:"last_seen_at"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	IsCurrent  bool               `json:"is_current"`
+}
+
+type cachedSession struct {
+	userID       primitive.ObjectID
+	active       bool
+	expiresAt    time.Time
+	checkedAt    time.Time
+	lastSeenSync time.Time
+}
+
+type sessionService struct {
+	sessionRepo repositories.SessionRepository
+
+	mu    sync.Mutex
+	cache map[primitive.ObjectID]*cachedSession
+}
+
+func NewSessionService(sessionRepo repositories.SessionRepository) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		cache:       make(map[primitive.ObjectID]*cachedSession),
+	}
+}
+
+func (s *sessionService) CreateSession(userID primitive.ObjectID, client ClientInfo) (*models.Session, error) {
+	now := time.Now()
+	device := strings.TrimSpace(client.Device)
+	if device == "" {
+		device = describeDevice(client.UserAgent)
+	}
+
+	session := &models.Session{
+		UserID:     userID,
+		Device:     device,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(pkg.TokenTTL),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ValidateSession checks that the session is active and belongs to userID.
+// Session state is cached for sessionCacheTTL and last-seen is written at most
+// once per lastSeenWriteInterval, so most requests never touch the database.
+func (s *sessionService) ValidateSession(sessionID, userID primitive.ObjectID) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[sessionID]
+	s.mu.Unlock()
+
+	if !ok || now.Sub(entry.checkedAt) > sessionCacheTTL {
+		session, err := s.sessionRepo.FindByID(sessionID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+		entry = &cachedSession{
+			userID:       session.UserID,
+			active:       session.RevokedAt == nil,
+			expiresAt:    session.ExpiresAt,
+			checkedAt:    now,
+			lastSeenSync: session.LastSeenAt,
+		}
+		s.store(sessionID, entry)
+	}
+
+	s.mu.Lock()
+	if entry.userID != userID {
+		s.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	if !entry.active || !now.Before(entry.expiresAt) {
+		s.mu.Unlock()
+		return ErrSessionInactive
+	}
+	// Only the request that claims the write makes it, outside the lock so
+	// other requests don't wait on the database
+	syncLastSeen := now.Sub(entry.lastSeenSync) >= lastSeenWriteInterval
+	if syncLastSeen {
+		entry.lastSeenSync = now
+	}
+	s.mu.Unlock()
+
+	if syncLastSeen {
+		if err := s.sessionRepo.UpdateLastSeen(sessionID, now); err != nil {
+			// Last-seen is informational; don't fail the request over it
+			log.Printf("Could not update last seen for session %s: %v", sessionID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+type sessionCursor struct {
+	LastSeenAt time.Time          `json:"last_seen_at"`
+	ID         primitive.ObjectID `json:"id"`
+}
+
+func (s *sessionService) ListSessions(userID, currentSessionID primitive.ObjectID, page pkg.PageRequest) (*pkg.Page[SessionResponse], error) {
+	var after *models.Session
+	if page.Cursor != "" {
+		var position sessionCursor
+		if err := pkg.DecodeCursor(page.Cursor, &position); err != nil {
+			return nil, err
+		}
+		after = &models.Session{ID: position.ID, LastSeenAt: position.LastSeenAt}
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = pkg.DefaultPageLimit
+	}
+	sessions, err := s.sessionRepo.FindActivePageByUser(userID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	// The cursor holds the last-seen time the database sorts by, not the cached one
+	stored := pkg.NewPage(sessions, limit, func(session models.Session) any {
+		return sessionCursor{LastSeenAt: session.LastSeenAt, ID: session.ID}
+	})
+
+	result := pkg.Page[SessionResponse]{Items: make([]SessionResponse, 0, len(stored.Items)), NextCursor: stored.NextCursor}
+	for _, session := range stored.Items {
+		lastSeen := session.LastSeenAt
+		// The cache may know about more recent activity than the database
+		s.mu.Lock()
+		if entry, ok := s.cache[session.ID]; ok && entry.lastSeenSync.After(lastSeen) {
+			lastSeen = entry.lastSeenSync
+		}
+		s.mu.Unlock()
+
+		result.Items = append(result.Items, SessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: lastSeen,
+			ExpiresAt:  session.ExpiresAt,
+			IsCurrent:  session.ID == currentSessionID,
+		})
+	}
+	return &result, nil
+}
+
+func (s *sessionService) RevokeSession(userID, sessionID primitive.ObjectID) error {
+	revoked, err := s.sessionRepo.Revoke(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	s.forget(sessionID)
+	return nil
+}
+
+// RevokeOtherSessions logs the user out everywhere except the current session.
+func (s *sessionService) RevokeOtherSessions(userID, currentSessionID primitive.ObjectID) (int, error) {
+	ids, err := s.sessionRepo.RevokeAllForUser(userID, currentSessionID)
+	s.forget(ids...)
+	return len(ids), err
+}
+
+// RevokeAllForUser logs the user out of every session, e.g. on an admin's request.
+func (s *sessionService) RevokeAllForUser(userID primitive.ObjectID) (int, error) {
+	ids, err := s.sessionRepo.RevokeAllForUser(userID, primitive.NilObjectID)
+	s.forget(ids...)
+	return len(ids), err
+}
+
+func (s *sessionService) store(sessionID primitive.ObjectID, entry *cachedSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.cache) >= sessionCachePruneSize {
+		cutoff := time.Now().Add(-sessionCacheTTL)
+		for id, cached := range s.cache {
+			if cached.checkedAt.Before(cutoff) {
+				delete(s.cache, id)
+			}
+		}
+	}
+	s.cache[sessionID] = entry
+}
+
+func (s *sessionService) forget(sessionIDs ...primitive.ObjectID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range sessionIDs {
+		delete(s.cache, id)
+	}
+}
+
+// describeDevice derives a short human readable label like "Chrome on Windows"
+// from a User-Agent header for clients that don't name their device.
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os x") || strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/pkg"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	recoveryCodeCount = 10
+	// twoFactorMaxAttempts invalid codes in a row lock the second factor for
+	// twoFactorLockout, so the codes cannot be guessed
+	twoFactorMaxAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+var (
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotStarted     = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for your role")
+	ErrTwoFactorLocked         = errors.New("too many invalid two-factor codes, try again later")
+)
+
+type TwoFactorService interface {
+	BeginEnrollment(userID primitive.ObjectID) (*TwoFactorEnrollment, error)
+	ConfirmEnrollment(userID primitive.ObjectID, code string) ([]string, error)
+	Disable(userID primitive.ObjectID, code string) error
+	RegenerateRecoveryCodes(userID primitive.ObjectID, code string) ([]string, error)
+	GetPolicy() (*models.SecurityPolicy, error)
+	UpdatePolicy(adminID primitive.ObjectID, input TwoFactorPolicyInput) (*models.SecurityPolicy, error)
+}
+
+type twoFactorService struct {
+	userRepo   repositories.UserRepository
+	policyRepo repositories.SecurityPolicyRepository
+}
+
+func NewTwoFactorService(userRepo repositories.UserRepository, policyRepo repositories.SecurityPolicyRepository) TwoFactorService {
+	return &twoFactorService{userRepo, policyRepo}
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorPolicyInput struct {
+	RequiredRoles []string `json:"required_roles"`
+}
+
+// BeginEnrollment generates a fresh secret for the user. It stays inactive
+// until ConfirmEnrollment is called with a valid code from the authenticator.
+func (s *twoFactorService) BeginEnrollment(userID primitive.ObjectID) (*TwoFactorEnrollment, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := pkg.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateTwoFactor(userID, models.TwoFactorSettings{Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: pkg.TOTPURI(totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment activates 2FA and returns the plaintext recovery codes.
+// They are only ever shown here; the database keeps bcrypt hashes.
+func (s *twoFactorService) ConfirmEnrollment(userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactor.Secret == "" {
+		return nil, ErrTwoFactorNotStarted
+	}
+
+	step, ok := pkg.ValidateTOTP(user.TwoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	settings := models.TwoFactorSettings{
+		Enabled:            true,
+		Secret:             user.TwoFactor.Secret,
+		LastUsedStep:       step,
+		RecoveryCodeHashes: hashes,
+	}
+	if err := s.userRepo.UpdateTwoFactor(userID, settings); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *twoFactorService) Disable(userID primitive.ObjectID, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactor.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	policy, err := s.policyRepo.Get()
+	if err != nil {
+		return err
+	}
+	if policy.RequiresTwoFactor(user.EffectiveRole()) {
+		return ErrTwoFactorRequired
+	}
+
+	if err := verifySecondFactor(s.userRepo, user, code, false); err != nil {
+		return err
+	}
+	return s.userRepo.UpdateTwoFactor(userID, models.TwoFactorSettings{})
+}
+
+// RegenerateRecoveryCodes replaces all remaining recovery codes with a new set.
+func (s *twoFactorService) RegenerateRecoveryCodes(userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := verifySecondFactor(s.userRepo, user, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	// verifySecondFactor updated LastUsedStep on the loaded user
+	user.TwoFactor.RecoveryCodeHashes = hashes
+	if err := s.userRepo.UpdateTwoFactor(userID, user.TwoFactor); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *twoFactorService) GetPolicy() (*models.SecurityPolicy, error) {
+	return s.policyRepo.Get()
+}
+
+func (s *twoFactorService) UpdatePolicy(adminID primitive.ObjectID, input TwoFactorPolicyInput) (*models.SecurityPolicy, error) {
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, role := range input.RequiredRoles {
+		switch role {
+		case models.RoleStudent, models.RoleInstructor, models.RoleAdmin, models.RoleGuardian, models.RoleOperator:
+		default:
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	policy, err := s.policyRepo.Get()
+	if err != nil {
+		return nil, err
+	}
+	policy.TwoFactorRequiredRoles = roles
+	policy.UpdatedBy = adminID
+	policy.UpdatedAt = time.Now()
+	if err := s.policyRepo.Save(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// verifySecondFactor accepts a TOTP code that has not been used before or,
+// when allowRecovery is set, consumes one of the user's recovery codes.
+// The user's stored two-factor state is updated on success. Invalid and
+// replayed codes count towards locking the second factor.
+func verifySecondFactor(userRepo repositories.UserRepository, user *models.User, code string, allowRecovery bool) error {
+	now := time.Now()
+	if user.TwoFactor.LockedUntil != nil && now.Before(*user.TwoFactor.LockedUntil) {
+		return ErrTwoFactorLocked
+	}
+
+	used, err := useSecondFactor(userRepo, user, code, allowRecovery, now)
+	if err != nil {
+		return err
+	}
+	if used {
+		user.TwoFactor.FailedAttempts, user.TwoFactor.LockedUntil = 0, nil
+		return nil
+	}
+
+	failures, err := userRepo.RecordTwoFactorFailure(user.ID)
+	if err != nil {
+		return err
+	}
+	if failures >= twoFactorMaxAttempts {
+		if err := userRepo.LockTwoFactor(user.ID, now.Add(twoFactorLockout)); err != nil {
+			return err
+		}
+		return ErrTwoFactorLocked
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// useSecondFactor marks the code used if it is valid. Each code is only
+// accepted once, even by concurrent requests.
+func useSecondFactor(userRepo repositories.UserRepository, user *models.User, code string, allowRecovery bool, now time.Time) (bool, error) {
+	if step, ok := pkg.ValidateTOTP(user.TwoFactor.Secret, code, now); ok {
+		if step <= user.TwoFactor.LastUsedStep {
+			return false, nil
+		}
+		used, err := userRepo.UseTOTPStep(user.ID, step)
+		if used {
+			user.TwoFactor.LastUsedStep = step
+		}
+		return used, err
+	}
+
+	if !allowRecovery {
+		return false, nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	for i, hash := range user.TwoFactor.RecoveryCodeHashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(normalized)) == nil {
+			used, err := userRepo.UseRecoveryCode(user.ID, hash)
+			if used {
+				remaining := append([]string{}, user.TwoFactor.RecoveryCodeHashes[:i]...)
+				user.TwoFactor.RecoveryCodeHashes = append(remaining, user.TwoFactor.RecoveryCodeHashes[i+1:]...)
+			}
+			return used, err
+		}
+	}
+	return false, nil
+}
+
+// generateRecoveryCodes returns plaintext codes like "k3j9d-q8xw2" and their bcrypt hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		code := encoded[:5] + "-" + encoded[5:]
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) == 10 && !strings.Contains(code, "-") {
+		code = code[:5] + "-" + code[5:]
+	}
+	return code
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Gamified Edu"
+}
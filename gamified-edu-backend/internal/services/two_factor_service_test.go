@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeTwoFactorUsers keeps the stored two-factor state of one user
+type fakeTwoFactorUsers struct {
+	repositories.UserRepository
+	stored models.TwoFactorSettings
+}
+
+func (r *fakeTwoFactorUsers) UseRecoveryCode(userID primitive.ObjectID, hash string) (bool, error) {
+	i := slices.Index(r.stored.RecoveryCodeHashes, hash)
+	if i < 0 {
+		return false, nil
+	}
+	r.stored.RecoveryCodeHashes = slices.Delete(r.stored.RecoveryCodeHashes, i, i+1)
+	r.stored.FailedAttempts, r.stored.LockedUntil = 0, nil
+	return true, nil
+}
+
+func (r *fakeTwoFactorUsers) RecordTwoFactorFailure(userID primitive.ObjectID) (int, error) {
+	r.stored.FailedAttempts++
+	return r.stored.FailedAttempts, nil
+}
+
+func (r *fakeTwoFactorUsers) LockTwoFactor(userID primitive.ObjectID, until time.Time) error {
+	r.stored.LockedUntil, r.stored.FailedAttempts = &until, 0
+	return nil
+}
+
+func newTwoFactorTest(t *testing.T) (*fakeTwoFactorUsers, []string) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeTwoFactorUsers{stored: models.TwoFactorSettings{Enabled: true, Secret: "JBSWY3DPEHPK3PXP", RecoveryCodeHashes: hashes}}, codes
+}
+
+// load is the user as a request reads it
+func (r *fakeTwoFactorUsers) load() *models.User {
+	settings := r.stored
+	settings.RecoveryCodeHashes = slices.Clone(settings.RecoveryCodeHashes)
+	return &models.User{ID: primitive.NewObjectID(), TwoFactor: settings}
+}
+
+func TestRecoveryCodesWorkOnce(t *testing.T) {
+	users, codes := newTwoFactorTest(t)
+	// Two logins read the user before either uses the code
+	first, second := users.load(), users.load()
+
+	if err := verifySecondFactor(users, first, codes[0], true); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := verifySecondFactor(users, second, codes[0], true); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("second use: got %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if users.stored.FailedAttempts != 1 {
+		t.Errorf("got %d failed attempts, want the replay counted", users.stored.FailedAttempts)
+	}
+}
+
+func TestInvalidCodesLockTheSecondFactor(t *testing.T) {
+	users, codes := newTwoFactorTest(t)
+	for attempt := 1; attempt < twoFactorMaxAttempts; attempt++ {
+		if err := verifySecondFactor(users, users.load(), "000000", true); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: got %v, want ErrInvalidTwoFactorCode", attempt, err)
+		}
+	}
+	if err := verifySecondFactor(users, users.load(), "000000", true); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Fatalf("last attempt: got %v, want ErrTwoFactorLocked", err)
+	}
+	// Not even valid codes get through while locked
+	if err := verifySecondFactor(users, users.load(), codes[0], true); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Errorf("valid code while locked: got %v, want ErrTwoFactorLocked", err)
+	}
+
+	expired := time.Now().Add(-time.Second)
+	users.stored.LockedUntil = &expired
+	if err := verifySecondFactor(users, users.load(), codes[0], true); err != nil {
+		t.Errorf("valid code after the lock: %v", err)
+	}
+}
@@ -0,0 +1,573 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gamified-edu-backend/internal/models"
+	"gamified-edu-backend/internal/repositories"
+	"gamified-edu-backend/internal/tenant"
+	"gamified-edu-backend/internal/xapi"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultStatementLimit = 100
+	maxStatementLimit     = 500
+
+	// Statements are forwarded in batches. Failed deliveries are retried
+	// with exponential backoff and given up after forwardMaxAttempts.
+	forwardBatchSize   = 50
+	forwardMaxAttempts = 12
+	forwardBaseDelay   = 30 * time.Second
+	forwardMaxDelay    = 6 * time.Hour
+)
+
+var (
+	ErrStatementNotFound       = errors.New("statement not found")
+	ErrStatementConflict       = errors.New("a different statement with this id already exists")
+	ErrInvalidStatementQuery   = errors.New("invalid statement query")
+	ErrStatementIDMismatch     = errors.New("statement id does not match the statementId parameter")
+	ErrSingleStatementExpected = errors.New("exactly one statement is expected")
+)
+
+// XAPIService keeps learning records as xAPI statements, forwards them to
+// the configured LRS and serves them through a built-in LRS.
+type XAPIService interface {
+	LearningRecorder
+	// StoreStatements stores a statement or a list of statements sent to the
+	// built-in LRS and returns their IDs
+	StoreStatements(ctx context.Context, data []byte) ([]string, error)
+	PutStatement(ctx context.Context, statementID string, data []byte) error
+	// GetStatement returns a statement, or a voided one if voided is set
+	GetStatement(ctx context.Context, statementID string, voided bool) (json.RawMessage, error)
+	QueryStatements(ctx context.Context, input StatementQueryInput) (*StatementQueryResponse, error)
+	// ForwardPending delivers the statements due for forwarding to the LRS
+	ForwardPending(ctx context.Context) error
+	// RunForwarder calls ForwardPending every interval until ctx is done
+	RunForwarder(ctx context.Context, interval time.Duration)
+	// AssignUsers ties statements stored before statements kept their
+	// learner to the learner, so they go with the learner's account
+	AssignUsers() error
+}
+
+// StatementQueryInput holds the query parameters of GET /statements, as sent.
+type StatementQueryInput struct {
+	// Agent is an agent in JSON
+	Agent        string
+	Verb         string
+	Activity     string
+	Registration string
+	Since        string
+	Until        string
+	Limit        string
+	Ascending    string
+	Cursor       string
+}
+
+type StatementQueryResponse struct {
+	Statements []json.RawMessage `json:"statements"`
+	// NextCursor continues the listing, empty on the last page
+	NextCursor string `json:"-"`
+}
+
+type xapiService struct {
+	statementRepo    repositories.XAPIStatementRepository
+	organizationRepo repositories.OrganizationRepository
+	userRepo         repositories.UserRepository
+	// client is nil when no LRS is configured
+	client *xapi.Client
+}
+
+func NewXAPIService(statementRepo repositories.XAPIStatementRepository, organizationRepo repositories.OrganizationRepository, userRepo repositories.UserRepository, client *xapi.Client) XAPIService {
+	return &xapiService{statementRepo: statementRepo, organizationRepo: organizationRepo, userRepo: userRepo, client: client}
+}
+
+// xapiActivityBase is where activity IRIs and learner accounts live
+func xapiActivityBase() string {
+	for _, name := range []string{"XAPI_ACTIVITY_BASE", "FRONTEND_URL"} {
+		if base := os.Getenv(name); base != "" {
+			return strings.TrimSuffix(base, "/")
+		}
+	}
+	return "http://localhost:5173"
+}
+
+// learnerAccountPrefix starts the actor key of every learner, followed by
+// the learner's user ID
+func learnerAccountPrefix() string {
+	return "account:" + xapiActivityBase() + "|"
+}
+
+// statementUserID returns the learner an actor key names, or the zero ID for
+// actors that are not learners here
+func statementUserID(actorKey string) primitive.ObjectID {
+	hex, ok := strings.CutPrefix(actorKey, learnerAccountPrefix())
+	if !ok {
+		return primitive.NilObjectID
+	}
+	userID, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	return userID
+}
+
+func (s *xapiService) RecordEnrollment(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	return s.record(ctx, userID, func(activities xapiActivities) xapi.Statement {
+		return xapi.Statement{Verb: xapi.VerbRegistered, Object: activities.course(course)}
+	})
+}
+
+func (s *xapiService) RecordComponent(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter, component models.ChapterComponent, report MarkComponentInput, completed bool) error {
+	componentType, err := lookupComponentType(component.Type)
+	if err != nil {
+		return err
+	}
+	result := &xapi.Result{Completion: &completed}
+	verb := xapi.VerbExperienced
+	switch {
+	case report.Score != nil:
+		result.Score = xapi.PercentScore(*report.Score)
+		verb = xapi.VerbCompleted
+		if component.PassingScore > 0 {
+			success := *report.Score >= component.PassingScore
+			result.Success = &success
+			verb = xapi.VerbFailed
+			if success {
+				verb = xapi.VerbPassed
+			}
+		}
+	case component.Type == models.ComponentAssignment:
+		verb = xapi.VerbCompleted
+		result.Response = report.Submission
+	case !completed:
+		return nil
+	}
+	if report.SecondsSpent != nil {
+		result.Duration = fmt.Sprintf("PT%dS", *report.SecondsSpent)
+	}
+
+	return s.record(ctx, userID, func(activities xapiActivities) xapi.Statement {
+		return xapi.Statement{
+			Verb:    verb,
+			Object:  activities.component(course, chapter, component, componentType.ActivityType()),
+			Result:  result,
+			Context: activities.context(course, activities.chapter(course, chapter)),
+		}
+	})
+}
+
+func (s *xapiService) RecordChapterCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course, chapter *models.Chapter) error {
+	completed := true
+	return s.record(ctx, userID, func(activities xapiActivities) xapi.Statement {
+		return xapi.Statement{
+			Verb:    xapi.VerbCompleted,
+			Object:  activities.chapter(course, chapter),
+			Result:  &xapi.Result{Completion: &completed},
+			Context: activities.context(course, activities.course(course)),
+		}
+	})
+}
+
+func (s *xapiService) RecordCourseCompleted(ctx context.Context, userID primitive.ObjectID, course *models.Course) error {
+	completed := true
+	return s.record(ctx, userID, func(activities xapiActivities) xapi.Statement {
+		return xapi.Statement{Verb: xapi.VerbCompleted, Object: activities.course(course), Result: &xapi.Result{Completion: &completed}}
+	})
+}
+
+// record stores the statement build returns, made by the user now
+func (s *xapiService) record(ctx context.Context, userID primitive.ObjectID, build func(xapiActivities) xapi.Statement) error {
+	organization, err := s.organization(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	activities := xapiActivities{base: xapiActivityBase(), organization: organization}
+	now := time.Now().UTC()
+	statement := build(activities)
+	statement.ID = xapi.NewStatementID()
+	statement.Timestamp = &now
+	statement.Actor = xapi.Agent{
+		ObjectType: "Agent",
+		Name:       strings.TrimSpace(user.DisplayNameOrFullName()),
+		Account:    &xapi.Account{HomePage: activities.base, Name: userID.Hex()},
+	}
+
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+	received, err := xapi.Parse(data)
+	if err != nil {
+		return err
+	}
+	return s.store(ctx, received[0], activities.authority(), now)
+}
+
+func (s *xapiService) organization(ctx context.Context) (*models.Organization, error) {
+	organizationID, err := tenant.OrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.organizationRepo.FindByID(organizationID)
+}
+
+// xapiActivities names the organization's activities
+type xapiActivities struct {
+	base         string
+	organization *models.Organization
+}
+
+// authority vouches for the organization's statements
+func (a xapiActivities) authority() xapi.Agent {
+	return xapi.Agent{
+		ObjectType: "Agent",
+		Name:       a.organization.Name,
+		Account:    &xapi.Account{HomePage: a.base, Name: "organizations/" + a.organization.Slug},
+	}
+}
+
+func (a xapiActivities) course(course *models.Course) xapi.Activity {
+	id := fmt.Sprintf("%s/xapi/activities/%s/courses/%s", a.base, url.PathEscape(a.organization.Slug), url.PathEscape(slugOrID(course.Slug, course.ID)))
+	return activity(id, course.Title, xapi.ActivityCourse)
+}
+
+func (a xapiActivities) chapter(course *models.Course, chapter *models.Chapter) xapi.Activity {
+	id := fmt.Sprintf("%s/chapters/%s", a.course(course).ID, url.PathEscape(slugOrID(chapter.Slug, chapter.ID)))
+	return activity(id, chapter.Title, xapi.ActivityLesson)
+}
+
+func (a xapiActivities) component(course *models.Course, chapter *models.Chapter, component models.ChapterComponent, activityType string) xapi.Activity {
+	id := fmt.Sprintf("%s/components/%s", a.chapter(course, chapter).ID, url.PathEscape(component.Key))
+	return activity(id, titleOr(component.Title, component.Key), activityType)
+}
+
+// context places an activity in parent, within the course
+func (a xapiActivities) context(course *models.Course, parent xapi.Activity) *xapi.Context {
+	return &xapi.Context{ContextActivities: &xapi.ContextActivities{
+		Parent:   []xapi.Activity{parent},
+		Grouping: []xapi.Activity{a.course(course)},
+	}}
+}
+
+func activity(id, name, activityType string) xapi.Activity {
+	return xapi.Activity{
+		ObjectType: "Activity",
+		ID:         id,
+		Definition: &xapi.ActivityDefinition{Name: map[string]string{"en-US": name}, Type: activityType},
+	}
+}
+
+func slugOrID(slug string, id primitive.ObjectID) string {
+	if slug != "" {
+		return slug
+	}
+	return id.Hex()
+}
+
+func (s *xapiService) StoreStatements(ctx context.Context, data []byte) ([]string, error) {
+	received, err := xapi.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.storeReceived(ctx, received)
+}
+
+// storeReceived stores statements sent to the built-in LRS. Statements
+// already stored with the same content are left as they are.
+func (s *xapiService) storeReceived(ctx context.Context, received []*xapi.Received) ([]string, error) {
+	organization, err := s.organization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	authority := xapiActivities{base: xapiActivityBase(), organization: organization}.authority()
+
+	// Statements are stored all or none, so conflicts are checked up front
+	stored := time.Now().UTC()
+	documents := make([][]byte, len(received))
+	known := make([]bool, len(received))
+	for i, statement := range received {
+		var existing *models.XAPIStatement
+		if statement.ID != "" {
+			if existing, err = s.statementRepo.FindByStatementID(ctx, statement.ID); err != nil {
+				return nil, err
+			}
+		}
+		if documents[i], err = statement.Stamp(stored, authority); err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if !xapi.Same([]byte(existing.Statement), documents[i]) {
+				return nil, fmt.Errorf("%w: %s", ErrStatementConflict, statement.ID)
+			}
+			known[i] = true
+		}
+	}
+
+	ids := make([]string, len(received))
+	for i, statement := range received {
+		ids[i] = statement.ID
+		if known[i] {
+			continue
+		}
+		if err := s.insert(ctx, statement, documents[i], stored); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func (s *xapiService) PutStatement(ctx context.Context, statementID string, data []byte) error {
+	if !xapi.ValidID(statementID) {
+		return fmt.Errorf("%w: statementId must be a UUID", ErrInvalidStatementQuery)
+	}
+	received, err := xapi.Parse(data)
+	if err != nil {
+		return err
+	}
+	if len(received) != 1 {
+		return ErrSingleStatementExpected
+	}
+	statementID = strings.ToLower(statementID)
+	if received[0].ID != "" && received[0].ID != statementID {
+		return ErrStatementIDMismatch
+	}
+	received[0].ID = statementID
+	_, err = s.storeReceived(ctx, received)
+	return err
+}
+
+func (s *xapiService) GetStatement(ctx context.Context, statementID string, voided bool) (json.RawMessage, error) {
+	statement, err := s.statementRepo.FindByStatementID(ctx, strings.ToLower(statementID))
+	if err != nil {
+		return nil, err
+	}
+	if statement == nil || statement.Voided != voided {
+		return nil, ErrStatementNotFound
+	}
+	return json.RawMessage(statement.Statement), nil
+}
+
+func (s *xapiService) QueryStatements(ctx context.Context, input StatementQueryInput) (*StatementQueryResponse, error) {
+	invalid := func(message string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidStatementQuery, message)
+	}
+	query := repositories.XAPIStatementQuery{
+		VerbID:       input.Verb,
+		ActivityID:   input.Activity,
+		Registration: strings.ToLower(input.Registration),
+		Limit:        defaultStatementLimit,
+	}
+	if input.Agent != "" {
+		var agent xapi.Agent
+		if err := json.Unmarshal([]byte(input.Agent), &agent); err != nil || agent.Key() == "" {
+			return nil, invalid("agent must be an agent in JSON with an mbox, mbox_sha1sum, openid or account")
+		}
+		query.ActorKey = agent.Key()
+	}
+	for _, bound := range []struct {
+		value  string
+		target **time.Time
+		name   string
+	}{{input.Since, &query.Since, "since"}, {input.Until, &query.Until, "until"}} {
+		if bound.value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, bound.value)
+		if err != nil {
+			return nil, invalid(bound.name + " must be an ISO 8601 date and time")
+		}
+		*bound.target = &at
+	}
+	if input.Limit != "" {
+		var limit int
+		if _, err := fmt.Sscan(input.Limit, &limit); err != nil || limit < 0 {
+			return nil, invalid("limit must be a number")
+		}
+		// As in xAPI, a limit of 0 asks for as many as the LRS returns
+		if limit == 0 || limit > maxStatementLimit {
+			limit = maxStatementLimit
+		}
+		query.Limit = limit
+	}
+	switch input.Ascending {
+	case "", "false":
+	case "true":
+		query.Ascending = true
+	default:
+		return nil, invalid("ascending must be true or false")
+	}
+	if input.Cursor != "" {
+		after, err := primitive.ObjectIDFromHex(input.Cursor)
+		if err != nil {
+			return nil, invalid("cursor is not valid")
+		}
+		query.After = after
+	}
+
+	// One more than asked tells whether there is another page
+	limit := query.Limit
+	query.Limit++
+	statements, err := s.statementRepo.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	response := &StatementQueryResponse{Statements: []json.RawMessage{}}
+	if len(statements) > limit {
+		statements = statements[:limit]
+		response.NextCursor = statements[limit-1].ID.Hex()
+	}
+	for _, statement := range statements {
+		response.Statements = append(response.Statements, json.RawMessage(statement.Statement))
+	}
+	return response, nil
+}
+
+// store stores a statement made by the backend itself
+func (s *xapiService) store(ctx context.Context, statement *xapi.Received, authority xapi.Agent, stored time.Time) error {
+	document, err := statement.Stamp(stored, authority)
+	if err != nil {
+		return err
+	}
+	return s.insert(ctx, statement, document, stored)
+}
+
+// insert saves a stamped statement, queues it for forwarding and applies it
+// if it voids another statement
+func (s *xapiService) insert(ctx context.Context, statement *xapi.Received, document []byte, stored time.Time) error {
+	record := &models.XAPIStatement{
+		StatementID:  statement.ID,
+		Statement:    string(document),
+		ActorKey:     statement.ActorKey,
+		UserID:       statementUserID(statement.ActorKey),
+		VerbID:       statement.VerbID,
+		ActivityID:   statement.ActivityID,
+		Registration: statement.Registration,
+		Timestamp:    statement.Timestamp,
+		Stored:       stored,
+		Forward:      models.XAPIForwarding{Pending: s.client != nil, NextAttemptAt: stored},
+	}
+	if err := s.statementRepo.Insert(ctx, record); err != nil {
+		return err
+	}
+	if statement.VoidedID == "" {
+		return nil
+	}
+	// Voiding statements cannot be voided themselves
+	target, err := s.statementRepo.FindByStatementID(ctx, statement.VoidedID)
+	if err != nil || target == nil || target.VerbID == xapi.VerbVoided.ID {
+		return err
+	}
+	return s.statementRepo.MarkVoided(ctx, statement.VoidedID)
+}
+
+func (s *xapiService) AssignUsers() error {
+	return s.statementRepo.AssignUsers(learnerAccountPrefix())
+}
+
+func (s *xapiService) ForwardPending(ctx context.Context) error {
+	if s.client == nil {
+		return nil
+	}
+	for ctx.Err() == nil {
+		due, err := s.statementRepo.FindDueForForwarding(time.Now(), forwardBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+		if err := s.forwardBatch(ctx, due); err != nil {
+			return err
+		}
+		if len(due) < forwardBatchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *xapiService) RunForwarder(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.ForwardPending(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Could not forward xAPI statements: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *xapiService) forwardBatch(ctx context.Context, statements []models.XAPIStatement) error {
+	documents := make([]json.RawMessage, len(statements))
+	ids := make([]primitive.ObjectID, len(statements))
+	for i, statement := range statements {
+		documents[i] = json.RawMessage(statement.Statement)
+		ids[i] = statement.ID
+	}
+	err := s.client.Send(ctx, documents)
+	if err == nil {
+		return s.statementRepo.MarkForwarded(ids, time.Now())
+	}
+
+	var sendErr *xapi.SendError
+	if len(statements) > 1 && errors.As(err, &sendErr) && sendErr.Permanent() {
+		// The LRS rejected the batch; send the statements one by one so a
+		// single bad statement does not hold back the others
+		for i := range statements {
+			if err := s.forwardOne(ctx, &statements[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i := range statements {
+		if err := s.scheduleRetry(&statements[i], err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *xapiService) forwardOne(ctx context.Context, statement *models.XAPIStatement) error {
+	err := s.client.Send(ctx, []json.RawMessage{json.RawMessage(statement.Statement)})
+	var sendErr *xapi.SendError
+	// A conflict means the LRS already has the statement
+	if err == nil || errors.As(err, &sendErr) && sendErr.Conflict() {
+		return s.statementRepo.MarkForwarded([]primitive.ObjectID{statement.ID}, time.Now())
+	}
+	return s.scheduleRetry(statement, err)
+}
+
+func (s *xapiService) scheduleRetry(statement *models.XAPIStatement, sendErr error) error {
+	attempts := statement.Forward.Attempts + 1
+	var rejected *xapi.SendError
+	giveUp := attempts >= forwardMaxAttempts || errors.As(sendErr, &rejected) && rejected.Permanent()
+	if giveUp {
+		log.Printf("Giving up forwarding xAPI statement %s after %d attempts: %v", statement.StatementID, attempts, sendErr)
+	}
+	delay := forwardMaxDelay
+	if attempts < 20 {
+		delay = forwardBaseDelay << (attempts - 1)
+	}
+	if delay > forwardMaxDelay {
+		delay = forwardMaxDelay
+	}
+	return s.statementRepo.ScheduleForwardRetry(statement.ID, attempts, time.Now().Add(delay), sendErr.Error(), giveUp)
+}
@@ -0,0 +1,31 @@
+// Package tenant carries the organization a request belongs to through
+// context.Context so that repositories can scope every query to it.
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNoOrganization is returned by tenant-scoped operations when the context
+// does not name an organization. Failing closed means a forgotten scope can
+// never read or write another organization's data.
+var ErrNoOrganization = errors.New("no organization in request context")
+
+type contextKey struct{}
+
+// WithOrganization returns a copy of ctx scoped to organizationID.
+func WithOrganization(ctx context.Context, organizationID primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, contextKey{}, organizationID)
+}
+
+// OrganizationID returns the organization ctx is scoped to.
+func OrganizationID(ctx context.Context) (primitive.ObjectID, error) {
+	organizationID, ok := ctx.Value(contextKey{}).(primitive.ObjectID)
+	if !ok || organizationID.IsZero() {
+		return primitive.NilObjectID, ErrNoOrganization
+	}
+	return organizationID, nil
+}
@@ -0,0 +1,94 @@
+package xapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client delivers statements to an external LRS.
+type Client struct {
+	endpoint string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient sends to the LRS whose xAPI endpoint is endpoint, e.g.
+// https://lrs.example.org/xapi/, authenticating with HTTP basic auth.
+func NewClient(endpoint, username, password string) *Client {
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/statements",
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ClientFromEnv builds the client for the LRS at XAPI_LRS_ENDPOINT, with the
+// credentials in XAPI_LRS_USERNAME and XAPI_LRS_PASSWORD. It returns nil
+// when no LRS is configured.
+func ClientFromEnv() *Client {
+	endpoint := os.Getenv("XAPI_LRS_ENDPOINT")
+	if endpoint == "" {
+		return nil
+	}
+	return NewClient(endpoint, os.Getenv("XAPI_LRS_USERNAME"), os.Getenv("XAPI_LRS_PASSWORD"))
+}
+
+// SendError is returned when the LRS did not accept statements.
+type SendError struct {
+	Status int
+	Body   string
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("LRS answered %d: %s", e.Status, e.Body)
+}
+
+// Permanent reports whether sending the same statements again cannot
+// succeed, as the LRS rejected them rather than failing itself.
+func (e *SendError) Permanent() bool {
+	return e.Status >= 400 && e.Status < 500 && e.Status != http.StatusRequestTimeout && e.Status != http.StatusTooManyRequests
+}
+
+// Conflict reports whether the LRS already has a different statement with
+// one of the IDs sent.
+func (e *SendError) Conflict() bool {
+	return e.Status == http.StatusConflict
+}
+
+// Send posts statements, each already in JSON, in one request.
+func (c *Client) Send(ctx context.Context, statements []json.RawMessage) error {
+	body, err := json.Marshal(statements)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(VersionHeader, Version)
+	if c.username != "" || c.password != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		io.Copy(io.Discard, response.Body)
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 1000))
+	return &SendError{Status: response.StatusCode, Body: strings.TrimSpace(string(message))}
+}
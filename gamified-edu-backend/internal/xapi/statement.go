@@ -0,0 +1,371 @@
+// Package xapi implements the parts of the Experience API (xAPI, formerly
+// Tin Can) the backend needs: statements for learning events, checking
+// statements an LRS receives, and a client that delivers statements to an
+// external Learning Record Store.
+package xapi
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Version is the xAPI version statements are written in and the LRS speaks.
+const Version = "1.0.3"
+
+// VersionHeader is sent with every xAPI request and response.
+const VersionHeader = "X-Experience-API-Version"
+
+// ErrInvalidStatement is returned for statements that break the xAPI rules.
+var ErrInvalidStatement = errors.New("invalid xAPI statement")
+
+// Verbs from the ADL vocabulary
+var (
+	VerbRegistered  = Verb{ID: "http://adlnet.gov/expapi/verbs/registered", Display: map[string]string{"en-US": "registered"}}
+	VerbExperienced = Verb{ID: "http://adlnet.gov/expapi/verbs/experienced", Display: map[string]string{"en-US": "experienced"}}
+	VerbCompleted   = Verb{ID: "http://adlnet.gov/expapi/verbs/completed", Display: map[string]string{"en-US": "completed"}}
+	VerbPassed      = Verb{ID: "http://adlnet.gov/expapi/verbs/passed", Display: map[string]string{"en-US": "passed"}}
+	VerbFailed      = Verb{ID: "http://adlnet.gov/expapi/verbs/failed", Display: map[string]string{"en-US": "failed"}}
+	VerbVoided      = Verb{ID: "http://adlnet.gov/expapi/verbs/voided", Display: map[string]string{"en-US": "voided"}}
+)
+
+// Activity types from the ADL and community vocabularies
+const (
+	ActivityCourse      = "http://adlnet.gov/expapi/activities/course"
+	ActivityLesson      = "http://adlnet.gov/expapi/activities/lesson"
+	ActivityAssessment  = "http://adlnet.gov/expapi/activities/assessment"
+	ActivityLink        = "http://adlnet.gov/expapi/activities/link"
+	ActivityPerformance = "http://adlnet.gov/expapi/activities/performance"
+	ActivityVideo       = "https://w3id.org/xapi/video/activity-type/video"
+	ActivityDocument    = "http://id.tincanapi.com/activitytype/document"
+	ActivitySlideDeck   = "http://id.tincanapi.com/activitytype/slide-deck"
+)
+
+// Statement is a statement the backend emits. Statements received by the
+// LRS may use more of the spec and are handled as Received.
+type Statement struct {
+	ID        string     `json:"id,omitempty"`
+	Actor     Agent      `json:"actor"`
+	Verb      Verb       `json:"verb"`
+	Object    Activity   `json:"object"`
+	Result    *Result    `json:"result,omitempty"`
+	Context   *Context   `json:"context,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+type Agent struct {
+	ObjectType  string   `json:"objectType,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Mbox        string   `json:"mbox,omitempty"`
+	MboxSHA1Sum string   `json:"mbox_sha1sum,omitempty"`
+	OpenID      string   `json:"openid,omitempty"`
+	Account     *Account `json:"account,omitempty"`
+}
+
+type Account struct {
+	HomePage string `json:"homePage"`
+	Name     string `json:"name"`
+}
+
+// Key identifies the agent by its inverse functional identifier, or is empty
+// when the agent has none. Agents with the same key are the same person.
+func (a Agent) Key() string {
+	switch {
+	case a.Mbox != "":
+		return "mbox:" + strings.ToLower(strings.TrimPrefix(a.Mbox, "mailto:"))
+	case a.MboxSHA1Sum != "":
+		return "mbox_sha1sum:" + strings.ToLower(a.MboxSHA1Sum)
+	case a.OpenID != "":
+		return "openid:" + a.OpenID
+	case a.Account != nil && a.Account.HomePage != "" && a.Account.Name != "":
+		return "account:" + a.Account.HomePage + "|" + a.Account.Name
+	}
+	return ""
+}
+
+type Verb struct {
+	ID      string            `json:"id"`
+	Display map[string]string `json:"display,omitempty"`
+}
+
+// Activity is also used for the other kinds of statement objects the LRS
+// accepts, told apart by ObjectType.
+type Activity struct {
+	ObjectType string              `json:"objectType,omitempty"`
+	ID         string              `json:"id,omitempty"`
+	Definition *ActivityDefinition `json:"definition,omitempty"`
+}
+
+type ActivityDefinition struct {
+	Name map[string]string `json:"name,omitempty"`
+	Type string            `json:"type,omitempty"`
+}
+
+type Result struct {
+	Score      *Score `json:"score,omitempty"`
+	Success    *bool  `json:"success,omitempty"`
+	Completion *bool  `json:"completion,omitempty"`
+	Response   string `json:"response,omitempty"`
+	// Duration is an ISO 8601 duration, e.g. PT90S
+	Duration string `json:"duration,omitempty"`
+}
+
+type Score struct {
+	Scaled *float64 `json:"scaled,omitempty"`
+	Raw    *float64 `json:"raw,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+type Context struct {
+	Registration      string             `json:"registration,omitempty"`
+	Platform          string             `json:"platform,omitempty"`
+	ContextActivities *ContextActivities `json:"contextActivities,omitempty"`
+}
+
+type ContextActivities struct {
+	Parent   []Activity `json:"parent,omitempty"`
+	Grouping []Activity `json:"grouping,omitempty"`
+}
+
+// PercentScore is the result score for a score in percent
+func PercentScore(percent int) *Score {
+	scaled, raw, min, max := float64(percent)/100, float64(percent), 0.0, 100.0
+	return &Score{Scaled: &scaled, Raw: &raw, Min: &min, Max: &max}
+}
+
+// NewStatementID returns a random (version 4) UUID.
+func NewStatementID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ValidID reports whether id is a UUID, as statement IDs and registrations are.
+func ValidID(id string) bool {
+	return uuidPattern.MatchString(id)
+}
+
+// Received is a statement as a client sent it, along with the fields an LRS
+// looks statements up by. The statement itself is kept as sent, so
+// extensions and other parts of the spec the backend does not use survive.
+type Received struct {
+	ID           string
+	ActorKey     string
+	VerbID       string
+	ActivityID   string
+	Registration string
+	// VoidedID is the statement a voiding statement voids
+	VoidedID  string
+	Timestamp time.Time
+	document  map[string]json.RawMessage
+}
+
+// received is how Parse reads the parts of a statement it checks
+type received struct {
+	ID        string          `json:"id"`
+	Actor     *receivedAgent  `json:"actor"`
+	Verb      *Verb           `json:"verb"`
+	Object    *receivedObject `json:"object"`
+	Result    *Result         `json:"result"`
+	Context   *Context        `json:"context"`
+	Timestamp string          `json:"timestamp"`
+}
+
+type receivedAgent struct {
+	Agent
+	Member []Agent `json:"member"`
+}
+
+type receivedObject struct {
+	receivedAgent
+	ID string `json:"id"`
+}
+
+// Parse reads a statement or a list of statements and checks them.
+func Parse(data []byte) ([]*Received, error) {
+	var documents []map[string]json.RawMessage
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &documents); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+	} else {
+		var document map[string]json.RawMessage
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		documents = append(documents, document)
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("%w: no statements", ErrInvalidStatement)
+	}
+
+	statements := make([]*Received, 0, len(documents))
+	ids := map[string]bool{}
+	for i, document := range documents {
+		statement, err := parseDocument(document)
+		if err != nil {
+			if len(documents) > 1 {
+				return nil, fmt.Errorf("statement %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+		if statement.ID != "" {
+			if ids[statement.ID] {
+				return nil, fmt.Errorf("%w: statement id %s is used twice", ErrInvalidStatement, statement.ID)
+			}
+			ids[statement.ID] = true
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+func parseDocument(document map[string]json.RawMessage) (*Received, error) {
+	if document == nil {
+		return nil, fmt.Errorf("%w: a statement must be an object", ErrInvalidStatement)
+	}
+	data, _ := json.Marshal(document)
+	var parsed received
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidStatement, fmt.Sprintf(format, args...))
+	}
+
+	statement := &Received{ID: strings.ToLower(parsed.ID), document: document}
+	if parsed.ID != "" && !ValidID(parsed.ID) {
+		return nil, invalid("id must be a UUID")
+	}
+	if parsed.Actor == nil {
+		return nil, invalid("actor is required")
+	}
+	statement.ActorKey = parsed.Actor.Key()
+	switch parsed.Actor.ObjectType {
+	case "", "Agent":
+		if statement.ActorKey == "" {
+			return nil, invalid("the actor needs an mbox, mbox_sha1sum, openid or account")
+		}
+	case "Group":
+		if statement.ActorKey == "" && len(parsed.Actor.Member) == 0 {
+			return nil, invalid("an anonymous group needs members")
+		}
+	default:
+		return nil, invalid("the actor must be an Agent or a Group")
+	}
+	if parsed.Verb == nil || !absoluteIRI(parsed.Verb.ID) {
+		return nil, invalid("verb.id must be an IRI")
+	}
+	statement.VerbID = parsed.Verb.ID
+
+	if parsed.Object == nil {
+		return nil, invalid("object is required")
+	}
+	switch parsed.Object.ObjectType {
+	case "", "Activity":
+		if !absoluteIRI(parsed.Object.ID) {
+			return nil, invalid("object.id must be an IRI")
+		}
+		statement.ActivityID = parsed.Object.ID
+	case "Agent", "Group":
+		if parsed.Object.Key() == "" && len(parsed.Object.Member) == 0 {
+			return nil, invalid("the object agent needs an mbox, mbox_sha1sum, openid or account")
+		}
+	case "StatementRef":
+		if !ValidID(parsed.Object.ID) {
+			return nil, invalid("a StatementRef needs the id of a statement")
+		}
+	case "SubStatement":
+	default:
+		return nil, invalid("unknown object type %q", parsed.Object.ObjectType)
+	}
+	if statement.VerbID == VerbVoided.ID {
+		if parsed.Object.ObjectType != "StatementRef" {
+			return nil, invalid("a voiding statement must refer to the statement it voids")
+		}
+		statement.VoidedID = strings.ToLower(parsed.Object.ID)
+	}
+
+	if parsed.Result != nil && parsed.Result.Score != nil && parsed.Result.Score.Scaled != nil {
+		if scaled := *parsed.Result.Score.Scaled; scaled < -1 || scaled > 1 {
+			return nil, invalid("result.score.scaled must be between -1 and 1")
+		}
+	}
+	if parsed.Context != nil && parsed.Context.Registration != "" {
+		if !ValidID(parsed.Context.Registration) {
+			return nil, invalid("context.registration must be a UUID")
+		}
+		statement.Registration = strings.ToLower(parsed.Context.Registration)
+	}
+	if parsed.Timestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, parsed.Timestamp)
+		if err != nil {
+			return nil, invalid("timestamp must be an ISO 8601 date and time")
+		}
+		statement.Timestamp = timestamp
+	}
+	return statement, nil
+}
+
+// Stamp completes the statement the way an LRS stores it: it gets an ID if
+// it has none, the time it was stored, the authority vouching for it and
+// the xAPI version. It returns the statement as JSON.
+func (r *Received) Stamp(stored time.Time, authority Agent) ([]byte, error) {
+	if r.ID == "" {
+		r.ID = NewStatementID()
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = stored
+	}
+	fields := map[string]interface{}{
+		"id":        r.ID,
+		"timestamp": r.Timestamp.UTC().Format(time.RFC3339Nano),
+		"stored":    stored.UTC().Format(time.RFC3339Nano),
+		"authority": authority,
+		"version":   Version,
+	}
+	for name, value := range fields {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		r.document[name] = data
+	}
+	return json.Marshal(r.document)
+}
+
+// Same reports whether two statements with the same ID say the same thing,
+// leaving out what the LRS adds when storing one.
+func Same(a, b []byte) bool {
+	normalize := func(data []byte) string {
+		// Decoding into interface{} sorts the keys of nested objects too
+		var document map[string]interface{}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return string(data)
+		}
+		for _, name := range []string{"stored", "authority", "version", "timestamp"} {
+			delete(document, name)
+		}
+		normalized, _ := json.Marshal(document)
+		return string(normalized)
+	}
+	return normalize(a) == normalize(b)
+}
+
+func absoluteIRI(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != "" && (parsed.Host != "" || parsed.Opaque != "")
+}
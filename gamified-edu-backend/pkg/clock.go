@@ -0,0 +1,25 @@
+package pkg
+
+import "time"
+
+// Clock tells the current time. Services take one instead of calling
+// time.Now, so tests can run them at any moment.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real clock.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock always tells the same time.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
@@ -10,30 +10,122 @@ import (
 
 var jwtSecretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
 
-func GenerateToken(userID primitive.ObjectID) (string, error) {
+// Purpose claim used for the short-lived token handed out between the
+// password step and the TOTP step of a two-factor login.
+const purposeTwoFactorChallenge = "2fa_challenge"
+
+const twoFactorChallengeTTL = 5 * time.Minute
+
+// TokenTTL is how long an access token (and the session behind it) stays valid.
+const TokenTTL = time.Hour * 72
+
+// AuthClaims is the information carried inside an access token.
+type AuthClaims struct {
+    UserID    primitive.ObjectID
+    SessionID primitive.ObjectID
+    Role      string
+    // OrganizationID is the tenant all requests made with the token are scoped to
+    OrganizationID primitive.ObjectID
+    // TwoFactorSetupRequired is set when the user's role requires 2FA but the
+    // user has not enrolled yet. Such tokens cannot reach role-protected routes.
+    TwoFactorSetupRequired bool
+}
+
+func GenerateToken(authClaims AuthClaims) (string, error) {
     claims := jwt.MapClaims{
-        "user_id": userID.Hex(), // Convert ObjectID to string
-        "exp":     time.Now().Add(time.Hour * 72).Unix(),
+        "user_id": authClaims.UserID.Hex(), // Convert ObjectID to string
+        "sid":     authClaims.SessionID.Hex(),
+        "role":    authClaims.Role,
+        "org_id":  authClaims.OrganizationID.Hex(),
+        "exp":     time.Now().Add(TokenTTL).Unix(),
         "iat":     time.Now().Unix(),
     }
+    if authClaims.TwoFactorSetupRequired {
+        claims["2fa_setup_required"] = true
+    }
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
     return token.SignedString(jwtSecretKey)
 }
 
-func ValidateToken(tokenString string) (primitive.ObjectID, error) {
+func ValidateToken(tokenString string) (*AuthClaims, error) {
+    claims, err := parseClaims(tokenString)
+    if err != nil {
+        return nil, err
+    }
+    // Challenge tokens only prove the password step and must never act as access tokens
+    if purpose, _ := claims["purpose"].(string); purpose != "" {
+        return nil, errors.New("invalid token")
+    }
+    userID, err := userIDFromClaims(claims)
+    if err != nil {
+        return nil, err
+    }
+    // Every access token is bound to a session so that it can be revoked
+    sessionIDHex, _ := claims["sid"].(string)
+    sessionID, err := primitive.ObjectIDFromHex(sessionIDHex)
+    if err != nil {
+        return nil, errors.New("invalid session in token")
+    }
+    // Tokens without an organization cannot be scoped and are rejected
+    organizationIDHex, _ := claims["org_id"].(string)
+    organizationID, err := primitive.ObjectIDFromHex(organizationIDHex)
+    if err != nil || organizationID.IsZero() {
+        return nil, errors.New("invalid organization in token")
+    }
+    role, _ := claims["role"].(string)
+    setupRequired, _ := claims["2fa_setup_required"].(bool)
+    return &AuthClaims{
+        UserID:                 userID,
+        SessionID:              sessionID,
+        Role:                   role,
+        OrganizationID:         organizationID,
+        TwoFactorSetupRequired: setupRequired,
+    }, nil
+}
+
+// GenerateChallengeToken issues a short-lived token proving that the user
+// passed the password step of a two-factor login.
+func GenerateChallengeToken(userID primitive.ObjectID) (string, error) {
+    claims := jwt.MapClaims{
+        "user_id": userID.Hex(),
+        "purpose": purposeTwoFactorChallenge,
+        "exp":     time.Now().Add(twoFactorChallengeTTL).Unix(),
+        "iat":     time.Now().Unix(),
+    }
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    return token.SignedString(jwtSecretKey)
+}
+
+func ValidateChallengeToken(tokenString string) (primitive.ObjectID, error) {
+    claims, err := parseClaims(tokenString)
+    if err != nil {
+        return primitive.NilObjectID, err
+    }
+    if purpose, _ := claims["purpose"].(string); purpose != purposeTwoFactorChallenge {
+        return primitive.NilObjectID, errors.New("invalid challenge token")
+    }
+    return userIDFromClaims(claims)
+}
+
+func parseClaims(tokenString string) (jwt.MapClaims, error) {
     token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
         return jwtSecretKey, nil
-    })
+    }, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
     if err != nil {
-        return primitive.NilObjectID, err
+        return nil, err
+    }
+    claims, ok := token.Claims.(jwt.MapClaims)
+    if !ok || !token.Valid {
+        return nil, errors.New("invalid token")
     }
-    if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-        userIDHex := claims["user_id"].(string)
-        userID, err := primitive.ObjectIDFromHex(userIDHex)
-        if err != nil {
-            return primitive.NilObjectID, errors.New("invalid user ID in token")
-        }
-        return userID, nil
+    return claims, nil
+}
+
+func userIDFromClaims(claims jwt.MapClaims) (primitive.ObjectID, error) {
+    userIDHex, _ := claims["user_id"].(string)
+    userID, err := primitive.ObjectIDFromHex(userIDHex)
+    if err != nil {
+        return primitive.NilObjectID, errors.New("invalid user ID in token")
     }
-    return primitive.NilObjectID, errors.New("invalid token")
-}
\ No newline at end of file
+    return userID, nil
+}
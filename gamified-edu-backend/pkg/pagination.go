@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// List endpoints page with cursors: a client asks for up to limit items and
+// passes the next_cursor of a page back as cursor to get the page after it.
+// Cursors hold the position of the last item returned rather than an offset,
+// so items added or removed meanwhile don't shift the pages.
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("limit must be a positive number")
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest asks for a page of a list.
+type PageRequest struct {
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+	Limit  int
+}
+
+// PageRequestFromQuery reads the "cursor" and "limit" query parameters. The
+// limit defaults to DefaultPageLimit and is capped at MaxPageLimit.
+func PageRequestFromQuery(c *gin.Context) (PageRequest, error) {
+	request := PageRequest{Cursor: c.Query("cursor"), Limit: DefaultPageLimit}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return request, ErrInvalidLimit
+		}
+		request.Limit = min(limit, MaxPageLimit)
+	}
+	return request, nil
+}
+
+// Page is the response of every list endpoint. Reports and search results
+// are not lists; they come whole.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor gets the next page; empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// NewPage makes a page of items, fetched with one more than limit to tell
+// whether there is a next page. position gives what the cursor of the next
+// page holds, usually the sort key and ID of the last item.
+func NewPage[T any](items []T, limit int, position func(T) any) Page[T] {
+	page := Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = EncodeCursor(position(items[limit-1]))
+	}
+	return page
+}
+
+// EncodeCursor makes an opaque cursor of a position.
+func EncodeCursor(position any) string {
+	data, err := json.Marshal(position)
+	if err != nil {
+		// Positions are plain structs of IDs, strings and numbers
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads the position in a cursor made by EncodeCursor.
+func DecodeCursor(cursor string, position any) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, position); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -37,7 +37,8 @@ export const loginUser = (credentials) =>
   apiClient.post("/auth/login", credentials);
 
 // Course endpoints
-export const getCourses = () => apiClient.get("/courses");
+// params: { cursor, limit, sort, completion }; responses are pages of { items, next_cursor }
+export const getCourses = (params) => apiClient.get("/courses", { params });
 export const getCourseDetails = (courseId) =>
   apiClient.get(`/courses/${courseId}`);
 
@@ -46,7 +47,11 @@ export const getDashboardData = () => apiClient.get("/dashboard");
 export const refreshDashboardData = () => apiClient.get("/dashboard"); // Alias for consistency
 
 // User endpoints
-export const getUserProfile = () => apiClient.get("/auth/profile");
+export const getUserProfile = () => apiClient.get("/me");
+export const updateUserProfile = (profileData) =>
+  apiClient.patch("/me", profileData);
+export const deleteAccount = (password) =>
+  apiClient.delete("/me", { data: { password } });
 
 // Progress endpoints
 export const updateProgress = (progressData) =>
@@ -80,16 +80,36 @@ const ErrorMessage = styled.p`
   margin-top: 2rem;
 `;
 
+const LoadMoreButton = styled.button`
+  display: block;
+  margin: 0 auto 3rem;
+  padding: 0.75rem 2rem;
+  border: none;
+  border-radius: 8px;
+  background: #4f46e5;
+  color: white;
+  font-size: 1rem;
+  cursor: pointer;
+
+  &:disabled {
+    opacity: 0.6;
+    cursor: default;
+  }
+`;
+
 const CoursesPage = () => {
   const [courses, setCourses] = useState([]);
+  const [nextCursor, setNextCursor] = useState('');
   const [loading, setLoading] = useState(true);
+  const [loadingMore, setLoadingMore] = useState(false);
   const [error, setError] = useState('');
 
   useEffect(() => {
     const fetchCourses = async () => {
       try {
         const response = await getCourses();
-        setCourses(response.data.data);
+        setCourses(response.data.data.items);
+        setNextCursor(response.data.data.next_cursor);
       } catch (err) {
         setError('Failed to fetch courses.');
       } finally {
@@ -99,6 +119,19 @@ const CoursesPage = () => {
     fetchCourses();
   }, []);
 
+  const loadMore = async () => {
+    setLoadingMore(true);
+    try {
+      const response = await getCourses({ cursor: nextCursor });
+      setCourses((loaded) => [...loaded, ...response.data.data.items]);
+      setNextCursor(response.data.data.next_cursor);
+    } catch (err) {
+      setError('Failed to fetch courses.');
+    } finally {
+      setLoadingMore(false);
+    }
+  };
+
   if (loading) return <LoadingSpinner>Loading your learning modules...</LoadingSpinner>;
   if (error) return <ErrorMessage>{error}</ErrorMessage>;
 
@@ -117,6 +150,11 @@ const CoursesPage = () => {
           <CourseCard key={course.id} course={course} />
         ))}
       </CoursesGrid>
+      {nextCursor && (
+        <LoadMoreButton onClick={loadMore} disabled={loadingMore}>
+          {loadingMore ? 'Loading...' : 'Load more'}
+        </LoadMoreButton>
+      )}
     </div>
   );
 };